@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
@@ -11,6 +12,8 @@ import (
 	"github.com/jorge-dev/centsible/internal/config"
 	"github.com/jorge-dev/centsible/internal/database"
 	"github.com/jorge-dev/centsible/internal/logger"
+	"github.com/jorge-dev/centsible/internal/recurring"
+	"github.com/jorge-dev/centsible/internal/repository"
 	"github.com/jorge-dev/centsible/server"
 )
 
@@ -45,14 +48,22 @@ func main() {
 	}
 	logger.InitLogger(cfg)
 
-	ctx := context.Background()
+	// Cancelled once the server has shut down, stopping background workers
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	config.Get().PrintBannerFromFile()
 
-	httpServer, serverImpl := server.NewServer(ctx)
+	httpServer, serverImpl, err := server.NewServer(ctx)
+	if err != nil {
+		slog.Error("Server configuration is invalid", "error", err)
+		os.Exit(1)
+	}
 
-	if httpServer == nil || serverImpl == nil {
-		slog.Error("Server configuration is invalid")
+	// Turn due recurring transactions into income and expenses until ctx is cancelled
+	if config.Get().AppEnv != "test" {
+		worker := recurring.NewWorker(repository.New(serverImpl.GetDB().GetPool()))
+		worker.Start(ctx, recurring.Interval)
 	}
 
 	go func() {
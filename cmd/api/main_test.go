@@ -9,7 +9,7 @@ import (
 	"testing"
 	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jorge-dev/centsible/server"
 )
 
@@ -30,10 +30,14 @@ func (m *MockDB) Close(ctx context.Context) error {
 	return nil
 }
 
-func (m *MockDB) GetConnection() *pgx.Conn {
+func (m *MockDB) GetPool() *pgxpool.Pool {
 	return nil // For testing purposes, we return nil as we don't need a real connection
 }
 
+func (m *MockDB) GetReplicaPool() *pgxpool.Pool {
+	return nil
+}
+
 func (m *MockDB) Health() map[string]string {
 	return map[string]string{
 		"status":  "up",
@@ -104,9 +108,9 @@ func TestMain(t *testing.T) {
 
 	// Start the server in a goroutine
 	go func() {
-		httpServer, serverImpl := server.NewServer(ctx)
-		if httpServer == nil || serverImpl == nil {
-			serverError <- fmt.Errorf("server initialization failed")
+		httpServer, serverImpl, err := server.NewServer(ctx)
+		if err != nil {
+			serverError <- fmt.Errorf("server initialization failed: %w", err)
 			return
 		}
 
@@ -159,9 +163,9 @@ func TestGracefulShutdown(t *testing.T) {
 	defer cleanup()
 
 	ctx := context.Background()
-	httpServer, serverImpl := server.NewServer(ctx)
-	if httpServer == nil || serverImpl == nil {
-		t.Fatal("Server initialization failed")
+	httpServer, serverImpl, err := server.NewServer(ctx)
+	if err != nil {
+		t.Fatalf("Server initialization failed: %v", err)
 	}
 
 	mockDB := NewMockDB()
@@ -0,0 +1,57 @@
+// Command backfill rebuilds the monthly aggregates that summaries are read
+// from, for every user or only the one given with -user. The aggregates are
+// kept up to date as transactions change, so this is only needed after data
+// was loaded around the database triggers or to repair aggregates that no
+// longer match the transactions. Writes to expenses and income wait while it
+// runs.
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/config"
+	"github.com/jorge-dev/centsible/internal/database"
+	"github.com/jorge-dev/centsible/internal/logger"
+	"github.com/jorge-dev/centsible/internal/repository"
+)
+
+func main() {
+	user := flag.String("user", "", "only rebuild the aggregates of the user with this ID")
+	flag.Parse()
+
+	logger.InitLogger(logger.LogConfig{
+		Level:      config.ParseLogLevel(config.Get().Logging.Level),
+		JSONOutput: config.Get().AppEnv == "prod",
+	})
+
+	var userID *uuid.UUID
+	if *user != "" {
+		id, err := uuid.Parse(*user)
+		if err != nil {
+			slog.Error("Invalid user ID", "user", *user, "error", err)
+			os.Exit(2)
+		}
+		userID = &id
+	}
+
+	ctx := context.Background()
+	db := database.New(ctx)
+	defer db.Close(ctx)
+
+	written, err := repository.New(db.GetPool()).RebuildMonthlyAggregates(ctx, userID)
+	if err != nil {
+		slog.Error("Error rebuilding monthly aggregates", "error", err)
+		db.Close(ctx)
+		os.Exit(1)
+	}
+
+	if userID != nil {
+		slog.Info("Rebuilt monthly aggregates", "user", userID.String(), "rows", written)
+	} else {
+		slog.Info("Rebuilt monthly aggregates", "rows", written)
+	}
+}
@@ -1,11 +1,17 @@
 package auth
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 const (
@@ -14,51 +20,161 @@ const (
 	InactivityTimeout    = 30 * time.Minute
 	claimIssuer          = "centisble-auth"
 	claimAudience        = "centisble-api"
+	refreshAudience      = "centisble-api-refresh"
+	storeTimeout         = 2 * time.Second
+	CleanupInterval      = time.Minute
+)
+
+// Refresh token and session errors
+var (
+	ErrRefreshTokenReused = fmt.Errorf("refresh token reuse detected")
+	ErrTokenFamilyRevoked = fmt.Errorf("refresh token family has been revoked")
+	ErrSessionNotFound    = fmt.Errorf("session not found")
 )
 
 type TokenPair struct {
 	AccessToken  string    `json:"access_token"`
 	RefreshToken string    `json:"refresh_token"`
 	ExpiresAt    time.Time `json:"expires_at"`
+	SessionID    string    `json:"session_id"`
 }
 
 type JWTClaims struct {
 	UserID    string `json:"user_id"`
 	RoleIDKey string `json:"role_id"`
 	Email     string `json:"email"`
+	// SessionID identifies the device session. The refresh tokens of a session
+	// form a single rotation family, so it doubles as the refresh token family.
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// DeviceInfo describes the client a session was started from
+type DeviceInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// JWTManager issues and validates tokens. It is safe for concurrent use as long
+// as its SessionStore is.
 type JWTManager struct {
-	secretKey []byte
-	blacklist map[string]time.Time // Simple in-memory blacklist
-	sessions  map[string]time.Time // Track last activity for each user
-	timeout   time.Duration        // Add this field
+	secretKey []byte       // HS256 secret, empty when only asymmetric keys are configured
+	keys      *KeySet      // RS256/EdDSA keys, nil when signing with the secret
+	store     SessionStore // Sessions, blacklist and refresh token state
+	mu        sync.RWMutex // Guards timeout and secretCutoff
+	timeout   time.Duration
+	// secretCutoff is when signing moved from the secret to keys. Zero rejects
+	// every HS256 token once keys are configured.
+	secretCutoff time.Time
 }
 
+// NewJWTManager creates a JWTManager that keeps its session state in memory
 func NewJWTManager(secretKey string) *JWTManager {
+	return NewJWTManagerWithStore(secretKey, NewMemoryStore())
+}
+
+// NewJWTManagerWithStore creates a JWTManager that keeps its session state in the given store
+func NewJWTManagerWithStore(secretKey string, store SessionStore) *JWTManager {
+	return NewJWTManagerWithKeys(secretKey, nil, store)
+}
+
+// NewJWTManagerWithKeys creates a JWTManager that signs tokens with the key set's
+// signing key. HS256 tokens are then rejected, even if secretKey is set, unless
+// SetSecretCutoff allows those issued before switching to keys. A nil key set
+// signs with secretKey.
+func NewJWTManagerWithKeys(secretKey string, keys *KeySet, store SessionStore) *JWTManager {
 	return &JWTManager{
 		secretKey: []byte(secretKey),
-		blacklist: make(map[string]time.Time),
-		sessions:  make(map[string]time.Time),
+		keys:      keys,
+		store:     store,
 		timeout:   InactivityTimeout, // Set default timeout
 	}
 }
 
-// Add this method for testing
+// JWKS returns the public keys tokens can be verified with. It is empty when
+// tokens are signed with the shared secret, which must never be published.
+func (m *JWTManager) JWKS() JWKS {
+	if m.keys == nil {
+		return JWKS{Keys: []JWK{}}
+	}
+	return m.keys.JWKS()
+}
+
+// SetTimeout overrides the inactivity timeout, mainly for testing
 func (m *JWTManager) SetTimeout(duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.timeout = duration
 }
 
+// SetSecretCutoff lets a manager with keys keep accepting HS256 tokens signed
+// with the secret, as long as they were issued before cutoff. This only lasts
+// until cutoff plus RefreshTokenDuration, when every token issued before it has
+// expired, so a leaked secret cannot mint tokens past then.
+func (m *JWTManager) SetSecretCutoff(cutoff time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secretCutoff = cutoff
+}
+
+// acceptsSecretToken reports whether a token signed with the secret may be
+// verified with it
+func (m *JWTManager) acceptsSecretToken(claims *JWTClaims) bool {
+	if len(m.secretKey) == 0 {
+		return false
+	}
+	if m.keys == nil {
+		return true
+	}
+	m.mu.RLock()
+	cutoff := m.secretCutoff
+	m.mu.RUnlock()
+	return !cutoff.IsZero() &&
+		time.Now().Before(cutoff.Add(RefreshTokenDuration)) &&
+		claims.IssuedAt != nil && claims.IssuedAt.Before(cutoff)
+}
+
+func (m *JWTManager) inactivityTimeout() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.timeout
+}
+
+// GenerateTokenPair starts a new session without device details and issues its first token pair
 func (m *JWTManager) GenerateTokenPair(userID, email, roleID string) (*TokenPair, error) {
+	return m.StartSession(userID, email, roleID, DeviceInfo{})
+}
+
+// StartSession starts a new session for the given device and issues its first token pair
+func (m *JWTManager) StartSession(userID, email, roleID string, device DeviceInfo) (*TokenPair, error) {
+	now := time.Now()
+	session := Session{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		UserAgent: device.UserAgent,
+		IPAddress: device.IPAddress,
+		CreatedAt: now,
+		LastSeen:  now,
+	}
+
+	ctx, cancel := storeContext()
+	defer cancel()
+	if err := m.store.SaveSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("error starting session: %w", err)
+	}
+
+	return m.generateTokenPair(userID, email, roleID, session.ID)
+}
+
+func (m *JWTManager) generateTokenPair(userID, email, roleID, sessionID string) (*TokenPair, error) {
 	// Generate access token
-	accessToken, err := m.generateAccessToken(userID, email, roleID)
+	accessToken, err := m.generateAccessToken(userID, email, roleID, sessionID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Generate refresh token with longer expiration
-	refreshToken, err := m.generateRefreshToken(userID, email, roleID)
+	refreshToken, err := m.generateRefreshToken(userID, email, roleID, sessionID)
 	if err != nil {
 		slog.Debug("Error generating refresh token", "error", err)
 		return nil, err
@@ -67,21 +183,28 @@ func (m *JWTManager) GenerateTokenPair(userID, email, roleID string) (*TokenPair
 	expiresAt := time.Now().Add(AccessTokenDuration)
 
 	// Update session activity
-	m.sessions[userID] = time.Now()
+	ctx, cancel := storeContext()
+	defer cancel()
+	if err := m.store.TouchSession(ctx, sessionID, time.Now()); err != nil {
+		return nil, fmt.Errorf("error updating session: %w", err)
+	}
 
 	return &TokenPair{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		ExpiresAt:    expiresAt,
+		SessionID:    sessionID,
 	}, nil
 }
 
-func (m *JWTManager) generateAccessToken(userID, email, roleID string) (string, error) {
+func (m *JWTManager) generateAccessToken(userID, email, roleID, sessionID string) (string, error) {
 	claims := JWTClaims{
 		UserID:    userID,
 		Email:     email,
 		RoleIDKey: roleID,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(), // Keeps tokens issued in the same second distinct so revoking one doesn't revoke the other
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    claimIssuer,
@@ -89,51 +212,173 @@ func (m *JWTManager) generateAccessToken(userID, email, roleID string) (string,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(m.secretKey)
+	return m.sign(claims)
 }
 
-func (m *JWTManager) generateRefreshToken(userID, email, roleID string) (string, error) {
+func (m *JWTManager) generateRefreshToken(userID, email, roleID, sessionID string) (string, error) {
+	expiresAt := time.Now().Add(RefreshTokenDuration)
 	claims := JWTClaims{
 		UserID:    userID,
 		Email:     email,
 		RoleIDKey: roleID,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(RefreshTokenDuration)),
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    claimIssuer,
-			Audience:  []string{"centisble-api-refresh"},
+			Audience:  []string{refreshAudience},
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(m.secretKey)
+	signed, err := m.sign(claims)
+	if err != nil {
+		return "", err
+	}
+
+	// Track the refresh token so it can only be exchanged once
+	ctx, cancel := storeContext()
+	defer cancel()
+	if err := m.store.SaveRefreshToken(ctx, RefreshTokenRecord{
+		ID:        claims.ID,
+		FamilyID:  sessionID,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return "", fmt.Errorf("error saving refresh token: %w", err)
+	}
+	return signed, nil
 }
 
+// RefreshToken exchanges a refresh token for a new token pair. Refresh tokens are
+// single use: presenting one that was already rotated revokes its whole family.
 func (m *JWTManager) RefreshToken(refreshToken string) (*TokenPair, error) {
-	claims, err := m.ValidateToken(refreshToken)
+	claims, err := m.parseToken(refreshToken)
 	if err != nil {
 		return nil, fmt.Errorf("invalid refresh token: %w", err)
 	}
 
 	// Verify this is a refresh token
-	if !contains(claims.Audience, "centisble-api-refresh") {
+	if !contains(claims.Audience, refreshAudience) || claims.ID == "" || claims.SessionID == "" {
 		return nil, fmt.Errorf("invalid token type for refresh")
 	}
 
+	ctx, cancel := storeContext()
+	defer cancel()
+
+	revoked, err := m.store.IsTokenFamilyRevoked(ctx, claims.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to verify refresh token: %w", err)
+	}
+	if revoked {
+		return nil, ErrTokenFamilyRevoked
+	}
+
+	familyID, alreadyUsed, err := m.store.UseRefreshToken(ctx, claims.ID)
+	if errors.Is(err, ErrRefreshTokenNotFound) || (err == nil && familyID != claims.SessionID) {
+		return nil, fmt.Errorf("invalid refresh token: unknown token")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to verify refresh token: %w", err)
+	}
+
+	// A rotated token being presented again means it leaked, so kill every token in the family
+	// along with the session it belongs to
+	if alreadyUsed {
+		if err := m.revokeSession(ctx, claims.SessionID); err != nil {
+			return nil, err
+		}
+		slog.Warn("Refresh token reuse detected", "user_id", claims.UserID, "session_id", claims.SessionID)
+		return nil, ErrRefreshTokenReused
+	}
+
 	// Check for session timeout
-	lastActivity, exists := m.sessions[claims.UserID]
-	if !exists || time.Since(lastActivity) > InactivityTimeout {
-		delete(m.sessions, claims.UserID) // Clear the session
+	session, exists, err := m.store.GetSession(ctx, claims.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to verify session: %w", err)
+	}
+	if !exists || session.UserID != claims.UserID {
+		return nil, fmt.Errorf("session expired due to inactivity")
+	}
+	if time.Since(session.LastSeen) > InactivityTimeout {
+		m.store.DeleteSession(ctx, claims.SessionID) // Clear the session
 		return nil, fmt.Errorf("session expired due to inactivity")
 	}
 
-	// Generate new token pair
-	return m.GenerateTokenPair(claims.UserID, claims.Email, claims.RoleIDKey)
+	// Generate new token pair within the same session
+	return m.generateTokenPair(claims.UserID, claims.Email, claims.RoleIDKey, claims.SessionID)
 }
 
+// UpdateActivity keeps the session of tokens issued by GenerateToken alive. Those
+// tokens carry no session ID and are tracked under the user's ID instead.
 func (m *JWTManager) UpdateActivity(userID string) {
-	m.sessions[userID] = time.Now()
+	ctx, cancel := storeContext()
+	defer cancel()
+	now := time.Now()
+	if err := m.store.SaveSession(ctx, Session{
+		ID:        userID,
+		UserID:    userID,
+		CreatedAt: now,
+		LastSeen:  now,
+	}); err != nil {
+		slog.Error("Error updating session activity", "user_id", userID, "error", err)
+	}
+}
+
+// ListSessions returns the user's active sessions, most recently seen first
+func (m *JWTManager) ListSessions(userID string) ([]Session, error) {
+	ctx, cancel := storeContext()
+	defer cancel()
+	sessions, err := m.store.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs a single session out. Its access tokens stop validating and
+// its refresh tokens can no longer be exchanged. Sessions owned by other users are
+// reported as ErrSessionNotFound.
+func (m *JWTManager) RevokeSession(userID, sessionID string) error {
+	ctx, cancel := storeContext()
+	defer cancel()
+
+	session, exists, err := m.store.GetSession(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("error getting session: %w", err)
+	}
+	if !exists || session.UserID != userID {
+		return ErrSessionNotFound
+	}
+	return m.revokeSession(ctx, sessionID)
+}
+
+// RevokeAllSessions signs the user out on every device and returns how many sessions were revoked
+func (m *JWTManager) RevokeAllSessions(userID string) (int, error) {
+	ctx, cancel := storeContext()
+	defer cancel()
+
+	sessions, err := m.store.ListSessions(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("error listing sessions: %w", err)
+	}
+	for _, session := range sessions {
+		if err := m.revokeSession(ctx, session.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(sessions), nil
+}
+
+// revokeSession deletes a session and revokes its refresh token family
+func (m *JWTManager) revokeSession(ctx context.Context, sessionID string) error {
+	if err := m.store.RevokeTokenFamily(ctx, sessionID, time.Now().Add(RefreshTokenDuration)); err != nil {
+		return fmt.Errorf("error revoking refresh token family: %w", err)
+	}
+	if err := m.store.DeleteSession(ctx, sessionID); err != nil {
+		return fmt.Errorf("error deleting session: %w", err)
+	}
+	return nil
 }
 
 func (m *JWTManager) GenerateToken(userID, email, roleID string) (string, error) {
@@ -149,8 +394,7 @@ func (m *JWTManager) GenerateToken(userID, email, roleID string) (string, error)
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(m.secretKey)
+	return m.sign(claims)
 }
 
 func (m *JWTManager) InvalidateToken(tokenString string) error {
@@ -160,25 +404,86 @@ func (m *JWTManager) InvalidateToken(tokenString string) error {
 	}
 
 	// Add to blacklist until expiration
-	m.blacklist[tokenString] = claims.ExpiresAt.Time
+	ctx, cancel := storeContext()
+	defer cancel()
+	if err := m.store.RevokeToken(ctx, hashToken(tokenString), claims.ExpiresAt.Time); err != nil {
+		return fmt.Errorf("error invalidating token: %w", err)
+	}
 	return nil
 }
 
 func (m *JWTManager) ValidateToken(tokenString string) (*JWTClaims, error) {
+	ctx, cancel := storeContext()
+	defer cancel()
+
 	// Check if token is blacklisted
-	if _, blacklisted := m.blacklist[tokenString]; blacklisted {
+	blacklisted, err := m.store.IsTokenRevoked(ctx, hashToken(tokenString))
+	if err != nil {
+		return nil, fmt.Errorf("unable to verify token: %w", err)
+	}
+	if blacklisted {
 		return nil, fmt.Errorf("token has been invalidated")
 	}
 
-	// Clean up expired blacklisted tokens and inactive sessions
-	m.cleanupBlacklist()
-	m.cleanupSessions()
+	claims, err := m.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if !contains(claims.Audience, claimAudience) {
+		return nil, fmt.Errorf("invalid token audience")
+	}
+
+	// Check for session timeout if it's an access token
+	if contains(claims.Audience, claimAudience) {
+		sessionID := sessionKey(claims)
+		session, exists, err := m.store.GetSession(ctx, sessionID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to verify session: %w", err)
+		}
+		// Sessions that were revoked or swept are gone, which also ends the session
+		if !exists || session.UserID != claims.UserID {
+			return nil, fmt.Errorf("session expired due to inactivity")
+		}
+		if time.Since(session.LastSeen) > m.inactivityTimeout() {
+			m.store.DeleteSession(ctx, sessionID) // Clear the session
+			return nil, fmt.Errorf("session expired due to inactivity")
+		}
+		// Update last activity
+		if err := m.store.TouchSession(ctx, sessionID, time.Now()); err != nil {
+			slog.Error("Error updating session activity", "session_id", sessionID, "error", err)
+		}
+	}
+
+	return claims, nil
+}
+
+// sign signs claims with the active asymmetric key, or with the shared secret if none is configured
+func (m *JWTManager) sign(claims JWTClaims) (string, error) {
+	if m.keys == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString(m.secretKey)
+	}
+
+	token := jwt.NewWithClaims(m.keys.signing.Method, claims)
+	token.Header["kid"] = m.keys.signing.ID
+	return token.SignedString(m.keys.signing.privateKey)
+}
 
+// parseToken verifies the signature, expiry and issuer of a token without checking its audience
+func (m *JWTManager) parseToken(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			if claims, ok := token.Claims.(*JWTClaims); !ok || !m.acceptsSecretToken(claims) {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return m.secretKey, nil
+		}
+		if m.keys == nil || token.Method == nil {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return m.secretKey, nil
+		kid, _ := token.Header["kid"].(string)
+		return m.keys.verificationKey(kid, token.Method)
 	})
 
 	if err != nil {
@@ -195,40 +500,58 @@ func (m *JWTManager) ValidateToken(tokenString string) (*JWTClaims, error) {
 		return nil, fmt.Errorf("invalid token issuer")
 	}
 
-	if !contains(claims.Audience, claimAudience) {
-		return nil, fmt.Errorf("invalid token audience")
-	}
+	return claims, nil
+}
 
-	// Check for session timeout if it's an access token
-	if contains(claims.Audience, claimAudience) {
-		lastActivity, exists := m.sessions[claims.UserID]
-		if !exists || time.Since(lastActivity) > m.timeout { // Use m.timeout instead of InactivityTimeout
-			delete(m.sessions, claims.UserID) // Clear the session
-			return nil, fmt.Errorf("session expired due to inactivity")
+// StartCleanup runs a background sweeper that removes expired blacklist entries,
+// refresh tokens and inactive sessions every interval until ctx is cancelled.
+// The returned channel is closed once the sweeper has stopped.
+func (m *JWTManager) StartCleanup(ctx context.Context, interval time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cleanupCtx, cancel := storeContext()
+				m.cleanup(cleanupCtx)
+				cancel()
+			}
 		}
-		// Update last activity
-		m.UpdateActivity(claims.UserID)
-	}
-
-	return claims, nil
+	}()
+	return done
 }
 
-func (m *JWTManager) cleanupBlacklist() {
+// cleanup removes expired blacklist entries and inactive sessions
+func (m *JWTManager) cleanup(ctx context.Context) {
 	now := time.Now()
-	for token, expiry := range m.blacklist {
-		if now.After(expiry) {
-			delete(m.blacklist, token)
-		}
+	if err := m.store.Cleanup(ctx, now, now.Add(-m.inactivityTimeout())); err != nil {
+		slog.Error("Error cleaning up sessions", "error", err)
 	}
 }
 
-func (m *JWTManager) cleanupSessions() {
-	now := time.Now()
-	for userID, lastActivity := range m.sessions {
-		if now.Sub(lastActivity) > InactivityTimeout {
-			delete(m.sessions, userID)
-		}
+// sessionKey returns the session a token is tracked under. Tokens from GenerateToken
+// carry no session ID and fall back to the user's ID.
+func sessionKey(claims *JWTClaims) string {
+	if claims.SessionID != "" {
+		return claims.SessionID
 	}
+	return claims.UserID
+}
+
+// hashToken returns the hex encoded SHA-256 of a token so raw tokens are never stored
+func hashToken(tokenString string) string {
+	sum := sha256.Sum256([]byte(tokenString))
+	return hex.EncodeToString(sum[:])
+}
+
+func storeContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), storeTimeout)
 }
 
 func contains(slice []string, item string) bool {
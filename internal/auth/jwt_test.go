@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -16,8 +17,7 @@ func TestJWTManagerGenerateToken(t *testing.T) {
 	manager := NewJWTManager(secretKey)
 	// Add cleanup
 	defer func() {
-		manager.sessions = make(map[string]time.Time)
-		manager.blacklist = make(map[string]time.Time)
+		manager.store = NewMemoryStore()
 	}()
 
 	tests := []struct {
@@ -56,8 +56,7 @@ func TestJWTManagerValidateToken(t *testing.T) {
 	manager := NewJWTManager(secretKey)
 	// Add cleanup after tests
 	defer func() {
-		manager.sessions = make(map[string]time.Time)
-		manager.blacklist = make(map[string]time.Time)
+		manager.store = NewMemoryStore()
 	}()
 
 	tests := []struct {
@@ -162,8 +161,7 @@ func TestJWTManager(t *testing.T) {
 	manager := NewJWTManager(secretKey)
 	// Add cleanup
 	defer func() {
-		manager.sessions = make(map[string]time.Time)
-		manager.blacklist = make(map[string]time.Time)
+		manager.store = NewMemoryStore()
 	}()
 
 	t.Run("GenerateToken", func(t *testing.T) {
@@ -236,7 +234,8 @@ func TestJWTManager(t *testing.T) {
 	})
 
 	t.Run("BlacklistCleanup", func(t *testing.T) {
-		manager := NewJWTManager(secretKey)
+		store := NewMemoryStore()
+		manager := NewJWTManagerWithStore(secretKey, store)
 
 		// Create an expired token
 		expiredClaims := &JWTClaims{
@@ -246,14 +245,14 @@ func TestJWTManager(t *testing.T) {
 		}
 
 		// Add expired token to blacklist
-		expiredToken := "expired-token"
-		manager.blacklist[expiredToken] = expiredClaims.ExpiresAt.Time
+		expiredToken := hashToken("expired-token")
+		store.blacklist[expiredToken] = expiredClaims.ExpiresAt.Time
 
 		// Trigger cleanup by validating any token
-		manager.cleanupBlacklist()
+		manager.cleanup(context.Background())
 
 		// Check if expired token was removed
-		_, exists := manager.blacklist[expiredToken]
+		_, exists := store.blacklist[expiredToken]
 		assert.False(t, exists, "Expired token should be removed from blacklist")
 	})
 
@@ -303,3 +302,211 @@ func TestJWTManager(t *testing.T) {
 		assert.Contains(t, err.Error(), "invalid token audience")
 	})
 }
+
+func TestJWTManagerRefreshToken(t *testing.T) {
+	t.Run("Rotates refresh token", func(t *testing.T) {
+		manager := NewJWTManager(secretKey)
+		pair, err := manager.GenerateTokenPair("123", testEmail, "user")
+		assert.NoError(t, err)
+
+		newPair, err := manager.RefreshToken(pair.RefreshToken)
+		assert.NoError(t, err)
+		assert.NotEqual(t, pair.RefreshToken, newPair.RefreshToken)
+
+		// The new access token is usable
+		claims, err := manager.ValidateToken(newPair.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, "123", claims.UserID)
+	})
+
+	t.Run("Reuse revokes family", func(t *testing.T) {
+		manager := NewJWTManager(secretKey)
+		pair, err := manager.GenerateTokenPair("123", testEmail, "user")
+		assert.NoError(t, err)
+
+		newPair, err := manager.RefreshToken(pair.RefreshToken)
+		assert.NoError(t, err)
+
+		_, err = manager.RefreshToken(pair.RefreshToken)
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+		_, err = manager.RefreshToken(newPair.RefreshToken)
+		assert.ErrorIs(t, err, ErrTokenFamilyRevoked)
+	})
+
+	t.Run("Other families are unaffected by reuse", func(t *testing.T) {
+		manager := NewJWTManager(secretKey)
+		pairA, err := manager.GenerateTokenPair("123", testEmail, "user")
+		assert.NoError(t, err)
+		pairB, err := manager.GenerateTokenPair("123", testEmail, "user")
+		assert.NoError(t, err)
+
+		_, err = manager.RefreshToken(pairA.RefreshToken)
+		assert.NoError(t, err)
+		_, err = manager.RefreshToken(pairA.RefreshToken)
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+		_, err = manager.RefreshToken(pairB.RefreshToken)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Rejects access token", func(t *testing.T) {
+		manager := NewJWTManager(secretKey)
+		pair, err := manager.GenerateTokenPair("123", testEmail, "user")
+		assert.NoError(t, err)
+
+		_, err = manager.RefreshToken(pair.AccessToken)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid token type for refresh")
+	})
+
+	t.Run("Refresh token is not an access token", func(t *testing.T) {
+		manager := NewJWTManager(secretKey)
+		pair, err := manager.GenerateTokenPair("123", testEmail, "user")
+		assert.NoError(t, err)
+
+		_, err = manager.ValidateToken(pair.RefreshToken)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid token audience")
+	})
+}
+
+func TestJWTManagerSessions(t *testing.T) {
+	t.Run("Each login gets its own session", func(t *testing.T) {
+		manager := NewJWTManager(secretKey)
+		laptop, err := manager.StartSession("123", testEmail, "user", DeviceInfo{UserAgent: "laptop", IPAddress: "10.0.0.1"})
+		assert.NoError(t, err)
+		phone, err := manager.StartSession("123", testEmail, "user", DeviceInfo{UserAgent: "phone", IPAddress: "10.0.0.2"})
+		assert.NoError(t, err)
+		assert.NotEqual(t, laptop.SessionID, phone.SessionID)
+
+		claims, err := manager.ValidateToken(laptop.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, laptop.SessionID, claims.SessionID)
+		assert.NotEmpty(t, claims.ID)
+
+		sessions, err := manager.ListSessions("123")
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 2)
+		for _, session := range sessions {
+			assert.Equal(t, "123", session.UserID)
+			assert.NotEmpty(t, session.UserAgent)
+			assert.NotEmpty(t, session.IPAddress)
+		}
+	})
+
+	t.Run("Refresh keeps the session", func(t *testing.T) {
+		manager := NewJWTManager(secretKey)
+		pair, err := manager.GenerateTokenPair("123", testEmail, "user")
+		assert.NoError(t, err)
+
+		newPair, err := manager.RefreshToken(pair.RefreshToken)
+		assert.NoError(t, err)
+		assert.Equal(t, pair.SessionID, newPair.SessionID)
+
+		sessions, err := manager.ListSessions("123")
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+	})
+
+	t.Run("Activity on one device does not keep another alive", func(t *testing.T) {
+		store := NewMemoryStore()
+		manager := NewJWTManagerWithStore(secretKey, store)
+		laptop, err := manager.GenerateTokenPair("123", testEmail, "user")
+		assert.NoError(t, err)
+		phone, err := manager.GenerateTokenPair("123", testEmail, "user")
+		assert.NoError(t, err)
+
+		// The laptop has been idle for longer than the timeout
+		store.sessions[laptop.SessionID] = Session{
+			ID:       laptop.SessionID,
+			UserID:   "123",
+			LastSeen: time.Now().Add(-2 * InactivityTimeout),
+		}
+
+		_, err = manager.ValidateToken(phone.AccessToken)
+		assert.NoError(t, err)
+
+		_, err = manager.ValidateToken(laptop.AccessToken)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "inactivity")
+	})
+
+	t.Run("Revoke a single session", func(t *testing.T) {
+		manager := NewJWTManager(secretKey)
+		laptop, err := manager.GenerateTokenPair("123", testEmail, "user")
+		assert.NoError(t, err)
+		phone, err := manager.GenerateTokenPair("123", testEmail, "user")
+		assert.NoError(t, err)
+
+		assert.ErrorIs(t, manager.RevokeSession("456", phone.SessionID), ErrSessionNotFound)
+		assert.NoError(t, manager.RevokeSession("123", phone.SessionID))
+		assert.ErrorIs(t, manager.RevokeSession("123", phone.SessionID), ErrSessionNotFound)
+
+		_, err = manager.ValidateToken(phone.AccessToken)
+		assert.Error(t, err)
+		_, err = manager.RefreshToken(phone.RefreshToken)
+		assert.ErrorIs(t, err, ErrTokenFamilyRevoked)
+
+		_, err = manager.ValidateToken(laptop.AccessToken)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Revoke all sessions", func(t *testing.T) {
+		manager := NewJWTManager(secretKey)
+		laptop, err := manager.GenerateTokenPair("123", testEmail, "user")
+		assert.NoError(t, err)
+		phone, err := manager.GenerateTokenPair("123", testEmail, "user")
+		assert.NoError(t, err)
+		other, err := manager.GenerateTokenPair("456", testEmail, "user")
+		assert.NoError(t, err)
+
+		revoked, err := manager.RevokeAllSessions("123")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, revoked)
+
+		_, err = manager.ValidateToken(laptop.AccessToken)
+		assert.Error(t, err)
+		_, err = manager.ValidateToken(phone.AccessToken)
+		assert.Error(t, err)
+		_, err = manager.ValidateToken(other.AccessToken)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Reuse ends the session", func(t *testing.T) {
+		manager := NewJWTManager(secretKey)
+		pair, err := manager.GenerateTokenPair("123", testEmail, "user")
+		assert.NoError(t, err)
+
+		newPair, err := manager.RefreshToken(pair.RefreshToken)
+		assert.NoError(t, err)
+		_, err = manager.RefreshToken(pair.RefreshToken)
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+		_, err = manager.ValidateToken(newPair.AccessToken)
+		assert.Error(t, err)
+	})
+}
+
+func TestJWTManagerStartCleanup(t *testing.T) {
+	store := NewMemoryStore()
+	manager := NewJWTManagerWithStore(secretKey, store)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	expiredToken := hashToken("expired-token")
+	store.RevokeToken(ctx, expiredToken, time.Now().Add(-time.Hour))
+
+	done := manager.StartCleanup(ctx, 5*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		revoked, _ := store.IsTokenRevoked(context.Background(), expiredToken)
+		return !revoked
+	}, time.Second, 5*time.Millisecond, "Sweeper should remove expired blacklist entries")
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Sweeper did not stop after context cancellation")
+	}
+}
@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSAKeyBits is the smallest RSA modulus accepted for signing or verification
+const minRSAKeyBits = 2048
+
+// Key is an asymmetric key used to sign or verify tokens. Keys loaded from a
+// public key can only verify.
+type Key struct {
+	ID         string // kid header, the RFC 7638 thumbprint of the public key
+	Method     jwt.SigningMethod
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+}
+
+// KeySet holds the key new tokens are signed with and every key tokens are still
+// accepted from. During a rotation the previous key stays in the set until the
+// tokens it signed have expired.
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+	order   []string // Keeps the JWKS output stable
+}
+
+// JWK is a single public key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the document served from /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKeySet creates a key set that signs with signing and also verifies tokens signed by any of previous
+func NewKeySet(signing *Key, previous ...*Key) (*KeySet, error) {
+	if signing == nil || signing.privateKey == nil {
+		return nil, fmt.Errorf("signing key must include a private key")
+	}
+
+	set := &KeySet{
+		signing: signing,
+		keys:    make(map[string]*Key),
+	}
+	for _, key := range append([]*Key{signing}, previous...) {
+		if _, exists := set.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key ID %q", key.ID)
+		}
+		set.keys[key.ID] = key
+		set.order = append(set.order, key.ID)
+	}
+	return set, nil
+}
+
+// LoadKeySet reads the signing key and any keys still accepted for verification from PEM files
+func LoadKeySet(signingKeyFile string, verificationKeyFiles []string) (*KeySet, error) {
+	data, err := os.ReadFile(signingKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading signing key: %w", err)
+	}
+	signing, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing signing key %s: %w", signingKeyFile, err)
+	}
+
+	var previous []*Key
+	for _, file := range verificationKeyFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading verification key: %w", err)
+		}
+		key, err := ParsePublicKeyPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing verification key %s: %w", file, err)
+		}
+		previous = append(previous, key)
+	}
+
+	return NewKeySet(signing, previous...)
+}
+
+// ParsePrivateKeyPEM parses a PKCS#8 or PKCS#1 encoded RSA or Ed25519 private key
+func ParsePrivateKeyPEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", parsed)
+	}
+	key, err := newKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.privateKey = signer
+	return key, nil
+}
+
+// ParsePublicKeyPEM parses a PKIX or PKCS#1 encoded RSA or Ed25519 public key. A private
+// key is accepted too, in which case only its public half is kept.
+func ParsePublicKeyPEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PRIVATE KEY", "RSA PRIVATE KEY":
+		key, err := ParsePrivateKeyPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		key.privateKey = nil
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newKey(parsed)
+}
+
+// newKey picks the signing method for a public key and derives its key ID
+func newKey(publicKey crypto.PublicKey) (*Key, error) {
+	key := &Key{publicKey: publicKey}
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+		}
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+
+	jwk := key.jwk()
+	id, err := thumbprint(jwk)
+	if err != nil {
+		return nil, err
+	}
+	key.ID = id
+	return key, nil
+}
+
+// jwk returns the public half of the key in JWK format
+func (k *Key) jwk() JWK {
+	jwk := JWK{
+		Use: "sig",
+		Alg: k.Method.Alg(),
+		Kid: k.ID,
+	}
+	switch pub := k.publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+	return jwk
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint, which only covers the required
+// members of the key in lexicographic order
+func thumbprint(jwk JWK) (string, error) {
+	var members any
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	default:
+		return "", fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+
+	encoded, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// verificationKey returns the public key for a kid, checking it was used with the expected algorithm
+func (s *KeySet) verificationKey(kid string, method jwt.SigningMethod) (crypto.PublicKey, error) {
+	key, exists := s.keys[kid]
+	if !exists {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if key.Method.Alg() != method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", method.Alg())
+	}
+	return key.publicKey, nil
+}
+
+// JWKS returns every verification key in the set
+func (s *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(s.order))}
+	for _, kid := range s.order {
+		jwks.Keys = append(jwks.Keys, s.keys[kid].jwk())
+	}
+	return jwks
+}
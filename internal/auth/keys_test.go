@@ -0,0 +1,363 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func rsaPrivateKeyPEM(t *testing.T, bits int) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func ed25519PrivateKeyPEM(t *testing.T) []byte {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func publicKeyPEM(t *testing.T, privatePEM []byte) []byte {
+	t.Helper()
+	key, err := ParsePrivateKeyPEM(privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func newTestKeySet(t *testing.T, signingPEM []byte, previousPEMs ...[]byte) *KeySet {
+	t.Helper()
+	signing, err := ParsePrivateKeyPEM(signingPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var previous []*Key
+	for _, data := range previousPEMs {
+		key, err := ParsePublicKeyPEM(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous = append(previous, key)
+	}
+	keys, err := NewKeySet(signing, previous...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		wantMethod string
+		wantErr    bool
+	}{
+		{
+			name:       "RSA PKCS#1 private key",
+			data:       rsaPrivateKeyPEM(t, 2048),
+			wantMethod: "RS256",
+		},
+		{
+			name:       "Ed25519 PKCS#8 private key",
+			data:       ed25519PrivateKeyPEM(t),
+			wantMethod: "EdDSA",
+		},
+		{
+			name:    "RSA key too small",
+			data:    rsaPrivateKeyPEM(t, 1024),
+			wantErr: true,
+		},
+		{
+			name:    "Not PEM",
+			data:    []byte("not a key"),
+			wantErr: true,
+		},
+		{
+			name:    "Certificate request",
+			data:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: []byte{0}}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePrivateKeyPEM(tt.data)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMethod, key.Method.Alg())
+			assert.NotEmpty(t, key.ID)
+
+			// The public half gets the same key ID, so a key keeps its kid once retired
+			public, err := ParsePublicKeyPEM(publicKeyPEM(t, tt.data))
+			assert.NoError(t, err)
+			assert.Equal(t, key.ID, public.ID)
+			assert.Nil(t, public.privateKey)
+		})
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	// Example key and thumbprint from RFC 7638 section 3.1
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := newKey(&rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537})
+	assert.NoError(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", key.ID)
+}
+
+func TestJWTManagerAsymmetricSigning(t *testing.T) {
+	tests := []struct {
+		name       string
+		signingPEM []byte
+	}{
+		{"RS256", rsaPrivateKeyPEM(t, 2048)},
+		{"EdDSA", ed25519PrivateKeyPEM(t)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := newTestKeySet(t, tt.signingPEM)
+			manager := NewJWTManagerWithKeys("", keys, NewMemoryStore())
+
+			pair, err := manager.GenerateTokenPair("123", testEmail, "user")
+			assert.NoError(t, err)
+
+			token, _, err := jwt.NewParser().ParseUnverified(pair.AccessToken, &JWTClaims{})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.name, token.Header["alg"])
+			assert.Equal(t, keys.signing.ID, token.Header["kid"])
+
+			claims, err := manager.ValidateToken(pair.AccessToken)
+			assert.NoError(t, err)
+			assert.Equal(t, "123", claims.UserID)
+
+			_, err = manager.RefreshToken(pair.RefreshToken)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestJWTManagerKeyRotation(t *testing.T) {
+	oldPEM := ed25519PrivateKeyPEM(t)
+	newPEM := rsaPrivateKeyPEM(t, 2048)
+	store := NewMemoryStore()
+
+	before := NewJWTManagerWithKeys("", newTestKeySet(t, oldPEM), store)
+	pair, err := before.GenerateTokenPair("123", testEmail, "user")
+	assert.NoError(t, err)
+
+	// After the rotation the old key only verifies
+	after := NewJWTManagerWithKeys("", newTestKeySet(t, newPEM, publicKeyPEM(t, oldPEM)), store)
+	_, err = after.ValidateToken(pair.AccessToken)
+	assert.NoError(t, err)
+
+	newPair, err := after.RefreshToken(pair.RefreshToken)
+	assert.NoError(t, err)
+	token, _, err := jwt.NewParser().ParseUnverified(newPair.AccessToken, &JWTClaims{})
+	assert.NoError(t, err)
+	assert.Equal(t, "RS256", token.Header["alg"])
+
+	// Once the old key is dropped its tokens are rejected
+	dropped := NewJWTManagerWithKeys("", newTestKeySet(t, newPEM), store)
+	_, err = dropped.ValidateToken(pair.AccessToken)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown signing key")
+
+	_, err = dropped.ValidateToken(newPair.AccessToken)
+	assert.NoError(t, err)
+}
+
+func TestJWTManagerMigrationFromSecret(t *testing.T) {
+	store := NewMemoryStore()
+	hmacManager := NewJWTManagerWithStore(secretKey, store)
+	pair, err := hmacManager.GenerateTokenPair("123", testEmail, "user")
+	assert.NoError(t, err)
+
+	keys := newTestKeySet(t, ed25519PrivateKeyPEM(t))
+
+	// Once keys are configured, the secret alone no longer validates anything
+	keysOnly := NewJWTManagerWithKeys(secretKey, keys, store)
+	_, err = keysOnly.ValidateToken(pair.AccessToken)
+	assert.Error(t, err)
+
+	// With a cutoff, tokens issued before it run out naturally
+	migrating := NewJWTManagerWithKeys(secretKey, keys, store)
+	migrating.SetSecretCutoff(time.Now().Add(time.Minute))
+	_, err = migrating.ValidateToken(pair.AccessToken)
+	assert.NoError(t, err)
+
+	// A token freshly signed with the secret after the cutoff is rejected
+	migrating.SetSecretCutoff(time.Now().Add(-time.Minute))
+	fresh, err := hmacManager.GenerateTokenPair("123", testEmail, "user")
+	assert.NoError(t, err)
+	_, err = migrating.ValidateToken(fresh.AccessToken)
+	assert.Error(t, err)
+
+	// So is one claiming to be older, once every token from before the cutoff
+	// has expired
+	migrating.SetSecretCutoff(time.Now().Add(-RefreshTokenDuration - time.Minute))
+	backdated, err := hmacManager.sign(JWTClaims{
+		UserID:    "123",
+		SessionID: fresh.SessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now().Add(-RefreshTokenDuration - time.Hour)),
+			Issuer:    claimIssuer,
+			Audience:  []string{claimAudience},
+		},
+	})
+	assert.NoError(t, err)
+	_, err = migrating.ValidateToken(backdated)
+	assert.Error(t, err)
+	_, err = hmacManager.ValidateToken(backdated)
+	assert.NoError(t, err, "the backdated token is otherwise valid")
+}
+
+func TestJWTManagerRejectsAlgorithmMismatch(t *testing.T) {
+	keys := newTestKeySet(t, rsaPrivateKeyPEM(t, 2048))
+	manager := NewJWTManagerWithKeys("", keys, NewMemoryStore())
+	manager.UpdateActivity("123")
+
+	// A token claiming the RS256 key's kid but signed with a different algorithm
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, JWTClaims{
+		UserID: "123",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:   claimIssuer,
+			Audience: []string{claimAudience},
+		},
+	})
+	token.Header["kid"] = keys.signing.ID
+	signed, err := token.SignedString(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = manager.ValidateToken(signed)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected signing method")
+
+	// An HS256 token is rejected when no secret is configured
+	hmacToken, err := NewJWTManager(secretKey).GenerateToken("123", testEmail, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = manager.ValidateToken(hmacToken)
+	assert.Error(t, err)
+}
+
+func TestJWKS(t *testing.T) {
+	t.Run("Secret is never published", func(t *testing.T) {
+		jwks := NewJWTManager(secretKey).JWKS()
+		assert.Empty(t, jwks.Keys)
+	})
+
+	t.Run("Publishes every verification key", func(t *testing.T) {
+		oldPEM := rsaPrivateKeyPEM(t, 2048)
+		keys := newTestKeySet(t, ed25519PrivateKeyPEM(t), publicKeyPEM(t, oldPEM))
+		jwks := NewJWTManagerWithKeys("", keys, NewMemoryStore()).JWKS()
+
+		if !assert.Len(t, jwks.Keys, 2) {
+			return
+		}
+		assert.Equal(t, JWK{
+			Kty: "OKP",
+			Use: "sig",
+			Alg: "EdDSA",
+			Kid: keys.signing.ID,
+			Crv: "Ed25519",
+			X:   jwks.Keys[0].X,
+		}, jwks.Keys[0])
+		assert.NotEmpty(t, jwks.Keys[0].X)
+
+		assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+		assert.Equal(t, "RS256", jwks.Keys[1].Alg)
+		assert.Equal(t, "AQAB", jwks.Keys[1].E)
+		assert.NotEmpty(t, jwks.Keys[1].N)
+		assert.Empty(t, jwks.Keys[1].X)
+	})
+
+	t.Run("Duplicate keys are rejected", func(t *testing.T) {
+		data := ed25519PrivateKeyPEM(t)
+		signing, err := ParsePrivateKeyPEM(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		public, err := ParsePublicKeyPEM(publicKeyPEM(t, data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = NewKeySet(signing, public)
+		assert.Error(t, err)
+	})
+
+	t.Run("Signing key needs a private key", func(t *testing.T) {
+		public, err := ParsePublicKeyPEM(publicKeyPEM(t, ed25519PrivateKeyPEM(t)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = NewKeySet(public)
+		assert.Error(t, err)
+	})
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+	signingFile := filepath.Join(dir, "current.pem")
+	previousFile := filepath.Join(dir, "previous.pem")
+	if err := os.WriteFile(signingFile, ed25519PrivateKeyPEM(t), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(previousFile, publicKeyPEM(t, rsaPrivateKeyPEM(t, 2048)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := LoadKeySet(signingFile, []string{previousFile})
+	assert.NoError(t, err)
+	assert.Len(t, keys.JWKS().Keys, 2)
+
+	_, err = LoadKeySet(filepath.Join(dir, "missing.pem"), nil)
+	assert.Error(t, err)
+
+	_, err = LoadKeySet(signingFile, []string{filepath.Join(dir, "missing.pem")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jorge-dev/centsible/internal/repository"
+)
+
+// PostgresStore is a SessionStore backed by the user_sessions, revoked_tokens,
+// refresh_tokens and revoked_token_families tables.
+type PostgresStore struct {
+	queries *repository.Queries
+}
+
+func NewPostgresStore(queries *repository.Queries) *PostgresStore {
+	return &PostgresStore{queries: queries}
+}
+
+// Ensure PostgresStore implements SessionStore
+var _ SessionStore = (*PostgresStore)(nil)
+
+func (s *PostgresStore) SaveSession(ctx context.Context, session Session) error {
+	id, err := uuid.Parse(session.ID)
+	if err != nil {
+		return fmt.Errorf("invalid session ID: %w", err)
+	}
+	userID, err := uuid.Parse(session.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+	return s.queries.UpsertSession(ctx, repository.UpsertSessionParams{
+		ID:        id,
+		UserID:    userID,
+		UserAgent: session.UserAgent,
+		IpAddress: session.IPAddress,
+		CreatedAt: session.CreatedAt,
+		LastSeen:  session.LastSeen,
+	})
+}
+
+func (s *PostgresStore) TouchSession(ctx context.Context, sessionID string, at time.Time) error {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return fmt.Errorf("invalid session ID: %w", err)
+	}
+	return s.queries.TouchSession(ctx, repository.TouchSessionParams{
+		ID:       id,
+		LastSeen: at,
+	})
+}
+
+func (s *PostgresStore) GetSession(ctx context.Context, sessionID string) (Session, bool, error) {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		// IDs that aren't UUIDs can never have been issued
+		return Session{}, false, nil
+	}
+	row, err := s.queries.GetSession(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Session{}, false, nil
+	}
+	if err != nil {
+		return Session{}, false, fmt.Errorf("error getting session: %w", err)
+	}
+	return sessionFromRow(row), true, nil
+}
+
+func (s *PostgresStore) ListSessions(ctx context.Context, userID string) ([]Session, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+	rows, err := s.queries.ListUserSessions(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sessions: %w", err)
+	}
+	sessions := make([]Session, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, sessionFromRow(row))
+	}
+	return sessions, nil
+}
+
+func (s *PostgresStore) DeleteSession(ctx context.Context, sessionID string) error {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return fmt.Errorf("invalid session ID: %w", err)
+	}
+	return s.queries.DeleteSession(ctx, id)
+}
+
+func (s *PostgresStore) RevokeToken(ctx context.Context, tokenHash string, expiresAt time.Time) error {
+	return s.queries.RevokeToken(ctx, repository.RevokeTokenParams{
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	})
+}
+
+func (s *PostgresStore) IsTokenRevoked(ctx context.Context, tokenHash string) (bool, error) {
+	return s.queries.IsTokenRevoked(ctx, tokenHash)
+}
+
+func (s *PostgresStore) SaveRefreshToken(ctx context.Context, token RefreshTokenRecord) error {
+	id, err := uuid.Parse(token.ID)
+	if err != nil {
+		return fmt.Errorf("invalid refresh token ID: %w", err)
+	}
+	familyID, err := uuid.Parse(token.FamilyID)
+	if err != nil {
+		return fmt.Errorf("invalid refresh token family: %w", err)
+	}
+	userID, err := uuid.Parse(token.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+	return s.queries.CreateRefreshToken(ctx, repository.CreateRefreshTokenParams{
+		ID:        id,
+		FamilyID:  familyID,
+		UserID:    userID,
+		ExpiresAt: token.ExpiresAt,
+	})
+}
+
+func (s *PostgresStore) UseRefreshToken(ctx context.Context, tokenID string) (string, bool, error) {
+	id, err := uuid.Parse(tokenID)
+	if err != nil {
+		return "", false, ErrRefreshTokenNotFound
+	}
+	row, err := s.queries.UseRefreshToken(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("error using refresh token: %w", err)
+	}
+	return row.FamilyID.String(), row.AlreadyUsed, nil
+}
+
+func (s *PostgresStore) RevokeTokenFamily(ctx context.Context, familyID string, expiresAt time.Time) error {
+	fid, err := uuid.Parse(familyID)
+	if err != nil {
+		return fmt.Errorf("invalid refresh token family: %w", err)
+	}
+	if _, err := s.queries.DeleteRefreshTokenFamily(ctx, fid); err != nil {
+		return fmt.Errorf("error deleting refresh token family: %w", err)
+	}
+	return s.queries.RevokeTokenFamily(ctx, repository.RevokeTokenFamilyParams{
+		FamilyID:  fid,
+		ExpiresAt: expiresAt,
+	})
+}
+
+func (s *PostgresStore) IsTokenFamilyRevoked(ctx context.Context, familyID string) (bool, error) {
+	fid, err := uuid.Parse(familyID)
+	if err != nil {
+		return false, fmt.Errorf("invalid refresh token family: %w", err)
+	}
+	return s.queries.IsTokenFamilyRevoked(ctx, fid)
+}
+
+func (s *PostgresStore) Cleanup(ctx context.Context, now time.Time, inactiveBefore time.Time) error {
+	if _, err := s.queries.DeleteExpiredRevokedTokens(ctx, now); err != nil {
+		return fmt.Errorf("error cleaning up revoked tokens: %w", err)
+	}
+	if _, err := s.queries.DeleteInactiveSessions(ctx, inactiveBefore); err != nil {
+		return fmt.Errorf("error cleaning up sessions: %w", err)
+	}
+	if _, err := s.queries.DeleteExpiredRefreshTokens(ctx, now); err != nil {
+		return fmt.Errorf("error cleaning up refresh tokens: %w", err)
+	}
+	if _, err := s.queries.DeleteExpiredTokenFamilies(ctx, now); err != nil {
+		return fmt.Errorf("error cleaning up token families: %w", err)
+	}
+	return nil
+}
+
+func sessionFromRow(row repository.UserSession) Session {
+	return Session{
+		ID:        row.ID.String(),
+		UserID:    row.UserID.String(),
+		UserAgent: row.UserAgent,
+		IPAddress: row.IpAddress,
+		CreatedAt: row.CreatedAt,
+		LastSeen:  row.LastSeen,
+	}
+}
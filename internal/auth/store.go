@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrRefreshTokenNotFound is returned when a refresh token was never issued or has already been cleaned up
+var ErrRefreshTokenNotFound = fmt.Errorf("refresh token not found")
+
+// Session is a single signed in device
+type Session struct {
+	ID        string
+	UserID    string
+	UserAgent string
+	IPAddress string
+	CreatedAt time.Time
+	LastSeen  time.Time
+}
+
+// RefreshTokenRecord describes an issued refresh token
+type RefreshTokenRecord struct {
+	ID        string
+	FamilyID  string
+	UserID    string
+	ExpiresAt time.Time
+}
+
+// SessionStore persists session activity and token revocation state so that it
+// survives restarts and is shared between replicas.
+type SessionStore interface {
+	// SaveSession creates a session, or only bumps its last seen time if it already exists
+	SaveSession(ctx context.Context, session Session) error
+	// TouchSession records activity on an existing session. Unknown sessions are ignored.
+	TouchSession(ctx context.Context, sessionID string, at time.Time) error
+	// GetSession returns a session and whether it exists
+	GetSession(ctx context.Context, sessionID string) (Session, bool, error)
+	// ListSessions returns a user's sessions, most recently seen first
+	ListSessions(ctx context.Context, userID string) ([]Session, error)
+	// DeleteSession removes a single session
+	DeleteSession(ctx context.Context, sessionID string) error
+
+	// RevokeToken blacklists a token hash until it expires
+	RevokeToken(ctx context.Context, tokenHash string, expiresAt time.Time) error
+	// IsTokenRevoked reports whether a token hash has been blacklisted
+	IsTokenRevoked(ctx context.Context, tokenHash string) (bool, error)
+
+	// SaveRefreshToken records a newly issued refresh token
+	SaveRefreshToken(ctx context.Context, token RefreshTokenRecord) error
+	// UseRefreshToken atomically marks a refresh token as used. It returns the token's
+	// family and whether it had already been used, or ErrRefreshTokenNotFound.
+	UseRefreshToken(ctx context.Context, tokenID string) (familyID string, alreadyUsed bool, err error)
+	// RevokeTokenFamily revokes every refresh token in a family until expiresAt
+	RevokeTokenFamily(ctx context.Context, familyID string, expiresAt time.Time) error
+	// IsTokenFamilyRevoked reports whether a refresh token family has been revoked
+	IsTokenFamilyRevoked(ctx context.Context, familyID string) (bool, error)
+
+	// Cleanup removes expired revocations and sessions idle since before inactiveBefore
+	Cleanup(ctx context.Context, now time.Time, inactiveBefore time.Time) error
+}
+
+// MemoryStore is an in-memory SessionStore, used in tests and single instance setups.
+// It is safe for concurrent use.
+type MemoryStore struct {
+	mu              sync.RWMutex
+	sessions        map[string]Session            // Sessions keyed by session ID
+	blacklist       map[string]time.Time          // Revoked token hashes and their expiry
+	refreshTokens   map[string]*refreshTokenState // Issued refresh tokens keyed by jti
+	revokedFamilies map[string]time.Time          // Refresh token families revoked after reuse
+}
+
+// refreshTokenState tracks a single issued refresh token so it can only be used once
+type refreshTokenState struct {
+	familyID  string
+	used      bool
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions:        make(map[string]Session),
+		blacklist:       make(map[string]time.Time),
+		refreshTokens:   make(map[string]*refreshTokenState),
+		revokedFamilies: make(map[string]time.Time),
+	}
+}
+
+// Ensure MemoryStore implements SessionStore
+var _ SessionStore = (*MemoryStore)(nil)
+
+func (s *MemoryStore) SaveSession(ctx context.Context, session Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, exists := s.sessions[session.ID]; exists {
+		existing.LastSeen = session.LastSeen
+		s.sessions[session.ID] = existing
+		return nil
+	}
+	s.sessions[session.ID] = session
+	return nil
+}
+
+func (s *MemoryStore) TouchSession(ctx context.Context, sessionID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session, exists := s.sessions[sessionID]; exists {
+		session.LastSeen = at
+		s.sessions[sessionID] = session
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetSession(ctx context.Context, sessionID string) (Session, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	session, exists := s.sessions[sessionID]
+	return session, exists, nil
+}
+
+func (s *MemoryStore) ListSessions(ctx context.Context, userID string) ([]Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sessions := []Session{}
+	for _, session := range s.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+	return sessions, nil
+}
+
+func (s *MemoryStore) DeleteSession(ctx context.Context, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sessionID)
+	return nil
+}
+
+func (s *MemoryStore) RevokeToken(ctx context.Context, tokenHash string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blacklist[tokenHash] = expiresAt
+	return nil
+}
+
+func (s *MemoryStore) IsTokenRevoked(ctx context.Context, tokenHash string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, revoked := s.blacklist[tokenHash]
+	return revoked, nil
+}
+
+func (s *MemoryStore) SaveRefreshToken(ctx context.Context, token RefreshTokenRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshTokens[token.ID] = &refreshTokenState{
+		familyID:  token.FamilyID,
+		expiresAt: token.ExpiresAt,
+	}
+	return nil
+}
+
+func (s *MemoryStore) UseRefreshToken(ctx context.Context, tokenID string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, exists := s.refreshTokens[tokenID]
+	if !exists {
+		return "", false, ErrRefreshTokenNotFound
+	}
+	alreadyUsed := state.used
+	state.used = true
+	return state.familyID, alreadyUsed, nil
+}
+
+func (s *MemoryStore) RevokeTokenFamily(ctx context.Context, familyID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for jti, state := range s.refreshTokens {
+		if state.familyID == familyID {
+			delete(s.refreshTokens, jti)
+		}
+	}
+	if current, exists := s.revokedFamilies[familyID]; !exists || expiresAt.After(current) {
+		s.revokedFamilies[familyID] = expiresAt
+	}
+	return nil
+}
+
+func (s *MemoryStore) IsTokenFamilyRevoked(ctx context.Context, familyID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, revoked := s.revokedFamilies[familyID]
+	return revoked, nil
+}
+
+func (s *MemoryStore) Cleanup(ctx context.Context, now time.Time, inactiveBefore time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for tokenHash, expiry := range s.blacklist {
+		if now.After(expiry) {
+			delete(s.blacklist, tokenHash)
+		}
+	}
+	for sessionID, session := range s.sessions {
+		if session.LastSeen.Before(inactiveBefore) {
+			delete(s.sessions, sessionID)
+		}
+	}
+	for jti, state := range s.refreshTokens {
+		if now.After(state.expiresAt) {
+			delete(s.refreshTokens, jti)
+		}
+	}
+	for familyID, expiry := range s.revokedFamilies {
+		if now.After(expiry) {
+			delete(s.revokedFamilies, familyID)
+		}
+	}
+	return nil
+}
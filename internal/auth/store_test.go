@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreSessions(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	_, exists, err := store.GetSession(ctx, "session-1")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// Touching an unknown session does not create it
+	assert.NoError(t, store.TouchSession(ctx, "session-1", time.Now()))
+	_, exists, _ = store.GetSession(ctx, "session-1")
+	assert.False(t, exists)
+
+	createdAt := time.Now().Add(-time.Minute)
+	assert.NoError(t, store.SaveSession(ctx, Session{
+		ID:        "session-1",
+		UserID:    "123",
+		UserAgent: "laptop",
+		IPAddress: "10.0.0.1",
+		CreatedAt: createdAt,
+		LastSeen:  createdAt,
+	}))
+	assert.NoError(t, store.SaveSession(ctx, Session{ID: "session-2", UserID: "123", UserAgent: "phone", CreatedAt: createdAt, LastSeen: createdAt}))
+	assert.NoError(t, store.SaveSession(ctx, Session{ID: "session-3", UserID: "456", CreatedAt: createdAt, LastSeen: createdAt}))
+
+	now := time.Now()
+	assert.NoError(t, store.TouchSession(ctx, "session-1", now))
+
+	session, exists, err := store.GetSession(ctx, "session-1")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, now, session.LastSeen)
+	assert.Equal(t, createdAt, session.CreatedAt)
+	assert.Equal(t, "laptop", session.UserAgent)
+
+	// Saving an existing session only bumps its last seen time
+	assert.NoError(t, store.SaveSession(ctx, Session{ID: "session-1", UserID: "123", LastSeen: now.Add(time.Second)}))
+	session, _, _ = store.GetSession(ctx, "session-1")
+	assert.Equal(t, "laptop", session.UserAgent)
+	assert.Equal(t, now.Add(time.Second), session.LastSeen)
+
+	// Sessions are listed per user, most recently seen first
+	sessions, err := store.ListSessions(ctx, "123")
+	assert.NoError(t, err)
+	if assert.Len(t, sessions, 2) {
+		assert.Equal(t, "session-1", sessions[0].ID)
+		assert.Equal(t, "session-2", sessions[1].ID)
+	}
+
+	assert.NoError(t, store.DeleteSession(ctx, "session-1"))
+	_, exists, err = store.GetSession(ctx, "session-1")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	sessions, err = store.ListSessions(ctx, "unknown")
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestMemoryStoreRevokedTokens(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	revoked, err := store.IsTokenRevoked(ctx, "hash")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	assert.NoError(t, store.RevokeToken(ctx, "hash", time.Now().Add(time.Hour)))
+
+	revoked, err = store.IsTokenRevoked(ctx, "hash")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestMemoryStoreRefreshTokens(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	_, _, err := store.UseRefreshToken(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrRefreshTokenNotFound)
+
+	assert.NoError(t, store.SaveRefreshToken(ctx, RefreshTokenRecord{
+		ID:        "jti-1",
+		FamilyID:  "family-1",
+		UserID:    "123",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}))
+
+	familyID, alreadyUsed, err := store.UseRefreshToken(ctx, "jti-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "family-1", familyID)
+	assert.False(t, alreadyUsed)
+
+	_, alreadyUsed, err = store.UseRefreshToken(ctx, "jti-1")
+	assert.NoError(t, err)
+	assert.True(t, alreadyUsed)
+
+	assert.NoError(t, store.RevokeTokenFamily(ctx, "family-1", time.Now().Add(time.Hour)))
+
+	revoked, err := store.IsTokenFamilyRevoked(ctx, "family-1")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	_, _, err = store.UseRefreshToken(ctx, "jti-1")
+	assert.ErrorIs(t, err, ErrRefreshTokenNotFound)
+}
+
+func TestMemoryStoreCleanup(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	store.SaveSession(ctx, Session{ID: "active", UserID: "123", LastSeen: now})
+	store.SaveSession(ctx, Session{ID: "idle", UserID: "123", LastSeen: now.Add(-time.Hour)})
+	store.RevokeToken(ctx, "expired", now.Add(-time.Minute))
+	store.RevokeToken(ctx, "live", now.Add(time.Minute))
+	store.SaveRefreshToken(ctx, RefreshTokenRecord{ID: "old", FamilyID: "f", ExpiresAt: now.Add(-time.Minute)})
+	store.RevokeTokenFamily(ctx, "revoked", now.Add(-time.Minute))
+
+	assert.NoError(t, store.Cleanup(ctx, now, now.Add(-InactivityTimeout)))
+
+	_, exists, _ := store.GetSession(ctx, "active")
+	assert.True(t, exists)
+	_, exists, _ = store.GetSession(ctx, "idle")
+	assert.False(t, exists)
+
+	revoked, _ := store.IsTokenRevoked(ctx, "expired")
+	assert.False(t, revoked)
+	revoked, _ = store.IsTokenRevoked(ctx, "live")
+	assert.True(t, revoked)
+
+	_, _, err := store.UseRefreshToken(ctx, "old")
+	assert.ErrorIs(t, err, ErrRefreshTokenNotFound)
+
+	revoked, _ = store.IsTokenFamilyRevoked(ctx, "revoked")
+	assert.False(t, revoked)
+}
+
+func TestJWTManagerSharedStore(t *testing.T) {
+	// Two managers sharing a store behave like two replicas behind a load balancer
+	store := NewMemoryStore()
+	replicaA := NewJWTManagerWithStore(secretKey, store)
+	replicaB := NewJWTManagerWithStore(secretKey, store)
+
+	pair, err := replicaA.GenerateTokenPair("123", testEmail, "user")
+	assert.NoError(t, err)
+
+	// Session started on replica A is visible on replica B
+	_, err = replicaB.ValidateToken(pair.AccessToken)
+	assert.NoError(t, err)
+
+	// Logout on replica B is honoured by replica A
+	assert.NoError(t, replicaB.InvalidateToken(pair.AccessToken))
+	_, err = replicaA.ValidateToken(pair.AccessToken)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "token has been invalidated")
+
+	// Refresh token rotated on replica A cannot be replayed on replica B
+	_, err = replicaA.RefreshToken(pair.RefreshToken)
+	assert.NoError(t, err)
+	_, err = replicaB.RefreshToken(pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+}
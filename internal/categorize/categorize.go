@@ -0,0 +1,177 @@
+// Package categorize files expenses under categories and tags, by the rules
+// a user sets and by how the user filed similar expenses before
+package categorize
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/repository"
+)
+
+// Expense is what rules match an expense on
+type Expense struct {
+	Description string
+	Amount      money.Amount
+	Currency    string
+}
+
+// Result is what the rules matching an expense set. CategoryID is nil when
+// none of them sets a category.
+type Result struct {
+	CategoryID *uuid.UUID
+	TagIDs     []uuid.UUID
+	RuleIDs    []uuid.UUID // The rules that matched, in the order tried
+}
+
+// Apply runs the enabled rules on an expense in the order given, which is the
+// order ListCategoryRules returns them in. The first rule that matches and
+// sets a category decides it, and every rule that matches adds its tags.
+func Apply(rules []repository.CategoryRule, expense Expense) Result {
+	var result Result
+	seen := make(map[uuid.UUID]bool)
+	for _, rule := range rules {
+		if !rule.Enabled || !Matches(rule, expense) {
+			continue
+		}
+		result.RuleIDs = append(result.RuleIDs, rule.ID)
+		if result.CategoryID == nil && rule.CategoryID != nil {
+			categoryID := *rule.CategoryID
+			result.CategoryID = &categoryID
+		}
+		for _, tagID := range rule.TagIds {
+			if !seen[tagID] {
+				seen[tagID] = true
+				result.TagIDs = append(result.TagIDs, tagID)
+			}
+		}
+	}
+	return result
+}
+
+// Matches reports whether every condition of a rule holds for an expense.
+// Conditions left unset always hold.
+func Matches(rule repository.CategoryRule, expense Expense) bool {
+	if rule.DescriptionPattern != nil && !MatchPattern(*rule.DescriptionPattern, expense.Description) {
+		return false
+	}
+	if rule.MinAmount != nil && expense.Amount.Cmp(*rule.MinAmount) < 0 {
+		return false
+	}
+	if rule.MaxAmount != nil && expense.Amount.Cmp(*rule.MaxAmount) > 0 {
+		return false
+	}
+	if rule.Currency != nil && !strings.EqualFold(*rule.Currency, expense.Currency) {
+		return false
+	}
+	return true
+}
+
+// MatchPattern reports whether the whole of text matches pattern, ignoring
+// case and surrounding space. In a pattern * stands for any run of
+// characters, none included, and ? for any one character.
+func MatchPattern(pattern, text string) bool {
+	p := []rune(strings.ToLower(strings.TrimSpace(pattern)))
+	t := []rune(strings.ToLower(strings.TrimSpace(text)))
+
+	// On a mismatch, let the last * seen take one more character of the
+	// text and carry on from there
+	var pi, ti int
+	star, starText := -1, 0
+	for ti < len(t) {
+		switch {
+		case pi < len(p) && p[pi] == '*':
+			star, starText = pi, ti
+			pi++
+		case pi < len(p) && (p[pi] == '?' || p[pi] == t[ti]):
+			pi++
+			ti++
+		case star >= 0:
+			starText++
+			pi, ti = star+1, starText
+		default:
+			return false
+		}
+	}
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
+
+// Words splits a description into the words GetCategoryWordCounts counts:
+// lower case runs of letters and digits, each once. Single characters and
+// plain numbers, such as store numbers and dates, say little about a
+// category and are left out.
+func Words(description string) []string {
+	var words []string
+	seen := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) < 2 || seen[word] || strings.IndexFunc(word, unicode.IsLetter) < 0 {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	return words
+}
+
+// Suggestion is a category an expense likely belongs in
+type Suggestion struct {
+	CategoryID   uuid.UUID `json:"category_id"`
+	CategoryName string    `json:"category_name"`
+	// Score runs from 0 to 1: the share of the user's past expenses with each
+	// word of the description that were in the category, averaged over the
+	// words
+	Score float64 `json:"score"`
+}
+
+// Suggest scores categories for a description with the given words by how
+// the user's past expenses with those words were filed, as counted by
+// GetCategoryWordCounts. A word the user never used scores 0 for every
+// category. It returns up to limit categories, best first.
+func Suggest(words []string, counts []repository.GetCategoryWordCountsRow, limit int) []Suggestion {
+	if len(words) == 0 {
+		return []Suggestion{}
+	}
+
+	wordTotals := make(map[string]int64)
+	for _, count := range counts {
+		wordTotals[count.Word] += count.ExpenseCount
+	}
+	scores := make(map[uuid.UUID]*Suggestion)
+	for _, count := range counts {
+		suggestion, ok := scores[count.CategoryID]
+		if !ok {
+			suggestion = &Suggestion{CategoryID: count.CategoryID, CategoryName: count.CategoryName}
+			scores[count.CategoryID] = suggestion
+		}
+		suggestion.Score += float64(count.ExpenseCount) / float64(wordTotals[count.Word]) / float64(len(words))
+	}
+
+	suggestions := make([]Suggestion, 0, len(scores))
+	for _, suggestion := range scores {
+		suggestion.Score = math.Round(suggestion.Score*1000) / 1000
+		suggestions = append(suggestions, *suggestion)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.CategoryName != b.CategoryName {
+			return a.CategoryName < b.CategoryName
+		}
+		return a.CategoryID.String() < b.CategoryID.String()
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
@@ -0,0 +1,133 @@
+package categorize
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		text    string
+		want    bool
+	}{
+		{"UBER*", "Uber *Trip 4411", true},
+		{"UBER*", "UBER", true},
+		{"UBER*", "Ubereats", true},
+		{"UBER*", "My Uber", false},
+		{"*netflix*", "NETFLIX.COM 866-579", true},
+		{"NETFLIX", "netflix", true},
+		{"NETFLIX", "NETFLIX.COM", false},
+		{"  netflix ", " NETFLIX", true},
+		{"caf?", "Café", true},
+		{"caf?", "Caf", false},
+		{"a*b*c", "aXXbYYbZZc", true},
+		{"a*b*c", "aXXcYYb", false},
+		{"*", "", true},
+		{"", "", true},
+		{"", "anything", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.want, MatchPattern(tt.pattern, tt.text))
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	lunch := Expense{Description: "UBER EATS", Amount: money.MustParse("25.50"), Currency: "USD"}
+
+	tests := []struct {
+		name string
+		rule repository.CategoryRule
+		want bool
+	}{
+		{"Pattern", repository.CategoryRule{DescriptionPattern: ptr("uber*")}, true},
+		{"Other pattern", repository.CategoryRule{DescriptionPattern: ptr("lyft*")}, false},
+		{"Within range", repository.CategoryRule{MinAmount: ptr(money.MustParse("10")), MaxAmount: ptr(money.MustParse("25.50"))}, true},
+		{"Below minimum", repository.CategoryRule{MinAmount: ptr(money.MustParse("30"))}, false},
+		{"Above maximum", repository.CategoryRule{MaxAmount: ptr(money.MustParse("25.49"))}, false},
+		{"Currency", repository.CategoryRule{Currency: ptr("usd")}, true},
+		{"Other currency", repository.CategoryRule{Currency: ptr("EUR")}, false},
+		{"Every condition", repository.CategoryRule{DescriptionPattern: ptr("*EATS"), MaxAmount: ptr(money.MustParse("50")), Currency: ptr("USD")}, true},
+		{"One condition failing", repository.CategoryRule{DescriptionPattern: ptr("*EATS"), Currency: ptr("CAD")}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Matches(tt.rule, lunch))
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	transport, food := uuid.New(), uuid.New()
+	work, travel := uuid.New(), uuid.New()
+	rules := []repository.CategoryRule{
+		{ID: uuid.New(), DescriptionPattern: ptr("uber eats*"), CategoryID: &food, Enabled: true},
+		{ID: uuid.New(), DescriptionPattern: ptr("uber*"), CategoryID: &transport, TagIds: []uuid.UUID{work}, Enabled: true},
+		{ID: uuid.New(), Currency: ptr("EUR"), TagIds: []uuid.UUID{travel, work}, Enabled: true},
+		{ID: uuid.New(), DescriptionPattern: ptr("*"), CategoryID: &transport, Enabled: false},
+	}
+
+	tests := []struct {
+		name         string
+		expense      Expense
+		wantCategory *uuid.UUID
+		wantTags     []uuid.UUID
+		wantRules    []uuid.UUID
+	}{
+		{"First category wins", Expense{Description: "Uber Eats", Currency: "USD"}, &food, []uuid.UUID{work}, []uuid.UUID{rules[0].ID, rules[1].ID}},
+		{"Tags add up once each", Expense{Description: "Uber Trip", Currency: "EUR"}, &transport, []uuid.UUID{work, travel}, []uuid.UUID{rules[1].ID, rules[2].ID}},
+		{"Tags only", Expense{Description: "Museum", Currency: "EUR"}, nil, []uuid.UUID{travel, work}, []uuid.UUID{rules[2].ID}},
+		{"No match", Expense{Description: "Groceries", Currency: "USD"}, nil, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Apply(rules, tt.expense)
+			assert.Equal(t, tt.wantCategory, result.CategoryID)
+			assert.Equal(t, tt.wantTags, result.TagIDs)
+			assert.Equal(t, tt.wantRules, result.RuleIDs)
+		})
+	}
+}
+
+func TestWords(t *testing.T) {
+	assert.Equal(t, []string{"uber", "trip", "help", "com", "ca"}, Words("UBER *TRIP HELP.UBER.COM CA 4411 1/2"))
+	assert.Equal(t, []string{"café", "mañana", "7eleven"}, Words("Café Mañana - 7ELEVEN #22"))
+	assert.Empty(t, Words("12/05 #4411"))
+}
+
+func TestSuggest(t *testing.T) {
+	transport, food, fun := uuid.New(), uuid.New(), uuid.New()
+	counts := []repository.GetCategoryWordCountsRow{
+		{CategoryID: transport, CategoryName: "Transport", Word: "uber", ExpenseCount: 6},
+		{CategoryID: food, CategoryName: "Food", Word: "uber", ExpenseCount: 2},
+		{CategoryID: food, CategoryName: "Food", Word: "eats", ExpenseCount: 3},
+		{CategoryID: fun, CategoryName: "Fun", Word: "eats", ExpenseCount: 1},
+	}
+
+	suggestions := Suggest([]string{"uber", "eats"}, counts, 5)
+	assert.Equal(t, []Suggestion{
+		{CategoryID: food, CategoryName: "Food", Score: 0.5},
+		{CategoryID: transport, CategoryName: "Transport", Score: 0.375},
+		{CategoryID: fun, CategoryName: "Fun", Score: 0.125},
+	}, suggestions)
+
+	// Words never used before count against every category
+	suggestions = Suggest([]string{"uber", "pool"}, counts[:2], 1)
+	assert.Equal(t, []Suggestion{{CategoryID: transport, CategoryName: "Transport", Score: 0.375}}, suggestions)
+
+	assert.Empty(t, Suggest(nil, counts, 5))
+	assert.Empty(t, Suggest([]string{"museum"}, nil, 5))
+}
@@ -32,10 +32,38 @@ type DatabaseConfig struct {
 	Password     string
 	Schema       string
 	RunMigration bool
+	Pool         PoolConfig
+	Replica      *ReplicaConfig // nil when no read replica is configured
+}
+
+// ReplicaConfig points reporting queries at a read replica of the database.
+// Its pool is sized like the primary's, and fields left empty default to the
+// primary's.
+type ReplicaConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+// PoolConfig sizes the database connection pool and sets how long its
+// connections live
+type PoolConfig struct {
+	MaxConns          int32
+	MinConns          int32         // Kept open even when idle
+	MaxConnLifetime   time.Duration // Connections are closed and replaced once this old
+	MaxConnIdleTime   time.Duration // Idle connections above MinConns are closed after this long
+	HealthCheckPeriod time.Duration // How often idle connections are checked
 }
 
 type JWTConfig struct {
-	Secret string
+	Secret               string
+	SigningKeyFile       string   // PEM encoded RSA or Ed25519 private key, switches signing to RS256/EdDSA
+	VerificationKeyFiles []string // PEM keys retired from signing that still verify tokens during a rotation
+	// SecretCutoff is when signing moved from Secret to SigningKeyFile. HS256
+	// tokens issued before it are accepted until they could have expired; zero
+	// rejects them all.
+	SecretCutoff time.Time
 }
 
 type LoggingConfig struct {
@@ -81,10 +109,10 @@ func Get() *Config {
 				Password:     requireEnv("CENTSIBLE_DB_PASSWORD"),
 				Schema:       requireEnv("CENTSIBLE_DB_SCHEMA"),
 				RunMigration: os.Getenv("RUN_MIGRATION") == "true",
+				Pool:         loadPoolConfig(),
+				Replica:      loadReplicaConfig(),
 			},
-			JWT: JWTConfig{
-				Secret: requireEnv("JWT_SECRET"),
-			},
+			JWT: loadJWTConfig(),
 			Logging: LoggingConfig{
 				Level: loadEnvWithDefault("LOG_LEVEL", "info"),
 			},
@@ -93,6 +121,82 @@ func Get() *Config {
 	return config
 }
 
+// loadJWTConfig only requires JWT_SECRET when no signing key file is configured.
+// With one, JWT_SECRET_CUTOFF keeps HS256 tokens issued before it valid.
+func loadJWTConfig() JWTConfig {
+	cfg := JWTConfig{
+		SigningKeyFile:       os.Getenv("JWT_SIGNING_KEY_FILE"),
+		VerificationKeyFiles: splitList(os.Getenv("JWT_VERIFICATION_KEY_FILES")),
+	}
+	if cfg.SigningKeyFile == "" {
+		cfg.Secret = requireEnv("JWT_SECRET")
+		return cfg
+	}
+
+	cfg.Secret = os.Getenv("JWT_SECRET")
+	if cutoff := os.Getenv("JWT_SECRET_CUTOFF"); cutoff != "" {
+		parsed, err := time.Parse(time.RFC3339, cutoff)
+		if err != nil {
+			log.Fatalf("JWT_SECRET_CUTOFF must be an RFC 3339 time such as 2024-05-01T00:00:00Z: %q", cutoff)
+		}
+		if cfg.Secret == "" {
+			log.Fatalf("JWT_SECRET_CUTOFF requires JWT_SECRET")
+		}
+		cfg.SecretCutoff = parsed
+	}
+	return cfg
+}
+
+// Pool defaults, used when the CENTSIBLE_DB_* pool variables are not set
+const (
+	defaultMaxConns          = 10
+	defaultMinConns          = 0
+	defaultMaxConnLifetime   = time.Hour
+	defaultMaxConnIdleTime   = 30 * time.Minute
+	defaultHealthCheckPeriod = time.Minute
+)
+
+func loadPoolConfig() PoolConfig {
+	cfg := PoolConfig{
+		MaxConns:          int32(loadIntWithDefault("CENTSIBLE_DB_MAX_CONNS", defaultMaxConns)),
+		MinConns:          int32(loadIntWithDefault("CENTSIBLE_DB_MIN_CONNS", defaultMinConns)),
+		MaxConnLifetime:   loadDurationWithDefault("CENTSIBLE_DB_MAX_CONN_LIFETIME", defaultMaxConnLifetime),
+		MaxConnIdleTime:   loadDurationWithDefault("CENTSIBLE_DB_MAX_CONN_IDLE_TIME", defaultMaxConnIdleTime),
+		HealthCheckPeriod: loadDurationWithDefault("CENTSIBLE_DB_HEALTH_CHECK_PERIOD", defaultHealthCheckPeriod),
+	}
+	if cfg.MaxConns < 1 || cfg.MinConns < 0 || cfg.MinConns > cfg.MaxConns {
+		log.Fatalf("CENTSIBLE_DB_MIN_CONNS (%d) must be between 0 and CENTSIBLE_DB_MAX_CONNS (%d), which must be at least 1",
+			cfg.MinConns, cfg.MaxConns)
+	}
+	return cfg
+}
+
+// loadReplicaConfig returns nil unless CENTSIBLE_DB_REPLICA_HOST is set. The
+// replica's port and credentials default to the primary's.
+func loadReplicaConfig() *ReplicaConfig {
+	host := os.Getenv("CENTSIBLE_DB_REPLICA_HOST")
+	if host == "" {
+		return nil
+	}
+	return &ReplicaConfig{
+		Host:     host,
+		Port:     loadEnvWithDefault("CENTSIBLE_DB_REPLICA_PORT", os.Getenv("CENTSIBLE_DB_PORT")),
+		Username: loadEnvWithDefault("CENTSIBLE_DB_REPLICA_USERNAME", os.Getenv("CENTSIBLE_DB_USERNAME")),
+		Password: loadEnvWithDefault("CENTSIBLE_DB_REPLICA_PASSWORD", os.Getenv("CENTSIBLE_DB_PASSWORD")),
+	}
+}
+
+// splitList splits a comma separated value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func loadPort() int {
 	portStr := os.Getenv("PORT")
 	if portStr == "" {
@@ -120,6 +224,31 @@ func loadEnvWithDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+func loadIntWithDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		log.Fatalf("%s must be a whole number: %v", key, err)
+	}
+	return int(parsed)
+}
+
+// loadDurationWithDefault reads a duration such as 30s or 1h30m
+func loadDurationWithDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		log.Fatalf("%s must be a positive duration such as 30s or 1h: %q", key, value)
+	}
+	return parsed
+}
+
 func ParseLogLevel(level string) slog.Level {
 	switch strings.ToLower(level) {
 	case "debug":
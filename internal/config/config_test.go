@@ -4,21 +4,35 @@ import (
 	"bytes"
 	"os"
 	"testing"
+	"time"
 )
 
 func setupTestEnv() func() {
 	// Save original env vars
 	originalEnv := map[string]string{
-		"PORT":                  os.Getenv("PORT"),
-		"APP_ENV":               os.Getenv("APP_ENV"),
-		"CENTSIBLE_DB_HOST":     os.Getenv("CENTSIBLE_DB_HOST"),
-		"CENTSIBLE_DB_PORT":     os.Getenv("CENTSIBLE_DB_PORT"),
-		"CENTSIBLE_DB_DATABASE": os.Getenv("CENTSIBLE_DB_DATABASE"),
-		"CENTSIBLE_DB_USERNAME": os.Getenv("CENTSIBLE_DB_USERNAME"),
-		"CENTSIBLE_DB_PASSWORD": os.Getenv("CENTSIBLE_DB_PASSWORD"),
-		"CENTSIBLE_DB_SCHEMA":   os.Getenv("CENTSIBLE_DB_SCHEMA"),
-		"RUN_MIGRATION":         os.Getenv("RUN_MIGRATION"),
-		"JWT_SECRET":            os.Getenv("JWT_SECRET"),
+		"PORT":                       os.Getenv("PORT"),
+		"APP_ENV":                    os.Getenv("APP_ENV"),
+		"CENTSIBLE_DB_HOST":          os.Getenv("CENTSIBLE_DB_HOST"),
+		"CENTSIBLE_DB_PORT":          os.Getenv("CENTSIBLE_DB_PORT"),
+		"CENTSIBLE_DB_DATABASE":      os.Getenv("CENTSIBLE_DB_DATABASE"),
+		"CENTSIBLE_DB_USERNAME":      os.Getenv("CENTSIBLE_DB_USERNAME"),
+		"CENTSIBLE_DB_PASSWORD":      os.Getenv("CENTSIBLE_DB_PASSWORD"),
+		"CENTSIBLE_DB_SCHEMA":        os.Getenv("CENTSIBLE_DB_SCHEMA"),
+		"RUN_MIGRATION":              os.Getenv("RUN_MIGRATION"),
+		"JWT_SECRET":                 os.Getenv("JWT_SECRET"),
+		"JWT_SIGNING_KEY_FILE":       os.Getenv("JWT_SIGNING_KEY_FILE"),
+		"JWT_VERIFICATION_KEY_FILES": os.Getenv("JWT_VERIFICATION_KEY_FILES"),
+		"JWT_SECRET_CUTOFF":          os.Getenv("JWT_SECRET_CUTOFF"),
+
+		"CENTSIBLE_DB_MAX_CONNS":           os.Getenv("CENTSIBLE_DB_MAX_CONNS"),
+		"CENTSIBLE_DB_MIN_CONNS":           os.Getenv("CENTSIBLE_DB_MIN_CONNS"),
+		"CENTSIBLE_DB_MAX_CONN_LIFETIME":   os.Getenv("CENTSIBLE_DB_MAX_CONN_LIFETIME"),
+		"CENTSIBLE_DB_MAX_CONN_IDLE_TIME":  os.Getenv("CENTSIBLE_DB_MAX_CONN_IDLE_TIME"),
+		"CENTSIBLE_DB_HEALTH_CHECK_PERIOD": os.Getenv("CENTSIBLE_DB_HEALTH_CHECK_PERIOD"),
+		"CENTSIBLE_DB_REPLICA_HOST":        os.Getenv("CENTSIBLE_DB_REPLICA_HOST"),
+		"CENTSIBLE_DB_REPLICA_PORT":        os.Getenv("CENTSIBLE_DB_REPLICA_PORT"),
+		"CENTSIBLE_DB_REPLICA_USERNAME":    os.Getenv("CENTSIBLE_DB_REPLICA_USERNAME"),
+		"CENTSIBLE_DB_REPLICA_PASSWORD":    os.Getenv("CENTSIBLE_DB_REPLICA_PASSWORD"),
 	}
 
 	// Return cleanup function
@@ -79,6 +93,154 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestLoadJWTConfig(t *testing.T) {
+	cleanup := setupTestEnv()
+	defer cleanup()
+
+	t.Run("Secret only", func(t *testing.T) {
+		os.Setenv("JWT_SECRET", "test-secret")
+		os.Unsetenv("JWT_SIGNING_KEY_FILE")
+		os.Unsetenv("JWT_VERIFICATION_KEY_FILES")
+
+		cfg := loadJWTConfig()
+		if cfg.Secret != "test-secret" {
+			t.Errorf("Expected secret test-secret, got %s", cfg.Secret)
+		}
+		if cfg.SigningKeyFile != "" || len(cfg.VerificationKeyFiles) != 0 {
+			t.Errorf("Expected no key files, got %+v", cfg)
+		}
+	})
+
+	t.Run("Signing keys without secret", func(t *testing.T) {
+		os.Unsetenv("JWT_SECRET")
+		os.Setenv("JWT_SIGNING_KEY_FILE", "/keys/current.pem")
+		os.Setenv("JWT_VERIFICATION_KEY_FILES", "/keys/previous.pem, ,/keys/older.pem")
+
+		cfg := loadJWTConfig()
+		if cfg.Secret != "" {
+			t.Errorf("Expected empty secret, got %s", cfg.Secret)
+		}
+		if cfg.SigningKeyFile != "/keys/current.pem" {
+			t.Errorf("Expected signing key file /keys/current.pem, got %s", cfg.SigningKeyFile)
+		}
+		want := []string{"/keys/previous.pem", "/keys/older.pem"}
+		if len(cfg.VerificationKeyFiles) != len(want) {
+			t.Fatalf("Expected verification key files %v, got %v", want, cfg.VerificationKeyFiles)
+		}
+		for i := range want {
+			if cfg.VerificationKeyFiles[i] != want[i] {
+				t.Errorf("Expected verification key files %v, got %v", want, cfg.VerificationKeyFiles)
+			}
+		}
+		if !cfg.SecretCutoff.IsZero() {
+			t.Errorf("Expected no secret cutoff, got %v", cfg.SecretCutoff)
+		}
+	})
+
+	t.Run("Signing keys with secret cutoff", func(t *testing.T) {
+		os.Setenv("JWT_SECRET", "test-secret")
+		os.Setenv("JWT_SIGNING_KEY_FILE", "/keys/current.pem")
+		os.Setenv("JWT_SECRET_CUTOFF", "2024-05-01T12:00:00+02:00")
+		defer os.Unsetenv("JWT_SECRET_CUTOFF")
+
+		cfg := loadJWTConfig()
+		want := time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC)
+		if !cfg.SecretCutoff.Equal(want) {
+			t.Errorf("Expected secret cutoff %v, got %v", want, cfg.SecretCutoff)
+		}
+	})
+}
+
+func TestLoadPoolConfig(t *testing.T) {
+	cleanup := setupTestEnv()
+	defer cleanup()
+
+	t.Run("Defaults", func(t *testing.T) {
+		for _, key := range []string{
+			"CENTSIBLE_DB_MAX_CONNS", "CENTSIBLE_DB_MIN_CONNS", "CENTSIBLE_DB_MAX_CONN_LIFETIME",
+			"CENTSIBLE_DB_MAX_CONN_IDLE_TIME", "CENTSIBLE_DB_HEALTH_CHECK_PERIOD",
+		} {
+			os.Unsetenv(key)
+		}
+
+		cfg := loadPoolConfig()
+		want := PoolConfig{
+			MaxConns:          defaultMaxConns,
+			MinConns:          defaultMinConns,
+			MaxConnLifetime:   defaultMaxConnLifetime,
+			MaxConnIdleTime:   defaultMaxConnIdleTime,
+			HealthCheckPeriod: defaultHealthCheckPeriod,
+		}
+		if cfg != want {
+			t.Errorf("Expected pool config %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("From environment", func(t *testing.T) {
+		os.Setenv("CENTSIBLE_DB_MAX_CONNS", "25")
+		os.Setenv("CENTSIBLE_DB_MIN_CONNS", "2")
+		os.Setenv("CENTSIBLE_DB_MAX_CONN_LIFETIME", "2h")
+		os.Setenv("CENTSIBLE_DB_MAX_CONN_IDLE_TIME", "5m")
+		os.Setenv("CENTSIBLE_DB_HEALTH_CHECK_PERIOD", "30s")
+
+		cfg := loadPoolConfig()
+		want := PoolConfig{
+			MaxConns:          25,
+			MinConns:          2,
+			MaxConnLifetime:   2 * time.Hour,
+			MaxConnIdleTime:   5 * time.Minute,
+			HealthCheckPeriod: 30 * time.Second,
+		}
+		if cfg != want {
+			t.Errorf("Expected pool config %+v, got %+v", want, cfg)
+		}
+	})
+}
+
+func TestLoadReplicaConfig(t *testing.T) {
+	cleanup := setupTestEnv()
+	defer cleanup()
+
+	os.Setenv("CENTSIBLE_DB_PORT", "5432")
+	os.Setenv("CENTSIBLE_DB_USERNAME", "primaryuser")
+	os.Setenv("CENTSIBLE_DB_PASSWORD", "primarypass")
+	for _, key := range []string{
+		"CENTSIBLE_DB_REPLICA_HOST", "CENTSIBLE_DB_REPLICA_PORT",
+		"CENTSIBLE_DB_REPLICA_USERNAME", "CENTSIBLE_DB_REPLICA_PASSWORD",
+	} {
+		os.Unsetenv(key)
+	}
+
+	t.Run("No replica", func(t *testing.T) {
+		if cfg := loadReplicaConfig(); cfg != nil {
+			t.Errorf("Expected no replica config, got %+v", cfg)
+		}
+	})
+
+	t.Run("Defaults to the primary's settings", func(t *testing.T) {
+		os.Setenv("CENTSIBLE_DB_REPLICA_HOST", "replica")
+
+		cfg := loadReplicaConfig()
+		want := ReplicaConfig{Host: "replica", Port: "5432", Username: "primaryuser", Password: "primarypass"}
+		if cfg == nil || *cfg != want {
+			t.Errorf("Expected replica config %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("From environment", func(t *testing.T) {
+		os.Setenv("CENTSIBLE_DB_REPLICA_HOST", "replica")
+		os.Setenv("CENTSIBLE_DB_REPLICA_PORT", "5433")
+		os.Setenv("CENTSIBLE_DB_REPLICA_USERNAME", "reader")
+		os.Setenv("CENTSIBLE_DB_REPLICA_PASSWORD", "readerpass")
+
+		cfg := loadReplicaConfig()
+		want := ReplicaConfig{Host: "replica", Port: "5433", Username: "reader", Password: "readerpass"}
+		if cfg == nil || *cfg != want {
+			t.Errorf("Expected replica config %+v, got %+v", want, cfg)
+		}
+	})
+}
+
 func TestLoadPort(t *testing.T) {
 	cleanup := setupTestEnv()
 	defer cleanup()
@@ -5,13 +5,14 @@ import (
 	"embed"
 	"fmt"
 	"log"
+	"strconv"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/jorge-dev/centsible/internal/config"
 )
@@ -25,16 +26,22 @@ type Service interface {
 	// The keys and values in the map are service-specific.
 	Health() map[string]string
 
-	// Close terminates the database connection.
-	// It returns an error if the connection cannot be closed.
+	// Close terminates every connection of the pool.
+	// It returns an error if the connections cannot be closed.
 	Close(ctx context.Context) error
 
-	// GetConnection returns the underlying database connection.
-	GetConnection() *pgx.Conn
+	// GetPool returns the underlying connection pool, which is safe for
+	// concurrent use.
+	GetPool() *pgxpool.Pool
+
+	// GetReplicaPool returns the connection pool of the read replica, or nil
+	// when none is configured. See NewRepository for what is read from it.
+	GetReplicaPool() *pgxpool.Pool
 }
 
 type dbService struct {
-	conn *pgx.Conn
+	pool    *pgxpool.Pool
+	replica *pgxpool.Pool
 }
 
 var dbInstance *dbService
@@ -47,27 +54,32 @@ func New(ctx context.Context) Service {
 
 	cfg := config.Get()
 
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&search_path=%s",
-		cfg.Database.Username,
-		cfg.Database.Password,
-		cfg.Database.Host,
-		cfg.Database.Port,
-		cfg.Database.Database,
-		cfg.Database.Schema,
-	)
-
-	connection, err := pgx.Connect(ctx, connStr)
+	connStr := connString(cfg.Database, cfg.Database.Host, cfg.Database.Port, cfg.Database.Username, cfg.Database.Password)
+	pool, err := newPool(ctx, connStr, cfg.Database.Pool)
 	if err != nil {
 		log.Fatalf("unable to connect to database: %v", err)
 	}
 
 	// Test the connection
-	if err := connection.Ping(ctx); err != nil {
+	if err := pool.Ping(ctx); err != nil {
 		log.Fatalf("unable to connect to database: %v", err)
 	}
 
 	dbInstance = &dbService{
-		conn: connection,
+		pool: pool,
+	}
+
+	// A replica that is down is only logged: reads fall back to the primary
+	// until it is back
+	if replica := cfg.Database.Replica; replica != nil {
+		replicaConnStr := connString(cfg.Database, replica.Host, replica.Port, replica.Username, replica.Password)
+		dbInstance.replica, err = newPool(ctx, replicaConnStr, cfg.Database.Pool)
+		if err != nil {
+			log.Fatalf("invalid read replica configuration: %v", err)
+		}
+		if err := dbInstance.replica.Ping(ctx); err != nil {
+			log.Printf("Warning: read replica is unavailable, reading from the primary: %v", err)
+		}
 	}
 
 	// Only run migrations if flag is set
@@ -80,6 +92,33 @@ func New(ctx context.Context) Service {
 	return dbInstance
 }
 
+func connString(db config.DatabaseConfig, host, port, username, password string) string {
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&search_path=%s",
+		username,
+		password,
+		host,
+		port,
+		db.Database,
+		db.Schema,
+	)
+}
+
+// newPool creates a connection pool sized by cfg. Connections are made as
+// they are needed, so it does not fail when the database is down.
+func newPool(ctx context.Context, connStr string, cfg config.PoolConfig) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, err
+	}
+	poolConfig.MaxConns = cfg.MaxConns
+	poolConfig.MinConns = cfg.MinConns
+	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+
+	return pgxpool.NewWithConfig(ctx, poolConfig)
+}
+
 // Add this helper function
 func runMigrations(connectionString string) error {
 	source, err := iofs.New(migrations, "migrations")
@@ -106,15 +145,22 @@ func runMigrations(connectionString string) error {
 }
 
 // Health checks the health of the database connection by pinging the database.
-// It returns a map with keys indicating various health statistics.
+// It returns a map with keys indicating various health statistics, including
+// those of the connection pool. With a read replica, replica_status tells
+// whether it is up; the primary's status is unaffected, as reads fall back to
+// it.
 func (s *dbService) Health() map[string]string {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
-	stats := make(map[string]string)
+	stats := poolStats(s.pool.Stat())
+
+	if s.replica != nil {
+		stats["replica_status"] = replicaStatus(s.replica)
+	}
 
 	// Ping the database
-	err := s.conn.Ping(ctx)
+	err := s.pool.Ping(ctx)
 	if err != nil {
 		stats["status"] = "down"
 		stats["message"] = "The database connection is unhealthy."
@@ -124,12 +170,13 @@ func (s *dbService) Health() map[string]string {
 	}
 
 	// try to query the database
-	_, err = s.conn.Exec(ctx, "SELECT 1")
+	_, err = s.pool.Exec(ctx, "SELECT 1")
 	if err != nil {
 		log.Printf("db not responding after query: %v", err)
 		stats["status"] = "degraded"
 		stats["message"] = "The database connection is unhealthy."
 		stats["error"] = fmt.Sprintf("Cant connect to database: %v", err)
+		return stats
 	}
 
 	// Database is up, add a basic health message
@@ -139,16 +186,55 @@ func (s *dbService) Health() map[string]string {
 	return stats
 }
 
-// Close closes the database connection.
-// It logs a message indicating the disconnection from the specific database.
-// If the connection is successfully closed, it returns nil.
-// If an error occurs while closing the connection, it returns the error.
+// replicaStatus pings the replica on its own deadline, so a replica that does
+// not answer leaves the primary its full time
+func replicaStatus(replica *pgxpool.Pool) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	if err := replica.Ping(ctx); err != nil {
+		log.Printf("read replica down: %v", err)
+		return "down"
+	}
+	return "up"
+}
+
+// poolStats reports the state of the connection pool. Wait counts are of
+// acquires that found no idle connection and had to wait for one.
+func poolStats(stat *pgxpool.Stat) map[string]string {
+	return map[string]string{
+		"pool_max_conns":               strconv.Itoa(int(stat.MaxConns())),
+		"pool_total_conns":             strconv.Itoa(int(stat.TotalConns())),
+		"pool_acquired_conns":          strconv.Itoa(int(stat.AcquiredConns())),
+		"pool_idle_conns":              strconv.Itoa(int(stat.IdleConns())),
+		"pool_constructing_conns":      strconv.Itoa(int(stat.ConstructingConns())),
+		"pool_acquire_count":           strconv.FormatInt(stat.AcquireCount(), 10),
+		"pool_acquire_duration":        stat.AcquireDuration().String(),
+		"pool_wait_count":              strconv.FormatInt(stat.EmptyAcquireCount(), 10),
+		"pool_canceled_acquire_count":  strconv.FormatInt(stat.CanceledAcquireCount(), 10),
+		"pool_new_conns_count":         strconv.FormatInt(stat.NewConnsCount(), 10),
+		"pool_max_lifetime_destroyed":  strconv.FormatInt(stat.MaxLifetimeDestroyCount(), 10),
+		"pool_max_idle_time_destroyed": strconv.FormatInt(stat.MaxIdleDestroyCount(), 10),
+	}
+}
+
+// Close closes every connection of the pool, waiting for those in use to be
+// released. It logs a message indicating the disconnection from the specific
+// database.
 func (s *dbService) Close(ctx context.Context) error {
 	cfg := config.Get()
+	if s.replica != nil {
+		s.replica.Close()
+	}
+	s.pool.Close()
 	log.Printf("Disconnected from database: %s", cfg.Database.Database)
-	return s.conn.Close(ctx)
+	return nil
+}
+
+func (s *dbService) GetPool() *pgxpool.Pool {
+	return s.pool
 }
 
-func (s *dbService) GetConnection() *pgx.Conn {
-	return s.conn
+func (s *dbService) GetReplicaPool() *pgxpool.Pool {
+	return s.replica
 }
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS revoked_token_families;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS user_sessions;
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS user_sessions;
+
+CREATE TABLE user_sessions (
+    user_id UUID PRIMARY KEY,
+    last_activity TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_sessions_last_activity ON user_sessions (last_activity);
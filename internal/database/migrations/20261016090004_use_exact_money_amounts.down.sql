@@ -0,0 +1,3 @@
+ALTER TABLE expenses ALTER COLUMN amount TYPE DOUBLE PRECISION;
+ALTER TABLE income ALTER COLUMN amount TYPE DOUBLE PRECISION;
+ALTER TABLE budgets ALTER COLUMN amount TYPE DOUBLE PRECISION;
@@ -0,0 +1,24 @@
+-- Amounts move from DOUBLE PRECISION to exact decimals. Four decimal places cover
+-- the largest ISO 4217 exponent; the application keeps each amount within the
+-- exponent of its own currency, so existing values are rounded the same way.
+CREATE FUNCTION pg_temp.currency_exponent(code VARCHAR) RETURNS INTEGER AS $$
+    SELECT CASE
+        WHEN code IN ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG',
+                      'RWF', 'UGX', 'UYI', 'VND', 'VUV', 'XAF', 'XOF', 'XPF') THEN 0
+        WHEN code IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 3
+        WHEN code IN ('CLF', 'UYW') THEN 4
+        ELSE 2
+    END
+$$ LANGUAGE SQL IMMUTABLE;
+
+ALTER TABLE expenses
+    ALTER COLUMN amount TYPE NUMERIC(18, 4)
+    USING ROUND(amount::NUMERIC, pg_temp.currency_exponent(currency));
+
+ALTER TABLE income
+    ALTER COLUMN amount TYPE NUMERIC(18, 4)
+    USING ROUND(amount::NUMERIC, pg_temp.currency_exponent(currency));
+
+ALTER TABLE budgets
+    ALTER COLUMN amount TYPE NUMERIC(18, 4)
+    USING ROUND(amount::NUMERIC, pg_temp.currency_exponent(currency));
@@ -0,0 +1,3 @@
+DROP FUNCTION IF EXISTS exchange_rate(VARCHAR, VARCHAR, DATE);
+DROP TABLE IF EXISTS exchange_rates;
+ALTER TABLE users DROP COLUMN IF EXISTS base_currency;
@@ -0,0 +1,54 @@
+-- One unit of base_currency buys rate units of quote_currency from date onwards,
+-- until a later rate for the same pair takes over.
+CREATE TABLE exchange_rates (
+    base_currency VARCHAR(3) NOT NULL,
+    quote_currency VARCHAR(3) NOT NULL,
+    date DATE NOT NULL,
+    rate NUMERIC(24, 12) NOT NULL CHECK (rate > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT NULL,
+    PRIMARY KEY (base_currency, quote_currency, date),
+    CONSTRAINT distinct_currencies CHECK (base_currency <> quote_currency)
+);
+
+CREATE INDEX idx_exchange_rates_quote ON exchange_rates (quote_currency, date);
+
+-- Currency that summaries and totals are converted into
+ALTER TABLE users ADD COLUMN base_currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+
+-- exchange_rate returns how many units of to_currency one unit of from_currency
+-- bought on on_date, using the latest rate on or before that day. A pair can be
+-- stored in either direction, or be derived through a common base currency
+-- (EUR -> CAD from USD -> EUR and USD -> CAD). Returns NULL when no rate is known.
+CREATE FUNCTION exchange_rate(from_currency VARCHAR, to_currency VARCHAR, on_date DATE)
+RETURNS NUMERIC AS $$
+    SELECT CASE WHEN from_currency = to_currency THEN 1::NUMERIC ELSE COALESCE(
+        (
+            SELECT rate FROM exchange_rates
+            WHERE base_currency = from_currency AND quote_currency = to_currency AND date <= on_date
+            ORDER BY date DESC
+            LIMIT 1
+        ),
+        (
+            SELECT 1 / rate FROM exchange_rates
+            WHERE base_currency = to_currency AND quote_currency = from_currency AND date <= on_date
+            ORDER BY date DESC
+            LIMIT 1
+        ),
+        (
+            SELECT t.rate / f.rate
+            FROM (
+                SELECT DISTINCT ON (base_currency) base_currency, rate FROM exchange_rates
+                WHERE quote_currency = from_currency AND date <= on_date
+                ORDER BY base_currency, date DESC
+            ) f
+            JOIN (
+                SELECT DISTINCT ON (base_currency) base_currency, rate FROM exchange_rates
+                WHERE quote_currency = to_currency AND date <= on_date
+                ORDER BY base_currency, date DESC
+            ) t USING (base_currency)
+            ORDER BY base_currency
+            LIMIT 1
+        )
+    ) END
+$$ LANGUAGE SQL STABLE;
@@ -0,0 +1,3 @@
+-- Budget usage sums a user's expenses in one category between two dates
+CREATE INDEX idx_expenses_user_category_date ON expenses (user_id, category_id, date)
+WHERE deleted_at IS NULL;
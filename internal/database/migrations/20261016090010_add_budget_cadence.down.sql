@@ -0,0 +1,17 @@
+ALTER TABLE budgets DROP CONSTRAINT IF EXISTS valid_budget_schedule;
+
+-- Recurring budgets without an end date keep their first period as their window
+UPDATE budgets
+SET end_date = start_date + CASE cadence
+        WHEN 'weekly' THEN INTERVAL '7 days'
+        WHEN 'monthly' THEN INTERVAL '1 month'
+        WHEN 'quarterly' THEN INTERVAL '3 months'
+        ELSE INTERVAL '1 year'
+    END
+WHERE end_date IS NULL;
+
+ALTER TABLE budgets ALTER COLUMN end_date SET NOT NULL;
+
+ALTER TABLE budgets
+    DROP COLUMN IF EXISTS rollover,
+    DROP COLUMN IF EXISTS cadence;
@@ -0,0 +1,25 @@
+-- Recurring budgets repeat at a cadence from their start date and can carry
+-- unspent money into the next period. Their end date becomes optional and,
+-- when set, is when the budget stops repeating.
+ALTER TABLE budgets
+    ADD COLUMN cadence VARCHAR(20) CHECK (cadence IN ('weekly', 'monthly', 'quarterly', 'yearly')),
+    ADD COLUMN rollover BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE budgets ALTER COLUMN end_date DROP NOT NULL;
+
+-- Existing recurring budgets repeat at the cadence closest to the window they
+-- were created with instead of expiring at its end
+UPDATE budgets
+SET cadence = CASE
+        WHEN end_date - start_date <= INTERVAL '7 days' THEN 'weekly'
+        WHEN end_date - start_date <= INTERVAL '31 days' THEN 'monthly'
+        WHEN end_date - start_date <= INTERVAL '92 days' THEN 'quarterly'
+        ELSE 'yearly'
+    END,
+    end_date = NULL
+WHERE type = 'recurring';
+
+ALTER TABLE budgets ADD CONSTRAINT valid_budget_schedule CHECK (
+    (type = 'recurring' AND cadence IS NOT NULL)
+    OR (type = 'one-time' AND cadence IS NULL AND NOT rollover AND end_date IS NOT NULL)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS recurring_transaction_occurrences;
+DROP TABLE IF EXISTS recurring_transactions;
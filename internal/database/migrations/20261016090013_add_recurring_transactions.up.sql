@@ -0,0 +1,50 @@
+-- A recurring transaction is a template for income or an expense that repeats
+-- on a schedule modelled on an iCalendar RRULE: it occurs at start_date and then
+-- every interval_count days, weeks, months or years, counting from start_date.
+-- It ends after occurrence_count occurrences, with the last occurrence on or
+-- before end_date, or never when neither is set.
+CREATE TABLE recurring_transactions (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    user_id UUID NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('expense', 'income')),
+    amount NUMERIC(18, 4) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL,
+    category_id UUID DEFAULT NULL,
+    source VARCHAR(255) DEFAULT NULL,
+    description VARCHAR(510) NOT NULL,
+    frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly', 'yearly')),
+    interval_count INTEGER NOT NULL DEFAULT 1 CHECK (interval_count > 0),
+    start_date TIMESTAMPTZ NOT NULL,
+    end_date TIMESTAMPTZ DEFAULT NULL,
+    occurrence_count INTEGER DEFAULT NULL CHECK (occurrence_count > 0),
+    -- Where the worker picks up: the next occurrence still to be generated,
+    -- NULL once the schedule has ended, and the last one it generated
+    next_occurrence TIMESTAMPTZ DEFAULT NULL,
+    last_occurrence TIMESTAMPTZ DEFAULT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT NULL,
+    deleted_at TIMESTAMPTZ DEFAULT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE RESTRICT,
+    CONSTRAINT valid_recurring_template CHECK (
+        (type = 'expense' AND category_id IS NOT NULL AND source IS NULL) OR
+        (type = 'income' AND category_id IS NULL AND source IS NOT NULL)
+    ),
+    CONSTRAINT single_end_condition CHECK (end_date IS NULL OR occurrence_count IS NULL)
+);
+
+CREATE INDEX idx_recurring_transactions_user_id ON recurring_transactions (user_id);
+CREATE INDEX idx_recurring_transactions_due ON recurring_transactions (next_occurrence)
+    WHERE deleted_at IS NULL AND next_occurrence IS NOT NULL;
+
+-- One row per occurrence ever generated, so that an occurrence is never
+-- generated twice, even after the income or expense it created is deleted.
+-- transaction_id is the ID given to that income or expense.
+CREATE TABLE recurring_transaction_occurrences (
+    recurring_transaction_id UUID NOT NULL,
+    occurrence_date TIMESTAMPTZ NOT NULL,
+    transaction_id UUID NOT NULL DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (recurring_transaction_id, occurrence_date),
+    FOREIGN KEY (recurring_transaction_id) REFERENCES recurring_transactions(id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_income_fingerprint;
+DROP INDEX IF EXISTS idx_expenses_fingerprint;
+DROP FUNCTION IF EXISTS transaction_fingerprint(NUMERIC, VARCHAR, TIMESTAMPTZ, TEXT, TEXT);
+ALTER TABLE income DROP COLUMN IF EXISTS external_id;
+ALTER TABLE expenses DROP COLUMN IF EXISTS external_id;
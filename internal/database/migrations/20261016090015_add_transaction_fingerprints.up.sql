@@ -0,0 +1,32 @@
+-- An ID the client or the bank gave the transaction, such as the FITID of an
+-- OFX file. It is never reused, even once the transaction is deleted, so that
+-- importing the same statement again cannot bring deleted transactions back.
+ALTER TABLE expenses ADD COLUMN external_id VARCHAR(255) DEFAULT NULL;
+ALTER TABLE income ADD COLUMN external_id VARCHAR(255) DEFAULT NULL;
+
+CREATE UNIQUE INDEX idx_expenses_external_id ON expenses (user_id, external_id);
+CREATE UNIQUE INDEX idx_income_external_id ON income (user_id, external_id);
+
+-- Transactions with the same fingerprint are likely duplicates: the same
+-- amount and currency on the same UTC day, with the same description and
+-- category or source, ignoring case and surrounding spaces. Every piece is
+-- turned into text with immutable functions only, so that it can be indexed;
+-- the date in particular is written as a day number because casting a DATE to
+-- text depends on the DateStyle setting.
+CREATE FUNCTION transaction_fingerprint(amount NUMERIC, currency VARCHAR, date TIMESTAMPTZ, description TEXT, party TEXT)
+RETURNS TEXT AS $$
+    SELECT md5(
+        trim_scale(amount)::TEXT || '|' ||
+        upper(currency) || '|' ||
+        ((date AT TIME ZONE 'UTC')::DATE - DATE '2000-01-01')::TEXT || '|' ||
+        lower(btrim(description)) || '|' ||
+        lower(btrim(party))
+    )
+$$ LANGUAGE SQL IMMUTABLE PARALLEL SAFE;
+
+CREATE INDEX idx_expenses_fingerprint ON expenses
+    (user_id, transaction_fingerprint(amount, currency, date, description, category_id::TEXT))
+    WHERE deleted_at IS NULL;
+CREATE INDEX idx_income_fingerprint ON income
+    (user_id, transaction_fingerprint(amount, currency, date, description, source))
+    WHERE deleted_at IS NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_income_search;
+DROP INDEX IF EXISTS idx_expenses_search;
+DROP FUNCTION IF EXISTS income_search_vector(TEXT, TEXT);
+DROP FUNCTION IF EXISTS expense_search_vector(TEXT);
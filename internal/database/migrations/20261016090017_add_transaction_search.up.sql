@@ -0,0 +1,18 @@
+-- Full-text search over what people remember a transaction by: its
+-- description and, for income, its source. Income sources are weighted above
+-- descriptions so that a match on who paid ranks first.
+--
+-- The vectors are not stored in columns, which every SELECT * would read, but
+-- in the indexes on these functions. Searches must call them the same way to
+-- use the indexes.
+CREATE FUNCTION expense_search_vector(description TEXT) RETURNS TSVECTOR AS $$
+    SELECT to_tsvector('english', coalesce(description, ''))
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE FUNCTION income_search_vector(source TEXT, description TEXT) RETURNS TSVECTOR AS $$
+    SELECT setweight(to_tsvector('english', coalesce(source, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B')
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE INDEX idx_expenses_search ON expenses USING GIN (expense_search_vector(description));
+CREATE INDEX idx_income_search ON income USING GIN (income_search_vector(source, description));
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS income_tags;
+DROP TABLE IF EXISTS expense_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,30 @@
+-- Tags label expenses and income across categories, such as "reimbursable" or
+-- "tax-deductible". A transaction has any number of them. Names are unique
+-- per user ignoring case, among tags that have not been deleted.
+CREATE TABLE tags (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    user_id UUID NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT NULL,
+    deleted_at TIMESTAMPTZ DEFAULT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_tags_user_name ON tags (user_id, LOWER(name)) WHERE deleted_at IS NULL;
+
+CREATE TABLE expense_tags (
+    expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (expense_id, tag_id)
+);
+
+CREATE TABLE income_tags (
+    income_id UUID NOT NULL REFERENCES income(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (income_id, tag_id)
+);
+
+-- For filtering and totalling by tag
+CREATE INDEX idx_expense_tags_tag_id ON expense_tags (tag_id);
+CREATE INDEX idx_income_tags_tag_id ON income_tags (tag_id);
@@ -0,0 +1,67 @@
+-- Categories nest, such as "Food > Groceries", so that spending rolls up into
+-- the categories above it. A category with no parent is top-level.
+ALTER TABLE categories ADD COLUMN parent_id UUID DEFAULT NULL
+    REFERENCES categories(id) ON DELETE SET NULL;
+ALTER TABLE categories ADD CONSTRAINT categories_parent_not_self CHECK (parent_id <> id);
+
+CREATE INDEX idx_categories_parent_id ON categories (parent_id);
+
+-- A category's parent must belong to the same user, and a category cannot be
+-- moved under itself or any of its subcategories. Parent changes are
+-- serialized per user so that two concurrent moves cannot close a loop.
+CREATE FUNCTION check_category_parent() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.parent_id IS NULL THEN
+        RETURN NEW;
+    END IF;
+
+    PERFORM pg_advisory_xact_lock(hashtext('categories:' || NEW.user_id::TEXT));
+
+    IF NOT EXISTS (SELECT 1 FROM categories WHERE id = NEW.parent_id AND user_id = NEW.user_id) THEN
+        RAISE EXCEPTION 'parent category % does not belong to the user', NEW.parent_id
+            USING ERRCODE = 'foreign_key_violation';
+    END IF;
+
+    IF EXISTS (
+        WITH RECURSIVE ancestors AS (
+            SELECT c.id, c.parent_id FROM categories c WHERE c.id = NEW.parent_id
+            UNION
+            SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id
+        )
+        SELECT 1 FROM ancestors WHERE id = NEW.id
+    ) THEN
+        RAISE EXCEPTION 'category % cannot be nested under itself', NEW.id
+            USING ERRCODE = 'check_violation';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER categories_check_parent
+    BEFORE INSERT OR UPDATE OF parent_id ON categories
+    FOR EACH ROW EXECUTE FUNCTION check_category_parent();
+
+-- category_ancestors pairs each of a user's categories with itself and with
+-- every category above it. Totals over a category's subtree join expenses on
+-- category_id and group by ancestor_id; is_root marks the top-level ancestor
+-- that summaries roll up into. A deleted category ends the chain above it.
+CREATE FUNCTION category_ancestors(owner_id UUID)
+RETURNS TABLE (category_id UUID, ancestor_id UUID, is_root BOOLEAN) AS $$
+    WITH RECURSIVE tree AS (
+        SELECT c.id AS category_id, c.id AS ancestor_id, c.parent_id
+        FROM categories c
+        WHERE c.user_id = owner_id
+        UNION
+        SELECT t.category_id, p.id, p.parent_id
+        FROM tree t
+        JOIN categories p ON p.id = t.parent_id AND p.deleted_at IS NULL
+    )
+    SELECT
+        t.category_id,
+        t.ancestor_id,
+        NOT EXISTS (
+            SELECT 1 FROM categories p WHERE p.id = t.parent_id AND p.deleted_at IS NULL
+        )
+    FROM tree t
+$$ LANGUAGE SQL STABLE;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS category_template_entries;
+DROP FUNCTION IF EXISTS check_category_template_parent();
+DROP TABLE IF EXISTS category_templates;
@@ -0,0 +1,120 @@
+-- Category templates are ready-made sets of categories, one per locale or
+-- profile, that new users start with and can apply again later. The template
+-- marked is_default is the one given to users of its locale. Templates nest
+-- two levels deep: an entry's parent is a top-level entry of the same template.
+CREATE TABLE category_templates (
+    key VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    locale VARCHAR(10) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE UNIQUE INDEX idx_category_templates_locale_default ON category_templates (locale) WHERE is_default;
+
+CREATE TABLE category_template_entries (
+    template_key VARCHAR(50) NOT NULL REFERENCES category_templates(key) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    parent_name VARCHAR(255) DEFAULT NULL,
+    position INT NOT NULL,
+    PRIMARY KEY (template_key, name),
+    FOREIGN KEY (template_key, parent_name) REFERENCES category_template_entries(template_key, name)
+);
+
+-- Keeps templates two levels deep, so that applying one can create every
+-- parent before its children
+CREATE FUNCTION check_category_template_parent() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.parent_name IS NOT NULL AND EXISTS (
+        SELECT 1 FROM category_template_entries
+        WHERE template_key = NEW.template_key
+            AND ((name = NEW.parent_name AND parent_name IS NOT NULL) OR parent_name = NEW.name)
+    ) THEN
+        RAISE EXCEPTION 'template entry % would nest more than two levels deep', NEW.name
+            USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER category_template_entries_check_parent
+    BEFORE INSERT OR UPDATE ON category_template_entries
+    FOR EACH ROW EXECUTE FUNCTION check_category_template_parent();
+
+INSERT INTO category_templates (key, name, locale, description, is_default) VALUES
+    ('basic', 'Basic', 'en', 'Everyday spending for most people', TRUE),
+    ('household', 'Household', 'en', 'A family home with children and shared bills', FALSE),
+    ('student', 'Student', 'en', 'Tuition, books and living on a budget', FALSE),
+    ('basico', 'Básico', 'es', 'Gastos cotidianos para la mayoría de las personas', TRUE),
+    ('essentiel', 'Essentiel', 'fr', 'Les dépenses courantes de la plupart des gens', TRUE);
+
+INSERT INTO category_template_entries (template_key, name, parent_name, position) VALUES
+    ('basic', 'Food', NULL, 1),
+    ('basic', 'Groceries', 'Food', 2),
+    ('basic', 'Restaurants', 'Food', 3),
+    ('basic', 'Housing', NULL, 4),
+    ('basic', 'Rent', 'Housing', 5),
+    ('basic', 'Utilities', 'Housing', 6),
+    ('basic', 'Transportation', NULL, 7),
+    ('basic', 'Healthcare', NULL, 8),
+    ('basic', 'Shopping', NULL, 9),
+    ('basic', 'Entertainment', NULL, 10),
+    ('basic', 'Travel', NULL, 11),
+    ('basic', 'Education', NULL, 12),
+
+    ('household', 'Food', NULL, 1),
+    ('household', 'Groceries', 'Food', 2),
+    ('household', 'Restaurants', 'Food', 3),
+    ('household', 'Housing', NULL, 4),
+    ('household', 'Mortgage', 'Housing', 5),
+    ('household', 'Utilities', 'Housing', 6),
+    ('household', 'Home Maintenance', 'Housing', 7),
+    ('household', 'Insurance', 'Housing', 8),
+    ('household', 'Children', NULL, 9),
+    ('household', 'Childcare', 'Children', 10),
+    ('household', 'School', 'Children', 11),
+    ('household', 'Activities', 'Children', 12),
+    ('household', 'Transportation', NULL, 13),
+    ('household', 'Fuel', 'Transportation', 14),
+    ('household', 'Car Maintenance', 'Transportation', 15),
+    ('household', 'Healthcare', NULL, 16),
+    ('household', 'Pets', NULL, 17),
+    ('household', 'Entertainment', NULL, 18),
+    ('household', 'Gifts', NULL, 19),
+
+    ('student', 'Education', NULL, 1),
+    ('student', 'Tuition', 'Education', 2),
+    ('student', 'Books & Supplies', 'Education', 3),
+    ('student', 'Food', NULL, 4),
+    ('student', 'Groceries', 'Food', 5),
+    ('student', 'Eating Out', 'Food', 6),
+    ('student', 'Rent', NULL, 7),
+    ('student', 'Transportation', NULL, 8),
+    ('student', 'Phone & Internet', NULL, 9),
+    ('student', 'Entertainment', NULL, 10),
+
+    ('basico', 'Comida', NULL, 1),
+    ('basico', 'Supermercado', 'Comida', 2),
+    ('basico', 'Restaurantes', 'Comida', 3),
+    ('basico', 'Vivienda', NULL, 4),
+    ('basico', 'Alquiler', 'Vivienda', 5),
+    ('basico', 'Servicios', 'Vivienda', 6),
+    ('basico', 'Transporte', NULL, 7),
+    ('basico', 'Salud', NULL, 8),
+    ('basico', 'Compras', NULL, 9),
+    ('basico', 'Ocio', NULL, 10),
+    ('basico', 'Viajes', NULL, 11),
+    ('basico', 'Educación', NULL, 12),
+
+    ('essentiel', 'Alimentation', NULL, 1),
+    ('essentiel', 'Courses', 'Alimentation', 2),
+    ('essentiel', 'Restaurants', 'Alimentation', 3),
+    ('essentiel', 'Logement', NULL, 4),
+    ('essentiel', 'Loyer', 'Logement', 5),
+    ('essentiel', 'Charges', 'Logement', 6),
+    ('essentiel', 'Transport', NULL, 7),
+    ('essentiel', 'Santé', NULL, 8),
+    ('essentiel', 'Shopping', NULL, 9),
+    ('essentiel', 'Loisirs', NULL, 10),
+    ('essentiel', 'Voyages', NULL, 11),
+    ('essentiel', 'Éducation', NULL, 12);
@@ -0,0 +1,38 @@
+-- A category rule fills in the category and tags of a user's new expenses,
+-- whether entered one at a time or imported from a statement. It matches an
+-- expense whose description matches description_pattern, whose amount lies in
+-- [min_amount, max_amount] and whose currency is currency, and a condition
+-- left NULL always holds. The pattern covers the whole description, ignoring
+-- case, with * standing for any run of characters and ? for any one.
+--
+-- Enabled rules are tried by priority, lowest first, and then by age. The
+-- first one that matches and sets a category decides the category, and every
+-- one that matches adds its tags.
+CREATE TABLE category_rules (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    user_id UUID NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description_pattern VARCHAR(255) DEFAULT NULL,
+    min_amount NUMERIC(18, 4) DEFAULT NULL,
+    max_amount NUMERIC(18, 4) DEFAULT NULL,
+    currency VARCHAR(3) DEFAULT NULL,
+    category_id UUID DEFAULT NULL,
+    tag_ids UUID[] NOT NULL DEFAULT '{}',
+    priority INTEGER NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT NULL,
+    deleted_at TIMESTAMPTZ DEFAULT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE RESTRICT,
+    CONSTRAINT category_rule_condition CHECK (
+        description_pattern IS NOT NULL OR min_amount IS NOT NULL OR
+        max_amount IS NOT NULL OR currency IS NOT NULL
+    ),
+    CONSTRAINT category_rule_action CHECK (category_id IS NOT NULL OR cardinality(tag_ids) > 0),
+    CONSTRAINT category_rule_amount_range CHECK (min_amount IS NULL OR max_amount IS NULL OR min_amount <= max_amount)
+);
+
+CREATE INDEX idx_category_rules_user_id ON category_rules (user_id, priority, created_at)
+    WHERE deleted_at IS NULL;
+CREATE INDEX idx_category_rules_category_id ON category_rules (category_id);
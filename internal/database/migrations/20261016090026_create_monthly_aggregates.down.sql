@@ -0,0 +1,8 @@
+DROP FUNCTION IF EXISTS rebuild_monthly_aggregates(UUID);
+DROP TRIGGER IF EXISTS income_aggregate ON income;
+DROP TRIGGER IF EXISTS expenses_aggregate ON expenses;
+DROP FUNCTION IF EXISTS aggregate_income_changes();
+DROP FUNCTION IF EXISTS aggregate_expense_changes();
+DROP FUNCTION IF EXISTS add_to_monthly_aggregate(UUID, TIMESTAMPTZ, VARCHAR, VARCHAR, UUID, NUMERIC, INTEGER);
+DROP FUNCTION IF EXISTS utc_month(TIMESTAMPTZ);
+DROP TABLE IF EXISTS monthly_aggregates;
@@ -0,0 +1,117 @@
+-- monthly_aggregates totals each user's income, and expenses per category, by
+-- calendar month (in UTC) and currency, so that summaries add up a handful of
+-- rows instead of every transaction. Triggers on expenses and income keep it
+-- in step with every write, and soft-deleted transactions are not counted.
+-- rebuild_monthly_aggregates recomputes it from the transactions.
+--
+-- Rows go away when their count drops to zero. There is no foreign key to
+-- users: aggregates are removed with the transactions they total.
+CREATE TABLE monthly_aggregates (
+    user_id UUID NOT NULL,
+    month DATE NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    kind VARCHAR(7) NOT NULL CHECK (kind IN ('income', 'expense')),
+    category_id UUID DEFAULT NULL,
+    total NUMERIC NOT NULL,
+    count INTEGER NOT NULL,
+    CONSTRAINT monthly_aggregates_key UNIQUE NULLS NOT DISTINCT (user_id, month, currency, kind, category_id),
+    -- Expenses are totalled per category, income is not
+    CONSTRAINT monthly_aggregates_category CHECK ((kind = 'expense') = (category_id IS NOT NULL))
+);
+
+-- utc_month is the month a transaction is totalled under
+CREATE FUNCTION utc_month(ts TIMESTAMPTZ) RETURNS DATE AS $$
+    SELECT DATE_TRUNC('month', ts AT TIME ZONE 'UTC')::DATE
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE FUNCTION add_to_monthly_aggregate(
+    owner_id UUID, ts TIMESTAMPTZ, code VARCHAR, total_kind VARCHAR, category UUID, amount NUMERIC, delta INTEGER
+) RETURNS VOID AS $$
+    INSERT INTO monthly_aggregates AS a (user_id, month, currency, kind, category_id, total, count)
+    VALUES (owner_id, utc_month(ts), code, total_kind, category, amount, delta)
+    ON CONFLICT ON CONSTRAINT monthly_aggregates_key DO UPDATE
+        SET total = a.total + EXCLUDED.total,
+            count = a.count + EXCLUDED.count;
+
+    DELETE FROM monthly_aggregates
+    WHERE user_id = owner_id
+        AND month = utc_month(ts)
+        AND currency = code
+        AND kind = total_kind
+        AND category_id IS NOT DISTINCT FROM category
+        AND count = 0;
+$$ LANGUAGE SQL;
+
+-- An update takes the old row out of its aggregate and adds the new one, so
+-- moving a transaction to another month, currency or category moves its
+-- amount too. Soft-deleting takes it out; restoring puts it back.
+CREATE FUNCTION aggregate_expense_changes() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        IF OLD.deleted_at IS NULL THEN
+            PERFORM add_to_monthly_aggregate(OLD.user_id, OLD.date, OLD.currency, 'expense', OLD.category_id, -OLD.amount, -1);
+        END IF;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        IF NEW.deleted_at IS NULL THEN
+            PERFORM add_to_monthly_aggregate(NEW.user_id, NEW.date, NEW.currency, 'expense', NEW.category_id, NEW.amount, 1);
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION aggregate_income_changes() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        IF OLD.deleted_at IS NULL THEN
+            PERFORM add_to_monthly_aggregate(OLD.user_id, OLD.date, OLD.currency, 'income', NULL, -OLD.amount, -1);
+        END IF;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        IF NEW.deleted_at IS NULL THEN
+            PERFORM add_to_monthly_aggregate(NEW.user_id, NEW.date, NEW.currency, 'income', NULL, NEW.amount, 1);
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER expenses_aggregate
+    AFTER INSERT OR DELETE OR UPDATE OF user_id, amount, currency, category_id, date, deleted_at ON expenses
+    FOR EACH ROW EXECUTE FUNCTION aggregate_expense_changes();
+
+CREATE TRIGGER income_aggregate
+    AFTER INSERT OR DELETE OR UPDATE OF user_id, amount, currency, date, deleted_at ON income
+    FOR EACH ROW EXECUTE FUNCTION aggregate_income_changes();
+
+-- rebuild_monthly_aggregates recomputes the aggregates of one user, or of
+-- every user when owner_id is NULL, returning how many rows it wrote. Writes
+-- to expenses and income wait until the transaction it runs in ends.
+CREATE FUNCTION rebuild_monthly_aggregates(owner_id UUID) RETURNS BIGINT AS $$
+DECLARE
+    written BIGINT;
+BEGIN
+    LOCK TABLE expenses, income IN SHARE MODE;
+
+    DELETE FROM monthly_aggregates WHERE owner_id IS NULL OR user_id = owner_id;
+
+    INSERT INTO monthly_aggregates (user_id, month, currency, kind, category_id, total, count)
+    SELECT user_id, utc_month(date), currency, 'expense', category_id, SUM(amount), COUNT(*)
+    FROM expenses
+    WHERE deleted_at IS NULL
+        AND (owner_id IS NULL OR user_id = owner_id)
+    GROUP BY user_id, utc_month(date), currency, category_id
+    UNION ALL
+    SELECT user_id, utc_month(date), currency, 'income', NULL, SUM(amount), COUNT(*)
+    FROM income
+    WHERE deleted_at IS NULL
+        AND (owner_id IS NULL OR user_id = owner_id)
+    GROUP BY user_id, utc_month(date), currency;
+
+    GET DIAGNOSTICS written = ROW_COUNT;
+    RETURN written;
+END;
+$$ LANGUAGE plpgsql;
+
+SELECT rebuild_monthly_aggregates(NULL);
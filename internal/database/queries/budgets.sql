@@ -1,11 +1,11 @@
 -- name: CreateBudget :one
 INSERT INTO budgets (
     id, user_id, amount, currency, category_id, 
-    type, start_date, end_date, name, created_at, updated_at
+    type, cadence, rollover, start_date, end_date, name, created_at, updated_at
 )
 VALUES (
     $1, $2, $3, $4, $5, 
-    $6, $7, $8, $9, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+    $6, $7, $8, $9, $10, $11, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
 )
 RETURNING *;
 
@@ -18,6 +18,43 @@ SELECT * FROM budgets
 WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY created_at DESC;
 
+-- name: ListBudgetsPage :many
+-- Lists a page of a user's budgets like ListExpensesPage. Budgets are dated
+-- by their start date and searched by name.
+SELECT * FROM budgets
+WHERE user_id = sqlc.arg('user_id')
+    AND deleted_at IS NULL
+    AND (sqlc.narg('min_amount')::NUMERIC IS NULL OR amount >= sqlc.narg('min_amount')::NUMERIC)
+    AND (sqlc.narg('max_amount')::NUMERIC IS NULL OR amount <= sqlc.narg('max_amount')::NUMERIC)
+    AND (sqlc.arg('currency')::VARCHAR = '' OR currency = sqlc.arg('currency')::VARCHAR)
+    AND (sqlc.narg('category_id')::UUID IS NULL OR category_id = sqlc.narg('category_id')::UUID)
+    AND (sqlc.arg('search')::TEXT = '' OR STRPOS(LOWER(name), LOWER(sqlc.arg('search')::TEXT)) > 0)
+    AND (sqlc.narg('start_date')::TIMESTAMPTZ IS NULL OR start_date >= sqlc.narg('start_date')::TIMESTAMPTZ)
+    AND (sqlc.narg('end_date')::TIMESTAMPTZ IS NULL OR start_date <= sqlc.narg('end_date')::TIMESTAMPTZ)
+    AND (sqlc.narg('after_id')::UUID IS NULL OR CASE
+        WHEN sqlc.arg('sort')::TEXT = 'amount' AND sqlc.arg('descending')::BOOLEAN
+            THEN (amount, id) < (sqlc.narg('after_amount')::NUMERIC, sqlc.narg('after_id')::UUID)
+        WHEN sqlc.arg('sort')::TEXT = 'amount'
+            THEN (amount, id) > (sqlc.narg('after_amount')::NUMERIC, sqlc.narg('after_id')::UUID)
+        WHEN sqlc.arg('sort')::TEXT = 'created_at' AND sqlc.arg('descending')::BOOLEAN
+            THEN (created_at, id) < (sqlc.narg('after_time')::TIMESTAMPTZ, sqlc.narg('after_id')::UUID)
+        WHEN sqlc.arg('sort')::TEXT = 'created_at'
+            THEN (created_at, id) > (sqlc.narg('after_time')::TIMESTAMPTZ, sqlc.narg('after_id')::UUID)
+        WHEN sqlc.arg('descending')::BOOLEAN
+            THEN (start_date, id) < (sqlc.narg('after_time')::TIMESTAMPTZ, sqlc.narg('after_id')::UUID)
+        ELSE (start_date, id) > (sqlc.narg('after_time')::TIMESTAMPTZ, sqlc.narg('after_id')::UUID)
+    END)
+ORDER BY
+    CASE WHEN sqlc.arg('sort')::TEXT = 'amount' AND NOT sqlc.arg('descending')::BOOLEAN THEN amount END,
+    CASE WHEN sqlc.arg('sort')::TEXT = 'amount' AND sqlc.arg('descending')::BOOLEAN THEN amount END DESC,
+    CASE WHEN sqlc.arg('sort')::TEXT = 'created_at' AND NOT sqlc.arg('descending')::BOOLEAN THEN created_at END,
+    CASE WHEN sqlc.arg('sort')::TEXT = 'created_at' AND sqlc.arg('descending')::BOOLEAN THEN created_at END DESC,
+    CASE WHEN sqlc.arg('sort')::TEXT NOT IN ('amount', 'created_at') AND NOT sqlc.arg('descending')::BOOLEAN THEN start_date END,
+    CASE WHEN sqlc.arg('sort')::TEXT NOT IN ('amount', 'created_at') AND sqlc.arg('descending')::BOOLEAN THEN start_date END DESC,
+    CASE WHEN NOT sqlc.arg('descending')::BOOLEAN THEN id END,
+    id DESC
+LIMIT sqlc.arg('page_size');
+
 -- name: UpdateBudget :one
 UPDATE budgets 
 SET 
@@ -25,11 +62,13 @@ SET
     currency = $3,
     category_id = $4,
     type = $5,
-    start_date = $6,
-    end_date = $7,
-    name = $8,
+    cadence = $6,
+    rollover = $7,
+    start_date = $8,
+    end_date = $9,
+    name = $10,
     updated_at = CURRENT_TIMESTAMP
-WHERE id = $1 AND user_id = $9 AND deleted_at IS NULL
+WHERE id = $1 AND user_id = $11 AND deleted_at IS NULL
 RETURNING *;
 
 -- name: DeleteBudget :execrows
@@ -53,22 +92,54 @@ WHERE user_id = $1
 ORDER BY start_date DESC;
 
 -- name: GetBudgetUsage :one
+-- Only expenses dated inside the given period count, in the budget's category
+-- or any of its subcategories. They are converted into the budget's currency
+-- at the rate of the day they were made; currencies without a known rate are
+-- left out of spent_amount and listed in missing_rates.
 WITH budget_expenses AS (
-    SELECT COALESCE(SUM(amount), 0)::float8 AS total_spent
-    FROM expenses
-    WHERE user_id = sqlc.arg('user_id')::uuid
-      AND category_id = (SELECT category_id FROM budgets WHERE id = sqlc.arg('budget_id')::uuid)
-      AND deleted_at IS NULL
+    SELECT
+        e.currency,
+        SUM(e.amount) AS amount,
+        SUM(e.amount * r.rate) AS converted_amount,
+        COUNT(*) FILTER (WHERE r.rate IS NULL) AS unconverted_count
+    FROM expenses e
+    JOIN budgets b ON b.user_id = e.user_id
+    CROSS JOIN LATERAL (SELECT exchange_rate(e.currency, b.currency, e.date::DATE) AS rate) r
+    WHERE b.id = sqlc.arg('budget_id')::uuid
+      AND e.user_id = sqlc.arg('user_id')::uuid
+      AND e.category_id IN (
+          SELECT ca.category_id FROM category_ancestors(sqlc.arg('user_id')::uuid) ca WHERE ca.ancestor_id = b.category_id
+      )
+      AND e.deleted_at IS NULL
+      AND e.date >= sqlc.arg('period_start')::timestamptz
+      AND e.date < sqlc.arg('period_end')::timestamptz
+    GROUP BY e.currency
+),
+budget_totals AS (
+    SELECT
+        COALESCE(SUM(converted_amount), 0) AS total_spent,
+        COALESCE(
+            json_agg(json_build_object(
+                'currency', currency,
+                'amount', amount,
+                'converted_amount', converted_amount
+            ) ORDER BY currency),
+            '[]'
+        ) AS spent_by_currency,
+        COALESCE(ARRAY_AGG(currency ORDER BY currency) FILTER (WHERE unconverted_count > 0), '{}') AS missing_rates
+    FROM budget_expenses
 )
 SELECT 
     sqlc.embed(b), -- Embed the entire budget row
-    e.total_spent::float8 AS spent_amount,
+    t.total_spent::numeric AS spent_amount,
     CASE 
-        WHEN b.amount > 0 THEN (e.total_spent / b.amount * 100)::float8
+        WHEN b.amount > 0 THEN (t.total_spent / b.amount * 100)::float8
         ELSE 0.0
-    END AS usage_percentage
+    END AS usage_percentage,
+    t.spent_by_currency::json AS spent_by_currency,
+    t.missing_rates::varchar[] AS missing_rates
 FROM budgets b
-CROSS JOIN budget_expenses e
+CROSS JOIN budget_totals t
 WHERE b.id = sqlc.arg('budget_id')::uuid
   AND b.user_id = sqlc.arg('user_id')::uuid
   AND b.deleted_at IS NULL;
@@ -87,33 +158,31 @@ WHERE user_id = $1
     AND deleted_at IS NULL
 ORDER BY start_date ASC;
 
--- name: GetBudgetsNearLimit :many
-SELECT 
-    sqlc.embed(b), -- Embed the entire budget row
-    COALESCE(spent_data.spent_amount, 0)::float8 AS spent_amount,
-    CASE 
-        WHEN b.amount > 0 THEN (COALESCE(spent_data.spent_amount, 0) / b.amount * 100)::float8
-        ELSE 0.0
-    END AS usage_percentage
-FROM budgets b
-LEFT JOIN (
-    SELECT 
-        e.category_id,
-        e.user_id,
-        SUM(e.amount) AS spent_amount
-    FROM expenses e
-    WHERE e.deleted_at IS NULL
-    GROUP BY e.category_id, e.user_id
-) AS spent_data 
-ON b.category_id = spent_data.category_id 
-   AND b.user_id = spent_data.user_id
-WHERE b.user_id = sqlc.arg('user_id')::uuid
-  AND b.deleted_at IS NULL
-  AND b.start_date <= CURRENT_DATE
-  AND (b.end_date >= CURRENT_DATE OR b.end_date IS NULL)
-  AND CASE 
-        WHEN b.amount > 0 THEN (COALESCE(spent_data.spent_amount, 0) / b.amount * 100)
-        ELSE 0 
-      END >= sqlc.arg('threshold')::float8
-ORDER BY usage_percentage DESC;
-
+-- name: GetBudgetSpendingByPeriod :many
+-- Spending in each of the given budget periods, converted into the budget's
+-- currency at the rate of the day each expense was made. A budget counts the
+-- expenses of its category's subcategories too. The arrays are parallel, one
+-- entry per period. Expenses without a known rate are left out.
+SELECT
+    p.budget_id::uuid AS budget_id,
+    p.period_start::timestamptz AS period_start,
+    p.period_end::timestamptz AS period_end,
+    COALESCE(SUM(e.amount * r.rate), 0)::numeric AS spent_amount
+FROM unnest(
+    sqlc.arg('budget_ids')::uuid[],
+    sqlc.arg('period_starts')::timestamptz[],
+    sqlc.arg('period_ends')::timestamptz[]
+) AS p(budget_id, period_start, period_end)
+JOIN budgets b ON b.id = p.budget_id
+    AND b.user_id = sqlc.arg('user_id')::uuid
+    AND b.deleted_at IS NULL
+LEFT JOIN expenses e ON e.user_id = b.user_id
+    AND e.category_id IN (
+        SELECT ca.category_id FROM category_ancestors(sqlc.arg('user_id')::uuid) ca WHERE ca.ancestor_id = b.category_id
+    )
+    AND e.deleted_at IS NULL
+    AND e.date >= p.period_start
+    AND e.date < p.period_end
+LEFT JOIN LATERAL (SELECT exchange_rate(e.currency, b.currency, e.date::DATE) AS rate) r ON TRUE
+GROUP BY p.budget_id, p.period_start, p.period_end
+ORDER BY p.budget_id, p.period_start;
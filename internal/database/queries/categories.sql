@@ -1,6 +1,6 @@
 -- name: CreateCategory :one
-INSERT INTO categories (id, user_id, name, created_at, updated_at)
-VALUES ($1, $2, $3, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+INSERT INTO categories (id, user_id, name, parent_id, created_at, updated_at)
+VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 RETURNING *;
 
 -- name: GetCategoryByID :one
@@ -12,18 +12,142 @@ SELECT * FROM categories
 WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY name ASC;
 
+-- name: ListCategoriesPage :many
+-- Lists a page of a user's categories sorted by name or created_at, with the
+-- ID breaking ties, continuing after the cursor like ListExpensesPage
+SELECT * FROM categories
+WHERE user_id = sqlc.arg('user_id')
+    AND deleted_at IS NULL
+    AND (sqlc.arg('search')::TEXT = '' OR STRPOS(LOWER(name), LOWER(sqlc.arg('search')::TEXT)) > 0)
+    AND (sqlc.narg('after_id')::UUID IS NULL OR CASE
+        WHEN sqlc.arg('sort')::TEXT = 'created_at' AND sqlc.arg('descending')::BOOLEAN
+            THEN (created_at, id) < (sqlc.narg('after_time')::TIMESTAMPTZ, sqlc.narg('after_id')::UUID)
+        WHEN sqlc.arg('sort')::TEXT = 'created_at'
+            THEN (created_at, id) > (sqlc.narg('after_time')::TIMESTAMPTZ, sqlc.narg('after_id')::UUID)
+        WHEN sqlc.arg('descending')::BOOLEAN
+            THEN (name, id) < (sqlc.arg('after_name')::TEXT, sqlc.narg('after_id')::UUID)
+        ELSE (name, id) > (sqlc.arg('after_name')::TEXT, sqlc.narg('after_id')::UUID)
+    END)
+ORDER BY
+    CASE WHEN sqlc.arg('sort')::TEXT = 'created_at' AND NOT sqlc.arg('descending')::BOOLEAN THEN created_at END,
+    CASE WHEN sqlc.arg('sort')::TEXT = 'created_at' AND sqlc.arg('descending')::BOOLEAN THEN created_at END DESC,
+    CASE WHEN sqlc.arg('sort')::TEXT <> 'created_at' AND NOT sqlc.arg('descending')::BOOLEAN THEN name END,
+    CASE WHEN sqlc.arg('sort')::TEXT <> 'created_at' AND sqlc.arg('descending')::BOOLEAN THEN name END DESC,
+    CASE WHEN NOT sqlc.arg('descending')::BOOLEAN THEN id END,
+    id DESC
+LIMIT sqlc.arg('page_size');
+
 -- name: UpdateCategory :one
 UPDATE categories 
 SET 
     name = $2,
+    parent_id = $4,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = $1 AND user_id = $3 AND deleted_at IS NULL
 RETURNING *;
 
 -- name: DeleteCategory :execrows
-UPDATE categories 
+-- Only deletes a category that is not in use; see IsCategoryInUse. Use
+-- MergeCategory to move its records and rules elsewhere first. Its
+-- subcategories move up to its parent in the same statement, so they keep
+-- rolling up into the categories above it and are only moved when it is
+-- deleted.
+WITH deleted AS (
+    SELECT d.id, d.parent_id
+    FROM categories d
+    WHERE d.id = $1 AND d.user_id = $2 AND d.deleted_at IS NULL
+        AND NOT EXISTS (SELECT 1 FROM expenses e WHERE e.category_id = d.id AND e.deleted_at IS NULL)
+        AND NOT EXISTS (SELECT 1 FROM budgets b WHERE b.category_id = d.id AND b.deleted_at IS NULL)
+        AND NOT EXISTS (SELECT 1 FROM recurring_transactions r WHERE r.category_id = d.id AND r.deleted_at IS NULL)
+        AND NOT EXISTS (SELECT 1 FROM category_rules cr WHERE cr.category_id = d.id AND cr.deleted_at IS NULL)
+),
+moved_subcategories AS (
+    UPDATE categories c
+    SET
+        parent_id = d.parent_id,
+        updated_at = CURRENT_TIMESTAMP
+    FROM deleted d
+    WHERE c.parent_id = d.id AND c.deleted_at IS NULL
+)
+UPDATE categories c
 SET deleted_at = CURRENT_TIMESTAMP
-WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+FROM deleted d
+WHERE c.id = d.id;
+
+-- name: IsCategoryInUse :one
+-- Reports whether any expense, budget, recurring transaction or category
+-- rule still uses a category. Its records would drop out of the totals once
+-- it is deleted, and its rules would file expenses under a deleted category.
+SELECT
+    EXISTS(SELECT 1 FROM expenses e WHERE e.category_id = $1 AND e.user_id = $2 AND e.deleted_at IS NULL)
+    OR EXISTS(SELECT 1 FROM budgets b WHERE b.category_id = $1 AND b.user_id = $2 AND b.deleted_at IS NULL)
+    OR EXISTS(SELECT 1 FROM recurring_transactions r WHERE r.category_id = $1 AND r.user_id = $2 AND r.deleted_at IS NULL)
+    OR EXISTS(SELECT 1 FROM category_rules cr WHERE cr.category_id = $1 AND cr.user_id = $2 AND cr.deleted_at IS NULL) AS in_use;
+
+-- name: MergeCategory :execrows
+-- Moves the expenses, budgets, recurring transactions and rules of a
+-- category, deleted ones included, to target_id and deletes the category, all in one
+-- statement so that nothing is left pointing at a deleted category. Its
+-- subcategories go under the target when adopt_subcategories is set, and up
+-- to its parent otherwise; a subcategory that is the target always goes up.
+-- Nothing changes unless both are live categories of the user and differ.
+WITH merge AS (
+    SELECT s.id AS source_id, s.parent_id, t.id AS target_id
+    FROM categories s
+    JOIN categories t ON t.user_id = s.user_id AND t.deleted_at IS NULL
+    WHERE s.id = sqlc.arg('id')
+        AND s.user_id = sqlc.arg('user_id')
+        AND s.deleted_at IS NULL
+        AND t.id = sqlc.arg('target_id')
+        AND t.id <> s.id
+),
+moved_expenses AS (
+    UPDATE expenses e
+    SET category_id = m.target_id, updated_at = CURRENT_TIMESTAMP
+    FROM merge m
+    WHERE e.category_id = m.source_id
+),
+moved_budgets AS (
+    UPDATE budgets b
+    SET category_id = m.target_id, updated_at = CURRENT_TIMESTAMP
+    FROM merge m
+    WHERE b.category_id = m.source_id
+),
+moved_recurring_transactions AS (
+    UPDATE recurring_transactions r
+    SET category_id = m.target_id, updated_at = CURRENT_TIMESTAMP
+    FROM merge m
+    WHERE r.category_id = m.source_id
+),
+moved_category_rules AS (
+    UPDATE category_rules cr
+    SET category_id = m.target_id, updated_at = CURRENT_TIMESTAMP
+    FROM merge m
+    WHERE cr.category_id = m.source_id
+),
+moved_subcategories AS (
+    UPDATE categories c
+    SET
+        parent_id = CASE
+            WHEN sqlc.arg('adopt_subcategories')::BOOLEAN AND c.id <> m.target_id THEN m.target_id
+            ELSE m.parent_id
+        END,
+        updated_at = CURRENT_TIMESTAMP
+    FROM merge m
+    WHERE c.parent_id = m.source_id AND c.deleted_at IS NULL
+)
+UPDATE categories c
+SET deleted_at = CURRENT_TIMESTAMP
+FROM merge m
+WHERE c.id = m.source_id;
+
+-- name: IsCategoryInSubtree :one
+-- Reports whether category_id is root_id or one of its subcategories, at
+-- any depth
+SELECT EXISTS(
+    SELECT 1 FROM category_ancestors(sqlc.arg('user_id'))
+    WHERE category_id = sqlc.arg('category_id') AND ancestor_id = sqlc.arg('root_id')
+);
 
 -- name: CheckCategoryExists :one
 SELECT EXISTS(
@@ -32,36 +156,45 @@ SELECT EXISTS(
 );
 
 -- name: GetCategoryUsage :one
+-- Counts and totals the expenses and budgets of a category together with
+-- those of all of its subcategories
+WITH subtree AS (
+    SELECT category_id FROM category_ancestors($2) WHERE ancestor_id = $1
+)
 SELECT 
     c.id,
     c.name,
     COUNT(DISTINCT e.id) as expense_count,
-    COUNT(DISTINCT b.id) as budget_count,
-    COALESCE(SUM(e.amount), 0) as total_expenses
+    (
+        SELECT COUNT(*) FROM budgets b
+        WHERE b.category_id IN (SELECT category_id FROM subtree)
+            AND b.user_id = c.user_id
+            AND b.deleted_at IS NULL
+    )::bigint as budget_count,
+    COALESCE(SUM(e.amount), 0)::numeric as total_expenses
 FROM categories c
 LEFT JOIN expenses e ON 
-     e.category_id = c.id
+     e.category_id IN (SELECT category_id FROM subtree)
     AND e.user_id = c.user_id 
     AND e.deleted_at IS NULL
-LEFT JOIN budgets b ON 
-     b.category_id = c.id 
-    AND b.user_id = c.user_id 
-    AND b.deleted_at IS NULL
 WHERE c.id = $1 AND c.user_id = $2 AND c.deleted_at IS NULL
 GROUP BY c.id, c.name;
 
 -- name: GetMostUsedCategories :many
+-- Ranks a user's categories by their expenses, counting each category's
+-- subcategories towards it
 SELECT 
     c.name,
     COUNT(e.id) as usage_count,
-    COALESCE(SUM(e.amount), 0) as total_amount
+    COALESCE(SUM(e.amount), 0)::numeric as total_amount
 FROM categories c
+JOIN category_ancestors(sqlc.arg('id')::UUID) ca ON ca.ancestor_id = c.id
 LEFT JOIN expenses e ON 
-    e.category_id = c.id 
+    e.category_id = ca.category_id 
     AND e.user_id = c.user_id 
     AND e.deleted_at IS NULL
 WHERE c.user_id = sqlc.arg('id')::UUID
     AND c.deleted_at IS NULL
-GROUP BY c.name
+GROUP BY c.id, c.name
 ORDER BY usage_count DESC
 LIMIT sqlc.arg('limit')::int;
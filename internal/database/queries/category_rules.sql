@@ -0,0 +1,63 @@
+-- name: CreateCategoryRule :one
+INSERT INTO category_rules (
+    id, user_id, name, description_pattern, min_amount, max_amount, currency,
+    category_id, tag_ids, priority, enabled, created_at, updated_at
+)
+VALUES (
+    sqlc.arg('id'), sqlc.arg('user_id'), sqlc.arg('name'), sqlc.narg('description_pattern'),
+    sqlc.narg('min_amount'), sqlc.narg('max_amount'), sqlc.narg('currency'),
+    sqlc.narg('category_id'), sqlc.arg('tag_ids')::UUID[], sqlc.arg('priority'), sqlc.arg('enabled'),
+    CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+)
+RETURNING *;
+
+-- name: GetCategoryRuleByID :one
+SELECT * FROM category_rules
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: ListCategoryRules :many
+-- Lists a user's rules in the order they are tried
+SELECT * FROM category_rules
+WHERE user_id = $1 AND deleted_at IS NULL
+ORDER BY priority, created_at, id;
+
+-- name: UpdateCategoryRule :one
+UPDATE category_rules
+SET
+    name = sqlc.arg('name'),
+    description_pattern = sqlc.narg('description_pattern'),
+    min_amount = sqlc.narg('min_amount'),
+    max_amount = sqlc.narg('max_amount'),
+    currency = sqlc.narg('currency'),
+    category_id = sqlc.narg('category_id'),
+    tag_ids = sqlc.arg('tag_ids')::UUID[],
+    priority = sqlc.arg('priority'),
+    enabled = sqlc.arg('enabled'),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NULL
+RETURNING *;
+
+-- name: DeleteCategoryRule :execrows
+UPDATE category_rules
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: GetCategoryWordCounts :many
+-- Counts, for each of words, the user's expenses in each live category whose
+-- description has the word. Descriptions are split into lower case words on
+-- anything but letters and digits, as categorize.Words splits them.
+SELECT
+    c.id AS category_id,
+    c.name AS category_name,
+    w.word::TEXT AS word,
+    COUNT(*) AS expense_count
+FROM expenses e
+JOIN categories c ON c.id = e.category_id AND c.deleted_at IS NULL
+CROSS JOIN LATERAL (
+    SELECT DISTINCT word
+    FROM regexp_split_to_table(LOWER(e.description), '[^[:alnum:]]+') AS word
+) w
+WHERE e.user_id = sqlc.arg('user_id')
+    AND e.deleted_at IS NULL
+    AND w.word = ANY(sqlc.arg('words')::TEXT[])
+GROUP BY c.id, c.name, w.word;
@@ -0,0 +1,47 @@
+-- name: ListCategoryTemplates :many
+-- Lists the templates, each locale's default first, with their entries in
+-- order. parent_names is parallel to category_names, with an empty name for
+-- top-level entries.
+SELECT
+    t.key,
+    t.name,
+    t.locale,
+    t.description,
+    t.is_default,
+    ARRAY_AGG(e.name ORDER BY e.position)::varchar[] AS category_names,
+    ARRAY_AGG(COALESCE(e.parent_name, '') ORDER BY e.position)::varchar[] AS parent_names
+FROM category_templates t
+JOIN category_template_entries e ON e.template_key = t.key
+GROUP BY t.key
+ORDER BY t.locale, t.is_default DESC, t.key;
+
+-- name: ApplyCategoryTemplate :many
+-- Adds the categories of a template that the user does not have yet, matching
+-- names ignoring case, and returns them. Subcategories go under the user's
+-- category with their parent's name, so applying a template again adds
+-- nothing. Parents are inserted before their children.
+WITH entries AS (
+    SELECT
+        e.name,
+        e.parent_name,
+        e.position,
+        COALESCE(existing.id, gen_random_uuid()) AS id,
+        existing.id IS NULL AS missing
+    FROM category_template_entries e
+    LEFT JOIN LATERAL (
+        SELECT c.id FROM categories c
+        WHERE c.user_id = sqlc.arg('user_id')
+            AND c.deleted_at IS NULL
+            AND LOWER(c.name) = LOWER(e.name)
+        ORDER BY c.created_at
+        LIMIT 1
+    ) existing ON TRUE
+    WHERE e.template_key = sqlc.arg('template_key')
+)
+INSERT INTO categories (id, user_id, name, parent_id, created_at, updated_at)
+SELECT c.id, sqlc.arg('user_id'), c.name, p.id, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+FROM entries c
+LEFT JOIN entries p ON p.name = c.parent_name
+WHERE c.missing
+ORDER BY c.parent_name IS NOT NULL, c.position
+RETURNING *;
@@ -0,0 +1,32 @@
+-- name: UpsertExchangeRate :one
+INSERT INTO exchange_rates (base_currency, quote_currency, date, rate)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (base_currency, quote_currency, date)
+DO UPDATE SET rate = EXCLUDED.rate, updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: UpsertExchangeRates :execrows
+-- Loads a batch of rates in one statement. The arrays are parallel and must not
+-- contain the same pair and date twice.
+INSERT INTO exchange_rates (base_currency, quote_currency, date, rate)
+SELECT base_currency, quote_currency, date, rate::NUMERIC
+FROM unnest(
+    sqlc.arg('base_currencies')::VARCHAR[],
+    sqlc.arg('quote_currencies')::VARCHAR[],
+    sqlc.arg('dates')::DATE[],
+    sqlc.arg('rates')::TEXT[]
+) AS r(base_currency, quote_currency, date, rate)
+ON CONFLICT (base_currency, quote_currency, date)
+DO UPDATE SET rate = EXCLUDED.rate, updated_at = CURRENT_TIMESTAMP;
+
+-- name: ListExchangeRates :many
+-- Empty currency filters match every currency
+SELECT * FROM exchange_rates
+WHERE (sqlc.arg('base_currency')::VARCHAR = '' OR base_currency = sqlc.arg('base_currency')::VARCHAR)
+  AND (sqlc.arg('quote_currency')::VARCHAR = '' OR quote_currency = sqlc.arg('quote_currency')::VARCHAR)
+ORDER BY base_currency, quote_currency, date DESC
+LIMIT sqlc.arg('limit')::int;
+
+-- name: DeleteExchangeRate :execrows
+DELETE FROM exchange_rates
+WHERE base_currency = $1 AND quote_currency = $2 AND date = $3;
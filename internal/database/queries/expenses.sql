@@ -1,13 +1,25 @@
 -- name: CreateExpense :one
-INSERT INTO expenses (
-    id, user_id, amount, currency, category_id,
-    date, description, created_at, updated_at
+-- Creates an expense tagged with those of tag_ids that are tags of the user,
+-- such as the tags of the category rules it matches
+WITH new_expense AS (
+    INSERT INTO expenses (
+        id, user_id, amount, currency, category_id,
+        date, description, external_id, created_at, updated_at
+    )
+    VALUES (
+        sqlc.arg('id'), sqlc.arg('user_id'), sqlc.arg('amount'), sqlc.arg('currency'), sqlc.arg('category_id'),
+        sqlc.arg('date'), sqlc.arg('description'), sqlc.narg('external_id'), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+    )
+    RETURNING *
+),
+tagged AS (
+    INSERT INTO expense_tags (expense_id, tag_id)
+    SELECT e.id, t.id
+    FROM new_expense e
+    JOIN tags t ON t.user_id = e.user_id AND t.deleted_at IS NULL
+    WHERE t.id = ANY(sqlc.arg('tag_ids')::UUID[])
 )
-VALUES (
-    $1, $2, $3, $4, $5,
-    $6, $7, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
-)
-RETURNING *;
+SELECT * FROM new_expense;
 
 -- name: GetExpenseByID :one
 SELECT * FROM expenses
@@ -18,6 +30,47 @@ SELECT * FROM expenses
 WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY date DESC;
 
+-- name: ListExpensesPage :many
+-- Lists a page of a user's expenses sorted by date, amount or created_at,
+-- with the ID breaking ties. after_id and the sort value of the last expense
+-- of a page continue from it. NULL and empty filters match every expense.
+SELECT * FROM expenses
+WHERE user_id = sqlc.arg('user_id')
+    AND deleted_at IS NULL
+    AND (sqlc.narg('min_amount')::NUMERIC IS NULL OR amount >= sqlc.narg('min_amount')::NUMERIC)
+    AND (sqlc.narg('max_amount')::NUMERIC IS NULL OR amount <= sqlc.narg('max_amount')::NUMERIC)
+    AND (sqlc.arg('currency')::VARCHAR = '' OR currency = sqlc.arg('currency')::VARCHAR)
+    AND (sqlc.narg('category_id')::UUID IS NULL OR category_id = sqlc.narg('category_id')::UUID)
+    AND (sqlc.narg('tag_id')::UUID IS NULL OR id IN (
+        SELECT expense_id FROM expense_tags WHERE tag_id = sqlc.narg('tag_id')::UUID
+    ))
+    AND (sqlc.arg('search')::TEXT = '' OR STRPOS(LOWER(description), LOWER(sqlc.arg('search')::TEXT)) > 0)
+    AND (sqlc.narg('start_date')::TIMESTAMPTZ IS NULL OR date >= sqlc.narg('start_date')::TIMESTAMPTZ)
+    AND (sqlc.narg('end_date')::TIMESTAMPTZ IS NULL OR date <= sqlc.narg('end_date')::TIMESTAMPTZ)
+    AND (sqlc.narg('after_id')::UUID IS NULL OR CASE
+        WHEN sqlc.arg('sort')::TEXT = 'amount' AND sqlc.arg('descending')::BOOLEAN
+            THEN (amount, id) < (sqlc.narg('after_amount')::NUMERIC, sqlc.narg('after_id')::UUID)
+        WHEN sqlc.arg('sort')::TEXT = 'amount'
+            THEN (amount, id) > (sqlc.narg('after_amount')::NUMERIC, sqlc.narg('after_id')::UUID)
+        WHEN sqlc.arg('sort')::TEXT = 'created_at' AND sqlc.arg('descending')::BOOLEAN
+            THEN (created_at, id) < (sqlc.narg('after_time')::TIMESTAMPTZ, sqlc.narg('after_id')::UUID)
+        WHEN sqlc.arg('sort')::TEXT = 'created_at'
+            THEN (created_at, id) > (sqlc.narg('after_time')::TIMESTAMPTZ, sqlc.narg('after_id')::UUID)
+        WHEN sqlc.arg('descending')::BOOLEAN
+            THEN (date, id) < (sqlc.narg('after_time')::TIMESTAMPTZ, sqlc.narg('after_id')::UUID)
+        ELSE (date, id) > (sqlc.narg('after_time')::TIMESTAMPTZ, sqlc.narg('after_id')::UUID)
+    END)
+ORDER BY
+    CASE WHEN sqlc.arg('sort')::TEXT = 'amount' AND NOT sqlc.arg('descending')::BOOLEAN THEN amount END,
+    CASE WHEN sqlc.arg('sort')::TEXT = 'amount' AND sqlc.arg('descending')::BOOLEAN THEN amount END DESC,
+    CASE WHEN sqlc.arg('sort')::TEXT = 'created_at' AND NOT sqlc.arg('descending')::BOOLEAN THEN created_at END,
+    CASE WHEN sqlc.arg('sort')::TEXT = 'created_at' AND sqlc.arg('descending')::BOOLEAN THEN created_at END DESC,
+    CASE WHEN sqlc.arg('sort')::TEXT NOT IN ('amount', 'created_at') AND NOT sqlc.arg('descending')::BOOLEAN THEN date END,
+    CASE WHEN sqlc.arg('sort')::TEXT NOT IN ('amount', 'created_at') AND sqlc.arg('descending')::BOOLEAN THEN date END DESC,
+    CASE WHEN NOT sqlc.arg('descending')::BOOLEAN THEN id END,
+    id DESC
+LIMIT sqlc.arg('page_size');
+
 -- name: UpdateExpense :one
 UPDATE expenses 
 SET 
@@ -51,17 +104,26 @@ WHERE user_id = $1
 ORDER BY date DESC;
 
 -- name: GetExpenseTotalsByCategory :many
+-- Totals each category's expenses together with those of its subcategories,
+-- so a parent's totals include its children's
 SELECT 
-    e.category_id,
+    c.id as category_id,
+    c.parent_id,
     c.name as category_name,
     e.currency,
     COUNT(*)::float8 as transaction_count,
-    SUM(e.amount)::float8 as total_amount
+    SUM(e.amount)::numeric as total_amount,
+    u.base_currency,
+    COALESCE(SUM(e.amount * r.rate), 0)::numeric as converted_amount,
+    COUNT(*) FILTER (WHERE r.rate IS NULL) as unconverted_count
 FROM expenses e
-JOIN categories c ON e.category_id = c.id
+JOIN category_ancestors($1) ca ON ca.category_id = e.category_id
+JOIN categories c ON c.id = ca.ancestor_id
+JOIN users u ON u.id = e.user_id
+CROSS JOIN LATERAL (SELECT exchange_rate(e.currency, u.base_currency, e.date::DATE) AS rate) r
 WHERE e.user_id = $1 
     AND e.deleted_at IS NULL
-GROUP BY e.category_id, c.name, e.currency
+GROUP BY c.id, c.parent_id, c.name, e.currency, u.base_currency
 ORDER BY total_amount DESC;
 
 -- name: GetRecentExpenses :many
@@ -73,10 +135,68 @@ LIMIT $2;
 
 -- name: GetMonthlyExpenseTotal :many
 SELECT 
-    COALESCE(SUM(amount), 0)::float8 as total_amount,
+    COALESCE(SUM(amount), 0)::numeric as total_amount,
     currency as currency
 FROM expenses
 WHERE user_id = $1 
     AND deleted_at IS NULL
     AND DATE_TRUNC('month', date) = DATE_TRUNC('month', sqlc.arg(date)::TIMESTAMPTZ)
 GROUP BY currency;
+
+-- name: FindDuplicateExpenses :many
+-- Finds the expenses a new one would likely duplicate: any with the same
+-- fingerprint, and any that already has its external ID, deleted or not
+SELECT * FROM expenses
+WHERE user_id = sqlc.arg('user_id')
+    AND (
+        (deleted_at IS NULL
+            AND transaction_fingerprint(amount, currency, date, description, category_id::TEXT)
+                = transaction_fingerprint(sqlc.arg('amount')::NUMERIC, sqlc.arg('currency')::VARCHAR, sqlc.arg('date')::TIMESTAMPTZ, sqlc.arg('description')::TEXT, sqlc.arg('category_id')::UUID::TEXT))
+        OR (sqlc.arg('external_id')::VARCHAR <> '' AND external_id = sqlc.arg('external_id')::VARCHAR)
+    )
+ORDER BY created_at;
+
+-- name: MatchImportedExpenses :many
+-- For each expense about to be imported, counts the expenses the user already
+-- has with the same fingerprint and reports whether its external ID is taken.
+-- The arrays are parallel and rows come back in the same order.
+SELECT
+    t.ordinal::INT AS ordinal,
+    transaction_fingerprint(t.amount::NUMERIC, t.currency, t.date, t.description, t.category_id::TEXT)::TEXT AS fingerprint,
+    (
+        SELECT COUNT(*) FROM expenses x
+        WHERE x.user_id = sqlc.arg('user_id')
+            AND x.deleted_at IS NULL
+            AND transaction_fingerprint(x.amount, x.currency, x.date, x.description, x.category_id::TEXT)
+                = transaction_fingerprint(t.amount::NUMERIC, t.currency, t.date, t.description, t.category_id::TEXT)
+    )::BIGINT AS matches,
+    EXISTS (
+        SELECT 1 FROM expenses x
+        WHERE x.user_id = sqlc.arg('user_id') AND t.external_id <> '' AND x.external_id = t.external_id
+    ) AS external_id_taken
+FROM unnest(
+    sqlc.arg('amounts')::TEXT[],
+    sqlc.arg('currencies')::VARCHAR[],
+    sqlc.arg('dates')::TIMESTAMPTZ[],
+    sqlc.arg('descriptions')::VARCHAR[],
+    sqlc.arg('category_ids')::UUID[],
+    sqlc.arg('external_ids')::VARCHAR[]
+) WITH ORDINALITY AS t(amount, currency, date, description, category_id, external_id, ordinal)
+ORDER BY t.ordinal;
+
+-- name: ListDuplicateExpenses :many
+-- Lists every expense that shares its fingerprint with another, along with the
+-- first expense of its group, which the others likely duplicate
+SELECT id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at, external_id, original_id
+FROM (
+    SELECT e.*,
+        first_value(e.id) OVER (PARTITION BY f.fingerprint ORDER BY e.created_at, e.id) AS original_id,
+        COUNT(*) OVER (PARTITION BY f.fingerprint) AS copies
+    FROM expenses e
+    CROSS JOIN LATERAL (
+        SELECT transaction_fingerprint(e.amount, e.currency, e.date, e.description, e.category_id::TEXT) AS fingerprint
+    ) f
+    WHERE e.user_id = $1 AND e.deleted_at IS NULL
+) d
+WHERE copies > 1
+ORDER BY date DESC, original_id, created_at;
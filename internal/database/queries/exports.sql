@@ -0,0 +1,46 @@
+-- name: ExportExpenses :many
+-- Pages through a user's expenses oldest first, so that an export never
+-- holds more than a page in memory. Each page starts after the (date, id) of
+-- the last expense of the one before; an empty currency and a NULL tag_id
+-- match any.
+SELECT * FROM expenses
+WHERE user_id = sqlc.arg('user_id')
+    AND deleted_at IS NULL
+    AND (date, id) > (sqlc.arg('after_date')::TIMESTAMPTZ, sqlc.arg('after_id')::UUID)
+    AND (sqlc.arg('currency')::VARCHAR = '' OR currency = sqlc.arg('currency')::VARCHAR)
+    AND (sqlc.narg('tag_id')::UUID IS NULL OR id IN (
+        SELECT expense_id FROM expense_tags WHERE tag_id = sqlc.narg('tag_id')::UUID
+    ))
+ORDER BY date, id
+LIMIT sqlc.arg('page_size');
+
+-- name: ExportIncome :many
+-- Pages through a user's income like ExportExpenses
+SELECT * FROM income
+WHERE user_id = sqlc.arg('user_id')
+    AND deleted_at IS NULL
+    AND (date, id) > (sqlc.arg('after_date')::TIMESTAMPTZ, sqlc.arg('after_id')::UUID)
+    AND (sqlc.arg('currency')::VARCHAR = '' OR currency = sqlc.arg('currency')::VARCHAR)
+    AND (sqlc.narg('tag_id')::UUID IS NULL OR id IN (
+        SELECT income_id FROM income_tags WHERE tag_id = sqlc.narg('tag_id')::UUID
+    ))
+ORDER BY date, id
+LIMIT sqlc.arg('page_size');
+
+-- name: ListStatementCurrencies :many
+-- Lists the currencies a user has transactions in, with the dates of the first
+-- and last and the balance of income less expenses, one statement's worth each
+SELECT
+    currency,
+    MIN(date)::TIMESTAMPTZ AS first_date,
+    MAX(date)::TIMESTAMPTZ AS last_date,
+    SUM(amount)::NUMERIC AS balance
+FROM (
+    SELECT currency, date, amount FROM income
+    WHERE user_id = $1 AND deleted_at IS NULL
+    UNION ALL
+    SELECT currency, date, -amount FROM expenses
+    WHERE user_id = $1 AND deleted_at IS NULL
+) t
+GROUP BY currency
+ORDER BY currency;
@@ -0,0 +1,43 @@
+-- name: ImportTransactions :one
+-- Saves a batch of imported expenses and income in one statement, so that
+-- either all of them are saved or none are. Each group of arrays is parallel;
+-- an empty external ID is saved as none.
+WITH imported_expenses AS (
+    INSERT INTO expenses (
+        id, user_id, amount, currency, category_id,
+        date, description, external_id, created_at, updated_at
+    )
+    SELECT id, sqlc.arg('user_id')::UUID, amount::NUMERIC, currency, category_id,
+           date, description, NULLIF(external_id, ''), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+    FROM unnest(
+        sqlc.arg('expense_ids')::UUID[],
+        sqlc.arg('expense_amounts')::TEXT[],
+        sqlc.arg('expense_currencies')::VARCHAR[],
+        sqlc.arg('expense_category_ids')::UUID[],
+        sqlc.arg('expense_dates')::TIMESTAMPTZ[],
+        sqlc.arg('expense_descriptions')::VARCHAR[],
+        sqlc.arg('expense_external_ids')::VARCHAR[]
+    ) AS e(id, amount, currency, category_id, date, description, external_id)
+    RETURNING id
+),
+imported_income AS (
+    INSERT INTO income (
+        id, user_id, amount, currency, source,
+        date, description, external_id, created_at, updated_at
+    )
+    SELECT id, sqlc.arg('user_id')::UUID, amount::NUMERIC, currency, source,
+           date, description, NULLIF(external_id, ''), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+    FROM unnest(
+        sqlc.arg('income_ids')::UUID[],
+        sqlc.arg('income_amounts')::TEXT[],
+        sqlc.arg('income_currencies')::VARCHAR[],
+        sqlc.arg('income_sources')::VARCHAR[],
+        sqlc.arg('income_dates')::TIMESTAMPTZ[],
+        sqlc.arg('income_descriptions')::VARCHAR[],
+        sqlc.arg('income_external_ids')::VARCHAR[]
+    ) AS i(id, amount, currency, source, date, description, external_id)
+    RETURNING id
+)
+SELECT
+    (SELECT COUNT(*) FROM imported_expenses)::BIGINT AS expenses,
+    (SELECT COUNT(*) FROM imported_income)::BIGINT AS income;
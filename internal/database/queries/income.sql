@@ -1,11 +1,11 @@
 -- name: CreateIncome :one
 INSERT INTO income (
     id, user_id, amount, currency, source,
-    date, description, created_at, updated_at
+    date, description, external_id, created_at, updated_at
 )
 VALUES (
     $1, $2, $3, $4, $5,
-    $6, $7, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+    $6, $7, $8, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
 )
 RETURNING *;
 
@@ -18,6 +18,46 @@ SELECT * FROM income
 WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY date DESC;
 
+-- name: ListIncomePage :many
+-- Lists a page of a user's income like ListExpensesPage, filtered by source
+-- rather than category
+SELECT * FROM income
+WHERE user_id = sqlc.arg('user_id')
+    AND deleted_at IS NULL
+    AND (sqlc.narg('min_amount')::NUMERIC IS NULL OR amount >= sqlc.narg('min_amount')::NUMERIC)
+    AND (sqlc.narg('max_amount')::NUMERIC IS NULL OR amount <= sqlc.narg('max_amount')::NUMERIC)
+    AND (sqlc.arg('currency')::VARCHAR = '' OR currency = sqlc.arg('currency')::VARCHAR)
+    AND (sqlc.arg('source')::VARCHAR = '' OR LOWER(source) = LOWER(sqlc.arg('source')::VARCHAR))
+    AND (sqlc.narg('tag_id')::UUID IS NULL OR id IN (
+        SELECT income_id FROM income_tags WHERE tag_id = sqlc.narg('tag_id')::UUID
+    ))
+    AND (sqlc.arg('search')::TEXT = '' OR STRPOS(LOWER(description), LOWER(sqlc.arg('search')::TEXT)) > 0)
+    AND (sqlc.narg('start_date')::TIMESTAMPTZ IS NULL OR date >= sqlc.narg('start_date')::TIMESTAMPTZ)
+    AND (sqlc.narg('end_date')::TIMESTAMPTZ IS NULL OR date <= sqlc.narg('end_date')::TIMESTAMPTZ)
+    AND (sqlc.narg('after_id')::UUID IS NULL OR CASE
+        WHEN sqlc.arg('sort')::TEXT = 'amount' AND sqlc.arg('descending')::BOOLEAN
+            THEN (amount, id) < (sqlc.narg('after_amount')::NUMERIC, sqlc.narg('after_id')::UUID)
+        WHEN sqlc.arg('sort')::TEXT = 'amount'
+            THEN (amount, id) > (sqlc.narg('after_amount')::NUMERIC, sqlc.narg('after_id')::UUID)
+        WHEN sqlc.arg('sort')::TEXT = 'created_at' AND sqlc.arg('descending')::BOOLEAN
+            THEN (created_at, id) < (sqlc.narg('after_time')::TIMESTAMPTZ, sqlc.narg('after_id')::UUID)
+        WHEN sqlc.arg('sort')::TEXT = 'created_at'
+            THEN (created_at, id) > (sqlc.narg('after_time')::TIMESTAMPTZ, sqlc.narg('after_id')::UUID)
+        WHEN sqlc.arg('descending')::BOOLEAN
+            THEN (date, id) < (sqlc.narg('after_time')::TIMESTAMPTZ, sqlc.narg('after_id')::UUID)
+        ELSE (date, id) > (sqlc.narg('after_time')::TIMESTAMPTZ, sqlc.narg('after_id')::UUID)
+    END)
+ORDER BY
+    CASE WHEN sqlc.arg('sort')::TEXT = 'amount' AND NOT sqlc.arg('descending')::BOOLEAN THEN amount END,
+    CASE WHEN sqlc.arg('sort')::TEXT = 'amount' AND sqlc.arg('descending')::BOOLEAN THEN amount END DESC,
+    CASE WHEN sqlc.arg('sort')::TEXT = 'created_at' AND NOT sqlc.arg('descending')::BOOLEAN THEN created_at END,
+    CASE WHEN sqlc.arg('sort')::TEXT = 'created_at' AND sqlc.arg('descending')::BOOLEAN THEN created_at END DESC,
+    CASE WHEN sqlc.arg('sort')::TEXT NOT IN ('amount', 'created_at') AND NOT sqlc.arg('descending')::BOOLEAN THEN date END,
+    CASE WHEN sqlc.arg('sort')::TEXT NOT IN ('amount', 'created_at') AND sqlc.arg('descending')::BOOLEAN THEN date END DESC,
+    CASE WHEN NOT sqlc.arg('descending')::BOOLEAN THEN id END,
+    id DESC
+LIMIT sqlc.arg('page_size');
+
 -- name: UpdateIncome :one
 UPDATE income 
 SET 
@@ -52,7 +92,7 @@ ORDER BY date DESC;
 
 -- name: GetMonthlyIncomeTotal :one
 SELECT 
-    COALESCE(SUM(amount), 0) as total_amount,
+    COALESCE(SUM(amount), 0)::numeric as total_amount,
     currency
 FROM income
 WHERE user_id = $1 
@@ -65,8 +105,8 @@ SELECT
     source,
     currency,
     COUNT(*) as transaction_count,
-    SUM(amount) as total_amount,
-    AVG(amount) as average_amount
+    SUM(amount)::numeric as total_amount,
+    ROUND(AVG(amount), 4)::numeric as average_amount
 FROM income
 WHERE user_id = $1 
     AND deleted_at IS NULL
@@ -79,3 +119,44 @@ WHERE user_id = $1
     AND deleted_at IS NULL
 ORDER BY date DESC
 LIMIT $2;
+
+-- name: FindDuplicateIncome :many
+-- Finds the income a new one would likely duplicate: any with the same
+-- fingerprint, and any that already has its external ID, deleted or not
+SELECT * FROM income
+WHERE user_id = sqlc.arg('user_id')
+    AND (
+        (deleted_at IS NULL
+            AND transaction_fingerprint(amount, currency, date, description, source)
+                = transaction_fingerprint(sqlc.arg('amount')::NUMERIC, sqlc.arg('currency')::VARCHAR, sqlc.arg('date')::TIMESTAMPTZ, sqlc.arg('description')::TEXT, sqlc.arg('source')::TEXT))
+        OR (sqlc.arg('external_id')::VARCHAR <> '' AND external_id = sqlc.arg('external_id')::VARCHAR)
+    )
+ORDER BY created_at;
+
+-- name: MatchImportedIncome :many
+-- For each income record about to be imported, counts the records the user
+-- already has with the same fingerprint and reports whether its external ID is
+-- taken. The arrays are parallel and rows come back in the same order.
+SELECT
+    t.ordinal::INT AS ordinal,
+    transaction_fingerprint(t.amount::NUMERIC, t.currency, t.date, t.description, t.source)::TEXT AS fingerprint,
+    (
+        SELECT COUNT(*) FROM income x
+        WHERE x.user_id = sqlc.arg('user_id')
+            AND x.deleted_at IS NULL
+            AND transaction_fingerprint(x.amount, x.currency, x.date, x.description, x.source)
+                = transaction_fingerprint(t.amount::NUMERIC, t.currency, t.date, t.description, t.source)
+    )::BIGINT AS matches,
+    EXISTS (
+        SELECT 1 FROM income x
+        WHERE x.user_id = sqlc.arg('user_id') AND t.external_id <> '' AND x.external_id = t.external_id
+    ) AS external_id_taken
+FROM unnest(
+    sqlc.arg('amounts')::TEXT[],
+    sqlc.arg('currencies')::VARCHAR[],
+    sqlc.arg('dates')::TIMESTAMPTZ[],
+    sqlc.arg('descriptions')::VARCHAR[],
+    sqlc.arg('sources')::VARCHAR[],
+    sqlc.arg('external_ids')::VARCHAR[]
+) WITH ORDINALITY AS t(amount, currency, date, description, source, external_id, ordinal)
+ORDER BY t.ordinal;
@@ -0,0 +1,108 @@
+-- name: CreateRecurringTransaction :one
+INSERT INTO recurring_transactions (
+    id, user_id, type, amount, currency, category_id, source, description,
+    frequency, interval_count, start_date, end_date, occurrence_count,
+    next_occurrence, created_at, updated_at
+)
+VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8,
+    $9, $10, $11, $12, $13,
+    $14, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+)
+RETURNING *;
+
+-- name: GetRecurringTransactionByID :one
+SELECT * FROM recurring_transactions
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: ListRecurringTransactions :many
+SELECT * FROM recurring_transactions
+WHERE user_id = $1 AND deleted_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: UpdateRecurringTransaction :one
+UPDATE recurring_transactions
+SET
+    amount = $2,
+    currency = $3,
+    category_id = $4,
+    source = $5,
+    description = $6,
+    frequency = $7,
+    interval_count = $8,
+    start_date = $9,
+    end_date = $10,
+    occurrence_count = $11,
+    next_occurrence = $12,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND user_id = $13 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: DeleteRecurringTransaction :execrows
+UPDATE recurring_transactions
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: ListDueRecurringTransactions :many
+-- Templates of every user with an occurrence due by now, the longest waiting first
+SELECT * FROM recurring_transactions
+WHERE deleted_at IS NULL AND next_occurrence <= sqlc.arg('now')::timestamptz
+ORDER BY next_occurrence
+LIMIT sqlc.arg('limit')::int;
+
+-- name: MaterializeRecurringExpenses :execrows
+-- Creates an expense for each occurrence date of an expense template. Dates
+-- already generated are skipped, so running this again for the same dates
+-- creates nothing.
+WITH claimed AS (
+    INSERT INTO recurring_transaction_occurrences (recurring_transaction_id, occurrence_date)
+    SELECT rt.id, d.occurrence_date
+    FROM recurring_transactions rt,
+        unnest(sqlc.arg('occurrence_dates')::timestamptz[]) AS d(occurrence_date)
+    WHERE rt.id = sqlc.arg('id') AND rt.type = 'expense' AND rt.deleted_at IS NULL
+    ON CONFLICT DO NOTHING
+    RETURNING recurring_transaction_id, occurrence_date, transaction_id
+)
+INSERT INTO expenses (
+    id, user_id, amount, currency, category_id,
+    date, description, created_at, updated_at
+)
+SELECT
+    c.transaction_id, rt.user_id, rt.amount, rt.currency, rt.category_id,
+    c.occurrence_date, rt.description, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+FROM claimed c
+JOIN recurring_transactions rt ON rt.id = c.recurring_transaction_id;
+
+-- name: MaterializeRecurringIncome :execrows
+-- Creates income for each occurrence date of an income template, skipping
+-- dates already generated like MaterializeRecurringExpenses
+WITH claimed AS (
+    INSERT INTO recurring_transaction_occurrences (recurring_transaction_id, occurrence_date)
+    SELECT rt.id, d.occurrence_date
+    FROM recurring_transactions rt,
+        unnest(sqlc.arg('occurrence_dates')::timestamptz[]) AS d(occurrence_date)
+    WHERE rt.id = sqlc.arg('id') AND rt.type = 'income' AND rt.deleted_at IS NULL
+    ON CONFLICT DO NOTHING
+    RETURNING recurring_transaction_id, occurrence_date, transaction_id
+)
+INSERT INTO income (
+    id, user_id, amount, currency, source,
+    date, description, created_at, updated_at
+)
+SELECT
+    c.transaction_id, rt.user_id, rt.amount, rt.currency, rt.source,
+    c.occurrence_date, rt.description, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+FROM claimed c
+JOIN recurring_transactions rt ON rt.id = c.recurring_transaction_id;
+
+-- name: AdvanceRecurringTransaction :execrows
+-- Records how far a template has been generated. Nothing changes when
+-- next_occurrence is no longer due_occurrence, as happens when the template
+-- was edited in the meantime.
+UPDATE recurring_transactions
+SET
+    next_occurrence = sqlc.narg('next_occurrence'),
+    last_occurrence = sqlc.narg('last_occurrence')
+WHERE id = sqlc.arg('id')
+  AND next_occurrence = sqlc.arg('due_occurrence')::timestamptz
+  AND deleted_at IS NULL;
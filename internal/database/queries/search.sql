@@ -0,0 +1,85 @@
+-- name: SearchTransactions :many
+-- Ranks a user's income and expenses against a search written the way web
+-- searches are, with "quoted phrases", or and -excluded words. Expenses match
+-- on their description and income on its source and description, and each
+-- comes with a snippet of that text with the matches between <mark> tags. A
+-- category keeps only expenses and a source only income, as does a kind of
+-- 'expense' or 'income'. Pages are sorted by rank, date or amount and continue
+-- like ListExpensesPage; NULL and empty filters match everything.
+WITH search AS (
+    SELECT websearch_to_tsquery('english', sqlc.arg('query')::TEXT) AS query
+)
+SELECT * FROM (
+    SELECT
+        'income'::TEXT AS kind,
+        i.id,
+        i.amount,
+        i.currency,
+        NULL::UUID AS category_id,
+        i.source,
+        i.date,
+        i.description,
+        ts_rank(income_search_vector(i.source, i.description), search.query)::REAL AS rank,
+        ts_headline('english', i.source || ': ' || i.description, search.query,
+            'StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10')::TEXT AS snippet
+    FROM income i, search
+    WHERE i.user_id = sqlc.arg('user_id')
+        AND i.deleted_at IS NULL
+        AND income_search_vector(i.source, i.description) @@ search.query
+        AND sqlc.arg('kind')::TEXT IN ('', 'income')
+        AND sqlc.narg('category_id')::UUID IS NULL
+        AND (sqlc.arg('source')::VARCHAR = '' OR LOWER(i.source) = LOWER(sqlc.arg('source')::VARCHAR))
+        AND (sqlc.narg('min_amount')::NUMERIC IS NULL OR i.amount >= sqlc.narg('min_amount')::NUMERIC)
+        AND (sqlc.narg('max_amount')::NUMERIC IS NULL OR i.amount <= sqlc.narg('max_amount')::NUMERIC)
+        AND (sqlc.arg('currency')::VARCHAR = '' OR i.currency = sqlc.arg('currency')::VARCHAR)
+        AND (sqlc.narg('start_date')::TIMESTAMPTZ IS NULL OR i.date >= sqlc.narg('start_date')::TIMESTAMPTZ)
+        AND (sqlc.narg('end_date')::TIMESTAMPTZ IS NULL OR i.date <= sqlc.narg('end_date')::TIMESTAMPTZ)
+    UNION ALL
+    SELECT
+        'expense',
+        e.id,
+        e.amount,
+        e.currency,
+        e.category_id,
+        '',
+        e.date,
+        e.description,
+        ts_rank(expense_search_vector(e.description), search.query)::REAL,
+        ts_headline('english', e.description, search.query,
+            'StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10')::TEXT
+    FROM expenses e, search
+    WHERE e.user_id = sqlc.arg('user_id')
+        AND e.deleted_at IS NULL
+        AND expense_search_vector(e.description) @@ search.query
+        AND sqlc.arg('kind')::TEXT IN ('', 'expense')
+        AND (sqlc.narg('category_id')::UUID IS NULL OR e.category_id = sqlc.narg('category_id')::UUID)
+        AND sqlc.arg('source')::VARCHAR = ''
+        AND (sqlc.narg('min_amount')::NUMERIC IS NULL OR e.amount >= sqlc.narg('min_amount')::NUMERIC)
+        AND (sqlc.narg('max_amount')::NUMERIC IS NULL OR e.amount <= sqlc.narg('max_amount')::NUMERIC)
+        AND (sqlc.arg('currency')::VARCHAR = '' OR e.currency = sqlc.arg('currency')::VARCHAR)
+        AND (sqlc.narg('start_date')::TIMESTAMPTZ IS NULL OR e.date >= sqlc.narg('start_date')::TIMESTAMPTZ)
+        AND (sqlc.narg('end_date')::TIMESTAMPTZ IS NULL OR e.date <= sqlc.narg('end_date')::TIMESTAMPTZ)
+) results
+WHERE sqlc.narg('after_id')::UUID IS NULL OR CASE
+    WHEN sqlc.arg('sort')::TEXT = 'amount' AND sqlc.arg('descending')::BOOLEAN
+        THEN (amount, id) < (sqlc.narg('after_amount')::NUMERIC, sqlc.narg('after_id')::UUID)
+    WHEN sqlc.arg('sort')::TEXT = 'amount'
+        THEN (amount, id) > (sqlc.narg('after_amount')::NUMERIC, sqlc.narg('after_id')::UUID)
+    WHEN sqlc.arg('sort')::TEXT = 'date' AND sqlc.arg('descending')::BOOLEAN
+        THEN (date, id) < (sqlc.narg('after_time')::TIMESTAMPTZ, sqlc.narg('after_id')::UUID)
+    WHEN sqlc.arg('sort')::TEXT = 'date'
+        THEN (date, id) > (sqlc.narg('after_time')::TIMESTAMPTZ, sqlc.narg('after_id')::UUID)
+    WHEN sqlc.arg('descending')::BOOLEAN
+        THEN (rank, id) < (sqlc.narg('after_rank')::REAL, sqlc.narg('after_id')::UUID)
+    ELSE (rank, id) > (sqlc.narg('after_rank')::REAL, sqlc.narg('after_id')::UUID)
+END
+ORDER BY
+    CASE WHEN sqlc.arg('sort')::TEXT = 'amount' AND NOT sqlc.arg('descending')::BOOLEAN THEN amount END,
+    CASE WHEN sqlc.arg('sort')::TEXT = 'amount' AND sqlc.arg('descending')::BOOLEAN THEN amount END DESC,
+    CASE WHEN sqlc.arg('sort')::TEXT = 'date' AND NOT sqlc.arg('descending')::BOOLEAN THEN date END,
+    CASE WHEN sqlc.arg('sort')::TEXT = 'date' AND sqlc.arg('descending')::BOOLEAN THEN date END DESC,
+    CASE WHEN sqlc.arg('sort')::TEXT NOT IN ('amount', 'date') AND NOT sqlc.arg('descending')::BOOLEAN THEN rank END,
+    CASE WHEN sqlc.arg('sort')::TEXT NOT IN ('amount', 'date') AND sqlc.arg('descending')::BOOLEAN THEN rank END DESC,
+    CASE WHEN NOT sqlc.arg('descending')::BOOLEAN THEN id END,
+    id DESC
+LIMIT sqlc.arg('page_size');
@@ -0,0 +1,85 @@
+-- name: UpsertSession :exec
+INSERT INTO user_sessions (id, user_id, user_agent, ip_address, created_at, last_seen)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (id) DO UPDATE
+SET last_seen = EXCLUDED.last_seen;
+
+-- name: TouchSession :exec
+UPDATE user_sessions
+SET last_seen = $2
+WHERE id = $1;
+
+-- name: GetSession :one
+SELECT id, user_id, user_agent, ip_address, created_at, last_seen
+FROM user_sessions
+WHERE id = $1;
+
+-- name: ListUserSessions :many
+SELECT id, user_id, user_agent, ip_address, created_at, last_seen
+FROM user_sessions
+WHERE user_id = $1
+ORDER BY last_seen DESC;
+
+-- name: DeleteSession :exec
+DELETE FROM user_sessions
+WHERE id = $1;
+
+-- name: DeleteInactiveSessions :execrows
+DELETE FROM user_sessions
+WHERE last_seen < sqlc.arg(cutoff)::TIMESTAMPTZ;
+
+-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (token_hash, expires_at, created_at)
+VALUES ($1, $2, CURRENT_TIMESTAMP)
+ON CONFLICT (token_hash) DO NOTHING;
+
+-- name: IsTokenRevoked :one
+SELECT EXISTS(
+    SELECT 1 FROM revoked_tokens
+    WHERE token_hash = $1
+);
+
+-- name: DeleteExpiredRevokedTokens :execrows
+DELETE FROM revoked_tokens
+WHERE expires_at < sqlc.arg(now)::TIMESTAMPTZ;
+
+-- name: CreateRefreshToken :exec
+INSERT INTO refresh_tokens (id, family_id, user_id, expires_at, created_at)
+VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP);
+
+-- name: UseRefreshToken :one
+WITH previous AS (
+    SELECT id, family_id, used_at
+    FROM refresh_tokens
+    WHERE id = sqlc.arg(id)::uuid
+    FOR UPDATE
+)
+UPDATE refresh_tokens r
+SET used_at = COALESCE(r.used_at, CURRENT_TIMESTAMP)
+FROM previous p
+WHERE r.id = p.id
+RETURNING p.family_id, (p.used_at IS NOT NULL)::boolean AS already_used;
+
+-- name: DeleteRefreshTokenFamily :execrows
+DELETE FROM refresh_tokens
+WHERE family_id = $1;
+
+-- name: DeleteExpiredRefreshTokens :execrows
+DELETE FROM refresh_tokens
+WHERE expires_at < sqlc.arg(now)::TIMESTAMPTZ;
+
+-- name: RevokeTokenFamily :exec
+INSERT INTO revoked_token_families (family_id, expires_at, created_at)
+VALUES ($1, $2, CURRENT_TIMESTAMP)
+ON CONFLICT (family_id) DO UPDATE
+SET expires_at = GREATEST(revoked_token_families.expires_at, EXCLUDED.expires_at);
+
+-- name: IsTokenFamilyRevoked :one
+SELECT EXISTS(
+    SELECT 1 FROM revoked_token_families
+    WHERE family_id = $1
+);
+
+-- name: DeleteExpiredTokenFamilies :execrows
+DELETE FROM revoked_token_families
+WHERE expires_at < sqlc.arg(now)::TIMESTAMPTZ;
@@ -0,0 +1,146 @@
+-- name: CreateTag :one
+INSERT INTO tags (id, user_id, name, created_at, updated_at)
+VALUES ($1, $2, $3, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+RETURNING *;
+
+-- name: GetTagByID :one
+SELECT * FROM tags
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: ListTags :many
+SELECT * FROM tags
+WHERE user_id = $1 AND deleted_at IS NULL
+ORDER BY LOWER(name), id;
+
+-- name: UpdateTag :one
+UPDATE tags
+SET
+    name = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND user_id = $3 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: DeleteTag :execrows
+UPDATE tags
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: CheckTagExists :one
+-- Reports whether the user has a tag other than id with the name, ignoring case
+SELECT EXISTS(
+    SELECT 1 FROM tags
+    WHERE user_id = sqlc.arg('user_id')
+        AND LOWER(name) = LOWER(sqlc.arg('name')::TEXT)
+        AND id <> sqlc.arg('id')
+        AND deleted_at IS NULL
+);
+
+-- name: CountUserTags :one
+-- Counts how many of tag_ids are tags of the user
+SELECT COUNT(*) FROM tags
+WHERE user_id = sqlc.arg('user_id')
+    AND id = ANY(sqlc.arg('tag_ids')::UUID[])
+    AND deleted_at IS NULL;
+
+-- name: ListExpenseTags :many
+SELECT t.* FROM tags t
+JOIN expense_tags et ON et.tag_id = t.id
+WHERE et.expense_id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL
+ORDER BY LOWER(t.name), t.id;
+
+-- name: SetExpenseTags :exec
+-- Replaces the tags of an expense with tag_ids
+WITH removed AS (
+    DELETE FROM expense_tags
+    WHERE expense_id = sqlc.arg('expense_id')
+        AND tag_id <> ALL(sqlc.arg('tag_ids')::UUID[])
+)
+INSERT INTO expense_tags (expense_id, tag_id)
+SELECT sqlc.arg('expense_id'), unnest(sqlc.arg('tag_ids')::UUID[])
+ON CONFLICT DO NOTHING;
+
+-- name: ListIncomeTags :many
+SELECT t.* FROM tags t
+JOIN income_tags it ON it.tag_id = t.id
+WHERE it.income_id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL
+ORDER BY LOWER(t.name), t.id;
+
+-- name: SetIncomeTags :exec
+-- Replaces the tags of an income record with tag_ids
+WITH removed AS (
+    DELETE FROM income_tags
+    WHERE income_id = sqlc.arg('income_id')
+        AND tag_id <> ALL(sqlc.arg('tag_ids')::UUID[])
+)
+INSERT INTO income_tags (income_id, tag_id)
+SELECT sqlc.arg('income_id'), unnest(sqlc.arg('tag_ids')::UUID[])
+ON CONFLICT DO NOTHING;
+
+-- name: ListTransactionTags :many
+-- Lists the tags of the user's expenses and income with the given IDs
+SELECT et.expense_id AS transaction_id, et.tag_id
+FROM expense_tags et
+JOIN tags t ON t.id = et.tag_id
+WHERE et.expense_id = ANY(sqlc.arg('transaction_ids')::UUID[])
+    AND t.user_id = sqlc.arg('user_id')
+    AND t.deleted_at IS NULL
+UNION ALL
+SELECT it.income_id, it.tag_id
+FROM income_tags it
+JOIN tags t ON t.id = it.tag_id
+WHERE it.income_id = ANY(sqlc.arg('transaction_ids')::UUID[])
+    AND t.user_id = sqlc.arg('user_id')
+    AND t.deleted_at IS NULL;
+
+-- name: LinkImportedTags :exec
+-- Tags the expenses and income of an import. Each pair of arrays is parallel,
+-- with a row per tag of a transaction. Tags deleted since, which a category
+-- rule may still name, are skipped.
+WITH expense_links AS (
+    INSERT INTO expense_tags (expense_id, tag_id)
+    SELECT l.expense_id, l.tag_id
+    FROM unnest(sqlc.arg('expense_ids')::UUID[], sqlc.arg('expense_tag_ids')::UUID[]) AS l(expense_id, tag_id)
+    JOIN tags t ON t.id = l.tag_id AND t.deleted_at IS NULL
+    ON CONFLICT DO NOTHING
+)
+INSERT INTO income_tags (income_id, tag_id)
+SELECT l.income_id, l.tag_id
+FROM unnest(sqlc.arg('income_ids')::UUID[], sqlc.arg('income_tag_ids')::UUID[]) AS l(income_id, tag_id)
+JOIN tags t ON t.id = l.tag_id AND t.deleted_at IS NULL
+ON CONFLICT DO NOTHING;
+
+-- name: GetTagTotals :many
+-- Totals a user's expenses and income by tag and currency, counting those
+-- dated in [start_date, end_date) and, when tag_id is set, only that tag. A
+-- transaction with several tags counts towards each of them.
+WITH tagged AS (
+    SELECT et.tag_id, 'expense' AS kind, e.amount, e.currency
+    FROM expense_tags et
+    JOIN expenses e ON e.id = et.expense_id
+    WHERE e.user_id = sqlc.arg('user_id')
+        AND e.deleted_at IS NULL
+        AND e.date >= sqlc.arg('start_date')::TIMESTAMPTZ
+        AND e.date < sqlc.arg('end_date')::TIMESTAMPTZ
+    UNION ALL
+    SELECT it.tag_id, 'income', i.amount, i.currency
+    FROM income_tags it
+    JOIN income i ON i.id = it.income_id
+    WHERE i.user_id = sqlc.arg('user_id')
+        AND i.deleted_at IS NULL
+        AND i.date >= sqlc.arg('start_date')::TIMESTAMPTZ
+        AND i.date < sqlc.arg('end_date')::TIMESTAMPTZ
+)
+SELECT
+    t.id AS tag_id,
+    t.name AS tag_name,
+    tagged.currency,
+    COUNT(*) FILTER (WHERE tagged.kind = 'expense') AS expense_count,
+    COALESCE(SUM(tagged.amount) FILTER (WHERE tagged.kind = 'expense'), 0)::NUMERIC AS total_expenses,
+    COUNT(*) FILTER (WHERE tagged.kind = 'income') AS income_count,
+    COALESCE(SUM(tagged.amount) FILTER (WHERE tagged.kind = 'income'), 0)::NUMERIC AS total_income
+FROM tagged
+JOIN tags t ON t.id = tagged.tag_id
+WHERE t.deleted_at IS NULL
+    AND (sqlc.narg('tag_id')::UUID IS NULL OR t.id = sqlc.narg('tag_id')::UUID)
+GROUP BY t.id, t.name, tagged.currency
+ORDER BY LOWER(t.name), t.id, tagged.currency;
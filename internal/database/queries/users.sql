@@ -3,13 +3,37 @@ INSERT INTO users (id, name, email, password_hash, created_at, updated_at)
 VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 RETURNING *;
 
+-- name: RegisterUser :one
+-- Creates a user together with the categories of a template, in one statement
+-- so that either both are saved or neither is. An empty template_key creates
+-- no categories. Parents are inserted before their children.
+WITH new_user AS (
+    INSERT INTO users (id, name, email, password_hash, created_at, updated_at)
+    VALUES (sqlc.arg('id'), sqlc.arg('name'), sqlc.arg('email'), sqlc.arg('password_hash'), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+    RETURNING *
+),
+entries AS (
+    SELECT e.name, e.parent_name, e.position, gen_random_uuid() AS id
+    FROM category_template_entries e
+    WHERE e.template_key = sqlc.arg('template_key')
+),
+new_categories AS (
+    INSERT INTO categories (id, user_id, name, parent_id, created_at, updated_at)
+    SELECT c.id, u.id, c.name, p.id, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+    FROM entries c
+    CROSS JOIN new_user u
+    LEFT JOIN entries p ON p.name = c.parent_name
+    ORDER BY c.parent_name IS NOT NULL, c.position
+)
+SELECT * FROM new_user;
+
 -- name: GetUserByEmail :one
 SELECT id, name, email, password_hash, role_id
 FROM users
 WHERE email = $1 AND deleted_at IS NULL;
 
 -- name: GetUserByID :one
-SELECT id, name, email, created_at 
+SELECT id, name, email, created_at, base_currency
 FROM users 
 WHERE id = $1 AND deleted_at IS NULL;
 
@@ -18,6 +42,7 @@ UPDATE users
 SET 
     name = $2,
     email = $3,
+    base_currency = $4,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = $1 AND deleted_at IS NULL
 RETURNING *;
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jorge-dev/centsible/internal/repository"
+)
+
+// replicaRetryAfter is how long reads stay on the primary after the replica
+// failed to answer, before it is tried again
+const replicaRetryAfter = 30 * time.Second
+
+// NewRepository returns the repository the API runs on. Without a replica
+// every operation runs on the primary. With one, the summary and stats
+// queries, which are the heaviest reads and can stand to lag a little behind
+// the primary, run on the replica instead; writes, transactions and the reads
+// that check or show what was just written stay on the primary.
+func NewRepository(primary, replica *pgxpool.Pool) repository.Repository {
+	if replica == nil {
+		return repository.New(primary)
+	}
+	return newReplicaRouter(repository.New(primary), repository.New(replica))
+}
+
+// replicaRouter runs the reporting queries it overrides on the replica and
+// everything else on the embedded primary. A query the replica cannot answer
+// is run again on the primary, and reads stay there for replicaRetryAfter.
+type replicaRouter struct {
+	repository.Repository
+	replica repository.Repository
+
+	mu        sync.Mutex
+	downUntil time.Time
+}
+
+func newReplicaRouter(primary, replica repository.Repository) *replicaRouter {
+	return &replicaRouter{
+		Repository: primary,
+		replica:    replica,
+	}
+}
+
+func (r *replicaRouter) replicaUp() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return time.Now().After(r.downUntil)
+}
+
+func (r *replicaRouter) replicaDown(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Now().After(r.downUntil) {
+		log.Printf("Read replica unavailable, reading from the primary for %s: %v", replicaRetryAfter, err)
+	}
+	r.downUntil = time.Now().Add(replicaRetryAfter)
+}
+
+// replicaUnavailable reports whether err means the replica could not run the
+// query, rather than that the query itself failed. Serialization failures
+// are included, as a replica cancels queries that conflict with the changes
+// it is replaying.
+func replicaUnavailable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || errors.Is(err, pgx.ErrNoRows) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		// Never reached the server, or lost it part way
+		return true
+	}
+	// connection_exception, and operator_intervention such as a replica
+	// shutting down or still starting up
+	return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "57P") ||
+		repository.IsSerializationFailure(err)
+}
+
+// readReplica runs query on the replica, or on the primary when the replica
+// is down or fails to answer
+func readReplica[T any](ctx context.Context, r *replicaRouter, query func(repository.Repository) (T, error)) (T, error) {
+	if r.replicaUp() {
+		result, err := query(r.replica)
+		if !replicaUnavailable(ctx, err) {
+			return result, err
+		}
+		r.replicaDown(err)
+	}
+	return query(r.Repository)
+}
+
+func (r *replicaRouter) GetUserStats(ctx context.Context, id uuid.UUID) (repository.GetUserStatsRow, error) {
+	return readReplica(ctx, r, func(db repository.Repository) (repository.GetUserStatsRow, error) {
+		return db.GetUserStats(ctx, id)
+	})
+}
+
+func (r *replicaRouter) GetCategoryUsage(ctx context.Context, arg repository.GetCategoryUsageParams) (repository.GetCategoryUsageRow, error) {
+	return readReplica(ctx, r, func(db repository.Repository) (repository.GetCategoryUsageRow, error) {
+		return db.GetCategoryUsage(ctx, arg)
+	})
+}
+
+func (r *replicaRouter) GetMostUsedCategories(ctx context.Context, arg repository.GetMostUsedCategoriesParams) ([]repository.GetMostUsedCategoriesRow, error) {
+	return readReplica(ctx, r, func(db repository.Repository) ([]repository.GetMostUsedCategoriesRow, error) {
+		return db.GetMostUsedCategories(ctx, arg)
+	})
+}
+
+func (r *replicaRouter) GetExpenseTotalsByCategory(ctx context.Context, userID uuid.UUID) ([]repository.GetExpenseTotalsByCategoryRow, error) {
+	return readReplica(ctx, r, func(db repository.Repository) ([]repository.GetExpenseTotalsByCategoryRow, error) {
+		return db.GetExpenseTotalsByCategory(ctx, userID)
+	})
+}
+
+func (r *replicaRouter) GetMonthlyExpenseTotal(ctx context.Context, arg repository.GetMonthlyExpenseTotalParams) ([]repository.GetMonthlyExpenseTotalRow, error) {
+	return readReplica(ctx, r, func(db repository.Repository) ([]repository.GetMonthlyExpenseTotalRow, error) {
+		return db.GetMonthlyExpenseTotal(ctx, arg)
+	})
+}
+
+func (r *replicaRouter) GetIncomeSummaryBySource(ctx context.Context, userID uuid.UUID) ([]repository.GetIncomeSummaryBySourceRow, error) {
+	return readReplica(ctx, r, func(db repository.Repository) ([]repository.GetIncomeSummaryBySourceRow, error) {
+		return db.GetIncomeSummaryBySource(ctx, userID)
+	})
+}
+
+func (r *replicaRouter) GetMonthlyIncomeTotal(ctx context.Context, arg repository.GetMonthlyIncomeTotalParams) (repository.GetMonthlyIncomeTotalRow, error) {
+	return readReplica(ctx, r, func(db repository.Repository) (repository.GetMonthlyIncomeTotalRow, error) {
+		return db.GetMonthlyIncomeTotal(ctx, arg)
+	})
+}
+
+func (r *replicaRouter) GetConvertedTotals(ctx context.Context, arg repository.GetConvertedTotalsParams) (repository.GetConvertedTotalsRow, error) {
+	return readReplica(ctx, r, func(db repository.Repository) (repository.GetConvertedTotalsRow, error) {
+		return db.GetConvertedTotals(ctx, arg)
+	})
+}
+
+func (r *replicaRouter) GetMonthlySummary(ctx context.Context, arg repository.GetMonthlySummaryParams) ([]repository.GetMonthlySummaryRow, error) {
+	return readReplica(ctx, r, func(db repository.Repository) ([]repository.GetMonthlySummaryRow, error) {
+		return db.GetMonthlySummary(ctx, arg)
+	})
+}
+
+func (r *replicaRouter) GetYearlySummary(ctx context.Context, arg repository.GetYearlySummaryParams) ([]repository.GetYearlySummaryRow, error) {
+	return readReplica(ctx, r, func(db repository.Repository) ([]repository.GetYearlySummaryRow, error) {
+		return db.GetYearlySummary(ctx, arg)
+	})
+}
+
+func (r *replicaRouter) GetTagTotals(ctx context.Context, arg repository.GetTagTotalsParams) ([]repository.GetTagTotalsRow, error) {
+	return readReplica(ctx, r, func(db repository.Repository) ([]repository.GetTagTotalsRow, error) {
+		return db.GetTagTotals(ctx, arg)
+	})
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/jorge-dev/centsible/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingReplica answers summaries with err, and counts how often it is asked
+type failingReplica struct {
+	repository.Repository
+	err   error
+	calls int
+}
+
+func (f *failingReplica) GetMonthlySummary(ctx context.Context, arg repository.GetMonthlySummaryParams) ([]repository.GetMonthlySummaryRow, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.Repository.GetMonthlySummary(ctx, arg)
+}
+
+func TestReplicaRouter(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	month := time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)
+	params := repository.GetMonthlySummaryParams{UserID: userID, Date: month}
+
+	setup := func(err error) (*replicaRouter, *mocks.MockRepository, *failingReplica) {
+		primary := mocks.NewMockRepository().(*mocks.MockRepository)
+		primary.GetSummaryMock().AddMonthlySummary(userID, month, []repository.GetMonthlySummaryRow{{Currency: "CAD"}})
+		replicaMock := mocks.NewMockRepository().(*mocks.MockRepository)
+		replicaMock.GetSummaryMock().AddMonthlySummary(userID, month, []repository.GetMonthlySummaryRow{{Currency: "EUR"}})
+		replica := &failingReplica{Repository: replicaMock, err: err}
+		return newReplicaRouter(primary, replica), primary, replica
+	}
+	currency := func(t *testing.T, rows []repository.GetMonthlySummaryRow, err error) string {
+		if !assert.NoError(t, err) || !assert.Len(t, rows, 1) {
+			return ""
+		}
+		return rows[0].Currency
+	}
+
+	t.Run("Reports are read from the replica", func(t *testing.T) {
+		router, _, _ := setup(nil)
+		rows, err := router.GetMonthlySummary(ctx, params)
+		assert.Equal(t, "EUR", currency(t, rows, err))
+	})
+
+	t.Run("Writes and other reads stay on the primary", func(t *testing.T) {
+		router, primary, _ := setup(nil)
+		_, err := router.CreateTag(ctx, repository.CreateTagParams{ID: uuid.New(), UserID: userID, Name: "Work"})
+		assert.NoError(t, err)
+		tags, _ := primary.ListTags(ctx, userID)
+		assert.Len(t, tags, 1)
+		tags, _ = router.ListTags(ctx, userID)
+		assert.Len(t, tags, 1)
+	})
+
+	t.Run("Fails over while the replica is down", func(t *testing.T) {
+		router, _, replica := setup(&pgconn.ConnectError{})
+		rows, err := router.GetMonthlySummary(ctx, params)
+		assert.Equal(t, "CAD", currency(t, rows, err))
+
+		// The replica is left alone until it is due to be tried again
+		rows, err = router.GetMonthlySummary(ctx, params)
+		assert.Equal(t, "CAD", currency(t, rows, err))
+		assert.Equal(t, 1, replica.calls)
+
+		replica.err = nil
+		router.downUntil = time.Now()
+		rows, err = router.GetMonthlySummary(ctx, params)
+		assert.Equal(t, "EUR", currency(t, rows, err))
+	})
+
+	t.Run("Query errors are not retried on the primary", func(t *testing.T) {
+		syntaxError := &pgconn.PgError{Code: "42601"}
+		router, _, replica := setup(syntaxError)
+		_, err := router.GetMonthlySummary(ctx, params)
+		assert.ErrorIs(t, err, syntaxError)
+		assert.True(t, router.replicaUp())
+		assert.Equal(t, 1, replica.calls)
+	})
+}
+
+func TestReplicaUnavailable(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"No error", context.Background(), nil, false},
+		{"No rows", context.Background(), pgx.ErrNoRows, false},
+		{"Connection refused", context.Background(), fmt.Errorf("summary: %w", &pgconn.ConnectError{}), true},
+		{"Connection lost", context.Background(), errors.New("unexpected EOF"), true},
+		{"Shutting down", context.Background(), &pgconn.PgError{Code: "57P01"}, true},
+		{"Starting up", context.Background(), &pgconn.PgError{Code: "57P03"}, true},
+		{"Conflict with recovery", context.Background(), &pgconn.PgError{Code: "40001"}, true},
+		{"Bad query", context.Background(), &pgconn.PgError{Code: "42601"}, false},
+		{"Request cancelled", cancelled, context.Canceled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, replicaUnavailable(tt.ctx, tt.err))
+		})
+	}
+}
@@ -0,0 +1,556 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDatabaseURL names the database the integration tests run against. They
+// migrate it and add and remove users of their own, so give them a database
+// nothing else uses.
+const testDatabaseURL = "CENTSIBLE_TEST_DATABASE_URL"
+
+var (
+	migrateTestDB    sync.Once
+	migrateTestDBErr error
+)
+
+// testPool connects to the database in CENTSIBLE_TEST_DATABASE_URL, migrated
+// to the latest version, and skips the test when it is not set
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv(testDatabaseURL)
+	if url == "" {
+		t.Skipf("%s is not set", testDatabaseURL)
+	}
+	migrateTestDB.Do(func() { migrateTestDBErr = runMigrations(url) })
+	require.NoError(t, migrateTestDBErr)
+
+	pool, err := pgxpool.New(context.Background(), url)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// summaryUser is a user of its own for one test, with a Food category that
+// has a Groceries subcategory, and a Rent category
+type summaryUser struct {
+	id         uuid.UUID
+	categories map[string]uuid.UUID
+}
+
+func newSummaryUser(t *testing.T, pool *pgxpool.Pool) summaryUser {
+	t.Helper()
+	ctx := context.Background()
+	q := repository.New(pool)
+	user := summaryUser{id: uuid.New(), categories: map[string]uuid.UUID{}}
+
+	_, err := q.CreateUser(ctx, repository.CreateUserParams{
+		ID:           user.id,
+		Name:         "Summary Test",
+		Email:        user.id.String() + "@example.com",
+		PasswordHash: "not a hash",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		// Transactions first, as categories in use cannot be deleted
+		for _, table := range []string{"expenses", "income", "categories", "users"} {
+			column := "user_id"
+			if table == "users" {
+				column = "id"
+			}
+			_, err := pool.Exec(ctx, "DELETE FROM "+table+" WHERE "+column+" = $1", user.id)
+			assert.NoError(t, err)
+		}
+	})
+
+	for _, c := range []struct{ name, parent string }{
+		{"Food", ""},
+		{"Groceries", "Food"},
+		{"Rent", ""},
+	} {
+		params := repository.CreateCategoryParams{ID: uuid.New(), UserID: user.id, Name: c.name}
+		if c.parent != "" {
+			parent := user.categories[c.parent]
+			params.ParentID = &parent
+		}
+		_, err := q.CreateCategory(ctx, params)
+		require.NoError(t, err)
+		user.categories[c.name] = params.ID
+	}
+	return user
+}
+
+// summaryTransaction is an income or expense to add before a summary is read.
+// Expenses name their category; deleted ones are added and then deleted.
+type summaryTransaction struct {
+	kind     string
+	amount   string
+	currency string
+	date     string
+	category string
+	deleted  bool
+}
+
+func income(amount, currency, date string) summaryTransaction {
+	return summaryTransaction{kind: "income", amount: amount, currency: currency, date: date}
+}
+
+func expense(amount, currency, date, category string) summaryTransaction {
+	return summaryTransaction{kind: "expense", amount: amount, currency: currency, date: date, category: category}
+}
+
+func deleted(tx summaryTransaction) summaryTransaction {
+	tx.deleted = true
+	return tx
+}
+
+func (u summaryUser) add(t *testing.T, q *repository.Queries, transactions ...summaryTransaction) []uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	ids := make([]uuid.UUID, len(transactions))
+	for i, tx := range transactions {
+		date, err := time.Parse(time.RFC3339, tx.date)
+		require.NoError(t, err)
+		ids[i] = uuid.New()
+
+		if tx.kind == "income" {
+			_, err = q.CreateIncome(ctx, repository.CreateIncomeParams{
+				ID:       ids[i],
+				UserID:   u.id,
+				Amount:   money.MustParse(tx.amount),
+				Currency: tx.currency,
+				Source:   "Salary",
+				Date:     date,
+			})
+			require.NoError(t, err)
+			if tx.deleted {
+				_, err = q.DeleteIncome(ctx, repository.DeleteIncomeParams{ID: ids[i], UserID: u.id})
+				require.NoError(t, err)
+			}
+			continue
+		}
+
+		category, ok := u.categories[tx.category]
+		require.True(t, ok, "unknown category %q", tx.category)
+		_, err = q.CreateExpense(ctx, repository.CreateExpenseParams{
+			ID:         ids[i],
+			UserID:     u.id,
+			Amount:     money.MustParse(tx.amount),
+			Currency:   tx.currency,
+			CategoryID: category,
+			Date:       date,
+		})
+		require.NoError(t, err)
+		if tx.deleted {
+			_, err = q.DeleteExpense(ctx, repository.DeleteExpenseParams{ID: ids[i], UserID: u.id})
+			require.NoError(t, err)
+		}
+	}
+	return ids
+}
+
+// totals are the income, expenses and savings expected in one currency
+type totals struct {
+	income, expenses, savings string
+}
+
+type topCategory struct {
+	CategoryName string       `json:"category_name"`
+	UsageCount   int          `json:"usage_count"`
+	TotalSpent   money.Amount `json:"total_spent"`
+}
+
+type trendPoint struct {
+	Month        time.Time    `json:"month"`
+	CategoryName string       `json:"category_name"`
+	Amount       money.Amount `json:"amount"`
+}
+
+// summaryRow is what the monthly and yearly summaries have in common
+type summaryRow struct {
+	currency                            string
+	totalIncome, totalExpenses, savings money.Amount
+	topCategories                       []topCategory
+	monthlyTrend                        []trendPoint
+}
+
+func assertTotals(t *testing.T, want map[string]totals, rows []summaryRow) {
+	t.Helper()
+	got := make(map[string]totals, len(rows))
+	for _, row := range rows {
+		got[row.currency] = totals{row.totalIncome.String(), row.totalExpenses.String(), row.savings.String()}
+	}
+	normalized := make(map[string]totals, len(want))
+	for currency, w := range want {
+		normalized[currency] = totals{
+			money.MustParse(w.income).String(),
+			money.MustParse(w.expenses).String(),
+			money.MustParse(w.savings).String(),
+		}
+	}
+	assert.Equal(t, normalized, got)
+}
+
+// describeTop lists top categories as "name count total", for comparing
+func describeTop(categories []topCategory) []string {
+	described := make([]string, len(categories))
+	for i, c := range categories {
+		described[i] = c.CategoryName + " " + strconv.Itoa(c.UsageCount) + " " + c.TotalSpent.String()
+	}
+	return described
+}
+
+func monthlySummary(t *testing.T, q *repository.Queries, userID uuid.UUID, date time.Time) []summaryRow {
+	t.Helper()
+	rows, err := q.GetMonthlySummary(context.Background(), repository.GetMonthlySummaryParams{UserID: userID, Date: date})
+	require.NoError(t, err)
+	summary := make([]summaryRow, len(rows))
+	for i, row := range rows {
+		summary[i] = summaryRow{currency: row.Currency, totalIncome: row.TotalIncome, totalExpenses: row.TotalExpenses, savings: row.TotalSavings}
+		require.NoError(t, json.Unmarshal(row.TopCategories, &summary[i].topCategories))
+	}
+	return summary
+}
+
+func yearlySummary(t *testing.T, q *repository.Queries, userID uuid.UUID, date time.Time) []summaryRow {
+	t.Helper()
+	rows, err := q.GetYearlySummary(context.Background(), repository.GetYearlySummaryParams{UserID: userID, Date: date})
+	require.NoError(t, err)
+	summary := make([]summaryRow, len(rows))
+	for i, row := range rows {
+		summary[i] = summaryRow{currency: row.Currency, totalIncome: row.TotalIncome, totalExpenses: row.TotalExpenses, savings: row.TotalSavings}
+		require.NoError(t, json.Unmarshal(row.TopCategories, &summary[i].topCategories))
+		require.NoError(t, json.Unmarshal(row.MonthlyTrend, &summary[i].monthlyTrend))
+	}
+	return summary
+}
+
+func TestGetMonthlySummary(t *testing.T) {
+	pool := testPool(t)
+	q := repository.New(pool)
+	may := time.Date(2024, time.May, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		transactions []summaryTransaction
+		want         map[string]totals
+		// top categories of the first currency, as describeTop lists them
+		wantTop []string
+	}{
+		{
+			name: "Several income and expenses are each counted once",
+			transactions: []summaryTransaction{
+				income("1000", "CAD", "2024-05-01T09:00:00Z"),
+				income("500", "CAD", "2024-05-15T09:00:00Z"),
+				expense("100", "CAD", "2024-05-02T12:00:00Z", "Food"),
+				expense("50", "CAD", "2024-05-03T12:00:00Z", "Groceries"),
+				expense("25", "CAD", "2024-05-04T12:00:00Z", "Rent"),
+			},
+			want:    map[string]totals{"CAD": {"1500", "175", "1325"}},
+			wantTop: []string{"Food 2 150", "Rent 1 25"},
+		},
+		{
+			name: "Month with expenses only",
+			transactions: []summaryTransaction{
+				expense("40", "USD", "2024-05-10T12:00:00Z", "Food"),
+				expense("2.50", "USD", "2024-05-11T12:00:00Z", "Food"),
+			},
+			want:    map[string]totals{"USD": {"0", "42.50", "-42.50"}},
+			wantTop: []string{"Food 2 42.5"},
+		},
+		{
+			name: "Month with income only",
+			transactions: []summaryTransaction{
+				income("300", "EUR", "2024-05-20T12:00:00Z"),
+				income("200", "EUR", "2024-05-21T12:00:00Z"),
+			},
+			want:    map[string]totals{"EUR": {"500", "0", "500"}},
+			wantTop: []string{},
+		},
+		{
+			name: "Currencies are totalled apart",
+			transactions: []summaryTransaction{
+				income("1000", "CAD", "2024-05-01T09:00:00Z"),
+				expense("200", "CAD", "2024-05-02T12:00:00Z", "Rent"),
+				expense("30", "USD", "2024-05-03T12:00:00Z", "Food"),
+				income("10", "USD", "2024-05-04T12:00:00Z"),
+				income("10", "USD", "2024-05-05T12:00:00Z"),
+			},
+			want: map[string]totals{
+				"CAD": {"1000", "200", "800"},
+				"USD": {"20", "30", "-10"},
+			},
+			wantTop: []string{"Rent 1 200"},
+		},
+		{
+			name: "Other months and deleted transactions are left out",
+			transactions: []summaryTransaction{
+				expense("10", "CAD", "2024-04-30T23:59:59Z", "Food"),
+				income("20", "CAD", "2024-06-01T00:00:00Z"),
+				deleted(expense("99", "CAD", "2024-05-05T12:00:00Z", "Food")),
+				deleted(income("99", "CAD", "2024-05-05T12:00:00Z")),
+				// May 1st in UTC
+				expense("5", "CAD", "2024-04-30T22:00:00-05:00", "Rent"),
+			},
+			want:    map[string]totals{"CAD": {"0", "5", "-5"}},
+			wantTop: []string{"Rent 1 5"},
+		},
+		{
+			name: "Nothing in the month",
+			transactions: []summaryTransaction{
+				income("100", "CAD", "2024-04-01T12:00:00Z"),
+				expense("100", "CAD", "2024-06-01T12:00:00Z", "Food"),
+			},
+			want: map[string]totals{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := newSummaryUser(t, pool)
+			user.add(t, q, tt.transactions...)
+
+			rows := monthlySummary(t, q, user.id, may)
+			assertTotals(t, tt.want, rows)
+			if tt.wantTop != nil && len(rows) > 0 {
+				assert.Equal(t, tt.wantTop, describeTop(rows[0].topCategories))
+			}
+		})
+	}
+}
+
+func TestGetYearlySummary(t *testing.T) {
+	pool := testPool(t)
+	q := repository.New(pool)
+	year := time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC)
+	month := func(m time.Month) time.Time { return time.Date(2024, m, 1, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name         string
+		transactions []summaryTransaction
+		want         map[string]totals
+		// top categories and trend of the first currency
+		wantTop   []string
+		wantTrend []trendPoint
+	}{
+		{
+			name: "Months with only income or only expenses count",
+			transactions: []summaryTransaction{
+				income("1000", "CAD", "2024-01-15T12:00:00Z"),
+				income("1000", "CAD", "2024-01-31T12:00:00Z"),
+				expense("200", "CAD", "2024-02-10T12:00:00Z", "Food"),
+				expense("300", "CAD", "2024-02-11T12:00:00Z", "Rent"),
+				expense("50", "CAD", "2024-03-05T12:00:00Z", "Groceries"),
+				income("100", "CAD", "2024-03-06T12:00:00Z"),
+			},
+			want:    map[string]totals{"CAD": {"2100", "550", "1550"}},
+			wantTop: []string{"Rent 1 300", "Food 2 250"},
+			wantTrend: []trendPoint{
+				{Month: month(time.February), CategoryName: "Food", Amount: money.MustParse("200")},
+				{Month: month(time.February), CategoryName: "Rent", Amount: money.MustParse("300")},
+				{Month: month(time.March), CategoryName: "Food", Amount: money.MustParse("50")},
+			},
+		},
+		{
+			name: "Other years and deleted transactions are left out",
+			transactions: []summaryTransaction{
+				expense("10", "CAD", "2023-12-31T23:59:59Z", "Food"),
+				income("20", "CAD", "2025-01-01T00:00:00Z"),
+				deleted(expense("99", "CAD", "2024-05-05T12:00:00Z", "Food")),
+				income("40", "CAD", "2024-12-31T23:59:59Z"),
+			},
+			want:      map[string]totals{"CAD": {"40", "0", "40"}},
+			wantTop:   []string{},
+			wantTrend: []trendPoint{},
+		},
+		{
+			name: "Currencies are totalled apart",
+			transactions: []summaryTransaction{
+				expense("70", "EUR", "2024-04-01T12:00:00Z", "Groceries"),
+				income("500", "USD", "2024-05-01T12:00:00Z"),
+				expense("25", "USD", "2024-06-01T12:00:00Z", "Rent"),
+			},
+			want: map[string]totals{
+				"EUR": {"0", "70", "-70"},
+				"USD": {"500", "25", "475"},
+			},
+			wantTop: []string{"Food 1 70"},
+			wantTrend: []trendPoint{
+				{Month: month(time.April), CategoryName: "Food", Amount: money.MustParse("70")},
+			},
+		},
+		{
+			name: "Nothing in the year",
+			transactions: []summaryTransaction{
+				income("100", "CAD", "2023-06-01T12:00:00Z"),
+			},
+			want: map[string]totals{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := newSummaryUser(t, pool)
+			user.add(t, q, tt.transactions...)
+
+			rows := yearlySummary(t, q, user.id, year)
+			assertTotals(t, tt.want, rows)
+			if len(rows) == 0 {
+				return
+			}
+			if tt.wantTop != nil {
+				assert.Equal(t, tt.wantTop, describeTop(rows[0].topCategories))
+			}
+			if tt.wantTrend != nil {
+				require.Len(t, rows[0].monthlyTrend, len(tt.wantTrend))
+				for i, want := range tt.wantTrend {
+					got := rows[0].monthlyTrend[i]
+					assert.True(t, want.Month.Equal(got.Month), "month %d: want %s, got %s", i, want.Month, got.Month)
+					assert.Equal(t, want.CategoryName, got.CategoryName)
+					assert.Equal(t, want.Amount.String(), got.Amount.String())
+				}
+			}
+		})
+	}
+}
+
+// TestMonthlyAggregatesFollowChanges checks that the aggregates summaries are
+// read from follow edits and deletes, and match what rebuilding them writes
+func TestMonthlyAggregatesFollowChanges(t *testing.T) {
+	pool := testPool(t)
+	q := repository.New(pool)
+	ctx := context.Background()
+	user := newSummaryUser(t, pool)
+	may := time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)
+	june := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	ids := user.add(t, q,
+		expense("100", "CAD", "2024-05-02T12:00:00Z", "Food"),
+		expense("60", "CAD", "2024-05-03T12:00:00Z", "Rent"),
+		income("1000", "CAD", "2024-05-01T12:00:00Z"),
+	)
+
+	// Moved to June, in another currency and category
+	_, err := q.UpdateExpense(ctx, repository.UpdateExpenseParams{
+		ID:         ids[0],
+		UserID:     user.id,
+		Amount:     money.MustParse("80"),
+		Currency:   "USD",
+		CategoryID: user.categories["Groceries"],
+		Date:       june.Add(36 * time.Hour),
+	})
+	require.NoError(t, err)
+	_, err = q.DeleteExpense(ctx, repository.DeleteExpenseParams{ID: ids[1], UserID: user.id})
+	require.NoError(t, err)
+
+	assertTotals(t, map[string]totals{"CAD": {"1000", "0", "1000"}}, monthlySummary(t, q, user.id, may))
+	juneSummary := monthlySummary(t, q, user.id, june)
+	assertTotals(t, map[string]totals{"USD": {"0", "80", "-80"}}, juneSummary)
+	assert.Equal(t, []string{"Food 1 80"}, describeTop(juneSummary[0].topCategories))
+
+	aggregates := func() []repository.MonthlyAggregate {
+		rows, err := pool.Query(ctx, `SELECT user_id, month, currency, kind, category_id, total, count
+			FROM monthly_aggregates WHERE user_id = $1
+			ORDER BY month, currency, kind, category_id`, user.id)
+		require.NoError(t, err)
+		aggregates, err := pgx.CollectRows(rows, pgx.RowToStructByPos[repository.MonthlyAggregate])
+		require.NoError(t, err)
+		return aggregates
+	}
+	maintained := aggregates()
+	require.Len(t, maintained, 2)
+
+	written, err := q.RebuildMonthlyAggregates(ctx, &user.id)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, written)
+	rebuilt := aggregates()
+	require.Len(t, rebuilt, len(maintained))
+	for i := range maintained {
+		assert.Equal(t, maintained[i].Month, rebuilt[i].Month)
+		assert.Equal(t, maintained[i].Currency, rebuilt[i].Currency)
+		assert.Equal(t, maintained[i].Kind, rebuilt[i].Kind)
+		assert.Equal(t, maintained[i].CategoryID, rebuilt[i].CategoryID)
+		assert.Equal(t, maintained[i].Total.String(), rebuilt[i].Total.String())
+		assert.Equal(t, maintained[i].Count, rebuilt[i].Count)
+	}
+}
+
+// TestConvertedTotalsCoverSummaryWindow checks that converted totals over
+// SummaryMonth and SummaryYear add up the same transactions as the summaries
+// beside them, for dates whose local month or year differs from UTC's
+func TestConvertedTotalsCoverSummaryWindow(t *testing.T) {
+	pool := testPool(t)
+	q := repository.New(pool)
+	ctx := context.Background()
+	eastern := time.FixedZone("EST", -5*60*60)
+
+	tests := []struct {
+		name   string
+		date   time.Time
+		window func(time.Time) (time.Time, time.Time)
+		rows   func(*testing.T, *repository.Queries, uuid.UUID, time.Time) []summaryRow
+		want   totals
+	}{
+		{
+			// April 30th here, May 1st in UTC
+			name:   "Month",
+			date:   time.Date(2024, time.April, 30, 23, 0, 0, 0, eastern),
+			window: repository.SummaryMonth,
+			rows:   monthlySummary,
+			want:   totals{"100", "5", "95"},
+		},
+		{
+			// December 31st 2023 here, January 1st 2024 in UTC
+			name:   "Year",
+			date:   time.Date(2023, time.December, 31, 23, 0, 0, 0, eastern),
+			window: repository.SummaryYear,
+			rows:   yearlySummary,
+			want:   totals{"100", "16", "84"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := newSummaryUser(t, pool)
+			user.add(t, q,
+				expense("3", "USD", "2023-12-31T23:30:00Z", "Food"),
+				expense("4", "USD", "2024-04-30T18:00:00-05:00", "Food"),
+				// May 1st in UTC
+				expense("5", "USD", "2024-04-30T22:00:00-05:00", "Rent"),
+				income("100", "USD", "2024-05-15T12:00:00Z"),
+				// June 1st in UTC
+				expense("7", "USD", "2024-05-31T21:00:00-05:00", "Rent"),
+			)
+
+			rows := tt.rows(t, q, user.id, tt.date)
+			assertTotals(t, map[string]totals{"USD": tt.want}, rows)
+
+			start, end := tt.window(tt.date)
+			converted, err := q.GetConvertedTotals(ctx, repository.GetConvertedTotalsParams{
+				UserID:    user.id,
+				StartDate: start,
+				EndDate:   end,
+			})
+			require.NoError(t, err)
+			require.Len(t, rows, 1)
+			assert.Equal(t, "USD", converted.BaseCurrency)
+			assert.Equal(t, rows[0].totalIncome.String(), converted.TotalIncome.String())
+			assert.Equal(t, rows[0].totalExpenses.String(), converted.TotalExpenses.String())
+			assert.Empty(t, converted.MissingRates)
+		})
+	}
+}
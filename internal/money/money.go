@@ -0,0 +1,340 @@
+// Package money provides an exact decimal type for monetary amounts
+package money
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/bojanz/currency"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Scale is the number of decimal places an Amount keeps. It covers the largest
+// exponent of any ISO 4217 currency and matches the NUMERIC(18,4) amount columns.
+const Scale = 4
+
+// maxUnits is the largest magnitude a NUMERIC(18,4) column can hold, in units of 10^-Scale
+const maxUnits = 999_999_999_999_999_999
+
+var (
+	ErrSyntax     = fmt.Errorf("invalid decimal amount")
+	ErrPrecision  = fmt.Errorf("amount has more than %d decimal places", Scale)
+	ErrOutOfRange = fmt.Errorf("amount is out of range")
+)
+
+var (
+	unitsPerWhole = big.NewInt(10_000)
+	bigMaxUnits   = big.NewInt(maxUnits)
+	bigTen        = big.NewInt(10)
+)
+
+// Amount is an exact decimal amount of money stored as an integer number of
+// 10^-Scale units. It does not carry a currency; callers pair it with one and
+// use FitsCurrency or Round to respect that currency's exponent.
+//
+// The zero value is an amount of 0.
+type Amount struct {
+	units int64
+}
+
+// Parse parses a decimal string such as "12", "-0.5" or "1234.5600". An
+// exponent ("1.5e2") is accepted so that any JSON number can be parsed.
+func Parse(s string) (Amount, error) {
+	n, exp, err := parseDecimal(s)
+	if err != nil {
+		return Amount{}, err
+	}
+	return fromDecimal(n, exp, false)
+}
+
+// parseDecimal splits a decimal string into an integer and a power of ten, so
+// that the value is n × 10^exp
+func parseDecimal(s string) (*big.Int, int, error) {
+	str := s
+	neg := false
+	if str != "" && (str[0] == '+' || str[0] == '-') {
+		neg = str[0] == '-'
+		str = str[1:]
+	}
+
+	exp := 0
+	if i := strings.IndexAny(str, "eE"); i >= 0 {
+		e, err := strconv.Atoi(str[i+1:])
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+		}
+		exp = e
+		str = str[:i]
+	}
+
+	whole, frac, _ := strings.Cut(str, ".")
+	if (whole == "" && frac == "") || !isDigits(whole) || !isDigits(frac) {
+		return nil, 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+
+	n, _ := new(big.Int).SetString("0"+whole+frac, 10)
+	if neg {
+		n.Neg(n)
+	}
+	return n, exp - len(frac), nil
+}
+
+// MustParse is like Parse but panics if s is not a valid amount. It is meant
+// for constants and tests.
+func MustParse(s string) Amount {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// FromInt returns the amount for a whole number
+func FromInt(n int64) Amount {
+	a, err := fromDecimal(big.NewInt(n), 0, false)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// fromDecimal converts n × 10^exp to an Amount. When round is false a value
+// with more than Scale decimal places is rejected; otherwise it is rounded half
+// away from zero.
+func fromDecimal(n *big.Int, exp int, round bool) (Amount, error) {
+	units := new(big.Int).Set(n)
+	shift := exp + Scale
+	if n.Sign() != 0 && (shift > 40 || shift < -40) {
+		return Amount{}, ErrOutOfRange
+	}
+
+	if shift > 0 {
+		units.Mul(units, new(big.Int).Exp(bigTen, big.NewInt(int64(shift)), nil))
+	} else if shift < 0 {
+		divisor := new(big.Int).Exp(bigTen, big.NewInt(int64(-shift)), nil)
+		if !round && new(big.Int).Rem(units, divisor).Sign() != 0 {
+			return Amount{}, ErrPrecision
+		}
+		units = roundQuo(units, divisor)
+	}
+
+	if new(big.Int).Abs(units).Cmp(bigMaxUnits) > 0 {
+		return Amount{}, ErrOutOfRange
+	}
+	return Amount{units: units.Int64()}, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Add returns a + b
+func (a Amount) Add(b Amount) Amount {
+	return Amount{units: a.units + b.units}
+}
+
+// Sub returns a - b
+func (a Amount) Sub(b Amount) Amount {
+	return Amount{units: a.units - b.units}
+}
+
+// Neg returns -a
+func (a Amount) Neg() Amount {
+	return Amount{units: -a.units}
+}
+
+// MulRat returns a × r rounded half away from zero to Scale decimal places.
+// It panics if the result is out of range.
+func (a Amount) MulRat(r *big.Rat) Amount {
+	product := new(big.Int).Mul(big.NewInt(a.units), r.Num())
+	result, err := fromDecimal(roundQuo(product, r.Denom()), -Scale, false)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// roundQuo returns n / d rounded half away from zero, for a positive d
+func roundQuo(n, d *big.Int) *big.Int {
+	quotient, remainder := new(big.Int).QuoRem(n, d, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(d) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(remainder.Sign())))
+	}
+	return quotient
+}
+
+// Cmp returns -1, 0 or +1 depending on whether a is less than, equal to or greater than b
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a.units < b.units:
+		return -1
+	case a.units > b.units:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// IsZero reports whether a is 0
+func (a Amount) IsZero() bool {
+	return a.units == 0
+}
+
+// IsPositive reports whether a is greater than 0
+func (a Amount) IsPositive() bool {
+	return a.units > 0
+}
+
+// IsNegative reports whether a is less than 0
+func (a Amount) IsNegative() bool {
+	return a.units < 0
+}
+
+// Float64 returns the nearest float64, for ratios and percentages only
+func (a Amount) Float64() float64 {
+	f, _ := new(big.Rat).SetFrac(big.NewInt(a.units), unitsPerWhole).Float64()
+	return f
+}
+
+// Digits returns the number of decimal places needed to represent a exactly
+func (a Amount) Digits() int {
+	units := a.units
+	digits := Scale
+	for digits > 0 && units%10 == 0 {
+		units /= 10
+		digits--
+	}
+	return digits
+}
+
+// FitsCurrency reports whether a has no more decimal places than the currency's
+// exponent allows, e.g. 2 for USD and 0 for JPY. Unknown currencies never fit.
+func (a Amount) FitsCurrency(currencyCode string) bool {
+	digits, ok := currency.GetDigits(currencyCode)
+	if !ok {
+		return false
+	}
+	return a.Digits() <= int(digits)
+}
+
+// Round rounds a half away from zero to the exponent of the given currency. An
+// unknown currency leaves a unchanged.
+func (a Amount) Round(currencyCode string) Amount {
+	digits, ok := currency.GetDigits(currencyCode)
+	if !ok {
+		return a
+	}
+	step := pow10(Scale - int(digits))
+	quotient, remainder := a.units/step, a.units%step
+	if remainder >= (step+1)/2 {
+		quotient++
+	} else if remainder <= -(step+1)/2 {
+		quotient--
+	}
+	return Amount{units: quotient * step}
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for range n {
+		p *= 10
+	}
+	return p
+}
+
+// String formats a as a plain decimal without trailing zeros, e.g. "12.5" or "-3"
+func (a Amount) String() string {
+	return a.format(a.Digits())
+}
+
+// StringFixed formats a with exactly the currency's number of decimal places,
+// e.g. "12.50" for USD. Amounts with more precision are rounded first.
+func (a Amount) StringFixed(currencyCode string) string {
+	digits, ok := currency.GetDigits(currencyCode)
+	if !ok {
+		return a.String()
+	}
+	return a.Round(currencyCode).format(int(digits))
+}
+
+func (a Amount) format(digits int) string {
+	units := a.units
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	whole := units / unitsPerWhole.Int64()
+	if digits == 0 {
+		return sign + strconv.FormatInt(whole, 10)
+	}
+	frac := fmt.Sprintf("%0*d", Scale, units%unitsPerWhole.Int64())
+	return sign + strconv.FormatInt(whole, 10) + "." + frac[:digits]
+}
+
+// MarshalJSON encodes a as a JSON number with its exact decimal digits
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON accepts either a JSON number or a decimal string, so clients
+// that cannot represent amounts exactly as numbers can send "12.30" instead
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s, ok, err := jsonDecimal(data)
+	if err != nil || !ok {
+		return err
+	}
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// jsonDecimal returns the text of a JSON number or decimal string. It reports
+// false for null, which leaves the destination unchanged.
+func jsonDecimal(data []byte) (string, bool, error) {
+	if bytes.Equal(data, []byte("null")) {
+		return "", false, nil
+	}
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return "", false, fmt.Errorf("%w: %s", ErrSyntax, s)
+		}
+		s = strings.TrimSpace(unquoted)
+	}
+	return s, true, nil
+}
+
+// ScanNumeric implements pgtype.NumericScanner. Values with more than Scale
+// decimal places, such as averages, are rounded half away from zero.
+func (a *Amount) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		return fmt.Errorf("cannot scan NULL into money.Amount")
+	}
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("cannot scan %v into money.Amount", v)
+	}
+	scanned, err := fromDecimal(v.Int, int(v.Exp), true)
+	if err != nil {
+		return err
+	}
+	*a = scanned
+	return nil
+}
+
+// NumericValue implements pgtype.NumericValuer
+func (a Amount) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(a.units), Exp: -Scale, Valid: true}, nil
+}
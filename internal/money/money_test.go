@@ -0,0 +1,173 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"integer", "12", "12", nil},
+		{"decimal", "12.50", "12.5", nil},
+		{"negative", "-0.01", "-0.01", nil},
+		{"explicit plus", "+3.1", "3.1", nil},
+		{"leading point", ".5", "0.5", nil},
+		{"trailing point", "5.", "5", nil},
+		{"four places", "0.0001", "0.0001", nil},
+		{"extra zero places", "1.230000", "1.23", nil},
+		{"exponent", "1.5e2", "150", nil},
+		{"negative exponent", "15e-3", "0.015", nil},
+		{"too precise", "0.00001", "", ErrPrecision},
+		{"too large", "100000000000000", "", ErrOutOfRange},
+		{"largest", "99999999999999.9999", "99999999999999.9999", nil},
+		{"empty", "", "", ErrSyntax},
+		{"sign only", "-", "", ErrSyntax},
+		{"letters", "12a", "", ErrSyntax},
+		{"two points", "1.2.3", "", ErrSyntax},
+		{"fraction", "1/3", "", ErrSyntax},
+		{"bad exponent", "1e", "", ErrSyntax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "got error %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	// 0.1 + 0.2 is the classic float64 failure
+	sum := MustParse("0.1").Add(MustParse("0.2"))
+	assert.Equal(t, MustParse("0.3"), sum)
+	assert.Equal(t, "-0.1", MustParse("0.2").Sub(MustParse("0.3")).String())
+	assert.Equal(t, MustParse("-4"), FromInt(4).Neg())
+	assert.Equal(t, -1, FromInt(1).Cmp(FromInt(2)))
+	assert.Equal(t, 0, FromInt(2).Cmp(MustParse("2.000")))
+	assert.True(t, Amount{}.IsZero())
+	assert.True(t, MustParse("0.0001").IsPositive())
+	assert.True(t, MustParse("-0.0001").IsNegative())
+	assert.Equal(t, 12.34, MustParse("12.34").Float64())
+}
+
+func TestCurrencyExponent(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		fits     bool
+		rounded  string
+		fixed    string
+	}{
+		{"12.34", "USD", true, "12.34", "12.34"},
+		{"12.345", "USD", false, "12.35", "12.35"},
+		{"-12.345", "USD", false, "-12.35", "-12.35"},
+		{"12.344", "USD", false, "12.34", "12.34"},
+		{"12", "USD", true, "12", "12.00"},
+		{"1500", "JPY", true, "1500", "1500"},
+		{"1500.5", "JPY", false, "1501", "1501"},
+		{"1.234", "KWD", true, "1.234", "1.234"},
+		{"1.2345", "KWD", false, "1.235", "1.235"},
+		{"1.2345", "CLF", true, "1.2345", "1.2345"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			a := MustParse(tt.amount)
+			assert.Equal(t, tt.fits, a.FitsCurrency(tt.currency))
+			assert.Equal(t, tt.rounded, a.Round(tt.currency).String())
+			assert.Equal(t, tt.fixed, a.StringFixed(tt.currency))
+		})
+	}
+
+	assert.False(t, FromInt(1).FitsCurrency("XXX"))
+}
+
+func TestJSON(t *testing.T) {
+	var body struct {
+		Amount   Amount  `json:"amount"`
+		Optional *Amount `json:"optional"`
+	}
+
+	for _, input := range []string{
+		`{"amount": 19.99}`,
+		`{"amount": "19.99"}`,
+		`{"amount": " 19.990 "}`,
+		`{"amount": 1.999e1, "optional": null}`,
+	} {
+		body.Amount = Amount{}
+		if err := json.Unmarshal([]byte(input), &body); err != nil {
+			t.Fatalf("unmarshal %s: %v", input, err)
+		}
+		assert.Equal(t, MustParse("19.99"), body.Amount, input)
+		assert.Nil(t, body.Optional, input)
+	}
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": "abc"}`), &body))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": true}`), &body))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": 0.00001}`), &body))
+
+	encoded, err := json.Marshal(map[string]Amount{"amount": MustParse("1234.5")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, `{"amount": 1234.5}`, string(encoded))
+}
+
+func TestNumeric(t *testing.T) {
+	value, err := MustParse("-12.3").NumericValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, pgtype.Numeric{Int: big.NewInt(-123000), Exp: -4, Valid: true}, value)
+
+	tests := []struct {
+		name    string
+		numeric pgtype.Numeric
+		want    string
+		wantErr bool
+	}{
+		{"round trip", value, "-12.3", false},
+		{"positive exponent", pgtype.Numeric{Int: big.NewInt(12), Exp: 3, Valid: true}, "12000", false},
+		{"average rounds", pgtype.Numeric{Int: big.NewInt(3333333333), Exp: -8, Valid: true}, "33.3333", false},
+		{"rounds half away from zero", pgtype.Numeric{Int: big.NewInt(-125), Exp: -5, Valid: true}, "-0.0013", false},
+		{"null", pgtype.Numeric{}, "", true},
+		{"nan", pgtype.Numeric{NaN: true, Valid: true}, "", true},
+		{"overflow", pgtype.Numeric{Int: big.NewInt(1), Exp: 20, Valid: true}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a Amount
+			err := a.ScanNumeric(tt.numeric)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, a.String())
+		})
+	}
+}
+
+func TestMulRat(t *testing.T) {
+	assert.Equal(t, "75", FromInt(100).MulRat(big.NewRat(3, 4)).String())
+	assert.Equal(t, "3.3333", FromInt(10).MulRat(big.NewRat(1, 3)).String())
+	assert.Equal(t, "6.6667", FromInt(20).MulRat(big.NewRat(1, 3)).String())
+	assert.Equal(t, "-6.6667", FromInt(-20).MulRat(big.NewRat(1, 3)).String())
+	assert.Equal(t, "1.1", MustParse("0.55").MulRat(big.NewRat(2, 1)).String())
+}
@@ -0,0 +1,156 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RateScale is the number of decimal places kept for exchange rates, matching
+// the NUMERIC(24,12) rate column
+const RateScale = 12
+
+var ErrRatePrecision = fmt.Errorf("rate has more than %d decimal places", RateScale)
+
+// Rate is an exact exchange rate: how many units of the quote currency one unit
+// of the base currency buys. The zero value is a rate of 0.
+type Rate struct {
+	rat *big.Rat
+}
+
+// ParseRate parses a decimal string such as "1.0845" or "0.000041"
+func ParseRate(s string) (Rate, error) {
+	n, exp, err := parseDecimal(s)
+	if err != nil {
+		return Rate{}, err
+	}
+	return rateFromDecimal(n, exp, false)
+}
+
+// MustParseRate is like ParseRate but panics if s is not a valid rate
+func MustParseRate(s string) Rate {
+	r, err := ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// rateFromDecimal converts n × 10^exp to a Rate, rejecting or rounding values
+// with more than RateScale decimal places
+func rateFromDecimal(n *big.Int, exp int, round bool) (Rate, error) {
+	if n.Sign() != 0 && (exp > 40 || exp < -40) {
+		return Rate{}, ErrOutOfRange
+	}
+
+	pow := new(big.Int).Exp(bigTen, big.NewInt(int64(abs(exp))), nil)
+	rat := new(big.Rat).SetInt(n)
+	if exp >= 0 {
+		rat.Mul(rat, new(big.Rat).SetInt(pow))
+	} else {
+		rat.Quo(rat, new(big.Rat).SetInt(pow))
+	}
+
+	scaled := new(big.Rat).Mul(rat, new(big.Rat).SetInt(rateUnits()))
+	if !scaled.IsInt() {
+		if !round {
+			return Rate{}, ErrRatePrecision
+		}
+		rat.SetFrac(roundQuo(scaled.Num(), scaled.Denom()), rateUnits())
+	}
+	return Rate{rat: rat}, nil
+}
+
+func rateUnits() *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(RateScale), nil)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Rat returns the rate as a fraction
+func (r Rate) Rat() *big.Rat {
+	if r.rat == nil {
+		return new(big.Rat)
+	}
+	return new(big.Rat).Set(r.rat)
+}
+
+// IsPositive reports whether r is greater than 0
+func (r Rate) IsPositive() bool {
+	return r.rat != nil && r.rat.Sign() > 0
+}
+
+// Inverse returns the rate for the opposite direction, rounded to RateScale
+// decimal places. It panics if r is 0.
+func (r Rate) Inverse() Rate {
+	inverse := new(big.Rat).Inv(r.Rat())
+	scaled := new(big.Rat).Mul(inverse, new(big.Rat).SetInt(rateUnits()))
+	inverse.SetFrac(roundQuo(scaled.Num(), scaled.Denom()), rateUnits())
+	return Rate{rat: inverse}
+}
+
+// Equal reports whether r and other are the same rate
+func (r Rate) Equal(other Rate) bool {
+	return r.Rat().Cmp(other.Rat()) == 0
+}
+
+// String formats r as a plain decimal without trailing zeros
+func (r Rate) String() string {
+	s := r.Rat().FloatString(RateScale)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Convert returns the amount in the quote currency for an amount in the base
+// currency, rounded half away from zero to Scale decimal places
+func (a Amount) Convert(r Rate) Amount {
+	return a.MulRat(r.Rat())
+}
+
+// MarshalJSON encodes r as a JSON number with its exact decimal digits
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON accepts either a JSON number or a decimal string
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	s, ok, err := jsonDecimal(data)
+	if err != nil || !ok {
+		return err
+	}
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// ScanNumeric implements pgtype.NumericScanner
+func (r *Rate) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		return fmt.Errorf("cannot scan NULL into money.Rate")
+	}
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("cannot scan %v into money.Rate", v)
+	}
+	scanned, err := rateFromDecimal(v.Int, int(v.Exp), true)
+	if err != nil {
+		return err
+	}
+	*r = scanned
+	return nil
+}
+
+// NumericValue implements pgtype.NumericValuer
+func (r Rate) NumericValue() (pgtype.Numeric, error) {
+	scaled := new(big.Rat).Mul(r.Rat(), new(big.Rat).SetInt(rateUnits()))
+	return pgtype.Numeric{Int: roundQuo(scaled.Num(), scaled.Denom()), Exp: -RateScale, Valid: true}, nil
+}
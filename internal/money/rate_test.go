@@ -0,0 +1,87 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"1.0845", "1.0845", nil},
+		{"0.000041", "0.000041", nil},
+		{"25000", "25000", nil},
+		{"1.500000000000", "1.5", nil},
+		{"0.000000000001", "0.000000000001", nil},
+		{"0.0000000000001", "", ErrRatePrecision},
+		{"abc", "", ErrSyntax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseRate(tt.input)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "got error %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+			assert.True(t, got.IsPositive())
+		})
+	}
+
+	assert.False(t, Rate{}.IsPositive())
+	assert.Equal(t, "0", Rate{}.String())
+}
+
+func TestConvert(t *testing.T) {
+	eurToUSD := MustParseRate("1.0845")
+	assert.Equal(t, "108.45", FromInt(100).Convert(eurToUSD).String())
+	assert.Equal(t, "10.8504", MustParse("10.005").Convert(eurToUSD).String())
+
+	usdToEUR := eurToUSD.Inverse()
+	assert.Equal(t, "0.922083909636", usdToEUR.String())
+	assert.Equal(t, "100", MustParse("108.45").Convert(usdToEUR).Round("EUR").String())
+
+	assert.True(t, MustParseRate("2").Inverse().Equal(MustParseRate("0.5")))
+}
+
+func TestRateJSONAndNumeric(t *testing.T) {
+	var body struct {
+		Rate Rate `json:"rate"`
+	}
+	if err := json.Unmarshal([]byte(`{"rate": "1.0845"}`), &body); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, body.Rate.Equal(MustParseRate("1.0845")))
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, `{"rate": 1.0845}`, string(encoded))
+
+	value, err := body.Rate.NumericValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, pgtype.Numeric{Int: big.NewInt(1084500000000), Exp: -12, Valid: true}, value)
+
+	var scanned Rate
+	assert.NoError(t, scanned.ScanNumeric(value))
+	assert.True(t, scanned.Equal(body.Rate))
+
+	// Rates computed in SQL, such as inverses, carry more digits than the column
+	assert.NoError(t, scanned.ScanNumeric(pgtype.Numeric{Int: big.NewInt(3333333333333333), Exp: -16, Valid: true}))
+	assert.Equal(t, "0.333333333333", scanned.String())
+
+	assert.Error(t, scanned.ScanNumeric(pgtype.Numeric{}))
+}
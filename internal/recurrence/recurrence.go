@@ -0,0 +1,309 @@
+// Package recurrence splits a schedule that repeats at a fixed cadence into
+// successive periods, and works out the occurrences of simple recurrence rules
+package recurrence
+
+import (
+	"fmt"
+	"time"
+)
+
+// Cadence is how often a schedule repeats
+type Cadence string
+
+const (
+	Weekly    Cadence = "weekly"
+	Monthly   Cadence = "monthly"
+	Quarterly Cadence = "quarterly"
+	Yearly    Cadence = "yearly"
+)
+
+var ErrInvalidCadence = fmt.Errorf("cadence must be one of 'weekly', 'monthly', 'quarterly' or 'yearly'")
+
+// ParseCadence returns the cadence named by s
+func ParseCadence(s string) (Cadence, error) {
+	c := Cadence(s)
+	switch c {
+	case Weekly, Monthly, Quarterly, Yearly:
+		return c, nil
+	}
+	return "", ErrInvalidCadence
+}
+
+// months is the length of the cadence in months, or 0 for weekly
+func (c Cadence) months() int {
+	switch c {
+	case Monthly:
+		return 1
+	case Quarterly:
+		return 3
+	case Yearly:
+		return 12
+	}
+	return 0
+}
+
+// Advance returns the start of the n-th repetition after start. It always
+// counts from start, so a monthly schedule starting on the 31st comes back to
+// the 31st after a shorter month. Days past the end of a shorter month are
+// clamped to its last day, as Postgres does when adding intervals.
+func (c Cadence) Advance(start time.Time, n int) time.Time {
+	if c == Weekly {
+		return start.AddDate(0, 0, 7*n)
+	}
+	return addMonths(start, c.months()*n)
+}
+
+func addMonths(t time.Time, n int) time.Time {
+	year, month, day := t.Date()
+	// Day 0 of the following month is the last day of the target month
+	lastDay := time.Date(year, month+time.Month(n)+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(year, month+time.Month(n), day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// Period is one repetition of a schedule. It includes Start and excludes End.
+type Period struct {
+	Index int
+	Start time.Time
+	End   time.Time
+}
+
+// Contains reports whether t falls within the period
+func (p Period) Contains(t time.Time) bool {
+	return !t.Before(p.Start) && t.Before(p.End)
+}
+
+// Schedule repeats every Cadence from Start. A schedule without a cadence has a
+// single period from Start to Until, or an empty one if Until is not set. Until
+// is otherwise optional: when set, no period starts at or after it and the last
+// one is cut short to end there.
+type Schedule struct {
+	Start   time.Time
+	Cadence Cadence
+	Until   *time.Time
+}
+
+// Period returns the n-th period of the schedule, counting from 0
+func (s Schedule) Period(n int) Period {
+	if s.Cadence == "" {
+		if s.Until == nil {
+			return Period{Start: s.Start, End: s.Start}
+		}
+		return Period{Start: s.Start, End: *s.Until}
+	}
+	p := Period{
+		Index: n,
+		Start: s.Cadence.Advance(s.Start, n),
+		End:   s.Cadence.Advance(s.Start, n+1),
+	}
+	if s.Until != nil && p.End.After(*s.Until) {
+		p.End = *s.Until
+	}
+	return p
+}
+
+// index returns the number of the period containing t, ignoring Until. Times
+// before the start of the schedule belong to the first period.
+func (s Schedule) index(t time.Time) int {
+	if s.Cadence == "" || !t.After(s.Start) {
+		return 0
+	}
+
+	// Estimate from the calendar, then step to the exact period since clamped
+	// month ends and daylight saving changes can put the estimate off by one
+	var n int
+	if months := s.Cadence.months(); months > 0 {
+		startYear, startMonth, _ := s.Start.Date()
+		year, month, _ := t.Date()
+		n = ((year-startYear)*12 + int(month-startMonth)) / months
+	} else {
+		n = int(t.Sub(s.Start) / (7 * 24 * time.Hour))
+	}
+	for n > 0 && s.Cadence.Advance(s.Start, n).After(t) {
+		n--
+	}
+	for !s.Cadence.Advance(s.Start, n+1).After(t) {
+		n++
+	}
+	return n
+}
+
+// last returns the number of the final period, or false if the schedule
+// repeats forever
+func (s Schedule) last() (int, bool) {
+	if s.Cadence == "" {
+		return 0, true
+	}
+	if s.Until == nil {
+		return 0, false
+	}
+	if !s.Until.After(s.Start) {
+		return 0, true
+	}
+	// Until itself is excluded, so the last period is the one holding the
+	// instant before it
+	return s.index(s.Until.Add(-time.Nanosecond)), true
+}
+
+// Current returns the period containing now. Before the schedule starts that
+// is the first period and after it ends, the last.
+func (s Schedule) Current(now time.Time) Period {
+	n := s.index(now)
+	if last, ok := s.last(); ok && n > last {
+		n = last
+	}
+	return s.Period(n)
+}
+
+// Periods returns every period from the first up to and including the current one
+func (s Schedule) Periods(now time.Time) []Period {
+	current := s.Current(now)
+	periods := make([]Period, 0, current.Index+1)
+	for n := 0; n < current.Index; n++ {
+		periods = append(periods, s.Period(n))
+	}
+	return append(periods, current)
+}
+
+// Frequency is the unit a Rule repeats in, like FREQ in an iCalendar RRULE
+type Frequency string
+
+const (
+	FreqDaily   Frequency = "daily"
+	FreqWeekly  Frequency = "weekly"
+	FreqMonthly Frequency = "monthly"
+	FreqYearly  Frequency = "yearly"
+)
+
+var ErrInvalidFrequency = fmt.Errorf("frequency must be one of 'daily', 'weekly', 'monthly' or 'yearly'")
+
+// ParseFrequency returns the frequency named by s
+func ParseFrequency(s string) (Frequency, error) {
+	f := Frequency(s)
+	switch f {
+	case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+		return f, nil
+	}
+	return "", ErrInvalidFrequency
+}
+
+// Advance returns start moved on by n units of the frequency, clamping month
+// ends the same way as Cadence.Advance
+func (f Frequency) Advance(start time.Time, n int) time.Time {
+	switch f {
+	case FreqDaily:
+		return start.AddDate(0, 0, n)
+	case FreqWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case FreqMonthly:
+		return addMonths(start, n)
+	}
+	return addMonths(start, 12*n)
+}
+
+// units estimates how many whole units of the frequency fit between from and
+// to. It can be off by one around clamped month ends and daylight saving changes.
+func (f Frequency) units(from, to time.Time) int {
+	switch f {
+	case FreqDaily:
+		return int(to.Sub(from) / (24 * time.Hour))
+	case FreqWeekly:
+		return int(to.Sub(from) / (7 * 24 * time.Hour))
+	}
+	fromYear, fromMonth, _ := from.Date()
+	year, month, _ := to.Date()
+	months := (year-fromYear)*12 + int(month-fromMonth)
+	if f == FreqMonthly {
+		return months
+	}
+	return months / 12
+}
+
+// Rule is a simplified iCalendar recurrence rule. It occurs at Start and then
+// every Interval units of Frequency, always counting from Start. It ends after
+// Count occurrences or with the last occurrence on or before Until, and never
+// ends when neither is set.
+type Rule struct {
+	Start     time.Time
+	Frequency Frequency
+	Interval  int
+	Until     *time.Time
+	Count     *int
+}
+
+func (r Rule) interval() int {
+	if r.Interval < 1 {
+		return 1
+	}
+	return r.Interval
+}
+
+// Occurrence returns the n-th occurrence of the rule, counting from 0 and
+// ignoring when the rule ends
+func (r Rule) Occurrence(n int) time.Time {
+	return r.Frequency.Advance(r.Start, r.interval()*n)
+}
+
+// ended reports whether the rule has ended before its n-th occurrence
+func (r Rule) ended(n int) bool {
+	if r.Count != nil && n >= *r.Count {
+		return true
+	}
+	return r.Until != nil && r.Occurrence(n).After(*r.Until)
+}
+
+// indexAfter returns the number of the first occurrence after t
+func (r Rule) indexAfter(t time.Time) int {
+	if t.Before(r.Start) {
+		return 0
+	}
+
+	// Estimate from the calendar, then step to the exact occurrence
+	n := r.Frequency.units(r.Start, t) / r.interval()
+	for n > 0 && r.Occurrence(n-1).After(t) {
+		n--
+	}
+	for !r.Occurrence(n).After(t) {
+		n++
+	}
+	return n
+}
+
+// First returns the first occurrence of the rule, or false if it never occurs
+func (r Rule) First() (time.Time, bool) {
+	if r.ended(0) {
+		return time.Time{}, false
+	}
+	return r.Start, true
+}
+
+// After returns the first occurrence after t, or false if the rule has ended by then
+func (r Rule) After(t time.Time) (time.Time, bool) {
+	n := r.indexAfter(t)
+	if r.ended(n) {
+		return time.Time{}, false
+	}
+	return r.Occurrence(n), true
+}
+
+// Between returns the occurrences after from, or from the start of the rule
+// when from is nil, up to and including through. At most limit occurrences
+// are returned, the earliest first.
+func (r Rule) Between(from *time.Time, through time.Time, limit int) []time.Time {
+	n := 0
+	if from != nil {
+		n = r.indexAfter(*from)
+	}
+
+	var occurrences []time.Time
+	for ; len(occurrences) < limit && !r.ended(n); n++ {
+		occurrence := r.Occurrence(n)
+		if occurrence.After(through) {
+			break
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences
+}
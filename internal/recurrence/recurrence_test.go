@@ -0,0 +1,256 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestParseCadence(t *testing.T) {
+	for _, s := range []string{"weekly", "monthly", "quarterly", "yearly"} {
+		c, err := ParseCadence(s)
+		assert.NoError(t, err)
+		assert.Equal(t, Cadence(s), c)
+	}
+
+	for _, s := range []string{"", "daily", "Monthly"} {
+		_, err := ParseCadence(s)
+		assert.ErrorIs(t, err, ErrInvalidCadence)
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	tests := []struct {
+		name    string
+		cadence Cadence
+		start   time.Time
+		n       int
+		want    time.Time
+	}{
+		{"Weekly", Weekly, date(2024, time.December, 30), 1, date(2025, time.January, 6)},
+		{"Monthly", Monthly, date(2024, time.January, 15), 2, date(2024, time.March, 15)},
+		{"Clamped to a shorter month", Monthly, date(2024, time.January, 31), 1, date(2024, time.February, 29)},
+		{"Back to the 31st", Monthly, date(2024, time.January, 31), 2, date(2024, time.March, 31)},
+		{"Quarterly", Quarterly, date(2024, time.November, 30), 1, date(2025, time.February, 28)},
+		{"Yearly from a leap day", Yearly, date(2024, time.February, 29), 1, date(2025, time.February, 28)},
+		{"Keeps the time of day", Monthly, time.Date(2024, time.May, 1, 6, 30, 0, 0, time.UTC), 1, time.Date(2024, time.June, 1, 6, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.cadence.Advance(tt.start, tt.n))
+		})
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	until := date(2024, time.April, 15)
+
+	tests := []struct {
+		name      string
+		schedule  Schedule
+		now       time.Time
+		wantIndex int
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "Before the start",
+			schedule:  Schedule{Start: date(2024, time.January, 31), Cadence: Monthly},
+			now:       date(2024, time.January, 1),
+			wantIndex: 0,
+			wantStart: date(2024, time.January, 31),
+			wantEnd:   date(2024, time.February, 29),
+		},
+		{
+			name:      "Clamped month end",
+			schedule:  Schedule{Start: date(2024, time.January, 31), Cadence: Monthly},
+			now:       date(2024, time.March, 30),
+			wantIndex: 1,
+			wantStart: date(2024, time.February, 29),
+			wantEnd:   date(2024, time.March, 31),
+		},
+		{
+			name:      "On a boundary",
+			schedule:  Schedule{Start: date(2024, time.January, 31), Cadence: Monthly},
+			now:       date(2024, time.March, 31),
+			wantIndex: 2,
+			wantStart: date(2024, time.March, 31),
+			wantEnd:   date(2024, time.April, 30),
+		},
+		{
+			name:      "Weekly, years later",
+			schedule:  Schedule{Start: date(2024, time.January, 1), Cadence: Weekly},
+			now:       date(2026, time.January, 1).Add(time.Hour),
+			wantIndex: 104,
+			wantStart: date(2025, time.December, 29),
+			wantEnd:   date(2026, time.January, 5),
+		},
+		{
+			name:      "Last period is cut short",
+			schedule:  Schedule{Start: date(2024, time.January, 1), Cadence: Monthly, Until: &until},
+			now:       date(2024, time.April, 10),
+			wantIndex: 3,
+			wantStart: date(2024, time.April, 1),
+			wantEnd:   until,
+		},
+		{
+			name:      "After the schedule ends",
+			schedule:  Schedule{Start: date(2024, time.January, 1), Cadence: Quarterly, Until: &until},
+			now:       date(2025, time.January, 1),
+			wantIndex: 1,
+			wantStart: date(2024, time.April, 1),
+			wantEnd:   until,
+		},
+		{
+			name:      "Without a cadence",
+			schedule:  Schedule{Start: date(2024, time.January, 1), Until: &until},
+			now:       date(2025, time.January, 1),
+			wantIndex: 0,
+			wantStart: date(2024, time.January, 1),
+			wantEnd:   until,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := tt.schedule.Current(tt.now)
+			assert.Equal(t, tt.wantIndex, current.Index)
+			assert.Equal(t, tt.wantStart, current.Start)
+			assert.Equal(t, tt.wantEnd, current.End)
+		})
+	}
+}
+
+func TestPeriods(t *testing.T) {
+	schedule := Schedule{Start: date(2024, time.November, 30), Cadence: Quarterly}
+	periods := schedule.Periods(date(2025, time.June, 1))
+
+	assert.Equal(t, []Period{
+		{Index: 0, Start: date(2024, time.November, 30), End: date(2025, time.February, 28)},
+		{Index: 1, Start: date(2025, time.February, 28), End: date(2025, time.May, 30)},
+		{Index: 2, Start: date(2025, time.May, 30), End: date(2025, time.August, 30)},
+	}, periods)
+
+	for _, p := range periods[1:] {
+		assert.True(t, p.Contains(p.Start))
+		assert.False(t, p.Contains(p.End))
+	}
+}
+
+func TestParseFrequency(t *testing.T) {
+	for _, s := range []string{"daily", "weekly", "monthly", "yearly"} {
+		f, err := ParseFrequency(s)
+		assert.NoError(t, err)
+		assert.Equal(t, Frequency(s), f)
+	}
+
+	for _, s := range []string{"", "quarterly", "DAILY"} {
+		_, err := ParseFrequency(s)
+		assert.ErrorIs(t, err, ErrInvalidFrequency)
+	}
+}
+
+func TestRuleBetween(t *testing.T) {
+	until := date(2024, time.April, 30)
+	three := 3
+
+	tests := []struct {
+		name    string
+		rule    Rule
+		from    *time.Time
+		through time.Time
+		limit   int
+		want    []time.Time
+	}{
+		{
+			name:    "Every other day",
+			rule:    Rule{Start: date(2024, time.February, 27), Frequency: FreqDaily, Interval: 2},
+			through: date(2024, time.March, 4),
+			limit:   10,
+			want:    []time.Time{date(2024, time.February, 27), date(2024, time.February, 29), date(2024, time.March, 2), date(2024, time.March, 4)},
+		},
+		{
+			name:    "Monthly on the 31st",
+			rule:    Rule{Start: date(2024, time.January, 31), Frequency: FreqMonthly},
+			through: date(2024, time.April, 1),
+			limit:   10,
+			want:    []time.Time{date(2024, time.January, 31), date(2024, time.February, 29), date(2024, time.March, 31)},
+		},
+		{
+			name:    "Resumes after the last one generated",
+			rule:    Rule{Start: date(2024, time.January, 31), Frequency: FreqMonthly},
+			from:    ptr(date(2024, time.February, 29)),
+			through: date(2024, time.June, 1),
+			limit:   2,
+			want:    []time.Time{date(2024, time.March, 31), date(2024, time.April, 30)},
+		},
+		{
+			name:    "Resumes from a time between occurrences",
+			rule:    Rule{Start: date(2024, time.January, 1), Frequency: FreqWeekly},
+			from:    ptr(date(2024, time.January, 10)),
+			through: date(2024, time.January, 22),
+			limit:   10,
+			want:    []time.Time{date(2024, time.January, 15), date(2024, time.January, 22)},
+		},
+		{
+			name:    "Until is inclusive",
+			rule:    Rule{Start: date(2024, time.January, 30), Frequency: FreqMonthly, Until: &until},
+			through: date(2025, time.January, 1),
+			limit:   10,
+			want:    []time.Time{date(2024, time.January, 30), date(2024, time.February, 29), date(2024, time.March, 30), date(2024, time.April, 30)},
+		},
+		{
+			name:    "Count",
+			rule:    Rule{Start: date(2020, time.February, 29), Frequency: FreqYearly, Count: &three},
+			through: date(2030, time.January, 1),
+			limit:   10,
+			want:    []time.Time{date(2020, time.February, 29), date(2021, time.February, 28), date(2022, time.February, 28)},
+		},
+		{
+			name:    "Not started yet",
+			rule:    Rule{Start: date(2024, time.January, 1), Frequency: FreqDaily},
+			through: date(2023, time.December, 31),
+			limit:   10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.rule.Between(tt.from, tt.through, tt.limit))
+		})
+	}
+}
+
+func TestRuleAfter(t *testing.T) {
+	two := 2
+	rule := Rule{Start: date(2024, time.January, 1), Frequency: FreqMonthly, Interval: 3, Count: &two}
+
+	next, ok := rule.After(date(2023, time.June, 1))
+	assert.True(t, ok)
+	assert.Equal(t, date(2024, time.January, 1), next)
+
+	next, ok = rule.After(date(2024, time.January, 1))
+	assert.True(t, ok)
+	assert.Equal(t, date(2024, time.April, 1), next)
+
+	_, ok = rule.After(date(2024, time.April, 1))
+	assert.False(t, ok)
+
+	first, ok := rule.First()
+	assert.True(t, ok)
+	assert.Equal(t, rule.Start, first)
+
+	until := date(2023, time.December, 31)
+	_, ok = Rule{Start: date(2024, time.January, 1), Frequency: FreqDaily, Until: &until}.First()
+	assert.False(t, ok)
+}
@@ -0,0 +1,178 @@
+// Package recurring turns recurring transaction templates into the income and
+// expenses they describe as their occurrences fall due
+package recurring
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jorge-dev/centsible/internal/recurrence"
+	"github.com/jorge-dev/centsible/internal/repository"
+)
+
+const (
+	// Interval is how often the worker looks for due occurrences
+	Interval = time.Minute
+
+	// batchSize is how many due templates are loaded at a time
+	batchSize = 100
+
+	// maxOccurrencesPerRun caps the occurrences of one template generated in
+	// a single pass, so that a template starting far in the past catches up
+	// over several passes rather than in one huge statement
+	maxOccurrencesPerRun = 366
+
+	runTimeout = 30 * time.Second
+)
+
+// Worker generates the due occurrences of every user's recurring transactions
+type Worker struct {
+	db repository.Repository
+}
+
+func NewWorker(db repository.Repository) *Worker {
+	return &Worker{db: db}
+}
+
+// Rule returns the recurrence rule a template's occurrences follow
+func Rule(template repository.RecurringTransaction) recurrence.Rule {
+	rule := recurrence.Rule{
+		Start:     template.StartDate,
+		Frequency: template.Frequency,
+		Interval:  int(template.IntervalCount),
+		Until:     template.EndDate,
+	}
+	if template.OccurrenceCount != nil {
+		count := int(*template.OccurrenceCount)
+		rule.Count = &count
+	}
+	return rule
+}
+
+// NextOccurrence returns the first occurrence of a rule after the last one
+// generated, or its very first occurrence when none has been. It returns nil
+// once the rule has ended.
+func NextOccurrence(rule recurrence.Rule, last *time.Time) *time.Time {
+	var next time.Time
+	var ok bool
+	if last == nil {
+		next, ok = rule.First()
+	} else {
+		next, ok = rule.After(*last)
+	}
+	if !ok {
+		return nil
+	}
+	return &next
+}
+
+// Start runs the worker every interval until ctx is cancelled. The first pass
+// runs straight away to catch up on anything that fell due while the server
+// was down. The returned channel is closed once the worker has stopped.
+func (w *Worker) Start(ctx context.Context, interval time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			runCtx, cancel := context.WithTimeout(ctx, runTimeout)
+			if created, err := w.Run(runCtx, time.Now()); err != nil {
+				slog.Error("Error generating recurring transactions", "error", err)
+			} else if created > 0 {
+				slog.Info("Generated recurring transactions", "created", created)
+			}
+			cancel()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return done
+}
+
+// Run generates every occurrence due by now and returns how many income and
+// expense records it created. A template that fails is logged and skipped so
+// that it cannot hold up the others.
+func (w *Worker) Run(ctx context.Context, now time.Time) (int64, error) {
+	var created int64
+	for {
+		due, err := w.db.ListDueRecurringTransactions(ctx, repository.ListDueRecurringTransactionsParams{
+			Now:   now,
+			Limit: batchSize,
+		})
+		if err != nil {
+			return created, fmt.Errorf("error listing due recurring transactions: %w", err)
+		}
+
+		advanced := 0
+		for _, template := range due {
+			n, ok, err := w.generate(ctx, template, now)
+			if err != nil {
+				slog.Error("Error generating recurring transaction", "id", template.ID, "error", err)
+				continue
+			}
+			created += n
+			if ok {
+				advanced++
+			}
+		}
+
+		// Stop once every due template has been seen, or when none of this
+		// batch could be moved on and the next query would return it again
+		if len(due) < batchSize || advanced == 0 {
+			return created, nil
+		}
+	}
+}
+
+// generate creates the due occurrences of one template and moves its next
+// occurrence on. It reports false when the template changed in the meantime
+// and was left for the next pass.
+func (w *Worker) generate(ctx context.Context, template repository.RecurringTransaction, now time.Time) (int64, bool, error) {
+	rule := Rule(template)
+	dates := rule.Between(template.LastOccurrence, now, maxOccurrencesPerRun)
+
+	var created int64
+	var err error
+	if len(dates) > 0 {
+		switch template.Type {
+		case "expense":
+			created, err = w.db.MaterializeRecurringExpenses(ctx, repository.MaterializeRecurringExpensesParams{
+				OccurrenceDates: dates,
+				ID:              template.ID,
+			})
+		case "income":
+			created, err = w.db.MaterializeRecurringIncome(ctx, repository.MaterializeRecurringIncomeParams{
+				OccurrenceDates: dates,
+				ID:              template.ID,
+			})
+		default:
+			err = fmt.Errorf("unknown recurring transaction type %q", template.Type)
+		}
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	last := template.LastOccurrence
+	if len(dates) > 0 {
+		last = &dates[len(dates)-1]
+	}
+	rows, err := w.db.AdvanceRecurringTransaction(ctx, repository.AdvanceRecurringTransactionParams{
+		NextOccurrence: NextOccurrence(rule, last),
+		LastOccurrence: last,
+		ID:             template.ID,
+		DueOccurrence:  *template.NextOccurrence,
+	})
+	if err != nil {
+		return created, false, err
+	}
+	return created, rows > 0, nil
+}
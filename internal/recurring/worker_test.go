@@ -0,0 +1,190 @@
+package recurring
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/recurrence"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/jorge-dev/centsible/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func setupWorkerTest(t *testing.T) (*mocks.MockRepository, *Worker) {
+	repo, ok := mocks.NewMockRepository().(*mocks.MockRepository)
+	if !ok {
+		t.Fatal("could not cast to MockRepository")
+	}
+	t.Cleanup(repo.Reset)
+	return repo, NewWorker(repo)
+}
+
+func TestRun(t *testing.T) {
+	repo, worker := setupWorkerTest(t)
+	userID := uuid.New()
+	categoryID := uuid.New()
+	source := "Salary"
+	three := int32(3)
+	start := date(2024, time.January, 31)
+
+	rent := repository.RecurringTransaction{
+		ID:             uuid.New(),
+		UserID:         userID,
+		Type:           "expense",
+		Amount:         money.MustParse("1200.00"),
+		Currency:       "USD",
+		CategoryID:     &categoryID,
+		Description:    "Rent",
+		Frequency:      recurrence.FreqMonthly,
+		IntervalCount:  1,
+		StartDate:      start,
+		NextOccurrence: &start,
+	}
+	salary := repository.RecurringTransaction{
+		ID:              uuid.New(),
+		UserID:          userID,
+		Type:            "income",
+		Amount:          money.MustParse("2500.00"),
+		Currency:        "USD",
+		Source:          &source,
+		Frequency:       recurrence.FreqWeekly,
+		IntervalCount:   2,
+		StartDate:       start,
+		OccurrenceCount: &three,
+		NextOccurrence:  &start,
+	}
+	repo.GetRecurringTransactionMock().AddRecurringTransaction(rent)
+	repo.GetRecurringTransactionMock().AddRecurringTransaction(salary)
+
+	// Rent on Jan 31, Feb 29 and Mar 31, and all three paychecks
+	created, err := worker.Run(context.Background(), date(2024, time.April, 15))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(6), created)
+
+	expenses, _ := repo.ListExpenses(context.Background(), userID)
+	assert.Len(t, expenses, 3)
+	income, _ := repo.ListIncome(context.Background(), userID)
+	assert.Len(t, income, 3)
+
+	saved, _ := repo.GetRecurringTransactionByID(context.Background(), repository.GetRecurringTransactionByIDParams{ID: rent.ID, UserID: userID})
+	assert.Equal(t, date(2024, time.March, 31), *saved.LastOccurrence)
+	assert.Equal(t, date(2024, time.April, 30), *saved.NextOccurrence)
+
+	saved, _ = repo.GetRecurringTransactionByID(context.Background(), repository.GetRecurringTransactionByIDParams{ID: salary.ID, UserID: userID})
+	assert.Equal(t, date(2024, time.February, 28), *saved.LastOccurrence)
+	assert.Nil(t, saved.NextOccurrence)
+
+	// Nothing more is due until the end of April
+	created, err = worker.Run(context.Background(), date(2024, time.April, 29))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(0), created)
+
+	created, err = worker.Run(context.Background(), date(2024, time.May, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), created)
+}
+
+func TestRunSkipsGeneratedOccurrences(t *testing.T) {
+	repo, worker := setupWorkerTest(t)
+	userID := uuid.New()
+	categoryID := uuid.New()
+	start := date(2024, time.January, 1)
+
+	template := repository.RecurringTransaction{
+		ID:             uuid.New(),
+		UserID:         userID,
+		Type:           "expense",
+		Amount:         money.MustParse("9.99"),
+		Currency:       "USD",
+		CategoryID:     &categoryID,
+		Description:    "Streaming",
+		Frequency:      recurrence.FreqMonthly,
+		IntervalCount:  1,
+		StartDate:      start,
+		NextOccurrence: &start,
+	}
+	repo.GetRecurringTransactionMock().AddRecurringTransaction(template)
+
+	created, err := worker.Run(context.Background(), date(2024, time.March, 15))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(3), created)
+
+	// Rewinding the template, as a crash before it was moved on would leave
+	// it, generates nothing twice
+	repo.GetRecurringTransactionMock().AddRecurringTransaction(template)
+	created, err = worker.Run(context.Background(), date(2024, time.March, 15))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(0), created)
+
+	expenses, _ := repo.ListExpenses(context.Background(), userID)
+	assert.Len(t, expenses, 3)
+
+	saved, _ := repo.GetRecurringTransactionByID(context.Background(), repository.GetRecurringTransactionByIDParams{ID: template.ID, UserID: userID})
+	assert.Equal(t, date(2024, time.April, 1), *saved.NextOccurrence)
+}
+
+func TestRunCatchesUpInBatches(t *testing.T) {
+	repo, worker := setupWorkerTest(t)
+	userID := uuid.New()
+	categoryID := uuid.New()
+	start := date(2020, time.January, 1)
+
+	repo.GetRecurringTransactionMock().AddRecurringTransaction(repository.RecurringTransaction{
+		ID:             uuid.New(),
+		UserID:         userID,
+		Type:           "expense",
+		Amount:         money.MustParse("4.50"),
+		Currency:       "USD",
+		CategoryID:     &categoryID,
+		Description:    "Coffee",
+		Frequency:      recurrence.FreqDaily,
+		IntervalCount:  1,
+		StartDate:      start,
+		NextOccurrence: &start,
+	})
+
+	// 376 occurrences are due, more than one pass generates
+	now := date(2021, time.January, 10)
+	created, err := worker.Run(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(maxOccurrencesPerRun), created)
+
+	created, err = worker.Run(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(376-maxOccurrencesPerRun), created)
+}
+
+func TestStart(t *testing.T) {
+	_, worker := setupWorkerTest(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := worker.Start(ctx, time.Hour)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop after its context was cancelled")
+	}
+}
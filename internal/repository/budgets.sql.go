@@ -10,30 +10,34 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/recurrence"
 )
 
 const createBudget = `-- name: CreateBudget :one
 INSERT INTO budgets (
     id, user_id, amount, currency, category_id, 
-    type, start_date, end_date, name, created_at, updated_at
+    type, cadence, rollover, start_date, end_date, name, created_at, updated_at
 )
 VALUES (
     $1, $2, $3, $4, $5, 
-    $6, $7, $8, $9, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+    $6, $7, $8, $9, $10, $11, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
 )
-RETURNING id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name
+RETURNING id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name, cadence, rollover
 `
 
 type CreateBudgetParams struct {
-	ID         uuid.UUID `json:"id"`
-	UserID     uuid.UUID `json:"user_id"`
-	Amount     float64   `json:"amount"`
-	Currency   string    `json:"currency"`
-	CategoryID uuid.UUID `json:"category_id"`
-	Type       string    `json:"type"`
-	StartDate  time.Time `json:"start_date"`
-	EndDate    time.Time `json:"end_date"`
-	Name       string    `json:"name"`
+	ID         uuid.UUID           `json:"id"`
+	UserID     uuid.UUID           `json:"user_id"`
+	Amount     money.Amount        `json:"amount"`
+	Currency   string              `json:"currency"`
+	CategoryID uuid.UUID           `json:"category_id"`
+	Type       string              `json:"type"`
+	Cadence    *recurrence.Cadence `json:"cadence"`
+	Rollover   bool                `json:"rollover"`
+	StartDate  time.Time           `json:"start_date"`
+	EndDate    *time.Time          `json:"end_date"`
+	Name       string              `json:"name"`
 }
 
 func (q *Queries) CreateBudget(ctx context.Context, arg CreateBudgetParams) (Budget, error) {
@@ -44,6 +48,8 @@ func (q *Queries) CreateBudget(ctx context.Context, arg CreateBudgetParams) (Bud
 		arg.Currency,
 		arg.CategoryID,
 		arg.Type,
+		arg.Cadence,
+		arg.Rollover,
 		arg.StartDate,
 		arg.EndDate,
 		arg.Name,
@@ -62,6 +68,8 @@ func (q *Queries) CreateBudget(ctx context.Context, arg CreateBudgetParams) (Bud
 		&i.UpdatedAt,
 		&i.DeletedAt,
 		&i.Name,
+		&i.Cadence,
+		&i.Rollover,
 	)
 	return i, err
 }
@@ -86,7 +94,7 @@ func (q *Queries) DeleteBudget(ctx context.Context, arg DeleteBudgetParams) (int
 }
 
 const getActiveBudgets = `-- name: GetActiveBudgets :many
-SELECT id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name FROM budgets
+SELECT id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name, cadence, rollover FROM budgets
 WHERE user_id = $1 
     AND deleted_at IS NULL
     AND start_date <= CURRENT_DATE
@@ -116,6 +124,8 @@ func (q *Queries) GetActiveBudgets(ctx context.Context, userID uuid.UUID) ([]Bud
 			&i.UpdatedAt,
 			&i.DeletedAt,
 			&i.Name,
+			&i.Cadence,
+			&i.Rollover,
 		); err != nil {
 			return nil, err
 		}
@@ -128,7 +138,7 @@ func (q *Queries) GetActiveBudgets(ctx context.Context, userID uuid.UUID) ([]Bud
 }
 
 const getBudgetByID = `-- name: GetBudgetByID :one
-SELECT id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name FROM budgets
+SELECT id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name, cadence, rollover FROM budgets
 WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
@@ -153,45 +163,162 @@ func (q *Queries) GetBudgetByID(ctx context.Context, arg GetBudgetByIDParams) (B
 		&i.UpdatedAt,
 		&i.DeletedAt,
 		&i.Name,
+		&i.Cadence,
+		&i.Rollover,
 	)
 	return i, err
 }
 
+const getBudgetSpendingByPeriod = `-- name: GetBudgetSpendingByPeriod :many
+SELECT
+    p.budget_id::uuid AS budget_id,
+    p.period_start::timestamptz AS period_start,
+    p.period_end::timestamptz AS period_end,
+    COALESCE(SUM(e.amount * r.rate), 0)::numeric AS spent_amount
+FROM unnest(
+    $1::uuid[],
+    $2::timestamptz[],
+    $3::timestamptz[]
+) AS p(budget_id, period_start, period_end)
+JOIN budgets b ON b.id = p.budget_id
+    AND b.user_id = $4::uuid
+    AND b.deleted_at IS NULL
+LEFT JOIN expenses e ON e.user_id = b.user_id
+    AND e.category_id IN (
+        SELECT ca.category_id FROM category_ancestors($4::uuid) ca WHERE ca.ancestor_id = b.category_id
+    )
+    AND e.deleted_at IS NULL
+    AND e.date >= p.period_start
+    AND e.date < p.period_end
+LEFT JOIN LATERAL (SELECT exchange_rate(e.currency, b.currency, e.date::DATE) AS rate) r ON TRUE
+GROUP BY p.budget_id, p.period_start, p.period_end
+ORDER BY p.budget_id, p.period_start
+`
+
+type GetBudgetSpendingByPeriodParams struct {
+	BudgetIds    []uuid.UUID `json:"budget_ids"`
+	PeriodStarts []time.Time `json:"period_starts"`
+	PeriodEnds   []time.Time `json:"period_ends"`
+	UserID       uuid.UUID   `json:"user_id"`
+}
+
+type GetBudgetSpendingByPeriodRow struct {
+	BudgetID    uuid.UUID    `json:"budget_id"`
+	PeriodStart time.Time    `json:"period_start"`
+	PeriodEnd   time.Time    `json:"period_end"`
+	SpentAmount money.Amount `json:"spent_amount"`
+}
+
+// Spending in each of the given budget periods, converted into the budget's
+// currency at the rate of the day each expense was made. A budget counts the
+// expenses of its category's subcategories too. The arrays are parallel, one
+// entry per period. Expenses without a known rate are left out.
+func (q *Queries) GetBudgetSpendingByPeriod(ctx context.Context, arg GetBudgetSpendingByPeriodParams) ([]GetBudgetSpendingByPeriodRow, error) {
+	rows, err := q.db.Query(ctx, getBudgetSpendingByPeriod,
+		arg.BudgetIds,
+		arg.PeriodStarts,
+		arg.PeriodEnds,
+		arg.UserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBudgetSpendingByPeriodRow
+	for rows.Next() {
+		var i GetBudgetSpendingByPeriodRow
+		if err := rows.Scan(
+			&i.BudgetID,
+			&i.PeriodStart,
+			&i.PeriodEnd,
+			&i.SpentAmount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getBudgetUsage = `-- name: GetBudgetUsage :one
 WITH budget_expenses AS (
-    SELECT COALESCE(SUM(amount), 0)::float8 AS total_spent
-    FROM expenses
-    WHERE user_id = $2::uuid
-      AND category_id = (SELECT category_id FROM budgets WHERE id = $1::uuid)
-      AND deleted_at IS NULL
+    SELECT
+        e.currency,
+        SUM(e.amount) AS amount,
+        SUM(e.amount * r.rate) AS converted_amount,
+        COUNT(*) FILTER (WHERE r.rate IS NULL) AS unconverted_count
+    FROM expenses e
+    JOIN budgets b ON b.user_id = e.user_id
+    CROSS JOIN LATERAL (SELECT exchange_rate(e.currency, b.currency, e.date::DATE) AS rate) r
+    WHERE b.id = $1::uuid
+      AND e.user_id = $2::uuid
+      AND e.category_id IN (
+          SELECT ca.category_id FROM category_ancestors($2::uuid) ca WHERE ca.ancestor_id = b.category_id
+      )
+      AND e.deleted_at IS NULL
+      AND e.date >= $3::timestamptz
+      AND e.date < $4::timestamptz
+    GROUP BY e.currency
+),
+budget_totals AS (
+    SELECT
+        COALESCE(SUM(converted_amount), 0) AS total_spent,
+        COALESCE(
+            json_agg(json_build_object(
+                'currency', currency,
+                'amount', amount,
+                'converted_amount', converted_amount
+            ) ORDER BY currency),
+            '[]'
+        ) AS spent_by_currency,
+        COALESCE(ARRAY_AGG(currency ORDER BY currency) FILTER (WHERE unconverted_count > 0), '{}') AS missing_rates
+    FROM budget_expenses
 )
 SELECT 
-    b.id, b.user_id, b.amount, b.currency, b.category_id, b.type, b.start_date, b.end_date, b.created_at, b.updated_at, b.deleted_at, b.name, -- Embed the entire budget row
-    e.total_spent::float8 AS spent_amount,
+    b.id, b.user_id, b.amount, b.currency, b.category_id, b.type, b.start_date, b.end_date, b.created_at, b.updated_at, b.deleted_at, b.name, b.cadence, b.rollover, -- Embed the entire budget row
+    t.total_spent::numeric AS spent_amount,
     CASE 
-        WHEN b.amount > 0 THEN (e.total_spent / b.amount * 100)::float8
+        WHEN b.amount > 0 THEN (t.total_spent / b.amount * 100)::float8
         ELSE 0.0
-    END AS usage_percentage
+    END AS usage_percentage,
+    t.spent_by_currency::json AS spent_by_currency,
+    t.missing_rates::varchar[] AS missing_rates
 FROM budgets b
-CROSS JOIN budget_expenses e
+CROSS JOIN budget_totals t
 WHERE b.id = $1::uuid
   AND b.user_id = $2::uuid
   AND b.deleted_at IS NULL
 `
 
 type GetBudgetUsageParams struct {
-	BudgetID uuid.UUID `json:"budget_id"`
-	UserID   uuid.UUID `json:"user_id"`
+	BudgetID    uuid.UUID `json:"budget_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
 }
 
 type GetBudgetUsageRow struct {
-	Budget          Budget  `json:"budget"`
-	SpentAmount     float64 `json:"spent_amount"`
-	UsagePercentage float64 `json:"usage_percentage"`
+	Budget          Budget       `json:"budget"`
+	SpentAmount     money.Amount `json:"spent_amount"`
+	UsagePercentage float64      `json:"usage_percentage"`
+	SpentByCurrency []byte       `json:"spent_by_currency"`
+	MissingRates    []string     `json:"missing_rates"`
 }
 
+// Only expenses dated inside the given period count, in the budget's category
+// or any of its subcategories. They are converted into the budget's currency
+// at the rate of the day they were made; currencies without a known rate are
+// left out of spent_amount and listed in missing_rates.
 func (q *Queries) GetBudgetUsage(ctx context.Context, arg GetBudgetUsageParams) (GetBudgetUsageRow, error) {
-	row := q.db.QueryRow(ctx, getBudgetUsage, arg.BudgetID, arg.UserID)
+	row := q.db.QueryRow(ctx, getBudgetUsage,
+		arg.BudgetID,
+		arg.UserID,
+		arg.PeriodStart,
+		arg.PeriodEnd,
+	)
 	var i GetBudgetUsageRow
 	err := row.Scan(
 		&i.Budget.ID,
@@ -206,14 +333,18 @@ func (q *Queries) GetBudgetUsage(ctx context.Context, arg GetBudgetUsageParams)
 		&i.Budget.UpdatedAt,
 		&i.Budget.DeletedAt,
 		&i.Budget.Name,
+		&i.Budget.Cadence,
+		&i.Budget.Rollover,
 		&i.SpentAmount,
 		&i.UsagePercentage,
+		&i.SpentByCurrency,
+		&i.MissingRates,
 	)
 	return i, err
 }
 
 const getBudgetsByCategory = `-- name: GetBudgetsByCategory :many
-SELECT id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name FROM budgets
+SELECT id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name, cadence, rollover FROM budgets
 WHERE user_id = $1 
     AND category_id = $2 
     AND deleted_at IS NULL
@@ -247,6 +378,8 @@ func (q *Queries) GetBudgetsByCategory(ctx context.Context, arg GetBudgetsByCate
 			&i.UpdatedAt,
 			&i.DeletedAt,
 			&i.Name,
+			&i.Cadence,
+			&i.Rollover,
 		); err != nil {
 			return nil, err
 		}
@@ -258,72 +391,38 @@ func (q *Queries) GetBudgetsByCategory(ctx context.Context, arg GetBudgetsByCate
 	return items, nil
 }
 
-const getBudgetsNearLimit = `-- name: GetBudgetsNearLimit :many
-SELECT 
-    b.id, b.user_id, b.amount, b.currency, b.category_id, b.type, b.start_date, b.end_date, b.created_at, b.updated_at, b.deleted_at, b.name, -- Embed the entire budget row
-    COALESCE(spent_data.spent_amount, 0)::float8 AS spent_amount,
-    CASE 
-        WHEN b.amount > 0 THEN (COALESCE(spent_data.spent_amount, 0) / b.amount * 100)::float8
-        ELSE 0.0
-    END AS usage_percentage
-FROM budgets b
-LEFT JOIN (
-    SELECT 
-        e.category_id,
-        e.user_id,
-        SUM(e.amount) AS spent_amount
-    FROM expenses e
-    WHERE e.deleted_at IS NULL
-    GROUP BY e.category_id, e.user_id
-) AS spent_data 
-ON b.category_id = spent_data.category_id 
-   AND b.user_id = spent_data.user_id
-WHERE b.user_id = $1::uuid
-  AND b.deleted_at IS NULL
-  AND b.start_date <= CURRENT_DATE
-  AND (b.end_date >= CURRENT_DATE OR b.end_date IS NULL)
-  AND CASE 
-        WHEN b.amount > 0 THEN (COALESCE(spent_data.spent_amount, 0) / b.amount * 100)
-        ELSE 0 
-      END >= $2::float8
-ORDER BY usage_percentage DESC
+const getOneTimeBudgets = `-- name: GetOneTimeBudgets :many
+SELECT id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name, cadence, rollover FROM budgets
+WHERE user_id = $1 
+    AND type = 'one-time'
+    AND deleted_at IS NULL
+ORDER BY start_date ASC
 `
 
-type GetBudgetsNearLimitParams struct {
-	UserID    uuid.UUID `json:"user_id"`
-	Threshold float64   `json:"threshold"`
-}
-
-type GetBudgetsNearLimitRow struct {
-	Budget          Budget  `json:"budget"`
-	SpentAmount     float64 `json:"spent_amount"`
-	UsagePercentage float64 `json:"usage_percentage"`
-}
-
-func (q *Queries) GetBudgetsNearLimit(ctx context.Context, arg GetBudgetsNearLimitParams) ([]GetBudgetsNearLimitRow, error) {
-	rows, err := q.db.Query(ctx, getBudgetsNearLimit, arg.UserID, arg.Threshold)
+func (q *Queries) GetOneTimeBudgets(ctx context.Context, userID uuid.UUID) ([]Budget, error) {
+	rows, err := q.db.Query(ctx, getOneTimeBudgets, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetBudgetsNearLimitRow
+	var items []Budget
 	for rows.Next() {
-		var i GetBudgetsNearLimitRow
+		var i Budget
 		if err := rows.Scan(
-			&i.Budget.ID,
-			&i.Budget.UserID,
-			&i.Budget.Amount,
-			&i.Budget.Currency,
-			&i.Budget.CategoryID,
-			&i.Budget.Type,
-			&i.Budget.StartDate,
-			&i.Budget.EndDate,
-			&i.Budget.CreatedAt,
-			&i.Budget.UpdatedAt,
-			&i.Budget.DeletedAt,
-			&i.Budget.Name,
-			&i.SpentAmount,
-			&i.UsagePercentage,
+			&i.ID,
+			&i.UserID,
+			&i.Amount,
+			&i.Currency,
+			&i.CategoryID,
+			&i.Type,
+			&i.StartDate,
+			&i.EndDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Name,
+			&i.Cadence,
+			&i.Rollover,
 		); err != nil {
 			return nil, err
 		}
@@ -335,16 +434,16 @@ func (q *Queries) GetBudgetsNearLimit(ctx context.Context, arg GetBudgetsNearLim
 	return items, nil
 }
 
-const getOneTimeBudgets = `-- name: GetOneTimeBudgets :many
-SELECT id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name FROM budgets
+const getRecurringBudgets = `-- name: GetRecurringBudgets :many
+SELECT id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name, cadence, rollover FROM budgets
 WHERE user_id = $1 
-    AND type = 'one-time'
+    AND type = 'recurring'
     AND deleted_at IS NULL
 ORDER BY start_date ASC
 `
 
-func (q *Queries) GetOneTimeBudgets(ctx context.Context, userID uuid.UUID) ([]Budget, error) {
-	rows, err := q.db.Query(ctx, getOneTimeBudgets, userID)
+func (q *Queries) GetRecurringBudgets(ctx context.Context, userID uuid.UUID) ([]Budget, error) {
+	rows, err := q.db.Query(ctx, getRecurringBudgets, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -365,6 +464,8 @@ func (q *Queries) GetOneTimeBudgets(ctx context.Context, userID uuid.UUID) ([]Bu
 			&i.UpdatedAt,
 			&i.DeletedAt,
 			&i.Name,
+			&i.Cadence,
+			&i.Rollover,
 		); err != nil {
 			return nil, err
 		}
@@ -376,16 +477,14 @@ func (q *Queries) GetOneTimeBudgets(ctx context.Context, userID uuid.UUID) ([]Bu
 	return items, nil
 }
 
-const getRecurringBudgets = `-- name: GetRecurringBudgets :many
-SELECT id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name FROM budgets
-WHERE user_id = $1 
-    AND type = 'recurring'
-    AND deleted_at IS NULL
-ORDER BY start_date ASC
+const listBudgets = `-- name: ListBudgets :many
+SELECT id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name, cadence, rollover FROM budgets
+WHERE user_id = $1 AND deleted_at IS NULL
+ORDER BY created_at DESC
 `
 
-func (q *Queries) GetRecurringBudgets(ctx context.Context, userID uuid.UUID) ([]Budget, error) {
-	rows, err := q.db.Query(ctx, getRecurringBudgets, userID)
+func (q *Queries) ListBudgets(ctx context.Context, userID uuid.UUID) ([]Budget, error) {
+	rows, err := q.db.Query(ctx, listBudgets, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -406,6 +505,8 @@ func (q *Queries) GetRecurringBudgets(ctx context.Context, userID uuid.UUID) ([]
 			&i.UpdatedAt,
 			&i.DeletedAt,
 			&i.Name,
+			&i.Cadence,
+			&i.Rollover,
 		); err != nil {
 			return nil, err
 		}
@@ -417,14 +518,78 @@ func (q *Queries) GetRecurringBudgets(ctx context.Context, userID uuid.UUID) ([]
 	return items, nil
 }
 
-const listBudgets = `-- name: ListBudgets :many
-SELECT id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name FROM budgets
-WHERE user_id = $1 AND deleted_at IS NULL
-ORDER BY created_at DESC
+const listBudgetsPage = `-- name: ListBudgetsPage :many
+SELECT id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name, cadence, rollover FROM budgets
+WHERE user_id = $1
+    AND deleted_at IS NULL
+    AND ($2::NUMERIC IS NULL OR amount >= $2::NUMERIC)
+    AND ($3::NUMERIC IS NULL OR amount <= $3::NUMERIC)
+    AND ($4::VARCHAR = '' OR currency = $4::VARCHAR)
+    AND ($5::UUID IS NULL OR category_id = $5::UUID)
+    AND ($6::TEXT = '' OR STRPOS(LOWER(name), LOWER($6::TEXT)) > 0)
+    AND ($7::TIMESTAMPTZ IS NULL OR start_date >= $7::TIMESTAMPTZ)
+    AND ($8::TIMESTAMPTZ IS NULL OR start_date <= $8::TIMESTAMPTZ)
+    AND ($9::UUID IS NULL OR CASE
+        WHEN $10::TEXT = 'amount' AND $11::BOOLEAN
+            THEN (amount, id) < ($12::NUMERIC, $9::UUID)
+        WHEN $10::TEXT = 'amount'
+            THEN (amount, id) > ($12::NUMERIC, $9::UUID)
+        WHEN $10::TEXT = 'created_at' AND $11::BOOLEAN
+            THEN (created_at, id) < ($13::TIMESTAMPTZ, $9::UUID)
+        WHEN $10::TEXT = 'created_at'
+            THEN (created_at, id) > ($13::TIMESTAMPTZ, $9::UUID)
+        WHEN $11::BOOLEAN
+            THEN (start_date, id) < ($13::TIMESTAMPTZ, $9::UUID)
+        ELSE (start_date, id) > ($13::TIMESTAMPTZ, $9::UUID)
+    END)
+ORDER BY
+    CASE WHEN $10::TEXT = 'amount' AND NOT $11::BOOLEAN THEN amount END,
+    CASE WHEN $10::TEXT = 'amount' AND $11::BOOLEAN THEN amount END DESC,
+    CASE WHEN $10::TEXT = 'created_at' AND NOT $11::BOOLEAN THEN created_at END,
+    CASE WHEN $10::TEXT = 'created_at' AND $11::BOOLEAN THEN created_at END DESC,
+    CASE WHEN $10::TEXT NOT IN ('amount', 'created_at') AND NOT $11::BOOLEAN THEN start_date END,
+    CASE WHEN $10::TEXT NOT IN ('amount', 'created_at') AND $11::BOOLEAN THEN start_date END DESC,
+    CASE WHEN NOT $11::BOOLEAN THEN id END,
+    id DESC
+LIMIT $14
 `
 
-func (q *Queries) ListBudgets(ctx context.Context, userID uuid.UUID) ([]Budget, error) {
-	rows, err := q.db.Query(ctx, listBudgets, userID)
+type ListBudgetsPageParams struct {
+	UserID      uuid.UUID     `json:"user_id"`
+	MinAmount   *money.Amount `json:"min_amount"`
+	MaxAmount   *money.Amount `json:"max_amount"`
+	Currency    string        `json:"currency"`
+	CategoryID  *uuid.UUID    `json:"category_id"`
+	Search      string        `json:"search"`
+	StartDate   *time.Time    `json:"start_date"`
+	EndDate     *time.Time    `json:"end_date"`
+	AfterID     *uuid.UUID    `json:"after_id"`
+	Sort        string        `json:"sort"`
+	Descending  bool          `json:"descending"`
+	AfterAmount *money.Amount `json:"after_amount"`
+	AfterTime   *time.Time    `json:"after_time"`
+	PageSize    int32         `json:"page_size"`
+}
+
+// Lists a page of a user's budgets like ListExpensesPage. Budgets are dated
+// by their start date and searched by name.
+func (q *Queries) ListBudgetsPage(ctx context.Context, arg ListBudgetsPageParams) ([]Budget, error) {
+	rows, err := q.db.Query(ctx, listBudgetsPage,
+		arg.UserID,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Currency,
+		arg.CategoryID,
+		arg.Search,
+		arg.StartDate,
+		arg.EndDate,
+		arg.AfterID,
+		arg.Sort,
+		arg.Descending,
+		arg.AfterAmount,
+		arg.AfterTime,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -445,6 +610,8 @@ func (q *Queries) ListBudgets(ctx context.Context, userID uuid.UUID) ([]Budget,
 			&i.UpdatedAt,
 			&i.DeletedAt,
 			&i.Name,
+			&i.Cadence,
+			&i.Rollover,
 		); err != nil {
 			return nil, err
 		}
@@ -463,24 +630,28 @@ SET
     currency = $3,
     category_id = $4,
     type = $5,
-    start_date = $6,
-    end_date = $7,
-    name = $8,
+    cadence = $6,
+    rollover = $7,
+    start_date = $8,
+    end_date = $9,
+    name = $10,
     updated_at = CURRENT_TIMESTAMP
-WHERE id = $1 AND user_id = $9 AND deleted_at IS NULL
-RETURNING id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name
+WHERE id = $1 AND user_id = $11 AND deleted_at IS NULL
+RETURNING id, user_id, amount, currency, category_id, type, start_date, end_date, created_at, updated_at, deleted_at, name, cadence, rollover
 `
 
 type UpdateBudgetParams struct {
-	ID         uuid.UUID `json:"id"`
-	Amount     float64   `json:"amount"`
-	Currency   string    `json:"currency"`
-	CategoryID uuid.UUID `json:"category_id"`
-	Type       string    `json:"type"`
-	StartDate  time.Time `json:"start_date"`
-	EndDate    time.Time `json:"end_date"`
-	Name       string    `json:"name"`
-	UserID     uuid.UUID `json:"user_id"`
+	ID         uuid.UUID           `json:"id"`
+	Amount     money.Amount        `json:"amount"`
+	Currency   string              `json:"currency"`
+	CategoryID uuid.UUID           `json:"category_id"`
+	Type       string              `json:"type"`
+	Cadence    *recurrence.Cadence `json:"cadence"`
+	Rollover   bool                `json:"rollover"`
+	StartDate  time.Time           `json:"start_date"`
+	EndDate    *time.Time          `json:"end_date"`
+	Name       string              `json:"name"`
+	UserID     uuid.UUID           `json:"user_id"`
 }
 
 func (q *Queries) UpdateBudget(ctx context.Context, arg UpdateBudgetParams) (Budget, error) {
@@ -490,6 +661,8 @@ func (q *Queries) UpdateBudget(ctx context.Context, arg UpdateBudgetParams) (Bud
 		arg.Currency,
 		arg.CategoryID,
 		arg.Type,
+		arg.Cadence,
+		arg.Rollover,
 		arg.StartDate,
 		arg.EndDate,
 		arg.Name,
@@ -509,6 +682,8 @@ func (q *Queries) UpdateBudget(ctx context.Context, arg UpdateBudgetParams) (Bud
 		&i.UpdatedAt,
 		&i.DeletedAt,
 		&i.Name,
+		&i.Cadence,
+		&i.Rollover,
 	)
 	return i, err
 }
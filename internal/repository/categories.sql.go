@@ -7,8 +7,10 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 )
 
 const checkCategoryExists = `-- name: CheckCategoryExists :one
@@ -31,19 +33,25 @@ func (q *Queries) CheckCategoryExists(ctx context.Context, arg CheckCategoryExis
 }
 
 const createCategory = `-- name: CreateCategory :one
-INSERT INTO categories (id, user_id, name, created_at, updated_at)
-VALUES ($1, $2, $3, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-RETURNING id, user_id, name, created_at, updated_at, deleted_at
+INSERT INTO categories (id, user_id, name, parent_id, created_at, updated_at)
+VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+RETURNING id, user_id, name, created_at, updated_at, deleted_at, parent_id
 `
 
 type CreateCategoryParams struct {
-	ID     uuid.UUID `json:"id"`
-	UserID uuid.UUID `json:"user_id"`
-	Name   string    `json:"name"`
+	ID       uuid.UUID  `json:"id"`
+	UserID   uuid.UUID  `json:"user_id"`
+	Name     string     `json:"name"`
+	ParentID *uuid.UUID `json:"parent_id"`
 }
 
 func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error) {
-	row := q.db.QueryRow(ctx, createCategory, arg.ID, arg.UserID, arg.Name)
+	row := q.db.QueryRow(ctx, createCategory,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.ParentID,
+	)
 	var i Category
 	err := row.Scan(
 		&i.ID,
@@ -52,14 +60,33 @@ func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.ParentID,
 	)
 	return i, err
 }
 
 const deleteCategory = `-- name: DeleteCategory :execrows
-UPDATE categories 
+WITH deleted AS (
+    SELECT d.id, d.parent_id
+    FROM categories d
+    WHERE d.id = $1 AND d.user_id = $2 AND d.deleted_at IS NULL
+        AND NOT EXISTS (SELECT 1 FROM expenses e WHERE e.category_id = d.id AND e.deleted_at IS NULL)
+        AND NOT EXISTS (SELECT 1 FROM budgets b WHERE b.category_id = d.id AND b.deleted_at IS NULL)
+        AND NOT EXISTS (SELECT 1 FROM recurring_transactions r WHERE r.category_id = d.id AND r.deleted_at IS NULL)
+        AND NOT EXISTS (SELECT 1 FROM category_rules cr WHERE cr.category_id = d.id AND cr.deleted_at IS NULL)
+),
+moved_subcategories AS (
+    UPDATE categories c
+    SET
+        parent_id = d.parent_id,
+        updated_at = CURRENT_TIMESTAMP
+    FROM deleted d
+    WHERE c.parent_id = d.id AND c.deleted_at IS NULL
+)
+UPDATE categories c
 SET deleted_at = CURRENT_TIMESTAMP
-WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+FROM deleted d
+WHERE c.id = d.id
 `
 
 type DeleteCategoryParams struct {
@@ -67,6 +94,11 @@ type DeleteCategoryParams struct {
 	UserID uuid.UUID `json:"user_id"`
 }
 
+// Only deletes a category that is not in use; see IsCategoryInUse. Use
+// MergeCategory to move its records and rules elsewhere first. Its
+// subcategories move up to its parent in the same statement, so they keep
+// rolling up into the categories above it and are only moved when it is
+// deleted.
 func (q *Queries) DeleteCategory(ctx context.Context, arg DeleteCategoryParams) (int64, error) {
 	result, err := q.db.Exec(ctx, deleteCategory, arg.ID, arg.UserID)
 	if err != nil {
@@ -76,7 +108,7 @@ func (q *Queries) DeleteCategory(ctx context.Context, arg DeleteCategoryParams)
 }
 
 const getCategoryByID = `-- name: GetCategoryByID :one
-SELECT id, user_id, name, created_at, updated_at, deleted_at FROM categories
+SELECT id, user_id, name, created_at, updated_at, deleted_at, parent_id FROM categories
 WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
@@ -95,26 +127,31 @@ func (q *Queries) GetCategoryByID(ctx context.Context, arg GetCategoryByIDParams
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.ParentID,
 	)
 	return i, err
 }
 
 const getCategoryUsage = `-- name: GetCategoryUsage :one
+WITH subtree AS (
+    SELECT category_id FROM category_ancestors($2) WHERE ancestor_id = $1
+)
 SELECT 
     c.id,
     c.name,
     COUNT(DISTINCT e.id) as expense_count,
-    COUNT(DISTINCT b.id) as budget_count,
-    COALESCE(SUM(e.amount), 0) as total_expenses
+    (
+        SELECT COUNT(*) FROM budgets b
+        WHERE b.category_id IN (SELECT category_id FROM subtree)
+            AND b.user_id = c.user_id
+            AND b.deleted_at IS NULL
+    )::bigint as budget_count,
+    COALESCE(SUM(e.amount), 0)::numeric as total_expenses
 FROM categories c
 LEFT JOIN expenses e ON 
-     e.category_id = c.id
+     e.category_id IN (SELECT category_id FROM subtree)
     AND e.user_id = c.user_id 
     AND e.deleted_at IS NULL
-LEFT JOIN budgets b ON 
-     b.category_id = c.id 
-    AND b.user_id = c.user_id 
-    AND b.deleted_at IS NULL
 WHERE c.id = $1 AND c.user_id = $2 AND c.deleted_at IS NULL
 GROUP BY c.id, c.name
 `
@@ -125,13 +162,15 @@ type GetCategoryUsageParams struct {
 }
 
 type GetCategoryUsageRow struct {
-	ID            uuid.UUID   `json:"id"`
-	Name          string      `json:"name"`
-	ExpenseCount  int64       `json:"expense_count"`
-	BudgetCount   int64       `json:"budget_count"`
-	TotalExpenses interface{} `json:"total_expenses"`
+	ID            uuid.UUID    `json:"id"`
+	Name          string       `json:"name"`
+	ExpenseCount  int64        `json:"expense_count"`
+	BudgetCount   int64        `json:"budget_count"`
+	TotalExpenses money.Amount `json:"total_expenses"`
 }
 
+// Counts and totals the expenses and budgets of a category together with
+// those of all of its subcategories
 func (q *Queries) GetCategoryUsage(ctx context.Context, arg GetCategoryUsageParams) (GetCategoryUsageRow, error) {
 	row := q.db.QueryRow(ctx, getCategoryUsage, arg.ID, arg.UserID)
 	var i GetCategoryUsageRow
@@ -149,15 +188,16 @@ const getMostUsedCategories = `-- name: GetMostUsedCategories :many
 SELECT 
     c.name,
     COUNT(e.id) as usage_count,
-    COALESCE(SUM(e.amount), 0) as total_amount
+    COALESCE(SUM(e.amount), 0)::numeric as total_amount
 FROM categories c
+JOIN category_ancestors($1::UUID) ca ON ca.ancestor_id = c.id
 LEFT JOIN expenses e ON 
-    e.category_id = c.id 
+    e.category_id = ca.category_id 
     AND e.user_id = c.user_id 
     AND e.deleted_at IS NULL
 WHERE c.user_id = $1::UUID
     AND c.deleted_at IS NULL
-GROUP BY c.name
+GROUP BY c.id, c.name
 ORDER BY usage_count DESC
 LIMIT $2::int
 `
@@ -168,11 +208,13 @@ type GetMostUsedCategoriesParams struct {
 }
 
 type GetMostUsedCategoriesRow struct {
-	Name        string      `json:"name"`
-	UsageCount  int64       `json:"usage_count"`
-	TotalAmount interface{} `json:"total_amount"`
+	Name        string       `json:"name"`
+	UsageCount  int64        `json:"usage_count"`
+	TotalAmount money.Amount `json:"total_amount"`
 }
 
+// Ranks a user's categories by their expenses, counting each category's
+// subcategories towards it
 func (q *Queries) GetMostUsedCategories(ctx context.Context, arg GetMostUsedCategoriesParams) ([]GetMostUsedCategoriesRow, error) {
 	rows, err := q.db.Query(ctx, getMostUsedCategories, arg.ID, arg.Limit)
 	if err != nil {
@@ -193,8 +235,53 @@ func (q *Queries) GetMostUsedCategories(ctx context.Context, arg GetMostUsedCate
 	return items, nil
 }
 
+const isCategoryInSubtree = `-- name: IsCategoryInSubtree :one
+SELECT EXISTS(
+    SELECT 1 FROM category_ancestors($1)
+    WHERE category_id = $2 AND ancestor_id = $3
+)
+`
+
+type IsCategoryInSubtreeParams struct {
+	UserID     uuid.UUID `json:"user_id"`
+	CategoryID uuid.UUID `json:"category_id"`
+	RootID     uuid.UUID `json:"root_id"`
+}
+
+// Reports whether category_id is root_id or one of its subcategories, at
+// any depth
+func (q *Queries) IsCategoryInSubtree(ctx context.Context, arg IsCategoryInSubtreeParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isCategoryInSubtree, arg.UserID, arg.CategoryID, arg.RootID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const isCategoryInUse = `-- name: IsCategoryInUse :one
+SELECT
+    EXISTS(SELECT 1 FROM expenses e WHERE e.category_id = $1 AND e.user_id = $2 AND e.deleted_at IS NULL)
+    OR EXISTS(SELECT 1 FROM budgets b WHERE b.category_id = $1 AND b.user_id = $2 AND b.deleted_at IS NULL)
+    OR EXISTS(SELECT 1 FROM recurring_transactions r WHERE r.category_id = $1 AND r.user_id = $2 AND r.deleted_at IS NULL)
+    OR EXISTS(SELECT 1 FROM category_rules cr WHERE cr.category_id = $1 AND cr.user_id = $2 AND cr.deleted_at IS NULL) AS in_use
+`
+
+type IsCategoryInUseParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// Reports whether any expense, budget, recurring transaction or category
+// rule still uses a category. Its records would drop out of the totals once
+// it is deleted, and its rules would file expenses under a deleted category.
+func (q *Queries) IsCategoryInUse(ctx context.Context, arg IsCategoryInUseParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isCategoryInUse, arg.ID, arg.UserID)
+	var in_use bool
+	err := row.Scan(&in_use)
+	return in_use, err
+}
+
 const listCategories = `-- name: ListCategories :many
-SELECT id, user_id, name, created_at, updated_at, deleted_at FROM categories
+SELECT id, user_id, name, created_at, updated_at, deleted_at, parent_id FROM categories
 WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY name ASC
 `
@@ -215,6 +302,7 @@ func (q *Queries) ListCategories(ctx context.Context, userID uuid.UUID) ([]Categ
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.ParentID,
 		); err != nil {
 			return nil, err
 		}
@@ -226,23 +314,182 @@ func (q *Queries) ListCategories(ctx context.Context, userID uuid.UUID) ([]Categ
 	return items, nil
 }
 
+const listCategoriesPage = `-- name: ListCategoriesPage :many
+SELECT id, user_id, name, created_at, updated_at, deleted_at, parent_id FROM categories
+WHERE user_id = $1
+    AND deleted_at IS NULL
+    AND ($2::TEXT = '' OR STRPOS(LOWER(name), LOWER($2::TEXT)) > 0)
+    AND ($3::UUID IS NULL OR CASE
+        WHEN $4::TEXT = 'created_at' AND $5::BOOLEAN
+            THEN (created_at, id) < ($6::TIMESTAMPTZ, $3::UUID)
+        WHEN $4::TEXT = 'created_at'
+            THEN (created_at, id) > ($6::TIMESTAMPTZ, $3::UUID)
+        WHEN $5::BOOLEAN
+            THEN (name, id) < ($7::TEXT, $3::UUID)
+        ELSE (name, id) > ($7::TEXT, $3::UUID)
+    END)
+ORDER BY
+    CASE WHEN $4::TEXT = 'created_at' AND NOT $5::BOOLEAN THEN created_at END,
+    CASE WHEN $4::TEXT = 'created_at' AND $5::BOOLEAN THEN created_at END DESC,
+    CASE WHEN $4::TEXT <> 'created_at' AND NOT $5::BOOLEAN THEN name END,
+    CASE WHEN $4::TEXT <> 'created_at' AND $5::BOOLEAN THEN name END DESC,
+    CASE WHEN NOT $5::BOOLEAN THEN id END,
+    id DESC
+LIMIT $8
+`
+
+type ListCategoriesPageParams struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	Search     string     `json:"search"`
+	AfterID    *uuid.UUID `json:"after_id"`
+	Sort       string     `json:"sort"`
+	Descending bool       `json:"descending"`
+	AfterTime  *time.Time `json:"after_time"`
+	AfterName  string     `json:"after_name"`
+	PageSize   int32      `json:"page_size"`
+}
+
+// Lists a page of a user's categories sorted by name or created_at, with the
+// ID breaking ties, continuing after the cursor like ListExpensesPage
+func (q *Queries) ListCategoriesPage(ctx context.Context, arg ListCategoriesPageParams) ([]Category, error) {
+	rows, err := q.db.Query(ctx, listCategoriesPage,
+		arg.UserID,
+		arg.Search,
+		arg.AfterID,
+		arg.Sort,
+		arg.Descending,
+		arg.AfterTime,
+		arg.AfterName,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Category
+	for rows.Next() {
+		var i Category
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.ParentID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const mergeCategory = `-- name: MergeCategory :execrows
+WITH merge AS (
+    SELECT s.id AS source_id, s.parent_id, t.id AS target_id
+    FROM categories s
+    JOIN categories t ON t.user_id = s.user_id AND t.deleted_at IS NULL
+    WHERE s.id = $1
+        AND s.user_id = $2
+        AND s.deleted_at IS NULL
+        AND t.id = $3
+        AND t.id <> s.id
+),
+moved_expenses AS (
+    UPDATE expenses e
+    SET category_id = m.target_id, updated_at = CURRENT_TIMESTAMP
+    FROM merge m
+    WHERE e.category_id = m.source_id
+),
+moved_budgets AS (
+    UPDATE budgets b
+    SET category_id = m.target_id, updated_at = CURRENT_TIMESTAMP
+    FROM merge m
+    WHERE b.category_id = m.source_id
+),
+moved_recurring_transactions AS (
+    UPDATE recurring_transactions r
+    SET category_id = m.target_id, updated_at = CURRENT_TIMESTAMP
+    FROM merge m
+    WHERE r.category_id = m.source_id
+),
+moved_category_rules AS (
+    UPDATE category_rules cr
+    SET category_id = m.target_id, updated_at = CURRENT_TIMESTAMP
+    FROM merge m
+    WHERE cr.category_id = m.source_id
+),
+moved_subcategories AS (
+    UPDATE categories c
+    SET
+        parent_id = CASE
+            WHEN $4::BOOLEAN AND c.id <> m.target_id THEN m.target_id
+            ELSE m.parent_id
+        END,
+        updated_at = CURRENT_TIMESTAMP
+    FROM merge m
+    WHERE c.parent_id = m.source_id AND c.deleted_at IS NULL
+)
+UPDATE categories c
+SET deleted_at = CURRENT_TIMESTAMP
+FROM merge m
+WHERE c.id = m.source_id
+`
+
+type MergeCategoryParams struct {
+	ID                 uuid.UUID `json:"id"`
+	UserID             uuid.UUID `json:"user_id"`
+	TargetID           uuid.UUID `json:"target_id"`
+	AdoptSubcategories bool      `json:"adopt_subcategories"`
+}
+
+// Moves the expenses, budgets, recurring transactions and rules of a
+// category, deleted ones included, to target_id and deletes the category, all in one
+// statement so that nothing is left pointing at a deleted category. Its
+// subcategories go under the target when adopt_subcategories is set, and up
+// to its parent otherwise; a subcategory that is the target always goes up.
+// Nothing changes unless both are live categories of the user and differ.
+func (q *Queries) MergeCategory(ctx context.Context, arg MergeCategoryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, mergeCategory,
+		arg.ID,
+		arg.UserID,
+		arg.TargetID,
+		arg.AdoptSubcategories,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateCategory = `-- name: UpdateCategory :one
 UPDATE categories 
 SET 
     name = $2,
+    parent_id = $4,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = $1 AND user_id = $3 AND deleted_at IS NULL
-RETURNING id, user_id, name, created_at, updated_at, deleted_at
+RETURNING id, user_id, name, created_at, updated_at, deleted_at, parent_id
 `
 
 type UpdateCategoryParams struct {
-	ID     uuid.UUID `json:"id"`
-	Name   string    `json:"name"`
-	UserID uuid.UUID `json:"user_id"`
+	ID       uuid.UUID  `json:"id"`
+	Name     string     `json:"name"`
+	UserID   uuid.UUID  `json:"user_id"`
+	ParentID *uuid.UUID `json:"parent_id"`
 }
 
 func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams) (Category, error) {
-	row := q.db.QueryRow(ctx, updateCategory, arg.ID, arg.Name, arg.UserID)
+	row := q.db.QueryRow(ctx, updateCategory,
+		arg.ID,
+		arg.Name,
+		arg.UserID,
+		arg.ParentID,
+	)
 	var i Category
 	err := row.Scan(
 		&i.ID,
@@ -251,6 +498,7 @@ func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.ParentID,
 	)
 	return i, err
 }
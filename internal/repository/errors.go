@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// The unique indexes that keep a user's external IDs apart, so a bank
+// transaction is only saved once
+const (
+	ExpenseExternalIDIndex = "idx_expenses_external_id"
+	IncomeExternalIDIndex  = "idx_income_external_id"
+)
+
+// IsUniqueViolation reports whether err is Postgres refusing a write that
+// would repeat a key of the named unique constraint or index
+func IsUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	// unique_violation
+	return pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsUniqueViolation(t *testing.T) {
+	externalID := &pgconn.PgError{Code: "23505", ConstraintName: ExpenseExternalIDIndex}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"External ID taken", externalID, true},
+		{"Wrapped", fmt.Errorf("importing transactions: %w", externalID), true},
+		{"Another index", &pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"}, false},
+		{"Other error on the index", &pgconn.PgError{Code: "23502", ConstraintName: ExpenseExternalIDIndex}, false},
+		{"Other error", errors.New("boom"), false},
+		{"No error", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsUniqueViolation(tt.err, ExpenseExternalIDIndex))
+		})
+	}
+}
@@ -10,30 +10,45 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 )
 
 const createExpense = `-- name: CreateExpense :one
-INSERT INTO expenses (
-    id, user_id, amount, currency, category_id,
-    date, description, created_at, updated_at
+WITH new_expense AS (
+    INSERT INTO expenses (
+        id, user_id, amount, currency, category_id,
+        date, description, external_id, created_at, updated_at
+    )
+    VALUES (
+        $1, $2, $3, $4, $5,
+        $6, $7, $8, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+    )
+    RETURNING id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at, external_id
+),
+tagged AS (
+    INSERT INTO expense_tags (expense_id, tag_id)
+    SELECT e.id, t.id
+    FROM new_expense e
+    JOIN tags t ON t.user_id = e.user_id AND t.deleted_at IS NULL
+    WHERE t.id = ANY($9::UUID[])
 )
-VALUES (
-    $1, $2, $3, $4, $5,
-    $6, $7, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
-)
-RETURNING id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at
+SELECT id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at, external_id FROM new_expense
 `
 
 type CreateExpenseParams struct {
-	ID          uuid.UUID `json:"id"`
-	UserID      uuid.UUID `json:"user_id"`
-	Amount      float64   `json:"amount"`
-	Currency    string    `json:"currency"`
-	CategoryID  uuid.UUID `json:"category_id"`
-	Date        time.Time `json:"date"`
-	Description string    `json:"description"`
+	ID          uuid.UUID    `json:"id"`
+	UserID      uuid.UUID    `json:"user_id"`
+	Amount      money.Amount `json:"amount"`
+	Currency    string       `json:"currency"`
+	CategoryID  uuid.UUID    `json:"category_id"`
+	Date        time.Time    `json:"date"`
+	Description string       `json:"description"`
+	ExternalID  *string      `json:"external_id"`
+	TagIds      []uuid.UUID  `json:"tag_ids"`
 }
 
+// Creates an expense tagged with those of tag_ids that are tags of the user,
+// such as the tags of the category rules it matches
 func (q *Queries) CreateExpense(ctx context.Context, arg CreateExpenseParams) (Expense, error) {
 	row := q.db.QueryRow(ctx, createExpense,
 		arg.ID,
@@ -43,6 +58,8 @@ func (q *Queries) CreateExpense(ctx context.Context, arg CreateExpenseParams) (E
 		arg.CategoryID,
 		arg.Date,
 		arg.Description,
+		arg.ExternalID,
+		arg.TagIds,
 	)
 	var i Expense
 	err := row.Scan(
@@ -56,6 +73,7 @@ func (q *Queries) CreateExpense(ctx context.Context, arg CreateExpenseParams) (E
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.ExternalID,
 	)
 	return i, err
 }
@@ -79,8 +97,72 @@ func (q *Queries) DeleteExpense(ctx context.Context, arg DeleteExpenseParams) (i
 	return result.RowsAffected(), nil
 }
 
+const findDuplicateExpenses = `-- name: FindDuplicateExpenses :many
+SELECT id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at, external_id FROM expenses
+WHERE user_id = $1
+    AND (
+        (deleted_at IS NULL
+            AND transaction_fingerprint(amount, currency, date, description, category_id::TEXT)
+                = transaction_fingerprint($2::NUMERIC, $3::VARCHAR, $4::TIMESTAMPTZ, $5::TEXT, $6::UUID::TEXT))
+        OR ($7::VARCHAR <> '' AND external_id = $7::VARCHAR)
+    )
+ORDER BY created_at
+`
+
+type FindDuplicateExpensesParams struct {
+	UserID      uuid.UUID    `json:"user_id"`
+	Amount      money.Amount `json:"amount"`
+	Currency    string       `json:"currency"`
+	Date        time.Time    `json:"date"`
+	Description string       `json:"description"`
+	CategoryID  uuid.UUID    `json:"category_id"`
+	ExternalID  string       `json:"external_id"`
+}
+
+// Finds the expenses a new one would likely duplicate: any with the same
+// fingerprint, and any that already has its external ID, deleted or not
+func (q *Queries) FindDuplicateExpenses(ctx context.Context, arg FindDuplicateExpensesParams) ([]Expense, error) {
+	rows, err := q.db.Query(ctx, findDuplicateExpenses,
+		arg.UserID,
+		arg.Amount,
+		arg.Currency,
+		arg.Date,
+		arg.Description,
+		arg.CategoryID,
+		arg.ExternalID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Expense
+	for rows.Next() {
+		var i Expense
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Amount,
+			&i.Currency,
+			&i.CategoryID,
+			&i.Date,
+			&i.Description,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.ExternalID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getExpenseByID = `-- name: GetExpenseByID :one
-SELECT id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at FROM expenses
+SELECT id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at, external_id FROM expenses
 WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
@@ -103,33 +185,47 @@ func (q *Queries) GetExpenseByID(ctx context.Context, arg GetExpenseByIDParams)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.ExternalID,
 	)
 	return i, err
 }
 
 const getExpenseTotalsByCategory = `-- name: GetExpenseTotalsByCategory :many
 SELECT 
-    e.category_id,
+    c.id as category_id,
+    c.parent_id,
     c.name as category_name,
     e.currency,
     COUNT(*)::float8 as transaction_count,
-    SUM(e.amount)::float8 as total_amount
+    SUM(e.amount)::numeric as total_amount,
+    u.base_currency,
+    COALESCE(SUM(e.amount * r.rate), 0)::numeric as converted_amount,
+    COUNT(*) FILTER (WHERE r.rate IS NULL) as unconverted_count
 FROM expenses e
-JOIN categories c ON e.category_id = c.id
+JOIN category_ancestors($1) ca ON ca.category_id = e.category_id
+JOIN categories c ON c.id = ca.ancestor_id
+JOIN users u ON u.id = e.user_id
+CROSS JOIN LATERAL (SELECT exchange_rate(e.currency, u.base_currency, e.date::DATE) AS rate) r
 WHERE e.user_id = $1 
     AND e.deleted_at IS NULL
-GROUP BY e.category_id, c.name, e.currency
+GROUP BY c.id, c.parent_id, c.name, e.currency, u.base_currency
 ORDER BY total_amount DESC
 `
 
 type GetExpenseTotalsByCategoryRow struct {
-	CategoryID       uuid.UUID `json:"category_id"`
-	CategoryName     string    `json:"category_name"`
-	Currency         string    `json:"currency"`
-	TransactionCount float64   `json:"transaction_count"`
-	TotalAmount      float64   `json:"total_amount"`
+	CategoryID       uuid.UUID    `json:"category_id"`
+	ParentID         *uuid.UUID   `json:"parent_id"`
+	CategoryName     string       `json:"category_name"`
+	Currency         string       `json:"currency"`
+	TransactionCount float64      `json:"transaction_count"`
+	TotalAmount      money.Amount `json:"total_amount"`
+	BaseCurrency     string       `json:"base_currency"`
+	ConvertedAmount  money.Amount `json:"converted_amount"`
+	UnconvertedCount int64        `json:"unconverted_count"`
 }
 
+// Totals each category's expenses together with those of its subcategories,
+// so a parent's totals include its children's
 func (q *Queries) GetExpenseTotalsByCategory(ctx context.Context, userID uuid.UUID) ([]GetExpenseTotalsByCategoryRow, error) {
 	rows, err := q.db.Query(ctx, getExpenseTotalsByCategory, userID)
 	if err != nil {
@@ -141,10 +237,14 @@ func (q *Queries) GetExpenseTotalsByCategory(ctx context.Context, userID uuid.UU
 		var i GetExpenseTotalsByCategoryRow
 		if err := rows.Scan(
 			&i.CategoryID,
+			&i.ParentID,
 			&i.CategoryName,
 			&i.Currency,
 			&i.TransactionCount,
 			&i.TotalAmount,
+			&i.BaseCurrency,
+			&i.ConvertedAmount,
+			&i.UnconvertedCount,
 		); err != nil {
 			return nil, err
 		}
@@ -157,7 +257,7 @@ func (q *Queries) GetExpenseTotalsByCategory(ctx context.Context, userID uuid.UU
 }
 
 const getExpensesByCategory = `-- name: GetExpensesByCategory :many
-SELECT id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at FROM expenses
+SELECT id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at, external_id FROM expenses
 WHERE user_id = $1 
     AND category_id = $2 
     AND deleted_at IS NULL
@@ -189,6 +289,7 @@ func (q *Queries) GetExpensesByCategory(ctx context.Context, arg GetExpensesByCa
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.ExternalID,
 		); err != nil {
 			return nil, err
 		}
@@ -201,7 +302,7 @@ func (q *Queries) GetExpensesByCategory(ctx context.Context, arg GetExpensesByCa
 }
 
 const getExpensesByDateRange = `-- name: GetExpensesByDateRange :many
-SELECT id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at FROM expenses
+SELECT id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at, external_id FROM expenses
 WHERE user_id = $1 
     AND deleted_at IS NULL
     AND date >= $2::TIMESTAMPTZ
@@ -235,6 +336,7 @@ func (q *Queries) GetExpensesByDateRange(ctx context.Context, arg GetExpensesByD
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.ExternalID,
 		); err != nil {
 			return nil, err
 		}
@@ -248,7 +350,7 @@ func (q *Queries) GetExpensesByDateRange(ctx context.Context, arg GetExpensesByD
 
 const getMonthlyExpenseTotal = `-- name: GetMonthlyExpenseTotal :many
 SELECT 
-    COALESCE(SUM(amount), 0)::float8 as total_amount,
+    COALESCE(SUM(amount), 0)::numeric as total_amount,
     currency as currency
 FROM expenses
 WHERE user_id = $1 
@@ -263,8 +365,8 @@ type GetMonthlyExpenseTotalParams struct {
 }
 
 type GetMonthlyExpenseTotalRow struct {
-	TotalAmount float64 `json:"total_amount"`
-	Currency    string  `json:"currency"`
+	TotalAmount money.Amount `json:"total_amount"`
+	Currency    string       `json:"currency"`
 }
 
 func (q *Queries) GetMonthlyExpenseTotal(ctx context.Context, arg GetMonthlyExpenseTotalParams) ([]GetMonthlyExpenseTotalRow, error) {
@@ -288,7 +390,7 @@ func (q *Queries) GetMonthlyExpenseTotal(ctx context.Context, arg GetMonthlyExpe
 }
 
 const getRecentExpenses = `-- name: GetRecentExpenses :many
-SELECT id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at FROM expenses
+SELECT id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at, external_id FROM expenses
 WHERE user_id = $1 
     AND deleted_at IS NULL
 ORDER BY date DESC
@@ -320,6 +422,73 @@ func (q *Queries) GetRecentExpenses(ctx context.Context, arg GetRecentExpensesPa
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.ExternalID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDuplicateExpenses = `-- name: ListDuplicateExpenses :many
+SELECT id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at, external_id, original_id
+FROM (
+    SELECT e.*,
+        first_value(e.id) OVER (PARTITION BY f.fingerprint ORDER BY e.created_at, e.id) AS original_id,
+        COUNT(*) OVER (PARTITION BY f.fingerprint) AS copies
+    FROM expenses e
+    CROSS JOIN LATERAL (
+        SELECT transaction_fingerprint(e.amount, e.currency, e.date, e.description, e.category_id::TEXT) AS fingerprint
+    ) f
+    WHERE e.user_id = $1 AND e.deleted_at IS NULL
+) d
+WHERE copies > 1
+ORDER BY date DESC, original_id, created_at
+`
+
+type ListDuplicateExpensesRow struct {
+	ID          uuid.UUID    `json:"id"`
+	UserID      uuid.UUID    `json:"user_id"`
+	Amount      money.Amount `json:"amount"`
+	Currency    string       `json:"currency"`
+	CategoryID  uuid.UUID    `json:"category_id"`
+	Date        time.Time    `json:"date"`
+	Description string       `json:"description"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   *time.Time   `json:"updated_at"`
+	DeletedAt   *time.Time   `json:"deleted_at"`
+	ExternalID  *string      `json:"external_id"`
+	OriginalID  uuid.UUID    `json:"original_id"`
+}
+
+// Lists every expense that shares its fingerprint with another, along with the
+// first expense of its group, which the others likely duplicate
+func (q *Queries) ListDuplicateExpenses(ctx context.Context, userID uuid.UUID) ([]ListDuplicateExpensesRow, error) {
+	rows, err := q.db.Query(ctx, listDuplicateExpenses, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDuplicateExpensesRow
+	for rows.Next() {
+		var i ListDuplicateExpensesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Amount,
+			&i.Currency,
+			&i.CategoryID,
+			&i.Date,
+			&i.Description,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.ExternalID,
+			&i.OriginalID,
 		); err != nil {
 			return nil, err
 		}
@@ -332,7 +501,7 @@ func (q *Queries) GetRecentExpenses(ctx context.Context, arg GetRecentExpensesPa
 }
 
 const listExpenses = `-- name: ListExpenses :many
-SELECT id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at FROM expenses
+SELECT id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at, external_id FROM expenses
 WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY date DESC
 `
@@ -357,6 +526,194 @@ func (q *Queries) ListExpenses(ctx context.Context, userID uuid.UUID) ([]Expense
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.ExternalID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExpensesPage = `-- name: ListExpensesPage :many
+SELECT id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at, external_id FROM expenses
+WHERE user_id = $1
+    AND deleted_at IS NULL
+    AND ($2::NUMERIC IS NULL OR amount >= $2::NUMERIC)
+    AND ($3::NUMERIC IS NULL OR amount <= $3::NUMERIC)
+    AND ($4::VARCHAR = '' OR currency = $4::VARCHAR)
+    AND ($5::UUID IS NULL OR category_id = $5::UUID)
+    AND ($6::UUID IS NULL OR id IN (
+        SELECT expense_id FROM expense_tags WHERE tag_id = $6::UUID
+    ))
+    AND ($7::TEXT = '' OR STRPOS(LOWER(description), LOWER($7::TEXT)) > 0)
+    AND ($8::TIMESTAMPTZ IS NULL OR date >= $8::TIMESTAMPTZ)
+    AND ($9::TIMESTAMPTZ IS NULL OR date <= $9::TIMESTAMPTZ)
+    AND ($10::UUID IS NULL OR CASE
+        WHEN $11::TEXT = 'amount' AND $12::BOOLEAN
+            THEN (amount, id) < ($13::NUMERIC, $10::UUID)
+        WHEN $11::TEXT = 'amount'
+            THEN (amount, id) > ($13::NUMERIC, $10::UUID)
+        WHEN $11::TEXT = 'created_at' AND $12::BOOLEAN
+            THEN (created_at, id) < ($14::TIMESTAMPTZ, $10::UUID)
+        WHEN $11::TEXT = 'created_at'
+            THEN (created_at, id) > ($14::TIMESTAMPTZ, $10::UUID)
+        WHEN $12::BOOLEAN
+            THEN (date, id) < ($14::TIMESTAMPTZ, $10::UUID)
+        ELSE (date, id) > ($14::TIMESTAMPTZ, $10::UUID)
+    END)
+ORDER BY
+    CASE WHEN $11::TEXT = 'amount' AND NOT $12::BOOLEAN THEN amount END,
+    CASE WHEN $11::TEXT = 'amount' AND $12::BOOLEAN THEN amount END DESC,
+    CASE WHEN $11::TEXT = 'created_at' AND NOT $12::BOOLEAN THEN created_at END,
+    CASE WHEN $11::TEXT = 'created_at' AND $12::BOOLEAN THEN created_at END DESC,
+    CASE WHEN $11::TEXT NOT IN ('amount', 'created_at') AND NOT $12::BOOLEAN THEN date END,
+    CASE WHEN $11::TEXT NOT IN ('amount', 'created_at') AND $12::BOOLEAN THEN date END DESC,
+    CASE WHEN NOT $12::BOOLEAN THEN id END,
+    id DESC
+LIMIT $15
+`
+
+type ListExpensesPageParams struct {
+	UserID      uuid.UUID     `json:"user_id"`
+	MinAmount   *money.Amount `json:"min_amount"`
+	MaxAmount   *money.Amount `json:"max_amount"`
+	Currency    string        `json:"currency"`
+	CategoryID  *uuid.UUID    `json:"category_id"`
+	TagID       *uuid.UUID    `json:"tag_id"`
+	Search      string        `json:"search"`
+	StartDate   *time.Time    `json:"start_date"`
+	EndDate     *time.Time    `json:"end_date"`
+	AfterID     *uuid.UUID    `json:"after_id"`
+	Sort        string        `json:"sort"`
+	Descending  bool          `json:"descending"`
+	AfterAmount *money.Amount `json:"after_amount"`
+	AfterTime   *time.Time    `json:"after_time"`
+	PageSize    int32         `json:"page_size"`
+}
+
+// Lists a page of a user's expenses sorted by date, amount or created_at,
+// with the ID breaking ties. after_id and the sort value of the last expense
+// of a page continue from it. NULL and empty filters match every expense.
+func (q *Queries) ListExpensesPage(ctx context.Context, arg ListExpensesPageParams) ([]Expense, error) {
+	rows, err := q.db.Query(ctx, listExpensesPage,
+		arg.UserID,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Currency,
+		arg.CategoryID,
+		arg.TagID,
+		arg.Search,
+		arg.StartDate,
+		arg.EndDate,
+		arg.AfterID,
+		arg.Sort,
+		arg.Descending,
+		arg.AfterAmount,
+		arg.AfterTime,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Expense
+	for rows.Next() {
+		var i Expense
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Amount,
+			&i.Currency,
+			&i.CategoryID,
+			&i.Date,
+			&i.Description,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.ExternalID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const matchImportedExpenses = `-- name: MatchImportedExpenses :many
+SELECT
+    t.ordinal::INT AS ordinal,
+    transaction_fingerprint(t.amount::NUMERIC, t.currency, t.date, t.description, t.category_id::TEXT)::TEXT AS fingerprint,
+    (
+        SELECT COUNT(*) FROM expenses x
+        WHERE x.user_id = $1
+            AND x.deleted_at IS NULL
+            AND transaction_fingerprint(x.amount, x.currency, x.date, x.description, x.category_id::TEXT)
+                = transaction_fingerprint(t.amount::NUMERIC, t.currency, t.date, t.description, t.category_id::TEXT)
+    )::BIGINT AS matches,
+    EXISTS (
+        SELECT 1 FROM expenses x
+        WHERE x.user_id = $1 AND t.external_id <> '' AND x.external_id = t.external_id
+    ) AS external_id_taken
+FROM unnest(
+    $2::TEXT[],
+    $3::VARCHAR[],
+    $4::TIMESTAMPTZ[],
+    $5::VARCHAR[],
+    $6::UUID[],
+    $7::VARCHAR[]
+) WITH ORDINALITY AS t(amount, currency, date, description, category_id, external_id, ordinal)
+ORDER BY t.ordinal
+`
+
+type MatchImportedExpensesParams struct {
+	UserID       uuid.UUID   `json:"user_id"`
+	Amounts      []string    `json:"amounts"`
+	Currencies   []string    `json:"currencies"`
+	Dates        []time.Time `json:"dates"`
+	Descriptions []string    `json:"descriptions"`
+	CategoryIds  []uuid.UUID `json:"category_ids"`
+	ExternalIds  []string    `json:"external_ids"`
+}
+
+type MatchImportedExpensesRow struct {
+	Ordinal         int32  `json:"ordinal"`
+	Fingerprint     string `json:"fingerprint"`
+	Matches         int64  `json:"matches"`
+	ExternalIDTaken bool   `json:"external_id_taken"`
+}
+
+// For each expense about to be imported, counts the expenses the user already
+// has with the same fingerprint and reports whether its external ID is taken.
+// The arrays are parallel and rows come back in the same order.
+func (q *Queries) MatchImportedExpenses(ctx context.Context, arg MatchImportedExpensesParams) ([]MatchImportedExpensesRow, error) {
+	rows, err := q.db.Query(ctx, matchImportedExpenses,
+		arg.UserID,
+		arg.Amounts,
+		arg.Currencies,
+		arg.Dates,
+		arg.Descriptions,
+		arg.CategoryIds,
+		arg.ExternalIds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MatchImportedExpensesRow
+	for rows.Next() {
+		var i MatchImportedExpensesRow
+		if err := rows.Scan(
+			&i.Ordinal,
+			&i.Fingerprint,
+			&i.Matches,
+			&i.ExternalIDTaken,
 		); err != nil {
 			return nil, err
 		}
@@ -378,17 +735,17 @@ SET
     description = $6,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = $1 AND user_id = $7 AND deleted_at IS NULL
-RETURNING id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at
+RETURNING id, user_id, amount, currency, category_id, date, description, created_at, updated_at, deleted_at, external_id
 `
 
 type UpdateExpenseParams struct {
-	ID          uuid.UUID `json:"id"`
-	Amount      float64   `json:"amount"`
-	Currency    string    `json:"currency"`
-	CategoryID  uuid.UUID `json:"category_id"`
-	Date        time.Time `json:"date"`
-	Description string    `json:"description"`
-	UserID      uuid.UUID `json:"user_id"`
+	ID          uuid.UUID    `json:"id"`
+	Amount      money.Amount `json:"amount"`
+	Currency    string       `json:"currency"`
+	CategoryID  uuid.UUID    `json:"category_id"`
+	Date        time.Time    `json:"date"`
+	Description string       `json:"description"`
+	UserID      uuid.UUID    `json:"user_id"`
 }
 
 func (q *Queries) UpdateExpense(ctx context.Context, arg UpdateExpenseParams) (Expense, error) {
@@ -413,6 +770,7 @@ func (q *Queries) UpdateExpense(ctx context.Context, arg UpdateExpenseParams) (E
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.ExternalID,
 	)
 	return i, err
 }
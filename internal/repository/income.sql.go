@@ -10,28 +10,30 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 )
 
 const createIncome = `-- name: CreateIncome :one
 INSERT INTO income (
     id, user_id, amount, currency, source,
-    date, description, created_at, updated_at
+    date, description, external_id, created_at, updated_at
 )
 VALUES (
     $1, $2, $3, $4, $5,
-    $6, $7, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+    $6, $7, $8, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
 )
-RETURNING id, user_id, amount, currency, source, date, description, created_at, updated_at, deleted_at
+RETURNING id, user_id, amount, currency, source, date, description, created_at, updated_at, deleted_at, external_id
 `
 
 type CreateIncomeParams struct {
-	ID          uuid.UUID `json:"id"`
-	UserID      uuid.UUID `json:"user_id"`
-	Amount      float64   `json:"amount"`
-	Currency    string    `json:"currency"`
-	Source      string    `json:"source"`
-	Date        time.Time `json:"date"`
-	Description string    `json:"description"`
+	ID          uuid.UUID    `json:"id"`
+	UserID      uuid.UUID    `json:"user_id"`
+	Amount      money.Amount `json:"amount"`
+	Currency    string       `json:"currency"`
+	Source      string       `json:"source"`
+	Date        time.Time    `json:"date"`
+	Description string       `json:"description"`
+	ExternalID  *string      `json:"external_id"`
 }
 
 func (q *Queries) CreateIncome(ctx context.Context, arg CreateIncomeParams) (Income, error) {
@@ -43,6 +45,7 @@ func (q *Queries) CreateIncome(ctx context.Context, arg CreateIncomeParams) (Inc
 		arg.Source,
 		arg.Date,
 		arg.Description,
+		arg.ExternalID,
 	)
 	var i Income
 	err := row.Scan(
@@ -56,6 +59,7 @@ func (q *Queries) CreateIncome(ctx context.Context, arg CreateIncomeParams) (Inc
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.ExternalID,
 	)
 	return i, err
 }
@@ -79,8 +83,72 @@ func (q *Queries) DeleteIncome(ctx context.Context, arg DeleteIncomeParams) (int
 	return result.RowsAffected(), nil
 }
 
+const findDuplicateIncome = `-- name: FindDuplicateIncome :many
+SELECT id, user_id, amount, currency, source, date, description, created_at, updated_at, deleted_at, external_id FROM income
+WHERE user_id = $1
+    AND (
+        (deleted_at IS NULL
+            AND transaction_fingerprint(amount, currency, date, description, source)
+                = transaction_fingerprint($2::NUMERIC, $3::VARCHAR, $4::TIMESTAMPTZ, $5::TEXT, $6::TEXT))
+        OR ($7::VARCHAR <> '' AND external_id = $7::VARCHAR)
+    )
+ORDER BY created_at
+`
+
+type FindDuplicateIncomeParams struct {
+	UserID      uuid.UUID    `json:"user_id"`
+	Amount      money.Amount `json:"amount"`
+	Currency    string       `json:"currency"`
+	Date        time.Time    `json:"date"`
+	Description string       `json:"description"`
+	Source      string       `json:"source"`
+	ExternalID  string       `json:"external_id"`
+}
+
+// Finds the income a new one would likely duplicate: any with the same
+// fingerprint, and any that already has its external ID, deleted or not
+func (q *Queries) FindDuplicateIncome(ctx context.Context, arg FindDuplicateIncomeParams) ([]Income, error) {
+	rows, err := q.db.Query(ctx, findDuplicateIncome,
+		arg.UserID,
+		arg.Amount,
+		arg.Currency,
+		arg.Date,
+		arg.Description,
+		arg.Source,
+		arg.ExternalID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Income
+	for rows.Next() {
+		var i Income
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Amount,
+			&i.Currency,
+			&i.Source,
+			&i.Date,
+			&i.Description,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.ExternalID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getIncomeByDateRange = `-- name: GetIncomeByDateRange :many
-SELECT id, user_id, amount, currency, source, date, description, created_at, updated_at, deleted_at FROM income
+SELECT id, user_id, amount, currency, source, date, description, created_at, updated_at, deleted_at, external_id FROM income
 WHERE user_id = $1 
     AND deleted_at IS NULL
     AND date >= $2::TIMESTAMPTZ
@@ -114,6 +182,7 @@ func (q *Queries) GetIncomeByDateRange(ctx context.Context, arg GetIncomeByDateR
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.ExternalID,
 		); err != nil {
 			return nil, err
 		}
@@ -126,7 +195,7 @@ func (q *Queries) GetIncomeByDateRange(ctx context.Context, arg GetIncomeByDateR
 }
 
 const getIncomeByID = `-- name: GetIncomeByID :one
-SELECT id, user_id, amount, currency, source, date, description, created_at, updated_at, deleted_at FROM income
+SELECT id, user_id, amount, currency, source, date, description, created_at, updated_at, deleted_at, external_id FROM income
 WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
@@ -149,12 +218,13 @@ func (q *Queries) GetIncomeByID(ctx context.Context, arg GetIncomeByIDParams) (I
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.ExternalID,
 	)
 	return i, err
 }
 
 const getIncomeBySource = `-- name: GetIncomeBySource :many
-SELECT id, user_id, amount, currency, source, date, description, created_at, updated_at, deleted_at FROM income
+SELECT id, user_id, amount, currency, source, date, description, created_at, updated_at, deleted_at, external_id FROM income
 WHERE user_id = $1 
     AND source = $2 
     AND deleted_at IS NULL
@@ -186,6 +256,7 @@ func (q *Queries) GetIncomeBySource(ctx context.Context, arg GetIncomeBySourcePa
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.ExternalID,
 		); err != nil {
 			return nil, err
 		}
@@ -202,8 +273,8 @@ SELECT
     source,
     currency,
     COUNT(*) as transaction_count,
-    SUM(amount) as total_amount,
-    AVG(amount) as average_amount
+    SUM(amount)::numeric as total_amount,
+    ROUND(AVG(amount), 4)::numeric as average_amount
 FROM income
 WHERE user_id = $1 
     AND deleted_at IS NULL
@@ -212,11 +283,11 @@ ORDER BY total_amount DESC
 `
 
 type GetIncomeSummaryBySourceRow struct {
-	Source           string  `json:"source"`
-	Currency         string  `json:"currency"`
-	TransactionCount int64   `json:"transaction_count"`
-	TotalAmount      int64   `json:"total_amount"`
-	AverageAmount    float64 `json:"average_amount"`
+	Source           string       `json:"source"`
+	Currency         string       `json:"currency"`
+	TransactionCount int64        `json:"transaction_count"`
+	TotalAmount      money.Amount `json:"total_amount"`
+	AverageAmount    money.Amount `json:"average_amount"`
 }
 
 func (q *Queries) GetIncomeSummaryBySource(ctx context.Context, userID uuid.UUID) ([]GetIncomeSummaryBySourceRow, error) {
@@ -247,7 +318,7 @@ func (q *Queries) GetIncomeSummaryBySource(ctx context.Context, userID uuid.UUID
 
 const getMonthlyIncomeTotal = `-- name: GetMonthlyIncomeTotal :one
 SELECT 
-    COALESCE(SUM(amount), 0) as total_amount,
+    COALESCE(SUM(amount), 0)::numeric as total_amount,
     currency
 FROM income
 WHERE user_id = $1 
@@ -262,8 +333,8 @@ type GetMonthlyIncomeTotalParams struct {
 }
 
 type GetMonthlyIncomeTotalRow struct {
-	TotalAmount interface{} `json:"total_amount"`
-	Currency    string      `json:"currency"`
+	TotalAmount money.Amount `json:"total_amount"`
+	Currency    string       `json:"currency"`
 }
 
 func (q *Queries) GetMonthlyIncomeTotal(ctx context.Context, arg GetMonthlyIncomeTotalParams) (GetMonthlyIncomeTotalRow, error) {
@@ -274,7 +345,7 @@ func (q *Queries) GetMonthlyIncomeTotal(ctx context.Context, arg GetMonthlyIncom
 }
 
 const getRecentIncome = `-- name: GetRecentIncome :many
-SELECT id, user_id, amount, currency, source, date, description, created_at, updated_at, deleted_at FROM income
+SELECT id, user_id, amount, currency, source, date, description, created_at, updated_at, deleted_at, external_id FROM income
 WHERE user_id = $1 
     AND deleted_at IS NULL
 ORDER BY date DESC
@@ -306,6 +377,7 @@ func (q *Queries) GetRecentIncome(ctx context.Context, arg GetRecentIncomeParams
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.ExternalID,
 		); err != nil {
 			return nil, err
 		}
@@ -318,7 +390,7 @@ func (q *Queries) GetRecentIncome(ctx context.Context, arg GetRecentIncomeParams
 }
 
 const listIncome = `-- name: ListIncome :many
-SELECT id, user_id, amount, currency, source, date, description, created_at, updated_at, deleted_at FROM income
+SELECT id, user_id, amount, currency, source, date, description, created_at, updated_at, deleted_at, external_id FROM income
 WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY date DESC
 `
@@ -343,6 +415,193 @@ func (q *Queries) ListIncome(ctx context.Context, userID uuid.UUID) ([]Income, e
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.ExternalID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listIncomePage = `-- name: ListIncomePage :many
+SELECT id, user_id, amount, currency, source, date, description, created_at, updated_at, deleted_at, external_id FROM income
+WHERE user_id = $1
+    AND deleted_at IS NULL
+    AND ($2::NUMERIC IS NULL OR amount >= $2::NUMERIC)
+    AND ($3::NUMERIC IS NULL OR amount <= $3::NUMERIC)
+    AND ($4::VARCHAR = '' OR currency = $4::VARCHAR)
+    AND ($5::VARCHAR = '' OR LOWER(source) = LOWER($5::VARCHAR))
+    AND ($6::UUID IS NULL OR id IN (
+        SELECT income_id FROM income_tags WHERE tag_id = $6::UUID
+    ))
+    AND ($7::TEXT = '' OR STRPOS(LOWER(description), LOWER($7::TEXT)) > 0)
+    AND ($8::TIMESTAMPTZ IS NULL OR date >= $8::TIMESTAMPTZ)
+    AND ($9::TIMESTAMPTZ IS NULL OR date <= $9::TIMESTAMPTZ)
+    AND ($10::UUID IS NULL OR CASE
+        WHEN $11::TEXT = 'amount' AND $12::BOOLEAN
+            THEN (amount, id) < ($13::NUMERIC, $10::UUID)
+        WHEN $11::TEXT = 'amount'
+            THEN (amount, id) > ($13::NUMERIC, $10::UUID)
+        WHEN $11::TEXT = 'created_at' AND $12::BOOLEAN
+            THEN (created_at, id) < ($14::TIMESTAMPTZ, $10::UUID)
+        WHEN $11::TEXT = 'created_at'
+            THEN (created_at, id) > ($14::TIMESTAMPTZ, $10::UUID)
+        WHEN $12::BOOLEAN
+            THEN (date, id) < ($14::TIMESTAMPTZ, $10::UUID)
+        ELSE (date, id) > ($14::TIMESTAMPTZ, $10::UUID)
+    END)
+ORDER BY
+    CASE WHEN $11::TEXT = 'amount' AND NOT $12::BOOLEAN THEN amount END,
+    CASE WHEN $11::TEXT = 'amount' AND $12::BOOLEAN THEN amount END DESC,
+    CASE WHEN $11::TEXT = 'created_at' AND NOT $12::BOOLEAN THEN created_at END,
+    CASE WHEN $11::TEXT = 'created_at' AND $12::BOOLEAN THEN created_at END DESC,
+    CASE WHEN $11::TEXT NOT IN ('amount', 'created_at') AND NOT $12::BOOLEAN THEN date END,
+    CASE WHEN $11::TEXT NOT IN ('amount', 'created_at') AND $12::BOOLEAN THEN date END DESC,
+    CASE WHEN NOT $12::BOOLEAN THEN id END,
+    id DESC
+LIMIT $15
+`
+
+type ListIncomePageParams struct {
+	UserID      uuid.UUID     `json:"user_id"`
+	MinAmount   *money.Amount `json:"min_amount"`
+	MaxAmount   *money.Amount `json:"max_amount"`
+	Currency    string        `json:"currency"`
+	Source      string        `json:"source"`
+	TagID       *uuid.UUID    `json:"tag_id"`
+	Search      string        `json:"search"`
+	StartDate   *time.Time    `json:"start_date"`
+	EndDate     *time.Time    `json:"end_date"`
+	AfterID     *uuid.UUID    `json:"after_id"`
+	Sort        string        `json:"sort"`
+	Descending  bool          `json:"descending"`
+	AfterAmount *money.Amount `json:"after_amount"`
+	AfterTime   *time.Time    `json:"after_time"`
+	PageSize    int32         `json:"page_size"`
+}
+
+// Lists a page of a user's income like ListExpensesPage, filtered by source
+// rather than category
+func (q *Queries) ListIncomePage(ctx context.Context, arg ListIncomePageParams) ([]Income, error) {
+	rows, err := q.db.Query(ctx, listIncomePage,
+		arg.UserID,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Currency,
+		arg.Source,
+		arg.TagID,
+		arg.Search,
+		arg.StartDate,
+		arg.EndDate,
+		arg.AfterID,
+		arg.Sort,
+		arg.Descending,
+		arg.AfterAmount,
+		arg.AfterTime,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Income
+	for rows.Next() {
+		var i Income
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Amount,
+			&i.Currency,
+			&i.Source,
+			&i.Date,
+			&i.Description,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.ExternalID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const matchImportedIncome = `-- name: MatchImportedIncome :many
+SELECT
+    t.ordinal::INT AS ordinal,
+    transaction_fingerprint(t.amount::NUMERIC, t.currency, t.date, t.description, t.source)::TEXT AS fingerprint,
+    (
+        SELECT COUNT(*) FROM income x
+        WHERE x.user_id = $1
+            AND x.deleted_at IS NULL
+            AND transaction_fingerprint(x.amount, x.currency, x.date, x.description, x.source)
+                = transaction_fingerprint(t.amount::NUMERIC, t.currency, t.date, t.description, t.source)
+    )::BIGINT AS matches,
+    EXISTS (
+        SELECT 1 FROM income x
+        WHERE x.user_id = $1 AND t.external_id <> '' AND x.external_id = t.external_id
+    ) AS external_id_taken
+FROM unnest(
+    $2::TEXT[],
+    $3::VARCHAR[],
+    $4::TIMESTAMPTZ[],
+    $5::VARCHAR[],
+    $6::VARCHAR[],
+    $7::VARCHAR[]
+) WITH ORDINALITY AS t(amount, currency, date, description, source, external_id, ordinal)
+ORDER BY t.ordinal
+`
+
+type MatchImportedIncomeParams struct {
+	UserID       uuid.UUID   `json:"user_id"`
+	Amounts      []string    `json:"amounts"`
+	Currencies   []string    `json:"currencies"`
+	Dates        []time.Time `json:"dates"`
+	Descriptions []string    `json:"descriptions"`
+	Sources      []string    `json:"sources"`
+	ExternalIds  []string    `json:"external_ids"`
+}
+
+type MatchImportedIncomeRow struct {
+	Ordinal         int32  `json:"ordinal"`
+	Fingerprint     string `json:"fingerprint"`
+	Matches         int64  `json:"matches"`
+	ExternalIDTaken bool   `json:"external_id_taken"`
+}
+
+// For each income record about to be imported, counts the records the user
+// already has with the same fingerprint and reports whether its external ID is
+// taken. The arrays are parallel and rows come back in the same order.
+func (q *Queries) MatchImportedIncome(ctx context.Context, arg MatchImportedIncomeParams) ([]MatchImportedIncomeRow, error) {
+	rows, err := q.db.Query(ctx, matchImportedIncome,
+		arg.UserID,
+		arg.Amounts,
+		arg.Currencies,
+		arg.Dates,
+		arg.Descriptions,
+		arg.Sources,
+		arg.ExternalIds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MatchImportedIncomeRow
+	for rows.Next() {
+		var i MatchImportedIncomeRow
+		if err := rows.Scan(
+			&i.Ordinal,
+			&i.Fingerprint,
+			&i.Matches,
+			&i.ExternalIDTaken,
 		); err != nil {
 			return nil, err
 		}
@@ -364,17 +623,17 @@ SET
     description = $6,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = $1 AND user_id = $7 AND deleted_at IS NULL
-RETURNING id, user_id, amount, currency, source, date, description, created_at, updated_at, deleted_at
+RETURNING id, user_id, amount, currency, source, date, description, created_at, updated_at, deleted_at, external_id
 `
 
 type UpdateIncomeParams struct {
-	ID          uuid.UUID `json:"id"`
-	Amount      float64   `json:"amount"`
-	Currency    string    `json:"currency"`
-	Source      string    `json:"source"`
-	Date        time.Time `json:"date"`
-	Description string    `json:"description"`
-	UserID      uuid.UUID `json:"user_id"`
+	ID          uuid.UUID    `json:"id"`
+	Amount      money.Amount `json:"amount"`
+	Currency    string       `json:"currency"`
+	Source      string       `json:"source"`
+	Date        time.Time    `json:"date"`
+	Description string       `json:"description"`
+	UserID      uuid.UUID    `json:"user_id"`
 }
 
 func (q *Queries) UpdateIncome(ctx context.Context, arg UpdateIncomeParams) (Income, error) {
@@ -399,6 +658,7 @@ func (q *Queries) UpdateIncome(ctx context.Context, arg UpdateIncomeParams) (Inc
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.ExternalID,
 	)
 	return i, err
 }
@@ -2,19 +2,24 @@ package mocks
 
 import (
 	"context"
+	"encoding/json"
+	"math/big"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 	"github.com/jorge-dev/centsible/internal/repository"
 )
 
 type BudgetMock struct {
 	budgets map[string]repository.Budget
+	spent   map[string]money.Amount
 }
 
 func NewBudgetMock() *BudgetMock {
 	return &BudgetMock{
 		budgets: make(map[string]repository.Budget),
+		spent:   make(map[string]money.Amount),
 	}
 }
 
@@ -23,6 +28,23 @@ func (m *BudgetMock) AddBudget(budget repository.Budget) {
 	m.budgets[budget.ID.String()] = budget
 }
 
+// SetPeriodSpending sets what was spent in the budget period starting at
+// periodStart. Periods without a set amount have spent 75% of the budget.
+func (m *BudgetMock) SetPeriodSpending(budgetID uuid.UUID, periodStart time.Time, amount money.Amount) {
+	m.spent[periodKey(budgetID, periodStart)] = amount
+}
+
+func periodKey(budgetID uuid.UUID, periodStart time.Time) string {
+	return budgetID.String() + periodStart.UTC().Format(time.RFC3339Nano)
+}
+
+func (m *BudgetMock) periodSpending(budget repository.Budget, periodStart time.Time) money.Amount {
+	if amount, ok := m.spent[periodKey(budget.ID, periodStart)]; ok {
+		return amount
+	}
+	return budget.Amount.MulRat(big.NewRat(3, 4)) // Mock 75% usage
+}
+
 func (m *BudgetMock) CreateBudget(ctx context.Context, arg repository.CreateBudgetParams) (repository.Budget, error) {
 	now := time.Now()
 	budget := repository.Budget{
@@ -32,6 +54,8 @@ func (m *BudgetMock) CreateBudget(ctx context.Context, arg repository.CreateBudg
 		Currency:   arg.Currency,
 		CategoryID: arg.CategoryID,
 		Type:       arg.Type,
+		Cadence:    arg.Cadence,
+		Rollover:   arg.Rollover,
 		StartDate:  arg.StartDate,
 		EndDate:    arg.EndDate,
 		CreatedAt:  now,
@@ -59,7 +83,7 @@ func (m *BudgetMock) GetActiveBudgets(ctx context.Context, userID uuid.UUID) ([]
 	for _, budget := range m.budgets {
 		if budget.UserID == userID && budget.DeletedAt == nil &&
 			!budget.StartDate.After(now) &&
-			(budget.EndDate.After(now) || budget.EndDate.IsZero()) {
+			(budget.EndDate == nil || budget.EndDate.After(now)) {
 			result = append(result, budget)
 		}
 	}
@@ -73,20 +97,48 @@ func (m *BudgetMock) GetBudgetByID(ctx context.Context, arg repository.GetBudget
 	return repository.Budget{}, ErrRecordNotFound
 }
 
+func (m *BudgetMock) GetBudgetSpendingByPeriod(ctx context.Context, arg repository.GetBudgetSpendingByPeriodParams) ([]repository.GetBudgetSpendingByPeriodRow, error) {
+	var result []repository.GetBudgetSpendingByPeriodRow
+	for i, budgetID := range arg.BudgetIds {
+		budget, exists := m.budgets[budgetID.String()]
+		if !exists || budget.UserID != arg.UserID || budget.DeletedAt != nil {
+			continue
+		}
+		result = append(result, repository.GetBudgetSpendingByPeriodRow{
+			BudgetID:    budgetID,
+			PeriodStart: arg.PeriodStarts[i],
+			PeriodEnd:   arg.PeriodEnds[i],
+			SpentAmount: m.periodSpending(budget, arg.PeriodStarts[i]),
+		})
+	}
+	return result, nil
+}
+
 func (m *BudgetMock) GetBudgetUsage(ctx context.Context, arg repository.GetBudgetUsageParams) (repository.GetBudgetUsageRow, error) {
 	budget, exists := m.budgets[arg.BudgetID.String()]
 	if !exists || budget.UserID != arg.UserID || budget.DeletedAt != nil {
 		return repository.GetBudgetUsageRow{}, ErrRecordNotFound
 	}
 
-	// Mock spent amount and usage percentage
-	spentAmount := budget.Amount * 0.75 // Mock 75% usage
-	usagePercentage := 75.0
+	spentAmount := m.periodSpending(budget, arg.PeriodStart)
+	usagePercentage := 0.0
+	if budget.Amount.IsPositive() {
+		usagePercentage = spentAmount.Float64() / budget.Amount.Float64() * 100
+	}
+	spentByCurrency, _ := json.Marshal([]map[string]interface{}{
+		{
+			"currency":         budget.Currency,
+			"amount":           spentAmount,
+			"converted_amount": spentAmount,
+		},
+	})
 
 	return repository.GetBudgetUsageRow{
 		Budget:          budget,
 		SpentAmount:     spentAmount,
 		UsagePercentage: usagePercentage,
+		SpentByCurrency: spentByCurrency,
+		MissingRates:    []string{},
 	}, nil
 }
 
@@ -100,24 +152,6 @@ func (m *BudgetMock) GetBudgetsByCategory(ctx context.Context, arg repository.Ge
 	return result, nil
 }
 
-func (m *BudgetMock) GetBudgetsNearLimit(ctx context.Context, arg repository.GetBudgetsNearLimitParams) ([]repository.GetBudgetsNearLimitRow, error) {
-	var result []repository.GetBudgetsNearLimitRow
-	for _, budget := range m.budgets {
-		if budget.UserID == arg.UserID && budget.DeletedAt == nil {
-			spentAmount := budget.Amount * 0.8 // Mock 80% usage
-			usagePercentage := 80.0
-			if usagePercentage >= arg.Threshold {
-				result = append(result, repository.GetBudgetsNearLimitRow{
-					Budget:          budget,
-					SpentAmount:     spentAmount,
-					UsagePercentage: usagePercentage,
-				})
-			}
-		}
-	}
-	return result, nil
-}
-
 func (m *BudgetMock) GetOneTimeBudgets(ctx context.Context, userID uuid.UUID) ([]repository.Budget, error) {
 	var result []repository.Budget
 	for _, budget := range m.budgets {
@@ -148,6 +182,25 @@ func (m *BudgetMock) ListBudgets(ctx context.Context, userID uuid.UUID) ([]repos
 	return result, nil
 }
 
+func (m *BudgetMock) ListBudgetsPage(ctx context.Context, arg repository.ListBudgetsPageParams) ([]repository.Budget, error) {
+	var result []repository.Budget
+	for _, budget := range m.budgets {
+		if budget.UserID == arg.UserID && budget.DeletedAt == nil &&
+			inAmountRange(budget.Amount, arg.MinAmount, arg.MaxAmount) &&
+			(arg.Currency == "" || budget.Currency == arg.Currency) &&
+			(arg.CategoryID == nil || budget.CategoryID == *arg.CategoryID) &&
+			containsFold(budget.Name, arg.Search) &&
+			inDateRange(budget.StartDate, arg.StartDate, arg.EndDate) {
+			result = append(result, budget)
+		}
+	}
+	key := func(budget repository.Budget) pageKey {
+		return sortKey(arg.Sort, budget.Amount, budget.CreatedAt, budget.StartDate, "", budget.ID)
+	}
+	after := afterKey(arg.Sort, arg.AfterID, arg.AfterAmount, arg.AfterTime, "")
+	return page(result, key, arg.Descending, after, arg.PageSize), nil
+}
+
 func (m *BudgetMock) UpdateBudget(ctx context.Context, arg repository.UpdateBudgetParams) (repository.Budget, error) {
 	if budget, exists := m.budgets[arg.ID.String()]; exists && budget.UserID == arg.UserID && budget.DeletedAt == nil {
 		now := time.Now()
@@ -155,6 +208,8 @@ func (m *BudgetMock) UpdateBudget(ctx context.Context, arg repository.UpdateBudg
 		budget.Currency = arg.Currency
 		budget.CategoryID = arg.CategoryID
 		budget.Type = arg.Type
+		budget.Cadence = arg.Cadence
+		budget.Rollover = arg.Rollover
 		budget.StartDate = arg.StartDate
 		budget.EndDate = arg.EndDate
 		budget.Name = arg.Name
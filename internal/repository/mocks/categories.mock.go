@@ -2,19 +2,33 @@ package mocks
 
 import (
 	"context"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 	"github.com/jorge-dev/centsible/internal/repository"
 )
 
+// CategoryMock keeps categories and moves the records of the expense,
+// budget and recurring transaction mocks it was created with between them
 type CategoryMock struct {
 	categories map[string]repository.Category
+	templates  []repository.ListCategoryTemplatesRow
+	expenses   *ExpenseMock
+	budgets    *BudgetMock
+	recurring  *RecurringTransactionMock
+	rules      *CategoryRuleMock
 }
 
-func NewCategoryMock() *CategoryMock {
+func NewCategoryMock(expenses *ExpenseMock, budgets *BudgetMock, recurring *RecurringTransactionMock, rules *CategoryRuleMock) *CategoryMock {
 	return &CategoryMock{
 		categories: make(map[string]repository.Category),
+		expenses:   expenses,
+		budgets:    budgets,
+		recurring:  recurring,
+		rules:      rules,
 	}
 }
 
@@ -22,6 +36,47 @@ func (m *CategoryMock) AddCategory(category repository.Category) {
 	m.categories[category.ID.String()] = category
 }
 
+// AddCategoryTemplate adds a template, listing parents before their children
+func (m *CategoryMock) AddCategoryTemplate(template repository.ListCategoryTemplatesRow) {
+	m.templates = append(m.templates, template)
+}
+
+// userCategoryNamed finds a user's live category by name, ignoring case
+func (m *CategoryMock) userCategoryNamed(userID uuid.UUID, name string) (repository.Category, bool) {
+	for _, cat := range m.categories {
+		if cat.UserID == userID && cat.DeletedAt == nil && strings.EqualFold(cat.Name, name) {
+			return cat, true
+		}
+	}
+	return repository.Category{}, false
+}
+
+func (m *CategoryMock) ApplyCategoryTemplate(ctx context.Context, arg repository.ApplyCategoryTemplateParams) ([]repository.Category, error) {
+	var created []repository.Category
+	for _, template := range m.templates {
+		if template.Key != arg.TemplateKey {
+			continue
+		}
+		for i, name := range template.CategoryNames {
+			if _, exists := m.userCategoryNamed(arg.UserID, name); exists {
+				continue
+			}
+			var parentID *uuid.UUID
+			if parent, ok := m.userCategoryNamed(arg.UserID, template.ParentNames[i]); ok {
+				parentID = &parent.ID
+			}
+			category, _ := m.CreateCategory(ctx, repository.CreateCategoryParams{
+				ID:       uuid.New(),
+				UserID:   arg.UserID,
+				Name:     name,
+				ParentID: parentID,
+			})
+			created = append(created, category)
+		}
+	}
+	return created, nil
+}
+
 func (m *CategoryMock) CheckCategoryExists(ctx context.Context, arg repository.CheckCategoryExistsParams) (bool, error) {
 	for _, cat := range m.categories {
 		if cat.UserID == arg.UserID && cat.Name == arg.Name {
@@ -37,6 +92,7 @@ func (m *CategoryMock) CreateCategory(ctx context.Context, arg repository.Create
 		ID:        arg.ID,
 		UserID:    arg.UserID,
 		Name:      arg.Name,
+		ParentID:  arg.ParentID,
 		CreatedAt: now,
 		UpdatedAt: &now,
 	}
@@ -46,13 +102,21 @@ func (m *CategoryMock) CreateCategory(ctx context.Context, arg repository.Create
 
 func (m *CategoryMock) DeleteCategory(ctx context.Context, arg repository.DeleteCategoryParams) (int64, error) {
 	key := arg.ID.String()
-	if cat, exists := m.categories[key]; exists && cat.UserID == arg.UserID {
-		now := time.Now()
-		cat.DeletedAt = &now
-		m.categories[key] = cat
-		return 1, nil
+	cat, exists := m.categories[key]
+	if !exists || cat.UserID != arg.UserID || cat.DeletedAt != nil || m.inUse(arg.ID) {
+		return 0, nil
+	}
+	now := time.Now()
+	for subKey, sub := range m.categories {
+		if sub.ParentID != nil && *sub.ParentID == cat.ID && sub.DeletedAt == nil {
+			sub.ParentID = cat.ParentID
+			sub.UpdatedAt = &now
+			m.categories[subKey] = sub
+		}
 	}
-	return 0, nil
+	cat.DeletedAt = &now
+	m.categories[key] = cat
+	return 1, nil
 }
 
 func (m *CategoryMock) GetCategoryByID(ctx context.Context, arg repository.GetCategoryByIDParams) (repository.Category, error) {
@@ -68,20 +132,116 @@ func (m *CategoryMock) GetCategoryUsage(ctx context.Context, arg repository.GetC
 		Name:          "Test Category",
 		ExpenseCount:  5,
 		BudgetCount:   2,
-		TotalExpenses: 1000.00,
+		TotalExpenses: money.FromInt(1000),
 	}, nil
 }
 
+func (m *CategoryMock) GetCategoryWordCounts(ctx context.Context, arg repository.GetCategoryWordCountsParams) ([]repository.GetCategoryWordCountsRow, error) {
+	type key struct {
+		categoryID uuid.UUID
+		word       string
+	}
+	wanted := make(map[string]bool, len(arg.Words))
+	for _, word := range arg.Words {
+		wanted[word] = true
+	}
+	counts := make(map[key]int64)
+	for _, expense := range m.expenses.expenses {
+		if expense.UserID != arg.UserID || expense.DeletedAt != nil {
+			continue
+		}
+		if cat, exists := m.categories[expense.CategoryID.String()]; !exists || cat.DeletedAt != nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, word := range strings.FieldsFunc(strings.ToLower(expense.Description), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if wanted[word] && !seen[word] {
+				seen[word] = true
+				counts[key{expense.CategoryID, word}]++
+			}
+		}
+	}
+
+	var result []repository.GetCategoryWordCountsRow
+	for k, count := range counts {
+		result = append(result, repository.GetCategoryWordCountsRow{
+			CategoryID:   k.categoryID,
+			CategoryName: m.categories[k.categoryID.String()].Name,
+			Word:         k.word,
+			ExpenseCount: count,
+		})
+	}
+	return result, nil
+}
+
 func (m *CategoryMock) GetMostUsedCategories(ctx context.Context, arg repository.GetMostUsedCategoriesParams) ([]repository.GetMostUsedCategoriesRow, error) {
 	return []repository.GetMostUsedCategoriesRow{
 		{
 			Name:        "Test Category",
 			UsageCount:  10,
-			TotalAmount: 2000.00,
+			TotalAmount: money.FromInt(2000),
 		},
 	}, nil
 }
 
+func (m *CategoryMock) IsCategoryInSubtree(ctx context.Context, arg repository.IsCategoryInSubtreeParams) (bool, error) {
+	cat, exists := m.categories[arg.CategoryID.String()]
+	if !exists || cat.UserID != arg.UserID {
+		return false, nil
+	}
+	// Climb through the category's live ancestors, as category_ancestors does
+	for seen := map[uuid.UUID]bool{}; !seen[cat.ID]; {
+		if cat.ID == arg.RootID {
+			return true, nil
+		}
+		seen[cat.ID] = true
+		if cat.ParentID == nil {
+			break
+		}
+		parent, exists := m.categories[cat.ParentID.String()]
+		if !exists || parent.DeletedAt != nil {
+			break
+		}
+		cat = parent
+	}
+	return false, nil
+}
+
+// inUse reports whether a live expense, budget or recurring transaction uses
+// the category
+func (m *CategoryMock) inUse(id uuid.UUID) bool {
+	for _, expense := range m.expenses.expenses {
+		if expense.CategoryID == id && expense.DeletedAt == nil {
+			return true
+		}
+	}
+	for _, budget := range m.budgets.budgets {
+		if budget.CategoryID == id && budget.DeletedAt == nil {
+			return true
+		}
+	}
+	for _, template := range m.recurring.templates {
+		if template.CategoryID != nil && *template.CategoryID == id && template.DeletedAt == nil {
+			return true
+		}
+	}
+	for _, rule := range m.rules.rules {
+		if rule.CategoryID != nil && *rule.CategoryID == id && rule.DeletedAt == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *CategoryMock) IsCategoryInUse(ctx context.Context, arg repository.IsCategoryInUseParams) (bool, error) {
+	if cat, exists := m.categories[arg.ID.String()]; !exists || cat.UserID != arg.UserID {
+		return false, nil
+	}
+	return m.inUse(arg.ID), nil
+}
+
 func (m *CategoryMock) ListCategories(ctx context.Context, userID uuid.UUID) ([]repository.Category, error) {
 	var result []repository.Category
 	for _, cat := range m.categories {
@@ -92,10 +252,89 @@ func (m *CategoryMock) ListCategories(ctx context.Context, userID uuid.UUID) ([]
 	return result, nil
 }
 
+func (m *CategoryMock) ListCategoriesPage(ctx context.Context, arg repository.ListCategoriesPageParams) ([]repository.Category, error) {
+	var result []repository.Category
+	for _, cat := range m.categories {
+		if cat.UserID == arg.UserID && cat.DeletedAt == nil && containsFold(cat.Name, arg.Search) {
+			result = append(result, cat)
+		}
+	}
+	// Categories are sorted by name unless sorted by created_at
+	field := "name"
+	if arg.Sort == "created_at" {
+		field = arg.Sort
+	}
+	key := func(cat repository.Category) pageKey {
+		return sortKey(field, money.Amount{}, cat.CreatedAt, time.Time{}, cat.Name, cat.ID)
+	}
+	after := afterKey(field, arg.AfterID, nil, arg.AfterTime, arg.AfterName)
+	return page(result, key, arg.Descending, after, arg.PageSize), nil
+}
+
+func (m *CategoryMock) ListCategoryTemplates(ctx context.Context) ([]repository.ListCategoryTemplatesRow, error) {
+	return m.templates, nil
+}
+
+func (m *CategoryMock) MergeCategory(ctx context.Context, arg repository.MergeCategoryParams) (int64, error) {
+	source, exists := m.categories[arg.ID.String()]
+	if !exists || source.UserID != arg.UserID || source.DeletedAt != nil {
+		return 0, nil
+	}
+	target, exists := m.categories[arg.TargetID.String()]
+	if !exists || target.UserID != arg.UserID || target.DeletedAt != nil || target.ID == source.ID {
+		return 0, nil
+	}
+
+	now := time.Now()
+	for key, expense := range m.expenses.expenses {
+		if expense.CategoryID == source.ID {
+			expense.CategoryID = target.ID
+			expense.UpdatedAt = &now
+			m.expenses.expenses[key] = expense
+		}
+	}
+	for key, budget := range m.budgets.budgets {
+		if budget.CategoryID == source.ID {
+			budget.CategoryID = target.ID
+			budget.UpdatedAt = &now
+			m.budgets.budgets[key] = budget
+		}
+	}
+	for key, template := range m.recurring.templates {
+		if template.CategoryID != nil && *template.CategoryID == source.ID {
+			template.CategoryID = &target.ID
+			template.UpdatedAt = &now
+			m.recurring.templates[key] = template
+		}
+	}
+	for key, rule := range m.rules.rules {
+		if rule.CategoryID != nil && *rule.CategoryID == source.ID {
+			rule.CategoryID = &target.ID
+			rule.UpdatedAt = &now
+			m.rules.rules[key] = rule
+		}
+	}
+	for key, cat := range m.categories {
+		if cat.ParentID == nil || *cat.ParentID != source.ID || cat.DeletedAt != nil {
+			continue
+		}
+		cat.ParentID = source.ParentID
+		if arg.AdoptSubcategories && cat.ID != target.ID {
+			cat.ParentID = &target.ID
+		}
+		cat.UpdatedAt = &now
+		m.categories[key] = cat
+	}
+	source.DeletedAt = &now
+	m.categories[source.ID.String()] = source
+	return 1, nil
+}
+
 func (m *CategoryMock) UpdateCategory(ctx context.Context, arg repository.UpdateCategoryParams) (repository.Category, error) {
 	if cat, exists := m.categories[arg.ID.String()]; exists && cat.UserID == arg.UserID {
 		now := time.Now()
 		cat.Name = arg.Name
+		cat.ParentID = arg.ParentID
 		cat.UpdatedAt = &now
 		m.categories[arg.ID.String()] = cat
 		return cat, nil
@@ -0,0 +1,110 @@
+package mocks
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/repository"
+)
+
+type CategoryRuleMock struct {
+	rules map[string]repository.CategoryRule
+}
+
+func NewCategoryRuleMock() *CategoryRuleMock {
+	return &CategoryRuleMock{
+		rules: make(map[string]repository.CategoryRule),
+	}
+}
+
+// Helper methods for setting up test data
+func (m *CategoryRuleMock) AddCategoryRule(rule repository.CategoryRule) {
+	m.rules[rule.ID.String()] = rule
+}
+
+// userRule returns a rule when it belongs to the user and is not deleted
+func (m *CategoryRuleMock) userRule(id, userID uuid.UUID) (repository.CategoryRule, bool) {
+	rule, exists := m.rules[id.String()]
+	return rule, exists && rule.UserID == userID && rule.DeletedAt == nil
+}
+
+func (m *CategoryRuleMock) CreateCategoryRule(ctx context.Context, arg repository.CreateCategoryRuleParams) (repository.CategoryRule, error) {
+	now := time.Now()
+	rule := repository.CategoryRule{
+		ID:                 arg.ID,
+		UserID:             arg.UserID,
+		Name:               arg.Name,
+		DescriptionPattern: arg.DescriptionPattern,
+		MinAmount:          arg.MinAmount,
+		MaxAmount:          arg.MaxAmount,
+		Currency:           arg.Currency,
+		CategoryID:         arg.CategoryID,
+		TagIds:             arg.TagIds,
+		Priority:           arg.Priority,
+		Enabled:            arg.Enabled,
+		CreatedAt:          now,
+		UpdatedAt:          &now,
+	}
+	m.rules[arg.ID.String()] = rule
+	return rule, nil
+}
+
+func (m *CategoryRuleMock) DeleteCategoryRule(ctx context.Context, arg repository.DeleteCategoryRuleParams) (int64, error) {
+	rule, ok := m.userRule(arg.ID, arg.UserID)
+	if !ok {
+		return 0, nil
+	}
+	now := time.Now()
+	rule.DeletedAt = &now
+	m.rules[arg.ID.String()] = rule
+	return 1, nil
+}
+
+func (m *CategoryRuleMock) GetCategoryRuleByID(ctx context.Context, arg repository.GetCategoryRuleByIDParams) (repository.CategoryRule, error) {
+	if rule, ok := m.userRule(arg.ID, arg.UserID); ok {
+		return rule, nil
+	}
+	return repository.CategoryRule{}, ErrRecordNotFound
+}
+
+func (m *CategoryRuleMock) ListCategoryRules(ctx context.Context, userID uuid.UUID) ([]repository.CategoryRule, error) {
+	var result []repository.CategoryRule
+	for _, rule := range m.rules {
+		if rule.UserID == userID && rule.DeletedAt == nil {
+			result = append(result, rule)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	return result, nil
+}
+
+func (m *CategoryRuleMock) UpdateCategoryRule(ctx context.Context, arg repository.UpdateCategoryRuleParams) (repository.CategoryRule, error) {
+	rule, ok := m.userRule(arg.ID, arg.UserID)
+	if !ok {
+		return repository.CategoryRule{}, ErrRecordNotFound
+	}
+	now := time.Now()
+	rule.Name = arg.Name
+	rule.DescriptionPattern = arg.DescriptionPattern
+	rule.MinAmount = arg.MinAmount
+	rule.MaxAmount = arg.MaxAmount
+	rule.Currency = arg.Currency
+	rule.CategoryID = arg.CategoryID
+	rule.TagIds = arg.TagIds
+	rule.Priority = arg.Priority
+	rule.Enabled = arg.Enabled
+	rule.UpdatedAt = &now
+	m.rules[arg.ID.String()] = rule
+	return rule, nil
+}
@@ -1,9 +1,23 @@
 package mocks
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
 
 var (
 	ErrRecordNotFound = errors.New("record not found")
 	ErrInvalidInput   = errors.New("invalid input")
 	ErrDuplicateKey   = errors.New("duplicate key value violates unique constraint")
 )
+
+// uniqueViolation is the error Postgres fails a write with when it would repeat
+// a key of the unique index
+func uniqueViolation(index string) error {
+	return &pgconn.PgError{
+		Code:           "23505",
+		Message:        "duplicate key value violates unique constraint \"" + index + "\"",
+		ConstraintName: index,
+	}
+}
@@ -0,0 +1,112 @@
+package mocks
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/repository"
+)
+
+type ExchangeRateMock struct {
+	rates map[string]repository.ExchangeRate
+}
+
+func NewExchangeRateMock() *ExchangeRateMock {
+	return &ExchangeRateMock{
+		rates: make(map[string]repository.ExchangeRate),
+	}
+}
+
+func exchangeRateKey(base, quote string, date time.Time) string {
+	return base + quote + date.Format(time.DateOnly)
+}
+
+// Helper methods for setting up test data
+func (m *ExchangeRateMock) AddExchangeRate(rate repository.ExchangeRate) {
+	m.rates[exchangeRateKey(rate.BaseCurrency, rate.QuoteCurrency, rate.Date)] = rate
+}
+
+// GetExchangeRate returns a stored rate, for asserting what a handler saved
+func (m *ExchangeRateMock) GetExchangeRate(base, quote string, date time.Time) (repository.ExchangeRate, bool) {
+	rate, exists := m.rates[exchangeRateKey(base, quote, date)]
+	return rate, exists
+}
+
+func (m *ExchangeRateMock) DeleteExchangeRate(ctx context.Context, arg repository.DeleteExchangeRateParams) (int64, error) {
+	key := exchangeRateKey(arg.BaseCurrency, arg.QuoteCurrency, arg.Date)
+	if _, exists := m.rates[key]; !exists {
+		return 0, nil
+	}
+	delete(m.rates, key)
+	return 1, nil
+}
+
+func (m *ExchangeRateMock) ListExchangeRates(ctx context.Context, arg repository.ListExchangeRatesParams) ([]repository.ExchangeRate, error) {
+	var result []repository.ExchangeRate
+	for _, rate := range m.rates {
+		if (arg.BaseCurrency == "" || rate.BaseCurrency == arg.BaseCurrency) &&
+			(arg.QuoteCurrency == "" || rate.QuoteCurrency == arg.QuoteCurrency) {
+			result = append(result, rate)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.BaseCurrency != b.BaseCurrency {
+			return a.BaseCurrency < b.BaseCurrency
+		}
+		if a.QuoteCurrency != b.QuoteCurrency {
+			return a.QuoteCurrency < b.QuoteCurrency
+		}
+		return a.Date.After(b.Date)
+	})
+
+	if int(arg.Limit) < len(result) {
+		result = result[:arg.Limit]
+	}
+	return result, nil
+}
+
+func (m *ExchangeRateMock) UpsertExchangeRate(ctx context.Context, arg repository.UpsertExchangeRateParams) (repository.ExchangeRate, error) {
+	now := time.Now()
+	key := exchangeRateKey(arg.BaseCurrency, arg.QuoteCurrency, arg.Date)
+	rate, exists := m.rates[key]
+	if exists {
+		rate.UpdatedAt = &now
+	} else {
+		rate = repository.ExchangeRate{
+			BaseCurrency:  arg.BaseCurrency,
+			QuoteCurrency: arg.QuoteCurrency,
+			Date:          arg.Date,
+			CreatedAt:     now,
+		}
+	}
+	rate.Rate = arg.Rate
+	m.rates[key] = rate
+	return rate, nil
+}
+
+func (m *ExchangeRateMock) UpsertExchangeRates(ctx context.Context, arg repository.UpsertExchangeRatesParams) (int64, error) {
+	n := len(arg.BaseCurrencies)
+	if len(arg.QuoteCurrencies) != n || len(arg.Dates) != n || len(arg.Rates) != n {
+		return 0, ErrInvalidInput
+	}
+
+	for i := range n {
+		rate, err := money.ParseRate(arg.Rates[i])
+		if err != nil {
+			return 0, err
+		}
+		if _, err := m.UpsertExchangeRate(ctx, repository.UpsertExchangeRateParams{
+			BaseCurrency:  arg.BaseCurrencies[i],
+			QuoteCurrency: arg.QuoteCurrencies[i],
+			Date:          arg.Dates[i],
+			Rate:          rate,
+		}); err != nil {
+			return 0, err
+		}
+	}
+	return int64(n), nil
+}
@@ -2,19 +2,26 @@ package mocks
 
 import (
 	"context"
+	"crypto/md5"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 	"github.com/jorge-dev/centsible/internal/repository"
 )
 
 type ExpenseMock struct {
 	expenses map[string]repository.Expense
+	tags     tagLinks
 }
 
 func NewExpenseMock() *ExpenseMock {
 	return &ExpenseMock{
 		expenses: make(map[string]repository.Expense),
+		tags:     make(tagLinks),
 	}
 }
 
@@ -24,6 +31,9 @@ func (m *ExpenseMock) AddExpense(expense repository.Expense) {
 }
 
 func (m *ExpenseMock) CreateExpense(ctx context.Context, arg repository.CreateExpenseParams) (repository.Expense, error) {
+	if m.externalIDTaken(arg.UserID, arg.ExternalID) {
+		return repository.Expense{}, uniqueViolation(repository.ExpenseExternalIDIndex)
+	}
 	now := time.Now()
 	expense := repository.Expense{
 		ID:          arg.ID,
@@ -34,11 +44,29 @@ func (m *ExpenseMock) CreateExpense(ctx context.Context, arg repository.CreateEx
 		Date:        arg.Date,
 		Description: arg.Description,
 		CreatedAt:   now,
+		ExternalID:  arg.ExternalID,
 	}
 	m.expenses[expense.ID.String()] = expense
+	if len(arg.TagIds) > 0 {
+		m.tags.set(expense.ID, arg.TagIds)
+	}
 	return expense, nil
 }
 
+// externalIDTaken reports whether one of the user's expenses, deleted ones
+// included, already has the external ID
+func (m *ExpenseMock) externalIDTaken(userID uuid.UUID, externalID *string) bool {
+	if externalID == nil {
+		return false
+	}
+	for _, expense := range m.expenses {
+		if expense.UserID == userID && expense.ExternalID != nil && *expense.ExternalID == *externalID {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *ExpenseMock) DeleteExpense(ctx context.Context, arg repository.DeleteExpenseParams) (int64, error) {
 	key := arg.ID.String()
 	expense, exists := m.expenses[key]
@@ -49,6 +77,22 @@ func (m *ExpenseMock) DeleteExpense(ctx context.Context, arg repository.DeleteEx
 	return 1, nil
 }
 
+func (m *ExpenseMock) FindDuplicateExpenses(ctx context.Context, arg repository.FindDuplicateExpensesParams) ([]repository.Expense, error) {
+	fingerprint := transactionFingerprint(arg.Amount, arg.Currency, arg.Date, arg.Description, arg.CategoryID.String())
+	var result []repository.Expense
+	for _, expense := range m.expenses {
+		if expense.UserID != arg.UserID {
+			continue
+		}
+		if expenseFingerprint(expense) == fingerprint ||
+			(arg.ExternalID != "" && expense.ExternalID != nil && *expense.ExternalID == arg.ExternalID) {
+			result = append(result, expense)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result, nil
+}
+
 func (m *ExpenseMock) GetExpenseByID(ctx context.Context, arg repository.GetExpenseByIDParams) (repository.Expense, error) {
 	expense, exists := m.expenses[arg.ID.String()]
 	if !exists || expense.UserID != arg.UserID {
@@ -68,10 +112,17 @@ func (m *ExpenseMock) GetExpenseTotalsByCategory(ctx context.Context, userID uui
 					CategoryID:   expense.CategoryID,
 					CategoryName: "Test Category",
 					Currency:     expense.Currency,
+					BaseCurrency: "USD",
 				}
 			}
 			total.TransactionCount++
-			total.TotalAmount += expense.Amount
+			total.TotalAmount = total.TotalAmount.Add(expense.Amount)
+			// The mock has no exchange rates, so only USD converts
+			if expense.Currency == total.BaseCurrency {
+				total.ConvertedAmount = total.ConvertedAmount.Add(expense.Amount)
+			} else {
+				total.UnconvertedCount++
+			}
 			totals[expense.CategoryID] = total
 		}
 	}
@@ -106,12 +157,12 @@ func (m *ExpenseMock) GetExpensesByDateRange(ctx context.Context, arg repository
 }
 
 func (m *ExpenseMock) GetMonthlyExpenseTotal(ctx context.Context, arg repository.GetMonthlyExpenseTotalParams) ([]repository.GetMonthlyExpenseTotalRow, error) {
-	totals := make(map[string]float64)
+	totals := make(map[string]money.Amount)
 
 	targetMonth := arg.Date.Format("2006-01")
 	for _, expense := range m.expenses {
 		if expense.UserID == arg.UserID && expense.Date.Format("2006-01") == targetMonth {
-			totals[expense.Currency] += expense.Amount
+			totals[expense.Currency] = totals[expense.Currency].Add(expense.Amount)
 		}
 	}
 
@@ -139,6 +190,51 @@ func (m *ExpenseMock) GetRecentExpenses(ctx context.Context, arg repository.GetR
 	return result, nil
 }
 
+func (m *ExpenseMock) ListDuplicateExpenses(ctx context.Context, userID uuid.UUID) ([]repository.ListDuplicateExpensesRow, error) {
+	groups := make(map[string][]repository.Expense)
+	for _, expense := range m.expenses {
+		if expense.UserID == userID {
+			groups[expenseFingerprint(expense)] = append(groups[expenseFingerprint(expense)], expense)
+		}
+	}
+
+	var result []repository.ListDuplicateExpensesRow
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool {
+			if !group[i].CreatedAt.Equal(group[j].CreatedAt) {
+				return group[i].CreatedAt.Before(group[j].CreatedAt)
+			}
+			return group[i].ID.String() < group[j].ID.String()
+		})
+		for _, expense := range group {
+			result = append(result, repository.ListDuplicateExpensesRow{
+				ID:          expense.ID,
+				UserID:      expense.UserID,
+				Amount:      expense.Amount,
+				Currency:    expense.Currency,
+				CategoryID:  expense.CategoryID,
+				Date:        expense.Date,
+				Description: expense.Description,
+				CreatedAt:   expense.CreatedAt,
+				UpdatedAt:   expense.UpdatedAt,
+				DeletedAt:   expense.DeletedAt,
+				ExternalID:  expense.ExternalID,
+				OriginalID:  group[0].ID,
+			})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].Date.Equal(result[j].Date) {
+			return result[i].Date.After(result[j].Date)
+		}
+		return result[i].OriginalID.String() < result[j].OriginalID.String()
+	})
+	return result, nil
+}
+
 func (m *ExpenseMock) ListExpenses(ctx context.Context, userID uuid.UUID) ([]repository.Expense, error) {
 	var result []repository.Expense
 	for _, expense := range m.expenses {
@@ -149,6 +245,53 @@ func (m *ExpenseMock) ListExpenses(ctx context.Context, userID uuid.UUID) ([]rep
 	return result, nil
 }
 
+func (m *ExpenseMock) ListExpensesPage(ctx context.Context, arg repository.ListExpensesPageParams) ([]repository.Expense, error) {
+	var result []repository.Expense
+	for _, expense := range m.expenses {
+		if expense.UserID == arg.UserID && expense.DeletedAt == nil &&
+			inAmountRange(expense.Amount, arg.MinAmount, arg.MaxAmount) &&
+			(arg.Currency == "" || expense.Currency == arg.Currency) &&
+			(arg.CategoryID == nil || expense.CategoryID == *arg.CategoryID) &&
+			m.tags.has(expense.ID, arg.TagID) &&
+			containsFold(expense.Description, arg.Search) &&
+			inDateRange(expense.Date, arg.StartDate, arg.EndDate) {
+			result = append(result, expense)
+		}
+	}
+	key := func(expense repository.Expense) pageKey {
+		return sortKey(arg.Sort, expense.Amount, expense.CreatedAt, expense.Date, "", expense.ID)
+	}
+	after := afterKey(arg.Sort, arg.AfterID, arg.AfterAmount, arg.AfterTime, "")
+	return page(result, key, arg.Descending, after, arg.PageSize), nil
+}
+
+func (m *ExpenseMock) MatchImportedExpenses(ctx context.Context, arg repository.MatchImportedExpensesParams) ([]repository.MatchImportedExpensesRow, error) {
+	result := make([]repository.MatchImportedExpensesRow, len(arg.Amounts))
+	for i, value := range arg.Amounts {
+		amount, err := money.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		row := repository.MatchImportedExpensesRow{
+			Ordinal:     int32(i + 1),
+			Fingerprint: transactionFingerprint(amount, arg.Currencies[i], arg.Dates[i], arg.Descriptions[i], arg.CategoryIds[i].String()),
+		}
+		for _, expense := range m.expenses {
+			if expense.UserID != arg.UserID {
+				continue
+			}
+			if expenseFingerprint(expense) == row.Fingerprint {
+				row.Matches++
+			}
+			if arg.ExternalIds[i] != "" && expense.ExternalID != nil && *expense.ExternalID == arg.ExternalIds[i] {
+				row.ExternalIDTaken = true
+			}
+		}
+		result[i] = row
+	}
+	return result, nil
+}
+
 func (m *ExpenseMock) UpdateExpense(ctx context.Context, arg repository.UpdateExpenseParams) (repository.Expense, error) {
 	expense, exists := m.expenses[arg.ID.String()]
 	if !exists || expense.UserID != arg.UserID {
@@ -166,3 +309,20 @@ func (m *ExpenseMock) UpdateExpense(ctx context.Context, arg repository.UpdateEx
 	m.expenses[arg.ID.String()] = expense
 	return expense, nil
 }
+
+// transactionFingerprint mirrors the transaction_fingerprint SQL function
+func transactionFingerprint(amount money.Amount, currency string, date time.Time, description, party string) string {
+	day := date.UTC().Format(time.DateOnly)
+	key := strings.Join([]string{
+		amount.String(),
+		strings.ToUpper(currency),
+		day,
+		strings.ToLower(strings.TrimSpace(description)),
+		strings.ToLower(strings.TrimSpace(party)),
+	}, "|")
+	return fmt.Sprintf("%x", md5.Sum([]byte(key)))
+}
+
+func expenseFingerprint(expense repository.Expense) string {
+	return transactionFingerprint(expense.Amount, expense.Currency, expense.Date, expense.Description, expense.CategoryID.String())
+}
@@ -0,0 +1,113 @@
+package mocks
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/repository"
+)
+
+// ExportMock pages through the expense and income mocks it was created with
+type ExportMock struct {
+	expenses *ExpenseMock
+	income   *IncomeMock
+}
+
+func NewExportMock(expenses *ExpenseMock, income *IncomeMock) *ExportMock {
+	return &ExportMock{
+		expenses: expenses,
+		income:   income,
+	}
+}
+
+// exportAfter reports whether a transaction comes after the (date, id) a page
+// starts from
+func exportAfter(date time.Time, id uuid.UUID, afterDate time.Time, afterID uuid.UUID) bool {
+	if !date.Equal(afterDate) {
+		return date.After(afterDate)
+	}
+	return id.String() > afterID.String()
+}
+
+func exportLess(dateI time.Time, idI uuid.UUID, dateJ time.Time, idJ uuid.UUID) bool {
+	if !dateI.Equal(dateJ) {
+		return dateI.Before(dateJ)
+	}
+	return idI.String() < idJ.String()
+}
+
+func (m *ExportMock) ExportExpenses(ctx context.Context, arg repository.ExportExpensesParams) ([]repository.Expense, error) {
+	var result []repository.Expense
+	for _, expense := range m.expenses.expenses {
+		if expense.UserID == arg.UserID &&
+			(arg.Currency == "" || expense.Currency == arg.Currency) &&
+			m.expenses.tags.has(expense.ID, arg.TagID) &&
+			exportAfter(expense.Date, expense.ID, arg.AfterDate, arg.AfterID) {
+			result = append(result, expense)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return exportLess(result[i].Date, result[i].ID, result[j].Date, result[j].ID)
+	})
+	if len(result) > int(arg.PageSize) {
+		result = result[:arg.PageSize]
+	}
+	return result, nil
+}
+
+func (m *ExportMock) ExportIncome(ctx context.Context, arg repository.ExportIncomeParams) ([]repository.Income, error) {
+	var result []repository.Income
+	for _, income := range m.income.incomes {
+		if income.UserID == arg.UserID && income.DeletedAt == nil &&
+			(arg.Currency == "" || income.Currency == arg.Currency) &&
+			m.income.tags.has(income.ID, arg.TagID) &&
+			exportAfter(income.Date, income.ID, arg.AfterDate, arg.AfterID) {
+			result = append(result, income)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return exportLess(result[i].Date, result[i].ID, result[j].Date, result[j].ID)
+	})
+	if len(result) > int(arg.PageSize) {
+		result = result[:arg.PageSize]
+	}
+	return result, nil
+}
+
+func (m *ExportMock) ListStatementCurrencies(ctx context.Context, userID uuid.UUID) ([]repository.ListStatementCurrenciesRow, error) {
+	rows := make(map[string]repository.ListStatementCurrenciesRow)
+	add := func(currency string, date time.Time, amount money.Amount) {
+		row, exists := rows[currency]
+		if !exists {
+			row = repository.ListStatementCurrenciesRow{Currency: currency, FirstDate: date, LastDate: date}
+		}
+		if date.Before(row.FirstDate) {
+			row.FirstDate = date
+		}
+		if date.After(row.LastDate) {
+			row.LastDate = date
+		}
+		row.Balance = row.Balance.Add(amount)
+		rows[currency] = row
+	}
+	for _, income := range m.income.incomes {
+		if income.UserID == userID && income.DeletedAt == nil {
+			add(income.Currency, income.Date, income.Amount)
+		}
+	}
+	for _, expense := range m.expenses.expenses {
+		if expense.UserID == userID {
+			add(expense.Currency, expense.Date, expense.Amount.Neg())
+		}
+	}
+
+	result := make([]repository.ListStatementCurrenciesRow, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, row)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Currency < result[j].Currency })
+	return result, nil
+}
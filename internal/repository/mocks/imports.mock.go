@@ -0,0 +1,116 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/repository"
+)
+
+// ImportMock saves imported transactions into the expense and income mocks it
+// was created with
+type ImportMock struct {
+	expenses *ExpenseMock
+	income   *IncomeMock
+}
+
+func NewImportMock(expenses *ExpenseMock, income *IncomeMock) *ImportMock {
+	return &ImportMock{
+		expenses: expenses,
+		income:   income,
+	}
+}
+
+func (m *ImportMock) ImportTransactions(ctx context.Context, arg repository.ImportTransactionsParams) (repository.ImportTransactionsRow, error) {
+	if len(arg.ExpenseAmounts) != len(arg.ExpenseIds) || len(arg.IncomeAmounts) != len(arg.IncomeIds) {
+		return repository.ImportTransactionsRow{}, errors.New("import arrays differ in length")
+	}
+
+	// Parse everything first so that a bad amount saves nothing
+	expenseAmounts := make([]money.Amount, len(arg.ExpenseAmounts))
+	for i, amount := range arg.ExpenseAmounts {
+		parsed, err := money.Parse(amount)
+		if err != nil {
+			return repository.ImportTransactionsRow{}, err
+		}
+		expenseAmounts[i] = parsed
+	}
+	incomeAmounts := make([]money.Amount, len(arg.IncomeAmounts))
+	for i, amount := range arg.IncomeAmounts {
+		parsed, err := money.Parse(amount)
+		if err != nil {
+			return repository.ImportTransactionsRow{}, err
+		}
+		incomeAmounts[i] = parsed
+	}
+
+	// The unique indexes reject external IDs that are taken or repeated
+	if externalIDRepeated(arg.ExpenseExternalIds, func(id *string) bool { return m.expenses.externalIDTaken(arg.UserID, id) }) {
+		return repository.ImportTransactionsRow{}, uniqueViolation(repository.ExpenseExternalIDIndex)
+	}
+	if externalIDRepeated(arg.IncomeExternalIds, func(id *string) bool { return m.income.externalIDTaken(arg.UserID, id) }) {
+		return repository.ImportTransactionsRow{}, uniqueViolation(repository.IncomeExternalIDIndex)
+	}
+
+	now := time.Now()
+	for i, id := range arg.ExpenseIds {
+		m.expenses.AddExpense(repository.Expense{
+			ID:          id,
+			UserID:      arg.UserID,
+			Amount:      expenseAmounts[i],
+			Currency:    arg.ExpenseCurrencies[i],
+			CategoryID:  arg.ExpenseCategoryIds[i],
+			Date:        arg.ExpenseDates[i],
+			Description: arg.ExpenseDescriptions[i],
+			CreatedAt:   now,
+			UpdatedAt:   &now,
+			ExternalID:  externalID(arg.ExpenseExternalIds, i),
+		})
+	}
+	for i, id := range arg.IncomeIds {
+		m.income.AddIncome(repository.Income{
+			ID:          id,
+			UserID:      arg.UserID,
+			Amount:      incomeAmounts[i],
+			Currency:    arg.IncomeCurrencies[i],
+			Source:      arg.IncomeSources[i],
+			Date:        arg.IncomeDates[i],
+			Description: arg.IncomeDescriptions[i],
+			CreatedAt:   now,
+			UpdatedAt:   &now,
+			ExternalID:  externalID(arg.IncomeExternalIds, i),
+		})
+	}
+
+	return repository.ImportTransactionsRow{
+		Expenses: int64(len(arg.ExpenseIds)),
+		Income:   int64(len(arg.IncomeIds)),
+	}, nil
+}
+
+// externalIDRepeated reports whether an external ID is taken, or appears
+// twice among ids
+func externalIDRepeated(ids []string, taken func(*string) bool) bool {
+	seen := make(map[string]bool, len(ids))
+	for i := range ids {
+		id := externalID(ids, i)
+		if id == nil {
+			continue
+		}
+		if seen[*id] || taken(id) {
+			return true
+		}
+		seen[*id] = true
+	}
+	return false
+}
+
+// externalID saves an empty external ID as none, as the query does
+func externalID(ids []string, i int) *string {
+	if i >= len(ids) || ids[i] == "" {
+		return nil
+	}
+	return &ids[i]
+}
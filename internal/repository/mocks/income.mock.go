@@ -2,19 +2,25 @@ package mocks
 
 import (
 	"context"
+	"math/big"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 	"github.com/jorge-dev/centsible/internal/repository"
 )
 
 type IncomeMock struct {
 	incomes map[string]repository.Income
+	tags    tagLinks
 }
 
 func NewIncomeMock() *IncomeMock {
 	return &IncomeMock{
 		incomes: make(map[string]repository.Income),
+		tags:    make(tagLinks),
 	}
 }
 
@@ -24,6 +30,9 @@ func (m *IncomeMock) AddIncome(income repository.Income) {
 }
 
 func (m *IncomeMock) CreateIncome(ctx context.Context, arg repository.CreateIncomeParams) (repository.Income, error) {
+	if m.externalIDTaken(arg.UserID, arg.ExternalID) {
+		return repository.Income{}, uniqueViolation(repository.IncomeExternalIDIndex)
+	}
 	income := repository.Income{
 		ID:          arg.ID,
 		UserID:      arg.UserID,
@@ -35,11 +44,26 @@ func (m *IncomeMock) CreateIncome(ctx context.Context, arg repository.CreateInco
 		CreatedAt:   time.Now(),
 		UpdatedAt:   nil,
 		DeletedAt:   nil,
+		ExternalID:  arg.ExternalID,
 	}
 	m.incomes[income.ID.String()] = income
 	return income, nil
 }
 
+// externalIDTaken reports whether one of the user's income, deleted or not,
+// already has the external ID
+func (m *IncomeMock) externalIDTaken(userID uuid.UUID, externalID *string) bool {
+	if externalID == nil {
+		return false
+	}
+	for _, income := range m.incomes {
+		if income.UserID == userID && income.ExternalID != nil && *income.ExternalID == *externalID {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *IncomeMock) DeleteIncome(ctx context.Context, arg repository.DeleteIncomeParams) (int64, error) {
 	if income, exists := m.incomes[arg.ID.String()]; exists && income.UserID == arg.UserID {
 		now := time.Now()
@@ -50,6 +74,22 @@ func (m *IncomeMock) DeleteIncome(ctx context.Context, arg repository.DeleteInco
 	return 0, nil
 }
 
+func (m *IncomeMock) FindDuplicateIncome(ctx context.Context, arg repository.FindDuplicateIncomeParams) ([]repository.Income, error) {
+	fingerprint := transactionFingerprint(arg.Amount, arg.Currency, arg.Date, arg.Description, arg.Source)
+	var result []repository.Income
+	for _, income := range m.incomes {
+		if income.UserID != arg.UserID {
+			continue
+		}
+		if (income.DeletedAt == nil && incomeFingerprint(income) == fingerprint) ||
+			(arg.ExternalID != "" && income.ExternalID != nil && *income.ExternalID == arg.ExternalID) {
+			result = append(result, income)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result, nil
+}
+
 func (m *IncomeMock) GetIncomeByID(ctx context.Context, arg repository.GetIncomeByIDParams) (repository.Income, error) {
 	if income, exists := m.incomes[arg.ID.String()]; exists && income.UserID == arg.UserID && income.DeletedAt == nil {
 		return income, nil
@@ -67,6 +107,53 @@ func (m *IncomeMock) ListIncome(ctx context.Context, userID uuid.UUID) ([]reposi
 	return incomes, nil
 }
 
+func (m *IncomeMock) ListIncomePage(ctx context.Context, arg repository.ListIncomePageParams) ([]repository.Income, error) {
+	var result []repository.Income
+	for _, income := range m.incomes {
+		if income.UserID == arg.UserID && income.DeletedAt == nil &&
+			inAmountRange(income.Amount, arg.MinAmount, arg.MaxAmount) &&
+			(arg.Currency == "" || income.Currency == arg.Currency) &&
+			(arg.Source == "" || strings.EqualFold(income.Source, arg.Source)) &&
+			m.tags.has(income.ID, arg.TagID) &&
+			containsFold(income.Description, arg.Search) &&
+			inDateRange(income.Date, arg.StartDate, arg.EndDate) {
+			result = append(result, income)
+		}
+	}
+	key := func(income repository.Income) pageKey {
+		return sortKey(arg.Sort, income.Amount, income.CreatedAt, income.Date, "", income.ID)
+	}
+	after := afterKey(arg.Sort, arg.AfterID, arg.AfterAmount, arg.AfterTime, "")
+	return page(result, key, arg.Descending, after, arg.PageSize), nil
+}
+
+func (m *IncomeMock) MatchImportedIncome(ctx context.Context, arg repository.MatchImportedIncomeParams) ([]repository.MatchImportedIncomeRow, error) {
+	result := make([]repository.MatchImportedIncomeRow, len(arg.Amounts))
+	for i, value := range arg.Amounts {
+		amount, err := money.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		row := repository.MatchImportedIncomeRow{
+			Ordinal:     int32(i + 1),
+			Fingerprint: transactionFingerprint(amount, arg.Currencies[i], arg.Dates[i], arg.Descriptions[i], arg.Sources[i]),
+		}
+		for _, income := range m.incomes {
+			if income.UserID != arg.UserID {
+				continue
+			}
+			if income.DeletedAt == nil && incomeFingerprint(income) == row.Fingerprint {
+				row.Matches++
+			}
+			if arg.ExternalIds[i] != "" && income.ExternalID != nil && *income.ExternalID == arg.ExternalIds[i] {
+				row.ExternalIDTaken = true
+			}
+		}
+		result[i] = row
+	}
+	return result, nil
+}
+
 func (m *IncomeMock) UpdateIncome(ctx context.Context, arg repository.UpdateIncomeParams) (repository.Income, error) {
 	if income, exists := m.incomes[arg.ID.String()]; exists && income.UserID == arg.UserID && income.DeletedAt == nil {
 		now := time.Now()
@@ -119,8 +206,8 @@ func (m *IncomeMock) GetIncomeSummaryBySource(ctx context.Context, userID uuid.U
 			}
 			s := summary[income.Source][income.Currency]
 			s.TransactionCount++
-			s.TotalAmount += int64(income.Amount)
-			s.AverageAmount = float64(s.TotalAmount) / float64(s.TransactionCount)
+			s.TotalAmount = s.TotalAmount.Add(income.Amount)
+			s.AverageAmount = s.TotalAmount.MulRat(big.NewRat(1, s.TransactionCount))
 		}
 	}
 
@@ -134,13 +221,13 @@ func (m *IncomeMock) GetIncomeSummaryBySource(ctx context.Context, userID uuid.U
 }
 
 func (m *IncomeMock) GetMonthlyIncomeTotal(ctx context.Context, arg repository.GetMonthlyIncomeTotalParams) (repository.GetMonthlyIncomeTotalRow, error) {
-	var total float64
+	var total money.Amount
 	var currency string
 
 	for _, income := range m.incomes {
 		if income.UserID == arg.UserID && income.DeletedAt == nil &&
 			income.Date.Year() == arg.Date.Year() && income.Date.Month() == arg.Date.Month() {
-			total += income.Amount
+			total = total.Add(income.Amount)
 			currency = income.Currency
 		}
 	}
@@ -165,3 +252,7 @@ func (m *IncomeMock) GetRecentIncome(ctx context.Context, arg repository.GetRece
 	}
 	return incomes, nil
 }
+
+func incomeFingerprint(income repository.Income) string {
+	return transactionFingerprint(income.Amount, income.Currency, income.Date, income.Description, income.Source)
+}
@@ -0,0 +1,113 @@
+package mocks
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+)
+
+// pageKey is what the List*Page and search queries sort a record by: the
+// value of its sort field, then its ID. Only the field being sorted by is set.
+type pageKey struct {
+	amount money.Amount
+	rank   float32
+	time   time.Time
+	name   string
+	id     uuid.UUID
+}
+
+// sortKey picks the value a record is sorted by. Sorts other than amount,
+// created_at and name are by date.
+func sortKey(field string, amount money.Amount, createdAt, date time.Time, name string, id uuid.UUID) pageKey {
+	switch field {
+	case "amount":
+		return pageKey{amount: amount, id: id}
+	case "created_at":
+		return pageKey{time: createdAt, id: id}
+	case "name":
+		return pageKey{name: name, id: id}
+	default:
+		return pageKey{time: date, id: id}
+	}
+}
+
+// afterKey is the key of the cursor a page continues from, or nil for the
+// first page
+func afterKey(field string, afterID *uuid.UUID, afterAmount *money.Amount, afterTime *time.Time, afterName string) *pageKey {
+	if afterID == nil {
+		return nil
+	}
+	var amount money.Amount
+	if afterAmount != nil {
+		amount = *afterAmount
+	}
+	var t time.Time
+	if afterTime != nil {
+		t = *afterTime
+	}
+	key := sortKey(field, amount, t, t, afterName, *afterID)
+	return &key
+}
+
+func (k pageKey) cmp(other pageKey) int {
+	if c := k.amount.Cmp(other.amount); c != 0 {
+		return c
+	}
+	if k.rank != other.rank {
+		if k.rank < other.rank {
+			return -1
+		}
+		return 1
+	}
+	if c := k.time.Compare(other.time); c != 0 {
+		return c
+	}
+	if c := strings.Compare(k.name, other.name); c != 0 {
+		return c
+	}
+	return strings.Compare(k.id.String(), other.id.String())
+}
+
+// page sorts records and keeps the size of them that follow after
+func page[T any](records []T, key func(T) pageKey, descending bool, after *pageKey, size int32) []T {
+	var result []T
+	for _, record := range records {
+		if after != nil {
+			c := key(record).cmp(*after)
+			if descending && c >= 0 || !descending && c <= 0 {
+				continue
+			}
+		}
+		result = append(result, record)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		c := key(result[i]).cmp(key(result[j]))
+		if descending {
+			return c > 0
+		}
+		return c < 0
+	})
+	if len(result) > int(size) {
+		result = result[:size]
+	}
+	return result
+}
+
+// inAmountRange reports whether an amount is within the optional bounds
+func inAmountRange(amount money.Amount, low, high *money.Amount) bool {
+	return (low == nil || amount.Cmp(*low) >= 0) && (high == nil || amount.Cmp(*high) <= 0)
+}
+
+// inDateRange reports whether a date is within the optional bounds
+func inDateRange(date time.Time, start, end *time.Time) bool {
+	return (start == nil || !date.Before(*start)) && (end == nil || !date.After(*end))
+}
+
+// containsFold reports whether s contains search ignoring case, as the
+// List*Page queries search
+func containsFold(s, search string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(search))
+}
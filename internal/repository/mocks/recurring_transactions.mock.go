@@ -0,0 +1,194 @@
+package mocks
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/repository"
+)
+
+// RecurringTransactionMock stores templates and generates their occurrences
+// into the expense and income mocks it was created with
+type RecurringTransactionMock struct {
+	templates   map[string]repository.RecurringTransaction
+	occurrences map[string]bool
+	expenses    *ExpenseMock
+	income      *IncomeMock
+}
+
+func NewRecurringTransactionMock(expenses *ExpenseMock, income *IncomeMock) *RecurringTransactionMock {
+	return &RecurringTransactionMock{
+		templates:   make(map[string]repository.RecurringTransaction),
+		occurrences: make(map[string]bool),
+		expenses:    expenses,
+		income:      income,
+	}
+}
+
+// Helper method for setting up test data
+func (m *RecurringTransactionMock) AddRecurringTransaction(template repository.RecurringTransaction) {
+	m.templates[template.ID.String()] = template
+}
+
+func occurrenceKey(templateID uuid.UUID, date time.Time) string {
+	return templateID.String() + date.UTC().Format(time.RFC3339Nano)
+}
+
+func (m *RecurringTransactionMock) AdvanceRecurringTransaction(ctx context.Context, arg repository.AdvanceRecurringTransactionParams) (int64, error) {
+	template, exists := m.templates[arg.ID.String()]
+	if !exists || template.DeletedAt != nil || template.NextOccurrence == nil || !template.NextOccurrence.Equal(arg.DueOccurrence) {
+		return 0, nil
+	}
+	template.NextOccurrence = arg.NextOccurrence
+	template.LastOccurrence = arg.LastOccurrence
+	m.templates[arg.ID.String()] = template
+	return 1, nil
+}
+
+func (m *RecurringTransactionMock) CreateRecurringTransaction(ctx context.Context, arg repository.CreateRecurringTransactionParams) (repository.RecurringTransaction, error) {
+	now := time.Now()
+	template := repository.RecurringTransaction{
+		ID:              arg.ID,
+		UserID:          arg.UserID,
+		Type:            arg.Type,
+		Amount:          arg.Amount,
+		Currency:        arg.Currency,
+		CategoryID:      arg.CategoryID,
+		Source:          arg.Source,
+		Description:     arg.Description,
+		Frequency:       arg.Frequency,
+		IntervalCount:   arg.IntervalCount,
+		StartDate:       arg.StartDate,
+		EndDate:         arg.EndDate,
+		OccurrenceCount: arg.OccurrenceCount,
+		NextOccurrence:  arg.NextOccurrence,
+		CreatedAt:       now,
+		UpdatedAt:       &now,
+	}
+	m.templates[template.ID.String()] = template
+	return template, nil
+}
+
+func (m *RecurringTransactionMock) DeleteRecurringTransaction(ctx context.Context, arg repository.DeleteRecurringTransactionParams) (int64, error) {
+	key := arg.ID.String()
+	template, exists := m.templates[key]
+	if !exists || template.UserID != arg.UserID || template.DeletedAt != nil {
+		return 0, nil
+	}
+	now := time.Now()
+	template.DeletedAt = &now
+	m.templates[key] = template
+	return 1, nil
+}
+
+func (m *RecurringTransactionMock) GetRecurringTransactionByID(ctx context.Context, arg repository.GetRecurringTransactionByIDParams) (repository.RecurringTransaction, error) {
+	template, exists := m.templates[arg.ID.String()]
+	if !exists || template.UserID != arg.UserID || template.DeletedAt != nil {
+		return repository.RecurringTransaction{}, ErrRecordNotFound
+	}
+	return template, nil
+}
+
+func (m *RecurringTransactionMock) ListDueRecurringTransactions(ctx context.Context, arg repository.ListDueRecurringTransactionsParams) ([]repository.RecurringTransaction, error) {
+	var result []repository.RecurringTransaction
+	for _, template := range m.templates {
+		if template.DeletedAt == nil && template.NextOccurrence != nil && !template.NextOccurrence.After(arg.Now) {
+			result = append(result, template)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].NextOccurrence.Before(*result[j].NextOccurrence)
+	})
+	if len(result) > int(arg.Limit) {
+		result = result[:arg.Limit]
+	}
+	return result, nil
+}
+
+func (m *RecurringTransactionMock) ListRecurringTransactions(ctx context.Context, userID uuid.UUID) ([]repository.RecurringTransaction, error) {
+	var result []repository.RecurringTransaction
+	for _, template := range m.templates {
+		if template.UserID == userID && template.DeletedAt == nil {
+			result = append(result, template)
+		}
+	}
+	return result, nil
+}
+
+// claim records the occurrences of a template that have not been generated
+// yet and returns them
+func (m *RecurringTransactionMock) claim(id uuid.UUID, templateType string, dates []time.Time) (repository.RecurringTransaction, []time.Time) {
+	template, exists := m.templates[id.String()]
+	if !exists || template.Type != templateType || template.DeletedAt != nil {
+		return template, nil
+	}
+	var claimed []time.Time
+	for _, date := range dates {
+		key := occurrenceKey(id, date)
+		if m.occurrences[key] {
+			continue
+		}
+		m.occurrences[key] = true
+		claimed = append(claimed, date)
+	}
+	return template, claimed
+}
+
+func (m *RecurringTransactionMock) MaterializeRecurringExpenses(ctx context.Context, arg repository.MaterializeRecurringExpensesParams) (int64, error) {
+	template, dates := m.claim(arg.ID, "expense", arg.OccurrenceDates)
+	for _, date := range dates {
+		m.expenses.AddExpense(repository.Expense{
+			ID:          uuid.New(),
+			UserID:      template.UserID,
+			Amount:      template.Amount,
+			Currency:    template.Currency,
+			CategoryID:  *template.CategoryID,
+			Date:        date,
+			Description: template.Description,
+			CreatedAt:   time.Now(),
+		})
+	}
+	return int64(len(dates)), nil
+}
+
+func (m *RecurringTransactionMock) MaterializeRecurringIncome(ctx context.Context, arg repository.MaterializeRecurringIncomeParams) (int64, error) {
+	template, dates := m.claim(arg.ID, "income", arg.OccurrenceDates)
+	for _, date := range dates {
+		m.income.AddIncome(repository.Income{
+			ID:          uuid.New(),
+			UserID:      template.UserID,
+			Amount:      template.Amount,
+			Currency:    template.Currency,
+			Source:      *template.Source,
+			Date:        date,
+			Description: template.Description,
+			CreatedAt:   time.Now(),
+		})
+	}
+	return int64(len(dates)), nil
+}
+
+func (m *RecurringTransactionMock) UpdateRecurringTransaction(ctx context.Context, arg repository.UpdateRecurringTransactionParams) (repository.RecurringTransaction, error) {
+	key := arg.ID.String()
+	template, exists := m.templates[key]
+	if !exists || template.UserID != arg.UserID || template.DeletedAt != nil {
+		return repository.RecurringTransaction{}, ErrRecordNotFound
+	}
+	now := time.Now()
+	template.Amount = arg.Amount
+	template.Currency = arg.Currency
+	template.CategoryID = arg.CategoryID
+	template.Source = arg.Source
+	template.Description = arg.Description
+	template.Frequency = arg.Frequency
+	template.IntervalCount = arg.IntervalCount
+	template.StartDate = arg.StartDate
+	template.EndDate = arg.EndDate
+	template.OccurrenceCount = arg.OccurrenceCount
+	template.NextOccurrence = arg.NextOccurrence
+	template.UpdatedAt = &now
+	m.templates[key] = template
+	return template, nil
+}
@@ -9,20 +9,42 @@ type MockRepository struct {
 	*UserMock
 	*BudgetMock
 	*CategoryMock
+	*CategoryRuleMock
+	*ExchangeRateMock
 	*ExpenseMock
+	*ExportMock
+	*ImportMock
 	*IncomeMock
+	*RecurringTransactionMock
+	*SearchMock
 	*SummaryMock
+	*TagMock
+
+	txFailures int // Transaction attempts left to fail, see FailTransactions
 }
 
 // NewMockRepository creates a new composite mock repository
 func NewMockRepository() repository.Repository {
+	expenses := NewExpenseMock()
+	income := NewIncomeMock()
+	budgets := NewBudgetMock()
+	recurring := NewRecurringTransactionMock(expenses, income)
+	rules := NewCategoryRuleMock()
+	categories := NewCategoryMock(expenses, budgets, recurring, rules)
 	return &MockRepository{
-		UserMock:     NewUserMock(),
-		BudgetMock:   NewBudgetMock(),
-		CategoryMock: NewCategoryMock(),
-		ExpenseMock:  NewExpenseMock(),
-		IncomeMock:   NewIncomeMock(),
-		SummaryMock:  NewSummaryMock(),
+		UserMock:                 NewUserMock(categories),
+		BudgetMock:               budgets,
+		CategoryMock:             categories,
+		CategoryRuleMock:         rules,
+		ExchangeRateMock:         NewExchangeRateMock(),
+		ExpenseMock:              expenses,
+		ExportMock:               NewExportMock(expenses, income),
+		ImportMock:               NewImportMock(expenses, income),
+		IncomeMock:               income,
+		RecurringTransactionMock: recurring,
+		SearchMock:               NewSearchMock(expenses, income),
+		SummaryMock:              NewSummaryMock(),
+		TagMock:                  NewTagMock(expenses, income),
 	}
 }
 
@@ -31,12 +53,20 @@ var _ repository.Repository = (*MockRepository)(nil)
 
 // Helper functions for testing
 func (m *MockRepository) Reset() {
-	m.UserMock = NewUserMock()
 	m.BudgetMock = NewBudgetMock()
-	m.CategoryMock = NewCategoryMock()
+	m.ExchangeRateMock = NewExchangeRateMock()
 	m.ExpenseMock = NewExpenseMock()
 	m.IncomeMock = NewIncomeMock()
+	m.ExportMock = NewExportMock(m.ExpenseMock, m.IncomeMock)
+	m.ImportMock = NewImportMock(m.ExpenseMock, m.IncomeMock)
+	m.RecurringTransactionMock = NewRecurringTransactionMock(m.ExpenseMock, m.IncomeMock)
+	m.CategoryRuleMock = NewCategoryRuleMock()
+	m.CategoryMock = NewCategoryMock(m.ExpenseMock, m.BudgetMock, m.RecurringTransactionMock, m.CategoryRuleMock)
+	m.UserMock = NewUserMock(m.CategoryMock)
+	m.SearchMock = NewSearchMock(m.ExpenseMock, m.IncomeMock)
 	m.SummaryMock = NewSummaryMock()
+	m.TagMock = NewTagMock(m.ExpenseMock, m.IncomeMock)
+	m.txFailures = 0
 }
 
 // GetUserMock returns the underlying UserMock for testing helpers
@@ -54,17 +84,52 @@ func (m *MockRepository) GetCategoryMock() *CategoryMock {
 	return m.CategoryMock
 }
 
+// GetCategoryRuleMock returns the underlying CategoryRuleMock for testing helpers
+func (m *MockRepository) GetCategoryRuleMock() *CategoryRuleMock {
+	return m.CategoryRuleMock
+}
+
+// GetExchangeRateMock returns the underlying ExchangeRateMock for testing helpers
+func (m *MockRepository) GetExchangeRateMock() *ExchangeRateMock {
+	return m.ExchangeRateMock
+}
+
 // GetExpenseMock returns the underlying ExpenseMock for testing helpers
 func (m *MockRepository) GetExpenseMock() *ExpenseMock {
 	return m.ExpenseMock
 }
 
+// GetExportMock returns the underlying ExportMock for testing helpers
+func (m *MockRepository) GetExportMock() *ExportMock {
+	return m.ExportMock
+}
+
+// GetImportMock returns the underlying ImportMock for testing helpers
+func (m *MockRepository) GetImportMock() *ImportMock {
+	return m.ImportMock
+}
+
 // GetIncomeMock returns the underlying IncomeMock for testing helpers
 func (m *MockRepository) GetIncomeMock() *IncomeMock {
 	return m.IncomeMock
 }
 
+// GetRecurringTransactionMock returns the underlying RecurringTransactionMock for testing helpers
+func (m *MockRepository) GetRecurringTransactionMock() *RecurringTransactionMock {
+	return m.RecurringTransactionMock
+}
+
+// GetSearchMock returns the underlying SearchMock for testing helpers
+func (m *MockRepository) GetSearchMock() *SearchMock {
+	return m.SearchMock
+}
+
 // GetSummaryMock returns the underlying SummaryMock for testing helpers
 func (m *MockRepository) GetSummaryMock() *SummaryMock {
 	return m.SummaryMock
 }
+
+// GetTagMock returns the underlying TagMock for testing helpers
+func (m *MockRepository) GetTagMock() *TagMock {
+	return m.TagMock
+}
@@ -0,0 +1,146 @@
+package mocks
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/jorge-dev/centsible/internal/repository"
+)
+
+// SearchMock searches the expense and income mocks it was created with. It
+// matches words anywhere in the text, ignoring case, rather than stemming
+// them as Postgres does, and ranks by how many times they appear.
+type SearchMock struct {
+	expenses *ExpenseMock
+	income   *IncomeMock
+}
+
+func NewSearchMock(expenses *ExpenseMock, income *IncomeMock) *SearchMock {
+	return &SearchMock{
+		expenses: expenses,
+		income:   income,
+	}
+}
+
+// searchTerms splits a search into the words a match must have and those it
+// must not, dropping quotes and "or"
+func searchTerms(query string) (include, exclude []string) {
+	for _, word := range strings.Fields(strings.ToLower(strings.ReplaceAll(query, `"`, " "))) {
+		switch {
+		case word == "or":
+		case strings.HasPrefix(word, "-"):
+			if word = strings.TrimPrefix(word, "-"); word != "" {
+				exclude = append(exclude, word)
+			}
+		default:
+			include = append(include, word)
+		}
+	}
+	return include, exclude
+}
+
+// searchRank is how many times the words appear in text, or 0 when text does
+// not match the search
+func searchRank(text string, include, exclude []string) float32 {
+	text = strings.ToLower(text)
+	for _, word := range exclude {
+		if strings.Contains(text, word) {
+			return 0
+		}
+	}
+	var rank float32
+	for _, word := range include {
+		count := strings.Count(text, word)
+		if count == 0 {
+			return 0
+		}
+		rank += float32(count)
+	}
+	return rank
+}
+
+// searchSnippet marks the words in text the way the query's headline does
+func searchSnippet(text string, include []string) string {
+	if len(include) == 0 {
+		return text
+	}
+	quoted := make([]string, len(include))
+	for i, word := range include {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+	return regexp.MustCompile("(?i)"+strings.Join(quoted, "|")).ReplaceAllString(text, "<mark>$0</mark>")
+}
+
+func (m *SearchMock) SearchTransactions(ctx context.Context, arg repository.SearchTransactionsParams) ([]repository.SearchTransactionsRow, error) {
+	include, exclude := searchTerms(arg.Query)
+	var result []repository.SearchTransactionsRow
+	if (arg.Kind == "" || arg.Kind == "income") && arg.CategoryID == nil {
+		for _, income := range m.income.incomes {
+			text := income.Source + ": " + income.Description
+			rank := searchRank(text, include, exclude)
+			if income.UserID == arg.UserID && income.DeletedAt == nil && rank > 0 &&
+				(arg.Source == "" || strings.EqualFold(income.Source, arg.Source)) &&
+				inAmountRange(income.Amount, arg.MinAmount, arg.MaxAmount) &&
+				(arg.Currency == "" || income.Currency == arg.Currency) &&
+				inDateRange(income.Date, arg.StartDate, arg.EndDate) {
+				result = append(result, repository.SearchTransactionsRow{
+					Kind:        "income",
+					ID:          income.ID,
+					Amount:      income.Amount,
+					Currency:    income.Currency,
+					Source:      income.Source,
+					Date:        income.Date,
+					Description: income.Description,
+					Rank:        rank,
+					Snippet:     searchSnippet(text, include),
+				})
+			}
+		}
+	}
+	if (arg.Kind == "" || arg.Kind == "expense") && arg.Source == "" {
+		for _, expense := range m.expenses.expenses {
+			rank := searchRank(expense.Description, include, exclude)
+			if expense.UserID == arg.UserID && expense.DeletedAt == nil && rank > 0 &&
+				(arg.CategoryID == nil || expense.CategoryID == *arg.CategoryID) &&
+				inAmountRange(expense.Amount, arg.MinAmount, arg.MaxAmount) &&
+				(arg.Currency == "" || expense.Currency == arg.Currency) &&
+				inDateRange(expense.Date, arg.StartDate, arg.EndDate) {
+				categoryID := expense.CategoryID
+				result = append(result, repository.SearchTransactionsRow{
+					Kind:        "expense",
+					ID:          expense.ID,
+					Amount:      expense.Amount,
+					Currency:    expense.Currency,
+					CategoryID:  &categoryID,
+					Date:        expense.Date,
+					Description: expense.Description,
+					Rank:        rank,
+					Snippet:     searchSnippet(expense.Description, include),
+				})
+			}
+		}
+	}
+
+	key := func(row repository.SearchTransactionsRow) pageKey {
+		switch arg.Sort {
+		case "amount", "date":
+			return sortKey(arg.Sort, row.Amount, row.Date, row.Date, "", row.ID)
+		default:
+			return pageKey{rank: row.Rank, id: row.ID}
+		}
+	}
+	var after *pageKey
+	if arg.AfterID != nil {
+		switch arg.Sort {
+		case "amount", "date":
+			after = afterKey(arg.Sort, arg.AfterID, arg.AfterAmount, arg.AfterTime, "")
+		default:
+			after = &pageKey{id: *arg.AfterID}
+			if arg.AfterRank != nil {
+				after.rank = *arg.AfterRank
+			}
+		}
+	}
+	return page(result, key, arg.Descending, after, arg.PageSize), nil
+}
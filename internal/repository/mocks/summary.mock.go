@@ -6,18 +6,21 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 	"github.com/jorge-dev/centsible/internal/repository"
 )
 
 type SummaryMock struct {
 	monthlySummaries map[string][]repository.GetMonthlySummaryRow
 	yearlySummaries  map[string][]repository.GetYearlySummaryRow
+	convertedTotals  map[string]repository.GetConvertedTotalsRow
 }
 
 func NewSummaryMock() *SummaryMock {
 	return &SummaryMock{
 		monthlySummaries: make(map[string][]repository.GetMonthlySummaryRow),
 		yearlySummaries:  make(map[string][]repository.GetYearlySummaryRow),
+		convertedTotals:  make(map[string]repository.GetConvertedTotalsRow),
 	}
 }
 
@@ -32,6 +35,12 @@ func (m *SummaryMock) AddYearlySummary(userID uuid.UUID, date time.Time, summary
 	m.yearlySummaries[key] = summary
 }
 
+// AddConvertedTotals sets the totals returned for the period starting at start
+func (m *SummaryMock) AddConvertedTotals(userID uuid.UUID, start time.Time, totals repository.GetConvertedTotalsRow) {
+	key := userID.String() + start.Format(time.DateOnly)
+	m.convertedTotals[key] = totals
+}
+
 // Implementation of summary-related Repository interface methods
 func (m *SummaryMock) GetMonthlySummary(ctx context.Context, arg repository.GetMonthlySummaryParams) ([]repository.GetMonthlySummaryRow, error) {
 	key := arg.UserID.String() + arg.Date.Format("2006-01")
@@ -50,9 +59,9 @@ func (m *SummaryMock) GetMonthlySummary(ctx context.Context, arg repository.GetM
 		return []repository.GetMonthlySummaryRow{
 			{
 				Currency:      "USD",
-				TotalIncome:   1000.00,
-				TotalExpenses: 500.00,
-				TotalSavings:  500.00,
+				TotalIncome:   money.FromInt(1000),
+				TotalExpenses: money.FromInt(500),
+				TotalSavings:  money.FromInt(500),
 				TopCategories: topCategories,
 			},
 		}, nil
@@ -85,9 +94,9 @@ func (m *SummaryMock) GetYearlySummary(ctx context.Context, arg repository.GetYe
 		return []repository.GetYearlySummaryRow{
 			{
 				Currency:      "USD",
-				TotalIncome:   12000.00,
-				TotalExpenses: 6000.00,
-				TotalSavings:  6000.00,
+				TotalIncome:   money.FromInt(12000),
+				TotalExpenses: money.FromInt(6000),
+				TotalSavings:  money.FromInt(6000),
 				TopCategories: topCategories,
 				MonthlyTrend:  monthlyTrend,
 			},
@@ -95,3 +104,22 @@ func (m *SummaryMock) GetYearlySummary(ctx context.Context, arg repository.GetYe
 	}
 	return summary, nil
 }
+
+func (m *SummaryMock) GetConvertedTotals(ctx context.Context, arg repository.GetConvertedTotalsParams) (repository.GetConvertedTotalsRow, error) {
+	key := arg.UserID.String() + arg.StartDate.Format(time.DateOnly)
+	totals, exists := m.convertedTotals[key]
+	if !exists {
+		// Match the default summaries, which are all in USD
+		totals = repository.GetConvertedTotalsRow{
+			BaseCurrency:  "USD",
+			TotalIncome:   money.FromInt(1000),
+			TotalExpenses: money.FromInt(500),
+			MissingRates:  []string{},
+		}
+		if arg.EndDate.Sub(arg.StartDate) > 31*24*time.Hour {
+			totals.TotalIncome = money.FromInt(12000)
+			totals.TotalExpenses = money.FromInt(6000)
+		}
+	}
+	return totals, nil
+}
@@ -0,0 +1,268 @@
+package mocks
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/repository"
+)
+
+// tagLinks holds the tag IDs of each expense or income record, standing in
+// for the expense_tags and income_tags tables
+type tagLinks map[uuid.UUID]map[uuid.UUID]bool
+
+// has reports whether a transaction has a tag, or true when tagID is nil
+func (l tagLinks) has(transactionID uuid.UUID, tagID *uuid.UUID) bool {
+	return tagID == nil || l[transactionID][*tagID]
+}
+
+func (l tagLinks) set(transactionID uuid.UUID, tagIDs []uuid.UUID) {
+	tags := make(map[uuid.UUID]bool, len(tagIDs))
+	for _, id := range tagIDs {
+		tags[id] = true
+	}
+	l[transactionID] = tags
+}
+
+func (l tagLinks) add(transactionID, tagID uuid.UUID) {
+	if l[transactionID] == nil {
+		l[transactionID] = make(map[uuid.UUID]bool)
+	}
+	l[transactionID][tagID] = true
+}
+
+// TagMock keeps tags and tags the records of the expense and income mocks it
+// was created with
+type TagMock struct {
+	tags     map[string]repository.Tag
+	expenses *ExpenseMock
+	income   *IncomeMock
+}
+
+func NewTagMock(expenses *ExpenseMock, income *IncomeMock) *TagMock {
+	return &TagMock{
+		tags:     make(map[string]repository.Tag),
+		expenses: expenses,
+		income:   income,
+	}
+}
+
+// Helper methods for setting up test data
+func (m *TagMock) AddTag(tag repository.Tag) {
+	m.tags[tag.ID.String()] = tag
+}
+
+func (m *TagMock) TagExpense(expenseID uuid.UUID, tagIDs ...uuid.UUID) {
+	for _, id := range tagIDs {
+		m.expenses.tags.add(expenseID, id)
+	}
+}
+
+func (m *TagMock) TagIncome(incomeID uuid.UUID, tagIDs ...uuid.UUID) {
+	for _, id := range tagIDs {
+		m.income.tags.add(incomeID, id)
+	}
+}
+
+// userTag returns a tag when it belongs to the user and is not deleted
+func (m *TagMock) userTag(id, userID uuid.UUID) (repository.Tag, bool) {
+	tag, exists := m.tags[id.String()]
+	return tag, exists && tag.UserID == userID && tag.DeletedAt == nil
+}
+
+func sortTags(tags []repository.Tag) {
+	sort.Slice(tags, func(i, j int) bool {
+		a, b := strings.ToLower(tags[i].Name), strings.ToLower(tags[j].Name)
+		if a != b {
+			return a < b
+		}
+		return tags[i].ID.String() < tags[j].ID.String()
+	})
+}
+
+func (m *TagMock) linkedTags(links tagLinks, transactionID, userID uuid.UUID) []repository.Tag {
+	var result []repository.Tag
+	for id := range links[transactionID] {
+		if tag, ok := m.userTag(id, userID); ok {
+			result = append(result, tag)
+		}
+	}
+	sortTags(result)
+	return result
+}
+
+func (m *TagMock) CheckTagExists(ctx context.Context, arg repository.CheckTagExistsParams) (bool, error) {
+	for _, tag := range m.tags {
+		if tag.UserID == arg.UserID && tag.ID != arg.ID && tag.DeletedAt == nil &&
+			strings.EqualFold(tag.Name, arg.Name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *TagMock) CountUserTags(ctx context.Context, arg repository.CountUserTagsParams) (int64, error) {
+	var count int64
+	for _, id := range arg.TagIds {
+		if _, ok := m.userTag(id, arg.UserID); ok {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *TagMock) CreateTag(ctx context.Context, arg repository.CreateTagParams) (repository.Tag, error) {
+	now := time.Now()
+	tag := repository.Tag{
+		ID:        arg.ID,
+		UserID:    arg.UserID,
+		Name:      arg.Name,
+		CreatedAt: now,
+		UpdatedAt: &now,
+	}
+	m.tags[arg.ID.String()] = tag
+	return tag, nil
+}
+
+func (m *TagMock) DeleteTag(ctx context.Context, arg repository.DeleteTagParams) (int64, error) {
+	tag, ok := m.userTag(arg.ID, arg.UserID)
+	if !ok {
+		return 0, nil
+	}
+	now := time.Now()
+	tag.DeletedAt = &now
+	m.tags[arg.ID.String()] = tag
+	return 1, nil
+}
+
+func (m *TagMock) GetTagByID(ctx context.Context, arg repository.GetTagByIDParams) (repository.Tag, error) {
+	if tag, ok := m.userTag(arg.ID, arg.UserID); ok {
+		return tag, nil
+	}
+	return repository.Tag{}, ErrRecordNotFound
+}
+
+func (m *TagMock) GetTagTotals(ctx context.Context, arg repository.GetTagTotalsParams) ([]repository.GetTagTotalsRow, error) {
+	type key struct {
+		tagID    uuid.UUID
+		currency string
+	}
+	rows := make(map[key]*repository.GetTagTotalsRow)
+	row := func(tag repository.Tag, currency string) *repository.GetTagTotalsRow {
+		k := key{tag.ID, currency}
+		if rows[k] == nil {
+			rows[k] = &repository.GetTagTotalsRow{TagID: tag.ID, TagName: tag.Name, Currency: currency}
+		}
+		return rows[k]
+	}
+	inPeriod := func(date time.Time) bool {
+		return !date.Before(arg.StartDate) && date.Before(arg.EndDate)
+	}
+
+	for _, expense := range m.expenses.expenses {
+		if expense.UserID != arg.UserID || expense.DeletedAt != nil || !inPeriod(expense.Date) {
+			continue
+		}
+		for _, tag := range m.linkedTags(m.expenses.tags, expense.ID, arg.UserID) {
+			if arg.TagID == nil || tag.ID == *arg.TagID {
+				r := row(tag, expense.Currency)
+				r.ExpenseCount++
+				r.TotalExpenses = r.TotalExpenses.Add(expense.Amount)
+			}
+		}
+	}
+	for _, income := range m.income.incomes {
+		if income.UserID != arg.UserID || income.DeletedAt != nil || !inPeriod(income.Date) {
+			continue
+		}
+		for _, tag := range m.linkedTags(m.income.tags, income.ID, arg.UserID) {
+			if arg.TagID == nil || tag.ID == *arg.TagID {
+				r := row(tag, income.Currency)
+				r.IncomeCount++
+				r.TotalIncome = r.TotalIncome.Add(income.Amount)
+			}
+		}
+	}
+
+	result := make([]repository.GetTagTotalsRow, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, *r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := strings.ToLower(result[i].TagName), strings.ToLower(result[j].TagName)
+		if a != b {
+			return a < b
+		}
+		if result[i].TagID != result[j].TagID {
+			return result[i].TagID.String() < result[j].TagID.String()
+		}
+		return result[i].Currency < result[j].Currency
+	})
+	return result, nil
+}
+
+func (m *TagMock) LinkImportedTags(ctx context.Context, arg repository.LinkImportedTagsParams) error {
+	for i, id := range arg.ExpenseIds {
+		m.expenses.tags.add(id, arg.ExpenseTagIds[i])
+	}
+	for i, id := range arg.IncomeIds {
+		m.income.tags.add(id, arg.IncomeTagIds[i])
+	}
+	return nil
+}
+
+func (m *TagMock) ListExpenseTags(ctx context.Context, arg repository.ListExpenseTagsParams) ([]repository.Tag, error) {
+	return m.linkedTags(m.expenses.tags, arg.ExpenseID, arg.UserID), nil
+}
+
+func (m *TagMock) ListIncomeTags(ctx context.Context, arg repository.ListIncomeTagsParams) ([]repository.Tag, error) {
+	return m.linkedTags(m.income.tags, arg.IncomeID, arg.UserID), nil
+}
+
+func (m *TagMock) ListTags(ctx context.Context, userID uuid.UUID) ([]repository.Tag, error) {
+	var result []repository.Tag
+	for _, tag := range m.tags {
+		if tag.UserID == userID && tag.DeletedAt == nil {
+			result = append(result, tag)
+		}
+	}
+	sortTags(result)
+	return result, nil
+}
+
+func (m *TagMock) ListTransactionTags(ctx context.Context, arg repository.ListTransactionTagsParams) ([]repository.ListTransactionTagsRow, error) {
+	var result []repository.ListTransactionTagsRow
+	for _, links := range []tagLinks{m.expenses.tags, m.income.tags} {
+		for _, transactionID := range arg.TransactionIds {
+			for _, tag := range m.linkedTags(links, transactionID, arg.UserID) {
+				result = append(result, repository.ListTransactionTagsRow{TransactionID: transactionID, TagID: tag.ID})
+			}
+		}
+	}
+	return result, nil
+}
+
+func (m *TagMock) SetExpenseTags(ctx context.Context, arg repository.SetExpenseTagsParams) error {
+	m.expenses.tags.set(arg.ExpenseID, arg.TagIds)
+	return nil
+}
+
+func (m *TagMock) SetIncomeTags(ctx context.Context, arg repository.SetIncomeTagsParams) error {
+	m.income.tags.set(arg.IncomeID, arg.TagIds)
+	return nil
+}
+
+func (m *TagMock) UpdateTag(ctx context.Context, arg repository.UpdateTagParams) (repository.Tag, error) {
+	tag, ok := m.userTag(arg.ID, arg.UserID)
+	if !ok {
+		return repository.Tag{}, ErrRecordNotFound
+	}
+	now := time.Now()
+	tag.Name = arg.Name
+	tag.UpdatedAt = &now
+	m.tags[arg.ID.String()] = tag
+	return tag, nil
+}
@@ -0,0 +1,108 @@
+package mocks
+
+import (
+	"context"
+	"maps"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jorge-dev/centsible/internal/repository"
+)
+
+// ErrSerializationFailure is what the database fails a transaction with when
+// it conflicts with another
+var ErrSerializationFailure = &pgconn.PgError{
+	Code:    "40001",
+	Message: "could not serialize access due to concurrent update",
+}
+
+// mockState is a copy of everything the mocks store, to undo a failed
+// transaction with
+type mockState struct {
+	users                map[string]repository.GetUserByIDRow
+	userRoles            map[string]repository.GetUserRoleRow
+	admins               map[string]bool
+	emails               map[string]bool
+	budgets              map[string]repository.Budget
+	categories           map[string]repository.Category
+	rules                map[string]repository.CategoryRule
+	rates                map[string]repository.ExchangeRate
+	expenses             map[string]repository.Expense
+	expenseTags          tagLinks
+	incomes              map[string]repository.Income
+	incomeTags           tagLinks
+	recurring            map[string]repository.RecurringTransaction
+	recurringOccurrences map[string]bool
+	tags                 map[string]repository.Tag
+}
+
+func (l tagLinks) clone() tagLinks {
+	cloned := make(tagLinks, len(l))
+	for id, tags := range l {
+		cloned[id] = maps.Clone(tags)
+	}
+	return cloned
+}
+
+func (m *MockRepository) snapshot() mockState {
+	return mockState{
+		users:                maps.Clone(m.UserMock.users),
+		userRoles:            maps.Clone(m.UserMock.userRoles),
+		admins:               maps.Clone(m.UserMock.admins),
+		emails:               maps.Clone(m.UserMock.emails),
+		budgets:              maps.Clone(m.BudgetMock.budgets),
+		categories:           maps.Clone(m.CategoryMock.categories),
+		rules:                maps.Clone(m.CategoryRuleMock.rules),
+		rates:                maps.Clone(m.ExchangeRateMock.rates),
+		expenses:             maps.Clone(m.ExpenseMock.expenses),
+		expenseTags:          m.ExpenseMock.tags.clone(),
+		incomes:              maps.Clone(m.IncomeMock.incomes),
+		incomeTags:           m.IncomeMock.tags.clone(),
+		recurring:            maps.Clone(m.RecurringTransactionMock.templates),
+		recurringOccurrences: maps.Clone(m.RecurringTransactionMock.occurrences),
+		tags:                 maps.Clone(m.TagMock.tags),
+	}
+}
+
+// restore puts back what snapshot copied. The maps are replaced on the mocks
+// themselves, so mocks that share another's records see them restored too.
+func (m *MockRepository) restore(state mockState) {
+	m.UserMock.users = state.users
+	m.UserMock.userRoles = state.userRoles
+	m.UserMock.admins = state.admins
+	m.UserMock.emails = state.emails
+	m.BudgetMock.budgets = state.budgets
+	m.CategoryMock.categories = state.categories
+	m.CategoryRuleMock.rules = state.rules
+	m.ExchangeRateMock.rates = state.rates
+	m.ExpenseMock.expenses = state.expenses
+	m.ExpenseMock.tags = state.expenseTags
+	m.IncomeMock.incomes = state.incomes
+	m.IncomeMock.tags = state.incomeTags
+	m.RecurringTransactionMock.templates = state.recurring
+	m.RecurringTransactionMock.occurrences = state.recurringOccurrences
+	m.TagMock.tags = state.tags
+}
+
+// FailTransactions makes the next n transaction attempts fail with
+// ErrSerializationFailure before fn runs, as a busy database would
+func (m *MockRepository) FailTransactions(n int) {
+	m.txFailures = n
+}
+
+// InTx runs fn against the mock as the database would in a transaction:
+// every write fn made is undone when it fails, and attempts failed with a
+// serialization failure are retried.
+func (m *MockRepository) InTx(ctx context.Context, fn func(repository.Repository) error) error {
+	return repository.RetryTx(ctx, func() error {
+		if m.txFailures > 0 {
+			m.txFailures--
+			return ErrSerializationFailure
+		}
+		state := m.snapshot()
+		if err := fn(m); err != nil {
+			m.restore(state)
+			return err
+		}
+		return nil
+	})
+}
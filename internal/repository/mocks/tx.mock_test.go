@@ -0,0 +1,66 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInTx(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	m := NewMockRepository().(*MockRepository)
+
+	// create writes a category, a tagged expense in it and a tag, then fails
+	// with err
+	create := func(err error) func(repository.Repository) error {
+		return func(tx repository.Repository) error {
+			category, _ := tx.CreateCategory(ctx, repository.CreateCategoryParams{ID: uuid.New(), UserID: userID, Name: "Food"})
+			tag, _ := tx.CreateTag(ctx, repository.CreateTagParams{ID: uuid.New(), UserID: userID, Name: "Work"})
+			_, _ = tx.CreateExpense(ctx, repository.CreateExpenseParams{
+				ID:          uuid.New(),
+				UserID:      userID,
+				Amount:      money.MustParse("9.99"),
+				Currency:    "CAD",
+				CategoryID:  category.ID,
+				Date:        time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC),
+				Description: "Lunch",
+				TagIds:      []uuid.UUID{tag.ID},
+			})
+			return err
+		}
+	}
+	counts := func() (categories, tags, expenses int) {
+		c, _ := m.ListCategories(ctx, userID)
+		g, _ := m.ListTags(ctx, userID)
+		e, _ := m.ListExpenses(ctx, userID)
+		return len(c), len(g), len(e)
+	}
+
+	t.Run("Failure undoes every write", func(t *testing.T) {
+		boom := errors.New("boom")
+		assert.ErrorIs(t, m.InTx(ctx, create(boom)), boom)
+		categories, tags, expenses := counts()
+		assert.Zero(t, categories+tags+expenses)
+	})
+
+	t.Run("Serialization failures are retried", func(t *testing.T) {
+		m.FailTransactions(repository.MaxTxAttempts - 1)
+		assert.NoError(t, m.InTx(ctx, create(nil)))
+		categories, tags, expenses := counts()
+		assert.Equal(t, []int{1, 1, 1}, []int{categories, tags, expenses})
+	})
+
+	t.Run("Too many serialization failures save nothing", func(t *testing.T) {
+		m.FailTransactions(repository.MaxTxAttempts)
+		assert.ErrorIs(t, m.InTx(ctx, create(nil)), ErrSerializationFailure)
+		categories, _, _ := counts()
+		assert.Equal(t, 1, categories)
+	})
+}
@@ -14,14 +14,17 @@ type UserMock struct {
 	userRoles map[string]repository.GetUserRoleRow
 	admins    map[string]bool
 	emails    map[string]bool // Add map to track existing emails
+
+	categories *CategoryMock // Given the template categories of registered users
 }
 
-func NewUserMock() *UserMock {
+func NewUserMock(categories *CategoryMock) *UserMock {
 	return &UserMock{
-		users:     make(map[string]repository.GetUserByIDRow),
-		userRoles: make(map[string]repository.GetUserRoleRow),
-		admins:    make(map[string]bool),
-		emails:    make(map[string]bool),
+		users:      make(map[string]repository.GetUserByIDRow),
+		userRoles:  make(map[string]repository.GetUserRoleRow),
+		admins:     make(map[string]bool),
+		emails:     make(map[string]bool),
+		categories: categories,
 	}
 }
 
@@ -69,11 +72,12 @@ func (m *UserMock) GetUserByEmail(ctx context.Context, email string) (repository
 func (m *UserMock) UpdateUser(ctx context.Context, arg repository.UpdateUserParams) (repository.User, error) {
 	now := time.Now()
 	return repository.User{
-		ID:        arg.ID,
-		Name:      arg.Name,
-		Email:     arg.Email,
-		CreatedAt: now,
-		UpdatedAt: &now,
+		ID:           arg.ID,
+		Name:         arg.Name,
+		Email:        arg.Email,
+		BaseCurrency: arg.BaseCurrency,
+		CreatedAt:    now,
+		UpdatedAt:    &now,
 	}, nil
 }
 
@@ -161,6 +165,25 @@ func (m *UserMock) CreateUser(ctx context.Context, arg repository.CreateUserPara
 	return user, nil
 }
 
+func (m *UserMock) RegisterUser(ctx context.Context, arg repository.RegisterUserParams) (repository.User, error) {
+	user, err := m.CreateUser(ctx, repository.CreateUserParams{
+		ID:           arg.ID,
+		Name:         arg.Name,
+		Email:        arg.Email,
+		PasswordHash: arg.PasswordHash,
+	})
+	if err != nil {
+		return repository.User{}, err
+	}
+	if arg.TemplateKey != "" {
+		m.categories.ApplyCategoryTemplate(ctx, repository.ApplyCategoryTemplateParams{
+			UserID:      arg.ID,
+			TemplateKey: arg.TemplateKey,
+		})
+	}
+	return user, nil
+}
+
 func (m *UserMock) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
 	_, exists := m.users[id.String()]
 	if !exists {
@@ -9,21 +9,25 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/recurrence"
 )
 
 type Budget struct {
-	ID         uuid.UUID  `json:"id"`
-	UserID     uuid.UUID  `json:"user_id"`
-	Amount     float64    `json:"amount"`
-	Currency   string     `json:"currency"`
-	CategoryID uuid.UUID  `json:"category_id"`
-	Type       string     `json:"type"`
-	StartDate  time.Time  `json:"start_date"`
-	EndDate    time.Time  `json:"end_date"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  *time.Time `json:"updated_at"`
-	DeletedAt  *time.Time `json:"deleted_at"`
-	Name       string     `json:"name"`
+	ID         uuid.UUID           `json:"id"`
+	UserID     uuid.UUID           `json:"user_id"`
+	Amount     money.Amount        `json:"amount"`
+	Currency   string              `json:"currency"`
+	CategoryID uuid.UUID           `json:"category_id"`
+	Type       string              `json:"type"`
+	StartDate  time.Time           `json:"start_date"`
+	EndDate    *time.Time          `json:"end_date"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  *time.Time          `json:"updated_at"`
+	DeletedAt  *time.Time          `json:"deleted_at"`
+	Name       string              `json:"name"`
+	Cadence    *recurrence.Cadence `json:"cadence"`
+	Rollover   bool                `json:"rollover"`
 }
 
 type Category struct {
@@ -33,32 +37,145 @@ type Category struct {
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt *time.Time `json:"updated_at"`
 	DeletedAt *time.Time `json:"deleted_at"`
+	ParentID  *uuid.UUID `json:"parent_id"`
+}
+
+type CategoryRule struct {
+	ID                 uuid.UUID     `json:"id"`
+	UserID             uuid.UUID     `json:"user_id"`
+	Name               string        `json:"name"`
+	DescriptionPattern *string       `json:"description_pattern"`
+	MinAmount          *money.Amount `json:"min_amount"`
+	MaxAmount          *money.Amount `json:"max_amount"`
+	Currency           *string       `json:"currency"`
+	CategoryID         *uuid.UUID    `json:"category_id"`
+	TagIds             []uuid.UUID   `json:"tag_ids"`
+	Priority           int32         `json:"priority"`
+	Enabled            bool          `json:"enabled"`
+	CreatedAt          time.Time     `json:"created_at"`
+	UpdatedAt          *time.Time    `json:"updated_at"`
+	DeletedAt          *time.Time    `json:"deleted_at"`
+}
+
+type CategoryTemplate struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Locale      string `json:"locale"`
+	Description string `json:"description"`
+	IsDefault   bool   `json:"is_default"`
+}
+
+type CategoryTemplateEntry struct {
+	TemplateKey string  `json:"template_key"`
+	Name        string  `json:"name"`
+	ParentName  *string `json:"parent_name"`
+	Position    int32   `json:"position"`
+}
+
+type ExchangeRate struct {
+	BaseCurrency  string     `json:"base_currency"`
+	QuoteCurrency string     `json:"quote_currency"`
+	Date          time.Time  `json:"date"`
+	Rate          money.Rate `json:"rate"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     *time.Time `json:"updated_at"`
 }
 
 type Expense struct {
-	ID          uuid.UUID  `json:"id"`
-	UserID      uuid.UUID  `json:"user_id"`
-	Amount      float64    `json:"amount"`
-	Currency    string     `json:"currency"`
-	CategoryID  uuid.UUID  `json:"category_id"`
-	Date        time.Time  `json:"date"`
-	Description string     `json:"description"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   *time.Time `json:"updated_at"`
-	DeletedAt   *time.Time `json:"deleted_at"`
+	ID          uuid.UUID    `json:"id"`
+	UserID      uuid.UUID    `json:"user_id"`
+	Amount      money.Amount `json:"amount"`
+	Currency    string       `json:"currency"`
+	CategoryID  uuid.UUID    `json:"category_id"`
+	Date        time.Time    `json:"date"`
+	Description string       `json:"description"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   *time.Time   `json:"updated_at"`
+	DeletedAt   *time.Time   `json:"deleted_at"`
+	ExternalID  *string      `json:"external_id"`
+}
+
+type ExpenseTag struct {
+	ExpenseID uuid.UUID `json:"expense_id"`
+	TagID     uuid.UUID `json:"tag_id"`
 }
 
 type Income struct {
-	ID          uuid.UUID  `json:"id"`
-	UserID      uuid.UUID  `json:"user_id"`
-	Amount      float64    `json:"amount"`
-	Currency    string     `json:"currency"`
-	Source      string     `json:"source"`
-	Date        time.Time  `json:"date"`
-	Description string     `json:"description"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   *time.Time `json:"updated_at"`
-	DeletedAt   *time.Time `json:"deleted_at"`
+	ID          uuid.UUID    `json:"id"`
+	UserID      uuid.UUID    `json:"user_id"`
+	Amount      money.Amount `json:"amount"`
+	Currency    string       `json:"currency"`
+	Source      string       `json:"source"`
+	Date        time.Time    `json:"date"`
+	Description string       `json:"description"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   *time.Time   `json:"updated_at"`
+	DeletedAt   *time.Time   `json:"deleted_at"`
+	ExternalID  *string      `json:"external_id"`
+}
+
+type IncomeTag struct {
+	IncomeID uuid.UUID `json:"income_id"`
+	TagID    uuid.UUID `json:"tag_id"`
+}
+
+type MonthlyAggregate struct {
+	UserID     uuid.UUID    `json:"user_id"`
+	Month      time.Time    `json:"month"`
+	Currency   string       `json:"currency"`
+	Kind       string       `json:"kind"`
+	CategoryID *uuid.UUID   `json:"category_id"`
+	Total      money.Amount `json:"total"`
+	Count      int32        `json:"count"`
+}
+
+type RecurringTransaction struct {
+	ID              uuid.UUID            `json:"id"`
+	UserID          uuid.UUID            `json:"user_id"`
+	Type            string               `json:"type"`
+	Amount          money.Amount         `json:"amount"`
+	Currency        string               `json:"currency"`
+	CategoryID      *uuid.UUID           `json:"category_id"`
+	Source          *string              `json:"source"`
+	Description     string               `json:"description"`
+	Frequency       recurrence.Frequency `json:"frequency"`
+	IntervalCount   int32                `json:"interval_count"`
+	StartDate       time.Time            `json:"start_date"`
+	EndDate         *time.Time           `json:"end_date"`
+	OccurrenceCount *int32               `json:"occurrence_count"`
+	NextOccurrence  *time.Time           `json:"next_occurrence"`
+	LastOccurrence  *time.Time           `json:"last_occurrence"`
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       *time.Time           `json:"updated_at"`
+	DeletedAt       *time.Time           `json:"deleted_at"`
+}
+
+type RecurringTransactionOccurrence struct {
+	RecurringTransactionID uuid.UUID `json:"recurring_transaction_id"`
+	OccurrenceDate         time.Time `json:"occurrence_date"`
+	TransactionID          uuid.UUID `json:"transaction_id"`
+	CreatedAt              time.Time `json:"created_at"`
+}
+
+type RefreshToken struct {
+	ID        uuid.UUID  `json:"id"`
+	FamilyID  uuid.UUID  `json:"family_id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type RevokedToken struct {
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RevokedTokenFamily struct {
+	FamilyID  uuid.UUID `json:"family_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Role struct {
@@ -68,6 +185,15 @@ type Role struct {
 	CreatedAt   time.Time   `json:"created_at"`
 }
 
+type Tag struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
 type User struct {
 	ID           uuid.UUID  `json:"id"`
 	Name         string     `json:"name"`
@@ -77,4 +203,14 @@ type User struct {
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    *time.Time `json:"updated_at"`
 	DeletedAt    *time.Time `json:"deleted_at"`
+	BaseCurrency string     `json:"base_currency"`
+}
+
+type UserSession struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	UserAgent string    `json:"user_agent"`
+	IpAddress string    `json:"ip_address"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
 }
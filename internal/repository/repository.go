@@ -9,10 +9,17 @@ import (
 // Repository defines all database operations
 type Repository interface {
 
+	// InTx runs fn in a transaction, handing it the Repository to run its
+	// operations on. Everything fn does is kept when it returns nil and undone
+	// when it fails, and fn is run again when the transaction conflicts with
+	// another.
+	InTx(ctx context.Context, fn func(Repository) error) error
+
 	// User operations
 	CheckEmailExists(ctx context.Context, email string) (bool, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) (int64, error)
+	RegisterUser(ctx context.Context, arg RegisterUserParams) (User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (GetUserByIDRow, error)
 	GetUserByEmail(ctx context.Context, email string) (GetUserByEmailRow, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
@@ -28,39 +35,73 @@ type Repository interface {
 	DeleteBudget(ctx context.Context, arg DeleteBudgetParams) (int64, error)
 	GetActiveBudgets(ctx context.Context, userID uuid.UUID) ([]Budget, error)
 	GetBudgetByID(ctx context.Context, arg GetBudgetByIDParams) (Budget, error)
+	GetBudgetSpendingByPeriod(ctx context.Context, arg GetBudgetSpendingByPeriodParams) ([]GetBudgetSpendingByPeriodRow, error)
 	GetBudgetUsage(ctx context.Context, arg GetBudgetUsageParams) (GetBudgetUsageRow, error)
 	GetBudgetsByCategory(ctx context.Context, arg GetBudgetsByCategoryParams) ([]Budget, error)
-	GetBudgetsNearLimit(ctx context.Context, arg GetBudgetsNearLimitParams) ([]GetBudgetsNearLimitRow, error)
 	GetOneTimeBudgets(ctx context.Context, userID uuid.UUID) ([]Budget, error)
 	GetRecurringBudgets(ctx context.Context, userID uuid.UUID) ([]Budget, error)
 	ListBudgets(ctx context.Context, userID uuid.UUID) ([]Budget, error)
+	ListBudgetsPage(ctx context.Context, arg ListBudgetsPageParams) ([]Budget, error)
 	UpdateBudget(ctx context.Context, arg UpdateBudgetParams) (Budget, error)
 
 	// Category operations
+	ApplyCategoryTemplate(ctx context.Context, arg ApplyCategoryTemplateParams) ([]Category, error)
 	CheckCategoryExists(ctx context.Context, arg CheckCategoryExistsParams) (bool, error)
 	CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error)
 	DeleteCategory(ctx context.Context, arg DeleteCategoryParams) (int64, error)
 	GetCategoryByID(ctx context.Context, arg GetCategoryByIDParams) (Category, error)
 	GetCategoryUsage(ctx context.Context, arg GetCategoryUsageParams) (GetCategoryUsageRow, error)
 	GetMostUsedCategories(ctx context.Context, arg GetMostUsedCategoriesParams) ([]GetMostUsedCategoriesRow, error)
+	IsCategoryInSubtree(ctx context.Context, arg IsCategoryInSubtreeParams) (bool, error)
+	IsCategoryInUse(ctx context.Context, arg IsCategoryInUseParams) (bool, error)
 	ListCategories(ctx context.Context, userID uuid.UUID) ([]Category, error)
+	ListCategoriesPage(ctx context.Context, arg ListCategoriesPageParams) ([]Category, error)
+	ListCategoryTemplates(ctx context.Context) ([]ListCategoryTemplatesRow, error)
+	MergeCategory(ctx context.Context, arg MergeCategoryParams) (int64, error)
 	UpdateCategory(ctx context.Context, arg UpdateCategoryParams) (Category, error)
 
+	// Category rule operations
+	CreateCategoryRule(ctx context.Context, arg CreateCategoryRuleParams) (CategoryRule, error)
+	DeleteCategoryRule(ctx context.Context, arg DeleteCategoryRuleParams) (int64, error)
+	GetCategoryRuleByID(ctx context.Context, arg GetCategoryRuleByIDParams) (CategoryRule, error)
+	GetCategoryWordCounts(ctx context.Context, arg GetCategoryWordCountsParams) ([]GetCategoryWordCountsRow, error)
+	ListCategoryRules(ctx context.Context, userID uuid.UUID) ([]CategoryRule, error)
+	UpdateCategoryRule(ctx context.Context, arg UpdateCategoryRuleParams) (CategoryRule, error)
+
+	// Exchange rate operations
+	DeleteExchangeRate(ctx context.Context, arg DeleteExchangeRateParams) (int64, error)
+	ListExchangeRates(ctx context.Context, arg ListExchangeRatesParams) ([]ExchangeRate, error)
+	UpsertExchangeRate(ctx context.Context, arg UpsertExchangeRateParams) (ExchangeRate, error)
+	UpsertExchangeRates(ctx context.Context, arg UpsertExchangeRatesParams) (int64, error)
+
 	// Expense operations
 	CreateExpense(ctx context.Context, arg CreateExpenseParams) (Expense, error)
 	DeleteExpense(ctx context.Context, arg DeleteExpenseParams) (int64, error)
+	FindDuplicateExpenses(ctx context.Context, arg FindDuplicateExpensesParams) ([]Expense, error)
 	GetExpenseByID(ctx context.Context, arg GetExpenseByIDParams) (Expense, error)
 	GetExpenseTotalsByCategory(ctx context.Context, userID uuid.UUID) ([]GetExpenseTotalsByCategoryRow, error)
 	GetExpensesByCategory(ctx context.Context, arg GetExpensesByCategoryParams) ([]Expense, error)
 	GetExpensesByDateRange(ctx context.Context, arg GetExpensesByDateRangeParams) ([]Expense, error)
 	GetMonthlyExpenseTotal(ctx context.Context, arg GetMonthlyExpenseTotalParams) ([]GetMonthlyExpenseTotalRow, error)
 	GetRecentExpenses(ctx context.Context, arg GetRecentExpensesParams) ([]Expense, error)
+	ListDuplicateExpenses(ctx context.Context, userID uuid.UUID) ([]ListDuplicateExpensesRow, error)
 	ListExpenses(ctx context.Context, userID uuid.UUID) ([]Expense, error)
+	ListExpensesPage(ctx context.Context, arg ListExpensesPageParams) ([]Expense, error)
+	MatchImportedExpenses(ctx context.Context, arg MatchImportedExpensesParams) ([]MatchImportedExpensesRow, error)
 	UpdateExpense(ctx context.Context, arg UpdateExpenseParams) (Expense, error)
 
+	// Export operations
+	ExportExpenses(ctx context.Context, arg ExportExpensesParams) ([]Expense, error)
+	ExportIncome(ctx context.Context, arg ExportIncomeParams) ([]Income, error)
+	ListStatementCurrencies(ctx context.Context, userID uuid.UUID) ([]ListStatementCurrenciesRow, error)
+
+	// Import operations
+	ImportTransactions(ctx context.Context, arg ImportTransactionsParams) (ImportTransactionsRow, error)
+
 	// Income operations
 	CreateIncome(ctx context.Context, arg CreateIncomeParams) (Income, error)
 	DeleteIncome(ctx context.Context, arg DeleteIncomeParams) (int64, error)
+	FindDuplicateIncome(ctx context.Context, arg FindDuplicateIncomeParams) ([]Income, error)
 	GetIncomeByDateRange(ctx context.Context, arg GetIncomeByDateRangeParams) ([]Income, error)
 	GetIncomeByID(ctx context.Context, arg GetIncomeByIDParams) (Income, error)
 	GetIncomeBySource(ctx context.Context, arg GetIncomeBySourceParams) ([]Income, error)
@@ -68,11 +109,44 @@ type Repository interface {
 	GetMonthlyIncomeTotal(ctx context.Context, arg GetMonthlyIncomeTotalParams) (GetMonthlyIncomeTotalRow, error)
 	GetRecentIncome(ctx context.Context, arg GetRecentIncomeParams) ([]Income, error)
 	ListIncome(ctx context.Context, userID uuid.UUID) ([]Income, error)
+	ListIncomePage(ctx context.Context, arg ListIncomePageParams) ([]Income, error)
+	MatchImportedIncome(ctx context.Context, arg MatchImportedIncomeParams) ([]MatchImportedIncomeRow, error)
 	UpdateIncome(ctx context.Context, arg UpdateIncomeParams) (Income, error)
 
+	// Recurring transaction operations
+	AdvanceRecurringTransaction(ctx context.Context, arg AdvanceRecurringTransactionParams) (int64, error)
+	CreateRecurringTransaction(ctx context.Context, arg CreateRecurringTransactionParams) (RecurringTransaction, error)
+	DeleteRecurringTransaction(ctx context.Context, arg DeleteRecurringTransactionParams) (int64, error)
+	GetRecurringTransactionByID(ctx context.Context, arg GetRecurringTransactionByIDParams) (RecurringTransaction, error)
+	ListDueRecurringTransactions(ctx context.Context, arg ListDueRecurringTransactionsParams) ([]RecurringTransaction, error)
+	ListRecurringTransactions(ctx context.Context, userID uuid.UUID) ([]RecurringTransaction, error)
+	MaterializeRecurringExpenses(ctx context.Context, arg MaterializeRecurringExpensesParams) (int64, error)
+	MaterializeRecurringIncome(ctx context.Context, arg MaterializeRecurringIncomeParams) (int64, error)
+	UpdateRecurringTransaction(ctx context.Context, arg UpdateRecurringTransactionParams) (RecurringTransaction, error)
+
+	// Search operations
+	SearchTransactions(ctx context.Context, arg SearchTransactionsParams) ([]SearchTransactionsRow, error)
+
 	// Summary operations
+	GetConvertedTotals(ctx context.Context, arg GetConvertedTotalsParams) (GetConvertedTotalsRow, error)
 	GetMonthlySummary(ctx context.Context, arg GetMonthlySummaryParams) ([]GetMonthlySummaryRow, error)
 	GetYearlySummary(ctx context.Context, arg GetYearlySummaryParams) ([]GetYearlySummaryRow, error)
+
+	// Tag operations
+	CheckTagExists(ctx context.Context, arg CheckTagExistsParams) (bool, error)
+	CountUserTags(ctx context.Context, arg CountUserTagsParams) (int64, error)
+	CreateTag(ctx context.Context, arg CreateTagParams) (Tag, error)
+	DeleteTag(ctx context.Context, arg DeleteTagParams) (int64, error)
+	GetTagByID(ctx context.Context, arg GetTagByIDParams) (Tag, error)
+	GetTagTotals(ctx context.Context, arg GetTagTotalsParams) ([]GetTagTotalsRow, error)
+	LinkImportedTags(ctx context.Context, arg LinkImportedTagsParams) error
+	ListExpenseTags(ctx context.Context, arg ListExpenseTagsParams) ([]Tag, error)
+	ListIncomeTags(ctx context.Context, arg ListIncomeTagsParams) ([]Tag, error)
+	ListTags(ctx context.Context, userID uuid.UUID) ([]Tag, error)
+	ListTransactionTags(ctx context.Context, arg ListTransactionTagsParams) ([]ListTransactionTagsRow, error)
+	SetExpenseTags(ctx context.Context, arg SetExpenseTagsParams) error
+	SetIncomeTags(ctx context.Context, arg SetIncomeTagsParams) error
+	UpdateTag(ctx context.Context, arg UpdateTagParams) (Tag, error)
 }
 
 // Ensure Queries implements Repository
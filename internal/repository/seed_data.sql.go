@@ -27,24 +27,32 @@ const seedBudgets = `-- name: SeedBudgets :exec
 WITH users AS (
     SELECT id, email FROM users WHERE email IN ('john.doe@example.com', 'jane.smith@example.com', 'bob.wilson@example.com')
 )
-INSERT INTO budgets (id, user_id, category_id, amount, currency, type, start_date, end_date, name, created_at, updated_at)
+INSERT INTO budgets (id, user_id, category_id, amount, currency, type, cadence, start_date, end_date, name, created_at, updated_at)
 SELECT
     uuid_generate_v4(),
-    u.id,
-    c.id,
+    b.user_id,
+    b.category_id,
     (random() * 1000 + 200)::numeric(10,2),
     'USD',
-    CASE (random() * 1)::int
-        WHEN 0 THEN 'recurring'
-        ELSE 'one-time'
-    END,
+    b.type,
+    CASE WHEN b.type = 'recurring' THEN 'monthly' END, -- Recurring budgets repeat every month
     date_trunc('month', CURRENT_DATE),
-    date_trunc('month', CURRENT_DATE) + interval '1 month' - interval '1 day',
-    c.name || ' Budget', -- Add budget name based on category
+    CASE WHEN b.type = 'one-time' THEN date_trunc('month', CURRENT_DATE) + interval '1 month' - interval '1 day' END,
+    b.category_name || ' Budget', -- Add budget name based on category
     CURRENT_TIMESTAMP,
     CURRENT_TIMESTAMP
-FROM users u
-JOIN categories c ON c.user_id = u.id
+FROM (
+    SELECT
+        u.id AS user_id,
+        c.id AS category_id,
+        c.name AS category_name,
+        CASE (random() * 1)::int
+            WHEN 0 THEN 'recurring'
+            ELSE 'one-time'
+        END AS type
+    FROM users u
+    JOIN categories c ON c.user_id = u.id
+) b
 `
 
 func (q *Queries) SeedBudgets(ctx context.Context) error {
@@ -0,0 +1,20 @@
+package repository
+
+import "time"
+
+// SummaryMonth returns the bounds [start, end) of the UTC month date falls
+// in. GetMonthlySummary buckets transactions by UTC month, so totals for the
+// same summary, such as GetConvertedTotals, have to cover this window too.
+func SummaryMonth(date time.Time) (start, end time.Time) {
+	date = date.UTC()
+	start = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// SummaryYear returns the bounds [start, end) of the UTC year date falls in,
+// the one GetYearlySummary covers
+func SummaryYear(date time.Time) (start, end time.Time) {
+	date = date.UTC()
+	start = time.Date(date.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(1, 0, 0)
+}
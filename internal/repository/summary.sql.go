@@ -10,61 +10,116 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 )
 
+const getConvertedTotals = `-- name: GetConvertedTotals :one
+WITH transactions AS (
+    SELECT 'income' AS kind, amount, currency, date
+    FROM income
+    WHERE user_id = $1
+        AND deleted_at IS NULL
+        AND date >= $2::TIMESTAMPTZ
+        AND date < $3::TIMESTAMPTZ
+    UNION ALL
+    SELECT 'expense' AS kind, amount, currency, date
+    FROM expenses
+    WHERE user_id = $1
+        AND deleted_at IS NULL
+        AND date >= $2::TIMESTAMPTZ
+        AND date < $3::TIMESTAMPTZ
+)
+SELECT
+    u.base_currency,
+    COALESCE(SUM(t.amount * r.rate) FILTER (WHERE t.kind = 'income'), 0)::numeric AS total_income,
+    COALESCE(SUM(t.amount * r.rate) FILTER (WHERE t.kind = 'expense'), 0)::numeric AS total_expenses,
+    COALESCE(ARRAY_AGG(DISTINCT t.currency) FILTER (WHERE t.amount IS NOT NULL AND r.rate IS NULL), '{}')::varchar[] AS missing_rates
+FROM users u
+LEFT JOIN transactions t ON TRUE
+LEFT JOIN LATERAL (SELECT exchange_rate(t.currency, u.base_currency, t.date::DATE) AS rate) r ON TRUE
+WHERE u.id = $1
+GROUP BY u.base_currency
+`
+
+type GetConvertedTotalsParams struct {
+	UserID    uuid.UUID `json:"user_id"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+}
+
+type GetConvertedTotalsRow struct {
+	BaseCurrency  string       `json:"base_currency"`
+	TotalIncome   money.Amount `json:"total_income"`
+	TotalExpenses money.Amount `json:"total_expenses"`
+	MissingRates  []string     `json:"missing_rates"`
+}
+
+// Income and expense totals dated in [start_date, end_date), converted into the
+// user's base currency at the rate of each transaction's day. Currencies without
+// a known rate are left out of the totals and listed in missing_rates.
+func (q *Queries) GetConvertedTotals(ctx context.Context, arg GetConvertedTotalsParams) (GetConvertedTotalsRow, error) {
+	row := q.db.QueryRow(ctx, getConvertedTotals, arg.UserID, arg.StartDate, arg.EndDate)
+	var i GetConvertedTotalsRow
+	err := row.Scan(
+		&i.BaseCurrency,
+		&i.TotalIncome,
+		&i.TotalExpenses,
+		&i.MissingRates,
+	)
+	return i, err
+}
+
 const getMonthlySummary = `-- name: GetMonthlySummary :many
-WITH monthly_totals AS (
-    SELECT 
-        i.currency::varchar(3) AS currency,
-        COALESCE(SUM(i.amount), 0)::float8 as total_income,
-        COALESCE(SUM(e.amount), 0)::float8 as total_expenses,
-        COALESCE(SUM(i.amount) - SUM(e.amount), 0)::float8 as total_savings
-    FROM income i
-    FULL OUTER JOIN expenses e ON 
-        e.user_id = i.user_id 
-        AND e.currency = i.currency::varchar(3)
-        AND DATE_TRUNC('month', e.date) = DATE_TRUNC('month', i.date)
-        AND e.deleted_at IS NULL
-    WHERE i.user_id = $1
-        AND i.deleted_at IS NULL
-        AND DATE_TRUNC('month', i.date) = DATE_TRUNC('month', $2::TIMESTAMPTZ)
-    GROUP BY i.currency::varchar(3)
+WITH totals AS (
+    SELECT
+        a.currency,
+        COALESCE(SUM(a.total) FILTER (WHERE a.kind = 'income'), 0)::numeric AS total_income,
+        COALESCE(SUM(a.total) FILTER (WHERE a.kind = 'expense'), 0)::numeric AS total_expenses
+    FROM monthly_aggregates a
+    WHERE a.user_id = $1
+        AND a.month = utc_month($2::TIMESTAMPTZ)
+    GROUP BY a.currency
 ),
 top_categories AS (
-    SELECT 
-        e.category_id,
-        c.name as category_name,
-        e.currency::varchar(3),
-        COUNT(*) as usage_count,
-        SUM(e.amount) as total_spent,
-        ROW_NUMBER() OVER (PARTITION BY e.currency::varchar(3) ORDER BY SUM(e.amount) DESC) as rank
-    FROM expenses e
-    JOIN categories c ON e.category_id = c.id
-    WHERE e.user_id = $1
-        AND e.deleted_at IS NULL
+    SELECT
+        a.currency,
+        c.id AS category_id,
+        c.name AS category_name,
+        SUM(a.count) AS usage_count,
+        SUM(a.total) AS total_spent,
+        ROW_NUMBER() OVER (PARTITION BY a.currency ORDER BY SUM(a.total) DESC) AS rank
+    FROM monthly_aggregates a
+    JOIN category_ancestors($1) ca ON ca.category_id = a.category_id AND ca.is_root
+    JOIN categories c ON c.id = ca.ancestor_id
+    WHERE a.user_id = $1
+        AND a.kind = 'expense'
+        AND a.month = utc_month($2::TIMESTAMPTZ)
         AND c.deleted_at IS NULL
-        AND DATE_TRUNC('month', e.date) = DATE_TRUNC('month', $2::TIMESTAMPTZ)
-    GROUP BY e.category_id, c.name, e.currency::varchar(3)
+    GROUP BY a.currency, c.id, c.name
 )
-SELECT 
-    mt.currency, mt.total_income, mt.total_expenses, mt.total_savings,
-    json_agg(
-        json_build_object(
-            'category_id', tc.category_id,
-            'category_name', tc.category_name,
-            'usage_count', tc.usage_count,
-            'total_spent', tc.total_spent
-        )
-    ) FILTER (WHERE tc.category_id IS NOT NULL) as top_categories
-FROM monthly_totals mt
-LEFT JOIN top_categories tc ON 
-    tc.currency = mt.currency::varchar(3)
-    AND tc.rank <= 5
-GROUP BY 
-    mt.currency::varchar(3), 
-    mt.total_income, 
-    mt.total_expenses, 
-    mt.total_savings
+SELECT
+    t.currency::varchar(3) AS currency,
+    t.total_income,
+    t.total_expenses,
+    (t.total_income - t.total_expenses)::numeric AS total_savings,
+    COALESCE(
+        (
+            SELECT json_agg(
+                json_build_object(
+                    'category_id', tc.category_id,
+                    'category_name', tc.category_name,
+                    'usage_count', tc.usage_count,
+                    'total_spent', tc.total_spent
+                ) ORDER BY tc.rank
+            )
+            FROM top_categories tc
+            WHERE tc.currency = t.currency
+                AND tc.rank <= 5
+        ),
+        '[]'
+    )::json AS top_categories
+FROM totals t
+ORDER BY t.currency
 `
 
 type GetMonthlySummaryParams struct {
@@ -73,13 +128,17 @@ type GetMonthlySummaryParams struct {
 }
 
 type GetMonthlySummaryRow struct {
-	Currency      string  `json:"currency"`
-	TotalIncome   float64 `json:"total_income"`
-	TotalExpenses float64 `json:"total_expenses"`
-	TotalSavings  float64 `json:"total_savings"`
-	TopCategories []byte  `json:"top_categories"`
+	Currency      string       `json:"currency"`
+	TotalIncome   money.Amount `json:"total_income"`
+	TotalExpenses money.Amount `json:"total_expenses"`
+	TotalSavings  money.Amount `json:"total_savings"`
+	TopCategories []byte       `json:"top_categories"`
 }
 
+// Totals are read from monthly_aggregates. Income and expenses are summed
+// apart and then set side by side per currency, so a currency with only income
+// or only expenses in the month is listed too. top_categories ranks top-level
+// categories, each counting the expenses of its subcategories
 func (q *Queries) GetMonthlySummary(ctx context.Context, arg GetMonthlySummaryParams) ([]GetMonthlySummaryRow, error) {
 	rows, err := q.db.Query(ctx, getMonthlySummary, arg.UserID, arg.Date)
 	if err != nil {
@@ -107,82 +166,92 @@ func (q *Queries) GetMonthlySummary(ctx context.Context, arg GetMonthlySummaryPa
 }
 
 const getYearlySummary = `-- name: GetYearlySummary :many
-WITH yearly_totals AS (
-    SELECT 
-        i.currency::varchar(3) AS currency,
-        COALESCE(SUM(i.amount), 0)::float8 as total_income,
-        COALESCE(SUM(e.amount), 0)::float8 as total_expenses,
-        COALESCE(SUM(i.amount) - SUM(e.amount), 0)::float8 as total_savings
-    FROM income i
-    FULL OUTER JOIN expenses e ON 
-        e.user_id = i.user_id 
-        AND e.currency = i.currency::varchar(3)
-        AND DATE_TRUNC('year', e.date) = DATE_TRUNC('year', i.date)
-        AND e.deleted_at IS NULL
-    WHERE i.user_id = $1
-        AND i.deleted_at IS NULL
-        AND DATE_TRUNC('year', i.date) = DATE_TRUNC('year', $2::TIMESTAMPTZ)
-    GROUP BY i.currency
+WITH months AS (
+    SELECT a.currency, a.month, a.kind, a.category_id, a.total, a.count
+    FROM monthly_aggregates a
+    WHERE a.user_id = $1
+        AND a.month >= DATE_TRUNC('year', utc_month($2::TIMESTAMPTZ))::DATE
+        AND a.month < (DATE_TRUNC('year', utc_month($2::TIMESTAMPTZ)) + INTERVAL '1 year')::DATE
 ),
-top_categories AS (
-    SELECT 
-        e.category_id,
-        c.name as category_name,
-        e.currency::varchar(3) AS currency,
-        COUNT(*) as usage_count,
-        SUM(e.amount) as total_spent,
-        ROW_NUMBER() OVER (PARTITION BY e.currency::varchar(3) ORDER BY SUM(e.amount) DESC) as rank
-    FROM expenses e
-    JOIN categories c ON e.category_id = c.id
-    WHERE e.user_id = $1
-        AND e.deleted_at IS NULL
+totals AS (
+    SELECT
+        m.currency,
+        COALESCE(SUM(m.total) FILTER (WHERE m.kind = 'income'), 0)::numeric AS total_income,
+        COALESCE(SUM(m.total) FILTER (WHERE m.kind = 'expense'), 0)::numeric AS total_expenses
+    FROM months m
+    GROUP BY m.currency
+),
+category_months AS (
+    SELECT
+        m.currency,
+        m.month,
+        c.id AS category_id,
+        c.name AS category_name,
+        m.count,
+        m.total
+    FROM months m
+    JOIN category_ancestors($1) ca ON ca.category_id = m.category_id AND ca.is_root
+    JOIN categories c ON c.id = ca.ancestor_id
+    WHERE m.kind = 'expense'
         AND c.deleted_at IS NULL
-        AND DATE_TRUNC('year', e.date) = DATE_TRUNC('year', $2::TIMESTAMPTZ)
-    GROUP BY e.category_id, c.name, e.currency::varchar(3)
+),
+top_categories AS (
+    SELECT
+        cm.currency,
+        cm.category_id,
+        cm.category_name,
+        SUM(cm.count) AS usage_count,
+        SUM(cm.total) AS total_spent,
+        ROW_NUMBER() OVER (PARTITION BY cm.currency ORDER BY SUM(cm.total) DESC) AS rank
+    FROM category_months cm
+    GROUP BY cm.currency, cm.category_id, cm.category_name
 ),
 monthly_trend AS (
-    SELECT 
-        DATE_TRUNC('month', e.date) as month,
-        e.currency::varchar(3),
-        c.name as category_name,
-        SUM(e.amount) as monthly_expenses
-    FROM expenses e
-    JOIN categories c ON e.category_id = c.id
-    WHERE e.user_id = $1
-        AND e.deleted_at IS NULL
-        AND c.deleted_at IS NULL
-        AND DATE_TRUNC('year', e.date) = DATE_TRUNC('year', $2::TIMESTAMPTZ)
-    GROUP BY DATE_TRUNC('month', e.date), e.currency::varchar(3), c.name
-    ORDER BY month
+    SELECT
+        cm.currency,
+        cm.month,
+        cm.category_name,
+        SUM(cm.total) AS monthly_expenses
+    FROM category_months cm
+    GROUP BY cm.currency, cm.month, cm.category_name
 )
-SELECT 
-    yt.currency, yt.total_income, yt.total_expenses, yt.total_savings,
-    json_agg(
-        json_build_object(
-            'category_id', tc.category_id,
-            'category_name', tc.category_name,
-            'usage_count', tc.usage_count,
-            'total_spent', tc.total_spent
-        )
-    ) FILTER (WHERE tc.category_id IS NOT NULL) as top_categories,
-    json_agg(
-        json_build_object(
-            'month', mt.month,
-            'category_name', mt.category_name,
-            'amount', mt.monthly_expenses
-        )
-    ) FILTER (WHERE mt.month IS NOT NULL) as monthly_trend
-FROM yearly_totals yt
-LEFT JOIN top_categories tc ON 
-    tc.currency = yt.currency::varchar(3)
-    AND tc.rank <= 5
-LEFT JOIN monthly_trend mt ON 
-    mt.currency = yt.currency::varchar(3)
-GROUP BY 
-    yt.currency::varchar(3), 
-    yt.total_income, 
-    yt.total_expenses, 
-    yt.total_savings
+SELECT
+    t.currency::varchar(3) AS currency,
+    t.total_income,
+    t.total_expenses,
+    (t.total_income - t.total_expenses)::numeric AS total_savings,
+    COALESCE(
+        (
+            SELECT json_agg(
+                json_build_object(
+                    'category_id', tc.category_id,
+                    'category_name', tc.category_name,
+                    'usage_count', tc.usage_count,
+                    'total_spent', tc.total_spent
+                ) ORDER BY tc.rank
+            )
+            FROM top_categories tc
+            WHERE tc.currency = t.currency
+                AND tc.rank <= 5
+        ),
+        '[]'
+    )::json AS top_categories,
+    COALESCE(
+        (
+            SELECT json_agg(
+                json_build_object(
+                    'month', mt.month::TIMESTAMP AT TIME ZONE 'UTC',
+                    'category_name', mt.category_name,
+                    'amount', mt.monthly_expenses
+                ) ORDER BY mt.month, mt.category_name
+            )
+            FROM monthly_trend mt
+            WHERE mt.currency = t.currency
+        ),
+        '[]'
+    )::json AS monthly_trend
+FROM totals t
+ORDER BY t.currency
 `
 
 type GetYearlySummaryParams struct {
@@ -191,14 +260,17 @@ type GetYearlySummaryParams struct {
 }
 
 type GetYearlySummaryRow struct {
-	Currency      string  `json:"currency"`
-	TotalIncome   float64 `json:"total_income"`
-	TotalExpenses float64 `json:"total_expenses"`
-	TotalSavings  float64 `json:"total_savings"`
-	TopCategories []byte  `json:"top_categories"`
-	MonthlyTrend  []byte  `json:"monthly_trend"`
+	Currency      string       `json:"currency"`
+	TotalIncome   money.Amount `json:"total_income"`
+	TotalExpenses money.Amount `json:"total_expenses"`
+	TotalSavings  money.Amount `json:"total_savings"`
+	TopCategories []byte       `json:"top_categories"`
+	MonthlyTrend  []byte       `json:"monthly_trend"`
 }
 
+// Like GetMonthlySummary, totals are read from monthly_aggregates, and
+// top_categories and monthly_trend roll subcategories up into their top-level
+// category
 func (q *Queries) GetYearlySummary(ctx context.Context, arg GetYearlySummaryParams) ([]GetYearlySummaryRow, error) {
 	rows, err := q.db.Query(ctx, getYearlySummary, arg.UserID, arg.Date)
 	if err != nil {
@@ -225,3 +297,16 @@ func (q *Queries) GetYearlySummary(ctx context.Context, arg GetYearlySummaryPara
 	}
 	return items, nil
 }
+
+const rebuildMonthlyAggregates = `-- name: RebuildMonthlyAggregates :one
+SELECT rebuild_monthly_aggregates($1::UUID)::BIGINT AS rows_written
+`
+
+// Recomputes the monthly aggregates of one user, or of every user when
+// user_id is NULL, from their transactions. Returns how many rows it wrote.
+func (q *Queries) RebuildMonthlyAggregates(ctx context.Context, userID *uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, rebuildMonthlyAggregates, userID)
+	var rows_written int64
+	err := row.Scan(&rows_written)
+	return rows_written, err
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummaryWindows(t *testing.T) {
+	eastern := time.FixedZone("EST", -5*60*60)
+	tokyo := time.FixedZone("JST", 9*60*60)
+	utc := func(year int, month time.Month) time.Time { return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name                string
+		date                time.Time
+		wantMonth, wantYear time.Time
+	}{
+		{"UTC", time.Date(2024, time.May, 15, 12, 0, 0, 0, time.UTC), utc(2024, time.May), utc(2024, time.January)},
+		{"Behind UTC, already next month there", time.Date(2024, time.April, 30, 22, 0, 0, 0, eastern), utc(2024, time.May), utc(2024, time.January)},
+		{"Ahead of UTC, still last month there", time.Date(2024, time.June, 1, 5, 0, 0, 0, tokyo), utc(2024, time.May), utc(2024, time.January)},
+		{"Behind UTC, already next year there", time.Date(2023, time.December, 31, 22, 0, 0, 0, eastern), utc(2024, time.January), utc(2024, time.January)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := SummaryMonth(tt.date)
+			assert.Equal(t, tt.wantMonth, start)
+			assert.Equal(t, tt.wantMonth.AddDate(0, 1, 0), end)
+
+			start, end = SummaryYear(tt.date)
+			assert.Equal(t, tt.wantYear, start)
+			assert.Equal(t, tt.wantYear.AddDate(1, 0, 0), end)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// MaxTxAttempts is how many times a transaction is tried before a
+// serialization failure is given up on
+const MaxTxAttempts = 3
+
+// txRetryDelay is the wait before the second attempt, doubled for each one
+// after it
+const txRetryDelay = 20 * time.Millisecond
+
+// ErrTxUnsupported is returned when starting a transaction on a connection
+// that cannot begin one
+var ErrTxUnsupported = errors.New("repository: connection does not support transactions")
+
+// txBeginner is a DBTX that starts transactions, such as a pgxpool.Pool or a
+// pgx.Conn
+type txBeginner interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// IsSerializationFailure reports whether err is Postgres giving up on a
+// transaction that conflicted with another, which succeeds when tried again
+func IsSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	// serialization_failure and deadlock_detected
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
+
+// RetryTx runs attempt until it succeeds, fails with anything but a
+// serialization failure, or has been tried MaxTxAttempts times, waiting a
+// little longer before each try
+func RetryTx(ctx context.Context, attempt func() error) error {
+	delay := txRetryDelay
+	for try := 1; ; try++ {
+		err := attempt()
+		if err == nil || !IsSerializationFailure(err) || try == MaxTxAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// ExecTx runs fn with queries bound to a serializable transaction,
+// committing when fn returns nil and rolling back when it fails or panics.
+// Serialization failures are retried as RetryTx describes, so fn may run more
+// than once and should not have effects outside the database.
+//
+// Called on queries already in a transaction, fn runs in a savepoint of it
+// instead: its writes are undone when it fails, and the outer transaction
+// decides whether the rest are kept.
+func (q *Queries) ExecTx(ctx context.Context, fn func(*Queries) error) error {
+	if tx, ok := q.db.(pgx.Tx); ok {
+		return runTx(ctx, func() (pgx.Tx, error) { return tx.Begin(ctx) }, fn)
+	}
+	beginner, ok := q.db.(txBeginner)
+	if !ok {
+		return ErrTxUnsupported
+	}
+	return RetryTx(ctx, func() error {
+		return runTx(ctx, func() (pgx.Tx, error) {
+			return beginner.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
+		}, fn)
+	})
+}
+
+// InTx runs fn as ExecTx does, handing it a Repository whose operations all
+// belong to the transaction
+func (q *Queries) InTx(ctx context.Context, fn func(Repository) error) error {
+	return q.ExecTx(ctx, func(tx *Queries) error {
+		return fn(tx)
+	})
+}
+
+func runTx(ctx context.Context, begin func() (pgx.Tx, error), fn func(*Queries) error) error {
+	tx, err := begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	// Rolling back a committed transaction does nothing
+	defer tx.Rollback(ctx)
+
+	if err := fn(New(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+var errSerialization = &pgconn.PgError{Code: "40001"}
+
+func TestIsSerializationFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"Serialization failure", errSerialization, true},
+		{"Deadlock", &pgconn.PgError{Code: "40P01"}, true},
+		{"Wrapped", fmt.Errorf("importing transactions: %w", errSerialization), true},
+		{"Unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"Other error", errors.New("boom"), false},
+		{"No error", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsSerializationFailure(tt.err))
+		})
+	}
+}
+
+func TestRetryTx(t *testing.T) {
+	failing := func(failures int, err error) (func() error, *int) {
+		attempts := 0
+		return func() error {
+			attempts++
+			if attempts <= failures {
+				return err
+			}
+			return nil
+		}, &attempts
+	}
+
+	t.Run("Retries serialization failures", func(t *testing.T) {
+		attempt, attempts := failing(MaxTxAttempts-1, errSerialization)
+		assert.NoError(t, RetryTx(context.Background(), attempt))
+		assert.Equal(t, MaxTxAttempts, *attempts)
+	})
+
+	t.Run("Gives up after MaxTxAttempts", func(t *testing.T) {
+		attempt, attempts := failing(MaxTxAttempts, errSerialization)
+		assert.ErrorIs(t, RetryTx(context.Background(), attempt), errSerialization)
+		assert.Equal(t, MaxTxAttempts, *attempts)
+	})
+
+	t.Run("Does not retry other errors", func(t *testing.T) {
+		boom := errors.New("boom")
+		attempt, attempts := failing(1, boom)
+		assert.ErrorIs(t, RetryTx(context.Background(), attempt), boom)
+		assert.Equal(t, 1, *attempts)
+	})
+
+	t.Run("Stops when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		attempt, attempts := failing(1, errSerialization)
+		assert.ErrorIs(t, RetryTx(ctx, attempt), context.Canceled)
+		assert.Equal(t, 1, *attempts)
+	})
+}
+
+// queryOnly is a DBTX that cannot begin transactions
+type queryOnly struct {
+	DBTX
+}
+
+func TestExecTxUnsupported(t *testing.T) {
+	ran := false
+	err := New(queryOnly{}).InTx(context.Background(), func(Repository) error {
+		ran = true
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrTxUnsupported)
+	assert.False(t, ran)
+}
@@ -45,7 +45,7 @@ func (q *Queries) CheckUserIsAdmin(ctx context.Context, userID uuid.UUID) (bool,
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, name, email, password_hash, created_at, updated_at)
 VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-RETURNING id, name, email, password_hash, role_id, created_at, updated_at, deleted_at
+RETURNING id, name, email, password_hash, role_id, created_at, updated_at, deleted_at, base_currency
 `
 
 type CreateUserParams struct {
@@ -72,6 +72,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.BaseCurrency,
 	)
 	return i, err
 }
@@ -120,16 +121,17 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (GetUserByEm
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, name, email, created_at 
+SELECT id, name, email, created_at, base_currency
 FROM users 
 WHERE id = $1 AND deleted_at IS NULL
 `
 
 type GetUserByIDRow struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	CreatedAt    time.Time `json:"created_at"`
+	BaseCurrency string    `json:"base_currency"`
 }
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (GetUserByIDRow, error) {
@@ -140,6 +142,7 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (GetUserByIDRow
 		&i.Name,
 		&i.Email,
 		&i.CreatedAt,
+		&i.BaseCurrency,
 	)
 	return i, err
 }
@@ -250,24 +253,87 @@ func (q *Queries) ListUsersByRole(ctx context.Context, name string) ([]ListUsers
 	return items, nil
 }
 
+const registerUser = `-- name: RegisterUser :one
+WITH new_user AS (
+    INSERT INTO users (id, name, email, password_hash, created_at, updated_at)
+    VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+    RETURNING id, name, email, password_hash, role_id, created_at, updated_at, deleted_at, base_currency
+),
+entries AS (
+    SELECT e.name, e.parent_name, e.position, gen_random_uuid() AS id
+    FROM category_template_entries e
+    WHERE e.template_key = $5
+),
+new_categories AS (
+    INSERT INTO categories (id, user_id, name, parent_id, created_at, updated_at)
+    SELECT c.id, u.id, c.name, p.id, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+    FROM entries c
+    CROSS JOIN new_user u
+    LEFT JOIN entries p ON p.name = c.parent_name
+    ORDER BY c.parent_name IS NOT NULL, c.position
+)
+SELECT id, name, email, password_hash, role_id, created_at, updated_at, deleted_at, base_currency FROM new_user
+`
+
+type RegisterUserParams struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"password_hash"`
+	TemplateKey  string    `json:"template_key"`
+}
+
+// Creates a user together with the categories of a template, in one statement
+// so that either both are saved or neither is. An empty template_key creates
+// no categories. Parents are inserted before their children.
+func (q *Queries) RegisterUser(ctx context.Context, arg RegisterUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, registerUser,
+		arg.ID,
+		arg.Name,
+		arg.Email,
+		arg.PasswordHash,
+		arg.TemplateKey,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.PasswordHash,
+		&i.RoleID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.BaseCurrency,
+	)
+	return i, err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users 
 SET 
     name = $2,
     email = $3,
+    base_currency = $4,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = $1 AND deleted_at IS NULL
-RETURNING id, name, email, password_hash, role_id, created_at, updated_at, deleted_at
+RETURNING id, name, email, password_hash, role_id, created_at, updated_at, deleted_at, base_currency
 `
 
 type UpdateUserParams struct {
-	ID    uuid.UUID `json:"id"`
-	Name  string    `json:"name"`
-	Email string    `json:"email"`
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	BaseCurrency string    `json:"base_currency"`
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRow(ctx, updateUser, arg.ID, arg.Name, arg.Email)
+	row := q.db.QueryRow(ctx, updateUser,
+		arg.ID,
+		arg.Name,
+		arg.Email,
+		arg.BaseCurrency,
+	)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -278,6 +344,7 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.BaseCurrency,
 	)
 	return i, err
 }
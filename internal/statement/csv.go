@@ -0,0 +1,239 @@
+package statement
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Mapping names the CSV columns each field is read from. Only the date and
+// either the amount or the debit and credit columns have to be present; any
+// other column the file does not have is left empty.
+type Mapping struct {
+	Date        string
+	Amount      string // Signed, negative for money leaving the account
+	Debit       string // Money leaving the account, for files that split the amount
+	Credit      string // Money reaching the account
+	Type        string // "expense" or "income", overriding the sign of the amount
+	Currency    string
+	Description string
+	Source      string
+	Category    string
+	ExternalID  string
+
+	// DateFormat spells out how dates are written using YYYY, YY, MM, M, DD,
+	// D, HH, mm and ss, e.g. "DD/MM/YYYY". When empty dates must be RFC 3339
+	// or YYYY-MM-DD.
+	DateFormat string
+
+	// DecimalComma reads "1.234,56" as one thousand two hundred and
+	// thirty-four and 56 hundredths
+	DecimalComma bool
+
+	// InvertSign is for exports, such as most credit card ones, that show
+	// spending as positive amounts
+	InvertSign bool
+}
+
+// DefaultMapping reads the columns this API exports: date, type, amount,
+// currency, category, source, description and external_id
+func DefaultMapping() Mapping {
+	return Mapping{
+		Date:        "date",
+		Amount:      "amount",
+		Type:        "type",
+		Currency:    "currency",
+		Description: "description",
+		Source:      "source",
+		Category:    "category",
+		ExternalID:  "external_id",
+	}
+}
+
+var dateFormatReplacer = strings.NewReplacer(
+	"YYYY", "2006",
+	"YY", "06",
+	"MM", "01",
+	"DD", "02",
+	"HH", "15",
+	"mm", "04",
+	"ss", "05",
+	"M", "1",
+	"D", "2",
+)
+
+// typeAliases maps the words banks use in a type column to a transaction type
+var typeAliases = map[string]string{
+	"expense":    TypeExpense,
+	"debit":      TypeExpense,
+	"dr":         TypeExpense,
+	"withdrawal": TypeExpense,
+	"payment":    TypeExpense,
+	"income":     TypeIncome,
+	"credit":     TypeIncome,
+	"cr":         TypeIncome,
+	"deposit":    TypeIncome,
+}
+
+func (m Mapping) parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, ErrMissingDate
+	}
+	if m.DateFormat != "" {
+		date, err := time.Parse(dateFormatReplacer.Replace(m.DateFormat), s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("date %q does not match the format %s", s, m.DateFormat)
+		}
+		return date, nil
+	}
+	if date, err := time.Parse(time.RFC3339, s); err == nil {
+		return date, nil
+	}
+	if date, err := time.Parse(time.DateOnly, s); err == nil {
+		return date, nil
+	}
+	return time.Time{}, fmt.Errorf("date %q is neither RFC 3339 nor YYYY-MM-DD", s)
+}
+
+// ParseCSV reads the transactions of a CSV file with a header row. Lines that
+// cannot be read are collected rather than returned so that the caller can
+// report all of them at once. An error is only returned when the file as a
+// whole is unusable.
+func ParseCSV(r io.Reader, m Mapping) ([]Transaction, []LineError, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("CSV file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Excel saves UTF-8 with a byte order mark
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	column := func(name string) (int, bool) {
+		if name == "" {
+			return 0, false
+		}
+		i, ok := columns[strings.ToLower(strings.TrimSpace(name))]
+		return i, ok
+	}
+
+	if _, ok := column(m.Date); !ok {
+		return nil, nil, fmt.Errorf("CSV header is missing the date column %q", m.Date)
+	}
+	_, hasDebit := column(m.Debit)
+	_, hasCredit := column(m.Credit)
+	if _, ok := column(m.Amount); !ok && !hasDebit && !hasCredit {
+		return nil, nil, fmt.Errorf("CSV header is missing the amount column %q, or debit and credit columns", m.Amount)
+	}
+	reader.FieldsPerRecord = len(header)
+
+	var transactions []Transaction
+	var lineErrors []LineError
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, nil, err
+			}
+			lineErrors = append(lineErrors, LineError{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(name string) string {
+			i, ok := column(name)
+			if !ok {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		transaction, err := m.transaction(field)
+		if err != nil {
+			lineErrors = append(lineErrors, LineError{Line: line, Err: err})
+			continue
+		}
+		transaction.Line = line
+		transactions = append(transactions, transaction)
+	}
+
+	if len(transactions) == 0 && len(lineErrors) == 0 {
+		return nil, nil, fmt.Errorf("CSV file has no transactions")
+	}
+	return transactions, lineErrors, nil
+}
+
+// transaction reads one CSV record, whose columns field looks up by name
+func (m Mapping) transaction(field func(name string) string) (Transaction, error) {
+	date, err := m.parseDate(field(m.Date))
+	if err != nil {
+		return Transaction{}, err
+	}
+	t := Transaction{
+		Date:        date,
+		Currency:    strings.ToUpper(field(m.Currency)),
+		Description: field(m.Description),
+		Source:      field(m.Source),
+		Category:    field(m.Category),
+		ExternalID:  field(m.ExternalID),
+	}
+
+	debit, credit := field(m.Debit), field(m.Credit)
+	switch {
+	case debit != "" && credit != "":
+		return Transaction{}, fmt.Errorf("both debit and credit are given")
+	case debit != "":
+		amount, err := parseAmount(debit, m.DecimalComma)
+		if err != nil {
+			return Transaction{}, err
+		}
+		// Some banks sign the debit column and some do not
+		if !amount.IsNegative() {
+			amount = amount.Neg()
+		}
+		t.signed(amount)
+	case credit != "":
+		amount, err := parseAmount(credit, m.DecimalComma)
+		if err != nil {
+			return Transaction{}, err
+		}
+		if amount.IsNegative() {
+			amount = amount.Neg()
+		}
+		t.signed(amount)
+	default:
+		amount, err := parseAmount(field(m.Amount), m.DecimalComma)
+		if err != nil {
+			return Transaction{}, err
+		}
+		if m.InvertSign {
+			amount = amount.Neg()
+		}
+		t.signed(amount)
+	}
+
+	if value := field(m.Type); value != "" {
+		transactionType, ok := typeAliases[strings.ToLower(value)]
+		if !ok {
+			return Transaction{}, fmt.Errorf("type %q is neither expense nor income", value)
+		}
+		t.Type = transactionType
+	}
+
+	return t, nil
+}
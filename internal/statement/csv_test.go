@@ -0,0 +1,168 @@
+package statement
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		input        string
+		decimalComma bool
+		want         string
+		wantErr      error
+	}{
+		{"12.50", false, "12.5", nil},
+		{"-12.50", false, "-12.5", nil},
+		{"$1,234.56", false, "1234.56", nil},
+		{"(45.00)", false, "-45", nil},
+		{"45.00-", false, "-45", nil},
+		{"USD 7", false, "7", nil},
+		{"1.234,56", true, "1234.56", nil},
+		{"-0,99 €", true, "-0.99", nil},
+		{"", false, "", ErrMissingAmount},
+		{"twelve", false, "", ErrInvalidAmount},
+		{"1.2.3", false, "", ErrInvalidAmount},
+		{"12-50", false, "", ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseAmount(tt.input, tt.decimalComma)
+			assert.Equal(t, tt.wantErr, err)
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.want, got.String())
+			}
+		})
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name       string
+		mapping    Mapping
+		body       string
+		want       []Transaction
+		wantErrors []int // Lines reported as invalid
+		wantErr    bool
+	}{
+		{
+			name:    "Default columns",
+			mapping: DefaultMapping(),
+			body: "date,type,amount,currency,category,source,description,external_id\n" +
+				"2024-01-05T00:00:00Z,expense,12.50,usd,Groceries,,Weekly shop,tx-1\n" +
+				"2024-01-06,income,2500,USD,,Employer,January pay,\n",
+			want: []Transaction{
+				{Line: 2, Type: TypeExpense, Date: date(2024, time.January, 5), Amount: money.MustParse("12.50"), Currency: "USD", Category: "Groceries", Description: "Weekly shop", ExternalID: "tx-1"},
+				{Line: 3, Type: TypeIncome, Date: date(2024, time.January, 6), Amount: money.MustParse("2500"), Currency: "USD", Source: "Employer", Description: "January pay"},
+			},
+		},
+		{
+			name: "Bank layout with signed amounts",
+			mapping: Mapping{
+				Date:        "Posted Date",
+				Amount:      "Amount",
+				Description: "Payee",
+				DateFormat:  "MM/DD/YYYY",
+			},
+			body: "\ufeffPosted Date,Payee,Amount\n" +
+				"01/31/2024,Coffee Shop,-4.75\n" +
+				"02/01/2024,Refund,\"1,000.00\"\n" +
+				"31/01/2024,Bad date,-1.00\n",
+			want: []Transaction{
+				{Line: 2, Type: TypeExpense, Date: date(2024, time.January, 31), Amount: money.MustParse("4.75"), Description: "Coffee Shop"},
+				{Line: 3, Type: TypeIncome, Date: date(2024, time.February, 1), Amount: money.MustParse("1000"), Description: "Refund"},
+			},
+			wantErrors: []int{4},
+		},
+		{
+			name: "Debit and credit columns",
+			mapping: Mapping{
+				Date:         "date",
+				Debit:        "debit",
+				Credit:       "credit",
+				Source:       "payee",
+				DateFormat:   "D/M/YYYY",
+				DecimalComma: true,
+			},
+			body: "date,payee,debit,credit\n" +
+				"5/1/2024,Landlord,\"-1.200,00\",\n" +
+				"6/1/2024,Employer,,\"2.500,00\"\n" +
+				"7/1/2024,Confused,\"1,00\",\"1,00\"\n" +
+				"8/1/2024,Nothing,,\n",
+			want: []Transaction{
+				{Line: 2, Type: TypeExpense, Date: date(2024, time.January, 5), Amount: money.MustParse("1200"), Source: "Landlord"},
+				{Line: 3, Type: TypeIncome, Date: date(2024, time.January, 6), Amount: money.MustParse("2500"), Source: "Employer"},
+			},
+			wantErrors: []int{4, 5},
+		},
+		{
+			name:    "Credit card export",
+			mapping: Mapping{Date: "date", Amount: "amount", Type: "kind", InvertSign: true},
+			body: "date,amount,kind\n" +
+				"2024-03-01,25.00,\n" +
+				"2024-03-02,-10.00,\n" +
+				"2024-03-03,5.00,transfer\n",
+			want: []Transaction{
+				{Line: 2, Type: TypeExpense, Date: date(2024, time.March, 1), Amount: money.MustParse("25")},
+				{Line: 3, Type: TypeIncome, Date: date(2024, time.March, 2), Amount: money.MustParse("10")},
+			},
+			wantErrors: []int{4},
+		},
+		{
+			name:    "Wrong number of fields",
+			mapping: DefaultMapping(),
+			body:    "date,amount\n2024-01-01,1,extra\n2024-01-02,-2\n",
+			want: []Transaction{
+				{Line: 3, Type: TypeExpense, Date: date(2024, time.January, 2), Amount: money.MustParse("2")},
+			},
+			wantErrors: []int{2},
+		},
+		{
+			name:    "Missing amount column",
+			mapping: DefaultMapping(),
+			body:    "date,description\n2024-01-01,Lunch\n",
+			wantErr: true,
+		},
+		{
+			name:    "Header only",
+			mapping: DefaultMapping(),
+			body:    "date,amount\n",
+			wantErr: true,
+		},
+		{
+			name:    "Empty file",
+			mapping: DefaultMapping(),
+			body:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, lineErrors, err := ParseCSV(strings.NewReader(tt.body), tt.mapping)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.want, got)
+			var lines []int
+			for _, lineErr := range lineErrors {
+				lines = append(lines, lineErr.Line)
+			}
+			assert.Equal(t, tt.wantErrors, lines)
+		})
+	}
+}
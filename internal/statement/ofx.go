@@ -0,0 +1,198 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ofxDateLayouts are the layouts of an OFX date by the number of digits given
+var ofxDateLayouts = map[int]string{
+	8:  "20060102",
+	12: "200601021504",
+	14: "20060102150405",
+}
+
+// ParseOFX reads the bank and credit card transactions of an OFX or QFX file.
+// Both the SGML of OFX 1.x, where elements are not closed, and the XML of OFX
+// 2.x are accepted. Transactions are in the statement's default currency
+// unless they give their own, and keep the bank's FITID as their external ID. Like ParseCSV, transactions that cannot be read
+// are collected rather than returned.
+func ParseOFX(r io.Reader) ([]Transaction, []LineError, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	start := bytes.Index(data, []byte("<OFX>"))
+	if start < 0 {
+		return nil, nil, fmt.Errorf("not an OFX file")
+	}
+
+	var (
+		transactions []Transaction
+		lineErrors   []LineError
+		open         []string // Aggregates currently open, innermost last
+		currency     string   // The statement's CURDEF
+		current      *ofxTransaction
+	)
+	line := 1 + bytes.Count(data[:start], []byte("\n"))
+	rest := data[start:]
+
+	for len(rest) > 0 {
+		lt := bytes.IndexByte(rest, '<')
+		if lt < 0 {
+			break
+		}
+		line += bytes.Count(rest[:lt], []byte("\n"))
+		gt := bytes.IndexByte(rest[lt:], '>')
+		if gt < 0 {
+			return nil, nil, fmt.Errorf("line %d: unterminated tag", line)
+		}
+		tag := strings.ToUpper(strings.TrimSpace(string(rest[lt+1 : lt+gt])))
+		rest = rest[lt+gt+1:]
+
+		// Processing instructions and comments of OFX 2.x
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+
+		if name, ok := strings.CutPrefix(tag, "/"); ok {
+			// Closing a leaf element in XML closes nothing that is open
+			if len(open) == 0 || open[len(open)-1] != name {
+				continue
+			}
+			open = open[:len(open)-1]
+			if name == "STMTTRN" && current != nil {
+				transaction, err := current.transaction(currency)
+				if err != nil {
+					lineErrors = append(lineErrors, LineError{Line: current.line, Err: err})
+				} else {
+					transactions = append(transactions, transaction)
+				}
+				current = nil
+			}
+			continue
+		}
+
+		// An element followed by text is a leaf holding a value, anything
+		// else opens an aggregate
+		end := bytes.IndexByte(rest, '<')
+		if end < 0 {
+			end = len(rest)
+		}
+		value := strings.TrimSpace(html.UnescapeString(string(rest[:end])))
+		if value == "" {
+			open = append(open, tag)
+			if tag == "STMTTRN" {
+				current = &ofxTransaction{line: line}
+			}
+			continue
+		}
+
+		parent := ""
+		if len(open) > 0 {
+			parent = open[len(open)-1]
+		}
+		switch {
+		case tag == "CURDEF":
+			currency = strings.ToUpper(value)
+		case current != nil && tag == "CURSYM" && parent == "CURRENCY":
+			current.currency = strings.ToUpper(value)
+		case current != nil:
+			current.set(tag, value)
+		}
+	}
+
+	if len(transactions) == 0 && len(lineErrors) == 0 {
+		return nil, nil, fmt.Errorf("OFX file has no transactions")
+	}
+	return transactions, lineErrors, nil
+}
+
+// ofxTransaction collects the elements of one STMTTRN aggregate
+type ofxTransaction struct {
+	line     int
+	posted   string
+	amount   string
+	name     string
+	memo     string
+	fitID    string
+	currency string
+}
+
+func (t *ofxTransaction) set(tag, value string) {
+	switch tag {
+	case "DTPOSTED":
+		t.posted = value
+	case "TRNAMT":
+		t.amount = value
+	case "NAME":
+		t.name = value
+	case "MEMO":
+		t.memo = value
+	case "FITID":
+		t.fitID = value
+	}
+}
+
+func (t *ofxTransaction) transaction(currency string) (Transaction, error) {
+	date, err := parseOFXDate(t.posted)
+	if err != nil {
+		return Transaction{}, err
+	}
+	// The decimal separator may be a comma outside the US
+	decimalComma := !strings.Contains(t.amount, ".")
+	amount, err := parseAmount(t.amount, decimalComma)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	transaction := Transaction{
+		Line:        t.line,
+		Date:        date,
+		Currency:    currency,
+		Description: t.memo,
+		Source:      t.name,
+		ExternalID:  t.fitID,
+	}
+	if t.currency != "" {
+		transaction.Currency = t.currency
+	}
+	transaction.signed(amount)
+	return transaction, nil
+}
+
+// parseOFXDate reads a date such as "20240131", "20240131120000.000" or
+// "20240131120000[-5:EST]". Without a time zone the date is taken as UTC.
+func parseOFXDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, ErrMissingDate
+	}
+
+	digits, zone, _ := strings.Cut(s, "[")
+	digits, _, _ = strings.Cut(digits, ".")
+	layout, ok := ofxDateLayouts[len(digits)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("date %q is not an OFX date", s)
+	}
+
+	location := time.UTC
+	if zone != "" {
+		offset, name, _ := strings.Cut(strings.TrimSuffix(zone, "]"), ":")
+		hours, err := strconv.ParseFloat(offset, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("date %q has an invalid time zone", s)
+		}
+		location = time.FixedZone(name, int(hours*3600))
+	}
+
+	date, err := time.ParseInLocation(layout, digits, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("date %q is not an OFX date", s)
+	}
+	return date, nil
+}
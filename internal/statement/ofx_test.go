@@ -0,0 +1,181 @@
+package statement
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/stretchr/testify/assert"
+)
+
+const sgmlStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+CHARSET:1252
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS>
+<STATUS><CODE>0<SEVERITY>INFO</STATUS>
+<DTSERVER>20240205120000
+<LANGUAGE>ENG
+</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><TRNUID>1
+<STMTRS>
+<CURDEF>usd
+<BANKACCTFROM><BANKID>123<ACCTID>456<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240101<DTEND>20240131
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240103120000.000[-5:EST]
+<TRNAMT>-42.17
+<FITID>2024010301
+<NAME>CORNER GROCER
+<MEMO>Card purchase
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240115
+<TRNAMT>2500.00
+<FITID>2024011501
+<NAME>ACME PAYROLL
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>January 20
+<TRNAMT>-5.00
+<FITID>2024012001
+<NAME>UNREADABLE
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+const xmlStatement = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE"?>
+<OFX>
+  <CREDITCARDMSGSRSV1>
+    <CCSTMTTRNRS>
+      <CCSTMTRS>
+        <CURDEF>CAD</CURDEF>
+        <BANKTRANLIST>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20240210</DTPOSTED>
+            <TRNAMT>-19,99</TRNAMT>
+            <FITID>A1</FITID>
+            <NAME>Books &amp; More</NAME>
+            <MEMO></MEMO>
+          </STMTTRN>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20240211</DTPOSTED>
+            <TRNAMT>-30.00</TRNAMT>
+            <FITID>A2</FITID>
+            <NAME>Hotel</NAME>
+            <CURRENCY><CURRATE>1.35</CURRATE><CURSYM>USD</CURSYM></CURRENCY>
+          </STMTTRN>
+        </BANKTRANLIST>
+      </CCSTMTRS>
+    </CCSTMTTRNRS>
+  </CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		want       []Transaction
+		wantErrors []int
+		wantErr    bool
+	}{
+		{
+			name: "OFX 1.x SGML",
+			body: sgmlStatement,
+			want: []Transaction{
+				{
+					Line:        18,
+					Type:        TypeExpense,
+					Date:        time.Date(2024, time.January, 3, 17, 0, 0, 0, time.UTC),
+					Amount:      money.MustParse("42.17"),
+					Currency:    "USD",
+					Description: "Card purchase",
+					Source:      "CORNER GROCER",
+					ExternalID:  "2024010301",
+				},
+				{
+					Line:       26,
+					Type:       TypeIncome,
+					Date:       time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC),
+					Amount:     money.MustParse("2500"),
+					Currency:   "USD",
+					Source:     "ACME PAYROLL",
+					ExternalID: "2024011501",
+				},
+			},
+			wantErrors: []int{33},
+		},
+		{
+			name: "OFX 2.x XML",
+			body: xmlStatement,
+			want: []Transaction{
+				{
+					Line:       9,
+					Type:       TypeExpense,
+					Date:       time.Date(2024, time.February, 10, 0, 0, 0, 0, time.UTC),
+					Amount:     money.MustParse("19.99"),
+					Currency:   "CAD",
+					Source:     "Books & More",
+					ExternalID: "A1",
+				},
+				{
+					Line:       17,
+					Type:       TypeExpense,
+					Date:       time.Date(2024, time.February, 11, 0, 0, 0, 0, time.UTC),
+					Amount:     money.MustParse("30"),
+					Currency:   "USD",
+					Source:     "Hotel",
+					ExternalID: "A2",
+				},
+			},
+		},
+		{
+			name:    "Not OFX",
+			body:    "date,amount\n2024-01-01,1\n",
+			wantErr: true,
+		},
+		{
+			name:    "No transactions",
+			body:    "<OFX><BANKMSGSRSV1></BANKMSGSRSV1></OFX>",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, lineErrors, err := ParseOFX(strings.NewReader(tt.body))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Len(t, got, len(tt.want))
+			for i := range tt.want {
+				assert.True(t, tt.want[i].Date.Equal(got[i].Date), "date of transaction %d", i)
+				got[i].Date = tt.want[i].Date
+			}
+			assert.Equal(t, tt.want, got)
+			var lines []int
+			for _, lineErr := range lineErrors {
+				lines = append(lines, lineErr.Line)
+			}
+			assert.Equal(t, tt.wantErrors, lines)
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// Package statement reads the transactions out of bank statement exports: CSV
+// files laid out however the bank chose, and OFX or QFX files
+package statement
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jorge-dev/centsible/internal/money"
+)
+
+const (
+	TypeExpense = "expense"
+	TypeIncome  = "income"
+)
+
+var (
+	ErrMissingDate   = errors.New("date is missing")
+	ErrMissingAmount = errors.New("amount is missing")
+	ErrInvalidAmount = errors.New("amount is not a number")
+)
+
+// Transaction is one line of a statement. Amount is never negative: whether
+// money left or reached the account is given by Type.
+type Transaction struct {
+	Line        int
+	Type        string
+	Date        time.Time
+	Amount      money.Amount
+	Currency    string // Empty when the statement does not say
+	Description string
+	Source      string // Who the money went to or came from
+	Category    string // A category name or ID, CSV only
+	ExternalID  string // The bank's ID for the transaction, when it gives one
+}
+
+// LineError reports why one line of a statement could not be read
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e LineError) Unwrap() error {
+	return e.Err
+}
+
+// signed sets the type of a transaction from the sign of its amount and
+// keeps the amount itself positive
+func (t *Transaction) signed(amount money.Amount) {
+	if amount.IsNegative() {
+		t.Type = TypeExpense
+		t.Amount = amount.Neg()
+		return
+	}
+	t.Type = TypeIncome
+	t.Amount = amount
+}
+
+// parseAmount reads an amount the way banks tend to write one: with a
+// currency symbol, thousands separators, or in parentheses or with a trailing
+// minus when it is negative. With decimalComma the roles of "." and "," swap.
+func parseAmount(s string, decimalComma bool) (money.Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return money.Amount{}, ErrMissingAmount
+	}
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+	if strings.HasSuffix(s, "-") {
+		negative = true
+		s = s[:len(s)-1]
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '-' || r == '+':
+			if b.Len() > 0 {
+				return money.Amount{}, ErrInvalidAmount
+			}
+			if r == '-' {
+				negative = !negative
+			}
+		case r == '.' && !decimalComma, r == ',' && decimalComma:
+			b.WriteByte('.')
+		case r == ',' || r == '.' || r == ' ' || r == '\'' || r == '\u00a0':
+			// Thousands separator
+		case strings.ContainsRune("$€£¥₹", r) || (r >= 'A' && r <= 'Z'):
+			// Currency symbol or code
+		default:
+			return money.Amount{}, ErrInvalidAmount
+		}
+	}
+	if b.Len() == 0 {
+		return money.Amount{}, ErrInvalidAmount
+	}
+
+	amount, err := money.Parse(b.String())
+	if err != nil {
+		return money.Amount{}, ErrInvalidAmount
+	}
+	if negative {
+		amount = amount.Neg()
+	}
+	return amount, nil
+}
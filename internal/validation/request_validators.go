@@ -3,20 +3,24 @@ package validation
 import (
 	"fmt"
 	"log"
+	"slices"
 	"strings"
 	"time"
 
 	currencyValidator "github.com/bojanz/currency"
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/recurrence"
 )
 
 // ExpenseValidation validates expense-related requests
 type ExpenseValidation struct {
-	Amount          float64
+	Amount          money.Amount
 	Currency        string
 	CategoryID      uuid.UUID
 	Description     string
 	Date            string
+	ExternalID      string
 	IsPartialUpdate bool
 }
 
@@ -46,12 +50,16 @@ func (v *ExpenseValidation) Validate() error {
 		return err
 	}
 
-	return nil
+	// Validate external ID (optional)
+	return (&TextValidator{
+		Text:   v.ExternalID,
+		MaxLen: ExternalIDMaxLength,
+	}).Validate()
 }
 
 // Add this new type
 type CurrentExpense struct {
-	Amount      float64
+	Amount      money.Amount
 	Currency    string
 	CategoryID  uuid.UUID
 	Date        time.Time
@@ -73,8 +81,8 @@ func (v *ExpenseValidation) ValidatePartialUpdate(current CurrentExpense) (Curre
 	}
 
 	// Handle individual field updates
-	if v.Amount != 0 {
-		if v.Amount <= 0 {
+	if !v.Amount.IsZero() {
+		if !v.Amount.IsPositive() {
 			return CurrentExpense{}, ErrInvalidAmount
 		}
 		result.Amount = v.Amount
@@ -112,6 +120,11 @@ func (v *ExpenseValidation) ValidatePartialUpdate(current CurrentExpense) (Curre
 		result.Description = v.Description
 	}
 
+	// The amount has to fit the currency even when only one of them changed
+	if !result.Amount.FitsCurrency(result.Currency) {
+		return CurrentExpense{}, ErrAmountPrecision
+	}
+
 	return result, nil
 }
 
@@ -129,12 +142,79 @@ func (v *CategoryValidation) Validate() error {
 	}).Validate()
 }
 
+// TagValidation validates tag-related requests
+type TagValidation struct {
+	Name string
+}
+
+func (v *TagValidation) Validate() error {
+	return (&TextValidator{
+		Text:     v.Name,
+		MinLen:   1,
+		MaxLen:   50,
+		Required: true,
+	}).Validate()
+}
+
+// CategoryRuleValidation validates category rule requests. A rule needs
+// something to match on and something to set.
+type CategoryRuleValidation struct {
+	Name               string
+	DescriptionPattern *string
+	MinAmount          *money.Amount
+	MaxAmount          *money.Amount
+	Currency           *string
+	CategoryID         *uuid.UUID
+	TagIDs             []uuid.UUID
+}
+
+func (v *CategoryRuleValidation) Validate() error {
+	if err := (&TextValidator{
+		Text:     v.Name,
+		MinLen:   1,
+		MaxLen:   CategoryRuleNameMaxLength,
+		Required: true,
+	}).Validate(); err != nil {
+		return err
+	}
+
+	if v.DescriptionPattern == nil && v.MinAmount == nil && v.MaxAmount == nil && v.Currency == nil {
+		return ErrRuleCondition
+	}
+	if v.DescriptionPattern != nil {
+		if err := (&TextValidator{
+			Text:     *v.DescriptionPattern,
+			MinLen:   1,
+			MaxLen:   CategoryRulePatternMaxLength,
+			Required: true,
+		}).Validate(); err != nil {
+			return fmt.Errorf("description_pattern: %w", err)
+		}
+	}
+	if (v.MinAmount != nil && v.MinAmount.IsNegative()) || (v.MaxAmount != nil && v.MaxAmount.IsNegative()) {
+		return ErrNegativeAmountRange
+	}
+	if v.MinAmount != nil && v.MaxAmount != nil && v.MinAmount.Cmp(*v.MaxAmount) > 0 {
+		return ErrAmountRange
+	}
+	if v.Currency != nil && !currencyValidator.IsValid(*v.Currency) {
+		return ErrInvalidCurrency
+	}
+
+	if (v.CategoryID == nil || *v.CategoryID == uuid.Nil) && len(v.TagIDs) == 0 {
+		return ErrRuleAction
+	}
+	return nil
+}
+
 // BudgetValidation validates budget-related requests
 type BudgetValidation struct {
-	Amount          float64
+	Amount          money.Amount
 	Currency        string
 	CategoryID      uuid.UUID
 	Type            string
+	Cadence         string
+	Rollover        *bool
 	StartDate       string
 	EndDate         string
 	AlertThreshold  *float64
@@ -142,6 +222,33 @@ type BudgetValidation struct {
 	Name            string // Add name field
 }
 
+// validateBudgetSchedule checks that a budget's type, cadence, rollover and
+// dates fit together. One-time budgets cover a single window of up to a year.
+// Recurring budgets repeat for as long as they have no end date, or until it.
+func validateBudgetSchedule(budgetType string, cadence *recurrence.Cadence, rollover bool, start time.Time, end *time.Time) error {
+	switch budgetType {
+	case "one-time":
+		if cadence != nil || rollover {
+			return ErrNotRecurring
+		}
+		if end == nil {
+			return ErrEndDateRequired
+		}
+		return (&DateRangeValidator{
+			StartDate: start,
+			EndDate:   *end,
+		}).Validate()
+	case "recurring":
+		if cadence == nil {
+			return ErrCadenceRequired
+		}
+		if end != nil && !end.After(start) {
+			return ErrDateRange
+		}
+	}
+	return nil
+}
+
 func (v *BudgetValidation) ValidateAlertThreshold() error {
 	if v.AlertThreshold != nil {
 		if *v.AlertThreshold < 0 || *v.AlertThreshold > 100 {
@@ -179,15 +286,26 @@ func (v *BudgetValidation) Validate() error {
 			return err
 		}
 
-		end, err := ValidateDate(v.EndDate)
-		if err != nil {
-			return err
+		var end *time.Time
+		if v.EndDate != "" {
+			endDate, err := ValidateDate(v.EndDate)
+			if err != nil {
+				return err
+			}
+			end = &endDate
 		}
 
-		if err := (&DateRangeValidator{
-			StartDate: start,
-			EndDate:   end,
-		}).Validate(); err != nil {
+		var cadence *recurrence.Cadence
+		if v.Cadence != "" {
+			parsed, err := recurrence.ParseCadence(v.Cadence)
+			if err != nil {
+				return err
+			}
+			cadence = &parsed
+		}
+
+		rollover := v.Rollover != nil && *v.Rollover
+		if err := validateBudgetSchedule(v.Type, cadence, rollover, start, end); err != nil {
 			return err
 		}
 	} else {
@@ -204,8 +322,8 @@ func (v *BudgetValidation) Validate() error {
 		}
 
 		// Partial update validation - validate amount and currency separately
-		if v.Amount != 0 {
-			if v.Amount <= 0 {
+		if !v.Amount.IsZero() {
+			if !v.Amount.IsPositive() {
 				return ErrInvalidAmount
 			}
 		}
@@ -224,6 +342,11 @@ func (v *BudgetValidation) Validate() error {
 		if v.Type != "" && v.Type != "recurring" && v.Type != "one-time" {
 			return fmt.Errorf("type must be either 'recurring' or 'one-time'")
 		}
+		if v.Cadence != "" {
+			if _, err := recurrence.ParseCadence(v.Cadence); err != nil {
+				return err
+			}
+		}
 
 		var start time.Time
 		var end time.Time
@@ -245,13 +368,10 @@ func (v *BudgetValidation) Validate() error {
 		}
 		log.Println("start date", start)
 
-		if !start.IsZero() && !end.IsZero() {
-			if err := (&DateRangeValidator{
-				StartDate: start,
-				EndDate:   end,
-			}).Validate(); err != nil {
-				return err
-			}
+		// The rest of the schedule is checked against the current budget in
+		// ValidatePartialUpdate
+		if !start.IsZero() && !end.IsZero() && !end.After(start) {
+			return ErrDateRange
 		}
 
 	}
@@ -266,12 +386,14 @@ func (v *BudgetValidation) Validate() error {
 
 // Add this new type
 type CurrentBudget struct {
-	Amount     float64
+	Amount     money.Amount
 	Currency   string
 	CategoryID uuid.UUID
 	Type       string
+	Cadence    *recurrence.Cadence
+	Rollover   bool
 	StartDate  time.Time
-	EndDate    time.Time
+	EndDate    *time.Time
 	Name       string // Add name field
 }
 
@@ -282,45 +404,49 @@ func (v *BudgetValidation) ValidatePartialUpdate(current CurrentBudget) (Current
 		Currency:   current.Currency,
 		CategoryID: current.CategoryID,
 		Type:       current.Type,
+		Cadence:    current.Cadence,
+		Rollover:   current.Rollover,
 		StartDate:  current.StartDate,
 		EndDate:    current.EndDate,
 		Name:       current.Name, // Copy existing name
 	}
 
 	// Handle date updates, considering both current and new dates
-	var newStartDate, newEndDate time.Time
-	var err error
-
 	if v.StartDate != "" {
-		newStartDate, err = time.Parse(time.RFC3339, v.StartDate)
+		newStartDate, err := time.Parse(time.RFC3339, v.StartDate)
 		if err != nil {
 			return CurrentBudget{}, fmt.Errorf("invalid start date format")
 		}
 		result.StartDate = newStartDate
-	} else {
-		newStartDate = current.StartDate
 	}
 
 	if v.EndDate != "" {
-		newEndDate, err = time.Parse(time.RFC3339, v.EndDate)
+		newEndDate, err := time.Parse(time.RFC3339, v.EndDate)
 		if err != nil {
 			return CurrentBudget{}, fmt.Errorf("invalid end date format")
 		}
-		result.EndDate = newEndDate
-	} else {
-		newEndDate = current.EndDate
+		result.EndDate = &newEndDate
 	}
 
-	// Validate the date range using both current and new dates
-	if err := (&DateRangeValidator{
-		StartDate: newStartDate,
-		EndDate:   newEndDate,
-	}).Validate(); err != nil {
-		return CurrentBudget{}, err
+	// Turning a recurring budget into a one-time one drops its cadence and
+	// rollover unless the request sets them
+	if v.Type == "one-time" {
+		result.Cadence = nil
+		result.Rollover = false
+	}
+	if v.Cadence != "" {
+		cadence, err := recurrence.ParseCadence(v.Cadence)
+		if err != nil {
+			return CurrentBudget{}, err
+		}
+		result.Cadence = &cadence
+	}
+	if v.Rollover != nil {
+		result.Rollover = *v.Rollover
 	}
 
 	// Handle other fields
-	if v.Amount != 0 {
+	if !v.Amount.IsZero() {
 		result.Amount = v.Amount
 	}
 	if v.Currency != "" {
@@ -333,11 +459,21 @@ func (v *BudgetValidation) ValidatePartialUpdate(current CurrentBudget) (Current
 		result.Type = v.Type
 	}
 
+	// Validate the schedule using both current and new values
+	if err := validateBudgetSchedule(result.Type, result.Cadence, result.Rollover, result.StartDate, result.EndDate); err != nil {
+		return CurrentBudget{}, err
+	}
+
 	// Handle name update
 	if v.Name != "" {
 		result.Name = v.Name
 	}
 
+	// The amount has to fit the currency even when only one of them changed
+	if !result.Amount.FitsCurrency(result.Currency) {
+		return CurrentBudget{}, ErrAmountPrecision
+	}
+
 	return result, nil
 }
 
@@ -438,8 +574,9 @@ func (v *UserUpdateValidation) Validate() error {
 }
 
 type UserProfileValidation struct {
-	Name  string
-	Email string
+	Name         string
+	Email        string
+	BaseCurrency string
 }
 
 func (v *UserProfileValidation) Validate() error {
@@ -468,6 +605,10 @@ func (v *UserProfileValidation) Validate() error {
 		}
 	}
 
+	if v.BaseCurrency != "" && !currencyValidator.IsValid(v.BaseCurrency) {
+		return ErrInvalidCurrency
+	}
+
 	return nil
 }
 
@@ -495,11 +636,12 @@ func (v *PasswordUpdateValidation) Validate() error {
 
 // IncomeValidation validates income-related requests
 type IncomeValidation struct {
-	Amount      float64
+	Amount      money.Amount
 	Currency    string
 	Source      string
 	Date        string
 	Description string
+	ExternalID  string
 }
 
 func (v *IncomeValidation) Validate() error {
@@ -535,11 +677,15 @@ func (v *IncomeValidation) Validate() error {
 		return err
 	}
 
-	return nil
+	// Validate external ID (optional)
+	return (&TextValidator{
+		Text:   v.ExternalID,
+		MaxLen: ExternalIDMaxLength,
+	}).Validate()
 }
 
 type CurrentIncome struct {
-	Amount      float64
+	Amount      money.Amount
 	Currency    string
 	Source      string
 	Date        time.Time
@@ -555,8 +701,8 @@ func (v *IncomeValidation) ValidatePartialUpdate(current CurrentIncome) (Current
 		Description: current.Description,
 	}
 
-	if v.Amount != 0 {
-		if v.Amount <= 0 {
+	if !v.Amount.IsZero() {
+		if !v.Amount.IsPositive() {
 			return CurrentIncome{}, ErrInvalidAmount
 		}
 		result.Amount = v.Amount
@@ -601,6 +747,11 @@ func (v *IncomeValidation) ValidatePartialUpdate(current CurrentIncome) (Current
 		result.Description = v.Description
 	}
 
+	// The amount has to fit the currency even when only one of them changed
+	if !result.Amount.FitsCurrency(result.Currency) {
+		return CurrentIncome{}, ErrAmountPrecision
+	}
+
 	return result, nil
 }
 
@@ -667,3 +818,335 @@ func (v *DateRangeQueryValidation) Validate() error {
 
 	return nil
 }
+
+// ListQueryValidation validates the paging, sorting and filtering parameters
+// of a list endpoint. Sorts are the fields the list can be sorted by; filters
+// left empty match everything and are left nil once parsed.
+type ListQueryValidation struct {
+	Limit     int32
+	Sort      string
+	Sorts     []string
+	Order     string
+	MinAmount string
+	MaxAmount string
+	Currency  string
+	StartDate string
+	EndDate   string
+	Search    string
+
+	ParsedMinAmount *money.Amount
+	ParsedMaxAmount *money.Amount
+	ParsedStartDate *time.Time
+	ParsedEndDate   *time.Time
+}
+
+func (v *ListQueryValidation) Validate() error {
+	if err := (&PaginationValidator{Limit: v.Limit}).Validate(); err != nil {
+		return err
+	}
+
+	if !slices.Contains(v.Sorts, v.Sort) {
+		return fmt.Errorf("sort must be one of %s", strings.Join(v.Sorts, ", "))
+	}
+	if v.Order != "asc" && v.Order != "desc" {
+		return ErrInvalidSortOrder
+	}
+
+	for _, bound := range []struct {
+		name   string
+		value  string
+		parsed **money.Amount
+	}{
+		{"min_amount", v.MinAmount, &v.ParsedMinAmount},
+		{"max_amount", v.MaxAmount, &v.ParsedMaxAmount},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		amount, err := money.Parse(bound.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", bound.name, err)
+		}
+		*bound.parsed = &amount
+	}
+	if v.ParsedMinAmount != nil && v.ParsedMaxAmount != nil && v.ParsedMinAmount.Cmp(*v.ParsedMaxAmount) > 0 {
+		return ErrAmountRange
+	}
+
+	if v.Currency != "" && !currencyValidator.IsValid(v.Currency) {
+		return ErrInvalidCurrency
+	}
+
+	for _, bound := range []struct {
+		value  string
+		parsed **time.Time
+	}{
+		{v.StartDate, &v.ParsedStartDate},
+		{v.EndDate, &v.ParsedEndDate},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		date, err := ValidateDate(bound.value)
+		if err != nil {
+			return err
+		}
+		*bound.parsed = &date
+	}
+	if v.ParsedStartDate != nil && v.ParsedEndDate != nil && v.ParsedEndDate.Before(*v.ParsedStartDate) {
+		return ErrDateRange
+	}
+
+	return (&TextValidator{
+		Text:   v.Search,
+		MaxLen: SearchMaxLength,
+	}).Validate()
+}
+
+// ExchangeRateValidation validates a single exchange rate, either from a
+// request body or from one line of a CSV import
+type ExchangeRateValidation struct {
+	BaseCurrency  string
+	QuoteCurrency string
+	Date          string
+	Rate          money.Rate
+	ParsedDate    time.Time
+}
+
+func (v *ExchangeRateValidation) Validate() error {
+	if !currencyValidator.IsValid(v.BaseCurrency) || !currencyValidator.IsValid(v.QuoteCurrency) {
+		return ErrInvalidCurrency
+	}
+	if v.BaseCurrency == v.QuoteCurrency {
+		return ErrSameCurrency
+	}
+
+	// Rates apply to whole days, so dates are plain YYYY-MM-DD
+	if v.Date == "" {
+		return ErrEmptyField
+	}
+	date, err := time.Parse(time.DateOnly, v.Date)
+	if err != nil {
+		return ErrInvalidDate
+	}
+	v.ParsedDate = date
+
+	if !v.Rate.IsPositive() {
+		return ErrInvalidRate
+	}
+
+	return nil
+}
+
+// RecurringTemplate is a recurring transaction with every field parsed. Expense
+// templates have a category and income templates a source.
+type RecurringTemplate struct {
+	Type            string
+	Amount          money.Amount
+	Currency        string
+	CategoryID      uuid.UUID
+	Source          string
+	Description     string
+	Frequency       recurrence.Frequency
+	IntervalCount   int32
+	StartDate       time.Time
+	EndDate         *time.Time
+	OccurrenceCount *int32
+}
+
+// Rule returns the recurrence rule the template's occurrences follow
+func (t RecurringTemplate) Rule() recurrence.Rule {
+	rule := recurrence.Rule{
+		Start:     t.StartDate,
+		Frequency: t.Frequency,
+		Interval:  int(t.IntervalCount),
+		Until:     t.EndDate,
+	}
+	if t.OccurrenceCount != nil {
+		count := int(*t.OccurrenceCount)
+		rule.Count = &count
+	}
+	return rule
+}
+
+// validate checks a template as a whole
+func (t RecurringTemplate) validate() error {
+	if err := (&MoneyValidator{Amount: t.Amount, Currency: t.Currency}).Validate(); err != nil {
+		return err
+	}
+
+	switch t.Type {
+	case "expense":
+		if t.CategoryID == uuid.Nil {
+			return ErrInvalidUUID
+		}
+	case "income":
+		if err := (&TextValidator{
+			Text:     t.Source,
+			MinLen:   1,
+			MaxLen:   255,
+			Required: true,
+		}).Validate(); err != nil {
+			return err
+		}
+	default:
+		return ErrInvalidTransactionType
+	}
+
+	// Expenses need a description, income can do without one
+	if err := (&TextValidator{
+		Text:     t.Description,
+		MinLen:   1,
+		MaxLen:   TransactionDescriptionMaxLength,
+		Required: t.Type == "expense",
+	}).Validate(); err != nil {
+		return err
+	}
+
+	if _, err := recurrence.ParseFrequency(string(t.Frequency)); err != nil {
+		return err
+	}
+	if t.IntervalCount < 1 || t.IntervalCount > RecurrenceIntervalMax {
+		return ErrInvalidInterval
+	}
+	if t.StartDate.IsZero() {
+		return ErrInvalidDate
+	}
+	if t.EndDate != nil && t.OccurrenceCount != nil {
+		return ErrEndCondition
+	}
+	if t.EndDate != nil && t.EndDate.Before(t.StartDate) {
+		return ErrDateRange
+	}
+	if t.OccurrenceCount != nil && *t.OccurrenceCount < 1 {
+		return ErrInvalidOccurrenceCount
+	}
+	return nil
+}
+
+// RecurringTransactionValidation validates requests for recurring income and
+// expense templates. The schedule repeats every IntervalCount units of
+// Frequency from StartDate, and ends on EndDate, after OccurrenceCount
+// occurrences or never.
+type RecurringTransactionValidation struct {
+	Type            string
+	Amount          money.Amount
+	Currency        string
+	CategoryID      uuid.UUID
+	Source          string
+	Description     string
+	Frequency       string
+	IntervalCount   *int32
+	StartDate       string
+	EndDate         string
+	OccurrenceCount *int32
+	IsPartialUpdate bool
+	Template        RecurringTemplate // Set by Validate
+}
+
+func (v *RecurringTransactionValidation) Validate() error {
+	template := RecurringTemplate{
+		Type:            v.Type,
+		Amount:          v.Amount,
+		Currency:        v.Currency,
+		Description:     v.Description,
+		Frequency:       recurrence.Frequency(v.Frequency),
+		IntervalCount:   1,
+		OccurrenceCount: v.OccurrenceCount,
+	}
+	if v.IntervalCount != nil {
+		template.IntervalCount = *v.IntervalCount
+	}
+
+	// Each kind of template keeps only the field that applies to it
+	switch v.Type {
+	case "expense":
+		template.CategoryID = v.CategoryID
+	case "income":
+		template.Source = v.Source
+	}
+
+	start, err := ValidateDate(v.StartDate)
+	if err != nil {
+		return err
+	}
+	template.StartDate = start
+
+	if v.EndDate != "" {
+		end, err := ValidateDate(v.EndDate)
+		if err != nil {
+			return err
+		}
+		template.EndDate = &end
+	}
+
+	if err := template.validate(); err != nil {
+		return err
+	}
+	v.Template = template
+	return nil
+}
+
+// ValidatePartialUpdate applies the fields set in the request to the current
+// template and validates the result. Setting an end date replaces an
+// occurrence count and the other way around.
+func (v *RecurringTransactionValidation) ValidatePartialUpdate(current RecurringTemplate) (RecurringTemplate, error) {
+	if !v.IsPartialUpdate {
+		return RecurringTemplate{}, fmt.Errorf("not a partial update")
+	}
+	if v.Type != "" && v.Type != current.Type {
+		return RecurringTemplate{}, ErrTransactionTypeChange
+	}
+
+	result := current
+	if !v.Amount.IsZero() {
+		result.Amount = v.Amount
+	}
+	if v.Currency != "" {
+		result.Currency = v.Currency
+	}
+	if v.CategoryID != uuid.Nil && result.Type == "expense" {
+		result.CategoryID = v.CategoryID
+	}
+	if v.Source != "" && result.Type == "income" {
+		result.Source = v.Source
+	}
+	if v.Description != "" {
+		result.Description = v.Description
+	}
+	if v.Frequency != "" {
+		result.Frequency = recurrence.Frequency(v.Frequency)
+	}
+	if v.IntervalCount != nil {
+		result.IntervalCount = *v.IntervalCount
+	}
+
+	if v.StartDate != "" {
+		start, err := ValidateDate(v.StartDate)
+		if err != nil {
+			return RecurringTemplate{}, err
+		}
+		result.StartDate = start
+	}
+
+	if v.EndDate != "" && v.OccurrenceCount != nil {
+		return RecurringTemplate{}, ErrEndCondition
+	}
+	if v.EndDate != "" {
+		end, err := ValidateDate(v.EndDate)
+		if err != nil {
+			return RecurringTemplate{}, err
+		}
+		result.EndDate = &end
+		result.OccurrenceCount = nil
+	}
+	if v.OccurrenceCount != nil {
+		result.OccurrenceCount = v.OccurrenceCount
+		result.EndDate = nil
+	}
+
+	if err := result.validate(); err != nil {
+		return RecurringTemplate{}, err
+	}
+	return result, nil
+}
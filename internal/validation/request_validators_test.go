@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/recurrence"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -50,7 +52,7 @@ func TestExpenseValidationValidate(t *testing.T) {
 		{
 			Name: "valid expense",
 			Input: ExpenseValidation{
-				Amount:      100.00,
+				Amount:      money.MustParse("100.00"),
 				Currency:    validCurrency,
 				CategoryID:  validUUID,
 				Description: validDescription,
@@ -61,7 +63,7 @@ func TestExpenseValidationValidate(t *testing.T) {
 		{
 			Name: "invalid amount",
 			Input: ExpenseValidation{
-				Amount:      -100.00,
+				Amount:      money.MustParse("-100.00"),
 				Currency:    validCurrency,
 				CategoryID:  validUUID,
 				Description: validDescription,
@@ -73,7 +75,7 @@ func TestExpenseValidationValidate(t *testing.T) {
 		{
 			Name: "invalid currency",
 			Input: ExpenseValidation{
-				Amount:      100.00,
+				Amount:      money.MustParse("100.00"),
 				Currency:    invalidCurrency,
 				CategoryID:  validUUID,
 				Description: validDescription,
@@ -85,7 +87,7 @@ func TestExpenseValidationValidate(t *testing.T) {
 		{
 			Name: "invalid date passed",
 			Input: ExpenseValidation{
-				Amount:      100.00,
+				Amount:      money.MustParse("100.00"),
 				Currency:    validCurrency,
 				CategoryID:  validUUID,
 				Description: validDescription,
@@ -97,7 +99,7 @@ func TestExpenseValidationValidate(t *testing.T) {
 		{
 			Name: "empty description",
 			Input: ExpenseValidation{
-				Amount:      100.00,
+				Amount:      money.MustParse("100.00"),
 				Currency:    validCurrency,
 				CategoryID:  validUUID,
 				Description: "",
@@ -106,6 +108,18 @@ func TestExpenseValidationValidate(t *testing.T) {
 			WantErr:     true,
 			ExpectedErr: ErrEmptyField,
 		},
+		{
+			Name: "external ID too long",
+			Input: ExpenseValidation{
+				Amount:      money.MustParse("100.00"),
+				Currency:    validCurrency,
+				CategoryID:  validUUID,
+				Description: validDescription,
+				Date:        validDate,
+				ExternalID:  strings.Repeat("x", ExternalIDMaxLength+1),
+			},
+			WantErr: true,
+		},
 	}
 
 	runValidationTest[ExpenseValidation](t, testCases)
@@ -117,7 +131,7 @@ func TestIncomeValidationValidate(t *testing.T) {
 		{
 			Name: "valid income",
 			Input: IncomeValidation{
-				Amount:      100.00,
+				Amount:      money.MustParse("100.00"),
 				Currency:    validCurrency,
 				Source:      "Salary",
 				Description: validDescription,
@@ -128,7 +142,7 @@ func TestIncomeValidationValidate(t *testing.T) {
 		{
 			Name: "invalid amount",
 			Input: IncomeValidation{
-				Amount:      -100.00,
+				Amount:      money.MustParse("-100.00"),
 				Currency:    validCurrency,
 				Source:      "Salary",
 				Description: validDescription,
@@ -140,7 +154,7 @@ func TestIncomeValidationValidate(t *testing.T) {
 		{
 			Name: "empty source",
 			Input: IncomeValidation{
-				Amount:      100.00,
+				Amount:      money.MustParse("100.00"),
 				Currency:    validCurrency,
 				Source:      "",
 				Description: validDescription,
@@ -152,7 +166,7 @@ func TestIncomeValidationValidate(t *testing.T) {
 		{
 			Name: "invalid date",
 			Input: IncomeValidation{
-				Amount:      100.00,
+				Amount:      money.MustParse("100.00"),
 				Currency:    validCurrency,
 				Source:      "Salary",
 				Description: validDescription,
@@ -282,6 +296,102 @@ func TestCategoryValidationValidate(t *testing.T) {
 	runValidationTest[CategoryValidation](t, tests)
 }
 
+func TestTagValidationValidate(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name: "valid tag",
+			Input: TagValidation{
+				Name: "vacation-2024",
+			},
+			WantErr: false,
+		},
+		{
+			Name: "empty name",
+			Input: TagValidation{
+				Name: "",
+			},
+			WantErr:     true,
+			ExpectedErr: ErrEmptyField,
+		},
+		{
+			Name: "too long name",
+			Input: TagValidation{
+				Name: strings.Repeat("a", 51),
+			},
+			WantErr: true,
+		},
+	}
+
+	runValidationTest[TagValidation](t, tests)
+}
+
+func TestCategoryRuleValidationValidate(t *testing.T) {
+	pattern, empty, currency, badCurrency := "UBER*", "", "USD", "XYZ"
+	low, high, negative := money.MustParse("5"), money.MustParse("50"), money.MustParse("-1")
+	categoryID, nilID := uuid.New(), uuid.Nil
+
+	tests := []TestCase{
+		{
+			Name:    "pattern and category",
+			Input:   CategoryRuleValidation{Name: "Rides", DescriptionPattern: &pattern, CategoryID: &categoryID},
+			WantErr: false,
+		},
+		{
+			Name:    "amount range, currency and tags",
+			Input:   CategoryRuleValidation{Name: "Small", MinAmount: &low, MaxAmount: &high, Currency: &currency, TagIDs: []uuid.UUID{uuid.New()}},
+			WantErr: false,
+		},
+		{
+			Name:        "empty name",
+			Input:       CategoryRuleValidation{DescriptionPattern: &pattern, CategoryID: &categoryID},
+			WantErr:     true,
+			ExpectedErr: ErrEmptyField,
+		},
+		{
+			Name:    "too long name",
+			Input:   CategoryRuleValidation{Name: strings.Repeat("a", 101), DescriptionPattern: &pattern, CategoryID: &categoryID},
+			WantErr: true,
+		},
+		{
+			Name:        "nothing to match on",
+			Input:       CategoryRuleValidation{Name: "Everything", CategoryID: &categoryID},
+			WantErr:     true,
+			ExpectedErr: ErrRuleCondition,
+		},
+		{
+			Name:    "empty pattern",
+			Input:   CategoryRuleValidation{Name: "Empty", DescriptionPattern: &empty, CategoryID: &categoryID},
+			WantErr: true,
+		},
+		{
+			Name:        "negative amount",
+			Input:       CategoryRuleValidation{Name: "Refunds", MaxAmount: &negative, CategoryID: &categoryID},
+			WantErr:     true,
+			ExpectedErr: ErrNegativeAmountRange,
+		},
+		{
+			Name:        "inverted range",
+			Input:       CategoryRuleValidation{Name: "Inverted", MinAmount: &high, MaxAmount: &low, CategoryID: &categoryID},
+			WantErr:     true,
+			ExpectedErr: ErrAmountRange,
+		},
+		{
+			Name:        "invalid currency",
+			Input:       CategoryRuleValidation{Name: "Odd", Currency: &badCurrency, CategoryID: &categoryID},
+			WantErr:     true,
+			ExpectedErr: ErrInvalidCurrency,
+		},
+		{
+			Name:        "nothing to set",
+			Input:       CategoryRuleValidation{Name: "Idle", DescriptionPattern: &pattern, CategoryID: &nilID},
+			WantErr:     true,
+			ExpectedErr: ErrRuleAction,
+		},
+	}
+
+	runValidationTest[CategoryRuleValidation](t, tests)
+}
+
 func TestUserUpdateValidationValidate(t *testing.T) {
 	tests := []TestCase{
 		{
@@ -428,14 +538,16 @@ func TestAuthValidationValidate(t *testing.T) {
 // test for auth validation
 
 func TestBudgetValidationValidate(t *testing.T) {
+	rollover := true
 	tests := []TestCase{
 		{
 			Name: "valid full budget",
 			Input: BudgetValidation{
-				Amount:     1000.00,
+				Amount:     money.MustParse("1000.00"),
 				Currency:   validCurrency,
 				CategoryID: validUUID,
 				Type:       "recurring",
+				Cadence:    "monthly",
 				StartDate:  validDate,
 				EndDate:    "2023-12-31T00:00:00Z",
 				Name:       "Monthly Groceries",
@@ -445,10 +557,11 @@ func TestBudgetValidationValidate(t *testing.T) {
 		{
 			Name: "missing name in new budget",
 			Input: BudgetValidation{
-				Amount:     1000.00,
+				Amount:     money.MustParse("1000.00"),
 				Currency:   validCurrency,
 				CategoryID: validUUID,
 				Type:       "recurring",
+				Cadence:    "monthly",
 				StartDate:  validDate,
 				EndDate:    "2024-12-31T00:00:00Z",
 			},
@@ -458,10 +571,11 @@ func TestBudgetValidationValidate(t *testing.T) {
 		{
 			Name: "name too long",
 			Input: BudgetValidation{
-				Amount:     1000.00,
+				Amount:     money.MustParse("1000.00"),
 				Currency:   validCurrency,
 				CategoryID: validUUID,
 				Type:       "recurring",
+				Cadence:    "monthly",
 				StartDate:  validDate,
 				EndDate:    "2024-12-31T00:00:00Z",
 				Name:       strings.Repeat("a", 256),
@@ -471,7 +585,7 @@ func TestBudgetValidationValidate(t *testing.T) {
 		{
 			Name: "valid partial update - amount only",
 			Input: BudgetValidation{
-				Amount:          2000.00,
+				Amount:          money.MustParse("2000.00"),
 				IsPartialUpdate: true,
 			},
 			WantErr: false,
@@ -495,7 +609,7 @@ func TestBudgetValidationValidate(t *testing.T) {
 		{
 			Name: "invalid amount in partial update",
 			Input: BudgetValidation{
-				Amount:          -100.00,
+				Amount:          money.MustParse("-100.00"),
 				IsPartialUpdate: true,
 			},
 			WantErr:     true,
@@ -521,7 +635,7 @@ func TestBudgetValidationValidate(t *testing.T) {
 		{
 			Name: "invalid full budget - missing required fields",
 			Input: BudgetValidation{
-				Amount:   1000.00,
+				Amount:   money.MustParse("1000.00"),
 				Currency: validCurrency,
 				// Missing other required fields
 			},
@@ -530,10 +644,11 @@ func TestBudgetValidationValidate(t *testing.T) {
 		{
 			Name: "invalid date range",
 			Input: BudgetValidation{
-				Amount:     1000.00,
+				Amount:     money.MustParse("1000.00"),
 				Currency:   validCurrency,
 				CategoryID: validUUID,
 				Type:       "recurring",
+				Cadence:    "monthly",
 				StartDate:  "2024-12-31T00:00:00Z",
 				EndDate:    validDate, // End date before start date
 				Name:       "Monthly Groceries",
@@ -541,6 +656,99 @@ func TestBudgetValidationValidate(t *testing.T) {
 			WantErr:     true,
 			ExpectedErr: ErrDateRange,
 		},
+		{
+			Name: "recurring budget without an end date or a one-year limit",
+			Input: BudgetValidation{
+				Amount:     money.MustParse("1000.00"),
+				Currency:   validCurrency,
+				CategoryID: validUUID,
+				Type:       "recurring",
+				Cadence:    "weekly",
+				Rollover:   &rollover,
+				StartDate:  "2020-01-01T00:00:00Z",
+				Name:       "Weekly Groceries",
+			},
+			WantErr: false,
+		},
+		{
+			Name: "recurring budget without a cadence",
+			Input: BudgetValidation{
+				Amount:     money.MustParse("1000.00"),
+				Currency:   validCurrency,
+				CategoryID: validUUID,
+				Type:       "recurring",
+				StartDate:  validDate,
+				Name:       "Monthly Groceries",
+			},
+			WantErr:     true,
+			ExpectedErr: ErrCadenceRequired,
+		},
+		{
+			Name: "unknown cadence",
+			Input: BudgetValidation{
+				Amount:     money.MustParse("1000.00"),
+				Currency:   validCurrency,
+				CategoryID: validUUID,
+				Type:       "recurring",
+				Cadence:    "daily",
+				StartDate:  validDate,
+				Name:       "Daily Groceries",
+			},
+			WantErr:     true,
+			ExpectedErr: recurrence.ErrInvalidCadence,
+		},
+		{
+			Name: "one-time budget with a cadence",
+			Input: BudgetValidation{
+				Amount:     money.MustParse("1000.00"),
+				Currency:   validCurrency,
+				CategoryID: validUUID,
+				Type:       "one-time",
+				Cadence:    "monthly",
+				StartDate:  validDate,
+				EndDate:    "2023-12-31T00:00:00Z",
+				Name:       "Holiday",
+			},
+			WantErr:     true,
+			ExpectedErr: ErrNotRecurring,
+		},
+		{
+			Name: "one-time budget with rollover",
+			Input: BudgetValidation{
+				Amount:     money.MustParse("1000.00"),
+				Currency:   validCurrency,
+				CategoryID: validUUID,
+				Type:       "one-time",
+				Rollover:   &rollover,
+				StartDate:  validDate,
+				EndDate:    "2023-12-31T00:00:00Z",
+				Name:       "Holiday",
+			},
+			WantErr:     true,
+			ExpectedErr: ErrNotRecurring,
+		},
+		{
+			Name: "one-time budget without an end date",
+			Input: BudgetValidation{
+				Amount:     money.MustParse("1000.00"),
+				Currency:   validCurrency,
+				CategoryID: validUUID,
+				Type:       "one-time",
+				StartDate:  validDate,
+				Name:       "Holiday",
+			},
+			WantErr:     true,
+			ExpectedErr: ErrEndDateRequired,
+		},
+		{
+			Name: "invalid cadence in partial update",
+			Input: BudgetValidation{
+				Cadence:         "fortnightly",
+				IsPartialUpdate: true,
+			},
+			WantErr:     true,
+			ExpectedErr: recurrence.ErrInvalidCadence,
+		},
 	}
 
 	runValidationTest[BudgetValidation](t, tests)
@@ -549,14 +757,18 @@ func TestBudgetValidationValidate(t *testing.T) {
 func TestBudgetValidationValidatePartialUpdate(t *testing.T) {
 	now := time.Now().UTC()
 	future := now.AddDate(0, 1, 0)
+	monthly := recurrence.Monthly
+	yearly := recurrence.Yearly
+	rollover := true
 
 	current := CurrentBudget{
-		Amount:     1000.00,
+		Amount:     money.MustParse("1000.00"),
 		Currency:   "USD",
 		CategoryID: validUUID,
 		Type:       "recurring",
+		Cadence:    &monthly,
 		StartDate:  now,
-		EndDate:    future,
+		EndDate:    &future,
 		Name:       "Original Budget",
 	}
 
@@ -569,14 +781,15 @@ func TestBudgetValidationValidatePartialUpdate(t *testing.T) {
 		{
 			name: "valid amount update",
 			input: BudgetValidation{
-				Amount:          2000.00,
+				Amount:          money.MustParse("2000.00"),
 				IsPartialUpdate: true,
 			},
 			want: CurrentBudget{
-				Amount:     2000.00,
+				Amount:     money.MustParse("2000.00"),
 				Currency:   current.Currency,
 				CategoryID: current.CategoryID,
 				Type:       current.Type,
+				Cadence:    current.Cadence,
 				StartDate:  current.StartDate,
 				EndDate:    current.EndDate,
 				Name:       current.Name,
@@ -586,13 +799,13 @@ func TestBudgetValidationValidatePartialUpdate(t *testing.T) {
 		{
 			name: "valid multiple field update",
 			input: BudgetValidation{
-				Amount:          2000.00,
+				Amount:          money.MustParse("2000.00"),
 				Currency:        "EUR",
 				Type:            "one-time",
 				IsPartialUpdate: true,
 			},
 			want: CurrentBudget{
-				Amount:     2000.00,
+				Amount:     money.MustParse("2000.00"),
 				Currency:   "EUR",
 				CategoryID: current.CategoryID,
 				Type:       "one-time",
@@ -630,12 +843,42 @@ func TestBudgetValidationValidatePartialUpdate(t *testing.T) {
 				Currency:   current.Currency,
 				CategoryID: current.CategoryID,
 				Type:       current.Type,
+				Cadence:    current.Cadence,
 				StartDate:  current.StartDate,
 				EndDate:    current.EndDate,
 				Name:       "Updated Budget Name",
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid cadence and rollover update",
+			input: BudgetValidation{
+				Cadence:         "yearly",
+				Rollover:        &rollover,
+				IsPartialUpdate: true,
+			},
+			want: CurrentBudget{
+				Amount:     current.Amount,
+				Currency:   current.Currency,
+				CategoryID: current.CategoryID,
+				Type:       current.Type,
+				Cadence:    &yearly,
+				Rollover:   true,
+				StartDate:  current.StartDate,
+				EndDate:    current.EndDate,
+				Name:       current.Name,
+			},
+			wantErr: false,
+		},
+		{
+			name: "rollover on a budget turned one-time",
+			input: BudgetValidation{
+				Type:            "one-time",
+				Rollover:        &rollover,
+				IsPartialUpdate: true,
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -658,7 +901,7 @@ func TestExpenseValidation_ValidatePartialUpdate(t *testing.T) {
 	testCategoryId := uuid.New()
 	testUpdateDate := now.Add(24 * time.Hour)
 	current := CurrentExpense{
-		Amount:      100.00,
+		Amount:      money.MustParse("100.00"),
 		Currency:    "USD",
 		CategoryID:  uuid.New(),
 		Date:        now,
@@ -674,11 +917,11 @@ func TestExpenseValidation_ValidatePartialUpdate(t *testing.T) {
 		{
 			name: "valid amount update",
 			update: ExpenseValidation{
-				Amount:          200.00,
+				Amount:          money.MustParse("200.00"),
 				IsPartialUpdate: true,
 			},
 			want: CurrentExpense{
-				Amount:      200.00,
+				Amount:      money.MustParse("200.00"),
 				Currency:    current.Currency,
 				CategoryID:  current.CategoryID,
 				Date:        current.Date,
@@ -689,7 +932,7 @@ func TestExpenseValidation_ValidatePartialUpdate(t *testing.T) {
 		{
 			name: "invalid amount update",
 			update: ExpenseValidation{
-				Amount:          -50.00,
+				Amount:          money.MustParse("-50.00"),
 				IsPartialUpdate: true,
 			},
 			wantErr: true,
@@ -717,6 +960,29 @@ func TestExpenseValidation_ValidatePartialUpdate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "amount too precise for currency",
+			update: ExpenseValidation{
+				Amount:          money.MustParse("100.001"),
+				IsPartialUpdate: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "currency update without minor units",
+			update: ExpenseValidation{
+				Currency:        "JPY",
+				IsPartialUpdate: true,
+			},
+			want: CurrentExpense{
+				Amount:      current.Amount,
+				Currency:    "JPY",
+				CategoryID:  current.CategoryID,
+				Date:        current.Date,
+				Description: current.Description,
+			},
+			wantErr: false,
+		},
 		{
 			name: "valid category update",
 			update: ExpenseValidation{
@@ -781,13 +1047,13 @@ func TestExpenseValidation_ValidatePartialUpdate(t *testing.T) {
 		{
 			name: "multiple field update",
 			update: ExpenseValidation{
-				Amount:          150.00,
+				Amount:          money.MustParse("150.00"),
 				Currency:        "EUR",
 				Description:     "Multiple update",
 				IsPartialUpdate: true,
 			},
 			want: CurrentExpense{
-				Amount:      150.00,
+				Amount:      money.MustParse("150.00"),
 				Currency:    "EUR",
 				CategoryID:  current.CategoryID,
 				Date:        current.Date,
@@ -806,7 +1072,7 @@ func TestExpenseValidation_ValidatePartialUpdate(t *testing.T) {
 		{
 			name: "not partial update",
 			update: ExpenseValidation{
-				Amount:          200.00,
+				Amount:          money.MustParse("200.00"),
 				IsPartialUpdate: false,
 			},
 			wantErr: true,
@@ -831,7 +1097,7 @@ func TestIncomeValidation_ValidatePartialUpdate(t *testing.T) {
 	testUpdateDate := now.Add(24 * time.Hour)
 
 	current := CurrentIncome{
-		Amount:      100.00,
+		Amount:      money.MustParse("100.00"),
 		Currency:    "USD",
 		Source:      "Salary",
 		Date:        now,
@@ -847,10 +1113,10 @@ func TestIncomeValidation_ValidatePartialUpdate(t *testing.T) {
 		{
 			name: "valid amount update",
 			update: IncomeValidation{
-				Amount: 200.00,
+				Amount: money.MustParse("200.00"),
 			},
 			want: CurrentIncome{
-				Amount:      200.00,
+				Amount:      money.MustParse("200.00"),
 				Currency:    current.Currency,
 				Source:      current.Source,
 				Date:        current.Date,
@@ -861,7 +1127,7 @@ func TestIncomeValidation_ValidatePartialUpdate(t *testing.T) {
 		{
 			name: "invalid amount update",
 			update: IncomeValidation{
-				Amount: -50.00,
+				Amount: money.MustParse("-50.00"),
 			},
 			wantErr: true,
 		},
@@ -945,12 +1211,12 @@ func TestIncomeValidation_ValidatePartialUpdate(t *testing.T) {
 		{
 			name: "multiple field update",
 			update: IncomeValidation{
-				Amount:      150.00,
+				Amount:      money.MustParse("150.00"),
 				Currency:    "EUR",
 				Description: "Multiple update",
 			},
 			want: CurrentIncome{
-				Amount:      150.00,
+				Amount:      money.MustParse("150.00"),
 				Currency:    "EUR",
 				Source:      current.Source,
 				Date:        current.Date,
@@ -1017,6 +1283,21 @@ func TestUserProfileValidation(t *testing.T) {
 			},
 			WantErr: true,
 		},
+		{
+			Name: "valid profile update - only base currency",
+			Input: UserProfileValidation{
+				BaseCurrency: "EUR",
+			},
+			WantErr: false,
+		},
+		{
+			Name: "invalid base currency",
+			Input: UserProfileValidation{
+				BaseCurrency: invalidCurrency,
+			},
+			WantErr:     true,
+			ExpectedErr: ErrInvalidCurrency,
+		},
 		{
 			Name:    "empty update - valid",
 			Input:   UserProfileValidation{},
@@ -1027,6 +1308,161 @@ func TestUserProfileValidation(t *testing.T) {
 	runValidationTest[UserProfileValidation](t, tests)
 }
 
+func TestExchangeRateValidation(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name: "valid rate",
+			Input: ExchangeRateValidation{
+				BaseCurrency:  "EUR",
+				QuoteCurrency: "USD",
+				Date:          "2024-01-02",
+				Rate:          money.MustParseRate("1.0945"),
+			},
+			WantErr: false,
+		},
+		{
+			Name: "invalid currency",
+			Input: ExchangeRateValidation{
+				BaseCurrency:  invalidCurrency,
+				QuoteCurrency: "USD",
+				Date:          "2024-01-02",
+				Rate:          money.MustParseRate("1.0945"),
+			},
+			WantErr:     true,
+			ExpectedErr: ErrInvalidCurrency,
+		},
+		{
+			Name: "same currency",
+			Input: ExchangeRateValidation{
+				BaseCurrency:  "USD",
+				QuoteCurrency: "USD",
+				Date:          "2024-01-02",
+				Rate:          money.MustParseRate("1"),
+			},
+			WantErr:     true,
+			ExpectedErr: ErrSameCurrency,
+		},
+		{
+			Name: "missing date",
+			Input: ExchangeRateValidation{
+				BaseCurrency:  "EUR",
+				QuoteCurrency: "USD",
+				Rate:          money.MustParseRate("1.0945"),
+			},
+			WantErr:     true,
+			ExpectedErr: ErrEmptyField,
+		},
+		{
+			Name: "timestamp instead of date",
+			Input: ExchangeRateValidation{
+				BaseCurrency:  "EUR",
+				QuoteCurrency: "USD",
+				Date:          validDate,
+				Rate:          money.MustParseRate("1.0945"),
+			},
+			WantErr:     true,
+			ExpectedErr: ErrInvalidDate,
+		},
+		{
+			Name: "zero rate",
+			Input: ExchangeRateValidation{
+				BaseCurrency:  "EUR",
+				QuoteCurrency: "USD",
+				Date:          "2024-01-02",
+			},
+			WantErr:     true,
+			ExpectedErr: ErrInvalidRate,
+		},
+	}
+
+	runValidationTest[ExchangeRateValidation](t, tests)
+}
+
+func TestListQueryValidation(t *testing.T) {
+	valid := ListQueryValidation{
+		Limit: 50,
+		Sort:  "date",
+		Sorts: []string{"date", "amount", "created_at"},
+		Order: "desc",
+	}
+	with := func(change func(*ListQueryValidation)) ListQueryValidation {
+		v := valid
+		change(&v)
+		return v
+	}
+
+	tests := []TestCase{
+		{
+			Name:    "no filters",
+			Input:   valid,
+			WantErr: false,
+		},
+		{
+			Name: "every filter",
+			Input: with(func(v *ListQueryValidation) {
+				v.MinAmount, v.MaxAmount = "10", "10.50"
+				v.Currency = "USD"
+				v.StartDate, v.EndDate = validDate, validDate
+				v.Search = "coffee"
+			}),
+			WantErr: false,
+		},
+		{
+			Name:        "limit too high",
+			Input:       with(func(v *ListQueryValidation) { v.Limit = 1001 }),
+			WantErr:     true,
+			ExpectedErr: ErrInvalidLimit,
+		},
+		{
+			Name:    "unknown sort",
+			Input:   with(func(v *ListQueryValidation) { v.Sort = "name" }),
+			WantErr: true,
+		},
+		{
+			Name:        "unknown order",
+			Input:       with(func(v *ListQueryValidation) { v.Order = "up" }),
+			WantErr:     true,
+			ExpectedErr: ErrInvalidSortOrder,
+		},
+		{
+			Name:    "invalid amount",
+			Input:   with(func(v *ListQueryValidation) { v.MinAmount = "ten" }),
+			WantErr: true,
+		},
+		{
+			Name:        "amounts reversed",
+			Input:       with(func(v *ListQueryValidation) { v.MinAmount, v.MaxAmount = "20", "10" }),
+			WantErr:     true,
+			ExpectedErr: ErrAmountRange,
+		},
+		{
+			Name:        "invalid currency",
+			Input:       with(func(v *ListQueryValidation) { v.Currency = invalidCurrency }),
+			WantErr:     true,
+			ExpectedErr: ErrInvalidCurrency,
+		},
+		{
+			Name:        "invalid date",
+			Input:       with(func(v *ListQueryValidation) { v.StartDate = invalidDate }),
+			WantErr:     true,
+			ExpectedErr: ErrInvalidDate,
+		},
+		{
+			Name:        "dates reversed",
+			Input:       with(func(v *ListQueryValidation) { v.StartDate, v.EndDate = "2024-02-01T00:00:00Z", "2024-01-01T00:00:00Z" }),
+			WantErr:     true,
+			ExpectedErr: ErrDateRange,
+		},
+		{
+			Name:    "search too long",
+			Input:   with(func(v *ListQueryValidation) { v.Search = strings.Repeat("a", SearchMaxLength+1) }),
+			WantErr: true,
+		},
+	}
+
+	runValidationTest[ListQueryValidation](t, tests)
+}
+
 func TestPasswordUpdateValidation(t *testing.T) {
 	tests := []TestCase{
 		{
@@ -1068,3 +1504,248 @@ func TestPasswordUpdateValidation(t *testing.T) {
 
 	runValidationTest[PasswordUpdateValidation](t, tests)
 }
+
+func TestRecurringTransactionValidation(t *testing.T) {
+	two := int32(2)
+	twelve := int32(12)
+	zero := int32(0)
+
+	tests := []TestCase{
+		{
+			Name: "monthly expense without an end",
+			Input: RecurringTransactionValidation{
+				Type:        "expense",
+				Amount:      money.MustParse("1200.00"),
+				Currency:    validCurrency,
+				CategoryID:  validUUID,
+				Description: "Rent",
+				Frequency:   "monthly",
+				StartDate:   validDate,
+			},
+			WantErr: false,
+		},
+		{
+			Name: "biweekly income for twelve occurrences",
+			Input: RecurringTransactionValidation{
+				Type:            "income",
+				Amount:          money.MustParse("2500.00"),
+				Currency:        validCurrency,
+				Source:          "Salary",
+				Frequency:       "weekly",
+				IntervalCount:   &two,
+				StartDate:       validDate,
+				OccurrenceCount: &twelve,
+			},
+			WantErr: false,
+		},
+		{
+			Name: "unknown type",
+			Input: RecurringTransactionValidation{
+				Type:        "transfer",
+				Amount:      money.MustParse("10.00"),
+				Currency:    validCurrency,
+				Description: validDescription,
+				Frequency:   "monthly",
+				StartDate:   validDate,
+			},
+			WantErr:     true,
+			ExpectedErr: ErrInvalidTransactionType,
+		},
+		{
+			Name: "expense without a category",
+			Input: RecurringTransactionValidation{
+				Type:        "expense",
+				Amount:      money.MustParse("10.00"),
+				Currency:    validCurrency,
+				Description: validDescription,
+				Frequency:   "monthly",
+				StartDate:   validDate,
+			},
+			WantErr:     true,
+			ExpectedErr: ErrInvalidUUID,
+		},
+		{
+			Name: "income without a source",
+			Input: RecurringTransactionValidation{
+				Type:       "income",
+				Amount:     money.MustParse("10.00"),
+				Currency:   validCurrency,
+				CategoryID: validUUID,
+				Frequency:  "monthly",
+				StartDate:  validDate,
+			},
+			WantErr:     true,
+			ExpectedErr: ErrEmptyField,
+		},
+		{
+			Name: "description too long for an expense",
+			Input: RecurringTransactionValidation{
+				Type:        "expense",
+				Amount:      money.MustParse("10.00"),
+				Currency:    validCurrency,
+				CategoryID:  validUUID,
+				Description: strings.Repeat("a", TransactionDescriptionMaxLength+1),
+				Frequency:   "monthly",
+				StartDate:   validDate,
+			},
+			WantErr: true,
+		},
+		{
+			Name: "unknown frequency",
+			Input: RecurringTransactionValidation{
+				Type:        "expense",
+				Amount:      money.MustParse("10.00"),
+				Currency:    validCurrency,
+				CategoryID:  validUUID,
+				Description: validDescription,
+				Frequency:   "hourly",
+				StartDate:   validDate,
+			},
+			WantErr:     true,
+			ExpectedErr: recurrence.ErrInvalidFrequency,
+		},
+		{
+			Name: "zero interval",
+			Input: RecurringTransactionValidation{
+				Type:          "expense",
+				Amount:        money.MustParse("10.00"),
+				Currency:      validCurrency,
+				CategoryID:    validUUID,
+				Description:   validDescription,
+				Frequency:     "daily",
+				IntervalCount: &zero,
+				StartDate:     validDate,
+			},
+			WantErr:     true,
+			ExpectedErr: ErrInvalidInterval,
+		},
+		{
+			Name: "end date and count",
+			Input: RecurringTransactionValidation{
+				Type:            "expense",
+				Amount:          money.MustParse("10.00"),
+				Currency:        validCurrency,
+				CategoryID:      validUUID,
+				Description:     validDescription,
+				Frequency:       "monthly",
+				StartDate:       validDate,
+				EndDate:         "2023-12-31T00:00:00Z",
+				OccurrenceCount: &twelve,
+			},
+			WantErr:     true,
+			ExpectedErr: ErrEndCondition,
+		},
+		{
+			Name: "ends before it starts",
+			Input: RecurringTransactionValidation{
+				Type:        "expense",
+				Amount:      money.MustParse("10.00"),
+				Currency:    validCurrency,
+				CategoryID:  validUUID,
+				Description: validDescription,
+				Frequency:   "monthly",
+				StartDate:   validDate,
+				EndDate:     "2022-12-31T00:00:00Z",
+			},
+			WantErr:     true,
+			ExpectedErr: ErrDateRange,
+		},
+		{
+			Name: "zero occurrences",
+			Input: RecurringTransactionValidation{
+				Type:            "expense",
+				Amount:          money.MustParse("10.00"),
+				Currency:        validCurrency,
+				CategoryID:      validUUID,
+				Description:     validDescription,
+				Frequency:       "monthly",
+				StartDate:       validDate,
+				OccurrenceCount: &zero,
+			},
+			WantErr:     true,
+			ExpectedErr: ErrInvalidOccurrenceCount,
+		},
+	}
+
+	runValidationTest[RecurringTransactionValidation](t, tests)
+}
+
+func TestRecurringTransactionValidation_ValidatePartialUpdate(t *testing.T) {
+	end := testTime.AddDate(1, 0, 0)
+	six := int32(6)
+
+	current := RecurringTemplate{
+		Type:          "expense",
+		Amount:        money.MustParse("1200.50"),
+		Currency:      "USD",
+		CategoryID:    validUUID,
+		Description:   "Rent",
+		Frequency:     recurrence.FreqMonthly,
+		IntervalCount: 1,
+		StartDate:     testTime,
+		EndDate:       &end,
+	}
+
+	tests := []struct {
+		name    string
+		update  RecurringTransactionValidation
+		want    RecurringTemplate
+		wantErr error
+	}{
+		{
+			name:   "amount",
+			update: RecurringTransactionValidation{Amount: money.MustParse("1250.00"), IsPartialUpdate: true},
+			want: func() RecurringTemplate {
+				want := current
+				want.Amount = money.MustParse("1250.00")
+				return want
+			}(),
+		},
+		{
+			name:   "count replaces the end date",
+			update: RecurringTransactionValidation{OccurrenceCount: &six, IsPartialUpdate: true},
+			want: func() RecurringTemplate {
+				want := current
+				want.EndDate = nil
+				want.OccurrenceCount = &six
+				return want
+			}(),
+		},
+		{
+			name:   "source is ignored on an expense",
+			update: RecurringTransactionValidation{Source: "Landlord", Frequency: "yearly", IsPartialUpdate: true},
+			want: func() RecurringTemplate {
+				want := current
+				want.Frequency = recurrence.FreqYearly
+				return want
+			}(),
+		},
+		{
+			name:    "type change",
+			update:  RecurringTransactionValidation{Type: "income", IsPartialUpdate: true},
+			wantErr: ErrTransactionTypeChange,
+		},
+		{
+			name:    "start after the end",
+			update:  RecurringTransactionValidation{StartDate: "2025-01-01T00:00:00Z", IsPartialUpdate: true},
+			wantErr: ErrDateRange,
+		},
+		{
+			name:    "amount too precise for the currency",
+			update:  RecurringTransactionValidation{Currency: "JPY", IsPartialUpdate: true},
+			wantErr: ErrAmountPrecision,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.update.ValidatePartialUpdate(current)
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	currencyValidator "github.com/bojanz/currency"
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 )
 
 // Common validation errors
@@ -13,16 +14,35 @@ var (
 	ErrEmptyField      = fmt.Errorf("field cannot be empty")
 	ErrInvalidAmount   = fmt.Errorf("amount must be greater than 0")
 	ErrInvalidCurrency = fmt.Errorf("invalid currency code")
+	ErrAmountPrecision = fmt.Errorf("amount has more decimal places than the currency allows")
 	ErrInvalidUUID     = fmt.Errorf("invalid UUID")
 	ErrInvalidDate     = fmt.Errorf("invalid date format")
 	ErrDateRange       = fmt.Errorf("end date must be after start date")
 	ErrInvalidLimit    = fmt.Errorf("limit must be between 1 and 1000")
 	ErrDateRangeYear   = fmt.Errorf("date range must not exceed 1 year")
+	ErrInvalidRate     = fmt.Errorf("rate must be greater than 0")
+	ErrSameCurrency    = fmt.Errorf("base and quote currency must differ")
+	ErrCadenceRequired = fmt.Errorf("recurring budgets need a cadence")
+	ErrEndDateRequired = fmt.Errorf("one-time budgets need an end date")
+	ErrNotRecurring    = fmt.Errorf("cadence and rollover only apply to recurring budgets")
+
+	ErrInvalidTransactionType = fmt.Errorf("type must be either 'expense' or 'income'")
+	ErrTransactionTypeChange  = fmt.Errorf("the type of a recurring transaction cannot be changed")
+	ErrInvalidInterval        = fmt.Errorf("interval must be between 1 and 1000")
+	ErrInvalidOccurrenceCount = fmt.Errorf("occurrence count must be greater than 0")
+	ErrEndCondition           = fmt.Errorf("a recurring transaction can end on a date or after a number of occurrences, not both")
+
+	ErrInvalidSortOrder = fmt.Errorf("order must be either 'asc' or 'desc'")
+	ErrAmountRange      = fmt.Errorf("min_amount must not exceed max_amount")
+
+	ErrRuleCondition       = fmt.Errorf("a rule needs a description_pattern, min_amount, max_amount or currency to match on")
+	ErrRuleAction          = fmt.Errorf("a rule needs a category_id or tag_ids to set")
+	ErrNegativeAmountRange = fmt.Errorf("min_amount and max_amount must not be negative")
 )
 
 // MoneyValidator validates amount and currency
 type MoneyValidator struct {
-	Amount   float64
+	Amount   money.Amount
 	Currency string
 }
 
@@ -34,15 +54,33 @@ const (
 	EmailMinLength        = 5
 	PasswordMaxLength     = 100
 	PasswordMinLength     = 6
+
+	// TransactionDescriptionMaxLength is the longest description the
+	// expenses and income tables hold
+	TransactionDescriptionMaxLength = 510
+	RecurrenceIntervalMax           = 1000
+
+	// ExternalIDMaxLength is the longest ID a client or bank may give a
+	// transaction
+	ExternalIDMaxLength = 255
+
+	// SearchMaxLength is the longest text a list can be searched for
+	SearchMaxLength = 100
+
+	CategoryRuleNameMaxLength    = 100
+	CategoryRulePatternMaxLength = 255
 )
 
 func (m *MoneyValidator) Validate() error {
-	if m.Amount <= 0 {
+	if !m.Amount.IsPositive() {
 		return ErrInvalidAmount
 	}
 	if m.Currency == "" || !currencyValidator.IsValid(m.Currency) {
 		return ErrInvalidCurrency
 	}
+	if !m.Amount.FitsCurrency(m.Currency) {
+		return ErrAmountPrecision
+	}
 	return nil
 }
 
@@ -5,26 +5,32 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestMoneyValidatorValidate(t *testing.T) {
 	tests := []struct {
 		name     string
-		amount   float64
+		amount   string
 		currency string
 		wantErr  error
 	}{
-		{"valid money", 100.00, "USD", nil},
-		{"zero amount", 0, "USD", ErrInvalidAmount},
-		{"negative amount", -100.00, "USD", ErrInvalidAmount},
-		{"invalid currency", 100.00, "XXX", ErrInvalidCurrency},
-		{"empty currency", 100.00, "", ErrInvalidCurrency},
+		{"valid money", "100.00", "USD", nil},
+		{"zero amount", "0", "USD", ErrInvalidAmount},
+		{"negative amount", "-100.00", "USD", ErrInvalidAmount},
+		{"invalid currency", "100.00", "XXX", ErrInvalidCurrency},
+		{"empty currency", "100.00", "", ErrInvalidCurrency},
+		{"cents", "0.01", "USD", nil},
+		{"fraction of a cent", "100.005", "USD", ErrAmountPrecision},
+		{"whole yen", "1500", "JPY", nil},
+		{"fraction of a yen", "1500.5", "JPY", ErrAmountPrecision},
+		{"three place dinar", "1.234", "KWD", nil},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			v := &MoneyValidator{Amount: tt.amount, Currency: tt.currency}
+			v := &MoneyValidator{Amount: money.MustParse(tt.amount), Currency: tt.currency}
 			err := v.Validate()
 			assert.Equal(t, tt.wantErr, err)
 		})
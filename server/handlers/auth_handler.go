@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/jorge-dev/centsible/internal/auth"
 	"github.com/jorge-dev/centsible/internal/repository"
 	"github.com/jorge-dev/centsible/internal/validation"
+	"github.com/jorge-dev/centsible/server/middleware"
 )
 
 type AuthHandler struct {
@@ -17,10 +19,14 @@ type AuthHandler struct {
 	jwtManager *auth.JWTManager
 }
 
+// RegisterRequest creates an account. CategoryTemplate names the template
+// the account's categories come from, "none" for no categories; without it the
+// default template of the client's Accept-Language is used.
 type RegisterRequest struct {
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Name             string `json:"name"`
+	Email            string `json:"email"`
+	Password         string `json:"password"`
+	CategoryTemplate string `json:"category_template"`
 }
 
 type LoginRequest struct {
@@ -28,6 +34,10 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 type AuthUser struct {
 	ID    string `json:"id"`
 	Name  string `json:"name"`
@@ -65,6 +75,19 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Pick the template for the account's first categories
+	templates, err := h.db.ListCategoryTemplates(r.Context())
+	if err != nil {
+		log.Printf("Error listing category templates: %v", err)
+		http.Error(w, "Error processing request", http.StatusInternalServerError)
+		return
+	}
+	templateKey, ok := registrationTemplate(templates, req.CategoryTemplate, r.Header.Get("Accept-Language"))
+	if !ok {
+		http.Error(w, "Unknown category template", http.StatusBadRequest)
+		return
+	}
+
 	// Check if email already exists
 	emailExists, err := h.db.CheckEmailExists(r.Context(), req.Email)
 	if err != nil {
@@ -83,13 +106,14 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create user
+	// Create user along with the template's categories
 	userID := uuid.New()
-	user, err := h.db.CreateUser(r.Context(), repository.CreateUserParams{
+	user, err := h.db.RegisterUser(r.Context(), repository.RegisterUserParams{
 		ID:           userID,
 		Name:         req.Name,
 		Email:        req.Email,
 		PasswordHash: hashedPassword,
+		TemplateKey:  templateKey,
 	})
 	if err != nil {
 		log.Printf("Error creating user: %v", err)
@@ -104,7 +128,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate JWT pair
-	tokenPair, err := h.jwtManager.GenerateTokenPair(user.ID.String(), user.Email, user.RoleID.String())
+	tokenPair, err := h.jwtManager.StartSession(user.ID.String(), user.Email, user.RoleID.String(), deviceInfo(r))
 	if err != nil {
 		http.Error(w, "Error generating tokens", http.StatusInternalServerError)
 		return
@@ -162,7 +186,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate JWT pair
-	tokenPair, err := h.jwtManager.GenerateTokenPair(user.ID.String(), user.Email, user.RoleID.String())
+	tokenPair, err := h.jwtManager.StartSession(user.ID.String(), user.Email, user.RoleID.String(), deviceInfo(r))
 	if err != nil {
 		http.Error(w, "Error generating tokens", http.StatusInternalServerError)
 		return
@@ -182,6 +206,62 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// Refresh handles POST /refresh and rotates the presented refresh token
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.RefreshToken == "" {
+		http.Error(w, "Refresh token is required", http.StatusBadRequest)
+		return
+	}
+
+	tokenPair, err := h.jwtManager.RefreshToken(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrRefreshTokenReused) || errors.Is(err, auth.ErrTokenFamilyRevoked) {
+			http.Error(w, "Refresh token has been revoked, please login again", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	// Read the user back from the freshly issued access token to build the response body
+	claims, err := h.jwtManager.ValidateToken(tokenPair.AccessToken)
+	if err != nil {
+		http.Error(w, "Error generating tokens", http.StatusInternalServerError)
+		return
+	}
+
+	uid, err := validation.ValidateUUID(claims.UserID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.db.GetUserByID(r.Context(), uid)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	response := AuthResponse{
+		TokenPair: *tokenPair,
+		User: AuthUser{
+			ID:    user.ID.String(),
+			Name:  user.Name,
+			Email: user.Email,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
+
 func (h *AuthHandler) Signout(w http.ResponseWriter, r *http.Request) {
 	// Get token from Authorization header
 	token := r.Header.Get("Authorization")
@@ -204,3 +284,11 @@ func (h *AuthHandler) Signout(w http.ResponseWriter, r *http.Request) {
 		"message": "Successfully signed out",
 	})
 }
+
+// deviceInfo describes the client making the request for session listings
+func deviceInfo(r *http.Request) auth.DeviceInfo {
+	return auth.DeviceInfo{
+		UserAgent: r.UserAgent(),
+		IPAddress: middleware.ClientIP(r),
+	}
+}
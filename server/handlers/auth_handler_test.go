@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -173,6 +174,54 @@ func TestRegister(t *testing.T) {
 	}
 }
 
+func TestRegisterCategoryTemplate(t *testing.T) {
+	suite := setupAuthHandlerTest(t)
+	addCategoryTemplates(suite.mockRepo)
+
+	tests := []struct {
+		name           string
+		template       string
+		acceptLanguage string
+		wantStatus     int
+		wantCategories []string
+	}{
+		{"Default template", "", "", http.StatusOK, []string{"Food", "Groceries", "Transport"}},
+		{"Default of the client's language", "", "es-ES,es;q=0.9", http.StatusOK, []string{"Comida", "Transporte"}},
+		{"Requested template", "household", "es-ES", http.StatusOK, []string{"Food", "Home", "Utilities"}},
+		{"No categories", "none", "", http.StatusOK, []string{}},
+		{"Unknown template", "travel", "", http.StatusBadRequest, nil},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(RegisterRequest{
+				Name:             "New User",
+				Email:            fmt.Sprintf("new%d@example.com", i),
+				Password:         "password123",
+				CategoryTemplate: tt.template,
+			})
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/register", bytes.NewBuffer(body))
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			w := httptest.NewRecorder()
+
+			suite.handler.Register(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var response AuthResponse
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+			categories, _ := suite.mockRepo.ListCategories(context.Background(), uuid.MustParse(response.User.ID))
+			names := []string{}
+			for _, category := range categories {
+				names = append(names, category.Name)
+			}
+			assert.ElementsMatch(t, tt.wantCategories, names)
+		})
+	}
+}
+
 func TestLogin(t *testing.T) {
 	suite := setupAuthHandlerTest(t)
 
@@ -254,6 +303,38 @@ func TestLogin(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("Records the device", func(t *testing.T) {
+		body, _ := json.Marshal(LoginRequest{
+			Email:    suite.testUser.Email,
+			Password: suite.testUser.Password,
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "centsible-ios/1.0")
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, suite.testUser.ID.String()))
+		w := httptest.NewRecorder()
+
+		suite.handler.Login(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response AuthResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.NotEmpty(t, response.TokenPair.SessionID)
+
+		sessions, err := suite.jwtManager.ListSessions(suite.testUser.ID.String())
+		assert.NoError(t, err)
+		var found bool
+		for _, session := range sessions {
+			if session.ID == response.TokenPair.SessionID {
+				found = true
+				assert.Equal(t, "centsible-ios/1.0", session.UserAgent)
+				assert.Equal(t, "203.0.113.7", session.IPAddress)
+			}
+		}
+		assert.True(t, found, "login should start a session for the device")
+	})
 }
 
 func TestSignout(t *testing.T) {
@@ -312,3 +393,117 @@ func TestSignout(t *testing.T) {
 		})
 	}
 }
+
+func TestRefresh(t *testing.T) {
+	suite := setupAuthHandlerTest(t)
+
+	newTokenPair := func() *auth.TokenPair {
+		tokenPair, err := suite.jwtManager.GenerateTokenPair(
+			suite.testUser.ID.String(),
+			suite.testUser.Email,
+			suite.testUser.RoleID.String(),
+		)
+		if err != nil {
+			t.Fatal("failed to generate token pair")
+		}
+		return tokenPair
+	}
+
+	doRefresh := func(refreshToken string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(RefreshRequest{RefreshToken: refreshToken})
+		req := httptest.NewRequest(http.MethodPost, "/refresh", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		suite.handler.Refresh(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name       string
+		setupToken func() string
+		wantStatus int
+	}{
+		{
+			name: "Valid refresh token",
+			setupToken: func() string {
+				return newTokenPair().RefreshToken
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "Missing refresh token",
+			setupToken: func() string {
+				return ""
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Invalid refresh token",
+			setupToken: func() string {
+				return "invalid.token.here"
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "Access token used as refresh token",
+			setupToken: func() string {
+				return newTokenPair().AccessToken
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "Already rotated refresh token",
+			setupToken: func() string {
+				refreshToken := newTokenPair().RefreshToken
+				if w := doRefresh(refreshToken); w.Code != http.StatusOK {
+					t.Fatalf("first refresh failed with status %d", w.Code)
+				}
+				return refreshToken
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doRefresh(tt.setupToken())
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				var response AuthResponse
+				err := json.NewDecoder(w.Body).Decode(&response)
+				assert.NoError(t, err)
+				assert.NotEmpty(t, response.TokenPair.AccessToken)
+				assert.NotEmpty(t, response.TokenPair.RefreshToken)
+				assert.Equal(t, suite.testUser.ID.String(), response.User.ID)
+				assert.Equal(t, suite.testUser.Name, response.User.Name)
+				assert.Equal(t, suite.testUser.Email, response.User.Email)
+			}
+		})
+	}
+
+	t.Run("Reuse revokes the token family", func(t *testing.T) {
+		original := newTokenPair().RefreshToken
+
+		w := doRefresh(original)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var rotated AuthResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&rotated))
+
+		// Replaying the original token is detected as reuse
+		w = doRefresh(original)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		// The legitimately rotated token is now revoked too
+		w = doRefresh(rotated.TokenPair.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		tokenPair, err := suite.jwtManager.GenerateTokenPair(uuid.New().String(), "ghost@example.com", uuid.New().String())
+		assert.NoError(t, err)
+
+		w := doRefresh(tokenPair.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
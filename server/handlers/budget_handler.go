@@ -1,13 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"log"
+	"math/big"
 	"net/http"
+	"sort"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/recurrence"
 	"github.com/jorge-dev/centsible/internal/repository"
 	"github.com/jorge-dev/centsible/internal/validation"
 	"github.com/jorge-dev/centsible/server/middleware"
@@ -18,13 +24,183 @@ type BudgetHandler struct {
 }
 
 type CreateBudgetRequest struct {
-	Amount     float64   `json:"amount"`
-	Currency   string    `json:"currency"`
-	CategoryID uuid.UUID `json:"category_id"`
-	Type       string    `json:"type"` // "recurring" or "one-time"
-	StartDate  string    `json:"start_date"`
-	EndDate    string    `json:"end_date"`
-	Name       string    `json:"name"`
+	Amount     money.Amount `json:"amount"`
+	Currency   string       `json:"currency"`
+	CategoryID uuid.UUID    `json:"category_id"`
+	Type       string       `json:"type"`    // "recurring" or "one-time"
+	Cadence    string       `json:"cadence"` // How often a recurring budget repeats
+	Rollover   *bool        `json:"rollover"`
+	StartDate  string       `json:"start_date"`
+	EndDate    string       `json:"end_date"`
+	Name       string       `json:"name"`
+}
+
+// CurrencySpend is the part of a budget's spending made in one currency.
+// ConvertedAmount is in the budget's currency and is null when no exchange
+// rate was available.
+type CurrencySpend struct {
+	Currency        string        `json:"currency"`
+	Amount          money.Amount  `json:"amount"`
+	ConvertedAmount *money.Amount `json:"converted_amount"`
+}
+
+// BudgetPeriod describes where spending stands in one period of a budget. A
+// recurring budget starts a new period at every step of its cadence; a one-time
+// budget has a single period covering its window. AvailableAmount adds what was
+// left of earlier periods to the budgeted amount when the budget rolls over.
+// RemainingAmount is negative once the period is overspent, and ProjectedSpend
+// extrapolates the spending rate so far to the end of the period.
+type BudgetPeriod struct {
+	Index           int          `json:"index"`
+	StartDate       time.Time    `json:"start_date"`
+	EndDate         time.Time    `json:"end_date"`
+	BudgetedAmount  money.Amount `json:"budgeted_amount"`
+	CarriedOver     money.Amount `json:"carried_over"`
+	AvailableAmount money.Amount `json:"available_amount"`
+	SpentAmount     money.Amount `json:"spent_amount"`
+	RemainingAmount money.Amount `json:"remaining_amount"`
+	UsagePercentage float64      `json:"usage_percentage"`
+	DaysLeft        int          `json:"days_left"`
+	ProjectedSpend  money.Amount `json:"projected_spend"`
+}
+
+type BudgetUsageResponse struct {
+	Budget          repository.Budget `json:"budget"`
+	SpentAmount     money.Amount      `json:"spent_amount"`
+	UsagePercentage float64           `json:"usage_percentage"`
+	SpentByCurrency []CurrencySpend   `json:"spent_by_currency"`
+	MissingRates    []string          `json:"missing_rates"`
+	Period          BudgetPeriod      `json:"period"`
+}
+
+// BudgetAlert is an active budget whose current period has reached the alert
+// threshold
+type BudgetAlert struct {
+	Budget          repository.Budget `json:"budget"`
+	SpentAmount     money.Amount      `json:"spent_amount"`
+	UsagePercentage float64           `json:"usage_percentage"`
+	Period          BudgetPeriod      `json:"period"`
+}
+
+// budgetSchedule returns the schedule a budget's periods follow
+func budgetSchedule(budget repository.Budget) recurrence.Schedule {
+	schedule := recurrence.Schedule{
+		Start: budget.StartDate,
+		Until: budget.EndDate,
+	}
+	if budget.Cadence != nil {
+		schedule.Cadence = *budget.Cadence
+	}
+	return schedule
+}
+
+// budgetPeriodsToLoad returns the periods of a budget whose spending is needed
+// to work out where its current period stands. That is every period so far when
+// unspent money rolls over, otherwise only the current one.
+func budgetPeriodsToLoad(budget repository.Budget, now time.Time) []recurrence.Period {
+	periods := budgetSchedule(budget).Periods(now)
+	if !budget.Rollover {
+		periods = periods[len(periods)-1:]
+	}
+	return periods
+}
+
+// spendingKey identifies one period of one budget
+type spendingKey struct {
+	budgetID uuid.UUID
+	start    int64 // Unix microseconds, the precision Postgres keeps
+}
+
+func newSpendingKey(budgetID uuid.UUID, start time.Time) spendingKey {
+	return spendingKey{budgetID: budgetID, start: start.UnixMicro()}
+}
+
+// budgetPeriod works out where spending stands in a period of a budget as of now
+func budgetPeriod(budget repository.Budget, p recurrence.Period, carried, spent money.Amount, now time.Time) BudgetPeriod {
+	available := budget.Amount.Add(carried)
+	period := BudgetPeriod{
+		Index:           p.Index,
+		StartDate:       p.Start,
+		EndDate:         p.End,
+		BudgetedAmount:  budget.Amount,
+		CarriedOver:     carried,
+		AvailableAmount: available,
+		SpentAmount:     spent,
+		RemainingAmount: available.Sub(spent),
+		ProjectedSpend:  spent,
+	}
+	if available.IsPositive() {
+		period.UsagePercentage = spent.Float64() / available.Float64() * 100
+	}
+
+	switch {
+	case !now.Before(p.End):
+		// The period is over, so what was spent is final
+	case !now.After(p.Start):
+		period.DaysLeft = daysUntil(p.Start, p.End)
+	default:
+		period.DaysLeft = daysUntil(now, p.End)
+		// A few hours of spending says little about the rest of the period
+		elapsed := now.Sub(p.Start)
+		if elapsed >= 24*time.Hour {
+			length := p.End.Sub(p.Start)
+			period.ProjectedSpend = spent.MulRat(big.NewRat(int64(length), int64(elapsed))).Round(budget.Currency)
+		}
+	}
+
+	return period
+}
+
+// budgetPeriods works out each of the given consecutive periods of a budget.
+// When the budget rolls over, whatever is left at the end of a period is added
+// to the next one; an overspent period carries nothing, rather than a debt.
+func budgetPeriods(budget repository.Budget, periods []recurrence.Period, spent map[spendingKey]money.Amount, now time.Time) []BudgetPeriod {
+	result := make([]BudgetPeriod, 0, len(periods))
+	var carried money.Amount
+	for _, p := range periods {
+		period := budgetPeriod(budget, p, carried, spent[newSpendingKey(budget.ID, p.Start)], now)
+		result = append(result, period)
+
+		carried = money.Amount{}
+		if budget.Rollover && period.RemainingAmount.IsPositive() {
+			carried = period.RemainingAmount
+		}
+	}
+	return result
+}
+
+// daysUntil counts the days from one time to another, with part of a day
+// counting as a whole one
+func daysUntil(from, to time.Time) int {
+	const day = 24 * time.Hour
+	return int((to.Sub(from) + day - 1) / day)
+}
+
+// loadSpending fetches what was spent in the given periods of each budget with
+// a single query
+func (h *BudgetHandler) loadSpending(ctx context.Context, userID uuid.UUID, periods map[uuid.UUID][]recurrence.Period) (map[spendingKey]money.Amount, error) {
+	params := repository.GetBudgetSpendingByPeriodParams{UserID: userID}
+	for budgetID, budgetPeriods := range periods {
+		for _, p := range budgetPeriods {
+			params.BudgetIds = append(params.BudgetIds, budgetID)
+			params.PeriodStarts = append(params.PeriodStarts, p.Start)
+			params.PeriodEnds = append(params.PeriodEnds, p.End)
+		}
+	}
+	if len(params.BudgetIds) == 0 {
+		return nil, nil
+	}
+
+	rows, err := h.db.GetBudgetSpendingByPeriod(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	spent := make(map[spendingKey]money.Amount, len(rows))
+	for _, row := range rows {
+		spent[newSpendingKey(row.BudgetID, row.PeriodStart)] = row.SpentAmount
+	}
+	return spent, nil
 }
 
 func NewBudgetHandler(db repository.Repository) *BudgetHandler {
@@ -43,6 +219,8 @@ func (h *BudgetHandler) CreateBudget(w http.ResponseWriter, r *http.Request) {
 		Currency:   req.Currency,
 		CategoryID: req.CategoryID,
 		Type:       req.Type,
+		Cadence:    req.Cadence,
+		Rollover:   req.Rollover,
 		StartDate:  req.StartDate,
 		EndDate:    req.EndDate,
 		Name:       req.Name,
@@ -61,7 +239,16 @@ func (h *BudgetHandler) CreateBudget(w http.ResponseWriter, r *http.Request) {
 	}
 
 	startDate, _ := validation.ValidateDate(req.StartDate)
-	endDate, _ := validation.ValidateDate(req.EndDate)
+	var endDate *time.Time
+	if req.EndDate != "" {
+		parsed, _ := validation.ValidateDate(req.EndDate)
+		endDate = &parsed
+	}
+	var cadence *recurrence.Cadence
+	if req.Cadence != "" {
+		parsed, _ := recurrence.ParseCadence(req.Cadence)
+		cadence = &parsed
+	}
 
 	budget, err := h.db.CreateBudget(r.Context(), repository.CreateBudgetParams{
 		ID:         uuid.New(),
@@ -70,6 +257,8 @@ func (h *BudgetHandler) CreateBudget(w http.ResponseWriter, r *http.Request) {
 		Currency:   req.Currency,
 		CategoryID: req.CategoryID,
 		Type:       req.Type,
+		Cadence:    cadence,
+		Rollover:   req.Rollover != nil && *req.Rollover,
 		StartDate:  startDate,
 		EndDate:    endDate,
 		Name:       req.Name,
@@ -84,6 +273,8 @@ func (h *BudgetHandler) CreateBudget(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(budget)
 }
 
+// GetBudgetUsage handles GET /budgets/{id}, reporting on the budget's
+// current period
 func (h *BudgetHandler) GetBudgetUsage(w http.ResponseWriter, r *http.Request) {
 	budgetID := chi.URLParam(r, "id")
 	bid, err := validation.ValidateUUID(budgetID)
@@ -99,9 +290,24 @@ func (h *BudgetHandler) GetBudgetUsage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	budget, err := h.db.GetBudgetByID(r.Context(), repository.GetBudgetByIDParams{
+		ID:     bid,
+		UserID: uid,
+	})
+	if err != nil {
+		http.Error(w, "Budget not found", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	periods := budgetPeriodsToLoad(budget, now)
+	current := periods[len(periods)-1]
+
 	usage, err := h.db.GetBudgetUsage(r.Context(), repository.GetBudgetUsageParams{
-		BudgetID: bid,
-		UserID:   uid,
+		BudgetID:    bid,
+		UserID:      uid,
+		PeriodStart: current.Start,
+		PeriodEnd:   current.End,
 	})
 	if err != nil {
 		log.Println(err)
@@ -109,8 +315,102 @@ func (h *BudgetHandler) GetBudgetUsage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Earlier periods are only loaded to work out what rolls over
+	spent, err := h.loadSpending(r.Context(), uid, map[uuid.UUID][]recurrence.Period{bid: periods[:len(periods)-1]})
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error getting budget usage", http.StatusInternalServerError)
+		return
+	}
+	if spent == nil {
+		spent = make(map[spendingKey]money.Amount, 1)
+	}
+	spent[newSpendingKey(bid, current.Start)] = usage.SpentAmount
+	history := budgetPeriods(usage.Budget, periods, spent, now)
+	period := history[len(history)-1]
+
+	var spentByCurrency []CurrencySpend
+	if err := json.Unmarshal(usage.SpentByCurrency, &spentByCurrency); err != nil {
+		http.Error(w, "Error processing budget usage", http.StatusInternalServerError)
+		return
+	}
+
+	response := BudgetUsageResponse{
+		Budget:          usage.Budget,
+		SpentAmount:     usage.SpentAmount,
+		UsagePercentage: period.UsagePercentage,
+		SpentByCurrency: spentByCurrency,
+		MissingRates:    usage.MissingRates,
+		Period:          period,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetBudgetPeriods handles GET /budgets/{id}/periods, listing the budget's
+// periods so far, newest first
+func (h *BudgetHandler) GetBudgetPeriods(w http.ResponseWriter, r *http.Request) {
+	budgetID := chi.URLParam(r, "id")
+	bid, err := validation.ValidateUUID(budgetID)
+	if err != nil {
+		http.Error(w, "Invalid budget ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	limit := int32(12)
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.ParseInt(limitStr, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid limit value", http.StatusBadRequest)
+			return
+		}
+		limit = int32(parsedLimit)
+	}
+	if err := (&validation.PaginationValidator{Limit: limit}).Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	budget, err := h.db.GetBudgetByID(r.Context(), repository.GetBudgetByIDParams{
+		ID:     bid,
+		UserID: uid,
+	})
+	if err != nil {
+		http.Error(w, "Budget not found", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	periods := budgetSchedule(budget).Periods(now)
+	// Without rollover each period stands alone, so only the ones returned
+	// need loading
+	if !budget.Rollover && len(periods) > int(limit) {
+		periods = periods[len(periods)-int(limit):]
+	}
+
+	spent, err := h.loadSpending(r.Context(), uid, map[uuid.UUID][]recurrence.Period{bid: periods})
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error getting budget periods", http.StatusInternalServerError)
+		return
+	}
+
+	history := budgetPeriods(budget, periods, spent, now)
+	response := make([]BudgetPeriod, 0, min(len(history), int(limit)))
+	for i := len(history) - 1; i >= 0 && len(response) < int(limit); i-- {
+		response = append(response, history[i])
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(usage)
+	json.NewEncoder(w).Encode(response)
 }
 
 func (h *BudgetHandler) GetBudgetsNearLimit(w http.ResponseWriter, r *http.Request) {
@@ -141,24 +441,51 @@ func (h *BudgetHandler) GetBudgetsNearLimit(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Get budgets that are at 80% or more of their limit
-	alerts, err := h.db.GetBudgetsNearLimit(r.Context(), repository.GetBudgetsNearLimitParams{
-		UserID:    uid,
-		Threshold: threshold, // Alert threshold percentage
-	})
+	budgets, err := h.db.GetActiveBudgets(r.Context(), uid)
 	if err != nil {
 		log.Println(err)
 		http.Error(w, "Error getting budget alerts", http.StatusInternalServerError)
 		return
 	}
 
-	if len(alerts) == 0 {
-		alerts = []repository.GetBudgetsNearLimitRow{}
+	now := time.Now()
+	periods := make(map[uuid.UUID][]recurrence.Period, len(budgets))
+	for _, budget := range budgets {
+		periods[budget.ID] = budgetPeriodsToLoad(budget, now)
 	}
+	spent, err := h.loadSpending(r.Context(), uid, periods)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error getting budget alerts", http.StatusInternalServerError)
+		return
+	}
+
+	// Budgets at the threshold (80% by default) or more of what their current
+	// period has available
+	alerts := []BudgetAlert{}
+	for _, budget := range budgets {
+		history := budgetPeriods(budget, periods[budget.ID], spent, now)
+		period := history[len(history)-1]
+		if period.UsagePercentage >= threshold {
+			alerts = append(alerts, BudgetAlert{
+				Budget:          budget,
+				SpentAmount:     period.SpentAmount,
+				UsagePercentage: period.UsagePercentage,
+				Period:          period,
+			})
+		}
+	}
+	sort.SliceStable(alerts, func(i, j int) bool {
+		return alerts[i].UsagePercentage > alerts[j].UsagePercentage
+	})
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(alerts)
 }
 
+// ListBudgets handles GET /budgets, most recently created first unless sorted
+// otherwise. Budgets are dated by their start date and searched by name; see
+// parseListQuery for paging, sorting and filtering.
 func (h *BudgetHandler) ListBudgets(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.UserIDKey).(string)
 	uid, err := validation.ValidateUUID(userID)
@@ -167,14 +494,36 @@ func (h *BudgetHandler) ListBudgets(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	budgets, err := h.db.ListBudgets(r.Context(), uid)
+	q, err := parseListQuery(r.URL.Query(), budgetSorts, "desc")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	budgets, err := h.db.ListBudgetsPage(r.Context(), repository.ListBudgetsPageParams{
+		UserID:      uid,
+		MinAmount:   q.minAmount,
+		MaxAmount:   q.maxAmount,
+		Currency:    q.currency,
+		CategoryID:  q.categoryID,
+		Search:      q.search,
+		StartDate:   q.startDate,
+		EndDate:     q.endDate,
+		AfterID:     q.afterID(),
+		Sort:        q.sort,
+		Descending:  q.fetchDescending(),
+		AfterAmount: q.afterAmount(),
+		AfterTime:   q.afterTime(),
+		PageSize:    q.pageSize(),
+	})
 	if err != nil {
 		http.Error(w, "Error listing budgets", http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(budgets)
+	writeJSON(w, http.StatusOK, paginate(q, budgets, func(budget repository.Budget) listCursor {
+		return q.cursorAt(budget.Amount, budget.CreatedAt, budget.StartDate, "", budget.ID)
+	}))
 }
 
 func (h *BudgetHandler) UpdateBudget(w http.ResponseWriter, r *http.Request) {
@@ -212,6 +561,8 @@ func (h *BudgetHandler) UpdateBudget(w http.ResponseWriter, r *http.Request) {
 		Currency:        req.Currency,
 		CategoryID:      req.CategoryID,
 		Type:            req.Type,
+		Cadence:         req.Cadence,
+		Rollover:        req.Rollover,
 		StartDate:       req.StartDate,
 		EndDate:         req.EndDate,
 		Name:            req.Name,
@@ -228,8 +579,11 @@ func (h *BudgetHandler) UpdateBudget(w http.ResponseWriter, r *http.Request) {
 		Currency:   currentBudget.Currency,
 		CategoryID: currentBudget.CategoryID,
 		Type:       currentBudget.Type,
+		Cadence:    currentBudget.Cadence,
+		Rollover:   currentBudget.Rollover,
 		StartDate:  currentBudget.StartDate,
 		EndDate:    currentBudget.EndDate,
+		Name:       currentBudget.Name,
 	}
 
 	validated, err := validator.ValidatePartialUpdate(current)
@@ -245,6 +599,8 @@ func (h *BudgetHandler) UpdateBudget(w http.ResponseWriter, r *http.Request) {
 		Currency:   validated.Currency,
 		CategoryID: validated.CategoryID,
 		Type:       validated.Type,
+		Cadence:    validated.Cadence,
+		Rollover:   validated.Rollover,
 		StartDate:  validated.StartDate,
 		EndDate:    validated.EndDate,
 		Name:       validated.Name,
@@ -13,6 +13,8 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/recurrence"
 	"github.com/jorge-dev/centsible/internal/repository"
 	"github.com/jorge-dev/centsible/internal/repository/mocks"
 	"github.com/jorge-dev/centsible/server/middleware"
@@ -48,15 +50,19 @@ func setupBudgetHandlerTest(t *testing.T) *budgetHandlerTestSuite {
 
 	// Setup test data
 	suite.testUser.ID = uuid.New()
+	monthly := recurrence.Monthly
+	start := time.Now()
+	end := start.AddDate(0, 3, 0)
 	suite.testBudget = repository.Budget{
 		ID:         uuid.New(),
 		UserID:     suite.testUser.ID,
-		Amount:     1000.00,
+		Amount:     money.MustParse("1000.00"),
 		Currency:   "USD",
 		CategoryID: uuid.New(),
 		Type:       "recurring",
-		StartDate:  time.Now(),
-		EndDate:    time.Now().AddDate(0, 1, 0),
+		Cadence:    &monthly,
+		StartDate:  start,
+		EndDate:    &end,
 		CreatedAt:  time.Now(),
 		Name:       "Test Budget", // Add name field
 	}
@@ -78,20 +84,45 @@ func TestCreateBudget(t *testing.T) {
 		{
 			name: "Valid budget creation",
 			reqBody: CreateBudgetRequest{
-				Amount:     1000.00,
+				Amount:     money.MustParse("1000.00"),
 				Currency:   "USD",
 				CategoryID: uuid.New(),
 				Type:       "recurring",
+				Cadence:    "monthly",
 				StartDate:  time.Now().Format(time.RFC3339),
-				EndDate:    time.Now().AddDate(0, 1, 0).Format(time.RFC3339),
 				Name:       "Monthly Groceries",
 			},
 			wantStatus: http.StatusCreated,
 		},
+		{
+			name: "Valid one-time budget",
+			reqBody: CreateBudgetRequest{
+				Amount:     money.MustParse("1000.00"),
+				Currency:   "USD",
+				CategoryID: uuid.New(),
+				Type:       "one-time",
+				StartDate:  time.Now().Format(time.RFC3339),
+				EndDate:    time.Now().AddDate(0, 1, 0).Format(time.RFC3339),
+				Name:       "Holiday",
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Recurring budget without a cadence",
+			reqBody: CreateBudgetRequest{
+				Amount:     money.MustParse("1000.00"),
+				Currency:   "USD",
+				CategoryID: uuid.New(),
+				Type:       "recurring",
+				StartDate:  time.Now().Format(time.RFC3339),
+				Name:       "Monthly Groceries",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name: "Invalid date format",
 			reqBody: CreateBudgetRequest{
-				Amount:     1000.00,
+				Amount:     money.MustParse("1000.00"),
 				Currency:   "USD",
 				CategoryID: uuid.New(),
 				Type:       "recurring",
@@ -103,7 +134,7 @@ func TestCreateBudget(t *testing.T) {
 		{
 			name: "Missing required fields",
 			reqBody: CreateBudgetRequest{
-				Amount: 1000.00,
+				Amount: money.MustParse("1000.00"),
 				// Missing other required fields
 			},
 			wantStatus: http.StatusBadRequest,
@@ -111,7 +142,7 @@ func TestCreateBudget(t *testing.T) {
 		{
 			name: "Invalid budget type",
 			reqBody: CreateBudgetRequest{
-				Amount:     1000.00,
+				Amount:     money.MustParse("1000.00"),
 				Currency:   "USD",
 				CategoryID: uuid.New(),
 				Type:       "invalid-type", // Should be "recurring" or "one-time"
@@ -123,19 +154,21 @@ func TestCreateBudget(t *testing.T) {
 		{
 			name: "End date before start date",
 			reqBody: CreateBudgetRequest{
-				Amount:     1000.00,
+				Amount:     money.MustParse("1000.00"),
 				Currency:   "USD",
 				CategoryID: uuid.New(),
 				Type:       "recurring",
+				Cadence:    "monthly",
 				StartDate:  time.Now().Format(time.RFC3339),
 				EndDate:    time.Now().AddDate(0, -1, 0).Format(time.RFC3339), // End date before start date
+				Name:       "Monthly Groceries",
 			},
 			wantStatus: http.StatusBadRequest,
 		},
 		{
 			name: "Missing name",
 			reqBody: CreateBudgetRequest{
-				Amount:     1000.00,
+				Amount:     money.MustParse("1000.00"),
 				Currency:   "USD",
 				CategoryID: uuid.New(),
 				Type:       "recurring",
@@ -193,6 +226,200 @@ func TestGetBudgetUsage(t *testing.T) {
 			suite.handler.GetBudgetUsage(w, req)
 
 			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantStatus == http.StatusOK {
+				var response BudgetUsageResponse
+				err := json.NewDecoder(w.Body).Decode(&response)
+				assert.NoError(t, err)
+				assert.Equal(t, suite.testBudget.ID, response.Budget.ID)
+				assert.Equal(t, money.MustParse("750"), response.SpentAmount)
+				if assert.Len(t, response.SpentByCurrency, 1) {
+					assert.Equal(t, "USD", response.SpentByCurrency[0].Currency)
+					assert.Equal(t, &response.SpentAmount, response.SpentByCurrency[0].ConvertedAmount)
+				}
+				assert.Empty(t, response.MissingRates)
+				assert.Equal(t, money.MustParse("250"), response.Period.RemainingAmount)
+				assert.Equal(t, 0, response.Period.Index)
+				assert.Equal(t, suite.testBudget.StartDate.AddDate(0, 1, 0).Unix(), response.Period.EndDate.Unix())
+				assert.Positive(t, response.Period.DaysLeft)
+			}
+		})
+	}
+}
+
+func TestBudgetPeriod(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	budget := repository.Budget{
+		Amount:   money.MustParse("300"),
+		Currency: "USD",
+	}
+	period := recurrence.Period{Index: 2, Start: start, End: start.AddDate(0, 0, 30)}
+
+	tests := []struct {
+		name          string
+		carried       string
+		spent         string
+		now           time.Time
+		wantRemaining string
+		wantDaysLeft  int
+		wantProjected string
+	}{
+		{"Not started", "0", "0", start.AddDate(0, 0, -5), "300", 30, "0"},
+		{"First hours", "0", "40", start.Add(6 * time.Hour), "260", 30, "40"},
+		{"A third in", "0", "120", start.AddDate(0, 0, 10), "180", 20, "360"},
+		{"Part of a day left counts", "0", "100", start.AddDate(0, 0, 20).Add(time.Hour), "200", 10, "149.69"},
+		{"Overspent", "0", "330", start.AddDate(0, 0, 15), "-30", 15, "660"},
+		{"Carried over", "50", "330", start.AddDate(0, 0, 15), "20", 15, "660"},
+		{"Ended", "0", "280", start.AddDate(0, 0, 45), "20", 0, "280"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := budgetPeriod(budget, period, money.MustParse(tt.carried), money.MustParse(tt.spent), tt.now)
+			assert.Equal(t, 2, got.Index)
+			assert.Equal(t, period.Start, got.StartDate)
+			assert.Equal(t, period.End, got.EndDate)
+			assert.Equal(t, budget.Amount.Add(money.MustParse(tt.carried)), got.AvailableAmount)
+			assert.Equal(t, tt.wantRemaining, got.RemainingAmount.String())
+			assert.Equal(t, tt.wantDaysLeft, got.DaysLeft)
+			assert.Equal(t, tt.wantProjected, got.ProjectedSpend.String())
+		})
+	}
+}
+
+func TestBudgetPeriods(t *testing.T) {
+	monthly := recurrence.Monthly
+	budget := repository.Budget{
+		ID:        uuid.New(),
+		Amount:    money.MustParse("100"),
+		Currency:  "USD",
+		Cadence:   &monthly,
+		StartDate: time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC),
+	}
+	now := time.Date(2024, time.May, 5, 0, 0, 0, 0, time.UTC)
+	periods := budgetSchedule(budget).Periods(now)
+
+	spent := make(map[spendingKey]money.Amount)
+	for i, amount := range []string{"60", "150", "70", "20"} {
+		spent[newSpendingKey(budget.ID, periods[i].Start)] = money.MustParse(amount)
+	}
+
+	tests := []struct {
+		name          string
+		rollover      bool
+		wantCarried   []string
+		wantRemaining []string
+	}{
+		{"Without rollover", false, []string{"0", "0", "0", "0"}, []string{"40", "-50", "30", "80"}},
+		{"With rollover", true, []string{"0", "40", "0", "30"}, []string{"40", "-10", "30", "110"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget.Rollover = tt.rollover
+			history := budgetPeriods(budget, periods, spent, now)
+
+			var carried, remaining []string
+			for _, period := range history {
+				carried = append(carried, period.CarriedOver.String())
+				remaining = append(remaining, period.RemainingAmount.String())
+			}
+			assert.Equal(t, tt.wantCarried, carried)
+			assert.Equal(t, tt.wantRemaining, remaining)
+			assert.Equal(t, time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC), history[1].StartDate)
+			assert.Equal(t, time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC), history[2].StartDate)
+			assert.Equal(t, time.Date(2024, time.April, 30, 0, 0, 0, 0, time.UTC), history[3].StartDate)
+		})
+	}
+}
+
+func TestGetBudgetPeriods(t *testing.T) {
+	suite := setupBudgetHandlerTest(t)
+
+	monthly := recurrence.Monthly
+	start := time.Now().AddDate(0, 0, -100)
+	budget := repository.Budget{
+		ID:         uuid.New(),
+		UserID:     suite.testUser.ID,
+		Amount:     money.MustParse("200"),
+		Currency:   "USD",
+		CategoryID: uuid.New(),
+		Type:       "recurring",
+		Cadence:    &monthly,
+		Rollover:   true,
+		StartDate:  start,
+		Name:       "Rolling Budget",
+	}
+	suite.mockRepo.GetBudgetMock().AddBudget(budget)
+	// 75% of the budget is spent in periods without a set amount
+	suite.mockRepo.GetBudgetMock().SetPeriodSpending(budget.ID, start, money.MustParse("120"))
+	suite.mockRepo.GetBudgetMock().SetPeriodSpending(budget.ID, monthly.Advance(start, 1), money.MustParse("300"))
+
+	tests := []struct {
+		name        string
+		budgetID    string
+		query       string
+		wantStatus  int
+		wantCarried []string
+	}{
+		{
+			name:        "All periods, newest first",
+			budgetID:    budget.ID.String(),
+			wantStatus:  http.StatusOK,
+			wantCarried: []string{"50", "0", "80", "0"},
+		},
+		{
+			name:        "Limited",
+			budgetID:    budget.ID.String(),
+			query:       "?limit=2",
+			wantStatus:  http.StatusOK,
+			wantCarried: []string{"50", "0"},
+		},
+		{
+			name:       "Invalid limit",
+			budgetID:   budget.ID.String(),
+			query:      "?limit=abc",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Unknown budget",
+			budgetID:   uuid.New().String(),
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Invalid budget ID",
+			budgetID:   "invalid-uuid",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/budgets/%s/periods%s", tt.budgetID, tt.query), nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.budgetID)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, suite.testUser.ID.String()))
+
+			w := httptest.NewRecorder()
+			suite.handler.GetBudgetPeriods(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response []BudgetPeriod
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			var carried []string
+			for _, period := range response {
+				carried = append(carried, period.CarriedOver.String())
+			}
+			assert.Equal(t, tt.wantCarried, carried)
+			assert.Equal(t, 3, response[0].Index)
+			assert.Positive(t, response[0].DaysLeft)
 		})
 	}
 }
@@ -204,11 +431,19 @@ func TestGetBudgetsNearLimit(t *testing.T) {
 		name           string
 		alertThreshold string
 		wantStatus     int
+		wantAlerts     int
 	}{
 		{
 			name:           "Valid threshold",
 			alertThreshold: "80",
 			wantStatus:     http.StatusOK,
+			wantAlerts:     0,
+		},
+		{
+			name:           "Threshold below current usage",
+			alertThreshold: "70",
+			wantStatus:     http.StatusOK,
+			wantAlerts:     1,
 		},
 		{
 			name:           "Invalid threshold",
@@ -227,6 +462,18 @@ func TestGetBudgetsNearLimit(t *testing.T) {
 			suite.handler.GetBudgetsNearLimit(w, req)
 
 			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var alerts []BudgetAlert
+			if err := json.NewDecoder(w.Body).Decode(&alerts); err != nil {
+				t.Fatal(err)
+			}
+			if assert.Len(t, alerts, tt.wantAlerts) && tt.wantAlerts > 0 {
+				assert.Equal(t, suite.testBudget.ID, alerts[0].Budget.ID)
+				assert.Equal(t, 75.0, alerts[0].UsagePercentage)
+			}
 		})
 	}
 }
@@ -245,7 +492,7 @@ func TestUpdateBudget(t *testing.T) {
 			name:     "Valid full update",
 			budgetID: suite.testBudget.ID.String(),
 			reqBody: CreateBudgetRequest{
-				Amount:     2000.00,
+				Amount:     money.MustParse("2000.00"),
 				Currency:   "EUR",
 				CategoryID: uuid.New(),
 				Type:       "recurring",
@@ -259,7 +506,7 @@ func TestUpdateBudget(t *testing.T) {
 			name:     "Valid partial update - amount only",
 			budgetID: suite.testBudget.ID.String(),
 			reqBody: CreateBudgetRequest{
-				Amount: 2500.00,
+				Amount: money.MustParse("2500.00"),
 			},
 			wantStatus: http.StatusOK,
 			setupMock:  func() {},
@@ -311,7 +558,7 @@ func TestUpdateBudget(t *testing.T) {
 			name:     "Invalid amount in partial update",
 			budgetID: suite.testBudget.ID.String(),
 			reqBody: CreateBudgetRequest{
-				Amount: -100.00,
+				Amount: money.MustParse("-100.00"),
 			},
 			wantStatus: http.StatusBadRequest,
 			setupMock:  func() {},
@@ -366,7 +613,7 @@ func TestUpdateBudget(t *testing.T) {
 			name:     "Budget not found",
 			budgetID: uuid.New().String(), // Non-existent budget ID
 			reqBody: CreateBudgetRequest{
-				Amount: 2000.00,
+				Amount: money.MustParse("2000.00"),
 			},
 			wantStatus: http.StatusNotFound,
 			setupMock: func() {
@@ -451,9 +698,10 @@ func TestListBudgets(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	var budgets []repository.Budget
-	err := json.NewDecoder(w.Body).Decode(&budgets)
+	var page Page[repository.Budget]
+	err := json.NewDecoder(w.Body).Decode(&page)
 	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
 }
 
 func TestGetRecurringBudgets(t *testing.T) {
@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 	"github.com/jorge-dev/centsible/internal/repository"
 	"github.com/jorge-dev/centsible/internal/validation"
 	"github.com/jorge-dev/centsible/server/middleware"
@@ -22,7 +26,8 @@ func NewCategoryHandler(db repository.Repository) *CategoryHandler {
 }
 
 type createCategoryRequest struct {
-	Name string `json:"name"`
+	Name     string     `json:"name"`
+	ParentID *uuid.UUID `json:"parent_id"`
 }
 
 func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
@@ -61,10 +66,15 @@ func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !h.checkParentCategory(w, r, uid, uuid.Nil, req.ParentID) {
+		return
+	}
+
 	category, err := h.queries.CreateCategory(r.Context(), repository.CreateCategoryParams{
-		ID:     uuid.New(),
-		UserID: uid,
-		Name:   req.Name,
+		ID:       uuid.New(),
+		UserID:   uid,
+		Name:     req.Name,
+		ParentID: req.ParentID,
 	})
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -101,6 +111,8 @@ func (h *CategoryHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, category)
 }
 
+// ListCategories handles GET /categories, by name unless sorted by
+// created_at. q searches names; see parseListQuery for paging and sorting.
 func (h *CategoryHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.UserIDKey).(string)
 	uid, err := uuid.Parse(userID)
@@ -108,17 +120,38 @@ func (h *CategoryHandler) ListCategories(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Invalid user ID", http.StatusBadRequest)
 		return
 	}
-	categories, err := h.queries.ListCategories(r.Context(), uid)
+
+	q, err := parseListQuery(r.URL.Query(), categorySorts, "asc")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	categories, err := h.queries.ListCategoriesPage(r.Context(), repository.ListCategoriesPageParams{
+		UserID:     uid,
+		Search:     q.search,
+		AfterID:    q.afterID(),
+		Sort:       q.sort,
+		Descending: q.fetchDescending(),
+		AfterTime:  q.afterTime(),
+		AfterName:  q.afterName(),
+		PageSize:   q.pageSize(),
+	})
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, categories)
+	writeJSON(w, http.StatusOK, paginate(q, categories, func(category repository.Category) listCursor {
+		return q.cursorAt(money.Amount{}, category.CreatedAt, time.Time{}, category.Name, category.ID)
+	}))
 }
 
+// updateCategoryRequest replaces a category's name and parent; a missing or
+// null parent_id moves the category to the top level
 type updateCategoryRequest struct {
-	Name string `json:"name"`
+	Name     string     `json:"name"`
+	ParentID *uuid.UUID `json:"parent_id"`
 }
 
 func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
@@ -151,10 +184,15 @@ func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !h.checkParentCategory(w, r, uid, cid, req.ParentID) {
+		return
+	}
+
 	category, err := h.queries.UpdateCategory(r.Context(), repository.UpdateCategoryParams{
-		ID:     cid,
-		Name:   req.Name,
-		UserID: uid,
+		ID:       cid,
+		Name:     req.Name,
+		UserID:   uid,
+		ParentID: req.ParentID,
 	})
 	if err != nil {
 		http.Error(w, "Category not found", http.StatusNotFound)
@@ -164,6 +202,47 @@ func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, http.StatusOK, category)
 }
 
+// checkParentCategory reports whether parentID can become the parent of the
+// category id, which is uuid.Nil for a new category, writing the error
+// response when it cannot. The parent must be another of the user's
+// categories and must not sit below id, which would make a cycle.
+func (h *CategoryHandler) checkParentCategory(w http.ResponseWriter, r *http.Request, userID, id uuid.UUID, parentID *uuid.UUID) bool {
+	if parentID == nil {
+		return true
+	}
+
+	if _, err := h.queries.GetCategoryByID(r.Context(), repository.GetCategoryByIDParams{
+		ID:     *parentID,
+		UserID: userID,
+	}); err != nil {
+		http.Error(w, "Parent category not found", http.StatusBadRequest)
+		return false
+	}
+
+	if id == uuid.Nil {
+		return true
+	}
+	nested, err := h.queries.IsCategoryInSubtree(r.Context(), repository.IsCategoryInSubtreeParams{
+		UserID:     userID,
+		CategoryID: *parentID,
+		RootID:     id,
+	})
+	if err != nil {
+		log.Printf("Error checking category parent: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if nested {
+		http.Error(w, "A category cannot be nested under itself or its subcategories", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// DeleteCategory handles DELETE /categories/{id}. A category that expenses,
+// budgets, recurring transactions or category rules still use is only deleted
+// with a reassign_to category to move them to; its subcategories move up to
+// its parent either way.
 func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
@@ -177,15 +256,54 @@ func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Invalid user ID", http.StatusBadRequest)
 		return
 	}
-	rows, err := h.queries.DeleteCategory(r.Context(), repository.DeleteCategoryParams{
-		ID:     id,
-		UserID: uid,
+
+	if reassignTo := r.URL.Query().Get("reassign_to"); reassignTo != "" {
+		targetID, err := uuid.Parse(reassignTo)
+		if err != nil {
+			http.Error(w, "Invalid reassign_to category ID", http.StatusBadRequest)
+			return
+		}
+		if _, ok := h.mergeCategory(w, r, uid, id, targetID, false); ok {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+
+	// Subcategories move up to the deleted category's parent in the same
+	// statement, and only when the category is deleted. Telling why nothing
+	// was deleted happens in the same transaction, so it sees the category as
+	// the delete did.
+	var deleted, inUse bool
+	err = h.queries.InTx(r.Context(), func(tx repository.Repository) error {
+		rows, err := tx.DeleteCategory(r.Context(), repository.DeleteCategoryParams{
+			ID:     id,
+			UserID: uid,
+		})
+		if err != nil {
+			return fmt.Errorf("deleting category: %w", err)
+		}
+		if deleted = rows > 0; deleted {
+			return nil
+		}
+		inUse, err = tx.IsCategoryInUse(r.Context(), repository.IsCategoryInUseParams{
+			ID:     id,
+			UserID: uid,
+		})
+		if err != nil {
+			return fmt.Errorf("checking category usage: %w", err)
+		}
+		return nil
 	})
 	if err != nil {
+		log.Printf("Error deleting category: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	if rows == 0 {
+	if inUse {
+		http.Error(w, "Category is used by expenses, budgets, recurring transactions or category rules; choose a reassign_to category for them", http.StatusConflict)
+		return
+	}
+	if !deleted {
 		http.Error(w, "Category not found", http.StatusNotFound)
 		return
 	}
@@ -193,6 +311,130 @@ func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+type mergeCategoryRequest struct {
+	TargetID uuid.UUID `json:"target_id"`
+}
+
+// MergeCategory handles POST /categories/{id}/merge. It moves the category's
+// expenses, budgets, recurring transactions, category rules and subcategories
+// to the target, deletes it and returns the target.
+func (h *CategoryHandler) MergeCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	var req mergeCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.TargetID == uuid.Nil {
+		http.Error(w, "target_id is required", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	target, ok := h.mergeCategory(w, r, uid, id, req.TargetID, true)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, target)
+}
+
+// mergeCategory moves the records of category id to targetID and deletes it,
+// returning the target as merged when it did and writing the error response
+// when it did not. Subcategories go under the target when adopt is set, and up
+// a level otherwise.
+func (h *CategoryHandler) mergeCategory(w http.ResponseWriter, r *http.Request, userID, id, targetID uuid.UUID, adopt bool) (repository.Category, bool) {
+	if targetID == id {
+		http.Error(w, "A category cannot be merged into itself", http.StatusBadRequest)
+		return repository.Category{}, false
+	}
+
+	// The target is checked in the merge's transaction, so a category moved
+	// meanwhile makes the merge try again rather than fail the parent check
+	var (
+		target  repository.Category
+		problem string
+		merged  bool
+	)
+	err := h.queries.InTx(r.Context(), func(tx repository.Repository) error {
+		problem = ""
+		if _, err := tx.GetCategoryByID(r.Context(), repository.GetCategoryByIDParams{
+			ID:     targetID,
+			UserID: userID,
+		}); err != nil {
+			if repository.IsSerializationFailure(err) {
+				return err
+			}
+			problem = "Target category not found"
+			return nil
+		}
+
+		// Subcategories would go under a target below them, making a cycle
+		if adopt {
+			nested, err := tx.IsCategoryInSubtree(r.Context(), repository.IsCategoryInSubtreeParams{
+				UserID:     userID,
+				CategoryID: targetID,
+				RootID:     id,
+			})
+			if err != nil {
+				return fmt.Errorf("checking merge target: %w", err)
+			}
+			if nested {
+				problem = "A category cannot be merged into its own subcategories"
+				return nil
+			}
+		}
+
+		rows, err := tx.MergeCategory(r.Context(), repository.MergeCategoryParams{
+			ID:                 id,
+			UserID:             userID,
+			TargetID:           targetID,
+			AdoptSubcategories: adopt,
+		})
+		if err != nil {
+			return fmt.Errorf("merging category: %w", err)
+		}
+		if merged = rows > 0; !merged {
+			return nil
+		}
+
+		// A target that was a subcategory has moved up
+		target, err = tx.GetCategoryByID(r.Context(), repository.GetCategoryByIDParams{
+			ID:     targetID,
+			UserID: userID,
+		})
+		if err != nil {
+			return fmt.Errorf("getting merged category: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error merging category: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return repository.Category{}, false
+	}
+	if problem != "" {
+		http.Error(w, problem, http.StatusBadRequest)
+		return repository.Category{}, false
+	}
+	if !merged {
+		http.Error(w, "Category not found", http.StatusNotFound)
+		return repository.Category{}, false
+	}
+	return target, true
+}
+
 func (h *CategoryHandler) GetCategoryStats(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
@@ -258,6 +500,146 @@ func (h *CategoryHandler) GetMostUsedCategories(w http.ResponseWriter, r *http.R
 	writeJSON(w, http.StatusOK, stats)
 }
 
+// noCategoryTemplate is the category_template that registers a user without
+// any categories
+const noCategoryTemplate = "none"
+
+// fallbackTemplateLocale is the locale whose default template new users get
+// when none of their Accept-Language locales has one
+const fallbackTemplateLocale = "en"
+
+// registrationTemplate returns the key of the template a new user's
+// categories come from: the requested one, or else the default template of
+// the first Accept-Language locale that has one. The key is empty when no
+// categories should be created, and ok is false for an unknown template.
+func registrationTemplate(templates []repository.ListCategoryTemplatesRow, requested, acceptLanguage string) (key string, ok bool) {
+	switch requested {
+	case noCategoryTemplate:
+		return "", true
+	case "":
+	default:
+		for _, template := range templates {
+			if template.Key == requested {
+				return template.Key, true
+			}
+		}
+		return "", false
+	}
+
+	defaults := make(map[string]string)
+	for _, template := range templates {
+		if template.IsDefault {
+			defaults[primaryLanguage(template.Locale)] = template.Key
+		}
+	}
+	for _, tag := range strings.Split(acceptLanguage, ",") {
+		tag, _, _ = strings.Cut(tag, ";")
+		if key, ok := defaults[primaryLanguage(tag)]; ok {
+			return key, true
+		}
+	}
+	return defaults[fallbackTemplateLocale], true
+}
+
+// primaryLanguage returns the language of a locale such as "en-GB" or "es_MX"
+func primaryLanguage(locale string) string {
+	language, _, _ := strings.Cut(strings.TrimSpace(locale), "-")
+	language, _, _ = strings.Cut(language, "_")
+	return strings.ToLower(language)
+}
+
+// categoryTemplateNode is a category of a template with its subcategories
+type categoryTemplateNode struct {
+	Name     string                 `json:"name"`
+	Children []categoryTemplateNode `json:"children,omitempty"`
+}
+
+type categoryTemplateResponse struct {
+	Key         string                 `json:"key"`
+	Name        string                 `json:"name"`
+	Locale      string                 `json:"locale"`
+	Description string                 `json:"description"`
+	IsDefault   bool                   `json:"is_default"`
+	Categories  []categoryTemplateNode `json:"categories"`
+}
+
+// ListCategoryTemplates handles GET /categories/templates
+func (h *CategoryHandler) ListCategoryTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.queries.ListCategoryTemplates(r.Context())
+	if err != nil {
+		log.Printf("Error listing category templates: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]categoryTemplateResponse, 0, len(templates))
+	for _, template := range templates {
+		// Templates are two levels deep, so children attach to top-level entries
+		var categories []categoryTemplateNode
+		top := make(map[string]int)
+		for i, name := range template.CategoryNames {
+			if template.ParentNames[i] == "" {
+				top[name] = len(categories)
+				categories = append(categories, categoryTemplateNode{Name: name})
+			}
+		}
+		for i, name := range template.CategoryNames {
+			if parent, ok := top[template.ParentNames[i]]; ok && template.ParentNames[i] != "" {
+				categories[parent].Children = append(categories[parent].Children, categoryTemplateNode{Name: name})
+			}
+		}
+		response = append(response, categoryTemplateResponse{
+			Key:         template.Key,
+			Name:        template.Name,
+			Locale:      template.Locale,
+			Description: template.Description,
+			IsDefault:   template.IsDefault,
+			Categories:  categories,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// ApplyCategoryTemplate handles POST /categories/templates/{key}/apply and
+// returns the categories it added. Categories the user already has, matched
+// by name ignoring case, are skipped, so applying a template twice is safe.
+func (h *CategoryHandler) ApplyCategoryTemplate(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	templates, err := h.queries.ListCategoryTemplates(r.Context())
+	if err != nil {
+		log.Printf("Error listing category templates: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	key := chi.URLParam(r, "key")
+	if templateKey, ok := registrationTemplate(templates, key, ""); !ok || templateKey == "" {
+		http.Error(w, "Category template not found", http.StatusNotFound)
+		return
+	}
+
+	categories, err := h.queries.ApplyCategoryTemplate(r.Context(), repository.ApplyCategoryTemplateParams{
+		UserID:      uid,
+		TemplateKey: key,
+	})
+	if err != nil {
+		log.Printf("Error applying category template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if categories == nil {
+		categories = []repository.Category{}
+	}
+
+	writeJSON(w, http.StatusOK, categories)
+}
+
 func writeJSON(w http.ResponseWriter, status int, data any) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
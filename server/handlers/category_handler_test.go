@@ -320,6 +320,346 @@ func TestDeleteCategory(t *testing.T) {
 	}
 }
 
+// categoryTree adds Food > Groceries > Organic for the suite's user
+func (s *categoryHandlerTestSuite) categoryTree() (food, groceries, organic repository.Category) {
+	food = repository.Category{ID: uuid.New(), UserID: s.testUser, Name: "Food"}
+	groceries = repository.Category{ID: uuid.New(), UserID: s.testUser, Name: "Groceries", ParentID: &food.ID}
+	organic = repository.Category{ID: uuid.New(), UserID: s.testUser, Name: "Organic", ParentID: &groceries.ID}
+	for _, cat := range []repository.Category{food, groceries, organic} {
+		s.mockRepo.GetCategoryMock().AddCategory(cat)
+	}
+	return food, groceries, organic
+}
+
+// serveCategory calls a category handler as the suite's user, with the {id}
+// path parameter when id is set
+func (s *categoryHandlerTestSuite) serveCategory(handler http.HandlerFunc, method, id string, body any) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := withUser(httptest.NewRequest(method, "/api/categories", &buf), s.testUser)
+	if id != "" {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+func TestCategoryParent(t *testing.T) {
+	suite := setupCategoryHandlerTest(t)
+	food, groceries, organic := suite.categoryTree()
+	theirs := repository.Category{ID: uuid.New(), UserID: uuid.New(), Name: "Theirs"}
+	suite.mockRepo.GetCategoryMock().AddCategory(theirs)
+	unknown := uuid.New()
+
+	createTests := []struct {
+		name       string
+		parentID   *uuid.UUID
+		wantStatus int
+	}{
+		{"Top-level", nil, http.StatusCreated},
+		{"Under a subcategory", &organic.ID, http.StatusCreated},
+		{"Unknown parent", &unknown, http.StatusBadRequest},
+		{"Another user's category", &theirs.ID, http.StatusBadRequest},
+	}
+
+	for i, tt := range createTests {
+		t.Run("Create "+tt.name, func(t *testing.T) {
+			w := suite.serveCategory(suite.handler.CreateCategory, http.MethodPost, "",
+				createCategoryRequest{Name: fmt.Sprintf("New %d", i), ParentID: tt.parentID})
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if w.Code != http.StatusCreated {
+				return
+			}
+			var created repository.Category
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+			assert.Equal(t, tt.parentID, created.ParentID)
+		})
+	}
+
+	updateTests := []struct {
+		name       string
+		id         uuid.UUID
+		parentID   *uuid.UUID
+		wantStatus int
+	}{
+		{"Under itself", food.ID, &food.ID, http.StatusBadRequest},
+		{"Under its child", food.ID, &groceries.ID, http.StatusBadRequest},
+		{"Under its grandchild", food.ID, &organic.ID, http.StatusBadRequest},
+		{"Under another user's category", food.ID, &theirs.ID, http.StatusBadRequest},
+		{"Up a level", organic.ID, &food.ID, http.StatusOK},
+		{"To the top level", groceries.ID, nil, http.StatusOK},
+		{"Under a former child", food.ID, &groceries.ID, http.StatusOK},
+	}
+
+	for _, tt := range updateTests {
+		t.Run("Update "+tt.name, func(t *testing.T) {
+			w := suite.serveCategory(suite.handler.UpdateCategory, http.MethodPut, tt.id.String(),
+				updateCategoryRequest{Name: fmt.Sprintf("Renamed %s", tt.id), ParentID: tt.parentID})
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			w = suite.serveCategory(suite.handler.GetCategory, http.MethodGet, tt.id.String(), nil)
+			var got repository.Category
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.parentID, got.ParentID)
+			} else {
+				assert.Nil(t, got.ParentID, "a rejected move leaves the category where it was")
+			}
+		})
+	}
+}
+
+func TestDeleteCategoryMovesSubcategoriesUp(t *testing.T) {
+	suite := setupCategoryHandlerTest(t)
+	food, groceries, organic := suite.categoryTree()
+
+	w := suite.serveCategory(suite.handler.DeleteCategory, http.MethodDelete, groceries.ID.String(), nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = suite.serveCategory(suite.handler.GetCategory, http.MethodGet, organic.ID.String(), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got repository.Category
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, &food.ID, got.ParentID)
+}
+
+// useCategory adds an expense, a budget and a recurring transaction in the
+// category for the suite's user
+func (s *categoryHandlerTestSuite) useCategory(categoryID uuid.UUID) (repository.Expense, repository.Budget, repository.RecurringTransaction) {
+	expense := repository.Expense{ID: uuid.New(), UserID: s.testUser, CategoryID: categoryID, Currency: "USD", Description: "Lunch"}
+	budget := repository.Budget{ID: uuid.New(), UserID: s.testUser, CategoryID: categoryID, Currency: "USD"}
+	recurring := repository.RecurringTransaction{ID: uuid.New(), UserID: s.testUser, Type: "expense", CategoryID: &categoryID, Currency: "USD"}
+	s.mockRepo.GetExpenseMock().AddExpense(expense)
+	s.mockRepo.GetBudgetMock().AddBudget(budget)
+	s.mockRepo.GetRecurringTransactionMock().AddRecurringTransaction(recurring)
+	return expense, budget, recurring
+}
+
+// assertCategoryRecords checks that the records from useCategory are in the
+// category
+func (s *categoryHandlerTestSuite) assertCategoryRecords(t *testing.T, categoryID uuid.UUID, expense repository.Expense, budget repository.Budget, recurring repository.RecurringTransaction) {
+	t.Helper()
+	gotExpense, err := s.mockRepo.GetExpenseByID(context.Background(), repository.GetExpenseByIDParams{ID: expense.ID, UserID: s.testUser})
+	assert.NoError(t, err)
+	assert.Equal(t, categoryID, gotExpense.CategoryID)
+	gotBudget, err := s.mockRepo.GetBudgetByID(context.Background(), repository.GetBudgetByIDParams{ID: budget.ID, UserID: s.testUser})
+	assert.NoError(t, err)
+	assert.Equal(t, categoryID, gotBudget.CategoryID)
+	gotRecurring, err := s.mockRepo.GetRecurringTransactionByID(context.Background(), repository.GetRecurringTransactionByIDParams{ID: recurring.ID, UserID: s.testUser})
+	assert.NoError(t, err)
+	assert.Equal(t, &categoryID, gotRecurring.CategoryID)
+}
+
+// serveDelete deletes a category as the suite's user, reassigning its records
+// when reassignTo is set
+func (s *categoryHandlerTestSuite) serveDelete(id uuid.UUID, reassignTo string) *httptest.ResponseRecorder {
+	url := "/api/categories/" + id.String()
+	if reassignTo != "" {
+		url += "?reassign_to=" + reassignTo
+	}
+	req := withUser(httptest.NewRequest(http.MethodDelete, url, nil), s.testUser)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+	s.handler.DeleteCategory(w, req)
+	return w
+}
+
+func TestDeleteCategoryInUse(t *testing.T) {
+	suite := setupCategoryHandlerTest(t)
+	food, groceries, organic := suite.categoryTree()
+	other := repository.Category{ID: uuid.New(), UserID: suite.testUser, Name: "Other"}
+	theirs := repository.Category{ID: uuid.New(), UserID: uuid.New(), Name: "Theirs"}
+	suite.mockRepo.GetCategoryMock().AddCategory(other)
+	suite.mockRepo.GetCategoryMock().AddCategory(theirs)
+	expense, budget, recurring := suite.useCategory(groceries.ID)
+
+	tests := []struct {
+		name       string
+		reassignTo string
+		wantStatus int
+	}{
+		{"Without a target", "", http.StatusConflict},
+		{"Invalid target ID", "other", http.StatusBadRequest},
+		{"Unknown target", uuid.NewString(), http.StatusBadRequest},
+		{"Another user's category", theirs.ID.String(), http.StatusBadRequest},
+		{"Itself", groceries.ID.String(), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := suite.serveDelete(groceries.ID, tt.reassignTo)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			suite.assertCategoryRecords(t, groceries.ID, expense, budget, recurring)
+		})
+	}
+
+	// A category that is not deleted keeps its subcategories
+	w := suite.serveCategory(suite.handler.GetCategory, http.MethodGet, organic.ID.String(), nil)
+	var got repository.Category
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, &groceries.ID, got.ParentID)
+
+	// Reassigning to a subcategory moves the records there and the
+	// subcategory up a level
+	w = suite.serveDelete(groceries.ID, organic.ID.String())
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	suite.assertCategoryRecords(t, organic.ID, expense, budget, recurring)
+	w = suite.serveCategory(suite.handler.GetCategory, http.MethodGet, organic.ID.String(), nil)
+	got = repository.Category{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, &food.ID, got.ParentID)
+
+	w = suite.serveDelete(groceries.ID, other.ID.String())
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeleteCategoryUsedByRule(t *testing.T) {
+	suite := setupCategoryHandlerTest(t)
+	_, groceries, _ := suite.categoryTree()
+	other := repository.Category{ID: uuid.New(), UserID: suite.testUser, Name: "Other"}
+	suite.mockRepo.GetCategoryMock().AddCategory(other)
+	rule := repository.CategoryRule{ID: uuid.New(), UserID: suite.testUser, Name: "Market", CategoryID: &groceries.ID, Enabled: true}
+	suite.mockRepo.GetCategoryRuleMock().AddCategoryRule(rule)
+
+	// A rule alone keeps the category, as it would file expenses under it
+	w := suite.serveDelete(groceries.ID, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "category rules")
+
+	// Reassigning moves the rule along
+	w = suite.serveDelete(groceries.ID, other.ID.String())
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	got, err := suite.mockRepo.GetCategoryRuleByID(context.Background(), repository.GetCategoryRuleByIDParams{ID: rule.ID, UserID: suite.testUser})
+	assert.NoError(t, err)
+	assert.Equal(t, &other.ID, got.CategoryID)
+}
+
+func TestDeleteCategoryRetriesConflicts(t *testing.T) {
+	suite := setupCategoryHandlerTest(t)
+	food, groceries, organic := suite.categoryTree()
+
+	// One that keeps conflicting deletes nothing and moves nothing
+	suite.mockRepo.FailTransactions(repository.MaxTxAttempts)
+	w := suite.serveDelete(groceries.ID, "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	w = suite.serveCategory(suite.handler.GetCategory, http.MethodGet, groceries.ID.String(), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A delete that conflicts is tried again
+	suite.mockRepo.FailTransactions(repository.MaxTxAttempts - 1)
+	w = suite.serveDelete(groceries.ID, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = suite.serveCategory(suite.handler.GetCategory, http.MethodGet, organic.ID.String(), nil)
+	var got repository.Category
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, &food.ID, got.ParentID)
+}
+
+func TestMergeCategory(t *testing.T) {
+	suite := setupCategoryHandlerTest(t)
+	food, groceries, organic := suite.categoryTree()
+	other := repository.Category{ID: uuid.New(), UserID: suite.testUser, Name: "Other"}
+	suite.mockRepo.GetCategoryMock().AddCategory(other)
+	expense, budget, recurring := suite.useCategory(groceries.ID)
+
+	merge := func(id uuid.UUID, body any) *httptest.ResponseRecorder {
+		return suite.serveCategory(suite.handler.MergeCategory, http.MethodPost, id.String(), body)
+	}
+
+	tests := []struct {
+		name       string
+		id         uuid.UUID
+		body       any
+		wantStatus int
+	}{
+		{"Missing target", groceries.ID, map[string]any{}, http.StatusBadRequest},
+		{"Unknown target", groceries.ID, mergeCategoryRequest{TargetID: uuid.New()}, http.StatusBadRequest},
+		{"Into itself", groceries.ID, mergeCategoryRequest{TargetID: groceries.ID}, http.StatusBadRequest},
+		{"Into its subcategory", food.ID, mergeCategoryRequest{TargetID: organic.ID}, http.StatusBadRequest},
+		{"Unknown category", uuid.New(), mergeCategoryRequest{TargetID: other.ID}, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := merge(tt.id, tt.body)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			suite.assertCategoryRecords(t, groceries.ID, expense, budget, recurring)
+		})
+	}
+
+	w := merge(groceries.ID, mergeCategoryRequest{TargetID: other.ID})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var target repository.Category
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&target))
+	assert.Equal(t, other.ID, target.ID)
+	suite.assertCategoryRecords(t, other.ID, expense, budget, recurring)
+
+	// The merged category is gone and its subcategory went under the target
+	w = suite.serveCategory(suite.handler.GetCategory, http.MethodGet, organic.ID.String(), nil)
+	var got repository.Category
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, &other.ID, got.ParentID)
+	w = suite.serveDelete(groceries.ID, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// movedMidway makes the first attempt of a transaction conflict with a move
+// that commits while it runs, so that later attempts see the category moved
+type movedMidway struct {
+	*mocks.MockRepository
+	move     func()
+	attempts int
+}
+
+func (m *movedMidway) InTx(ctx context.Context, fn func(repository.Repository) error) error {
+	return m.MockRepository.InTx(ctx, func(tx repository.Repository) error {
+		m.attempts++
+		if m.attempts == 1 {
+			if err := fn(tx); err != nil {
+				return err
+			}
+			return mocks.ErrSerializationFailure
+		}
+		// Undoing the first attempt would undo the move with it, so the
+		// move lands as the next attempt starts
+		if m.attempts == 2 {
+			m.move()
+		}
+		return fn(tx)
+	})
+}
+
+func TestMergeCategoryRetriesConflicts(t *testing.T) {
+	suite := setupCategoryHandlerTest(t)
+	food, _, _ := suite.categoryTree()
+	other := repository.Category{ID: uuid.New(), UserID: suite.testUser, Name: "Other"}
+	suite.mockRepo.GetCategoryMock().AddCategory(other)
+	expense, budget, recurring := suite.useCategory(other.ID)
+
+	// Food moves under Other while Other is merged into it, so the merge
+	// tries again, finds the cycle and changes nothing
+	movedFood := food
+	movedFood.ParentID = &other.ID
+	suite.handler = NewCategoryHandler(&movedMidway{
+		MockRepository: suite.mockRepo,
+		move:           func() { suite.mockRepo.GetCategoryMock().AddCategory(movedFood) },
+	})
+	w := suite.serveCategory(suite.handler.MergeCategory, http.MethodPost, other.ID.String(), mergeCategoryRequest{TargetID: food.ID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	suite.assertCategoryRecords(t, other.ID, expense, budget, recurring)
+	w = suite.serveCategory(suite.handler.GetCategory, http.MethodGet, food.ID.String(), nil)
+	var got repository.Category
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, &other.ID, got.ParentID)
+}
+
 func TestListCategories(t *testing.T) {
 	suite := setupCategoryHandlerTest(t)
 
@@ -371,10 +711,10 @@ func TestListCategories(t *testing.T) {
 			assert.Equal(t, tt.wantStatus, w.Code)
 
 			if w.Code == http.StatusOK {
-				var categories []repository.Category
-				err := json.NewDecoder(w.Body).Decode(&categories)
+				var page Page[repository.Category]
+				err := json.NewDecoder(w.Body).Decode(&page)
 				assert.NoError(t, err)
-				assert.Equal(t, tt.wantCount, len(categories))
+				assert.Equal(t, tt.wantCount, len(page.Items))
 			}
 		})
 	}
@@ -468,3 +808,113 @@ func TestGetMostUsedCategories(t *testing.T) {
 		})
 	}
 }
+
+// addCategoryTemplates adds English and Spanish default templates and an
+// English household template
+func addCategoryTemplates(mock *mocks.MockRepository) {
+	for _, template := range []repository.ListCategoryTemplatesRow{
+		{
+			Key: "basic", Name: "Basic", Locale: "en", IsDefault: true,
+			CategoryNames: []string{"Food", "Groceries", "Transport"},
+			ParentNames:   []string{"", "Food", ""},
+		},
+		{
+			Key: "basico", Name: "Básico", Locale: "es", IsDefault: true,
+			CategoryNames: []string{"Comida", "Transporte"},
+			ParentNames:   []string{"", ""},
+		},
+		{
+			Key: "household", Name: "Household", Locale: "en",
+			CategoryNames: []string{"Home", "Utilities", "Food"},
+			ParentNames:   []string{"", "Home", ""},
+		},
+	} {
+		mock.GetCategoryMock().AddCategoryTemplate(template)
+	}
+}
+
+func TestRegistrationTemplate(t *testing.T) {
+	suite := setupCategoryHandlerTest(t)
+	addCategoryTemplates(suite.mockRepo)
+	templates, _ := suite.mockRepo.ListCategoryTemplates(context.Background())
+
+	tests := []struct {
+		name           string
+		requested      string
+		acceptLanguage string
+		wantKey        string
+		wantOK         bool
+	}{
+		{"Requested template", "household", "es", "household", true},
+		{"No categories", "none", "", "", true},
+		{"Unknown template", "travel", "", "", false},
+		{"Default without a language", "", "", "basic", true},
+		{"Default of the language", "", "es-MX", "basico", true},
+		{"First language with a default", "", "de-DE, es;q=0.8, en;q=0.5", "basico", true},
+		{"Fallback language", "", "de-DE", "basic", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, ok := registrationTemplate(templates, tt.requested, tt.acceptLanguage)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantKey, key)
+		})
+	}
+}
+
+func TestListCategoryTemplates(t *testing.T) {
+	suite := setupCategoryHandlerTest(t)
+	addCategoryTemplates(suite.mockRepo)
+
+	w := suite.serveCategory(suite.handler.ListCategoryTemplates, http.MethodGet, "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var templates []categoryTemplateResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&templates))
+	assert.Len(t, templates, 3)
+	assert.Equal(t, "basic", templates[0].Key)
+	assert.True(t, templates[0].IsDefault)
+	assert.Equal(t, []categoryTemplateNode{
+		{Name: "Food", Children: []categoryTemplateNode{{Name: "Groceries"}}},
+		{Name: "Transport"},
+	}, templates[0].Categories)
+}
+
+func TestApplyCategoryTemplate(t *testing.T) {
+	suite := setupCategoryHandlerTest(t)
+	addCategoryTemplates(suite.mockRepo)
+	// The user already has a Food category under another case
+	food := repository.Category{ID: uuid.New(), UserID: suite.testUser, Name: "food"}
+	suite.mockRepo.GetCategoryMock().AddCategory(food)
+
+	apply := func(key string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/categories/templates/"+key+"/apply", nil), suite.testUser)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("key", key)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		suite.handler.ApplyCategoryTemplate(w, req)
+		return w
+	}
+
+	w := apply("basic")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var created []repository.Category
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	if assert.Len(t, created, 2) {
+		assert.Equal(t, "Groceries", created[0].Name)
+		assert.Equal(t, &food.ID, created[0].ParentID, "subcategories go under the existing parent")
+		assert.Equal(t, "Transport", created[1].Name)
+	}
+
+	// Applying it again adds nothing
+	w = apply("basic")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+
+	for _, key := range []string{"travel", "none"} {
+		w = apply(key)
+		assert.Equal(t, http.StatusNotFound, w.Code, key)
+	}
+}
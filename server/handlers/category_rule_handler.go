@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/categorize"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/jorge-dev/centsible/internal/validation"
+	"github.com/jorge-dev/centsible/server/middleware"
+)
+
+type CategoryRuleHandler struct {
+	db repository.Repository
+}
+
+func NewCategoryRuleHandler(db repository.Repository) *CategoryRuleHandler {
+	return &CategoryRuleHandler{db: db}
+}
+
+// categoryRuleRequest creates or replaces a rule. Conditions left out always
+// hold; a rule needs at least one of them, and a category or tags to set.
+type categoryRuleRequest struct {
+	Name               string        `json:"name"`
+	DescriptionPattern *string       `json:"description_pattern"`
+	MinAmount          *money.Amount `json:"min_amount"`
+	MaxAmount          *money.Amount `json:"max_amount"`
+	Currency           *string       `json:"currency"`
+	CategoryID         *uuid.UUID    `json:"category_id"`
+	TagIDs             []uuid.UUID   `json:"tag_ids"`
+	Priority           int32         `json:"priority"`
+	Enabled            *bool         `json:"enabled"` // Defaults to true
+}
+
+// CategorySuggestionResponse is what GET /categories/suggest returns for a
+// description: what the user's rules would set, and the categories the user
+// filed expenses with the same words under
+type CategorySuggestionResponse struct {
+	CategoryID  *uuid.UUID              `json:"category_id"` // Set by the first matching rule, if any
+	TagIDs      []uuid.UUID             `json:"tag_ids"`
+	RuleIDs     []uuid.UUID             `json:"rule_ids"`
+	Suggestions []categorize.Suggestion `json:"suggestions"`
+}
+
+// decodeCategoryRule reads and validates a rule request, checking its
+// category and tags belong to the user. It writes the error and returns false
+// when the request is unusable.
+func (h *CategoryRuleHandler) decodeCategoryRule(w http.ResponseWriter, r *http.Request, uid uuid.UUID) (categoryRuleRequest, bool) {
+	var req categoryRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return req, false
+	}
+	if req.CategoryID != nil && *req.CategoryID == uuid.Nil {
+		req.CategoryID = nil
+	}
+	req.TagIDs = uniqueIDs(req.TagIDs)
+
+	validator := &validation.CategoryRuleValidation{
+		Name:               req.Name,
+		DescriptionPattern: req.DescriptionPattern,
+		MinAmount:          req.MinAmount,
+		MaxAmount:          req.MaxAmount,
+		Currency:           req.Currency,
+		CategoryID:         req.CategoryID,
+		TagIDs:             req.TagIDs,
+	}
+	if err := validator.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return req, false
+	}
+
+	if req.CategoryID != nil {
+		category, err := h.db.GetCategoryByID(r.Context(), repository.GetCategoryByIDParams{
+			ID:     *req.CategoryID,
+			UserID: uid,
+		})
+		if err != nil || category.DeletedAt != nil {
+			http.Error(w, "Category not found", http.StatusBadRequest)
+			return req, false
+		}
+	}
+	if !checkUserTags(w, r, h.db, uid, req.TagIDs) {
+		return req, false
+	}
+	return req, true
+}
+
+// CreateCategoryRule handles POST /category-rules
+func (h *CategoryRuleHandler) CreateCategoryRule(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	req, ok := h.decodeCategoryRule(w, r, uid)
+	if !ok {
+		return
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+
+	rule, err := h.db.CreateCategoryRule(r.Context(), repository.CreateCategoryRuleParams{
+		ID:                 uuid.New(),
+		UserID:             uid,
+		Name:               req.Name,
+		DescriptionPattern: req.DescriptionPattern,
+		MinAmount:          req.MinAmount,
+		MaxAmount:          req.MaxAmount,
+		Currency:           req.Currency,
+		CategoryID:         req.CategoryID,
+		TagIds:             req.TagIDs,
+		Priority:           req.Priority,
+		Enabled:            enabled,
+	})
+	if err != nil {
+		log.Printf("Error creating category rule: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+// ListCategoryRules handles GET /category-rules, listing the user's rules in
+// the order they are tried
+func (h *CategoryRuleHandler) ListCategoryRules(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	rules, err := h.db.ListCategoryRules(r.Context(), uid)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if rules == nil {
+		rules = []repository.CategoryRule{}
+	}
+
+	writeJSON(w, http.StatusOK, rules)
+}
+
+func (h *CategoryRuleHandler) GetCategoryRule(w http.ResponseWriter, r *http.Request) {
+	id, err := validation.ValidateUUID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid category rule ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.db.GetCategoryRuleByID(r.Context(), repository.GetCategoryRuleByIDParams{
+		ID:     id,
+		UserID: uid,
+	})
+	if err != nil {
+		http.Error(w, "Category rule not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rule)
+}
+
+// UpdateCategoryRule handles PUT /category-rules/{id}, replacing the whole
+// rule. Expenses filed by it before keep their category and tags.
+func (h *CategoryRuleHandler) UpdateCategoryRule(w http.ResponseWriter, r *http.Request) {
+	id, err := validation.ValidateUUID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid category rule ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	req, ok := h.decodeCategoryRule(w, r, uid)
+	if !ok {
+		return
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+
+	rule, err := h.db.UpdateCategoryRule(r.Context(), repository.UpdateCategoryRuleParams{
+		Name:               req.Name,
+		DescriptionPattern: req.DescriptionPattern,
+		MinAmount:          req.MinAmount,
+		MaxAmount:          req.MaxAmount,
+		Currency:           req.Currency,
+		CategoryID:         req.CategoryID,
+		TagIds:             req.TagIDs,
+		Priority:           req.Priority,
+		Enabled:            enabled,
+		ID:                 id,
+		UserID:             uid,
+	})
+	if err != nil {
+		http.Error(w, "Category rule not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rule)
+}
+
+func (h *CategoryRuleHandler) DeleteCategoryRule(w http.ResponseWriter, r *http.Request) {
+	id, err := validation.ValidateUUID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid category rule ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.db.DeleteCategoryRule(r.Context(), repository.DeleteCategoryRuleParams{
+		ID:     id,
+		UserID: uid,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		http.Error(w, "Category rule not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SuggestCategory handles GET /categories/suggest?description=, telling what
+// the user's rules would set on an expense with that description, and which
+// categories the user most often filed expenses with the same words under.
+// amount and currency are optional, but rules conditioned on them only match
+// when they are given.
+func (h *CategoryRuleHandler) SuggestCategory(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	expense := categorize.Expense{
+		Description: query.Get("description"),
+		Currency:    query.Get("currency"),
+	}
+	if expense.Description == "" {
+		http.Error(w, "description is required", http.StatusBadRequest)
+		return
+	}
+	if amount := query.Get("amount"); amount != "" {
+		if expense.Amount, err = money.Parse(amount); err != nil {
+			http.Error(w, "Invalid amount", http.StatusBadRequest)
+			return
+		}
+	}
+	limit := int32(5)
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.ParseInt(limitStr, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid limit value", http.StatusBadRequest)
+			return
+		}
+		limit = int32(parsedLimit)
+		validator := &validation.PaginationValidator{
+			Limit: limit,
+		}
+		if err := validator.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	rules, err := h.db.ListCategoryRules(r.Context(), uid)
+	if err != nil {
+		log.Printf("Error listing category rules: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	matched := categorize.Apply(rules, expense)
+
+	words := categorize.Words(expense.Description)
+	var counts []repository.GetCategoryWordCountsRow
+	if len(words) > 0 {
+		counts, err = h.db.GetCategoryWordCounts(r.Context(), repository.GetCategoryWordCountsParams{
+			UserID: uid,
+			Words:  words,
+		})
+		if err != nil {
+			log.Printf("Error counting category words: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	response := CategorySuggestionResponse{
+		CategoryID:  matched.CategoryID,
+		TagIDs:      matched.TagIDs,
+		RuleIDs:     matched.RuleIDs,
+		Suggestions: categorize.Suggest(words, counts, int(limit)),
+	}
+	if response.TagIDs == nil {
+		response.TagIDs = []uuid.UUID{}
+	}
+	if response.RuleIDs == nil {
+		response.RuleIDs = []uuid.UUID{}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/categorize"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/jorge-dev/centsible/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+type categoryRuleHandlerTestSuite struct {
+	mockRepo  *mocks.MockRepository
+	handler   *CategoryRuleHandler
+	userID    uuid.UUID
+	groceries repository.Category
+	dining    repository.Category
+	work      repository.Tag
+}
+
+func (s *categoryRuleHandlerTestSuite) cleanup() {
+	s.mockRepo.Reset()
+}
+
+func setupCategoryRuleHandlerTest(t *testing.T) *categoryRuleHandlerTestSuite {
+	suite := &categoryRuleHandlerTestSuite{}
+	t.Cleanup(suite.cleanup)
+
+	repo := mocks.NewMockRepository()
+	mock, ok := repo.(*mocks.MockRepository)
+	if !ok {
+		t.Fatal("could not cast to MockRepository")
+	}
+	suite.mockRepo = mock
+	suite.handler = NewCategoryRuleHandler(repo)
+	suite.userID = uuid.New()
+
+	suite.groceries = repository.Category{ID: uuid.New(), UserID: suite.userID, Name: "Groceries"}
+	suite.dining = repository.Category{ID: uuid.New(), UserID: suite.userID, Name: "Dining"}
+	suite.mockRepo.GetCategoryMock().AddCategory(suite.groceries)
+	suite.mockRepo.GetCategoryMock().AddCategory(suite.dining)
+	suite.work = repository.Tag{ID: uuid.New(), UserID: suite.userID, Name: "Work"}
+	suite.mockRepo.GetTagMock().AddTag(suite.work)
+
+	return suite
+}
+
+// serve calls a handler as the suite's user, with the {id} path parameter
+// when id is set
+func (s *categoryRuleHandlerTestSuite) serve(handler http.HandlerFunc, method, target, id, body string) *httptest.ResponseRecorder {
+	req := withUser(httptest.NewRequest(method, target, strings.NewReader(body)), s.userID)
+	if id != "" {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+func (s *categoryRuleHandlerTestSuite) addRule(rule repository.CategoryRule) repository.CategoryRule {
+	rule.ID = uuid.New()
+	rule.UserID = s.userID
+	rule.Enabled = true
+	rule.CreatedAt = time.Now()
+	s.mockRepo.GetCategoryRuleMock().AddCategoryRule(rule)
+	return rule
+}
+
+func TestCreateCategoryRule(t *testing.T) {
+	suite := setupCategoryRuleHandlerTest(t)
+	otherUsersCategory := uuid.New()
+	suite.mockRepo.GetCategoryMock().AddCategory(repository.Category{ID: otherUsersCategory, UserID: uuid.New(), Name: "Theirs"})
+
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantEnabled bool
+	}{
+		{"Pattern and category", `{"name":"Grocers","description_pattern":"*grocer*","category_id":"` + suite.groceries.ID.String() + `"}`, http.StatusCreated, true},
+		{"Amount range and tags, disabled", `{"name":"Big","min_amount":"100","max_amount":"500","tag_ids":["` + suite.work.ID.String() + `"],"enabled":false}`, http.StatusCreated, false},
+		{"Currency only", `{"name":"Euro trips","currency":"EUR","tag_ids":["` + suite.work.ID.String() + `"]}`, http.StatusCreated, true},
+		{"No condition", `{"name":"Everything","category_id":"` + suite.groceries.ID.String() + `"}`, http.StatusBadRequest, false},
+		{"No action", `{"name":"Nothing","description_pattern":"*"}`, http.StatusBadRequest, false},
+		{"Empty name", `{"name":"","description_pattern":"*","category_id":"` + suite.groceries.ID.String() + `"}`, http.StatusBadRequest, false},
+		{"Reversed amount range", `{"name":"Odd","min_amount":"50","max_amount":"10","category_id":"` + suite.groceries.ID.String() + `"}`, http.StatusBadRequest, false},
+		{"Invalid currency", `{"name":"Odd","currency":"EURO","category_id":"` + suite.groceries.ID.String() + `"}`, http.StatusBadRequest, false},
+		{"Unknown category", `{"name":"Odd","description_pattern":"*","category_id":"` + uuid.NewString() + `"}`, http.StatusBadRequest, false},
+		{"Another user's category", `{"name":"Odd","description_pattern":"*","category_id":"` + otherUsersCategory.String() + `"}`, http.StatusBadRequest, false},
+		{"Unknown tag", `{"name":"Odd","description_pattern":"*","tag_ids":["` + uuid.NewString() + `"]}`, http.StatusBadRequest, false},
+		{"Malformed JSON", `{"name":`, http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := suite.serve(suite.handler.CreateCategoryRule, http.MethodPost, "/category-rules", "", tt.body)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+			var rule repository.CategoryRule
+			if err := json.NewDecoder(w.Body).Decode(&rule); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, suite.userID, rule.UserID)
+			assert.Equal(t, tt.wantEnabled, rule.Enabled)
+		})
+	}
+}
+
+func TestListCategoryRules(t *testing.T) {
+	suite := setupCategoryRuleHandlerTest(t)
+	pattern := "*"
+	late := suite.addRule(repository.CategoryRule{Name: "Late", DescriptionPattern: &pattern, CategoryID: &suite.dining.ID, Priority: 10})
+	early := suite.addRule(repository.CategoryRule{Name: "Early", DescriptionPattern: &pattern, CategoryID: &suite.groceries.ID, Priority: -1})
+	suite.mockRepo.GetCategoryRuleMock().AddCategoryRule(repository.CategoryRule{ID: uuid.New(), UserID: uuid.New(), Name: "Someone else's"})
+
+	w := suite.serve(suite.handler.ListCategoryRules, http.MethodGet, "/category-rules", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var rules []repository.CategoryRule
+	if err := json.NewDecoder(w.Body).Decode(&rules); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, rules, 2) {
+		assert.Equal(t, early.ID, rules[0].ID)
+		assert.Equal(t, late.ID, rules[1].ID)
+	}
+}
+
+func TestUpdateAndDeleteCategoryRule(t *testing.T) {
+	suite := setupCategoryRuleHandlerTest(t)
+	pattern := "coffee*"
+	rule := suite.addRule(repository.CategoryRule{Name: "Coffee", DescriptionPattern: &pattern, CategoryID: &suite.dining.ID})
+
+	body := `{"name":"Coffee beans","description_pattern":"coffee beans*","category_id":"` + suite.groceries.ID.String() + `","priority":3}`
+	w := suite.serve(suite.handler.UpdateCategoryRule, http.MethodPut, "/", rule.ID.String(), body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var updated repository.CategoryRule
+	if err := json.NewDecoder(w.Body).Decode(&updated); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Coffee beans", updated.Name)
+	assert.Equal(t, suite.groceries.ID, *updated.CategoryID)
+	assert.Equal(t, int32(3), updated.Priority)
+
+	w = suite.serve(suite.handler.UpdateCategoryRule, http.MethodPut, "/", uuid.NewString(), body)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = suite.serve(suite.handler.UpdateCategoryRule, http.MethodPut, "/", "coffee", body)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = suite.serve(suite.handler.DeleteCategoryRule, http.MethodDelete, "/", rule.ID.String(), "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = suite.serve(suite.handler.DeleteCategoryRule, http.MethodDelete, "/", rule.ID.String(), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = suite.serve(suite.handler.GetCategoryRule, http.MethodGet, "/", rule.ID.String(), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSuggestCategory(t *testing.T) {
+	suite := setupCategoryRuleHandlerTest(t)
+	pattern := "*coffee*"
+	rule := suite.addRule(repository.CategoryRule{Name: "Coffee", DescriptionPattern: &pattern, CategoryID: &suite.dining.ID, TagIds: []uuid.UUID{suite.work.ID}})
+	for _, past := range []struct {
+		category    uuid.UUID
+		description string
+	}{
+		{suite.groceries.ID, "Coffee beans, Corner Grocer"},
+		{suite.groceries.ID, "Corner Grocer"},
+		{suite.dining.ID, "Coffee at the corner cafe"},
+	} {
+		suite.mockRepo.GetExpenseMock().AddExpense(repository.Expense{
+			ID:          uuid.New(),
+			UserID:      suite.userID,
+			Amount:      money.MustParse("5"),
+			Currency:    "CAD",
+			CategoryID:  past.category,
+			Date:        time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC),
+			Description: past.description,
+		})
+	}
+
+	w := suite.serve(suite.handler.SuggestCategory, http.MethodGet, "/categories/suggest?description=Corner+grocer", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp CategorySuggestionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, resp.CategoryID)
+	assert.Empty(t, resp.RuleIDs)
+	// "corner" is in two groceries and one dining expense, "grocer" only in
+	// groceries
+	assert.Equal(t, []categorize.Suggestion{
+		{CategoryID: suite.groceries.ID, CategoryName: "Groceries", Score: 0.833},
+		{CategoryID: suite.dining.ID, CategoryName: "Dining", Score: 0.167},
+	}, resp.Suggestions)
+
+	w = suite.serve(suite.handler.SuggestCategory, http.MethodGet, "/categories/suggest?description=Iced+coffee&limit=1", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	resp = CategorySuggestionResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, suite.dining.ID, *resp.CategoryID)
+	assert.Equal(t, []uuid.UUID{suite.work.ID}, resp.TagIDs)
+	assert.Equal(t, []uuid.UUID{rule.ID}, resp.RuleIDs)
+	assert.Len(t, resp.Suggestions, 1)
+
+	for _, query := range []string{"", "?description=Coffee&amount=lots", "?description=Coffee&limit=0"} {
+		w = suite.serve(suite.handler.SuggestCategory, http.MethodGet, "/categories/suggest"+query, "", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
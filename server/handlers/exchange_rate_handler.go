@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/jorge-dev/centsible/internal/validation"
+	"github.com/jorge-dev/centsible/server/middleware"
+)
+
+// maxRateImportSize caps the size of an uploaded exchange-rate CSV
+const maxRateImportSize = 5 << 20
+
+// rateImportColumns are the columns an exchange-rate CSV must have, in any order
+var rateImportColumns = []string{"date", "base_currency", "quote_currency", "rate"}
+
+type ExchangeRateHandler struct {
+	db repository.Repository
+}
+
+type ExchangeRateRequest struct {
+	BaseCurrency  string     `json:"base_currency"`
+	QuoteCurrency string     `json:"quote_currency"`
+	Date          string     `json:"date"`
+	Rate          money.Rate `json:"rate"`
+}
+
+type ExchangeRateResponse struct {
+	BaseCurrency  string     `json:"base_currency"`
+	QuoteCurrency string     `json:"quote_currency"`
+	Date          string     `json:"date"`
+	Rate          money.Rate `json:"rate"`
+}
+
+// ImportLineError reports why one line of an uploaded CSV was rejected
+type ImportLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type ImportRatesResponse struct {
+	Imported int64 `json:"imported"`
+}
+
+type ImportRatesErrorResponse struct {
+	Errors []ImportLineError `json:"errors"`
+}
+
+func NewExchangeRateHandler(db repository.Repository) *ExchangeRateHandler {
+	return &ExchangeRateHandler{db: db}
+}
+
+func toExchangeRateResponse(rate repository.ExchangeRate) ExchangeRateResponse {
+	return ExchangeRateResponse{
+		BaseCurrency:  rate.BaseCurrency,
+		QuoteCurrency: rate.QuoteCurrency,
+		Date:          rate.Date.Format(time.DateOnly),
+		Rate:          rate.Rate,
+	}
+}
+
+// requireAdmin writes an error response and returns false unless the
+// authenticated user is an admin
+func (h *ExchangeRateHandler) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return false
+	}
+
+	isAdmin, err := h.db.CheckUserIsAdmin(r.Context(), uid)
+	if err != nil {
+		log.Printf("Error checking user role: %v", err)
+		http.Error(w, "Error checking user role", http.StatusInternalServerError)
+		return false
+	}
+	if !isAdmin {
+		http.Error(w, "You do not have permission to manage exchange rates", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// ListExchangeRates handles GET /exchange-rates
+func (h *ExchangeRateHandler) ListExchangeRates(w http.ResponseWriter, r *http.Request) {
+	base := strings.ToUpper(r.URL.Query().Get("base"))
+	quote := strings.ToUpper(r.URL.Query().Get("quote"))
+	limit := int32(100)
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.ParseInt(limitStr, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid limit value", http.StatusBadRequest)
+			return
+		}
+		limit = int32(parsedLimit)
+	}
+	if err := (&validation.PaginationValidator{Limit: limit}).Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rates, err := h.db.ListExchangeRates(r.Context(), repository.ListExchangeRatesParams{
+		BaseCurrency:  base,
+		QuoteCurrency: quote,
+		Limit:         limit,
+	})
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error fetching exchange rates", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]ExchangeRateResponse, 0, len(rates))
+	for _, rate := range rates {
+		response = append(response, toExchangeRateResponse(rate))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpsertExchangeRate handles POST /admin/exchange-rates. Posting a pair and
+// date that already exists replaces its rate.
+func (h *ExchangeRateHandler) UpsertExchangeRate(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	var req ExchangeRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	validator := &validation.ExchangeRateValidation{
+		BaseCurrency:  strings.ToUpper(req.BaseCurrency),
+		QuoteCurrency: strings.ToUpper(req.QuoteCurrency),
+		Date:          req.Date,
+		Rate:          req.Rate,
+	}
+	if err := validator.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rate, err := h.db.UpsertExchangeRate(r.Context(), repository.UpsertExchangeRateParams{
+		BaseCurrency:  validator.BaseCurrency,
+		QuoteCurrency: validator.QuoteCurrency,
+		Date:          validator.ParsedDate,
+		Rate:          validator.Rate,
+	})
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error saving exchange rate", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toExchangeRateResponse(rate))
+}
+
+// ImportExchangeRates handles POST /admin/exchange-rates/import. The body is a
+// CSV file with a header row naming the date, base_currency, quote_currency and
+// rate columns. Either every line is imported or, if any line is invalid,
+// none are and each bad line is reported.
+func (h *ExchangeRateHandler) ImportExchangeRates(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	params, lineErrors, err := parseRateCSV(http.MaxBytesReader(w, r.Body, maxRateImportSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "CSV file is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(lineErrors) > 0 {
+		writeJSON(w, http.StatusBadRequest, ImportRatesErrorResponse{Errors: lineErrors})
+		return
+	}
+
+	imported, err := h.db.UpsertExchangeRates(r.Context(), params)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error importing exchange rates", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ImportRatesResponse{Imported: imported})
+}
+
+// parseRateCSV reads an exchange-rate CSV into parallel arrays for a bulk
+// upsert. Problems with individual lines are collected rather than returned
+// so that the caller can report all of them at once.
+func parseRateCSV(body io.Reader) (repository.UpsertExchangeRatesParams, []ImportLineError, error) {
+	var params repository.UpsertExchangeRatesParams
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return params, nil, fmt.Errorf("CSV file is empty")
+	}
+	if err != nil {
+		return params, nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range rateImportColumns {
+		if _, ok := columns[name]; !ok {
+			return params, nil, fmt.Errorf("CSV header is missing the %s column", name)
+		}
+	}
+	reader.FieldsPerRecord = len(header)
+
+	var lineErrors []ImportLineError
+	seen := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return params, nil, err
+			}
+			lineErrors = append(lineErrors, ImportLineError{Line: parseErr.StartLine, Error: parseErr.Err.Error()})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(name string) string {
+			return strings.TrimSpace(record[columns[name]])
+		}
+
+		rate, err := money.ParseRate(field("rate"))
+		if err != nil {
+			lineErrors = append(lineErrors, ImportLineError{Line: line, Error: err.Error()})
+			continue
+		}
+
+		validator := &validation.ExchangeRateValidation{
+			BaseCurrency:  strings.ToUpper(field("base_currency")),
+			QuoteCurrency: strings.ToUpper(field("quote_currency")),
+			Date:          field("date"),
+			Rate:          rate,
+		}
+		if err := validator.Validate(); err != nil {
+			lineErrors = append(lineErrors, ImportLineError{Line: line, Error: err.Error()})
+			continue
+		}
+
+		// One statement cannot update the same row twice, and a file that
+		// gives two rates for the same day is most likely a mistake
+		key := validator.BaseCurrency + validator.QuoteCurrency + validator.Date
+		if first, ok := seen[key]; ok {
+			lineErrors = append(lineErrors, ImportLineError{
+				Line:  line,
+				Error: fmt.Sprintf("duplicate rate for %s/%s on %s, first given on line %d", validator.BaseCurrency, validator.QuoteCurrency, validator.Date, first),
+			})
+			continue
+		}
+		seen[key] = line
+
+		params.BaseCurrencies = append(params.BaseCurrencies, validator.BaseCurrency)
+		params.QuoteCurrencies = append(params.QuoteCurrencies, validator.QuoteCurrency)
+		params.Dates = append(params.Dates, validator.ParsedDate)
+		params.Rates = append(params.Rates, validator.Rate.String())
+	}
+
+	if len(params.Rates) == 0 && len(lineErrors) == 0 {
+		return params, nil, fmt.Errorf("CSV file has no rates")
+	}
+	return params, lineErrors, nil
+}
+
+// DeleteExchangeRate handles DELETE /admin/exchange-rates/{base}/{quote}/{date}
+func (h *ExchangeRateHandler) DeleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	base := strings.ToUpper(chi.URLParam(r, "base"))
+	quote := strings.ToUpper(chi.URLParam(r, "quote"))
+	date, err := time.Parse(time.DateOnly, chi.URLParam(r, "date"))
+	if err != nil {
+		http.Error(w, validation.ErrInvalidDate.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.db.DeleteExchangeRate(r.Context(), repository.DeleteExchangeRateParams{
+		BaseCurrency:  base,
+		QuoteCurrency: quote,
+		Date:          date,
+	})
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error deleting exchange rate", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		http.Error(w, "Exchange rate not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/jorge-dev/centsible/internal/repository/mocks"
+	"github.com/jorge-dev/centsible/server/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+type exchangeRateHandlerTestSuite struct {
+	mockRepo *mocks.MockRepository
+	handler  *ExchangeRateHandler
+	adminID  uuid.UUID
+	userID   uuid.UUID
+}
+
+func (s *exchangeRateHandlerTestSuite) cleanup() {
+	s.mockRepo.Reset()
+}
+
+func setupExchangeRateHandlerTest(t *testing.T) *exchangeRateHandlerTestSuite {
+	suite := &exchangeRateHandlerTestSuite{}
+	t.Cleanup(suite.cleanup)
+
+	repo := mocks.NewMockRepository()
+	mock, ok := repo.(*mocks.MockRepository)
+	if !ok {
+		t.Fatal("could not cast to MockRepository")
+	}
+	suite.mockRepo = mock
+	suite.handler = NewExchangeRateHandler(repo)
+
+	suite.adminID = uuid.New()
+	suite.userID = uuid.New()
+	suite.mockRepo.GetUserMock().SetAdmin(suite.adminID.String(), true)
+
+	suite.mockRepo.GetExchangeRateMock().AddExchangeRate(repository.ExchangeRate{
+		BaseCurrency:  "EUR",
+		QuoteCurrency: "USD",
+		Date:          time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Rate:          money.MustParseRate("1.1"),
+	})
+	suite.mockRepo.GetExchangeRateMock().AddExchangeRate(repository.ExchangeRate{
+		BaseCurrency:  "EUR",
+		QuoteCurrency: "USD",
+		Date:          time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC),
+		Rate:          money.MustParseRate("1.0945"),
+	})
+	suite.mockRepo.GetExchangeRateMock().AddExchangeRate(repository.ExchangeRate{
+		BaseCurrency:  "USD",
+		QuoteCurrency: "JPY",
+		Date:          time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC),
+		Rate:          money.MustParseRate("141.87"),
+	})
+
+	return suite
+}
+
+func withUser(req *http.Request, userID uuid.UUID) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID.String()))
+}
+
+func TestListExchangeRates(t *testing.T) {
+	suite := setupExchangeRateHandlerTest(t)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantRates  []string
+	}{
+		{
+			name:       "All rates, newest first per pair",
+			query:      "",
+			wantStatus: http.StatusOK,
+			wantRates:  []string{"1.0945", "1.1", "141.87"},
+		},
+		{
+			name:       "Filtered by base currency",
+			query:      "?base=usd",
+			wantStatus: http.StatusOK,
+			wantRates:  []string{"141.87"},
+		},
+		{
+			name:       "Limited",
+			query:      "?limit=1",
+			wantStatus: http.StatusOK,
+			wantRates:  []string{"1.0945"},
+		},
+		{
+			name:       "Invalid limit",
+			query:      "?limit=0",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := withUser(httptest.NewRequest(http.MethodGet, "/exchange-rates"+tt.query, nil), suite.userID)
+			w := httptest.NewRecorder()
+			suite.handler.ListExchangeRates(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response []ExchangeRateResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			var rates []string
+			for _, rate := range response {
+				rates = append(rates, rate.Rate.String())
+			}
+			assert.Equal(t, tt.wantRates, rates)
+		})
+	}
+}
+
+func TestUpsertExchangeRate(t *testing.T) {
+	suite := setupExchangeRateHandlerTest(t)
+
+	tests := []struct {
+		name       string
+		userID     uuid.UUID
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "Valid rate",
+			userID:     suite.adminID,
+			body:       `{"base_currency": "gbp", "quote_currency": "USD", "date": "2024-01-02", "rate": "1.2701"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Not an admin",
+			userID:     suite.userID,
+			body:       `{"base_currency": "GBP", "quote_currency": "USD", "date": "2024-01-02", "rate": "1.2701"}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Same currency",
+			userID:     suite.adminID,
+			body:       `{"base_currency": "USD", "quote_currency": "USD", "date": "2024-01-02", "rate": 1}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Zero rate",
+			userID:     suite.adminID,
+			body:       `{"base_currency": "GBP", "quote_currency": "USD", "date": "2024-01-02", "rate": 0}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Timestamp instead of date",
+			userID:     suite.adminID,
+			body:       `{"base_currency": "GBP", "quote_currency": "USD", "date": "2024-01-02T00:00:00Z", "rate": 1.27}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Unknown currency",
+			userID:     suite.adminID,
+			body:       `{"base_currency": "GBX", "quote_currency": "USD", "date": "2024-01-02", "rate": 1.27}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := withUser(httptest.NewRequest(http.MethodPost, "/admin/exchange-rates", strings.NewReader(tt.body)), tt.userID)
+			w := httptest.NewRecorder()
+			suite.handler.UpsertExchangeRate(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+
+	saved, ok := suite.mockRepo.GetExchangeRateMock().GetExchangeRate("GBP", "USD", time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.True(t, saved.Rate.Equal(money.MustParseRate("1.2701")))
+}
+
+func TestImportExchangeRates(t *testing.T) {
+	suite := setupExchangeRateHandlerTest(t)
+
+	tests := []struct {
+		name         string
+		userID       uuid.UUID
+		csv          string
+		wantStatus   int
+		wantImported int64
+		wantErrors   []ImportLineError
+	}{
+		{
+			name:   "Valid file with columns in any order",
+			userID: suite.adminID,
+			csv: "base_currency,quote_currency,date,rate\n" +
+				"EUR,USD,2024-01-03,1.0919\n" +
+				"eur,cad,2024-01-03, 1.4601\n",
+			wantStatus:   http.StatusOK,
+			wantImported: 2,
+		},
+		{
+			name:       "Not an admin",
+			userID:     suite.userID,
+			csv:        "date,base_currency,quote_currency,rate\n2024-01-03,EUR,USD,1.0919\n",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Missing column",
+			userID:     suite.adminID,
+			csv:        "date,base_currency,rate\n2024-01-03,EUR,1.0919\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Header only",
+			userID:     suite.adminID,
+			csv:        "date,base_currency,quote_currency,rate\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "Every bad line is reported",
+			userID: suite.adminID,
+			csv: "date,base_currency,quote_currency,rate\n" +
+				"2024-01-04,EUR,USD,1.0945\n" +
+				"01/04/2024,EUR,CAD,1.46\n" +
+				"2024-01-04,EUR,USD,1.0950\n" +
+				"2024-01-04,EUR,JPY,abc\n" +
+				"2024-01-04,EUR\n",
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []ImportLineError{
+				{Line: 3, Error: "invalid date format"},
+				{Line: 4, Error: "duplicate rate for EUR/USD on 2024-01-04, first given on line 2"},
+				{Line: 5, Error: `invalid decimal amount: "abc"`},
+				{Line: 6, Error: "wrong number of fields"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := withUser(httptest.NewRequest(http.MethodPost, "/admin/exchange-rates/import", bytes.NewBufferString(tt.csv)), tt.userID)
+			w := httptest.NewRecorder()
+			suite.handler.ImportExchangeRates(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantImported > 0 {
+				var response ImportRatesResponse
+				if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantImported, response.Imported)
+			}
+			if tt.wantErrors != nil {
+				var response ImportRatesErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantErrors, response.Errors)
+			}
+		})
+	}
+
+	// Nothing from the rejected file was saved
+	_, ok := suite.mockRepo.GetExchangeRateMock().GetExchangeRate("EUR", "USD", time.Date(2024, time.January, 4, 0, 0, 0, 0, time.UTC))
+	assert.False(t, ok)
+
+	saved, ok := suite.mockRepo.GetExchangeRateMock().GetExchangeRate("EUR", "CAD", time.Date(2024, time.January, 3, 0, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.True(t, saved.Rate.Equal(money.MustParseRate("1.4601")))
+}
+
+func TestDeleteExchangeRate(t *testing.T) {
+	suite := setupExchangeRateHandlerTest(t)
+
+	tests := []struct {
+		name       string
+		userID     uuid.UUID
+		base       string
+		quote      string
+		date       string
+		wantStatus int
+	}{
+		{"Not an admin", suite.userID, "EUR", "USD", "2024-01-01", http.StatusForbidden},
+		{"Existing rate", suite.adminID, "eur", "usd", "2024-01-01", http.StatusNoContent},
+		{"Already deleted", suite.adminID, "EUR", "USD", "2024-01-01", http.StatusNotFound},
+		{"Invalid date", suite.adminID, "EUR", "USD", "January", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/admin/exchange-rates/"+tt.base+"/"+tt.quote+"/"+tt.date, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("base", tt.base)
+			rctx.URLParams.Add("quote", tt.quote)
+			rctx.URLParams.Add("date", tt.date)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req = withUser(req, tt.userID)
+
+			w := httptest.NewRecorder()
+			suite.handler.DeleteExchangeRate(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
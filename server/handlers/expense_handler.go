@@ -9,6 +9,8 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/categorize"
+	"github.com/jorge-dev/centsible/internal/money"
 	"github.com/jorge-dev/centsible/internal/repository"
 	"github.com/jorge-dev/centsible/internal/validation"
 	"github.com/jorge-dev/centsible/server/middleware"
@@ -19,24 +21,71 @@ type ExpenseHandler struct {
 }
 
 type ExpenseRequest struct {
-	Amount      float64   `json:"amount"`
-	Currency    string    `json:"currency"`
-	CategoryID  uuid.UUID `json:"category_id"`
-	Date        string    `json:"date"`
-	Description string    `json:"description"`
+	Amount      money.Amount `json:"amount"`
+	Currency    string       `json:"currency"`
+	CategoryID  uuid.UUID    `json:"category_id"`
+	Date        string       `json:"date"`
+	Description string       `json:"description"`
+	ExternalID  string       `json:"external_id"` // Create only
+}
+
+// DuplicateExpenseResponse is the 409 Conflict body of an expense that likely
+// duplicates ones the user already has
+type DuplicateExpenseResponse struct {
+	Error      string               `json:"error"`
+	Duplicates []repository.Expense `json:"duplicates"`
+}
+
+// ExpenseDuplicatePair is an expense and the earlier one it likely duplicates
+type ExpenseDuplicatePair struct {
+	Original  repository.Expense `json:"original"`
+	Duplicate repository.Expense `json:"duplicate"`
 }
 
 func NewExpenseHandler(db repository.Repository) *ExpenseHandler {
 	return &ExpenseHandler{db: db}
 }
 
-// CreateExpense handles POST /expenses
+// CreateExpense handles POST /expenses. An expense with the same amount,
+// currency, day, description and category as an existing one, or with an
+// external ID already used, is refused with 409 Conflict and the expenses it
+// matches. allow_duplicate=true saves it anyway unless its external ID is
+// taken.
 func (h *ExpenseHandler) CreateExpense(w http.ResponseWriter, r *http.Request) {
 	var req ExpenseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	allowDuplicate, err := queryBool(r.URL.Query(), "allow_duplicate")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	// The user's category rules file the expense when it comes without a
+	// category, and tag it either way
+	rules, err := h.db.ListCategoryRules(r.Context(), uid)
+	if err != nil {
+		log.Printf("Error listing category rules: %v", err)
+		http.Error(w, "Error creating expense", http.StatusInternalServerError)
+		return
+	}
+	matched := categorize.Apply(rules, categorize.Expense{
+		Description: req.Description,
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+	})
+	if req.CategoryID == uuid.Nil && matched.CategoryID != nil {
+		req.CategoryID = *matched.CategoryID
+	}
 
 	validator := &validation.ExpenseValidation{
 		Amount:      req.Amount,
@@ -44,6 +93,7 @@ func (h *ExpenseHandler) CreateExpense(w http.ResponseWriter, r *http.Request) {
 		CategoryID:  req.CategoryID,
 		Description: req.Description,
 		Date:        req.Date,
+		ExternalID:  req.ExternalID,
 	}
 
 	if err := validator.Validate(); err != nil {
@@ -51,15 +101,34 @@ func (h *ExpenseHandler) CreateExpense(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := r.Context().Value(middleware.UserIDKey).(string)
-	uid, err := validation.ValidateUUID(userID)
+	date, _ := validation.ValidateDate(req.Date) // Already validated by ExpenseValidation
+
+	duplicateArgs := repository.FindDuplicateExpensesParams{
+		UserID:      uid,
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		Date:        date,
+		Description: req.Description,
+		CategoryID:  req.CategoryID,
+		ExternalID:  req.ExternalID,
+	}
+	duplicates, err := h.db.FindDuplicateExpenses(r.Context(), duplicateArgs)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		http.Error(w, "Error checking for duplicate expenses", http.StatusInternalServerError)
+		return
+	}
+	if len(duplicates) > 0 && (!allowDuplicate || expenseExternalIDTaken(duplicates, req.ExternalID)) {
+		writeJSON(w, http.StatusConflict, DuplicateExpenseResponse{
+			Error:      "Expense likely duplicates an existing one",
+			Duplicates: duplicates,
+		})
 		return
 	}
 
-	date, _ := validation.ValidateDate(req.Date) // Already validated by ExpenseValidation
-
+	var externalID *string
+	if req.ExternalID != "" {
+		externalID = &req.ExternalID
+	}
 	expense, err := h.db.CreateExpense(r.Context(), repository.CreateExpenseParams{
 		ID:          uuid.New(),
 		UserID:      uid,
@@ -68,7 +137,21 @@ func (h *ExpenseHandler) CreateExpense(w http.ResponseWriter, r *http.Request) {
 		CategoryID:  req.CategoryID,
 		Date:        date,
 		Description: req.Description,
+		ExternalID:  externalID,
+		TagIds:      matched.TagIDs,
 	})
+	if repository.IsUniqueViolation(err, repository.ExpenseExternalIDIndex) {
+		// Saved by a request running alongside this one, after the check above
+		duplicates, _ = h.db.FindDuplicateExpenses(r.Context(), duplicateArgs)
+		if duplicates == nil {
+			duplicates = []repository.Expense{}
+		}
+		writeJSON(w, http.StatusConflict, DuplicateExpenseResponse{
+			Error:      "Expense likely duplicates an existing one",
+			Duplicates: duplicates,
+		})
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error creating expense", http.StatusInternalServerError)
 		return
@@ -107,7 +190,8 @@ func (h *ExpenseHandler) GetExpenseByID(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(expense)
 }
 
-// ListExpenses handles GET /expenses
+// ListExpenses handles GET /expenses, newest first unless sorted otherwise.
+// See parseListQuery for paging, sorting and filtering.
 func (h *ExpenseHandler) ListExpenses(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.UserIDKey).(string)
 	uid, err := uuid.Parse(userID)
@@ -116,13 +200,72 @@ func (h *ExpenseHandler) ListExpenses(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	expenses, err := h.db.ListExpenses(r.Context(), uid)
+	q, err := parseListQuery(r.URL.Query(), transactionSorts, "desc")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	expenses, err := h.db.ListExpensesPage(r.Context(), repository.ListExpensesPageParams{
+		UserID:      uid,
+		MinAmount:   q.minAmount,
+		MaxAmount:   q.maxAmount,
+		Currency:    q.currency,
+		CategoryID:  q.categoryID,
+		TagID:       q.tagID,
+		Search:      q.search,
+		StartDate:   q.startDate,
+		EndDate:     q.endDate,
+		AfterID:     q.afterID(),
+		Sort:        q.sort,
+		Descending:  q.fetchDescending(),
+		AfterAmount: q.afterAmount(),
+		AfterTime:   q.afterTime(),
+		PageSize:    q.pageSize(),
+	})
 	if err != nil {
 		http.Error(w, "Error fetching expenses", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(expenses)
+
+	writeJSON(w, http.StatusOK, paginate(q, expenses, func(expense repository.Expense) listCursor {
+		return q.cursorAt(expense.Amount, expense.CreatedAt, expense.Date, "", expense.ID)
+	}))
+}
+
+// ListDuplicateExpenses handles GET /expenses/duplicates. It pairs every
+// expense that likely duplicates an earlier one with that earlier expense.
+func (h *ExpenseHandler) ListDuplicateExpenses(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.db.ListDuplicateExpenses(r.Context(), uid)
+	if err != nil {
+		http.Error(w, "Error fetching duplicate expenses", http.StatusInternalServerError)
+		return
+	}
+
+	// Every group of duplicates includes its original
+	originals := make(map[uuid.UUID]repository.Expense)
+	for _, row := range rows {
+		if row.ID == row.OriginalID {
+			originals[row.ID] = duplicateExpense(row)
+		}
+	}
+	pairs := make([]ExpenseDuplicatePair, 0, len(rows)-len(originals))
+	for _, row := range rows {
+		if row.ID != row.OriginalID {
+			pairs = append(pairs, ExpenseDuplicatePair{
+				Original:  originals[row.OriginalID],
+				Duplicate: duplicateExpense(row),
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, pairs)
 }
 
 // GetExpensesByCategory handles GET /expenses/category/{category}
@@ -410,3 +553,30 @@ func (h *ExpenseHandler) GetRecentExpenses(w http.ResponseWriter, r *http.Reques
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(expenses)
 }
+
+// expenseExternalIDTaken reports whether one of the duplicates already has the
+// external ID, which no two expenses may share
+func expenseExternalIDTaken(duplicates []repository.Expense, externalID string) bool {
+	for _, duplicate := range duplicates {
+		if externalID != "" && duplicate.ExternalID != nil && *duplicate.ExternalID == externalID {
+			return true
+		}
+	}
+	return false
+}
+
+func duplicateExpense(row repository.ListDuplicateExpensesRow) repository.Expense {
+	return repository.Expense{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		Amount:      row.Amount,
+		Currency:    row.Currency,
+		CategoryID:  row.CategoryID,
+		Date:        row.Date,
+		Description: row.Description,
+		CreatedAt:   row.CreatedAt,
+		UpdatedAt:   row.UpdatedAt,
+		DeletedAt:   row.DeletedAt,
+		ExternalID:  row.ExternalID,
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 	"github.com/jorge-dev/centsible/internal/repository"
 	"github.com/jorge-dev/centsible/internal/repository/mocks"
 	"github.com/jorge-dev/centsible/server/middleware"
@@ -53,7 +54,7 @@ func setupExpenseHandlerTest(t *testing.T) *expenseHandlerTestSuite {
 	suite.testExpense = repository.Expense{
 		ID:          uuid.New(),
 		UserID:      suite.testUserID,
-		Amount:      100.50,
+		Amount:      money.MustParse("100.50"),
 		Currency:    "USD",
 		CategoryID:  categoryID,
 		Date:        now,
@@ -78,7 +79,7 @@ func TestCreateExpense(t *testing.T) {
 		{
 			name: "Valid expense",
 			reqBody: ExpenseRequest{
-				Amount:      100.50,
+				Amount:      money.MustParse("100.50"),
 				Currency:    "USD",
 				CategoryID:  uuid.New(),
 				Date:        time.Now().Format(time.RFC3339),
@@ -89,7 +90,7 @@ func TestCreateExpense(t *testing.T) {
 		{
 			name: "Invalid date format",
 			reqBody: ExpenseRequest{
-				Amount:      100.50,
+				Amount:      money.MustParse("100.50"),
 				Currency:    "USD",
 				CategoryID:  uuid.New(),
 				Date:        "invalid-date",
@@ -132,7 +133,7 @@ func TestCreateExpense_EdgeCases(t *testing.T) {
 		{
 			name: "Missing user context",
 			reqBody: ExpenseRequest{
-				Amount:   100.50,
+				Amount:   money.MustParse("100.50"),
 				Currency: "USD",
 			},
 			userID:     "",
@@ -141,7 +142,7 @@ func TestCreateExpense_EdgeCases(t *testing.T) {
 		{
 			name: "Zero amount",
 			reqBody: ExpenseRequest{
-				Amount:     0,
+				Amount:     money.MustParse("0"),
 				Currency:   "USD",
 				CategoryID: uuid.New(),
 				Date:       time.Now().Format(time.RFC3339),
@@ -152,7 +153,7 @@ func TestCreateExpense_EdgeCases(t *testing.T) {
 		{
 			name: "Empty currency",
 			reqBody: ExpenseRequest{
-				Amount:     100.50,
+				Amount:     money.MustParse("100.50"),
 				CategoryID: uuid.New(),
 				Date:       time.Now().Format(time.RFC3339),
 			},
@@ -162,7 +163,7 @@ func TestCreateExpense_EdgeCases(t *testing.T) {
 		{
 			name: "Very long description",
 			reqBody: ExpenseRequest{
-				Amount:      100.50,
+				Amount:      money.MustParse("100.50"),
 				Currency:    "USD",
 				CategoryID:  uuid.New(),
 				Date:        time.Now().Format(time.RFC3339),
@@ -174,7 +175,7 @@ func TestCreateExpense_EdgeCases(t *testing.T) {
 		{
 			name: "Invalid currency code",
 			reqBody: ExpenseRequest{
-				Amount:      100.50,
+				Amount:      money.MustParse("100.50"),
 				Currency:    "INVALID",
 				CategoryID:  uuid.New(),
 				Date:        time.Now().Format(time.RFC3339),
@@ -186,7 +187,19 @@ func TestCreateExpense_EdgeCases(t *testing.T) {
 		{
 			name: "Negative amount",
 			reqBody: ExpenseRequest{
-				Amount:      -100.50,
+				Amount:      money.MustParse("-100.50"),
+				Currency:    "USD",
+				CategoryID:  uuid.New(),
+				Date:        time.Now().Format(time.RFC3339),
+				Description: "Test expense",
+			},
+			userID:     suite.testUserID.String(),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Fraction of a cent",
+			reqBody: ExpenseRequest{
+				Amount:      money.MustParse("100.505"),
 				Currency:    "USD",
 				CategoryID:  uuid.New(),
 				Date:        time.Now().Format(time.RFC3339),
@@ -214,6 +227,280 @@ func TestCreateExpense_EdgeCases(t *testing.T) {
 	}
 }
 
+func TestCreateExpense_DecimalAmounts(t *testing.T) {
+	suite := setupExpenseHandlerTest(t)
+	categoryID := uuid.New()
+
+	tests := []struct {
+		name       string
+		amount     string
+		currency   string
+		wantStatus int
+		wantAmount string
+	}{
+		{"Decimal string", `"0.10"`, "USD", http.StatusCreated, "0.1"},
+		{"Number", `19.99`, "USD", http.StatusCreated, "19.99"},
+		{"Whole yen", `"1500"`, "JPY", http.StatusCreated, "1500"},
+		{"Fraction of a yen", `"1500.5"`, "JPY", http.StatusBadRequest, ""},
+		{"Three place dinar", `"1.125"`, "KWD", http.StatusCreated, "1.125"},
+		{"Not a number", `"ten"`, "USD", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"amount": %s, "currency": %q, "category_id": %q, "date": %q, "description": "Test expense"}`,
+				tt.amount, tt.currency, categoryID, time.Now().Format(time.RFC3339))
+			req := httptest.NewRequest(http.MethodPost, "/api/expenses", bytes.NewBufferString(body))
+			ctx := context.WithValue(req.Context(), middleware.UserIDKey, suite.testUserID.String())
+			req = req.WithContext(ctx)
+
+			w := httptest.NewRecorder()
+			suite.handler.CreateExpense(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusCreated {
+				var response map[string]json.RawMessage
+				err := json.NewDecoder(w.Body).Decode(&response)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantAmount, string(response["amount"]))
+			}
+		})
+	}
+}
+
+func TestCreateExpense_Duplicates(t *testing.T) {
+	suite := setupExpenseHandlerTest(t)
+	existing := suite.testExpense
+
+	tests := []struct {
+		name       string
+		query      string
+		reqBody    ExpenseRequest
+		wantStatus int
+	}{
+		{
+			name: "Same expense again",
+			reqBody: ExpenseRequest{
+				Amount:      money.MustParse("100.5"),
+				Currency:    "USD",
+				CategoryID:  existing.CategoryID,
+				Date:        existing.Date.Format(time.RFC3339),
+				Description: " test EXPENSE",
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name: "Same expense in another category",
+			reqBody: ExpenseRequest{
+				Amount:      existing.Amount,
+				Currency:    "USD",
+				CategoryID:  uuid.New(),
+				Date:        existing.Date.Format(time.RFC3339),
+				Description: existing.Description,
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:  "Same expense allowed",
+			query: "?allow_duplicate=true",
+			reqBody: ExpenseRequest{
+				Amount:      existing.Amount,
+				Currency:    "USD",
+				CategoryID:  existing.CategoryID,
+				Date:        existing.Date.Format(time.RFC3339),
+				Description: existing.Description,
+				ExternalID:  "bank-42",
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:  "External ID taken",
+			query: "?allow_duplicate=true",
+			reqBody: ExpenseRequest{
+				Amount:      money.MustParse("3.25"),
+				Currency:    "USD",
+				CategoryID:  existing.CategoryID,
+				Date:        time.Now().Format(time.RFC3339),
+				Description: "Coffee",
+				ExternalID:  "bank-42",
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:  "Invalid allow_duplicate",
+			query: "?allow_duplicate=maybe",
+			reqBody: ExpenseRequest{
+				Amount:      money.MustParse("3.25"),
+				Currency:    "USD",
+				CategoryID:  existing.CategoryID,
+				Date:        time.Now().Format(time.RFC3339),
+				Description: "Coffee",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.reqBody)
+			req := httptest.NewRequest(http.MethodPost, "/api/expenses"+tt.query, bytes.NewBuffer(body))
+			ctx := context.WithValue(req.Context(), middleware.UserIDKey, suite.testUserID.String())
+			req = req.WithContext(ctx)
+
+			w := httptest.NewRecorder()
+			suite.handler.CreateExpense(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if w.Code == http.StatusConflict {
+				var resp DuplicateExpenseResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				assert.NotEmpty(t, resp.Duplicates)
+			}
+		})
+	}
+}
+
+// racedDuplicates misses the duplicates of the first check for them, as when
+// another request saves one right after the check
+type racedDuplicates struct {
+	repository.Repository
+	checked bool
+}
+
+func (r *racedDuplicates) miss() bool {
+	missed := !r.checked
+	r.checked = true
+	return missed
+}
+
+func (r *racedDuplicates) FindDuplicateExpenses(ctx context.Context, arg repository.FindDuplicateExpensesParams) ([]repository.Expense, error) {
+	if r.miss() {
+		return nil, nil
+	}
+	return r.Repository.FindDuplicateExpenses(ctx, arg)
+}
+
+func (r *racedDuplicates) FindDuplicateIncome(ctx context.Context, arg repository.FindDuplicateIncomeParams) ([]repository.Income, error) {
+	if r.miss() {
+		return nil, nil
+	}
+	return r.Repository.FindDuplicateIncome(ctx, arg)
+}
+
+func (r *racedDuplicates) MatchImportedExpenses(ctx context.Context, arg repository.MatchImportedExpensesParams) ([]repository.MatchImportedExpensesRow, error) {
+	if r.miss() {
+		return nil, nil
+	}
+	return r.Repository.MatchImportedExpenses(ctx, arg)
+}
+
+func TestCreateExpense_ExternalIDRace(t *testing.T) {
+	suite := setupExpenseHandlerTest(t)
+	externalID := "bank-7"
+	saved := suite.testExpense
+	saved.ID = uuid.New()
+	saved.ExternalID = &externalID
+	suite.mockRepo.GetExpenseMock().AddExpense(saved)
+	handler := NewExpenseHandler(&racedDuplicates{Repository: suite.mockRepo})
+
+	body, _ := json.Marshal(ExpenseRequest{
+		Amount:      money.MustParse("3.25"),
+		Currency:    "USD",
+		CategoryID:  saved.CategoryID,
+		Date:        time.Now().Format(time.RFC3339),
+		Description: "Coffee",
+		ExternalID:  externalID,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/expenses", bytes.NewBuffer(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, suite.testUserID.String()))
+	w := httptest.NewRecorder()
+	handler.CreateExpense(w, req)
+
+	// The unique index catches what the check missed
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var resp DuplicateExpenseResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	if assert.Len(t, resp.Duplicates, 1) {
+		assert.Equal(t, saved.ID, resp.Duplicates[0].ID)
+	}
+}
+
+func TestCreateExpense_CategoryRules(t *testing.T) {
+	suite := setupExpenseHandlerTest(t)
+	dining, explicit, work := uuid.New(), uuid.New(), uuid.New()
+	suite.mockRepo.GetTagMock().AddTag(repository.Tag{ID: work, UserID: suite.testUserID, Name: "Work"})
+	pattern := "*coffee*"
+	suite.mockRepo.GetCategoryRuleMock().AddCategoryRule(repository.CategoryRule{
+		ID:                 uuid.New(),
+		UserID:             suite.testUserID,
+		Name:               "Coffee",
+		DescriptionPattern: &pattern,
+		CategoryID:         &dining,
+		TagIds:             []uuid.UUID{work},
+		Enabled:            true,
+	})
+
+	tests := []struct {
+		name         string
+		reqBody      ExpenseRequest
+		wantStatus   int
+		wantCategory uuid.UUID
+		wantTags     []string
+	}{
+		{
+			name:         "Rule files an expense without a category",
+			reqBody:      ExpenseRequest{Amount: money.MustParse("4.50"), Currency: "USD", Date: "2024-05-01T00:00:00Z", Description: "Coffee with client"},
+			wantStatus:   http.StatusCreated,
+			wantCategory: dining,
+			wantTags:     []string{"Work"},
+		},
+		{
+			name:         "Given category wins over the rule's",
+			reqBody:      ExpenseRequest{Amount: money.MustParse("12"), Currency: "USD", CategoryID: explicit, Date: "2024-05-01T00:00:00Z", Description: "Coffee beans"},
+			wantStatus:   http.StatusCreated,
+			wantCategory: explicit,
+			wantTags:     []string{"Work"},
+		},
+		{
+			name:       "No category and no matching rule",
+			reqBody:    ExpenseRequest{Amount: money.MustParse("30"), Currency: "USD", Date: "2024-05-01T00:00:00Z", Description: "Fuel"},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.reqBody)
+			req := withUser(httptest.NewRequest(http.MethodPost, "/api/expenses", bytes.NewBuffer(body)), suite.testUserID)
+			w := httptest.NewRecorder()
+			suite.handler.CreateExpense(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+			var expense repository.Expense
+			if err := json.NewDecoder(w.Body).Decode(&expense); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.wantCategory, expense.CategoryID)
+
+			tags, err := suite.mockRepo.ListExpenseTags(context.Background(), repository.ListExpenseTagsParams{
+				ExpenseID: expense.ID,
+				UserID:    suite.testUserID,
+			})
+			assert.NoError(t, err)
+			var names []string
+			for _, tag := range tags {
+				names = append(names, tag.Name)
+			}
+			assert.Equal(t, tt.wantTags, names)
+		})
+	}
+}
+
 func TestGetExpenseByID(t *testing.T) {
 	suite := setupExpenseHandlerTest(t)
 
@@ -279,7 +566,7 @@ func TestUpdateExpense(t *testing.T) {
 			name:      "Valid update",
 			expenseID: suite.testExpense.ID.String(),
 			reqBody: ExpenseRequest{
-				Amount:      200.50,
+				Amount:      money.MustParse("200.50"),
 				Currency:    "EUR",
 				CategoryID:  suite.testExpense.CategoryID,
 				Date:        time.Now().Format(time.RFC3339),
@@ -291,7 +578,7 @@ func TestUpdateExpense(t *testing.T) {
 			name:      "Invalid expense ID",
 			expenseID: "invalid-uuid",
 			reqBody: ExpenseRequest{
-				Amount: 200.50,
+				Amount: money.MustParse("200.50"),
 			},
 			wantStatus: http.StatusBadRequest,
 		},
@@ -329,7 +616,7 @@ func TestUpdateExpense_EdgeCases(t *testing.T) {
 			name:      "Partial update - only amount",
 			expenseID: suite.testExpense.ID.String(),
 			reqBody: ExpenseRequest{
-				Amount: 299.99,
+				Amount: money.MustParse("299.99"),
 			},
 			setupMock:  nil,
 			wantStatus: http.StatusOK,
@@ -351,7 +638,7 @@ func TestUpdateExpense_EdgeCases(t *testing.T) {
 			name:      "Update non-existent expense",
 			expenseID: uuid.New().String(),
 			reqBody: ExpenseRequest{
-				Amount: 150.00,
+				Amount: money.MustParse("150.00"),
 			},
 			setupMock:  nil,
 			wantStatus: http.StatusNotFound,
@@ -438,10 +725,44 @@ func TestListExpenses(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	var expenses []repository.Expense
-	err := json.NewDecoder(w.Body).Decode(&expenses)
+	var page Page[repository.Expense]
+	err := json.NewDecoder(w.Body).Decode(&page)
 	assert.NoError(t, err)
-	assert.NotEmpty(t, expenses)
+	assert.NotEmpty(t, page.Items)
+}
+
+func TestListDuplicateExpenses(t *testing.T) {
+	suite := setupExpenseHandlerTest(t)
+	original := suite.testExpense
+
+	duplicate := original
+	duplicate.ID = uuid.New()
+	duplicate.Description = "TEST EXPENSE "
+	duplicate.CreatedAt = original.CreatedAt.Add(time.Minute)
+	suite.mockRepo.GetExpenseMock().AddExpense(duplicate)
+
+	different := original
+	different.ID = uuid.New()
+	different.Amount = money.MustParse("7")
+	suite.mockRepo.GetExpenseMock().AddExpense(different)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/expenses/duplicates", nil)
+	ctx := context.WithValue(req.Context(), middleware.UserIDKey, suite.testUserID.String())
+	req = req.WithContext(ctx)
+
+	w := httptest.NewRecorder()
+	suite.handler.ListDuplicateExpenses(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var pairs []ExpenseDuplicatePair
+	if err := json.NewDecoder(w.Body).Decode(&pairs); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, pairs, 1) {
+		assert.Equal(t, original.ID, pairs[0].Original.ID)
+		assert.Equal(t, duplicate.ID, pairs[0].Duplicate.ID)
+	}
 }
 
 func TestGetMonthlyExpenseTotal(t *testing.T) {
@@ -516,14 +837,14 @@ func TestGetMonthlyExpenseTotal_EdgeCases(t *testing.T) {
 				suite.mockRepo.GetExpenseMock().AddExpense(repository.Expense{
 					ID:       uuid.New(),
 					UserID:   suite.testUserID,
-					Amount:   100.50,
+					Amount:   money.MustParse("100.50"),
 					Currency: "USD",
 					Date:     now,
 				})
 				suite.mockRepo.GetExpenseMock().AddExpense(repository.Expense{
 					ID:       uuid.New(),
 					UserID:   suite.testUserID,
-					Amount:   95.50,
+					Amount:   money.MustParse("95.50"),
 					Currency: "EUR",
 					Date:     now,
 				})
@@ -0,0 +1,595 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/jorge-dev/centsible/internal/statement"
+	"github.com/jorge-dev/centsible/internal/validation"
+	"github.com/jorge-dev/centsible/server/middleware"
+)
+
+const (
+	// exportPageSize is how many expenses or income records an export loads
+	// at a time
+	exportPageSize = 500
+
+	// exportVersion is the version of the UserExport layout, which imports
+	// check before reading one
+	exportVersion = 1
+
+	// ofxDateLayout writes dates the way OFX files give them, in UTC
+	ofxDateLayout = "20060102150405.000[0:GMT]"
+)
+
+type ExportHandler struct {
+	db repository.Repository
+}
+
+func NewExportHandler(db repository.Repository) *ExportHandler {
+	return &ExportHandler{db: db}
+}
+
+// ExportedCategory is a category in a JSON export
+type ExportedCategory struct {
+	ID       uuid.UUID  `json:"id"`
+	Name     string     `json:"name"`
+	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+}
+
+// ExportedTag is a tag in a JSON export
+type ExportedTag struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// ExportedExpense is an expense in a JSON export, in the shape POST /expenses
+// takes, with the IDs of its tags. IDs are those of the exporting instance;
+// imports assign new ones.
+type ExportedExpense struct {
+	ID uuid.UUID `json:"id"`
+	ExpenseRequest
+	TagIDs []uuid.UUID `json:"tag_ids,omitempty"`
+}
+
+// ExportedIncome is an income record in a JSON export, in the shape
+// POST /income takes, with the IDs of its tags
+type ExportedIncome struct {
+	ID uuid.UUID `json:"id"`
+	CreateIncomeRequest
+	TagIDs []uuid.UUID `json:"tag_ids,omitempty"`
+}
+
+// ExportedBudget is a budget in a JSON export, in the shape POST /budgets
+// takes
+type ExportedBudget struct {
+	ID uuid.UUID `json:"id"`
+	CreateBudgetRequest
+}
+
+// UserExport is everything a user has, as GET /user/export?format=json writes
+// it and POST /user/import?format=json reads it back
+type UserExport struct {
+	Version      int                `json:"version"`
+	ExportedAt   time.Time          `json:"exported_at"`
+	BaseCurrency string             `json:"base_currency"`
+	Categories   []ExportedCategory `json:"categories"`
+	Tags         []ExportedTag      `json:"tags"`
+	Expenses     []ExportedExpense  `json:"expenses"`
+	Income       []ExportedIncome   `json:"income"`
+	Budgets      []ExportedBudget   `json:"budgets"`
+}
+
+// ExportUserData handles GET /user/export. format=json, the default, writes a
+// UserExport of every category, tag, expense, income record and budget the
+// user has. format=csv writes expenses and income in the columns the CSV import
+// reads by default, and format=ofx writes them as an OFX bank statement per
+// currency. tag_id limits the expenses and income of any format to those with
+// the tag. Expenses and income are loaded a page at a time and sent as they
+// are written, so exports of any size stream.
+func (h *ExportHandler) ExportUserData(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = "json"
+	}
+	var contentType string
+	switch format {
+	case "json":
+		contentType = "application/json"
+	case "csv":
+		contentType = "text/csv; charset=utf-8"
+	case "ofx":
+		contentType = "application/x-ofx"
+	default:
+		http.Error(w, "format must be csv, json or ofx", http.StatusBadRequest)
+		return
+	}
+	var tagID *uuid.UUID
+	if tagIDStr := r.URL.Query().Get("tag_id"); tagIDStr != "" {
+		tid, err := validation.ValidateUUID(tagIDStr)
+		if err != nil {
+			http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+			return
+		}
+		tagID = &tid
+	}
+
+	user, err := h.db.GetUserByID(r.Context(), uid)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error fetching user", http.StatusInternalServerError)
+		return
+	}
+	categories, err := h.db.ListCategories(r.Context(), uid)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error fetching categories", http.StatusInternalServerError)
+		return
+	}
+
+	// Budgets and statement currencies are few enough to load before
+	// anything is sent, so that failing to load them is still an error
+	// response
+	var budgets []repository.Budget
+	var tags []repository.Tag
+	var currencies []repository.ListStatementCurrenciesRow
+	switch format {
+	case "json":
+		if budgets, err = h.db.ListBudgets(r.Context(), uid); err != nil {
+			log.Println(err)
+			http.Error(w, "Error fetching budgets", http.StatusInternalServerError)
+			return
+		}
+		if tags, err = h.db.ListTags(r.Context(), uid); err != nil {
+			log.Println(err)
+			http.Error(w, "Error fetching tags", http.StatusInternalServerError)
+			return
+		}
+	case "ofx":
+		if currencies, err = h.db.ListStatementCurrencies(r.Context(), uid); err != nil {
+			log.Println(err)
+			http.Error(w, "Error fetching transactions", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Large exports take longer than the server's write timeout allows
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Println(err)
+	}
+
+	now := time.Now().UTC()
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="centsible-%s.%s"`, now.Format(time.DateOnly), format))
+	w.WriteHeader(http.StatusOK)
+
+	e := &exporter{
+		ctx:      r.Context(),
+		db:       h.db,
+		userID:   uid,
+		tagID:    tagID,
+		withTags: format == "json",
+		out:      bufio.NewWriter(w),
+		rc:       rc,
+	}
+	switch format {
+	case "json":
+		err = e.writeJSON(now, user.BaseCurrency, categories, tags, budgets)
+	case "csv":
+		err = e.writeCSV(categories)
+	case "ofx":
+		err = e.writeOFX(now, currencies, categories)
+	}
+	if err == nil {
+		err = e.flush()
+	}
+	if err != nil {
+		// The status went out with the first page, so dropping the
+		// connection is the only way left to say the export is incomplete
+		log.Println(err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exporter writes one export, sending it a page at a time
+type exporter struct {
+	ctx    context.Context
+	db     repository.Repository
+	userID uuid.UUID
+	tagID  *uuid.UUID
+	out    *bufio.Writer
+	rc     *http.ResponseController
+
+	// withTags loads the tags of each page into pageTags, by transaction ID
+	withTags bool
+	pageTags map[uuid.UUID][]uuid.UUID
+}
+
+// flush sends what has been written so far
+func (e *exporter) flush() error {
+	if err := e.out.Flush(); err != nil {
+		return err
+	}
+	if err := e.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// loadPageTags loads the tags of a page of transactions when the export
+// includes them
+func (e *exporter) loadPageTags(ids []uuid.UUID) error {
+	if !e.withTags {
+		return nil
+	}
+	rows, err := e.db.ListTransactionTags(e.ctx, repository.ListTransactionTagsParams{
+		TransactionIds: ids,
+		UserID:         e.userID,
+	})
+	if err != nil {
+		return err
+	}
+	e.pageTags = make(map[uuid.UUID][]uuid.UUID, len(ids))
+	for _, row := range rows {
+		e.pageTags[row.TransactionID] = append(e.pageTags[row.TransactionID], row.TagID)
+	}
+	return nil
+}
+
+// eachExpense calls fn with each of the user's expenses oldest first, only
+// those in the currency unless it is empty and with the export's tag if it
+// has one
+func (e *exporter) eachExpense(currency string, fn func(repository.Expense) error) error {
+	params := repository.ExportExpensesParams{
+		UserID:   e.userID,
+		Currency: currency,
+		TagID:    e.tagID,
+		PageSize: exportPageSize,
+	}
+	for {
+		page, err := e.db.ExportExpenses(e.ctx, params)
+		if err != nil {
+			return err
+		}
+		ids := make([]uuid.UUID, len(page))
+		for i, expense := range page {
+			ids[i] = expense.ID
+		}
+		if err := e.loadPageTags(ids); err != nil {
+			return err
+		}
+		for _, expense := range page {
+			if err := fn(expense); err != nil {
+				return err
+			}
+		}
+		if err := e.flush(); err != nil {
+			return err
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+		params.AfterDate, params.AfterID = page[len(page)-1].Date, page[len(page)-1].ID
+	}
+}
+
+// eachIncome calls fn with each of the user's income records like eachExpense
+func (e *exporter) eachIncome(currency string, fn func(repository.Income) error) error {
+	params := repository.ExportIncomeParams{
+		UserID:   e.userID,
+		Currency: currency,
+		TagID:    e.tagID,
+		PageSize: exportPageSize,
+	}
+	for {
+		page, err := e.db.ExportIncome(e.ctx, params)
+		if err != nil {
+			return err
+		}
+		ids := make([]uuid.UUID, len(page))
+		for i, income := range page {
+			ids[i] = income.ID
+		}
+		if err := e.loadPageTags(ids); err != nil {
+			return err
+		}
+		for _, income := range page {
+			if err := fn(income); err != nil {
+				return err
+			}
+		}
+		if err := e.flush(); err != nil {
+			return err
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+		params.AfterDate, params.AfterID = page[len(page)-1].Date, page[len(page)-1].ID
+	}
+}
+
+// writeJSON writes a UserExport one element at a time
+func (e *exporter) writeJSON(now time.Time, baseCurrency string, categories []repository.Category, tags []repository.Tag, budgets []repository.Budget) error {
+	header, err := json.Marshal(struct {
+		Version      int       `json:"version"`
+		ExportedAt   time.Time `json:"exported_at"`
+		BaseCurrency string    `json:"base_currency"`
+	}{exportVersion, now, baseCurrency})
+	if err != nil {
+		return err
+	}
+	// Leave the object open for the arrays that follow
+	e.out.Write(header[:len(header)-1])
+
+	err = e.jsonArray("categories", func(add func(any) error) error {
+		for _, category := range categories {
+			if err := add(ExportedCategory{ID: category.ID, Name: category.Name, ParentID: category.ParentID}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = e.jsonArray("tags", func(add func(any) error) error {
+		for _, tag := range tags {
+			if err := add(ExportedTag{ID: tag.ID, Name: tag.Name}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = e.jsonArray("expenses", func(add func(any) error) error {
+		return e.eachExpense("", func(expense repository.Expense) error {
+			exported := exportedExpense(expense)
+			exported.TagIDs = e.pageTags[expense.ID]
+			return add(exported)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	err = e.jsonArray("income", func(add func(any) error) error {
+		return e.eachIncome("", func(income repository.Income) error {
+			exported := exportedIncome(income)
+			exported.TagIDs = e.pageTags[income.ID]
+			return add(exported)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	err = e.jsonArray("budgets", func(add func(any) error) error {
+		for _, budget := range budgets {
+			if err := add(exportedBudget(budget)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	_, err = e.out.WriteString("\n}\n")
+	return err
+}
+
+// jsonArray writes the elements each adds as an array member of the export,
+// one per line
+func (e *exporter) jsonArray(key string, each func(add func(any) error) error) error {
+	fmt.Fprintf(e.out, ",\n%q:[", key)
+	first := true
+	err := each(func(v any) error {
+		element, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if !first {
+			e.out.WriteByte(',')
+		}
+		first = false
+		e.out.WriteByte('\n')
+		_, err = e.out.Write(element)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = e.out.WriteString("\n]")
+	return err
+}
+
+func exportedExpense(expense repository.Expense) ExportedExpense {
+	return ExportedExpense{
+		ID: expense.ID,
+		ExpenseRequest: ExpenseRequest{
+			Amount:      expense.Amount,
+			Currency:    expense.Currency,
+			CategoryID:  expense.CategoryID,
+			Date:        expense.Date.UTC().Format(time.RFC3339Nano),
+			Description: expense.Description,
+			ExternalID:  stringValue(expense.ExternalID),
+		},
+	}
+}
+
+func exportedIncome(income repository.Income) ExportedIncome {
+	return ExportedIncome{
+		ID: income.ID,
+		CreateIncomeRequest: CreateIncomeRequest{
+			Amount:      income.Amount,
+			Currency:    income.Currency,
+			Source:      income.Source,
+			Date:        income.Date.UTC(),
+			Description: income.Description,
+			ExternalID:  stringValue(income.ExternalID),
+		},
+	}
+}
+
+func exportedBudget(budget repository.Budget) ExportedBudget {
+	exported := ExportedBudget{
+		ID: budget.ID,
+		CreateBudgetRequest: CreateBudgetRequest{
+			Amount:     budget.Amount,
+			Currency:   budget.Currency,
+			CategoryID: budget.CategoryID,
+			Type:       budget.Type,
+			Rollover:   &budget.Rollover,
+			StartDate:  budget.StartDate.UTC().Format(time.RFC3339Nano),
+			Name:       budget.Name,
+		},
+	}
+	if budget.Cadence != nil {
+		exported.Cadence = string(*budget.Cadence)
+	}
+	if budget.EndDate != nil {
+		exported.EndDate = budget.EndDate.UTC().Format(time.RFC3339Nano)
+	}
+	return exported
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// writeCSV writes expenses and then income under the header the CSV import
+// reads by default, naming categories rather than giving their IDs
+func (e *exporter) writeCSV(categories []repository.Category) error {
+	names := make(map[uuid.UUID]string, len(categories))
+	for _, category := range categories {
+		names[category.ID] = category.Name
+	}
+
+	m := statement.DefaultMapping()
+	out := csv.NewWriter(e.out)
+	if err := out.Write([]string{m.Date, m.Type, m.Amount, m.Currency, m.Category, m.Source, m.Description, m.ExternalID}); err != nil {
+		return err
+	}
+	err := e.eachExpense("", func(expense repository.Expense) error {
+		out.Write([]string{
+			expense.Date.UTC().Format(time.RFC3339),
+			statement.TypeExpense,
+			expense.Amount.String(),
+			expense.Currency,
+			names[expense.CategoryID],
+			"",
+			expense.Description,
+			stringValue(expense.ExternalID),
+		})
+		out.Flush()
+		return out.Error()
+	})
+	if err != nil {
+		return err
+	}
+	return e.eachIncome("", func(income repository.Income) error {
+		out.Write([]string{
+			income.Date.UTC().Format(time.RFC3339),
+			statement.TypeIncome,
+			income.Amount.String(),
+			income.Currency,
+			"",
+			income.Source,
+			income.Description,
+			stringValue(income.ExternalID),
+		})
+		out.Flush()
+		return out.Error()
+	})
+}
+
+// writeOFX writes an OFX 2 file with a bank statement for each currency. A
+// transaction's FITID is its external ID, or its ID when it has none, so that
+// importing the file again skips what is already there.
+func (e *exporter) writeOFX(now time.Time, currencies []repository.ListStatementCurrenciesRow, categories []repository.Category) error {
+	names := make(map[uuid.UUID]string, len(categories))
+	for _, category := range categories {
+		names[category.ID] = category.Name
+	}
+
+	e.out.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="no"?>` + "\n")
+	e.out.WriteString(`<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n")
+	e.out.WriteString("<OFX>\n")
+	fmt.Fprintf(e.out, "<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>\n", now.Format(ofxDateLayout))
+	e.out.WriteString("<BANKMSGSRSV1>\n")
+
+	for i, currency := range currencies {
+		fmt.Fprintf(e.out, "<STMTTRNRS><TRNUID>%d</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n", i+1)
+		fmt.Fprintf(e.out, "<STMTRS><CURDEF>%s</CURDEF>\n", currency.Currency)
+		fmt.Fprintf(e.out, "<BANKACCTFROM><BANKID>CENTSIBLE</BANKID><ACCTID>CENTSIBLE-%s</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>\n", currency.Currency)
+		fmt.Fprintf(e.out, "<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>\n", currency.FirstDate.UTC().Format(ofxDateLayout), currency.LastDate.UTC().Format(ofxDateLayout))
+
+		err := e.eachExpense(currency.Currency, func(expense repository.Expense) error {
+			return e.ofxTransaction("DEBIT", expense.ID, expense.ExternalID, expense.Date, expense.Amount.Neg().String(), names[expense.CategoryID], expense.Description)
+		})
+		if err != nil {
+			return err
+		}
+		err = e.eachIncome(currency.Currency, func(income repository.Income) error {
+			return e.ofxTransaction("CREDIT", income.ID, income.ExternalID, income.Date, income.Amount.String(), income.Source, income.Description)
+		})
+		if err != nil {
+			return err
+		}
+
+		e.out.WriteString("</BANKTRANLIST>\n")
+		fmt.Fprintf(e.out, "<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n", currency.Balance, now.Format(ofxDateLayout))
+		e.out.WriteString("</STMTRS></STMTTRNRS>\n")
+	}
+
+	_, err := e.out.WriteString("</BANKMSGSRSV1>\n</OFX>\n")
+	return err
+}
+
+// ofxTransaction writes one STMTTRN, leaving out empty elements
+func (e *exporter) ofxTransaction(kind string, id uuid.UUID, externalID *string, date time.Time, amount, name, memo string) error {
+	fitID := id.String()
+	if externalID != nil {
+		fitID = *externalID
+	}
+
+	fmt.Fprintf(e.out, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT>", kind, date.UTC().Format(ofxDateLayout), amount)
+	for _, element := range []struct{ tag, value string }{
+		{"FITID", fitID},
+		{"NAME", name},
+		{"MEMO", memo},
+	} {
+		if element.value == "" {
+			continue
+		}
+		fmt.Fprintf(e.out, "<%s>", element.tag)
+		if err := xml.EscapeText(e.out, []byte(element.value)); err != nil {
+			return err
+		}
+		fmt.Fprintf(e.out, "</%s>", element.tag)
+	}
+	_, err := e.out.WriteString("</STMTTRN>\n")
+	return err
+}
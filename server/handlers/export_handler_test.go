@@ -0,0 +1,433 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/recurrence"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/jorge-dev/centsible/internal/repository/mocks"
+	"github.com/jorge-dev/centsible/internal/statement"
+	"github.com/stretchr/testify/assert"
+)
+
+type exportHandlerTestSuite struct {
+	mockRepo  *mocks.MockRepository
+	handler   *ExportHandler
+	importer  *ImportHandler
+	userID    uuid.UUID
+	groceries uuid.UUID
+	trip      uuid.UUID
+}
+
+func (s *exportHandlerTestSuite) cleanup() {
+	s.mockRepo.Reset()
+}
+
+func setupExportHandlerTest(t *testing.T) *exportHandlerTestSuite {
+	suite := &exportHandlerTestSuite{}
+	t.Cleanup(suite.cleanup)
+
+	repo := mocks.NewMockRepository()
+	mock, ok := repo.(*mocks.MockRepository)
+	if !ok {
+		t.Fatal("could not cast to MockRepository")
+	}
+	suite.mockRepo = mock
+	suite.handler = NewExportHandler(repo)
+	suite.importer = NewImportHandler(repo)
+
+	suite.userID = uuid.New()
+	suite.groceries = uuid.New()
+	suite.mockRepo.GetUserMock().AddUser(repository.GetUserByIDRow{
+		ID:           suite.userID,
+		Name:         "Exporter",
+		Email:        "exporter@example.com",
+		BaseCurrency: "CAD",
+	})
+	suite.mockRepo.GetCategoryMock().AddCategory(repository.Category{
+		ID:     suite.groceries,
+		UserID: suite.userID,
+		Name:   "Groceries",
+	})
+
+	suite.trip = uuid.New()
+	suite.mockRepo.GetTagMock().AddTag(repository.Tag{
+		ID:     suite.trip,
+		UserID: suite.userID,
+		Name:   "Trip",
+	})
+
+	externalID := "bank-1"
+	deli := uuid.New()
+	suite.mockRepo.GetExpenseMock().AddExpense(repository.Expense{
+		ID:          deli,
+		UserID:      suite.userID,
+		Amount:      money.MustParse("42.17"),
+		Currency:    "USD",
+		CategoryID:  suite.groceries,
+		Date:        time.Date(2024, time.January, 3, 0, 0, 0, 0, time.UTC),
+		Description: "Corner grocer & deli",
+		ExternalID:  &externalID,
+	})
+	suite.mockRepo.GetExpenseMock().AddExpense(repository.Expense{
+		ID:          uuid.New(),
+		UserID:      suite.userID,
+		Amount:      money.MustParse("12.50"),
+		Currency:    "CAD",
+		CategoryID:  suite.groceries,
+		Date:        time.Date(2024, time.January, 5, 0, 0, 0, 0, time.UTC),
+		Description: "Weekly shop",
+	})
+	pay := uuid.New()
+	suite.mockRepo.GetIncomeMock().AddIncome(repository.Income{
+		ID:          pay,
+		UserID:      suite.userID,
+		Amount:      money.MustParse("2500"),
+		Currency:    "USD",
+		Source:      "Employer",
+		Date:        time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC),
+		Description: "January pay",
+	})
+	suite.mockRepo.GetTagMock().TagExpense(deli, suite.trip)
+	suite.mockRepo.GetTagMock().TagIncome(pay, suite.trip)
+	monthly := recurrence.Monthly
+	suite.mockRepo.GetBudgetMock().AddBudget(repository.Budget{
+		ID:         uuid.New(),
+		UserID:     suite.userID,
+		Amount:     money.MustParse("400"),
+		Currency:   "CAD",
+		CategoryID: suite.groceries,
+		Type:       "recurring",
+		Cadence:    &monthly,
+		StartDate:  time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Name:       "Food",
+	})
+
+	return suite
+}
+
+func (s *exportHandlerTestSuite) export(t *testing.T, query string) *httptest.ResponseRecorder {
+	req := withUser(httptest.NewRequest(http.MethodGet, "/user/export"+query, nil), s.userID)
+	w := httptest.NewRecorder()
+	s.handler.ExportUserData(w, req)
+	return w
+}
+
+func TestExportUserData(t *testing.T) {
+	suite := setupExportHandlerTest(t)
+
+	t.Run("JSON", func(t *testing.T) {
+		w := suite.export(t, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), ".json")
+
+		var export UserExport
+		if err := json.NewDecoder(w.Body).Decode(&export); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, exportVersion, export.Version)
+		assert.Equal(t, "CAD", export.BaseCurrency)
+		assert.Equal(t, []ExportedCategory{{ID: suite.groceries, Name: "Groceries"}}, export.Categories)
+		assert.Equal(t, []ExportedTag{{ID: suite.trip, Name: "Trip"}}, export.Tags)
+		if assert.Len(t, export.Expenses, 2) {
+			assert.Equal(t, "bank-1", export.Expenses[0].ExternalID)
+			assert.Equal(t, []uuid.UUID{suite.trip}, export.Expenses[0].TagIDs)
+			assert.Equal(t, "Weekly shop", export.Expenses[1].Description)
+			assert.Empty(t, export.Expenses[1].TagIDs)
+		}
+		if assert.Len(t, export.Income, 1) {
+			assert.Equal(t, []uuid.UUID{suite.trip}, export.Income[0].TagIDs)
+		}
+		if assert.Len(t, export.Budgets, 1) {
+			assert.Equal(t, "monthly", export.Budgets[0].Cadence)
+		}
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		w := suite.export(t, "?format=csv")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		transactions, lineErrors, err := statement.ParseCSV(w.Body, statement.DefaultMapping())
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Empty(t, lineErrors)
+		if assert.Len(t, transactions, 3) {
+			assert.Equal(t, "Groceries", transactions[0].Category)
+			assert.Equal(t, "bank-1", transactions[0].ExternalID)
+			assert.Equal(t, statement.TypeIncome, transactions[2].Type)
+		}
+	})
+
+	t.Run("OFX", func(t *testing.T) {
+		w := suite.export(t, "?format=ofx")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 2, strings.Count(w.Body.String(), "<STMTRS>"), "a statement per currency")
+		assert.Contains(t, w.Body.String(), "<BALAMT>2457.83</BALAMT>")
+
+		transactions, lineErrors, err := statement.ParseOFX(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Empty(t, lineErrors)
+		if assert.Len(t, transactions, 3) {
+			assert.Equal(t, "CAD", transactions[0].Currency)
+			assert.Equal(t, "bank-1", transactions[1].ExternalID)
+			assert.Equal(t, "Corner grocer & deli", transactions[1].Description)
+			assert.Equal(t, money.MustParse("2500"), transactions[2].Amount)
+		}
+	})
+
+	t.Run("Tag", func(t *testing.T) {
+		w := suite.export(t, "?format=csv&tag_id="+suite.trip.String())
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		transactions, _, err := statement.ParseCSV(w.Body, statement.DefaultMapping())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if assert.Len(t, transactions, 2) {
+			assert.Equal(t, "bank-1", transactions[0].ExternalID)
+			assert.Equal(t, statement.TypeIncome, transactions[1].Type)
+		}
+
+		w = suite.export(t, "?tag_id=trip")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		w := suite.export(t, "?format=xlsx")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestExportUserDataPages(t *testing.T) {
+	suite := setupExportHandlerTest(t)
+	start := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 2*exportPageSize; i++ {
+		suite.mockRepo.GetExpenseMock().AddExpense(repository.Expense{
+			ID:          uuid.New(),
+			UserID:      suite.userID,
+			Amount:      money.MustParse("1"),
+			Currency:    "CAD",
+			CategoryID:  suite.groceries,
+			Date:        start.Add(time.Duration(i/3) * time.Hour), // Pages split dates
+			Description: fmt.Sprintf("Expense %d", i),
+		})
+	}
+
+	w := suite.export(t, "?format=csv")
+	assert.Equal(t, http.StatusOK, w.Code)
+	transactions, _, err := statement.ParseCSV(w.Body, statement.DefaultMapping())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, transactions, 2*exportPageSize+3)
+	seen := make(map[string]bool)
+	for _, transaction := range transactions {
+		assert.False(t, seen[transaction.Description], "%s exported twice", transaction.Description)
+		seen[transaction.Description] = true
+	}
+}
+
+func TestImportUserExport(t *testing.T) {
+	suite := setupExportHandlerTest(t)
+	w := suite.export(t, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	export := w.Body.String()
+
+	otherUser := uuid.New()
+	suite.mockRepo.GetUserMock().AddUser(repository.GetUserByIDRow{
+		ID:           otherUser,
+		Name:         "Importer",
+		Email:        "importer@example.com",
+		BaseCurrency: "USD",
+	})
+	importExport := func(query string) ImportTransactionsResponse {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/user/import"+query, strings.NewReader(export)), otherUser)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		suite.importer.ImportTransactions(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp ImportTransactionsResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := importExport("?dry_run=true")
+	assert.Equal(t, ImportTransactionsResponse{DryRun: true, Expenses: 2, Income: 1, Categories: 1, Tags: 1, Budgets: 1}, countsOf(resp))
+	categories, _ := suite.mockRepo.ListCategories(context.Background(), otherUser)
+	assert.Empty(t, categories)
+
+	resp = importExport("")
+	assert.Equal(t, ImportTransactionsResponse{Expenses: 2, Income: 1, Categories: 1, Tags: 1, Budgets: 1}, countsOf(resp))
+	categories, _ = suite.mockRepo.ListCategories(context.Background(), otherUser)
+	if assert.Len(t, categories, 1) {
+		assert.Equal(t, "Groceries", categories[0].Name)
+		assert.NotEqual(t, suite.groceries, categories[0].ID)
+
+		expenses, _ := suite.mockRepo.ListExpenses(context.Background(), otherUser)
+		assert.Len(t, expenses, 2)
+		for _, expense := range expenses {
+			assert.Equal(t, categories[0].ID, expense.CategoryID)
+		}
+		budgets, _ := suite.mockRepo.ListBudgets(context.Background(), otherUser)
+		if assert.Len(t, budgets, 1) {
+			assert.Equal(t, categories[0].ID, budgets[0].CategoryID)
+		}
+	}
+	tags, _ := suite.mockRepo.ListTags(context.Background(), otherUser)
+	if assert.Len(t, tags, 1) {
+		assert.Equal(t, "Trip", tags[0].Name)
+		assert.NotEqual(t, suite.trip, tags[0].ID)
+
+		tagged, _ := suite.mockRepo.ListExpensesPage(context.Background(), repository.ListExpensesPageParams{
+			UserID:   otherUser,
+			TagID:    &tags[0].ID,
+			PageSize: 10,
+		})
+		if assert.Len(t, tagged, 1) {
+			assert.Equal(t, "Corner grocer & deli", tagged[0].Description)
+		}
+	}
+
+	// Importing the same export again adds nothing
+	resp = importExport("")
+	assert.Equal(t, ImportTransactionsResponse{}, countsOf(resp))
+	assert.Len(t, resp.Duplicates, 4)
+}
+
+func TestImportUserExportInvalid(t *testing.T) {
+	suite := setupExportHandlerTest(t)
+	category := uuid.New()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantErrors []int
+	}{
+		{
+			name:       "Unsupported version",
+			body:       `{"version":99,"expenses":[]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Not an export",
+			body:       `[1,2,3]`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Invalid records",
+			body: `{"version":1,
+"categories":[
+{"id":"` + category.String() + `","name":"Travel"}
+],
+"expenses":[
+{"id":"` + uuid.NewString() + `","amount":"10","currency":"USD","category_id":"` + category.String() + `","date":"2024-01-01T00:00:00Z","description":"Train"},
+{"id":"` + uuid.NewString() + `","amount":"10","currency":"USD","category_id":"` + uuid.NewString() + `","date":"2024-01-01T00:00:00Z","description":"Unknown category"},
+{"id":"` + uuid.NewString() + `","amount":"10","currency":"usd","category_id":"` + category.String() + `","date":"2024-01-01T00:00:00Z","description":"Lowercase currency"}
+]}`,
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []int{7, 8},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := withUser(httptest.NewRequest(http.MethodPost, "/user/import?format=json", strings.NewReader(tt.body)), suite.userID)
+			w := httptest.NewRecorder()
+			suite.importer.ImportTransactions(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantErrors == nil {
+				return
+			}
+
+			var resp ImportTransactionsResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			var lines []int
+			for _, lineErr := range resp.Errors {
+				lines = append(lines, lineErr.Line)
+			}
+			assert.Equal(t, tt.wantErrors, lines)
+			categories, _ := suite.mockRepo.ListCategories(context.Background(), suite.userID)
+			assert.Len(t, categories, 1, "nothing is saved")
+		})
+	}
+}
+
+func TestImportUserExportCategoryParents(t *testing.T) {
+	suite := setupExportHandlerTest(t)
+	food, organic, snacks := uuid.New(), uuid.New(), uuid.New()
+	loopA, loopB := uuid.New(), uuid.New()
+	// Subcategories come before their parents, and two categories are each
+	// other's parent
+	body := `{"version":1,"categories":[
+{"id":"` + organic.String() + `","name":"Organic","parent_id":"` + food.String() + `"},
+{"id":"` + snacks.String() + `","name":"Snacks","parent_id":"` + suite.groceries.String() + `"},
+{"id":"` + suite.groceries.String() + `","name":"Groceries"},
+{"id":"` + food.String() + `","name":"Food"},
+{"id":"` + loopA.String() + `","name":"Loop A","parent_id":"` + loopB.String() + `"},
+{"id":"` + loopB.String() + `","name":"Loop B","parent_id":"` + loopA.String() + `"}
+],"expenses":[]}`
+
+	req := withUser(httptest.NewRequest(http.MethodPost, "/user/import?format=json", strings.NewReader(body)), suite.userID)
+	w := httptest.NewRecorder()
+	suite.importer.ImportTransactions(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	categories, _ := suite.mockRepo.ListCategories(context.Background(), suite.userID)
+	byName := make(map[string]repository.Category)
+	for _, category := range categories {
+		byName[category.Name] = category
+	}
+	assert.Len(t, byName, 6)
+	assert.Equal(t, byName["Food"].ID, *byName["Organic"].ParentID)
+	assert.Equal(t, suite.groceries, *byName["Snacks"].ParentID, "new categories can go under existing ones")
+	assert.Nil(t, byName["Groceries"].ParentID)
+	assert.True(t, (byName["Loop A"].ParentID == nil) != (byName["Loop B"].ParentID == nil), "the loop is broken once")
+}
+
+func TestParentsFirst(t *testing.T) {
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	ordered := parentsFirst([]repository.CreateCategoryParams{
+		{ID: c, Name: "c", ParentID: &b},
+		{ID: b, Name: "b", ParentID: &a},
+		{ID: a, Name: "a"},
+		{ID: d, Name: "d", ParentID: &d},
+	})
+
+	var names []string
+	for _, category := range ordered {
+		names = append(names, category.Name)
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d"}, names)
+	assert.Nil(t, ordered[3].ParentID)
+}
+
+// countsOf keeps only the counts of an import response
+func countsOf(resp ImportTransactionsResponse) ImportTransactionsResponse {
+	return ImportTransactionsResponse{
+		DryRun:     resp.DryRun,
+		Expenses:   resp.Expenses,
+		Income:     resp.Income,
+		Categories: resp.Categories,
+		Tags:       resp.Tags,
+		Budgets:    resp.Budgets,
+	}
+}
@@ -0,0 +1,1052 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/categorize"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/recurrence"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/jorge-dev/centsible/internal/statement"
+	"github.com/jorge-dev/centsible/internal/validation"
+	"github.com/jorge-dev/centsible/server/middleware"
+)
+
+const (
+	// maxTransactionImportSize caps the size of an uploaded bank statement
+	maxTransactionImportSize = 10 << 20
+
+	// maxUserExportImportSize caps the size of an uploaded JSON export, which
+	// holds everything a user has rather than one statement
+	maxUserExportImportSize = 100 << 20
+)
+
+type ImportHandler struct {
+	db repository.Repository
+}
+
+// ImportedTransaction is one line of an import as it was, or in a dry run
+// would be, saved
+type ImportedTransaction struct {
+	Line        int          `json:"line"`
+	Type        string       `json:"type"`
+	Date        time.Time    `json:"date"`
+	Amount      money.Amount `json:"amount"`
+	Currency    string       `json:"currency"`
+	CategoryID  *uuid.UUID   `json:"category_id,omitempty"`
+	Source      string       `json:"source,omitempty"`
+	Description string       `json:"description"`
+	ExternalID  string       `json:"external_id,omitempty"`
+	TagIDs      []uuid.UUID  `json:"tag_ids,omitempty"` // From a JSON import or category rules
+}
+
+// ImportDuplicate is a statement line left out of an import because it was
+// likely imported before
+type ImportDuplicate struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+type ImportTransactionsResponse struct {
+	DryRun       bool                  `json:"dry_run"`
+	Expenses     int64                 `json:"expenses"`
+	Income       int64                 `json:"income"`
+	Categories   int64                 `json:"categories,omitempty"` // Created by a JSON import
+	Tags         int64                 `json:"tags,omitempty"`       // Created by a JSON import
+	Budgets      int64                 `json:"budgets,omitempty"`    // Created by a JSON import
+	Transactions []ImportedTransaction `json:"transactions,omitempty"`
+	Duplicates   []ImportDuplicate     `json:"duplicates,omitempty"`
+	Errors       []ImportLineError     `json:"errors"`
+}
+
+// importOptions are the settings of an import read from its query string
+type importOptions struct {
+	format          string
+	mapping         statement.Mapping
+	dryRun          bool
+	allowDuplicates bool
+	currency        string
+	categoryID      uuid.UUID
+}
+
+// importMatch is what the transactions already saved say about one line of
+// an import
+type importMatch struct {
+	fingerprint     string
+	existing        int64 // Transactions saved with the same fingerprint
+	externalIDTaken bool
+}
+
+func NewImportHandler(db repository.Repository) *ImportHandler {
+	return &ImportHandler{db: db}
+}
+
+// parseImportOptions reads the format, CSV column mapping and defaults of an
+// import. The format falls back to the Content-Type, and then to CSV.
+func parseImportOptions(r *http.Request) (importOptions, error) {
+	query := r.URL.Query()
+	opts := importOptions{
+		format:   strings.ToLower(query.Get("format")),
+		mapping:  statement.DefaultMapping(),
+		currency: strings.ToUpper(query.Get("currency")),
+	}
+
+	if opts.format == "" {
+		switch strings.ToLower(strings.TrimSpace(strings.Split(r.Header.Get("Content-Type"), ";")[0])) {
+		case "application/x-ofx", "application/ofx":
+			opts.format = "ofx"
+		case "application/vnd.intu.qfx", "application/x-qfx":
+			opts.format = "qfx"
+		case "application/json":
+			opts.format = "json"
+		default:
+			opts.format = "csv"
+		}
+	}
+	if opts.format != "csv" && opts.format != "ofx" && opts.format != "qfx" && opts.format != "json" {
+		return opts, fmt.Errorf("format must be csv, ofx, qfx or json")
+	}
+
+	// Columns not renamed keep the names this API exports them under
+	m := &opts.mapping
+	columns := map[string]*string{
+		"date_column":        &m.Date,
+		"amount_column":      &m.Amount,
+		"debit_column":       &m.Debit,
+		"credit_column":      &m.Credit,
+		"type_column":        &m.Type,
+		"currency_column":    &m.Currency,
+		"description_column": &m.Description,
+		"source_column":      &m.Source,
+		"category_column":    &m.Category,
+		"external_id_column": &m.ExternalID,
+	}
+	for param, field := range columns {
+		if query.Has(param) {
+			*field = query.Get(param)
+		}
+	}
+	opts.mapping.DateFormat = query.Get("date_format")
+
+	var err error
+	if opts.mapping.DecimalComma, err = queryBool(query, "decimal_comma"); err != nil {
+		return opts, err
+	}
+	if opts.mapping.InvertSign, err = queryBool(query, "invert_amounts"); err != nil {
+		return opts, err
+	}
+	if opts.dryRun, err = queryBool(query, "dry_run"); err != nil {
+		return opts, err
+	}
+	if opts.allowDuplicates, err = queryBool(query, "allow_duplicates"); err != nil {
+		return opts, err
+	}
+
+	if opts.currency != "" {
+		if err := (&validation.MoneyValidator{Amount: money.FromInt(1), Currency: opts.currency}).Validate(); err != nil {
+			return opts, err
+		}
+	}
+	if categoryID := query.Get("category_id"); categoryID != "" {
+		if opts.categoryID, err = validation.ValidateUUID(categoryID); err != nil {
+			return opts, fmt.Errorf("invalid category_id")
+		}
+	}
+
+	return opts, nil
+}
+
+// queryBool reads an optional boolean query parameter
+func queryBool(query url.Values, name string) (bool, error) {
+	value := query.Get(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false", name)
+	}
+	return b, nil
+}
+
+// ImportTransactions handles POST /user/import. The body is a bank statement,
+// either a CSV file whose columns are named by the *_column query parameters
+// or an OFX or QFX file. Negative amounts become expenses and positive ones
+// income. Every line is validated like a single expense or income would be;
+// if any line is invalid nothing is saved and each bad line is reported. With
+// dry_run=true nothing is saved either way, and the response previews what
+// would be.
+//
+// Lines that were likely imported before are left out and reported as
+// duplicates: those whose external ID is already taken or repeats an earlier
+// line, and those matching the fingerprint of a saved transaction. A statement
+// may hold several identical transactions, so a line is only left out for as
+// many saved ones as match it. allow_duplicates=true imports fingerprint
+// matches anyway.
+//
+// Expenses are filed and tagged by the user's category rules, as
+// importedTransaction describes.
+//
+// format=json reads back a JSON export instead, as importUserExport describes.
+func (h *ImportHandler) ImportTransactions(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	opts, err := parseImportOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.format == "json" {
+		h.importUserExport(w, r, uid, opts)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxTransactionImportSize)
+	var transactions []statement.Transaction
+	var parseErrors []statement.LineError
+	if opts.format == "csv" {
+		transactions, parseErrors, err = statement.ParseCSV(body, opts.mapping)
+	} else {
+		transactions, parseErrors, err = statement.ParseOFX(body)
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Statement file is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Transactions the statement gives no currency for are in the user's
+	// base currency unless the request says otherwise
+	if opts.currency == "" {
+		user, err := h.db.GetUserByID(r.Context(), uid)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Error fetching user", http.StatusInternalServerError)
+			return
+		}
+		opts.currency = user.BaseCurrency
+	}
+
+	categories, err := h.loadCategories(r, uid)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error fetching categories", http.StatusInternalServerError)
+		return
+	}
+	if opts.categoryID != uuid.Nil && !categories.has(opts.categoryID) {
+		http.Error(w, "Category not found", http.StatusBadRequest)
+		return
+	}
+	rules, err := h.db.ListCategoryRules(r.Context(), uid)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error fetching category rules", http.StatusInternalServerError)
+		return
+	}
+
+	var lineErrors []ImportLineError
+	for _, parseErr := range parseErrors {
+		lineErrors = append(lineErrors, ImportLineError{Line: parseErr.Line, Error: parseErr.Err.Error()})
+	}
+
+	var imported []ImportedTransaction
+	for _, transaction := range transactions {
+		row, err := importedTransaction(transaction, opts, categories, rules)
+		if err != nil {
+			lineErrors = append(lineErrors, ImportLineError{Line: transaction.Line, Error: err.Error()})
+			continue
+		}
+		imported = append(imported, row)
+	}
+	sort.SliceStable(lineErrors, func(i, j int) bool {
+		return lineErrors[i].Line < lineErrors[j].Line
+	})
+	if lineErrors == nil {
+		lineErrors = []ImportLineError{}
+	}
+	if len(lineErrors) > 0 && !opts.dryRun {
+		writeJSON(w, http.StatusBadRequest, ImportTransactionsResponse{Errors: lineErrors})
+		return
+	}
+
+	imported, duplicates, err := h.skipDuplicates(r, uid, imported, opts.allowDuplicates)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error checking for duplicate transactions", http.StatusInternalServerError)
+		return
+	}
+
+	params := importParams(uid, imported)
+
+	if opts.dryRun {
+		writeJSON(w, http.StatusOK, ImportTransactionsResponse{
+			DryRun:       true,
+			Expenses:     int64(len(params.ExpenseIds)),
+			Income:       int64(len(params.IncomeIds)),
+			Transactions: imported,
+			Duplicates:   duplicates,
+			Errors:       lineErrors,
+		})
+		return
+	}
+
+	var saved repository.ImportTransactionsRow
+	err = h.db.InTx(r.Context(), func(tx repository.Repository) error {
+		var err error
+		if saved, err = tx.ImportTransactions(r.Context(), params); err != nil {
+			return fmt.Errorf("importing transactions: %w", err)
+		}
+		if err := tx.LinkImportedTags(r.Context(), importTagLinks(imported, params)); err != nil {
+			return fmt.Errorf("tagging transactions: %w", err)
+		}
+		return nil
+	})
+	if externalIDConflict(err) {
+		http.Error(w, externalIDConflictMessage, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error importing transactions", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ImportTransactionsResponse{
+		Expenses:     saved.Expenses,
+		Income:       saved.Income,
+		Transactions: imported,
+		Duplicates:   duplicates,
+		Errors:       lineErrors,
+	})
+}
+
+const externalIDConflictMessage = "Transactions with the same external IDs were saved while importing; import again to leave them out as duplicates"
+
+// externalIDConflict reports whether an import failed because transactions
+// with its external IDs were saved after it checked for duplicates, by a
+// request running alongside it
+func externalIDConflict(err error) bool {
+	return repository.IsUniqueViolation(err, repository.ExpenseExternalIDIndex) ||
+		repository.IsUniqueViolation(err, repository.IncomeExternalIDIndex)
+}
+
+// importParams lays out the rows of an import as the arrays ImportTransactions
+// saves
+func importParams(userID uuid.UUID, rows []ImportedTransaction) repository.ImportTransactionsParams {
+	params := repository.ImportTransactionsParams{UserID: userID}
+	for _, row := range rows {
+		if row.Type == statement.TypeExpense {
+			params.ExpenseIds = append(params.ExpenseIds, uuid.New())
+			params.ExpenseAmounts = append(params.ExpenseAmounts, row.Amount.String())
+			params.ExpenseCurrencies = append(params.ExpenseCurrencies, row.Currency)
+			params.ExpenseCategoryIds = append(params.ExpenseCategoryIds, *row.CategoryID)
+			params.ExpenseDates = append(params.ExpenseDates, row.Date)
+			params.ExpenseDescriptions = append(params.ExpenseDescriptions, row.Description)
+			params.ExpenseExternalIds = append(params.ExpenseExternalIds, row.ExternalID)
+		} else {
+			params.IncomeIds = append(params.IncomeIds, uuid.New())
+			params.IncomeAmounts = append(params.IncomeAmounts, row.Amount.String())
+			params.IncomeCurrencies = append(params.IncomeCurrencies, row.Currency)
+			params.IncomeSources = append(params.IncomeSources, row.Source)
+			params.IncomeDates = append(params.IncomeDates, row.Date)
+			params.IncomeDescriptions = append(params.IncomeDescriptions, row.Description)
+			params.IncomeExternalIds = append(params.IncomeExternalIds, row.ExternalID)
+		}
+	}
+	return params
+}
+
+// importTagLinks pairs the IDs importParams gave the rows of an import with
+// their tags, as LinkImportedTags takes them
+func importTagLinks(rows []ImportedTransaction, params repository.ImportTransactionsParams) repository.LinkImportedTagsParams {
+	var links repository.LinkImportedTagsParams
+	var expense, income int
+	for _, row := range rows {
+		if row.Type == statement.TypeExpense {
+			for _, tagID := range row.TagIDs {
+				links.ExpenseIds = append(links.ExpenseIds, params.ExpenseIds[expense])
+				links.ExpenseTagIds = append(links.ExpenseTagIds, tagID)
+			}
+			expense++
+		} else {
+			for _, tagID := range row.TagIDs {
+				links.IncomeIds = append(links.IncomeIds, params.IncomeIds[income])
+				links.IncomeTagIds = append(links.IncomeTagIds, tagID)
+			}
+			income++
+		}
+	}
+	return links
+}
+
+// skipDuplicates leaves out the rows of an import that were likely imported
+// before, as ImportTransactions describes, and reports why
+func (h *ImportHandler) skipDuplicates(r *http.Request, userID uuid.UUID, rows []ImportedTransaction, allowDuplicates bool) ([]ImportedTransaction, []ImportDuplicate, error) {
+	var expenses, income []int // Indexes of the rows of each type
+	expenseArgs := repository.MatchImportedExpensesParams{UserID: userID}
+	incomeArgs := repository.MatchImportedIncomeParams{UserID: userID}
+	for i, row := range rows {
+		if row.Type == statement.TypeExpense {
+			expenses = append(expenses, i)
+			expenseArgs.Amounts = append(expenseArgs.Amounts, row.Amount.String())
+			expenseArgs.Currencies = append(expenseArgs.Currencies, row.Currency)
+			expenseArgs.Dates = append(expenseArgs.Dates, row.Date)
+			expenseArgs.Descriptions = append(expenseArgs.Descriptions, row.Description)
+			expenseArgs.CategoryIds = append(expenseArgs.CategoryIds, *row.CategoryID)
+			expenseArgs.ExternalIds = append(expenseArgs.ExternalIds, row.ExternalID)
+		} else {
+			income = append(income, i)
+			incomeArgs.Amounts = append(incomeArgs.Amounts, row.Amount.String())
+			incomeArgs.Currencies = append(incomeArgs.Currencies, row.Currency)
+			incomeArgs.Dates = append(incomeArgs.Dates, row.Date)
+			incomeArgs.Descriptions = append(incomeArgs.Descriptions, row.Description)
+			incomeArgs.Sources = append(incomeArgs.Sources, row.Source)
+			incomeArgs.ExternalIds = append(incomeArgs.ExternalIds, row.ExternalID)
+		}
+	}
+
+	// Fingerprints are prefixed with the type, as expenses only ever
+	// duplicate expenses and income income
+	matches := make([]importMatch, len(rows))
+	if len(expenses) > 0 {
+		found, err := h.db.MatchImportedExpenses(r.Context(), expenseArgs)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, match := range found {
+			matches[expenses[match.Ordinal-1]] = importMatch{
+				fingerprint:     statement.TypeExpense + ":" + match.Fingerprint,
+				existing:        match.Matches,
+				externalIDTaken: match.ExternalIDTaken,
+			}
+		}
+	}
+	if len(income) > 0 {
+		found, err := h.db.MatchImportedIncome(r.Context(), incomeArgs)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, match := range found {
+			matches[income[match.Ordinal-1]] = importMatch{
+				fingerprint:     statement.TypeIncome + ":" + match.Fingerprint,
+				existing:        match.Matches,
+				externalIDTaken: match.ExternalIDTaken,
+			}
+		}
+	}
+
+	var kept []ImportedTransaction
+	var duplicates []ImportDuplicate
+	externalIDs := make(map[string]int) // Line of each external ID kept, by type
+	matched := make(map[string]int64)   // Rows left out so far, by fingerprint
+	for i, row := range rows {
+		match := matches[i]
+		externalID := row.Type + ":" + row.ExternalID
+		reason := ""
+		switch {
+		case match.externalIDTaken:
+			reason = fmt.Sprintf("external ID %q was already imported", row.ExternalID)
+		case row.ExternalID != "" && externalIDs[externalID] != 0:
+			reason = fmt.Sprintf("external ID %q is also on line %d", row.ExternalID, externalIDs[externalID])
+		case !allowDuplicates && matched[match.fingerprint] < match.existing:
+			matched[match.fingerprint]++
+			reason = fmt.Sprintf("likely duplicates a saved %s", row.Type)
+		}
+		if reason != "" {
+			duplicates = append(duplicates, ImportDuplicate{Line: row.Line, Reason: reason})
+			continue
+		}
+		if row.ExternalID != "" {
+			externalIDs[externalID] = row.Line
+		}
+		kept = append(kept, row)
+	}
+	return kept, duplicates, nil
+}
+
+// importedTransaction validates one statement line as the expense or income
+// it becomes. Statements rarely have every field, so an expense without a
+// description is described by who was paid and income without a source is
+// named after its description.
+//
+// An expense goes in the category the statement names, else the one the
+// first matching category rule sets, else category_id. Every matching rule
+// adds its tags.
+func importedTransaction(transaction statement.Transaction, opts importOptions, categories categoryLookup, rules []repository.CategoryRule) (ImportedTransaction, error) {
+	row := ImportedTransaction{
+		Line:        transaction.Line,
+		Type:        transaction.Type,
+		Date:        transaction.Date,
+		Amount:      transaction.Amount,
+		Currency:    transaction.Currency,
+		Description: transaction.Description,
+		ExternalID:  transaction.ExternalID,
+	}
+	if row.Currency == "" {
+		row.Currency = opts.currency
+	}
+	date := transaction.Date.Format(time.RFC3339)
+
+	if transaction.Type == statement.TypeExpense {
+		if row.Description == "" {
+			row.Description = transaction.Source
+		}
+		matched := categorize.Apply(rules, categorize.Expense{
+			Description: row.Description,
+			Amount:      row.Amount,
+			Currency:    row.Currency,
+		})
+		row.TagIDs = matched.TagIDs
+
+		categoryID := opts.categoryID
+		if matched.CategoryID != nil {
+			categoryID = *matched.CategoryID
+		}
+		if transaction.Category != "" {
+			var ok bool
+			if categoryID, ok = categories.find(transaction.Category); !ok {
+				return row, fmt.Errorf("category %q not found", transaction.Category)
+			}
+		}
+		if categoryID == uuid.Nil {
+			return row, fmt.Errorf("expense has no category; map a category column, pass category_id or add a category rule")
+		}
+		row.CategoryID = &categoryID
+
+		validator := &validation.ExpenseValidation{
+			Amount:      row.Amount,
+			Currency:    row.Currency,
+			CategoryID:  categoryID,
+			Description: row.Description,
+			Date:        date,
+			ExternalID:  row.ExternalID,
+		}
+		if err := validator.Validate(); err != nil {
+			return row, err
+		}
+	} else {
+		row.Source = transaction.Source
+		if row.Source == "" {
+			row.Source = transaction.Description
+		}
+
+		validator := &validation.IncomeValidation{
+			Amount:      row.Amount,
+			Currency:    row.Currency,
+			Source:      row.Source,
+			Date:        date,
+			Description: row.Description,
+			ExternalID:  row.ExternalID,
+		}
+		if err := validator.Validate(); err != nil {
+			return row, err
+		}
+	}
+
+	if len(row.Description) > validation.TransactionDescriptionMaxLength {
+		return row, fmt.Errorf("description must not exceed %d characters", validation.TransactionDescriptionMaxLength)
+	}
+	return row, nil
+}
+
+// parentsFirst orders new categories so that each is created after its
+// parent. Categories whose parents form a loop are made top-level until the
+// loop is broken.
+func parentsFirst(categories []repository.CreateCategoryParams) []repository.CreateCategoryParams {
+	pending := make(map[uuid.UUID]bool, len(categories))
+	for _, category := range categories {
+		pending[category.ID] = true
+	}
+	ordered := make([]repository.CreateCategoryParams, 0, len(categories))
+	for len(ordered) < len(categories) {
+		added := false
+		for _, category := range categories {
+			if !pending[category.ID] || (category.ParentID != nil && pending[*category.ParentID]) {
+				continue
+			}
+			ordered = append(ordered, category)
+			delete(pending, category.ID)
+			added = true
+		}
+		if added {
+			continue
+		}
+		for i, category := range categories {
+			if pending[category.ID] {
+				categories[i].ParentID = nil
+				break
+			}
+		}
+	}
+	return ordered
+}
+
+// categoryLookup finds a user's categories by ID or, ignoring case, by name
+type categoryLookup struct {
+	byID   map[uuid.UUID]bool
+	byName map[string]uuid.UUID
+}
+
+func (h *ImportHandler) loadCategories(r *http.Request, userID uuid.UUID) (categoryLookup, error) {
+	categories, err := h.db.ListCategories(r.Context(), userID)
+	if err != nil {
+		return categoryLookup{}, err
+	}
+	lookup := categoryLookup{
+		byID:   make(map[uuid.UUID]bool, len(categories)),
+		byName: make(map[string]uuid.UUID, len(categories)),
+	}
+	for _, category := range categories {
+		lookup.byID[category.ID] = true
+		lookup.byName[strings.ToLower(category.Name)] = category.ID
+	}
+	return lookup, nil
+}
+
+func (c categoryLookup) has(id uuid.UUID) bool {
+	return c.byID[id]
+}
+
+func (c categoryLookup) find(nameOrID string) (uuid.UUID, bool) {
+	if id, err := uuid.Parse(nameOrID); err == nil && c.byID[id] {
+		return id, true
+	}
+	id, ok := c.byName[strings.ToLower(strings.TrimSpace(nameOrID))]
+	return id, ok
+}
+
+// add makes a category created by the import findable before it is saved
+func (c categoryLookup) add(id uuid.UUID, name string) {
+	c.byID[id] = true
+	c.byName[strings.ToLower(strings.TrimSpace(name))] = id
+}
+
+// exportLines are the lines of a JSON export each record starts on, so that
+// invalid records are reported like invalid statement lines
+type exportLines struct {
+	categories []int
+	tags       []int
+	expenses   []int
+	income     []int
+	budgets    []int
+}
+
+// decodeUserExport reads a JSON export, noting the line of every record
+func decodeUserExport(data []byte) (UserExport, exportLines, error) {
+	var export UserExport
+	var lines exportLines
+	dec := json.NewDecoder(bytes.NewReader(data))
+	lineAt := func() int {
+		offset := int(dec.InputOffset())
+		for offset < len(data) && strings.IndexByte(" \t\r\n,", data[offset]) >= 0 {
+			offset++
+		}
+		return 1 + bytes.Count(data[:offset], []byte("\n"))
+	}
+
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return export, lines, fmt.Errorf("not a JSON export")
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return export, lines, err
+		}
+		switch token {
+		case "categories":
+			export.Categories, lines.categories, err = decodeExportArray[ExportedCategory](dec, lineAt)
+		case "tags":
+			export.Tags, lines.tags, err = decodeExportArray[ExportedTag](dec, lineAt)
+		case "expenses":
+			export.Expenses, lines.expenses, err = decodeExportArray[ExportedExpense](dec, lineAt)
+		case "income":
+			export.Income, lines.income, err = decodeExportArray[ExportedIncome](dec, lineAt)
+		case "budgets":
+			export.Budgets, lines.budgets, err = decodeExportArray[ExportedBudget](dec, lineAt)
+		case "version":
+			err = dec.Decode(&export.Version)
+		case "exported_at":
+			err = dec.Decode(&export.ExportedAt)
+		case "base_currency":
+			err = dec.Decode(&export.BaseCurrency)
+		default:
+			var skipped json.RawMessage
+			err = dec.Decode(&skipped)
+		}
+		if err != nil {
+			return export, lines, fmt.Errorf("%v: %w", token, err)
+		}
+	}
+	return export, lines, nil
+}
+
+// decodeExportArray reads one array of a JSON export and the line each of its
+// elements starts on
+func decodeExportArray[T any](dec *json.Decoder, lineAt func() int) ([]T, []int, error) {
+	if token, err := dec.Token(); err != nil || token != json.Delim('[') {
+		return nil, nil, fmt.Errorf("must be an array")
+	}
+	var items []T
+	var lines []int
+	for dec.More() {
+		line := lineAt()
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		items = append(items, item)
+		lines = append(lines, line)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, err
+	}
+	return items, lines, nil
+}
+
+// importUserExport reads back a JSON export, such as one from another
+// instance. Categories and tags are matched to the user's by name and created
+// when missing, and budgets are recreated unless the user has one with the same
+// name, category, type and start date. Expenses and income are validated and
+// checked for duplicates like the lines of a statement, so importing an
+// export twice adds nothing the second time. If any record is invalid nothing
+// is saved; otherwise categories, tags, transactions and budgets are saved
+// together in one transaction.
+func (h *ImportHandler) importUserExport(w http.ResponseWriter, r *http.Request, uid uuid.UUID, opts importOptions) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUserExportImportSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Export file is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Error reading export", http.StatusBadRequest)
+		return
+	}
+	export, lines, err := decodeUserExport(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if export.Version != exportVersion {
+		http.Error(w, fmt.Sprintf("export version %d is not supported", export.Version), http.StatusBadRequest)
+		return
+	}
+
+	categories, err := h.loadCategories(r, uid)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error fetching categories", http.StatusInternalServerError)
+		return
+	}
+	userTags, err := h.db.ListTags(r.Context(), uid)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error fetching tags", http.StatusInternalServerError)
+		return
+	}
+	budgets, err := h.db.ListBudgets(r.Context(), uid)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error fetching budgets", http.StatusInternalServerError)
+		return
+	}
+
+	var lineErrors []ImportLineError
+	fail := func(line int, err error) {
+		lineErrors = append(lineErrors, ImportLineError{Line: line, Error: err.Error()})
+	}
+
+	// The export's category IDs become those of the user's categories
+	var newCategories []repository.CreateCategoryParams
+	categoryIDs := make(map[uuid.UUID]uuid.UUID, len(export.Categories))
+	exportParents := make(map[uuid.UUID]*uuid.UUID)
+	for i, category := range export.Categories {
+		if err := (&validation.CategoryValidation{Name: category.Name}).Validate(); err != nil {
+			fail(lines.categories[i], err)
+			continue
+		}
+		id, ok := categories.byName[strings.ToLower(strings.TrimSpace(category.Name))]
+		if !ok {
+			id = uuid.New()
+			newCategories = append(newCategories, repository.CreateCategoryParams{ID: id, UserID: uid, Name: category.Name})
+			exportParents[id] = category.ParentID
+			categories.add(id, category.Name)
+		}
+		categoryIDs[category.ID] = id
+	}
+	// New categories keep their place in the export's tree; the user's
+	// existing categories stay where they are
+	for i, category := range newCategories {
+		if parent := exportParents[category.ID]; parent != nil {
+			if id, ok := categoryIDs[*parent]; ok && id != category.ID {
+				newCategories[i].ParentID = &id
+			}
+		}
+	}
+	newCategories = parentsFirst(newCategories)
+	category := func(id uuid.UUID) (string, error) {
+		mapped, ok := categoryIDs[id]
+		if !ok {
+			return "", fmt.Errorf("category %s is not in the export", id)
+		}
+		return mapped.String(), nil
+	}
+
+	// Likewise for tags
+	tagsByName := make(map[string]uuid.UUID, len(userTags))
+	for _, tag := range userTags {
+		tagsByName[strings.ToLower(strings.TrimSpace(tag.Name))] = tag.ID
+	}
+	var newTags []repository.CreateTagParams
+	tagIDs := make(map[uuid.UUID]uuid.UUID, len(export.Tags))
+	for i, tag := range export.Tags {
+		if err := (&validation.TagValidation{Name: tag.Name}).Validate(); err != nil {
+			fail(lines.tags[i], err)
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(tag.Name))
+		id, ok := tagsByName[name]
+		if !ok {
+			id = uuid.New()
+			newTags = append(newTags, repository.CreateTagParams{ID: id, UserID: uid, Name: tag.Name})
+			tagsByName[name] = id
+		}
+		tagIDs[tag.ID] = id
+	}
+	// transactionTags maps the tags of the transaction on each line
+	transactionTags := make(map[int][]uuid.UUID)
+	tags := func(line int, ids []uuid.UUID) error {
+		for _, id := range ids {
+			mapped, ok := tagIDs[id]
+			if !ok {
+				return fmt.Errorf("tag %s is not in the export", id)
+			}
+			transactionTags[line] = append(transactionTags[line], mapped)
+		}
+		return nil
+	}
+
+	var transactions []statement.Transaction
+	for i, expense := range export.Expenses {
+		transaction := statement.Transaction{
+			Line:        lines.expenses[i],
+			Type:        statement.TypeExpense,
+			Amount:      expense.Amount,
+			Currency:    expense.Currency,
+			Description: expense.Description,
+			ExternalID:  expense.ExternalID,
+		}
+		if transaction.Date, err = validation.ValidateDate(expense.Date); err != nil {
+			fail(transaction.Line, err)
+			continue
+		}
+		if transaction.Category, err = category(expense.CategoryID); err != nil {
+			fail(transaction.Line, err)
+			continue
+		}
+		if err := tags(transaction.Line, expense.TagIDs); err != nil {
+			fail(transaction.Line, err)
+			continue
+		}
+		transactions = append(transactions, transaction)
+	}
+	for i, income := range export.Income {
+		if err := tags(lines.income[i], income.TagIDs); err != nil {
+			fail(lines.income[i], err)
+			continue
+		}
+		transactions = append(transactions, statement.Transaction{
+			Line:        lines.income[i],
+			Type:        statement.TypeIncome,
+			Date:        income.Date,
+			Amount:      income.Amount,
+			Currency:    income.Currency,
+			Description: income.Description,
+			Source:      income.Source,
+			ExternalID:  income.ExternalID,
+		})
+	}
+	var imported []ImportedTransaction
+	for _, transaction := range transactions {
+		row, err := importedTransaction(transaction, opts, categories, nil)
+		if err != nil {
+			fail(transaction.Line, err)
+			continue
+		}
+		row.TagIDs = transactionTags[transaction.Line]
+		imported = append(imported, row)
+	}
+
+	var newBudgets []repository.CreateBudgetParams
+	var duplicates []ImportDuplicate
+	for i, budget := range export.Budgets {
+		line := lines.budgets[i]
+		mapped, err := category(budget.CategoryID)
+		if err != nil {
+			fail(line, err)
+			continue
+		}
+		params, err := importedBudget(uid, budget.CreateBudgetRequest, uuid.MustParse(mapped))
+		if err != nil {
+			fail(line, err)
+			continue
+		}
+		if budgetExists(budgets, params) {
+			duplicates = append(duplicates, ImportDuplicate{Line: line, Reason: "budget already exists"})
+			continue
+		}
+		newBudgets = append(newBudgets, params)
+	}
+
+	sort.SliceStable(lineErrors, func(i, j int) bool {
+		return lineErrors[i].Line < lineErrors[j].Line
+	})
+	if lineErrors == nil {
+		lineErrors = []ImportLineError{}
+	}
+	if len(lineErrors) > 0 && !opts.dryRun {
+		writeJSON(w, http.StatusBadRequest, ImportTransactionsResponse{Errors: lineErrors})
+		return
+	}
+
+	imported, skipped, err := h.skipDuplicates(r, uid, imported, opts.allowDuplicates)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error checking for duplicate transactions", http.StatusInternalServerError)
+		return
+	}
+	duplicates = append(skipped, duplicates...)
+	params := importParams(uid, imported)
+
+	resp := ImportTransactionsResponse{
+		DryRun:       opts.dryRun,
+		Expenses:     int64(len(params.ExpenseIds)),
+		Income:       int64(len(params.IncomeIds)),
+		Categories:   int64(len(newCategories)),
+		Tags:         int64(len(newTags)),
+		Budgets:      int64(len(newBudgets)),
+		Transactions: imported,
+		Duplicates:   duplicates,
+		Errors:       lineErrors,
+	}
+	if opts.dryRun {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	var saved repository.ImportTransactionsRow
+	err = h.db.InTx(r.Context(), func(tx repository.Repository) error {
+		for _, category := range newCategories {
+			if _, err := tx.CreateCategory(r.Context(), category); err != nil {
+				return fmt.Errorf("creating categories: %w", err)
+			}
+		}
+		for _, tag := range newTags {
+			if _, err := tx.CreateTag(r.Context(), tag); err != nil {
+				return fmt.Errorf("creating tags: %w", err)
+			}
+		}
+		var err error
+		if saved, err = tx.ImportTransactions(r.Context(), params); err != nil {
+			return fmt.Errorf("importing transactions: %w", err)
+		}
+		if err := tx.LinkImportedTags(r.Context(), importTagLinks(imported, params)); err != nil {
+			return fmt.Errorf("tagging transactions: %w", err)
+		}
+		for _, budget := range newBudgets {
+			if _, err := tx.CreateBudget(r.Context(), budget); err != nil {
+				return fmt.Errorf("creating budgets: %w", err)
+			}
+		}
+		return nil
+	})
+	if externalIDConflict(err) {
+		http.Error(w, externalIDConflictMessage, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error importing export", http.StatusInternalServerError)
+		return
+	}
+
+	resp.Expenses, resp.Income = saved.Expenses, saved.Income
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// importedBudget validates an exported budget like POST /budgets does
+func importedBudget(userID uuid.UUID, req CreateBudgetRequest, categoryID uuid.UUID) (repository.CreateBudgetParams, error) {
+	validator := &validation.BudgetValidation{
+		Amount:     req.Amount,
+		Currency:   req.Currency,
+		CategoryID: categoryID,
+		Type:       req.Type,
+		Cadence:    req.Cadence,
+		Rollover:   req.Rollover,
+		StartDate:  req.StartDate,
+		EndDate:    req.EndDate,
+		Name:       req.Name,
+	}
+	if err := validator.Validate(); err != nil {
+		return repository.CreateBudgetParams{}, err
+	}
+
+	startDate, _ := validation.ValidateDate(req.StartDate)
+	var endDate *time.Time
+	if req.EndDate != "" {
+		parsed, _ := validation.ValidateDate(req.EndDate)
+		endDate = &parsed
+	}
+	var cadence *recurrence.Cadence
+	if req.Cadence != "" {
+		parsed, _ := recurrence.ParseCadence(req.Cadence)
+		cadence = &parsed
+	}
+	return repository.CreateBudgetParams{
+		ID:         uuid.New(),
+		UserID:     userID,
+		Amount:     req.Amount,
+		Currency:   req.Currency,
+		CategoryID: categoryID,
+		Type:       req.Type,
+		Cadence:    cadence,
+		Rollover:   req.Rollover != nil && *req.Rollover,
+		StartDate:  startDate,
+		EndDate:    endDate,
+		Name:       req.Name,
+	}, nil
+}
+
+// budgetExists reports whether the user already has the budget
+func budgetExists(budgets []repository.Budget, params repository.CreateBudgetParams) bool {
+	for _, budget := range budgets {
+		if strings.EqualFold(budget.Name, params.Name) &&
+			budget.CategoryID == params.CategoryID &&
+			budget.Type == params.Type &&
+			budget.StartDate.Equal(params.StartDate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,431 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/jorge-dev/centsible/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+type importHandlerTestSuite struct {
+	mockRepo   *mocks.MockRepository
+	handler    *ImportHandler
+	userID     uuid.UUID
+	groceries  uuid.UUID
+	otherUsers uuid.UUID
+}
+
+func (s *importHandlerTestSuite) cleanup() {
+	s.mockRepo.Reset()
+}
+
+func setupImportHandlerTest(t *testing.T) *importHandlerTestSuite {
+	suite := &importHandlerTestSuite{}
+	t.Cleanup(suite.cleanup)
+
+	repo := mocks.NewMockRepository()
+	mock, ok := repo.(*mocks.MockRepository)
+	if !ok {
+		t.Fatal("could not cast to MockRepository")
+	}
+	suite.mockRepo = mock
+	suite.handler = NewImportHandler(repo)
+
+	suite.userID = uuid.New()
+	suite.groceries = uuid.New()
+	suite.otherUsers = uuid.New()
+	suite.mockRepo.GetUserMock().AddUser(repository.GetUserByIDRow{
+		ID:           suite.userID,
+		Name:         "Importer",
+		Email:        "importer@example.com",
+		BaseCurrency: "CAD",
+	})
+	suite.mockRepo.GetCategoryMock().AddCategory(repository.Category{
+		ID:     suite.groceries,
+		UserID: suite.userID,
+		Name:   "Groceries",
+	})
+	suite.mockRepo.GetCategoryMock().AddCategory(repository.Category{
+		ID:     suite.otherUsers,
+		UserID: uuid.New(),
+		Name:   "Travel",
+	})
+
+	return suite
+}
+
+const importOFX = `OFXHEADER:100
+DATA:OFXSGML
+
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>USD
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20240103<TRNAMT>-42.17<FITID>1<NAME>CORNER GROCER</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20240115<TRNAMT>2500.00<FITID>2<NAME>ACME PAYROLL<MEMO>January</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>
+`
+
+func TestImportTransactions(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		contentType  string
+		body         string
+		wantStatus   int
+		wantExpenses int64
+		wantIncome   int64
+		wantErrors   []int // Lines reported as invalid
+		wantSaved    bool
+	}{
+		{
+			name:  "CSV with the default columns",
+			query: "",
+			body: "date,type,amount,currency,category,source,description\n" +
+				"2024-01-05T00:00:00Z,expense,12.50,USD,groceries,,Weekly shop\n" +
+				"2024-01-06T00:00:00Z,income,2500,USD,,Employer,January pay\n",
+			wantStatus:   http.StatusOK,
+			wantExpenses: 1,
+			wantIncome:   1,
+			wantSaved:    true,
+		},
+		{
+			name:  "CSV with a column mapping and a default category",
+			query: "?date_column=Posted&amount_column=Amount&description_column=Payee&date_format=MM/DD/YYYY&category_id={groceries}",
+			body: "Posted,Payee,Amount\n" +
+				"01/31/2024,Corner Grocer,-4.75\n" +
+				"02/01/2024,Refund,\"1,000.00\"\n",
+			wantStatus:   http.StatusOK,
+			wantExpenses: 1,
+			wantIncome:   1,
+			wantSaved:    true,
+		},
+		{
+			name:  "Any invalid line saves nothing",
+			query: "",
+			body: "date,type,amount,currency,category,source,description\n" +
+				"2024-01-05T00:00:00Z,expense,12.50,USD,Groceries,,Weekly shop\n" +
+				"2024-01-06T00:00:00Z,expense,12.50,USD,Travel,,Another user's category\n" +
+				"2024-01-07T00:00:00Z,expense,12.505,USD,Groceries,,Too precise\n" +
+				"2024-01-08T00:00:00Z,income,100,USD,,,\n" +
+				"yesterday,income,100,USD,,Gift,\n",
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []int{3, 4, 5, 6},
+		},
+		{
+			name:  "Dry run previews and reports",
+			query: "?dry_run=true",
+			body: "date,type,amount,currency,category,source,description\n" +
+				"2024-01-05T00:00:00Z,expense,12.50,USD,Groceries,,Weekly shop\n" +
+				"2024-01-06T00:00:00Z,expense,12.50,USD,,,No category\n",
+			wantStatus:   http.StatusOK,
+			wantExpenses: 1,
+			wantErrors:   []int{3},
+		},
+		{
+			name:         "OFX by content type",
+			contentType:  "application/x-ofx",
+			query:        "?category_id={groceries}",
+			body:         importOFX,
+			wantStatus:   http.StatusOK,
+			wantExpenses: 1,
+			wantIncome:   1,
+			wantSaved:    true,
+		},
+		{
+			name:       "OFX without a category for expenses",
+			query:      "?format=qfx",
+			body:       importOFX,
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []int{7},
+		},
+		{
+			name:       "Another user's default category",
+			query:      "?category_id={travel}",
+			body:       "date,amount\n2024-01-01,-1\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Unknown format",
+			query:      "?format=xlsx",
+			body:       "date,amount\n2024-01-01,-1\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Missing columns",
+			query:      "?amount_column=Betrag",
+			body:       "date,amount\n2024-01-01,-1\n",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite := setupImportHandlerTest(t)
+			query := strings.NewReplacer("{groceries}", suite.groceries.String(), "{travel}", suite.otherUsers.String()).Replace(tt.query)
+
+			req := withUser(httptest.NewRequest(http.MethodPost, "/user/import"+query, strings.NewReader(tt.body)), suite.userID)
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			suite.handler.ImportTransactions(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if w.Header().Get("Content-Type") != "application/json" {
+				return
+			}
+
+			var resp ImportTransactionsResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.wantExpenses, resp.Expenses)
+			assert.Equal(t, tt.wantIncome, resp.Income)
+			var lines []int
+			for _, lineErr := range resp.Errors {
+				lines = append(lines, lineErr.Line)
+			}
+			assert.Equal(t, tt.wantErrors, lines)
+
+			expenses, _ := suite.mockRepo.ListExpenses(context.Background(), suite.userID)
+			income, _ := suite.mockRepo.ListIncome(context.Background(), suite.userID)
+			if tt.wantSaved {
+				assert.Len(t, expenses, int(tt.wantExpenses))
+				assert.Len(t, income, int(tt.wantIncome))
+			} else {
+				assert.Empty(t, expenses)
+				assert.Empty(t, income)
+			}
+		})
+	}
+}
+
+func TestImportTransactionsDefaultsCurrency(t *testing.T) {
+	suite := setupImportHandlerTest(t)
+
+	body := "date,amount,category,description\n2024-01-05,-3.00,Groceries,Milk\n2024-01-06,-4.00,Groceries,Bread\n"
+	for _, tt := range []struct {
+		query        string
+		wantCurrency string
+	}{
+		{"?dry_run=true", "CAD"},
+		{"?dry_run=true&currency=eur", "EUR"},
+	} {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/user/import"+tt.query, strings.NewReader(body)), suite.userID)
+		w := httptest.NewRecorder()
+		suite.handler.ImportTransactions(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp ImportTransactionsResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, resp.Transactions, 2)
+		for _, transaction := range resp.Transactions {
+			assert.Equal(t, tt.wantCurrency, transaction.Currency)
+			assert.Equal(t, suite.groceries, *transaction.CategoryID)
+		}
+	}
+}
+
+func TestImportTransactionsSkipsDuplicates(t *testing.T) {
+	suite := setupImportHandlerTest(t)
+	body := "date,type,amount,currency,category,source,description,external_id\n" +
+		"2024-01-05T00:00:00Z,expense,3.50,USD,Groceries,,Coffee,\n" +
+		"2024-01-05T00:00:00Z,expense,3.50,USD,Groceries,,Coffee,\n" +
+		"2024-01-06T00:00:00Z,income,2500,USD,,Employer,January pay,pay-1\n"
+
+	importStatement := func(query string) ImportTransactionsResponse {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/user/import"+query, strings.NewReader(body)), suite.userID)
+		w := httptest.NewRecorder()
+		suite.handler.ImportTransactions(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp ImportTransactionsResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	duplicateLines := func(resp ImportTransactionsResponse) []int {
+		var lines []int
+		for _, duplicate := range resp.Duplicates {
+			lines = append(lines, duplicate.Line)
+		}
+		return lines
+	}
+
+	// Identical lines within one statement are all imported
+	resp := importStatement("")
+	assert.Equal(t, int64(2), resp.Expenses)
+	assert.Equal(t, int64(1), resp.Income)
+	assert.Empty(t, resp.Duplicates)
+
+	// Importing the statement again imports nothing
+	resp = importStatement("")
+	assert.Equal(t, int64(0), resp.Expenses)
+	assert.Equal(t, int64(0), resp.Income)
+	assert.Equal(t, []int{2, 3, 4}, duplicateLines(resp))
+
+	// Allowing duplicates still skips external IDs already imported
+	resp = importStatement("?allow_duplicates=true&dry_run=true")
+	assert.Equal(t, int64(2), resp.Expenses)
+	assert.Equal(t, int64(0), resp.Income)
+	assert.Equal(t, []int{4}, duplicateLines(resp))
+
+	expenses, _ := suite.mockRepo.ListExpenses(context.Background(), suite.userID)
+	income, _ := suite.mockRepo.ListIncome(context.Background(), suite.userID)
+	assert.Len(t, expenses, 2)
+	if assert.Len(t, income, 1) {
+		assert.Equal(t, "pay-1", *income[0].ExternalID)
+	}
+}
+
+func TestImportTransactionsCategoryRules(t *testing.T) {
+	suite := setupImportHandlerTest(t)
+	dining, work := uuid.New(), uuid.New()
+	suite.mockRepo.GetCategoryMock().AddCategory(repository.Category{ID: dining, UserID: suite.userID, Name: "Dining"})
+	suite.mockRepo.GetTagMock().AddTag(repository.Tag{ID: work, UserID: suite.userID, Name: "Work"})
+	pattern := "*cafe*"
+	suite.mockRepo.GetCategoryRuleMock().AddCategoryRule(repository.CategoryRule{
+		ID:                 uuid.New(),
+		UserID:             suite.userID,
+		Name:               "Cafes",
+		DescriptionPattern: &pattern,
+		CategoryID:         &dining,
+		TagIds:             []uuid.UUID{work},
+		Enabled:            true,
+	})
+
+	// The statement's category wins over the rule's, which wins over
+	// category_id
+	body := "date,amount,category,description\n" +
+		"2024-01-05,-3.50,,Corner Cafe\n" +
+		"2024-01-06,-8.00,Groceries,Cafe beans\n" +
+		"2024-01-07,-30.00,,Fuel\n"
+	req := withUser(httptest.NewRequest(http.MethodPost, "/user/import?category_id="+suite.groceries.String(), strings.NewReader(body)), suite.userID)
+	w := httptest.NewRecorder()
+	suite.handler.ImportTransactions(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp ImportTransactionsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, resp.Transactions, 3) {
+		return
+	}
+	for i, want := range []struct {
+		category uuid.UUID
+		tags     []uuid.UUID
+	}{
+		{dining, []uuid.UUID{work}},
+		{suite.groceries, []uuid.UUID{work}},
+		{suite.groceries, nil},
+	} {
+		assert.Equal(t, want.category, *resp.Transactions[i].CategoryID)
+		assert.Equal(t, want.tags, resp.Transactions[i].TagIDs)
+	}
+
+	expenses, _ := suite.mockRepo.ListExpenses(context.Background(), suite.userID)
+	tagged := 0
+	for _, expense := range expenses {
+		tags, _ := suite.mockRepo.ListExpenseTags(context.Background(), repository.ListExpenseTagsParams{
+			ExpenseID: expense.ID,
+			UserID:    suite.userID,
+		})
+		tagged += len(tags)
+	}
+	assert.Equal(t, 2, tagged)
+}
+
+func TestImportTransactionsRepeatedExternalID(t *testing.T) {
+	suite := setupImportHandlerTest(t)
+	body := "date,amount,category,description,external_id\n" +
+		"2024-01-05,-3.50,Groceries,Coffee,A1\n" +
+		"2024-01-06,-8.00,Groceries,Lunch,A1\n"
+
+	req := withUser(httptest.NewRequest(http.MethodPost, "/user/import", strings.NewReader(body)), suite.userID)
+	w := httptest.NewRecorder()
+	suite.handler.ImportTransactions(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp ImportTransactionsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), resp.Expenses)
+	assert.Equal(t, []ImportDuplicate{{Line: 3, Reason: `external ID "A1" is also on line 2`}}, resp.Duplicates)
+}
+
+func TestImportTransactionsExternalIDRace(t *testing.T) {
+	suite := setupImportHandlerTest(t)
+	externalID := "A1"
+	suite.mockRepo.GetExpenseMock().AddExpense(repository.Expense{
+		ID:          uuid.New(),
+		UserID:      suite.userID,
+		Amount:      money.MustParse("9.99"),
+		Currency:    "USD",
+		CategoryID:  suite.groceries,
+		Description: "Imported alongside",
+		ExternalID:  &externalID,
+	})
+	body := "date,amount,category,description,external_id\n" +
+		"2024-01-05,-3.00,Groceries,Milk,A1\n" +
+		"2024-01-06,-4.00,Groceries,Bread,A2\n"
+	importStatement := func(handler *ImportHandler) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/user/import", strings.NewReader(body)), suite.userID)
+		w := httptest.NewRecorder()
+		handler.ImportTransactions(w, req)
+		return w
+	}
+
+	// The unique index catches what the duplicate check missed, and the
+	// import saves nothing
+	w := importStatement(NewImportHandler(&racedDuplicates{Repository: suite.mockRepo}))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	expenses, _ := suite.mockRepo.ListExpenses(context.Background(), suite.userID)
+	assert.Len(t, expenses, 1)
+
+	// Importing again leaves the line out as a duplicate
+	w = importStatement(suite.handler)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp ImportTransactionsResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, int64(1), resp.Expenses)
+	if assert.Len(t, resp.Duplicates, 1) {
+		assert.Equal(t, 2, resp.Duplicates[0].Line)
+	}
+}
+
+func TestImportTransactionsRetriesConflicts(t *testing.T) {
+	suite := setupImportHandlerTest(t)
+	body := "date,amount,category,description\n2024-01-05,-3.00,Groceries,Milk\n2024-01-06,-4.00,Groceries,Bread\n"
+	importStatement := func() int {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/user/import?allow_duplicates=true", strings.NewReader(body)), suite.userID)
+		w := httptest.NewRecorder()
+		suite.handler.ImportTransactions(w, req)
+		return w.Code
+	}
+
+	// A transaction that conflicts is tried again, importing the statement once
+	suite.mockRepo.FailTransactions(repository.MaxTxAttempts - 1)
+	assert.Equal(t, http.StatusOK, importStatement())
+	expenses, _ := suite.mockRepo.ListExpenses(context.Background(), suite.userID)
+	assert.Len(t, expenses, 2)
+
+	// One that keeps conflicting imports nothing
+	suite.mockRepo.FailTransactions(repository.MaxTxAttempts)
+	assert.Equal(t, http.StatusInternalServerError, importStatement())
+	expenses, _ = suite.mockRepo.ListExpenses(context.Background(), suite.userID)
+	assert.Len(t, expenses, 2)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 	"github.com/jorge-dev/centsible/internal/repository"
 	"github.com/jorge-dev/centsible/internal/validation"
 	"github.com/jorge-dev/centsible/server/middleware"
@@ -21,19 +22,34 @@ func NewIncomeHandler(db repository.Repository) *IncomeHandler {
 }
 
 type CreateIncomeRequest struct {
-	Amount      float64   `json:"amount"`
-	Currency    string    `json:"currency"`
-	Source      string    `json:"source"`
-	Date        time.Time `json:"date"`
-	Description string    `json:"description"`
+	Amount      money.Amount `json:"amount"`
+	Currency    string       `json:"currency"`
+	Source      string       `json:"source"`
+	Date        time.Time    `json:"date"`
+	Description string       `json:"description"`
+	ExternalID  string       `json:"external_id"`
 }
 
+// DuplicateIncomeResponse is the 409 Conflict body of income that likely
+// duplicates records the user already has
+type DuplicateIncomeResponse struct {
+	Error      string              `json:"error"`
+	Duplicates []repository.Income `json:"duplicates"`
+}
+
+// CreateIncome refuses income likely duplicating existing records like
+// CreateExpense does, with the source in place of the category
 func (h *IncomeHandler) CreateIncome(w http.ResponseWriter, r *http.Request) {
 	var req CreateIncomeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	allowDuplicate, err := queryBool(r.URL.Query(), "allow_duplicate")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	validator := validation.IncomeValidation{
 		Amount:      req.Amount,
@@ -41,6 +57,7 @@ func (h *IncomeHandler) CreateIncome(w http.ResponseWriter, r *http.Request) {
 		Source:      req.Source,
 		Date:        req.Date.Format(time.RFC3339),
 		Description: req.Description,
+		ExternalID:  req.ExternalID,
 	}
 
 	if err := validator.Validate(); err != nil {
@@ -59,6 +76,32 @@ func (h *IncomeHandler) CreateIncome(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	duplicateArgs := repository.FindDuplicateIncomeParams{
+		UserID:      uid,
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		Date:        req.Date,
+		Description: req.Description,
+		Source:      req.Source,
+		ExternalID:  req.ExternalID,
+	}
+	duplicates, err := h.db.FindDuplicateIncome(r.Context(), duplicateArgs)
+	if err != nil {
+		http.Error(w, "Error checking for duplicate income", http.StatusInternalServerError)
+		return
+	}
+	if len(duplicates) > 0 && (!allowDuplicate || incomeExternalIDTaken(duplicates, req.ExternalID)) {
+		writeJSON(w, http.StatusConflict, DuplicateIncomeResponse{
+			Error:      "Income likely duplicates an existing record",
+			Duplicates: duplicates,
+		})
+		return
+	}
+
+	var externalID *string
+	if req.ExternalID != "" {
+		externalID = &req.ExternalID
+	}
 	income, err := h.db.CreateIncome(r.Context(), repository.CreateIncomeParams{
 		ID:          uuid.New(),
 		UserID:      uid,
@@ -67,7 +110,20 @@ func (h *IncomeHandler) CreateIncome(w http.ResponseWriter, r *http.Request) {
 		Source:      req.Source,
 		Date:        req.Date,
 		Description: req.Description,
+		ExternalID:  externalID,
 	})
+	if repository.IsUniqueViolation(err, repository.IncomeExternalIDIndex) {
+		// Saved by a request running alongside this one, after the check above
+		duplicates, _ = h.db.FindDuplicateIncome(r.Context(), duplicateArgs)
+		if duplicates == nil {
+			duplicates = []repository.Income{}
+		}
+		writeJSON(w, http.StatusConflict, DuplicateIncomeResponse{
+			Error:      "Income likely duplicates an existing record",
+			Duplicates: duplicates,
+		})
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error creating income record", http.StatusInternalServerError)
 		return
@@ -78,6 +134,8 @@ func (h *IncomeHandler) CreateIncome(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(income)
 }
 
+// GetIncomeList handles GET /income, newest first unless sorted otherwise.
+// See parseListQuery for paging, sorting and filtering.
 func (h *IncomeHandler) GetIncomeList(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
 	if !ok {
@@ -90,19 +148,37 @@ func (h *IncomeHandler) GetIncomeList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	incomes, err := h.db.ListIncome(r.Context(), uid)
+	q, err := parseListQuery(r.URL.Query(), transactionSorts, "desc")
 	if err != nil {
-		http.Error(w, "Error fetching income records", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	//TODO: find a better way to handle empty slices
-	if len(incomes) == 0 {
-		incomes = []repository.Income{}
+	incomes, err := h.db.ListIncomePage(r.Context(), repository.ListIncomePageParams{
+		UserID:      uid,
+		MinAmount:   q.minAmount,
+		MaxAmount:   q.maxAmount,
+		Currency:    q.currency,
+		Source:      q.source,
+		TagID:       q.tagID,
+		Search:      q.search,
+		StartDate:   q.startDate,
+		EndDate:     q.endDate,
+		AfterID:     q.afterID(),
+		Sort:        q.sort,
+		Descending:  q.fetchDescending(),
+		AfterAmount: q.afterAmount(),
+		AfterTime:   q.afterTime(),
+		PageSize:    q.pageSize(),
+	})
+	if err != nil {
+		http.Error(w, "Error fetching income records", http.StatusInternalServerError)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(incomes)
+	writeJSON(w, http.StatusOK, paginate(q, incomes, func(income repository.Income) listCursor {
+		return q.cursorAt(income.Amount, income.CreatedAt, income.Date, "", income.ID)
+	}))
 }
 
 func (h *IncomeHandler) GetIncomeByID(w http.ResponseWriter, r *http.Request) {
@@ -263,3 +339,14 @@ func (h *IncomeHandler) DeleteIncome(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// incomeExternalIDTaken reports whether one of the duplicates already has the
+// external ID, which no two income records may share
+func incomeExternalIDTaken(duplicates []repository.Income, externalID string) bool {
+	for _, duplicate := range duplicates {
+		if externalID != "" && duplicate.ExternalID != nil && *duplicate.ExternalID == externalID {
+			return true
+		}
+	}
+	return false
+}
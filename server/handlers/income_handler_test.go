@@ -11,6 +11,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 	"github.com/jorge-dev/centsible/internal/repository"
 	"github.com/jorge-dev/centsible/internal/repository/mocks"
 	"github.com/jorge-dev/centsible/server/middleware"
@@ -50,7 +51,7 @@ func setupIncomeHandlerTest(t *testing.T) *incomeHandlerTestSuite {
 	suite.testIncome = repository.Income{
 		ID:          uuid.New(),
 		UserID:      suite.testUserID,
-		Amount:      1000.50,
+		Amount:      money.MustParse("1000.50"),
 		Currency:    "USD",
 		Source:      "Salary",
 		Date:        now,
@@ -69,24 +70,62 @@ func TestCreateIncome(t *testing.T) {
 
 	tests := []struct {
 		name       string
+		query      string
 		reqBody    CreateIncomeRequest
 		wantStatus int
 	}{
 		{
 			name: "Valid income",
 			reqBody: CreateIncomeRequest{
-				Amount:      1000.50,
+				Amount:      money.MustParse("1000.50"),
 				Currency:    "USD",
 				Source:      "Salary",
 				Date:        time.Now(),
+				Description: "Year-end bonus",
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Likely duplicate",
+			reqBody: CreateIncomeRequest{
+				Amount:      money.MustParse("1000.5"),
+				Currency:    "USD",
+				Source:      "salary ",
+				Date:        suite.testIncome.Date,
+				Description: "Monthly Salary",
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:  "Likely duplicate allowed",
+			query: "?allow_duplicate=true",
+			reqBody: CreateIncomeRequest{
+				Amount:      money.MustParse("1000.50"),
+				Currency:    "USD",
+				Source:      "Salary",
+				Date:        suite.testIncome.Date,
 				Description: "Monthly salary",
+				ExternalID:  "payroll-1",
 			},
 			wantStatus: http.StatusCreated,
 		},
+		{
+			name:  "External ID taken",
+			query: "?allow_duplicate=true",
+			reqBody: CreateIncomeRequest{
+				Amount:      money.MustParse("20"),
+				Currency:    "USD",
+				Source:      "Refund",
+				Date:        time.Now(),
+				Description: "Returned shoes",
+				ExternalID:  "payroll-1",
+			},
+			wantStatus: http.StatusConflict,
+		},
 		{
 			name: "Invalid amount",
 			reqBody: CreateIncomeRequest{
-				Amount:      -100,
+				Amount:      money.MustParse("-100"),
 				Currency:    "USD",
 				Source:      "Salary",
 				Date:        time.Now(),
@@ -97,7 +136,7 @@ func TestCreateIncome(t *testing.T) {
 		{
 			name: "Missing currency",
 			reqBody: CreateIncomeRequest{
-				Amount:      1000.50,
+				Amount:      money.MustParse("1000.50"),
 				Source:      "Salary",
 				Date:        time.Now(),
 				Description: "Monthly salary",
@@ -109,7 +148,7 @@ func TestCreateIncome(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			body, _ := json.Marshal(tt.reqBody)
-			req := httptest.NewRequest(http.MethodPost, "/api/income", bytes.NewBuffer(body))
+			req := httptest.NewRequest(http.MethodPost, "/api/income"+tt.query, bytes.NewBuffer(body))
 			ctx := context.WithValue(req.Context(), middleware.UserIDKey, suite.testUserID.String())
 			req = req.WithContext(ctx)
 
@@ -121,6 +160,37 @@ func TestCreateIncome(t *testing.T) {
 	}
 }
 
+func TestCreateIncome_ExternalIDRace(t *testing.T) {
+	suite := setupIncomeHandlerTest(t)
+	externalID := "pay-7"
+	saved := suite.testIncome
+	saved.ID = uuid.New()
+	saved.ExternalID = &externalID
+	suite.mockRepo.GetIncomeMock().AddIncome(saved)
+	handler := NewIncomeHandler(&racedDuplicates{Repository: suite.mockRepo})
+
+	body, _ := json.Marshal(CreateIncomeRequest{
+		Amount:      money.MustParse("250"),
+		Currency:    "USD",
+		Source:      "Freelance",
+		Date:        time.Now(),
+		Description: "Invoice 7",
+		ExternalID:  externalID,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/income", bytes.NewBuffer(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, suite.testUserID.String()))
+	w := httptest.NewRecorder()
+	handler.CreateIncome(w, req)
+
+	// The unique index catches what the check missed
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var resp DuplicateIncomeResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	if assert.Len(t, resp.Duplicates, 1) {
+		assert.Equal(t, saved.ID, resp.Duplicates[0].ID)
+	}
+}
+
 func TestGetIncomeByID(t *testing.T) {
 	suite := setupIncomeHandlerTest(t)
 
@@ -186,7 +256,7 @@ func TestUpdateIncome(t *testing.T) {
 			name:     "Valid update",
 			incomeID: suite.testIncome.ID.String(),
 			reqBody: CreateIncomeRequest{
-				Amount:      2000.50,
+				Amount:      money.MustParse("2000.50"),
 				Currency:    "EUR",
 				Source:      "Bonus",
 				Date:        time.Now(),
@@ -198,7 +268,7 @@ func TestUpdateIncome(t *testing.T) {
 			name:     "Invalid amount",
 			incomeID: suite.testIncome.ID.String(),
 			reqBody: CreateIncomeRequest{
-				Amount: -100,
+				Amount: money.MustParse("-100"),
 			},
 			wantStatus: http.StatusBadRequest,
 		},
@@ -276,8 +346,8 @@ func TestListIncome(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	var incomes []repository.Income
-	err := json.NewDecoder(w.Body).Decode(&incomes)
+	var page Page[repository.Income]
+	err := json.NewDecoder(w.Body).Decode(&page)
 	assert.NoError(t, err)
-	assert.NotEmpty(t, incomes)
+	assert.NotEmpty(t, page.Items)
 }
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/jorge-dev/centsible/internal/auth"
+)
+
+// jwksMaxAge lets verifiers cache the key set, short enough that a rotated key is picked up quickly
+const jwksMaxAge = "public, max-age=300"
+
+type KeysHandler struct {
+	jwtManager *auth.JWTManager
+}
+
+func NewKeysHandler(jm *auth.JWTManager) *KeysHandler {
+	return &KeysHandler{jwtManager: jm}
+}
+
+// JWKS handles GET /.well-known/jwks.json
+func (h *KeysHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", jwksMaxAge)
+	if err := writeJSON(w, http.StatusOK, h.jwtManager.JWKS()); err != nil {
+		log.Printf("Error writing JWKS: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jorge-dev/centsible/internal/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func newEd25519KeySet(t *testing.T) *auth.KeySet {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := auth.ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.NewKeySet(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestJWKS(t *testing.T) {
+	tests := []struct {
+		name     string
+		manager  *auth.JWTManager
+		wantKeys int
+	}{
+		{
+			name:     "Shared secret publishes nothing",
+			manager:  auth.NewJWTManager("test_secret"),
+			wantKeys: 0,
+		},
+		{
+			name:     "Asymmetric signing key",
+			manager:  auth.NewJWTManagerWithKeys("", newEd25519KeySet(t), auth.NewMemoryStore()),
+			wantKeys: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+			w := httptest.NewRecorder()
+
+			NewKeysHandler(tt.manager).JWKS(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			assert.Contains(t, w.Header().Get("Cache-Control"), "max-age")
+
+			var response auth.JWKS
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+			assert.NotNil(t, response.Keys)
+			assert.Len(t, response.Keys, tt.wantKeys)
+			for _, key := range response.Keys {
+				assert.Equal(t, "OKP", key.Kty)
+				assert.Equal(t, "EdDSA", key.Alg)
+				assert.NotEmpty(t, key.Kid)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/validation"
+)
+
+// defaultListLimit is how many items a page of a list holds unless the
+// request asks for another limit
+const defaultListLimit = 50
+
+var (
+	// transactionSorts are the fields expenses and income are sorted by, the
+	// first by default
+	transactionSorts = []string{"date", "amount", "created_at"}
+
+	// budgetSorts are the fields budgets are sorted by, the first by default.
+	// Budgets are dated by their start date.
+	budgetSorts = []string{"created_at", "date", "amount"}
+
+	// categorySorts are the fields categories are sorted by, the first by
+	// default
+	categorySorts = []string{"name", "created_at"}
+
+	// searchSorts are the fields search results are sorted by, the first by
+	// default
+	searchSorts = []string{"rank", "date", "amount"}
+)
+
+var errCursor = fmt.Errorf("invalid cursor")
+
+// Page is one page of a list. NextCursor continues the list after the last
+// item and PrevCursor before the first; each is left out when there is
+// nothing more that way.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// listCursor is where a page starts: the sort value and ID of the item it
+// follows, or precedes when Before is set. Clients only see it encoded.
+type listCursor struct {
+	Sort   string        `json:"s"`
+	Desc   bool          `json:"d,omitempty"`
+	Before bool          `json:"b,omitempty"`
+	Amount *money.Amount `json:"a,omitempty"`
+	Rank   *float32      `json:"r,omitempty"`
+	Time   *time.Time    `json:"t,omitempty"`
+	Name   string        `json:"n,omitempty"`
+	ID     uuid.UUID     `json:"i"`
+}
+
+func (c listCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (listCursor, error) {
+	var c listCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, errCursor
+	}
+	switch {
+	case c.Sort == "amount" && c.Amount == nil,
+		c.Sort == "rank" && c.Rank == nil,
+		(c.Sort == "date" || c.Sort == "created_at") && c.Time == nil,
+		c.ID == uuid.Nil:
+		return c, errCursor
+	}
+	return c, nil
+}
+
+// listQuery is how a request pages, sorts and filters a list
+type listQuery struct {
+	limit      int32
+	sort       string
+	descending bool
+	cursor     *listCursor
+
+	minAmount  *money.Amount
+	maxAmount  *money.Amount
+	currency   string
+	categoryID *uuid.UUID
+	tagID      *uuid.UUID
+	source     string
+	search     string
+	startDate  *time.Time
+	endDate    *time.Time
+}
+
+// parseListQuery reads the paging, sorting and filtering parameters of a list
+// request. A list is sorted by one of sorts (the first unless sort says
+// otherwise) in order, asc or desc (defaultOrder unless order says
+// otherwise), and limit items at a time. A cursor from a previous page
+// carries its sort and order, which the request may repeat but not change.
+// Filters the list does not have are ignored.
+func parseListQuery(query url.Values, sorts []string, defaultOrder string) (listQuery, error) {
+	q := listQuery{limit: defaultListLimit}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.ParseInt(limitStr, 10, 32)
+		if err != nil {
+			return q, fmt.Errorf("invalid limit value")
+		}
+		q.limit = int32(parsedLimit)
+	}
+
+	sort := strings.ToLower(query.Get("sort"))
+	order := strings.ToLower(query.Get("order"))
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		cursor, err := decodeCursor(cursorStr)
+		if err != nil {
+			return q, err
+		}
+		cursorOrder := "asc"
+		if cursor.Desc {
+			cursorOrder = "desc"
+		}
+		if sort != "" && sort != cursor.Sort || order != "" && order != cursorOrder {
+			return q, fmt.Errorf("cursor is for sort=%s&order=%s", cursor.Sort, cursorOrder)
+		}
+		sort, order = cursor.Sort, cursorOrder
+		q.cursor = &cursor
+	}
+	if sort == "" {
+		sort = sorts[0]
+	}
+	if order == "" {
+		order = defaultOrder
+	}
+
+	validator := &validation.ListQueryValidation{
+		Limit:     q.limit,
+		Sort:      sort,
+		Sorts:     sorts,
+		Order:     order,
+		MinAmount: query.Get("min_amount"),
+		MaxAmount: query.Get("max_amount"),
+		Currency:  strings.ToUpper(query.Get("currency")),
+		StartDate: query.Get("start_date"),
+		EndDate:   query.Get("end_date"),
+		Search:    strings.TrimSpace(query.Get("q")),
+	}
+	if err := validator.Validate(); err != nil {
+		return q, err
+	}
+	q.sort = sort
+	q.descending = order == "desc"
+	q.minAmount, q.maxAmount = validator.ParsedMinAmount, validator.ParsedMaxAmount
+	q.currency = validator.Currency
+	q.startDate, q.endDate = validator.ParsedStartDate, validator.ParsedEndDate
+	q.search = validator.Search
+	q.source = strings.TrimSpace(query.Get("source"))
+
+	if categoryID := query.Get("category_id"); categoryID != "" {
+		cid, err := validation.ValidateUUID(categoryID)
+		if err != nil {
+			return q, fmt.Errorf("invalid category_id")
+		}
+		q.categoryID = &cid
+	}
+	if tagID := query.Get("tag_id"); tagID != "" {
+		tid, err := validation.ValidateUUID(tagID)
+		if err != nil {
+			return q, fmt.Errorf("invalid tag_id")
+		}
+		q.tagID = &tid
+	}
+	return q, nil
+}
+
+// pageSize is how many items to fetch: one more than the page holds, so that
+// an extra item shows there are more to come
+func (q listQuery) pageSize() int32 {
+	return q.limit + 1
+}
+
+// fetchDescending is the order to fetch a page in. A page before a cursor is
+// fetched backwards from it and then turned around.
+func (q listQuery) fetchDescending() bool {
+	return q.descending != (q.cursor != nil && q.cursor.Before)
+}
+
+func (q listQuery) afterID() *uuid.UUID {
+	if q.cursor == nil {
+		return nil
+	}
+	return &q.cursor.ID
+}
+
+func (q listQuery) afterAmount() *money.Amount {
+	if q.cursor == nil {
+		return nil
+	}
+	return q.cursor.Amount
+}
+
+func (q listQuery) afterRank() *float32 {
+	if q.cursor == nil {
+		return nil
+	}
+	return q.cursor.Rank
+}
+
+func (q listQuery) afterTime() *time.Time {
+	if q.cursor == nil {
+		return nil
+	}
+	return q.cursor.Time
+}
+
+func (q listQuery) afterName() string {
+	if q.cursor == nil {
+		return ""
+	}
+	return q.cursor.Name
+}
+
+// cursorAt is the cursor of an item, holding the value it is sorted by
+func (q listQuery) cursorAt(amount money.Amount, createdAt, date time.Time, name string, id uuid.UUID) listCursor {
+	c := listCursor{Sort: q.sort, Desc: q.descending, ID: id}
+	switch q.sort {
+	case "amount":
+		c.Amount = &amount
+	case "created_at":
+		c.Time = &createdAt
+	case "name":
+		c.Name = name
+	default:
+		c.Time = &date
+	}
+	return c
+}
+
+// paginate makes a page of the items fetched for it, with cursors to the
+// pages either side
+func paginate[T any](q listQuery, items []T, cursorAt func(T) listCursor) Page[T] {
+	more := len(items) > int(q.limit)
+	if more {
+		items = items[:q.limit]
+	}
+	before := q.cursor != nil && q.cursor.Before
+	if before {
+		slices.Reverse(items)
+	}
+
+	page := Page[T]{Items: items}
+	if len(items) == 0 {
+		page.Items = []T{}
+		return page
+	}
+	// A page before a cursor has the cursor's item after it, and a page
+	// after one has it before
+	if more || before {
+		page.NextCursor = cursorAt(items[len(items)-1]).encode()
+	}
+	if more && before || q.cursor != nil && !before {
+		prev := cursorAt(items[0])
+		prev.Before = true
+		page.PrevCursor = prev.encode()
+	}
+	return page
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+// listExpensePage gets one page of GET /expenses
+func (s *expenseHandlerTestSuite) listExpensePage(t *testing.T, query url.Values) (Page[repository.Expense], int) {
+	req := withUser(httptest.NewRequest(http.MethodGet, "/expenses?"+query.Encode(), nil), s.testUserID)
+	w := httptest.NewRecorder()
+	s.handler.ListExpenses(w, req)
+
+	var page Page[repository.Expense]
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return page, w.Code
+}
+
+func expenseIDs(expenses []repository.Expense) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(expenses))
+	for _, expense := range expenses {
+		ids = append(ids, expense.ID)
+	}
+	return ids
+}
+
+func TestListExpensesPages(t *testing.T) {
+	suite := setupExpenseHandlerTest(t)
+	day := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 9; i++ {
+		suite.mockRepo.GetExpenseMock().AddExpense(repository.Expense{
+			ID:          uuid.New(),
+			UserID:      suite.testUserID,
+			Amount:      money.FromInt(int64(10 + i%4)), // Ties on amount
+			Currency:    "USD",
+			CategoryID:  suite.testExpense.CategoryID,
+			Date:        day.AddDate(0, 0, i/2), // Ties on date
+			Description: "Paged expense",
+			CreatedAt:   day,
+		})
+	}
+	all, _ := suite.mockRepo.ListExpenses(context.Background(), suite.testUserID)
+
+	tests := []struct {
+		sort  string
+		order string
+		cmp   func(a, b repository.Expense) int
+	}{
+		{"", "", func(a, b repository.Expense) int { return -a.Date.Compare(b.Date) }},
+		{"amount", "asc", func(a, b repository.Expense) int { return a.Amount.Cmp(b.Amount) }},
+		{"created_at", "desc", func(a, b repository.Expense) int { return -a.CreatedAt.Compare(b.CreatedAt) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort+" "+tt.order, func(t *testing.T) {
+			want := append([]repository.Expense(nil), all...)
+			sort.Slice(want, func(i, j int) bool {
+				if c := tt.cmp(want[i], want[j]); c != 0 {
+					return c < 0
+				}
+				if tt.order == "asc" {
+					return want[i].ID.String() < want[j].ID.String()
+				}
+				return want[i].ID.String() > want[j].ID.String()
+			})
+
+			query := url.Values{"limit": {"3"}}
+			if tt.sort != "" {
+				query.Set("sort", tt.sort)
+				query.Set("order", tt.order)
+			}
+
+			// Forward through every page
+			var pages []Page[repository.Expense]
+			var got []uuid.UUID
+			for {
+				page, status := suite.listExpensePage(t, query)
+				if !assert.Equal(t, http.StatusOK, status) {
+					return
+				}
+				pages = append(pages, page)
+				got = append(got, expenseIDs(page.Items)...)
+				if page.NextCursor == "" {
+					break
+				}
+				query = url.Values{"limit": {"3"}, "cursor": {page.NextCursor}}
+			}
+			assert.Equal(t, expenseIDs(want), got)
+			assert.Len(t, pages, 4)
+			assert.Empty(t, pages[0].PrevCursor)
+
+			// And back again
+			for i := len(pages) - 1; i > 0; i-- {
+				page, status := suite.listExpensePage(t, url.Values{"limit": {"3"}, "cursor": {pages[i].PrevCursor}})
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, expenseIDs(pages[i-1].Items), expenseIDs(page.Items), "page %d", i-1)
+				assert.Equal(t, i > 1, page.PrevCursor != "", "page %d", i-1)
+				assert.NotEmpty(t, page.NextCursor)
+			}
+		})
+	}
+}
+
+func TestListExpensesFilters(t *testing.T) {
+	suite := setupExpenseHandlerTest(t)
+	travel := uuid.New()
+	trip := uuid.New()
+	for _, expense := range []repository.Expense{
+		{Amount: money.MustParse("4.50"), Currency: "CAD", CategoryID: travel, Date: time.Date(2024, time.January, 5, 0, 0, 0, 0, time.UTC), Description: "Bus ticket"},
+		{Amount: money.MustParse("320"), Currency: "EUR", CategoryID: travel, Date: time.Date(2024, time.February, 10, 0, 0, 0, 0, time.UTC), Description: "Train to Lyon"},
+		{Amount: money.MustParse("12"), Currency: "CAD", CategoryID: suite.testExpense.CategoryID, Date: time.Date(2024, time.February, 11, 0, 0, 0, 0, time.UTC), Description: "Lunch on the TRAIN"},
+	} {
+		expense.ID = uuid.New()
+		expense.UserID = suite.testUserID
+		suite.mockRepo.GetExpenseMock().AddExpense(expense)
+		if expense.CategoryID == travel {
+			suite.mockRepo.GetTagMock().TagExpense(expense.ID, trip)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		query      url.Values
+		wantStatus int
+		wantCount  int
+	}{
+		{"No filters", url.Values{}, http.StatusOK, 4},
+		{"Amount range", url.Values{"min_amount": {"4.50"}, "max_amount": {"100.50"}}, http.StatusOK, 3},
+		{"Currency", url.Values{"currency": {"cad"}}, http.StatusOK, 2},
+		{"Category", url.Values{"category_id": {travel.String()}}, http.StatusOK, 2},
+		{"Tag", url.Values{"tag_id": {trip.String()}}, http.StatusOK, 2},
+		{"Description", url.Values{"q": {"train"}}, http.StatusOK, 2},
+		{"Date range", url.Values{"start_date": {"2024-02-01T00:00:00Z"}, "end_date": {"2024-02-10T23:59:59Z"}}, http.StatusOK, 1},
+		{"Combined", url.Values{"q": {"train"}, "currency": {"EUR"}}, http.StatusOK, 1},
+		{"Amounts reversed", url.Values{"min_amount": {"10"}, "max_amount": {"1"}}, http.StatusBadRequest, 0},
+		{"Invalid category", url.Values{"category_id": {"groceries"}}, http.StatusBadRequest, 0},
+		{"Invalid tag", url.Values{"tag_id": {"vacation"}}, http.StatusBadRequest, 0},
+		{"Invalid date", url.Values{"start_date": {"2024-02-01"}}, http.StatusBadRequest, 0},
+		{"Unknown sort", url.Values{"sort": {"description"}}, http.StatusBadRequest, 0},
+		{"Limit too high", url.Values{"limit": {"5000"}}, http.StatusBadRequest, 0},
+		{"Invalid cursor", url.Values{"cursor": {"not-a-cursor"}}, http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, status := suite.listExpensePage(t, tt.query)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Len(t, page.Items, tt.wantCount)
+		})
+	}
+}
+
+func TestListExpensesCursorKeepsSort(t *testing.T) {
+	suite := setupExpenseHandlerTest(t)
+	for i := 0; i < 2; i++ {
+		expense := suite.testExpense
+		expense.ID = uuid.New()
+		suite.mockRepo.GetExpenseMock().AddExpense(expense)
+	}
+
+	page, status := suite.listExpensePage(t, url.Values{"limit": {"1"}, "sort": {"amount"}})
+	if !assert.Equal(t, http.StatusOK, status) || !assert.NotEmpty(t, page.NextCursor) {
+		return
+	}
+
+	_, status = suite.listExpensePage(t, url.Values{"cursor": {page.NextCursor}, "sort": {"amount"}, "order": {"desc"}})
+	assert.Equal(t, http.StatusOK, status, "repeating the cursor's sort")
+	_, status = suite.listExpensePage(t, url.Values{"cursor": {page.NextCursor}, "sort": {"date"}})
+	assert.Equal(t, http.StatusBadRequest, status, "changing the cursor's sort")
+	_, status = suite.listExpensePage(t, url.Values{"cursor": {page.NextCursor}, "order": {"asc"}})
+	assert.Equal(t, http.StatusBadRequest, status, "changing the cursor's order")
+}
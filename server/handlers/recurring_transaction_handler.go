@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/recurring"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/jorge-dev/centsible/internal/validation"
+	"github.com/jorge-dev/centsible/server/middleware"
+)
+
+type RecurringTransactionHandler struct {
+	db repository.Repository
+}
+
+// RecurringTransactionRequest describes income or an expense that repeats
+// every interval_count days, weeks, months or years from start_date. It ends
+// on end_date, after occurrence_count occurrences, or never.
+type RecurringTransactionRequest struct {
+	Type            string       `json:"type"` // "expense" or "income"
+	Amount          money.Amount `json:"amount"`
+	Currency        string       `json:"currency"`
+	CategoryID      uuid.UUID    `json:"category_id"` // Expenses only
+	Source          string       `json:"source"`      // Income only
+	Description     string       `json:"description"`
+	Frequency       string       `json:"frequency"`
+	IntervalCount   *int32       `json:"interval_count"`
+	StartDate       string       `json:"start_date"`
+	EndDate         string       `json:"end_date"`
+	OccurrenceCount *int32       `json:"occurrence_count"`
+}
+
+func NewRecurringTransactionHandler(db repository.Repository) *RecurringTransactionHandler {
+	return &RecurringTransactionHandler{db: db}
+}
+
+// templateFields returns the category and source columns of a template, only
+// one of which is set depending on its type
+func templateFields(template validation.RecurringTemplate) (*uuid.UUID, *string) {
+	if template.Type == "expense" {
+		return &template.CategoryID, nil
+	}
+	return nil, &template.Source
+}
+
+func toRecurringTemplate(row repository.RecurringTransaction) validation.RecurringTemplate {
+	template := validation.RecurringTemplate{
+		Type:            row.Type,
+		Amount:          row.Amount,
+		Currency:        row.Currency,
+		Description:     row.Description,
+		Frequency:       row.Frequency,
+		IntervalCount:   row.IntervalCount,
+		StartDate:       row.StartDate,
+		EndDate:         row.EndDate,
+		OccurrenceCount: row.OccurrenceCount,
+	}
+	if row.CategoryID != nil {
+		template.CategoryID = *row.CategoryID
+	}
+	if row.Source != nil {
+		template.Source = *row.Source
+	}
+	return template
+}
+
+// CreateRecurringTransaction handles POST /recurring-transactions. Occurrences
+// from the start date on are generated in the background, including any that
+// are already in the past.
+func (h *RecurringTransactionHandler) CreateRecurringTransaction(w http.ResponseWriter, r *http.Request) {
+	var req RecurringTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	validator := &validation.RecurringTransactionValidation{
+		Type:            req.Type,
+		Amount:          req.Amount,
+		Currency:        req.Currency,
+		CategoryID:      req.CategoryID,
+		Source:          req.Source,
+		Description:     req.Description,
+		Frequency:       req.Frequency,
+		IntervalCount:   req.IntervalCount,
+		StartDate:       req.StartDate,
+		EndDate:         req.EndDate,
+		OccurrenceCount: req.OccurrenceCount,
+	}
+	if err := validator.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	template := validator.Template
+	categoryID, source := templateFields(template)
+	created, err := h.db.CreateRecurringTransaction(r.Context(), repository.CreateRecurringTransactionParams{
+		ID:              uuid.New(),
+		UserID:          uid,
+		Type:            template.Type,
+		Amount:          template.Amount,
+		Currency:        template.Currency,
+		CategoryID:      categoryID,
+		Source:          source,
+		Description:     template.Description,
+		Frequency:       template.Frequency,
+		IntervalCount:   template.IntervalCount,
+		StartDate:       template.StartDate,
+		EndDate:         template.EndDate,
+		OccurrenceCount: template.OccurrenceCount,
+		NextOccurrence:  recurring.NextOccurrence(template.Rule(), nil),
+	})
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error creating recurring transaction", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// ListRecurringTransactions handles GET /recurring-transactions
+func (h *RecurringTransactionHandler) ListRecurringTransactions(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	templates, err := h.db.ListRecurringTransactions(r.Context(), uid)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error fetching recurring transactions", http.StatusInternalServerError)
+		return
+	}
+	if templates == nil {
+		templates = []repository.RecurringTransaction{}
+	}
+
+	writeJSON(w, http.StatusOK, templates)
+}
+
+// GetRecurringTransaction handles GET /recurring-transactions/{id}
+func (h *RecurringTransactionHandler) GetRecurringTransaction(w http.ResponseWriter, r *http.Request) {
+	id, err := validation.ValidateUUID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid recurring transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.db.GetRecurringTransactionByID(r.Context(), repository.GetRecurringTransactionByIDParams{
+		ID:     id,
+		UserID: uid,
+	})
+	if err != nil {
+		http.Error(w, "Recurring transaction not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, template)
+}
+
+// UpdateRecurringTransaction handles PUT /recurring-transactions/{id}. Only the
+// fields given change. Occurrences already generated are kept, and a changed
+// schedule picks up with its first occurrence after the last one generated.
+func (h *RecurringTransactionHandler) UpdateRecurringTransaction(w http.ResponseWriter, r *http.Request) {
+	var req RecurringTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	id, err := validation.ValidateUUID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid recurring transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.db.GetRecurringTransactionByID(r.Context(), repository.GetRecurringTransactionByIDParams{
+		ID:     id,
+		UserID: uid,
+	})
+	if err != nil {
+		http.Error(w, "Recurring transaction not found", http.StatusNotFound)
+		return
+	}
+
+	validator := &validation.RecurringTransactionValidation{
+		Type:            req.Type,
+		Amount:          req.Amount,
+		Currency:        req.Currency,
+		CategoryID:      req.CategoryID,
+		Source:          req.Source,
+		Description:     req.Description,
+		Frequency:       req.Frequency,
+		IntervalCount:   req.IntervalCount,
+		StartDate:       req.StartDate,
+		EndDate:         req.EndDate,
+		OccurrenceCount: req.OccurrenceCount,
+		IsPartialUpdate: true,
+	}
+	validated, err := validator.ValidatePartialUpdate(toRecurringTemplate(current))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	categoryID, source := templateFields(validated)
+	updated, err := h.db.UpdateRecurringTransaction(r.Context(), repository.UpdateRecurringTransactionParams{
+		ID:              id,
+		Amount:          validated.Amount,
+		Currency:        validated.Currency,
+		CategoryID:      categoryID,
+		Source:          source,
+		Description:     validated.Description,
+		Frequency:       validated.Frequency,
+		IntervalCount:   validated.IntervalCount,
+		StartDate:       validated.StartDate,
+		EndDate:         validated.EndDate,
+		OccurrenceCount: validated.OccurrenceCount,
+		NextOccurrence:  recurring.NextOccurrence(validated.Rule(), current.LastOccurrence),
+		UserID:          uid,
+	})
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error updating recurring transaction", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// DeleteRecurringTransaction handles DELETE /recurring-transactions/{id}. The
+// income and expenses it already generated are kept.
+func (h *RecurringTransactionHandler) DeleteRecurringTransaction(w http.ResponseWriter, r *http.Request) {
+	id, err := validation.ValidateUUID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid recurring transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.db.DeleteRecurringTransaction(r.Context(), repository.DeleteRecurringTransactionParams{
+		ID:     id,
+		UserID: uid,
+	})
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Error deleting recurring transaction", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		http.Error(w, "Recurring transaction not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
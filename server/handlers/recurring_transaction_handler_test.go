@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/recurrence"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/jorge-dev/centsible/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+type recurringTransactionHandlerTestSuite struct {
+	mockRepo   *mocks.MockRepository
+	handler    *RecurringTransactionHandler
+	userID     uuid.UUID
+	categoryID uuid.UUID
+	rent       repository.RecurringTransaction
+}
+
+func (s *recurringTransactionHandlerTestSuite) cleanup() {
+	s.mockRepo.Reset()
+}
+
+func setupRecurringTransactionHandlerTest(t *testing.T) *recurringTransactionHandlerTestSuite {
+	suite := &recurringTransactionHandlerTestSuite{}
+	t.Cleanup(suite.cleanup)
+
+	repo := mocks.NewMockRepository()
+	mock, ok := repo.(*mocks.MockRepository)
+	if !ok {
+		t.Fatal("could not cast to MockRepository")
+	}
+	suite.mockRepo = mock
+	suite.handler = NewRecurringTransactionHandler(repo)
+
+	suite.userID = uuid.New()
+	suite.categoryID = uuid.New()
+
+	// Rent from the start of 2024, generated up to March
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	next := time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)
+	suite.rent = repository.RecurringTransaction{
+		ID:             uuid.New(),
+		UserID:         suite.userID,
+		Type:           "expense",
+		Amount:         money.MustParse("1200.00"),
+		Currency:       "USD",
+		CategoryID:     &suite.categoryID,
+		Description:    "Rent",
+		Frequency:      recurrence.FreqMonthly,
+		IntervalCount:  1,
+		StartDate:      start,
+		LastOccurrence: &last,
+		NextOccurrence: &next,
+	}
+	suite.mockRepo.GetRecurringTransactionMock().AddRecurringTransaction(suite.rent)
+
+	return suite
+}
+
+func withRecurringTransactionID(req *http.Request, id string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestCreateRecurringTransaction(t *testing.T) {
+	suite := setupRecurringTransactionHandlerTest(t)
+
+	tests := []struct {
+		name           string
+		body           string
+		wantStatus     int
+		wantNext       string
+		wantCategory   bool
+		wantSourceText string
+	}{
+		{
+			name:         "Monthly expense",
+			body:         `{"type": "expense", "amount": "15.99", "currency": "USD", "category_id": "` + suite.categoryID.String() + `", "description": "Streaming", "frequency": "monthly", "start_date": "2024-01-15T00:00:00Z"}`,
+			wantStatus:   http.StatusCreated,
+			wantNext:     "2024-01-15T00:00:00Z",
+			wantCategory: true,
+		},
+		{
+			name:           "Biweekly income with a count, category ignored",
+			body:           `{"type": "income", "amount": "2500", "currency": "USD", "category_id": "` + suite.categoryID.String() + `", "source": "Salary", "frequency": "weekly", "interval_count": 2, "start_date": "2024-01-05T00:00:00Z", "occurrence_count": 26}`,
+			wantStatus:     http.StatusCreated,
+			wantNext:       "2024-01-05T00:00:00Z",
+			wantSourceText: "Salary",
+		},
+		{
+			name:       "Both end conditions",
+			body:       `{"type": "expense", "amount": "15.99", "currency": "USD", "category_id": "` + suite.categoryID.String() + `", "description": "Streaming", "frequency": "monthly", "start_date": "2024-01-15T00:00:00Z", "end_date": "2024-12-31T00:00:00Z", "occurrence_count": 12}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Unknown frequency",
+			body:       `{"type": "expense", "amount": "15.99", "currency": "USD", "category_id": "` + suite.categoryID.String() + `", "description": "Streaming", "frequency": "fortnightly", "start_date": "2024-01-15T00:00:00Z"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Invalid body",
+			body:       `{"type": `,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := withUser(httptest.NewRequest(http.MethodPost, "/recurring-transactions", strings.NewReader(tt.body)), suite.userID)
+			w := httptest.NewRecorder()
+			suite.handler.CreateRecurringTransaction(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+
+			var created repository.RecurringTransaction
+			if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, suite.userID, created.UserID)
+			assert.Equal(t, tt.wantNext, created.NextOccurrence.Format(time.RFC3339))
+			assert.Equal(t, tt.wantCategory, created.CategoryID != nil)
+			if tt.wantSourceText != "" {
+				assert.Equal(t, tt.wantSourceText, *created.Source)
+			} else {
+				assert.Nil(t, created.Source)
+			}
+		})
+	}
+}
+
+func TestUpdateRecurringTransaction(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		body       string
+		wantStatus int
+		wantNext   string // Empty once the schedule has ended
+	}{
+		{
+			name:       "Amount only",
+			body:       `{"amount": "1250.00"}`,
+			wantStatus: http.StatusOK,
+			wantNext:   "2024-04-01T00:00:00Z",
+		},
+		{
+			name:       "New schedule picks up after the last occurrence generated",
+			body:       `{"frequency": "weekly", "start_date": "2024-01-05T00:00:00Z"}`,
+			wantStatus: http.StatusOK,
+			wantNext:   "2024-03-08T00:00:00Z",
+		},
+		{
+			name:       "Ended by the new count",
+			body:       `{"occurrence_count": 3}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Type change",
+			body:       `{"type": "income", "source": "Tenant"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Unknown template",
+			id:         uuid.New().String(),
+			body:       `{"amount": "1250.00"}`,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite := setupRecurringTransactionHandlerTest(t)
+			id := tt.id
+			if id == "" {
+				id = suite.rent.ID.String()
+			}
+
+			req := httptest.NewRequest(http.MethodPut, "/recurring-transactions/"+id, strings.NewReader(tt.body))
+			req = withUser(withRecurringTransactionID(req, id), suite.userID)
+			w := httptest.NewRecorder()
+			suite.handler.UpdateRecurringTransaction(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var updated repository.RecurringTransaction
+			if err := json.NewDecoder(w.Body).Decode(&updated); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantNext == "" {
+				assert.Nil(t, updated.NextOccurrence)
+			} else {
+				assert.Equal(t, tt.wantNext, updated.NextOccurrence.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestDeleteRecurringTransaction(t *testing.T) {
+	suite := setupRecurringTransactionHandlerTest(t)
+
+	tests := []struct {
+		name       string
+		id         string
+		userID     uuid.UUID
+		wantStatus int
+	}{
+		{"Another user's template", suite.rent.ID.String(), uuid.New(), http.StatusNotFound},
+		{"Own template", suite.rent.ID.String(), suite.userID, http.StatusNoContent},
+		{"Already deleted", suite.rent.ID.String(), suite.userID, http.StatusNotFound},
+		{"Invalid ID", "rent", suite.userID, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/recurring-transactions/"+tt.id, nil)
+			req = withUser(withRecurringTransactionID(req, tt.id), tt.userID)
+			w := httptest.NewRecorder()
+			suite.handler.DeleteRecurringTransaction(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+
+	req := withUser(httptest.NewRequest(http.MethodGet, "/recurring-transactions", nil), suite.userID)
+	w := httptest.NewRecorder()
+	suite.handler.ListRecurringTransactions(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, "[]", w.Body.String())
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/jorge-dev/centsible/internal/validation"
+	"github.com/jorge-dev/centsible/server/middleware"
+)
+
+type SearchHandler struct {
+	db repository.Repository
+}
+
+func NewSearchHandler(db repository.Repository) *SearchHandler {
+	return &SearchHandler{db: db}
+}
+
+// Search handles GET /search. It finds the expenses and income whose
+// descriptions, or sources for income, match q, best matches first unless
+// sorted by date or amount. Results are paged and filtered like GET /expenses,
+// and type=expense or type=income keeps only one kind.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	q, err := parseListQuery(r.URL.Query(), searchSorts, "desc")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.search == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	kind := r.URL.Query().Get("type")
+	if kind != "" && kind != "expense" && kind != "income" {
+		http.Error(w, "type must be either 'expense' or 'income'", http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.db.SearchTransactions(r.Context(), repository.SearchTransactionsParams{
+		Query:       q.search,
+		UserID:      uid,
+		Kind:        kind,
+		CategoryID:  q.categoryID,
+		Source:      q.source,
+		MinAmount:   q.minAmount,
+		MaxAmount:   q.maxAmount,
+		Currency:    q.currency,
+		StartDate:   q.startDate,
+		EndDate:     q.endDate,
+		AfterID:     q.afterID(),
+		Sort:        q.sort,
+		Descending:  q.fetchDescending(),
+		AfterAmount: q.afterAmount(),
+		AfterTime:   q.afterTime(),
+		AfterRank:   q.afterRank(),
+		PageSize:    q.pageSize(),
+	})
+	if err != nil {
+		log.Printf("Error searching transactions: %v", err)
+		http.Error(w, "Error searching transactions", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, paginate(q, results, func(result repository.SearchTransactionsRow) listCursor {
+		if q.sort == "rank" {
+			return listCursor{Sort: q.sort, Desc: q.descending, Rank: &result.Rank, ID: result.ID}
+		}
+		return q.cursorAt(result.Amount, result.Date, result.Date, "", result.ID)
+	}))
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/jorge-dev/centsible/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+type searchHandlerTestSuite struct {
+	mockRepo *mocks.MockRepository
+	handler  *SearchHandler
+	userID   uuid.UUID
+	health   uuid.UUID
+}
+
+func (s *searchHandlerTestSuite) cleanup() {
+	s.mockRepo.Reset()
+}
+
+func setupSearchHandlerTest(t *testing.T) *searchHandlerTestSuite {
+	suite := &searchHandlerTestSuite{}
+	t.Cleanup(suite.cleanup)
+
+	repo := mocks.NewMockRepository()
+	mock, ok := repo.(*mocks.MockRepository)
+	if !ok {
+		t.Fatal("could not cast to MockRepository")
+	}
+	suite.mockRepo = mock
+	suite.handler = NewSearchHandler(repo)
+	suite.userID = uuid.New()
+	suite.health = uuid.New()
+
+	spring := time.Date(2024, time.April, 12, 0, 0, 0, 0, time.UTC)
+	for _, expense := range []repository.Expense{
+		{Amount: money.MustParse("180"), Currency: "CAD", CategoryID: suite.health, Date: spring, Description: "Dentist cleaning and dentist x-rays"},
+		{Amount: money.MustParse("95"), Currency: "CAD", CategoryID: suite.health, Date: spring.AddDate(0, 5, 0), Description: "Dentist follow-up"},
+		{Amount: money.MustParse("12"), Currency: "CAD", CategoryID: uuid.New(), Date: spring, Description: "Toothpaste"},
+		{Amount: money.MustParse("60"), Currency: "CAD", CategoryID: suite.health, Date: spring, Description: "Dentist", UserID: uuid.New()},
+	} {
+		expense.ID = uuid.New()
+		if expense.UserID == uuid.Nil {
+			expense.UserID = suite.userID
+		}
+		suite.mockRepo.GetExpenseMock().AddExpense(expense)
+	}
+	suite.mockRepo.GetIncomeMock().AddIncome(repository.Income{
+		ID:          uuid.New(),
+		UserID:      suite.userID,
+		Amount:      money.MustParse("40"),
+		Currency:    "CAD",
+		Source:      "Insurance",
+		Date:        spring.AddDate(0, 0, 7),
+		Description: "Dentist claim refund",
+	})
+	return suite
+}
+
+func (s *searchHandlerTestSuite) search(t *testing.T, query url.Values) (Page[repository.SearchTransactionsRow], int) {
+	req := withUser(httptest.NewRequest(http.MethodGet, "/search?"+query.Encode(), nil), s.userID)
+	w := httptest.NewRecorder()
+	s.handler.Search(w, req)
+
+	var page Page[repository.SearchTransactionsRow]
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return page, w.Code
+}
+
+func TestSearch(t *testing.T) {
+	suite := setupSearchHandlerTest(t)
+
+	tests := []struct {
+		name       string
+		query      url.Values
+		wantStatus int
+		wantKinds  []string
+	}{
+		{"Expenses and income", url.Values{"q": {"dentist"}}, http.StatusOK, []string{"expense", "expense", "income"}},
+		{"Income source", url.Values{"q": {"insurance"}}, http.StatusOK, []string{"income"}},
+		{"Excluded word", url.Values{"q": {"dentist -refund"}}, http.StatusOK, []string{"expense", "expense"}},
+		{"Expenses only", url.Values{"q": {"dentist"}, "type": {"expense"}}, http.StatusOK, []string{"expense", "expense"}},
+		{"Category", url.Values{"q": {"dentist"}, "category_id": {suite.health.String()}}, http.StatusOK, []string{"expense", "expense"}},
+		{"Source", url.Values{"q": {"dentist"}, "source": {"insurance"}}, http.StatusOK, []string{"income"}},
+		{"Spring only", url.Values{"q": {"dentist"}, "start_date": {"2024-03-20T00:00:00Z"}, "end_date": {"2024-06-20T00:00:00Z"}}, http.StatusOK, []string{"expense", "income"}},
+		{"Amount range", url.Values{"q": {"dentist"}, "min_amount": {"50"}, "max_amount": {"100"}}, http.StatusOK, []string{"expense"}},
+		{"No match", url.Values{"q": {"plumber"}}, http.StatusOK, []string{}},
+		{"Missing query", url.Values{}, http.StatusBadRequest, nil},
+		{"Unknown type", url.Values{"q": {"dentist"}, "type": {"transfer"}}, http.StatusBadRequest, nil},
+		{"Unknown sort", url.Values{"q": {"dentist"}, "sort": {"name"}}, http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, status := suite.search(t, tt.query)
+			assert.Equal(t, tt.wantStatus, status)
+			if tt.wantKinds == nil {
+				return
+			}
+			kinds := []string{}
+			for _, result := range page.Items {
+				kinds = append(kinds, result.Kind)
+			}
+			assert.ElementsMatch(t, tt.wantKinds, kinds)
+		})
+	}
+}
+
+func TestSearchRanksAndHighlights(t *testing.T) {
+	suite := setupSearchHandlerTest(t)
+
+	page, status := suite.search(t, url.Values{"q": {"dentist"}})
+	if !assert.Equal(t, http.StatusOK, status) || !assert.Len(t, page.Items, 3) {
+		return
+	}
+	best := page.Items[0]
+	assert.Equal(t, "Dentist cleaning and dentist x-rays", best.Description)
+	assert.Equal(t, "<mark>Dentist</mark> cleaning and <mark>dentist</mark> x-rays", best.Snippet)
+	assert.Equal(t, &suite.health, best.CategoryID)
+	for i := 1; i < len(page.Items); i++ {
+		assert.GreaterOrEqual(t, page.Items[i-1].Rank, page.Items[i].Rank)
+	}
+}
+
+func TestSearchPages(t *testing.T) {
+	suite := setupSearchHandlerTest(t)
+
+	for _, sort := range []string{"rank", "date", "amount"} {
+		t.Run(sort, func(t *testing.T) {
+			all, _ := suite.search(t, url.Values{"q": {"dentist"}, "sort": {sort}})
+
+			var got []uuid.UUID
+			query := url.Values{"q": {"dentist"}, "sort": {sort}, "limit": {"1"}}
+			for {
+				page, status := suite.search(t, query)
+				if !assert.Equal(t, http.StatusOK, status) {
+					return
+				}
+				for _, result := range page.Items {
+					got = append(got, result.ID)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				query = url.Values{"q": {"dentist"}, "limit": {"1"}, "cursor": {page.NextCursor}}
+			}
+
+			var want []uuid.UUID
+			for _, result := range all.Items {
+				want = append(want, result.ID)
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 
@@ -22,33 +23,27 @@ func NewSeedHandler(queries *repository.Queries) *SeedHandler {
 func (h *SeedHandler) SeedData(w http.ResponseWriter, r *http.Request) {
 	ctx := context.Background()
 
-	// Execute seed operations in sequence
-	if err := h.queries.SeedUsers(ctx); err != nil {
-		log.Printf("Error seeding users: %v", err)
-		http.Error(w, "Error seeding database", http.StatusInternalServerError)
-		return
-	}
-
-	if err := h.queries.SeedCategories(ctx); err != nil {
-		log.Printf("Error seeding categories: %v", err)
-		http.Error(w, "Error seeding database", http.StatusInternalServerError)
-		return
-	}
-
-	if err := h.queries.SeedIncome(ctx); err != nil {
-		log.Printf("Error seeding income: %v", err)
-		http.Error(w, "Error seeding database", http.StatusInternalServerError)
-		return
-	}
-
-	if err := h.queries.SeedExpenses(ctx); err != nil {
-		log.Printf("Error seeding expenses: %v", err)
-		http.Error(w, "Error seeding database", http.StatusInternalServerError)
-		return
-	}
-
-	if err := h.queries.SeedBudgets(ctx); err != nil {
-		log.Printf("Error seeding budgets: %v", err)
+	// Seed everything or nothing, so a failure leaves no half-seeded data
+	err := h.queries.ExecTx(ctx, func(tx *repository.Queries) error {
+		seeds := []struct {
+			name string
+			seed func(context.Context) error
+		}{
+			{"users", tx.SeedUsers},
+			{"categories", tx.SeedCategories},
+			{"income", tx.SeedIncome},
+			{"expenses", tx.SeedExpenses},
+			{"budgets", tx.SeedBudgets},
+		}
+		for _, s := range seeds {
+			if err := s.seed(ctx); err != nil {
+				return fmt.Errorf("seeding %s: %w", s.name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error seeding database: %v", err)
 		http.Error(w, "Error seeding database", http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jorge-dev/centsible/internal/auth"
+	"github.com/jorge-dev/centsible/server/middleware"
+)
+
+type SessionHandler struct {
+	jwtManager *auth.JWTManager
+}
+
+type SessionResponse struct {
+	ID        string    `json:"id"`
+	UserAgent string    `json:"user_agent"`
+	IPAddress string    `json:"ip_address"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	Current   bool      `json:"current"`
+}
+
+func NewSessionHandler(jm *auth.JWTManager) *SessionHandler {
+	return &SessionHandler{jwtManager: jm}
+}
+
+// ListSessions handles GET /user/sessions
+func (h *SessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	currentSessionID, _ := r.Context().Value(middleware.SessionIDKey).(string)
+
+	sessions, err := h.jwtManager.ListSessions(userID)
+	if err != nil {
+		log.Printf("Error listing sessions: %v", err)
+		http.Error(w, "Error retrieving sessions", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponse{
+			ID:        session.ID,
+			UserAgent: session.UserAgent,
+			IPAddress: session.IPAddress,
+			CreatedAt: session.CreatedAt,
+			LastSeen:  session.LastSeen,
+			Current:   session.ID == currentSessionID,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// RevokeSession handles DELETE /user/sessions/{id}
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	sessionID := chi.URLParam(r, "id")
+
+	if err := h.jwtManager.RevokeSession(userID, sessionID); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error revoking session: %v", err)
+		http.Error(w, "Error revoking session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeAllSessions handles DELETE /user/sessions and signs the user out everywhere,
+// including the session making the request
+func (h *SessionHandler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	revoked, err := h.jwtManager.RevokeAllSessions(userID)
+	if err != nil {
+		log.Printf("Error revoking sessions: %v", err)
+		http.Error(w, "Error revoking sessions", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message": "Successfully signed out of all sessions",
+		"revoked": revoked,
+	})
+}
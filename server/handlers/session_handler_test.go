@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/auth"
+	"github.com/jorge-dev/centsible/server/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+type sessionHandlerTestSuite struct {
+	handler    *SessionHandler
+	jwtManager *auth.JWTManager
+	userID     string
+}
+
+func setupSessionHandlerTest(t *testing.T) *sessionHandlerTestSuite {
+	suite := &sessionHandlerTestSuite{}
+	suite.jwtManager = auth.NewJWTManager("test_secret")
+	suite.handler = NewSessionHandler(suite.jwtManager)
+	suite.userID = uuid.New().String()
+	return suite
+}
+
+// startSession signs the test user in from the given device
+func (s *sessionHandlerTestSuite) startSession(t *testing.T, userID, userAgent string) *auth.TokenPair {
+	tokenPair, err := s.jwtManager.StartSession(userID, "test@example.com", uuid.New().String(), auth.DeviceInfo{
+		UserAgent: userAgent,
+		IPAddress: "10.0.0.1",
+	})
+	if err != nil {
+		t.Fatal("failed to start session")
+	}
+	return tokenPair
+}
+
+func (s *sessionHandlerTestSuite) request(method, target, userID, currentSessionID, sessionIDParam string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	rctx := chi.NewRouteContext()
+	if sessionIDParam != "" {
+		rctx.URLParams.Add("id", sessionIDParam)
+	}
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, middleware.UserIDKey, userID)
+	ctx = context.WithValue(ctx, middleware.SessionIDKey, currentSessionID)
+	return req.WithContext(ctx)
+}
+
+func TestListSessions(t *testing.T) {
+	suite := setupSessionHandlerTest(t)
+
+	laptop := suite.startSession(t, suite.userID, "laptop")
+	suite.startSession(t, suite.userID, "phone")
+	suite.startSession(t, uuid.New().String(), "someone else")
+
+	w := httptest.NewRecorder()
+	suite.handler.ListSessions(w, suite.request(http.MethodGet, "/user/sessions", suite.userID, laptop.SessionID, ""))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response []SessionResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Len(t, response, 2)
+
+	userAgents := map[string]bool{}
+	for _, session := range response {
+		userAgents[session.UserAgent] = true
+		assert.Equal(t, "10.0.0.1", session.IPAddress)
+		assert.False(t, session.CreatedAt.IsZero())
+		assert.False(t, session.LastSeen.IsZero())
+		assert.Equal(t, session.ID == laptop.SessionID, session.Current)
+	}
+	assert.True(t, userAgents["laptop"])
+	assert.True(t, userAgents["phone"])
+
+	t.Run("No sessions", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		suite.handler.ListSessions(w, suite.request(http.MethodGet, "/user/sessions", uuid.New().String(), "", ""))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, "[]", w.Body.String())
+	})
+}
+
+func TestRevokeSession(t *testing.T) {
+	suite := setupSessionHandlerTest(t)
+
+	laptop := suite.startSession(t, suite.userID, "laptop")
+	phone := suite.startSession(t, suite.userID, "phone")
+	other := suite.startSession(t, uuid.New().String(), "someone else")
+
+	tests := []struct {
+		name       string
+		sessionID  string
+		wantStatus int
+	}{
+		{
+			name:       "Revoke own session",
+			sessionID:  phone.SessionID,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "Already revoked session",
+			sessionID:  phone.SessionID,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Another user's session",
+			sessionID:  other.SessionID,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Unknown session",
+			sessionID:  uuid.New().String(),
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := suite.request(http.MethodDelete, "/user/sessions/"+tt.sessionID, suite.userID, laptop.SessionID, tt.sessionID)
+			suite.handler.RevokeSession(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+
+	// The revoked device is signed out, the others are not
+	_, err := suite.jwtManager.ValidateToken(phone.AccessToken)
+	assert.Error(t, err)
+	_, err = suite.jwtManager.RefreshToken(phone.RefreshToken)
+	assert.Error(t, err)
+
+	_, err = suite.jwtManager.ValidateToken(laptop.AccessToken)
+	assert.NoError(t, err)
+	_, err = suite.jwtManager.ValidateToken(other.AccessToken)
+	assert.NoError(t, err)
+}
+
+func TestRevokeAllSessions(t *testing.T) {
+	suite := setupSessionHandlerTest(t)
+
+	laptop := suite.startSession(t, suite.userID, "laptop")
+	phone := suite.startSession(t, suite.userID, "phone")
+	other := suite.startSession(t, uuid.New().String(), "someone else")
+
+	w := httptest.NewRecorder()
+	suite.handler.RevokeAllSessions(w, suite.request(http.MethodDelete, "/user/sessions", suite.userID, laptop.SessionID, ""))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]any
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, float64(2), response["revoked"])
+
+	for _, tokenPair := range []*auth.TokenPair{laptop, phone} {
+		_, err := suite.jwtManager.ValidateToken(tokenPair.AccessToken)
+		assert.Error(t, err)
+		_, err = suite.jwtManager.RefreshToken(tokenPair.RefreshToken)
+		assert.Error(t, err)
+	}
+
+	// Other users stay signed in
+	_, err := suite.jwtManager.ValidateToken(other.AccessToken)
+	assert.NoError(t, err)
+}
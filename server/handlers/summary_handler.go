@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 	"github.com/jorge-dev/centsible/internal/repository"
 	"github.com/jorge-dev/centsible/internal/validation"
 	"github.com/jorge-dev/centsible/server/middleware"
@@ -22,35 +23,57 @@ func NewSummaryHandler(db repository.Repository) *SummaryHandler {
 }
 
 type TopCategory struct {
-	CategoryID   string  `json:"category_id"`
-	CategoryName string  `json:"category_name"`
-	UsageCount   int     `json:"usage_count"`
-	TotalSpent   float64 `json:"total_spent"`
+	CategoryID   string       `json:"category_id"`
+	CategoryName string       `json:"category_name"`
+	UsageCount   int          `json:"usage_count"`
+	TotalSpent   money.Amount `json:"total_spent"`
 }
 
 type MonthlySummaryResponse struct {
 	Currency      string        `json:"currency"`
-	TotalIncome   float64       `json:"total_income"`
-	TotalExpenses float64       `json:"total_expenses"`
-	TotalSavings  float64       `json:"total_savings"`
+	TotalIncome   money.Amount  `json:"total_income"`
+	TotalExpenses money.Amount  `json:"total_expenses"`
+	TotalSavings  money.Amount  `json:"total_savings"`
 	TopCategories []TopCategory `json:"top_categories"`
 }
 
+// MonthlySummary reports totals converted into the user's base currency next to
+// the per-currency breakdown. Currencies listed in MissingRates had no exchange
+// rate and are left out of the converted totals.
+type MonthlySummary struct {
+	BaseCurrency  string                   `json:"base_currency"`
+	TotalIncome   money.Amount             `json:"total_income"`
+	TotalExpenses money.Amount             `json:"total_expenses"`
+	TotalSavings  money.Amount             `json:"total_savings"`
+	MissingRates  []string                 `json:"missing_rates"`
+	Currencies    []MonthlySummaryResponse `json:"currencies"`
+}
+
 type MonthlyTrend struct {
-	Month        time.Time `json:"month"`
-	CategoryName string    `json:"category_name"`
-	Amount       float64   `json:"amount"`
+	Month        time.Time    `json:"month"`
+	CategoryName string       `json:"category_name"`
+	Amount       money.Amount `json:"amount"`
 }
 
 type YearlySummaryResponse struct {
 	Currency      string         `json:"currency"`
-	TotalIncome   float64        `json:"total_income"`
-	TotalExpenses float64        `json:"total_expenses"`
-	TotalSavings  float64        `json:"total_savings"`
+	TotalIncome   money.Amount   `json:"total_income"`
+	TotalExpenses money.Amount   `json:"total_expenses"`
+	TotalSavings  money.Amount   `json:"total_savings"`
 	TopCategories []TopCategory  `json:"top_categories"`
 	MonthlyTrend  []MonthlyTrend `json:"monthly_trend"`
 }
 
+// YearlySummary is the yearly counterpart of MonthlySummary
+type YearlySummary struct {
+	BaseCurrency  string                  `json:"base_currency"`
+	TotalIncome   money.Amount            `json:"total_income"`
+	TotalExpenses money.Amount            `json:"total_expenses"`
+	TotalSavings  money.Amount            `json:"total_savings"`
+	MissingRates  []string                `json:"missing_rates"`
+	Currencies    []YearlySummaryResponse `json:"currencies"`
+}
+
 // GetMonthlySummary handles GET /api/summary/monthly
 func (h *SummaryHandler) GetMonthlySummary(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.UserIDKey).(string)
@@ -88,7 +111,7 @@ func (h *SummaryHandler) GetMonthlySummary(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	if summary == nil {
-		http.Error(w, fmt.Sprintf("No summary found for month %s", date.Format("January 2006")), http.StatusNotFound)
+		http.Error(w, fmt.Sprintf("No summary found for month %s", date.UTC().Format("January 2006")), http.StatusNotFound)
 		return
 	}
 
@@ -109,8 +132,27 @@ func (h *SummaryHandler) GetMonthlySummary(w http.ResponseWriter, r *http.Reques
 		})
 	}
 
+	// The same UTC month the per-currency totals cover
+	start, end := repository.SummaryMonth(date)
+	converted, err := h.db.GetConvertedTotals(r.Context(), repository.GetConvertedTotalsParams{
+		UserID:    uid,
+		StartDate: start,
+		EndDate:   end,
+	})
+	if err != nil {
+		http.Error(w, "Error converting monthly summary", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(responses)
+	json.NewEncoder(w).Encode(MonthlySummary{
+		BaseCurrency:  converted.BaseCurrency,
+		TotalIncome:   converted.TotalIncome,
+		TotalExpenses: converted.TotalExpenses,
+		TotalSavings:  converted.TotalIncome.Sub(converted.TotalExpenses),
+		MissingRates:  converted.MissingRates,
+		Currencies:    responses,
+	})
 }
 
 // GetYearlySummary handles GET /api/summary/yearly
@@ -149,7 +191,7 @@ func (h *SummaryHandler) GetYearlySummary(w http.ResponseWriter, r *http.Request
 		return
 	}
 	if summary == nil {
-		http.Error(w, fmt.Sprintf("No summary found for year %s", date.Format("2006")), http.StatusNotFound)
+		http.Error(w, fmt.Sprintf("No summary found for year %s", date.UTC().Format("2006")), http.StatusNotFound)
 		return
 	}
 
@@ -179,6 +221,103 @@ func (h *SummaryHandler) GetYearlySummary(w http.ResponseWriter, r *http.Request
 		})
 	}
 
+	start, end := repository.SummaryYear(date)
+	converted, err := h.db.GetConvertedTotals(r.Context(), repository.GetConvertedTotalsParams{
+		UserID:    uid,
+		StartDate: start,
+		EndDate:   end,
+	})
+	if err != nil {
+		http.Error(w, "Error converting yearly summary", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(responses)
+	json.NewEncoder(w).Encode(YearlySummary{
+		BaseCurrency:  converted.BaseCurrency,
+		TotalIncome:   converted.TotalIncome,
+		TotalExpenses: converted.TotalExpenses,
+		TotalSavings:  converted.TotalIncome.Sub(converted.TotalExpenses),
+		MissingRates:  converted.MissingRates,
+		Currencies:    responses,
+	})
+}
+
+type TagCurrencyTotals struct {
+	Currency      string       `json:"currency"`
+	ExpenseCount  int64        `json:"expense_count"`
+	TotalExpenses money.Amount `json:"total_expenses"`
+	IncomeCount   int64        `json:"income_count"`
+	TotalIncome   money.Amount `json:"total_income"`
+}
+
+// TagSummary totals the expenses and income with a tag, per currency
+type TagSummary struct {
+	TagID      uuid.UUID           `json:"tag_id"`
+	TagName    string              `json:"tag_name"`
+	Currencies []TagCurrencyTotals `json:"currencies"`
+}
+
+// GetTagSummary handles GET /api/summary/tags, totalling the transactions
+// dated from start_date up to but not including end_date by tag. tag_id
+// limits it to one tag. A transaction with several tags counts towards each.
+func (h *SummaryHandler) GetTagSummary(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	validator := &validation.DateRangeQueryValidation{
+		StartDate: query.Get("start_date"),
+		EndDate:   query.Get("end_date"),
+	}
+	if err := validator.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	start, _ := validation.ValidateDate(validator.StartDate)
+	end, _ := validation.ValidateDate(validator.EndDate)
+
+	var tagID *uuid.UUID
+	if tagIDStr := query.Get("tag_id"); tagIDStr != "" {
+		tid, err := validation.ValidateUUID(tagIDStr)
+		if err != nil {
+			http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+			return
+		}
+		tagID = &tid
+	}
+
+	rows, err := h.db.GetTagTotals(r.Context(), repository.GetTagTotalsParams{
+		UserID:    uid,
+		StartDate: start,
+		EndDate:   end,
+		TagID:     tagID,
+	})
+	if err != nil {
+		log.Printf("Error fetching tag totals: %v", err)
+		http.Error(w, "Error fetching tag summary", http.StatusInternalServerError)
+		return
+	}
+
+	// Rows come ordered by tag, so each tag's currencies are together
+	summaries := []TagSummary{}
+	for _, row := range rows {
+		if n := len(summaries); n == 0 || summaries[n-1].TagID != row.TagID {
+			summaries = append(summaries, TagSummary{TagID: row.TagID, TagName: row.TagName})
+		}
+		summary := &summaries[len(summaries)-1]
+		summary.Currencies = append(summary.Currencies, TagCurrencyTotals{
+			Currency:      row.Currency,
+			ExpenseCount:  row.ExpenseCount,
+			TotalExpenses: row.TotalExpenses,
+			IncomeCount:   row.IncomeCount,
+			TotalIncome:   row.TotalIncome,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, summaries)
 }
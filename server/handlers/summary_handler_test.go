@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
 	"github.com/jorge-dev/centsible/internal/repository"
 	"github.com/jorge-dev/centsible/internal/repository/mocks"
 	"github.com/jorge-dev/centsible/server/middleware"
@@ -50,16 +51,16 @@ func setupSummaryHandlerTest(t *testing.T) *summaryHandlerTestSuite {
 			CategoryID:   uuid.New().String(),
 			CategoryName: "Food",
 			UsageCount:   10,
-			TotalSpent:   500.00,
+			TotalSpent:   money.MustParse("500.00"),
 		},
 	})
 
 	monthlySummary := []repository.GetMonthlySummaryRow{
 		{
 			Currency:      "USD",
-			TotalIncome:   1000.00,
-			TotalExpenses: 500.00,
-			TotalSavings:  500.00,
+			TotalIncome:   money.MustParse("1000.00"),
+			TotalExpenses: money.MustParse("500.00"),
+			TotalSavings:  money.MustParse("500.00"),
 			TopCategories: topCategories,
 		},
 	}
@@ -68,16 +69,16 @@ func setupSummaryHandlerTest(t *testing.T) *summaryHandlerTestSuite {
 		{
 			Month:        time.Now(),
 			CategoryName: "Food",
-			Amount:       500.00,
+			Amount:       money.MustParse("500.00"),
 		},
 	})
 
 	yearlySummary := []repository.GetYearlySummaryRow{
 		{
 			Currency:      "USD",
-			TotalIncome:   12000.00,
-			TotalExpenses: 6000.00,
-			TotalSavings:  6000.00,
+			TotalIncome:   money.MustParse("12000.00"),
+			TotalExpenses: money.MustParse("6000.00"),
+			TotalSavings:  money.MustParse("6000.00"),
 			TopCategories: topCategories,
 			MonthlyTrend:  monthlyTrend,
 		},
@@ -147,13 +148,15 @@ func TestGetMonthlySummary(t *testing.T) {
 			assert.Equal(t, tt.wantStatus, w.Code)
 
 			if tt.wantBody {
-				var response []MonthlySummaryResponse
+				var response MonthlySummary
 				err := json.NewDecoder(w.Body).Decode(&response)
 				assert.NoError(t, err)
-				assert.NotEmpty(t, response)
-				assert.Equal(t, "USD", response[0].Currency)
-				assert.NotEmpty(t, response[0].TopCategories)
-				assert.Equal(t, "Food", response[0].TopCategories[0].CategoryName)
+				assert.Equal(t, "USD", response.BaseCurrency)
+				assert.Equal(t, money.MustParse("500"), response.TotalSavings)
+				assert.NotEmpty(t, response.Currencies)
+				assert.Equal(t, "USD", response.Currencies[0].Currency)
+				assert.NotEmpty(t, response.Currencies[0].TopCategories)
+				assert.Equal(t, "Food", response.Currencies[0].TopCategories[0].CategoryName)
 			}
 		})
 	}
@@ -216,16 +219,137 @@ func TestGetYearlySummary(t *testing.T) {
 			assert.Equal(t, tt.wantStatus, w.Code)
 
 			if tt.wantBody {
-				var response []YearlySummaryResponse
+				var response YearlySummary
 				err := json.NewDecoder(w.Body).Decode(&response)
 				assert.NoError(t, err)
-				assert.NotEmpty(t, response)
-				assert.Equal(t, "USD", response[0].Currency)
-				assert.NotEmpty(t, response[0].TopCategories)
-				assert.NotEmpty(t, response[0].MonthlyTrend)
-				assert.Equal(t, "Food", response[0].TopCategories[0].CategoryName)
-				assert.Equal(t, "Food", response[0].MonthlyTrend[0].CategoryName)
+				assert.Equal(t, "USD", response.BaseCurrency)
+				assert.Equal(t, money.MustParse("6000"), response.TotalSavings)
+				assert.NotEmpty(t, response.Currencies)
+				assert.Equal(t, "USD", response.Currencies[0].Currency)
+				assert.NotEmpty(t, response.Currencies[0].TopCategories)
+				assert.NotEmpty(t, response.Currencies[0].MonthlyTrend)
+				assert.Equal(t, "Food", response.Currencies[0].TopCategories[0].CategoryName)
+				assert.Equal(t, "Food", response.Currencies[0].MonthlyTrend[0].CategoryName)
 			}
 		})
 	}
 }
+
+func TestGetMonthlySummary_ConvertedTotals(t *testing.T) {
+	suite := setupSummaryHandlerTest(t)
+
+	date := time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)
+	suite.mockRepo.GetSummaryMock().AddMonthlySummary(suite.testUser.ID, date, []repository.GetMonthlySummaryRow{
+		{
+			Currency:      "EUR",
+			TotalIncome:   money.MustParse("1000"),
+			TotalExpenses: money.MustParse("400"),
+			TotalSavings:  money.MustParse("600"),
+			TopCategories: []byte("[]"),
+		},
+		{
+			Currency:      "MXN",
+			TotalIncome:   money.MustParse("0"),
+			TotalExpenses: money.MustParse("250"),
+			TotalSavings:  money.MustParse("-250"),
+			TopCategories: []byte("[]"),
+		},
+	})
+
+	// The month is converted from its first day, not from the requested date
+	suite.mockRepo.GetSummaryMock().AddConvertedTotals(suite.testUser.ID, time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), repository.GetConvertedTotalsRow{
+		BaseCurrency:  "CAD",
+		TotalIncome:   money.MustParse("1470.3"),
+		TotalExpenses: money.MustParse("588.12"),
+		MissingRates:  []string{"MXN"},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/summary/monthly?date="+date.Format(time.RFC3339), nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, suite.testUser.ID.String()))
+
+	w := httptest.NewRecorder()
+	suite.handler.GetMonthlySummary(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response MonthlySummary
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "CAD", response.BaseCurrency)
+	assert.Equal(t, money.MustParse("1470.3"), response.TotalIncome)
+	assert.Equal(t, money.MustParse("588.12"), response.TotalExpenses)
+	assert.Equal(t, money.MustParse("882.18"), response.TotalSavings)
+	assert.Equal(t, []string{"MXN"}, response.MissingRates)
+	assert.Len(t, response.Currencies, 2)
+}
+
+func TestGetTagSummary(t *testing.T) {
+	suite := setupSummaryHandlerTest(t)
+	travel := repository.Tag{ID: uuid.New(), UserID: suite.testUser.ID, Name: "Travel"}
+	work := repository.Tag{ID: uuid.New(), UserID: suite.testUser.ID, Name: "work"}
+	suite.mockRepo.GetTagMock().AddTag(travel)
+	suite.mockRepo.GetTagMock().AddTag(work)
+
+	june := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	for _, expense := range []repository.Expense{
+		{Amount: money.MustParse("120"), Currency: "EUR", Date: june.AddDate(0, 0, 2), Description: "Hotel"},
+		{Amount: money.MustParse("35.50"), Currency: "EUR", Date: june.AddDate(0, 0, 3), Description: "Dinner with client"},
+		{Amount: money.MustParse("60"), Currency: "CAD", Date: june.AddDate(0, 0, 20), Description: "Airport parking"},
+		{Amount: money.MustParse("90"), Currency: "CAD", Date: june.AddDate(0, 1, 0), Description: "July flight"},
+	} {
+		expense.ID = uuid.New()
+		expense.UserID = suite.testUser.ID
+		suite.mockRepo.GetExpenseMock().AddExpense(expense)
+		suite.mockRepo.GetTagMock().TagExpense(expense.ID, travel.ID)
+		if expense.Description == "Dinner with client" {
+			suite.mockRepo.GetTagMock().TagExpense(expense.ID, work.ID)
+		}
+	}
+	fee := repository.Income{ID: uuid.New(), UserID: suite.testUser.ID, Amount: money.MustParse("500"), Currency: "EUR", Source: "Client", Date: june.AddDate(0, 0, 4)}
+	suite.mockRepo.GetIncomeMock().AddIncome(fee)
+	suite.mockRepo.GetTagMock().TagIncome(fee.ID, work.ID)
+
+	summary := func(query string) ([]TagSummary, int) {
+		req := httptest.NewRequest(http.MethodGet, "/api/summary/tags?"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, suite.testUser.ID.String()))
+		w := httptest.NewRecorder()
+		suite.handler.GetTagSummary(w, req)
+
+		var summaries []TagSummary
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&summaries); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return summaries, w.Code
+	}
+
+	// July 1st is the end of the range and is left out
+	summaries, status := summary("start_date=2024-06-01T00:00:00Z&end_date=2024-07-01T00:00:00Z")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []TagSummary{
+		{TagID: travel.ID, TagName: "Travel", Currencies: []TagCurrencyTotals{
+			{Currency: "CAD", ExpenseCount: 1, TotalExpenses: money.MustParse("60")},
+			{Currency: "EUR", ExpenseCount: 2, TotalExpenses: money.MustParse("155.50")},
+		}},
+		{TagID: work.ID, TagName: "work", Currencies: []TagCurrencyTotals{
+			{Currency: "EUR", ExpenseCount: 1, TotalExpenses: money.MustParse("35.50"), IncomeCount: 1, TotalIncome: money.MustParse("500")},
+		}},
+	}, summaries)
+
+	summaries, status = summary("start_date=2024-06-01T00:00:00Z&end_date=2024-07-01T00:00:00Z&tag_id=" + work.ID.String())
+	assert.Equal(t, http.StatusOK, status)
+	if assert.Len(t, summaries, 1) {
+		assert.Equal(t, work.ID, summaries[0].TagID)
+	}
+
+	summaries, status = summary("start_date=2025-01-01T00:00:00Z&end_date=2025-02-01T00:00:00Z")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, summaries)
+
+	_, status = summary("start_date=2024-06-01T00:00:00Z")
+	assert.Equal(t, http.StatusBadRequest, status)
+	_, status = summary("start_date=2024-06-01T00:00:00Z&end_date=2024-07-01T00:00:00Z&tag_id=travel")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/jorge-dev/centsible/internal/validation"
+	"github.com/jorge-dev/centsible/server/middleware"
+)
+
+type TagHandler struct {
+	db repository.Repository
+}
+
+func NewTagHandler(db repository.Repository) *TagHandler {
+	return &TagHandler{db: db}
+}
+
+type tagRequest struct {
+	Name string `json:"name"`
+}
+
+type transactionTagsRequest struct {
+	TagIDs []uuid.UUID `json:"tag_ids"`
+}
+
+func (h *TagHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	var req tagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	validator := &validation.TagValidation{
+		Name: req.Name,
+	}
+	if err := validator.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	id := uuid.New()
+	exists, err := h.db.CheckTagExists(r.Context(), repository.CheckTagExistsParams{
+		UserID: uid,
+		Name:   req.Name,
+		ID:     id,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if exists {
+		http.Error(w, "Tag already exists", http.StatusConflict)
+		return
+	}
+
+	tag, err := h.db.CreateTag(r.Context(), repository.CreateTagParams{
+		ID:     id,
+		UserID: uid,
+		Name:   req.Name,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, tag)
+}
+
+// ListTags handles GET /tags, listing every tag of the user by name
+func (h *TagHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	tags, err := h.db.ListTags(r.Context(), uid)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if tags == nil {
+		tags = []repository.Tag{}
+	}
+
+	writeJSON(w, http.StatusOK, tags)
+}
+
+func (h *TagHandler) GetTag(w http.ResponseWriter, r *http.Request) {
+	tid, err := validation.ValidateUUID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	tag, err := h.db.GetTagByID(r.Context(), repository.GetTagByIDParams{
+		ID:     tid,
+		UserID: uid,
+	})
+	if err != nil {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tag)
+}
+
+// UpdateTag handles PUT /tags/{id}, renaming a tag. Transactions keep the
+// tag under its new name.
+func (h *TagHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	tid, err := validation.ValidateUUID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	var req tagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	validator := &validation.TagValidation{
+		Name: req.Name,
+	}
+	if err := validator.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	exists, err := h.db.CheckTagExists(r.Context(), repository.CheckTagExistsParams{
+		UserID: uid,
+		Name:   req.Name,
+		ID:     tid,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if exists {
+		http.Error(w, "Tag already exists", http.StatusConflict)
+		return
+	}
+
+	tag, err := h.db.UpdateTag(r.Context(), repository.UpdateTagParams{
+		ID:     tid,
+		Name:   req.Name,
+		UserID: uid,
+	})
+	if err != nil {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tag)
+}
+
+// DeleteTag handles DELETE /tags/{id}. The tag drops off every transaction
+// that had it.
+func (h *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	tid, err := validation.ValidateUUID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err := validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.db.DeleteTag(r.Context(), repository.DeleteTagParams{
+		ID:     tid,
+		UserID: uid,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// transactionTagIDs reads the path's transaction ID, the user and, for a PUT,
+// the tag IDs of the body without duplicates. It writes the error and returns
+// false when any of them is invalid.
+func transactionTagIDs(w http.ResponseWriter, r *http.Request, kind string) (id, uid uuid.UUID, tagIDs []uuid.UUID, ok bool) {
+	id, err := validation.ValidateUUID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid "+kind+" ID", http.StatusBadRequest)
+		return id, uid, nil, false
+	}
+
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+	uid, err = validation.ValidateUUID(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return id, uid, nil, false
+	}
+
+	if r.Method != http.MethodPut {
+		return id, uid, nil, true
+	}
+	var req transactionTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return id, uid, nil, false
+	}
+	return id, uid, uniqueIDs(req.TagIDs), true
+}
+
+// uniqueIDs returns ids without repeats, in the order they first appear
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := []uuid.UUID{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// checkUserTags reports whether every tag ID belongs to the user, writing the
+// error when not
+func checkUserTags(w http.ResponseWriter, r *http.Request, db repository.Repository, uid uuid.UUID, tagIDs []uuid.UUID) bool {
+	if len(tagIDs) == 0 {
+		return true
+	}
+	count, err := db.CountUserTags(r.Context(), repository.CountUserTagsParams{
+		UserID: uid,
+		TagIds: tagIDs,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if count != int64(len(tagIDs)) {
+		http.Error(w, "Unknown tag ID", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeTags(w http.ResponseWriter, tags []repository.Tag) {
+	if tags == nil {
+		tags = []repository.Tag{}
+	}
+	writeJSON(w, http.StatusOK, tags)
+}
+
+// GetExpenseTags handles GET /expenses/{id}/tags
+func (h *TagHandler) GetExpenseTags(w http.ResponseWriter, r *http.Request) {
+	id, uid, _, ok := transactionTagIDs(w, r, "expense")
+	if !ok {
+		return
+	}
+	if _, err := h.db.GetExpenseByID(r.Context(), repository.GetExpenseByIDParams{ID: id, UserID: uid}); err != nil {
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+
+	tags, err := h.db.ListExpenseTags(r.Context(), repository.ListExpenseTagsParams{
+		ExpenseID: id,
+		UserID:    uid,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeTags(w, tags)
+}
+
+// SetExpenseTags handles PUT /expenses/{id}/tags, replacing the expense's
+// tags with tag_ids and returning them
+func (h *TagHandler) SetExpenseTags(w http.ResponseWriter, r *http.Request) {
+	id, uid, tagIDs, ok := transactionTagIDs(w, r, "expense")
+	if !ok {
+		return
+	}
+	if _, err := h.db.GetExpenseByID(r.Context(), repository.GetExpenseByIDParams{ID: id, UserID: uid}); err != nil {
+		http.Error(w, "Expense not found", http.StatusNotFound)
+		return
+	}
+	if !checkUserTags(w, r, h.db, uid, tagIDs) {
+		return
+	}
+
+	if err := h.db.SetExpenseTags(r.Context(), repository.SetExpenseTagsParams{
+		ExpenseID: id,
+		TagIds:    tagIDs,
+	}); err != nil {
+		log.Printf("Error setting expense tags: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := h.db.ListExpenseTags(r.Context(), repository.ListExpenseTagsParams{
+		ExpenseID: id,
+		UserID:    uid,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeTags(w, tags)
+}
+
+// GetIncomeTags handles GET /income/{id}/tags
+func (h *TagHandler) GetIncomeTags(w http.ResponseWriter, r *http.Request) {
+	id, uid, _, ok := transactionTagIDs(w, r, "income")
+	if !ok {
+		return
+	}
+	if _, err := h.db.GetIncomeByID(r.Context(), repository.GetIncomeByIDParams{ID: id, UserID: uid}); err != nil {
+		http.Error(w, "Income not found", http.StatusNotFound)
+		return
+	}
+
+	tags, err := h.db.ListIncomeTags(r.Context(), repository.ListIncomeTagsParams{
+		IncomeID: id,
+		UserID:   uid,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeTags(w, tags)
+}
+
+// SetIncomeTags handles PUT /income/{id}/tags, replacing the income record's
+// tags with tag_ids and returning them
+func (h *TagHandler) SetIncomeTags(w http.ResponseWriter, r *http.Request) {
+	id, uid, tagIDs, ok := transactionTagIDs(w, r, "income")
+	if !ok {
+		return
+	}
+	if _, err := h.db.GetIncomeByID(r.Context(), repository.GetIncomeByIDParams{ID: id, UserID: uid}); err != nil {
+		http.Error(w, "Income not found", http.StatusNotFound)
+		return
+	}
+	if !checkUserTags(w, r, h.db, uid, tagIDs) {
+		return
+	}
+
+	if err := h.db.SetIncomeTags(r.Context(), repository.SetIncomeTagsParams{
+		IncomeID: id,
+		TagIds:   tagIDs,
+	}); err != nil {
+		log.Printf("Error setting income tags: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := h.db.ListIncomeTags(r.Context(), repository.ListIncomeTagsParams{
+		IncomeID: id,
+		UserID:   uid,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeTags(w, tags)
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jorge-dev/centsible/internal/money"
+	"github.com/jorge-dev/centsible/internal/repository"
+	"github.com/jorge-dev/centsible/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+type tagHandlerTestSuite struct {
+	mockRepo *mocks.MockRepository
+	handler  *TagHandler
+	userID   uuid.UUID
+	travel   repository.Tag
+	work     repository.Tag
+	expense  repository.Expense
+	income   repository.Income
+}
+
+func (s *tagHandlerTestSuite) cleanup() {
+	s.mockRepo.Reset()
+}
+
+func setupTagHandlerTest(t *testing.T) *tagHandlerTestSuite {
+	suite := &tagHandlerTestSuite{}
+	t.Cleanup(suite.cleanup)
+
+	repo := mocks.NewMockRepository()
+	mock, ok := repo.(*mocks.MockRepository)
+	if !ok {
+		t.Fatal("could not cast to MockRepository")
+	}
+	suite.mockRepo = mock
+	suite.handler = NewTagHandler(repo)
+	suite.userID = uuid.New()
+
+	suite.travel = repository.Tag{ID: uuid.New(), UserID: suite.userID, Name: "travel"}
+	suite.work = repository.Tag{ID: uuid.New(), UserID: suite.userID, Name: "Work"}
+	suite.mockRepo.GetTagMock().AddTag(suite.travel)
+	suite.mockRepo.GetTagMock().AddTag(suite.work)
+
+	suite.expense = repository.Expense{
+		ID:          uuid.New(),
+		UserID:      suite.userID,
+		Amount:      money.MustParse("84.20"),
+		Currency:    "EUR",
+		CategoryID:  uuid.New(),
+		Date:        time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC),
+		Description: "Hotel in Porto",
+	}
+	suite.mockRepo.GetExpenseMock().AddExpense(suite.expense)
+	suite.income = repository.Income{
+		ID:          uuid.New(),
+		UserID:      suite.userID,
+		Amount:      money.MustParse("300"),
+		Currency:    "EUR",
+		Source:      "Client",
+		Date:        time.Date(2024, time.June, 4, 0, 0, 0, 0, time.UTC),
+		Description: "Workshop fee",
+	}
+	suite.mockRepo.GetIncomeMock().AddIncome(suite.income)
+
+	return suite
+}
+
+// serve calls a handler as the suite's user, with the {id} path parameter
+// when id is set
+func (s *tagHandlerTestSuite) serve(handler http.HandlerFunc, method, id, body string) *httptest.ResponseRecorder {
+	req := withUser(httptest.NewRequest(method, "/", strings.NewReader(body)), s.userID)
+	if id != "" {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+func tagNames(t *testing.T, body *bytes.Buffer) []string {
+	var tags []repository.Tag
+	if err := json.NewDecoder(body).Decode(&tags); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func TestCreateTag(t *testing.T) {
+	suite := setupTagHandlerTest(t)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"Valid tag", `{"name":"vacation-2024"}`, http.StatusCreated},
+		{"Same name in another case", `{"name":"TRAVEL"}`, http.StatusConflict},
+		{"Empty name", `{"name":""}`, http.StatusBadRequest},
+		{"Too long name", `{"name":"` + strings.Repeat("a", 51) + `"}`, http.StatusBadRequest},
+		{"Malformed JSON", `{"name":`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := suite.serve(suite.handler.CreateTag, http.MethodPost, "", tt.body)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestListTags(t *testing.T) {
+	suite := setupTagHandlerTest(t)
+	suite.mockRepo.GetTagMock().AddTag(repository.Tag{ID: uuid.New(), UserID: uuid.New(), Name: "Someone else's"})
+
+	w := suite.serve(suite.handler.ListTags, http.MethodGet, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"travel", "Work"}, tagNames(t, w.Body))
+}
+
+func TestUpdateTag(t *testing.T) {
+	suite := setupTagHandlerTest(t)
+
+	tests := []struct {
+		name       string
+		id         string
+		body       string
+		wantStatus int
+	}{
+		{"Rename", suite.travel.ID.String(), `{"name":"Trips"}`, http.StatusOK},
+		{"Change case of own name", suite.work.ID.String(), `{"name":"work"}`, http.StatusOK},
+		{"Name of another tag", suite.work.ID.String(), `{"name":"trips"}`, http.StatusConflict},
+		{"Unknown tag", uuid.NewString(), `{"name":"Other"}`, http.StatusNotFound},
+		{"Invalid ID", "travel", `{"name":"Other"}`, http.StatusBadRequest},
+		{"Empty name", suite.work.ID.String(), `{"name":""}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := suite.serve(suite.handler.UpdateTag, http.MethodPut, tt.id, tt.body)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestDeleteTag(t *testing.T) {
+	suite := setupTagHandlerTest(t)
+	suite.mockRepo.GetTagMock().TagExpense(suite.expense.ID, suite.travel.ID)
+
+	w := suite.serve(suite.handler.DeleteTag, http.MethodDelete, suite.travel.ID.String(), "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = suite.serve(suite.handler.DeleteTag, http.MethodDelete, suite.travel.ID.String(), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = suite.serve(suite.handler.GetTag, http.MethodGet, suite.travel.ID.String(), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The expense no longer shows the tag
+	w = suite.serve(suite.handler.GetExpenseTags, http.MethodGet, suite.expense.ID.String(), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, tagNames(t, w.Body))
+}
+
+func TestSetExpenseTags(t *testing.T) {
+	suite := setupTagHandlerTest(t)
+	otherUsersTag := uuid.New()
+	suite.mockRepo.GetTagMock().AddTag(repository.Tag{ID: otherUsersTag, UserID: uuid.New(), Name: "Theirs"})
+
+	tests := []struct {
+		name       string
+		id         string
+		tagIDs     []uuid.UUID
+		wantStatus int
+		wantTags   []string
+	}{
+		{"Two tags", suite.expense.ID.String(), []uuid.UUID{suite.work.ID, suite.travel.ID}, http.StatusOK, []string{"travel", "Work"}},
+		{"Repeated tag", suite.expense.ID.String(), []uuid.UUID{suite.work.ID, suite.work.ID}, http.StatusOK, []string{"Work"}},
+		{"No tags", suite.expense.ID.String(), []uuid.UUID{}, http.StatusOK, []string{}},
+		{"Unknown tag", suite.expense.ID.String(), []uuid.UUID{uuid.New()}, http.StatusBadRequest, nil},
+		{"Another user's tag", suite.expense.ID.String(), []uuid.UUID{otherUsersTag}, http.StatusBadRequest, nil},
+		{"Unknown expense", uuid.NewString(), []uuid.UUID{suite.work.ID}, http.StatusNotFound, nil},
+		{"Invalid expense ID", "hotel", []uuid.UUID{suite.work.ID}, http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(transactionTagsRequest{TagIDs: tt.tagIDs})
+			w := suite.serve(suite.handler.SetExpenseTags, http.MethodPut, tt.id, string(body))
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantTags == nil {
+				return
+			}
+			assert.Equal(t, tt.wantTags, tagNames(t, w.Body))
+
+			w = suite.serve(suite.handler.GetExpenseTags, http.MethodGet, tt.id, "")
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.wantTags, tagNames(t, w.Body))
+		})
+	}
+}
+
+func TestSetIncomeTags(t *testing.T) {
+	suite := setupTagHandlerTest(t)
+
+	body, _ := json.Marshal(transactionTagsRequest{TagIDs: []uuid.UUID{suite.work.ID}})
+	w := suite.serve(suite.handler.SetIncomeTags, http.MethodPut, suite.income.ID.String(), string(body))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"Work"}, tagNames(t, w.Body))
+
+	w = suite.serve(suite.handler.GetIncomeTags, http.MethodGet, suite.income.ID.String(), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"Work"}, tagNames(t, w.Body))
+
+	w = suite.serve(suite.handler.GetIncomeTags, http.MethodGet, uuid.NewString(), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -17,8 +17,9 @@ type UserHandler struct {
 }
 
 type UpdateProfileRequest struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	BaseCurrency string `json:"base_currency"`
 }
 
 type UpdatePasswordRequest struct {
@@ -27,10 +28,11 @@ type UpdatePasswordRequest struct {
 }
 
 type UserResponse struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Email     string `json:"email"`
-	CreatedAt string `json:"created_at"`
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	BaseCurrency string `json:"base_currency"`
+	CreatedAt    string `json:"created_at"`
 }
 
 func NewUserHandler(db repository.Repository) *UserHandler {
@@ -57,10 +59,11 @@ func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := UserResponse{
-		ID:        user.ID.String(),
-		Name:      user.Name,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt.String(),
+		ID:           user.ID.String(),
+		Name:         user.Name,
+		Email:        user.Email,
+		BaseCurrency: user.BaseCurrency,
+		CreatedAt:    user.CreatedAt.String(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -76,8 +79,9 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	validator := &validation.UserProfileValidation{
-		Name:  req.Name,
-		Email: req.Email,
+		Name:         req.Name,
+		Email:        req.Email,
+		BaseCurrency: req.BaseCurrency,
 	}
 
 	if err := validator.Validate(); err != nil {
@@ -102,6 +106,7 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	// Use current values if request fields are empty
 	name := currentUser.Name
 	email := currentUser.Email
+	baseCurrency := currentUser.BaseCurrency
 
 	if req.Name != "" {
 		name = req.Name
@@ -109,11 +114,15 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	if req.Email != "" {
 		email = req.Email
 	}
+	if req.BaseCurrency != "" {
+		baseCurrency = req.BaseCurrency
+	}
 
 	user, err := h.db.UpdateUser(r.Context(), repository.UpdateUserParams{
-		ID:    uid,
-		Name:  name,
-		Email: email,
+		ID:           uid,
+		Name:         name,
+		Email:        email,
+		BaseCurrency: baseCurrency,
 	})
 	if err != nil {
 		http.Error(w, "Error updating user", http.StatusInternalServerError)
@@ -121,10 +130,11 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := UserResponse{
-		ID:        user.ID.String(),
-		Name:      user.Name,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt.String(),
+		ID:           user.ID.String(),
+		Name:         user.Name,
+		Email:        user.Email,
+		BaseCurrency: user.BaseCurrency,
+		CreatedAt:    user.CreatedAt.String(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -194,6 +194,18 @@ func TestUpdateProfile(t *testing.T) {
 			reqBody:    UpdateProfileRequest{Email: "newemail@example.com"},
 			wantStatus: http.StatusOK,
 		},
+		{
+			name:       "Partial update - base currency only",
+			userID:     suite.testUser.ID.String(),
+			reqBody:    UpdateProfileRequest{BaseCurrency: "EUR"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Invalid base currency",
+			userID:     suite.testUser.ID.String(),
+			reqBody:    UpdateProfileRequest{BaseCurrency: "EURO"},
+			wantStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -14,9 +14,10 @@ type AuthMiddleware struct {
 }
 
 const (
-	UserIDKey contextKey = "user_id"
-	EmailKey  contextKey = "email"
-	RoleIDKey contextKey = "role_id"
+	UserIDKey    contextKey = "user_id"
+	EmailKey     contextKey = "email"
+	RoleIDKey    contextKey = "role_id"
+	SessionIDKey contextKey = "session_id"
 )
 
 type contextKey string
@@ -64,12 +65,11 @@ func (m *AuthMiddleware) AuthRequired(next http.Handler) http.Handler {
 			return
 		}
 
-		// Update user's last activity
-		m.jwtManager.UpdateActivity(claims.UserID)
-
+		// ValidateToken has already recorded the session's activity
 		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 		ctx = context.WithValue(ctx, EmailKey, claims.Email)
 		ctx = context.WithValue(ctx, RoleIDKey, claims.RoleIDKey)
+		ctx = context.WithValue(ctx, SessionIDKey, claims.SessionID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
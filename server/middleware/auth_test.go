@@ -1,10 +1,13 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -115,7 +118,7 @@ func TestAuthRequired(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var contextUserID, contextEmail, contextRoleID string
+			var contextUserID, contextEmail, contextRoleID, contextSessionID string
 
 			// Create test handler that checks context values
 			nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -123,6 +126,7 @@ func TestAuthRequired(t *testing.T) {
 					contextUserID = r.Context().Value(UserIDKey).(string)
 					contextEmail = r.Context().Value(EmailKey).(string)
 					contextRoleID = r.Context().Value(RoleIDKey).(string)
+					contextSessionID = r.Context().Value(SessionIDKey).(string)
 				}
 				w.WriteHeader(http.StatusOK)
 			})
@@ -167,11 +171,82 @@ func TestAuthRequired(t *testing.T) {
 				if contextRoleID != "user" {
 					t.Errorf("context role ID = %v, want %v", contextRoleID, "user")
 				}
+				if contextSessionID == "" {
+					t.Error("context session ID is empty")
+				}
 			}
 		})
 	}
 }
 
+// TestAuthRequiredConcurrent hammers AuthRequired from many goroutines while tokens are
+// issued, refreshed and invalidated. Run with -race to catch unsynchronized access.
+func TestAuthRequiredConcurrent(t *testing.T) {
+	jwtManager := auth.NewJWTManager("test-secret")
+	middleware := NewAuthMiddleware(jwtManager)
+	handler := middleware.AuthRequired(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Run the sweeper concurrently with request traffic
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sweeperDone := jwtManager.StartCleanup(ctx, time.Millisecond)
+
+	const workers = 16
+	const iterations = 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			userID := fmt.Sprintf("user-%d", worker)
+			for j := 0; j < iterations; j++ {
+				tokenPair, err := jwtManager.GenerateTokenPair(userID, "test@example.com", "user")
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				req := httptest.NewRequest("GET", "/test", nil)
+				req.Header.Set("Authorization", "Bearer "+tokenPair.AccessToken)
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+				if rr.Code != http.StatusOK {
+					errs <- fmt.Errorf("worker %d: got status %d", worker, rr.Code)
+					return
+				}
+
+				switch j % 3 {
+				case 0:
+					if _, err := jwtManager.RefreshToken(tokenPair.RefreshToken); err != nil {
+						errs <- fmt.Errorf("worker %d: refresh failed: %w", worker, err)
+						return
+					}
+				case 1:
+					if err := jwtManager.InvalidateToken(tokenPair.AccessToken); err != nil {
+						errs <- fmt.Errorf("worker %d: invalidate failed: %w", worker, err)
+						return
+					}
+				default:
+					jwtManager.SetTimeout(auth.InactivityTimeout)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	cancel()
+	<-sweeperDone
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
 // Helper function to check if a string contains another string
 func contains(s, substr string) bool {
 	return strings.Contains(s, substr)
@@ -32,6 +32,11 @@ func (rl *RateLimiter) getClientLimiter(clientIP string) *rate.Limiter {
 }
 
 func (rl *RateLimiter) getClientIP(r *http.Request) string {
+	return ClientIP(r)
+}
+
+// ClientIP returns the caller's IP address, preferring the first X-Forwarded-For entry
+func ClientIP(r *http.Request) string {
 	// Check X-Forwarded-For header
 	forwardedFor := r.Header.Get("X-Forwarded-For")
 	if forwardedFor != "" {
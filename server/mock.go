@@ -1,7 +1,7 @@
 package server
 
 import (
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jorge-dev/centsible/internal/database"
 )
 
@@ -24,9 +24,13 @@ func (m *mockDB) Health() map[string]string {
 	return map[string]string{"status": "down", "message": "Database connection failed"}
 }
 
-func (m *mockDB) GetConnection() *pgx.Conn {
+func (m *mockDB) GetPool() *pgxpool.Pool {
 	if !m.healthStatus {
 		return nil
 	}
-	return &pgx.Conn{} // Return empty connection for testing
+	return &pgxpool.Pool{} // Return empty pool for testing
+}
+
+func (m *mockDB) GetReplicaPool() *pgxpool.Pool {
+	return nil // No replica, everything runs on the primary
 }
@@ -7,8 +7,9 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jorge-dev/centsible/internal/auth"
+	"github.com/jorge-dev/centsible/internal/database"
 	"github.com/jorge-dev/centsible/internal/repository"
 	"github.com/jorge-dev/centsible/internal/version"
 	"github.com/jorge-dev/centsible/server/handlers"
@@ -17,9 +18,11 @@ import (
 	"golang.org/x/time/rate"
 )
 
-func (s *Server) RegisterRoutes(conn *pgx.Conn, jwtManager auth.JWTManager, env string) http.Handler {
+func (s *Server) RegisterRoutes(pool, replica *pgxpool.Pool, jwtManager *auth.JWTManager, env string) http.Handler {
 
-	queries := repository.New(conn)
+	// Seeding runs on the primary; everything else may read reports from the replica
+	seedQueries := repository.New(pool)
+	queries := database.NewRepository(pool, replica)
 	r := chi.NewRouter()
 
 	// Add security headers middleware first
@@ -42,9 +45,13 @@ func (s *Server) RegisterRoutes(conn *pgx.Conn, jwtManager auth.JWTManager, env
 		r.Get("/live", s.liveCheck)
 		r.Get("/health", s.healthHandler)
 
+		// Public keys for services that verify our tokens themselves
+		keysHandler := handlers.NewKeysHandler(jwtManager)
+		r.Get("/.well-known/jwks.json", keysHandler.JWKS)
+
 		// Add seed routes (only in development)
 		if env == "local" {
-			seedHandler := handlers.NewSeedHandler(queries)
+			seedHandler := handlers.NewSeedHandler(seedQueries)
 			r.Post("/api/seed", seedHandler.SeedData)
 			r.Delete("/api/seed", seedHandler.DeleteSeedData)
 		}
@@ -54,16 +61,17 @@ func (s *Server) RegisterRoutes(conn *pgx.Conn, jwtManager auth.JWTManager, env
 	// Auth routes
 	r.Group(func(r chi.Router) {
 		r.Use(authRateLimiter.Limit)
-		authHandler := handlers.NewAuthHandler(queries, &jwtManager)
+		authHandler := handlers.NewAuthHandler(queries, jwtManager)
 		r.Post("/register", authHandler.Register)
 		r.Post("/login", authHandler.Login)
+		r.Post("/refresh", authHandler.Refresh)
 		r.Post("/logout", authHandler.Signout)
 	})
 
 	// Private routes
 	r.Group(func(r chi.Router) {
 		r.Use(privateRateLimiter.Limit)
-		authMiddleware := customMiddleware.NewAuthMiddleware(&jwtManager)
+		authMiddleware := customMiddleware.NewAuthMiddleware(jwtManager)
 		r.Use(authMiddleware.AuthRequired)
 
 		// User routes
@@ -76,6 +84,20 @@ func (s *Server) RegisterRoutes(conn *pgx.Conn, jwtManager auth.JWTManager, env
 		r.Put("/user/roles", userHandler.UpdateUserRole)
 		r.Get("/user/roles/list", userHandler.ListUsersByRole)
 
+		// Session routes
+		sessionHandler := handlers.NewSessionHandler(jwtManager)
+		r.Get("/user/sessions", sessionHandler.ListSessions)
+		r.Delete("/user/sessions", sessionHandler.RevokeAllSessions)
+		r.Delete("/user/sessions/{id}", sessionHandler.RevokeSession)
+
+		// Bank statement import
+		importHandler := handlers.NewImportHandler(queries)
+		r.Post("/user/import", importHandler.ImportTransactions)
+
+		// Full export of a user's data
+		exportHandler := handlers.NewExportHandler(queries)
+		r.Get("/user/export", exportHandler.ExportUserData)
+
 		// Income routes
 		incomeHandler := handlers.NewIncomeHandler(queries)
 		r.Post("/income", incomeHandler.CreateIncome)
@@ -97,22 +119,48 @@ func (s *Server) RegisterRoutes(conn *pgx.Conn, jwtManager auth.JWTManager, env
 		r.Get("/expenses/monthly/total", expenseHandler.GetMonthlyExpenseTotal)
 		r.Get("/expenses/category/totals", expenseHandler.GetExpenseTotalsByCategory)
 		r.Get("/expenses/recent", expenseHandler.GetRecentExpenses)
+		r.Get("/expenses/duplicates", expenseHandler.ListDuplicateExpenses)
 
 		// Category routes
 		categoryHandler := handlers.NewCategoryHandler(queries)
 		r.Post("/categories", categoryHandler.CreateCategory)
 		r.Get("/categories", categoryHandler.ListCategories)
+		r.Get("/categories/templates", categoryHandler.ListCategoryTemplates)
+		r.Post("/categories/templates/{key}/apply", categoryHandler.ApplyCategoryTemplate)
 		r.Get("/categories/{id}", categoryHandler.GetCategory)
 		r.Put("/categories/{id}", categoryHandler.UpdateCategory)
 		r.Delete("/categories/{id}", categoryHandler.DeleteCategory)
+		r.Post("/categories/{id}/merge", categoryHandler.MergeCategory)
 		r.Get("/categories/{id}/stats", categoryHandler.GetCategoryStats)
 		r.Get("/categories/stats/most-used", categoryHandler.GetMostUsedCategories)
 
+		// Category rule routes
+		categoryRuleHandler := handlers.NewCategoryRuleHandler(queries)
+		r.Post("/category-rules", categoryRuleHandler.CreateCategoryRule)
+		r.Get("/category-rules", categoryRuleHandler.ListCategoryRules)
+		r.Get("/category-rules/{id}", categoryRuleHandler.GetCategoryRule)
+		r.Put("/category-rules/{id}", categoryRuleHandler.UpdateCategoryRule)
+		r.Delete("/category-rules/{id}", categoryRuleHandler.DeleteCategoryRule)
+		r.Get("/categories/suggest", categoryRuleHandler.SuggestCategory)
+
+		// Tag routes
+		tagHandler := handlers.NewTagHandler(queries)
+		r.Post("/tags", tagHandler.CreateTag)
+		r.Get("/tags", tagHandler.ListTags)
+		r.Get("/tags/{id}", tagHandler.GetTag)
+		r.Put("/tags/{id}", tagHandler.UpdateTag)
+		r.Delete("/tags/{id}", tagHandler.DeleteTag)
+		r.Get("/expenses/{id}/tags", tagHandler.GetExpenseTags)
+		r.Put("/expenses/{id}/tags", tagHandler.SetExpenseTags)
+		r.Get("/income/{id}/tags", tagHandler.GetIncomeTags)
+		r.Put("/income/{id}/tags", tagHandler.SetIncomeTags)
+
 		// Budget routes
 		budgetHandler := handlers.NewBudgetHandler(queries)
 		r.Post("/budgets", budgetHandler.CreateBudget)
 		r.Get("/budgets", budgetHandler.ListBudgets)
 		r.Get("/budgets/{id}", budgetHandler.GetBudgetUsage)
+		r.Get("/budgets/{id}/periods", budgetHandler.GetBudgetPeriods)
 		r.Put("/budgets/{id}", budgetHandler.UpdateBudget)
 		r.Delete("/budgets/{id}", budgetHandler.DeleteBudget)
 		r.Get("/budgets/recurring", budgetHandler.GetRecurringBudgets)
@@ -120,10 +168,30 @@ func (s *Server) RegisterRoutes(conn *pgx.Conn, jwtManager auth.JWTManager, env
 		r.Get("/budgets/category/{categoryId}", budgetHandler.GetBudgetsByCategory)
 		r.Get("/budgets/alerts", budgetHandler.GetBudgetsNearLimit)
 
+		// Recurring transaction routes; due occurrences are generated by a background worker
+		recurringHandler := handlers.NewRecurringTransactionHandler(queries)
+		r.Post("/recurring-transactions", recurringHandler.CreateRecurringTransaction)
+		r.Get("/recurring-transactions", recurringHandler.ListRecurringTransactions)
+		r.Get("/recurring-transactions/{id}", recurringHandler.GetRecurringTransaction)
+		r.Put("/recurring-transactions/{id}", recurringHandler.UpdateRecurringTransaction)
+		r.Delete("/recurring-transactions/{id}", recurringHandler.DeleteRecurringTransaction)
+
+		// Exchange rate routes; changes are limited to admins
+		exchangeRateHandler := handlers.NewExchangeRateHandler(queries)
+		r.Get("/exchange-rates", exchangeRateHandler.ListExchangeRates)
+		r.Post("/admin/exchange-rates", exchangeRateHandler.UpsertExchangeRate)
+		r.Post("/admin/exchange-rates/import", exchangeRateHandler.ImportExchangeRates)
+		r.Delete("/admin/exchange-rates/{base}/{quote}/{date}", exchangeRateHandler.DeleteExchangeRate)
+
+		// Full-text search across expenses and income
+		searchHandler := handlers.NewSearchHandler(queries)
+		r.Get("/search", searchHandler.Search)
+
 		// Summary routes
 		summaryHandler := handlers.NewSummaryHandler(queries)
 		r.Get("/summary/monthly", summaryHandler.GetMonthlySummary)
 		r.Get("/summary/yearly", summaryHandler.GetYearlySummary)
+		r.Get("/summary/tags", summaryHandler.GetTagSummary)
 	})
 
 	return r
@@ -21,7 +21,7 @@ func TestRegisterRoutes(t *testing.T) {
 	}
 
 	jwtManager := auth.NewJWTManager("test-secret")
-	handler := s.RegisterRoutes(nil, *jwtManager, "local")
+	handler := s.RegisterRoutes(nil, nil, jwtManager, "local")
 
 	if handler == nil {
 		t.Error("RegisterRoutes() returned nil handler")
@@ -156,7 +156,7 @@ func TestPublicRoutesRateLimiting(t *testing.T) {
 	}
 
 	jwtManager := auth.NewJWTManager("test-secret")
-	handler := s.RegisterRoutes(nil, *jwtManager, "local")
+	handler := s.RegisterRoutes(nil, nil, jwtManager, "local")
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -187,7 +187,7 @@ func TestAuthRoutesRateLimiting(t *testing.T) {
 	}
 
 	jwtManager := auth.NewJWTManager("test-secret")
-	handler := s.RegisterRoutes(nil, *jwtManager, "local")
+	handler := s.RegisterRoutes(nil, nil, jwtManager, "local")
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -220,7 +220,7 @@ func TestPrivateRoutesRateLimiting(t *testing.T) {
 
 	jwtManager := auth.NewJWTManager("test-secret")
 	token, _ := jwtManager.GenerateToken(uuid.New().String(), "test@email.com", uuid.New().String())
-	handler := s.RegisterRoutes(nil, *jwtManager, "test")
+	handler := s.RegisterRoutes(nil, nil, jwtManager, "test")
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -259,7 +259,7 @@ func TestRateLimitReset(t *testing.T) {
 	}
 
 	jwtManager := auth.NewJWTManager("test-secret")
-	handler := s.RegisterRoutes(nil, *jwtManager, "local")
+	handler := s.RegisterRoutes(nil, nil, jwtManager, "local")
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
@@ -3,7 +3,6 @@ package server
 import (
 	"context"
 	"fmt"
-	"log"
 	"net/http"
 	"time"
 
@@ -11,6 +10,7 @@ import (
 	"github.com/jorge-dev/centsible/internal/auth"
 	"github.com/jorge-dev/centsible/internal/config"
 	"github.com/jorge-dev/centsible/internal/database"
+	"github.com/jorge-dev/centsible/internal/repository"
 )
 
 type Server struct {
@@ -28,13 +28,24 @@ func (s *Server) SetDB(db database.Service) {
 	s.db = db
 }
 
-func NewServer(ctx context.Context) (*http.Server, *Server) {
+// NewServer connects to the database and sets up the HTTP server. It fails
+// without connecting when the configuration is invalid.
+func NewServer(ctx context.Context) (*http.Server, *Server, error) {
 	cfg := config.Get()
 
 	// Validate required configuration
 	if cfg.Port <= 0 {
-		log.Printf("Invalid port configuration: %d", cfg.Port)
-		return nil, nil
+		return nil, nil, fmt.Errorf("invalid port configuration: %d", cfg.Port)
+	}
+
+	// Sign with RS256/EdDSA when a key is configured so other services can verify tokens through the JWKS
+	var keys *auth.KeySet
+	if cfg.JWT.SigningKeyFile != "" {
+		var err error
+		keys, err = auth.LoadKeySet(cfg.JWT.SigningKeyFile, cfg.JWT.VerificationKeyFiles)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid JWT key configuration: %w", err)
+		}
 	}
 
 	if cfg.AppEnv == "local" {
@@ -53,16 +64,27 @@ func NewServer(ctx context.Context) (*http.Server, *Server) {
 		db:   db,
 	}
 
-	jwtManager := auth.NewJWTManager(cfg.JWT.Secret)
+	// Persist sessions and revoked tokens so they survive restarts and are shared between replicas
+	var sessionStore auth.SessionStore
+	if cfg.AppEnv == "test" {
+		sessionStore = auth.NewMemoryStore()
+	} else {
+		sessionStore = auth.NewPostgresStore(repository.New(serverImpl.db.GetPool()))
+	}
+	jwtManager := auth.NewJWTManagerWithKeys(cfg.JWT.Secret, keys, sessionStore)
+	jwtManager.SetSecretCutoff(cfg.JWT.SecretCutoff)
+
+	// Sweep expired tokens and idle sessions in the background until ctx is cancelled
+	jwtManager.StartCleanup(ctx, auth.CleanupInterval)
 
 	// Declare Server config
 	httpServer := &http.Server{
 		Addr:         fmt.Sprintf(":%d", serverImpl.port),
-		Handler:      serverImpl.RegisterRoutes(serverImpl.db.GetConnection(), *jwtManager, cfg.AppEnv),
+		Handler:      serverImpl.RegisterRoutes(serverImpl.db.GetPool(), serverImpl.db.GetReplicaPool(), jwtManager, cfg.AppEnv),
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
 
-	return httpServer, serverImpl
+	return httpServer, serverImpl, nil
 }
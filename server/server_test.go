@@ -81,6 +81,15 @@ func TestNewServer(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "unreadable signing key",
+			envVars: map[string]string{
+				"PORT":                 "8080",
+				"APP_ENV":              "test",
+				"JWT_SIGNING_KEY_FILE": "/nonexistent/jwt-signing-key.pem",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -88,15 +97,21 @@ func TestNewServer(t *testing.T) {
 			cleanup := setupTest(tt.envVars)
 			defer cleanup()
 
-			httpServer, serverImpl := NewServer(context.Background())
+			httpServer, serverImpl, err := NewServer(context.Background())
 
 			if tt.wantErr {
+				if err == nil {
+					t.Error("NewServer() should fail when configuration is invalid")
+				}
 				if httpServer != nil || serverImpl != nil {
 					t.Error("NewServer() should return nil when configuration is invalid")
 				}
 				return
 			}
 
+			if err != nil {
+				t.Fatalf("NewServer() failed with valid configuration: %v", err)
+			}
 			if httpServer == nil || serverImpl == nil {
 				t.Fatal("NewServer() returned nil with valid configuration")
 			}